@@ -8,14 +8,18 @@ package main_test
 // TODO: Results.SpanData could become a struct now
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"regexp"
+	"runtime"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/equinix-labs/otel-cli/otelcli"
 	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/google/go-cmp/cmp"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
@@ -57,6 +61,9 @@ type FixtureConfig struct {
 	// and the signal to send can be specified
 	KillAfter  time.Duration
 	KillSignal os.Signal
+	// runs otel-cli with stdout connected to a pipe that's already closed
+	// on the reading end, like piping into `head -0`
+	ClosedStdout bool
 }
 
 // mostly mirrors otelcli.StatusOutput but we need more
@@ -214,13 +221,15 @@ var suites = []FixtureSuite{
 				SpanData: map[string]string{
 					"span_id":    "*",
 					"trace_id":   "*",
-					"attributes": `medium=book,protagonist=DentArthurdent`,
+					"attributes": `forced_ids=true,medium=book,protagonist=DentArthurdent`,
 				},
+				// the compressors otel-cli can use are advertised for responses
 				Headers: map[string]string{
-					":authority":   "{{endpoint}}\n",
-					"content-type": "application/grpc\n",
-					"user-agent":   "*",
-					"lue":          "42\n",
+					":authority":           "{{endpoint}}\n",
+					"content-type":         "application/grpc\n",
+					"grpc-accept-encoding": "\"gzip,zstd\"\n",
+					"user-agent":           "*",
+					"lue":                  "42\n",
 				},
 				CliOutput: "" +
 					"# trace id: aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa\n" +
@@ -250,6 +259,7 @@ var suites = []FixtureSuite{
 					WithEndpoint("https://{{endpoint}}").
 					WithProtocol("grpc").
 					WithVerbose(true).
+					WithFail(true).
 					WithTlsNoVerify(true),
 				Diagnostics: otelcli.Diagnostics{
 					IsRecording:        true,
@@ -311,7 +321,8 @@ var suites = []FixtureSuite{
 					WithTlsCACert("{{tls_ca_cert}}").
 					WithTlsClientKey("{{tls_client_key}}").
 					WithTlsClientCert("{{tls_client_cert}}").
-					WithVerbose(true),
+					WithVerbose(true).
+					WithFail(true),
 				Diagnostics: otelcli.Diagnostics{
 					IsRecording:        true,
 					NumArgs:            13,
@@ -346,7 +357,8 @@ var suites = []FixtureSuite{
 					WithTlsCACert("{{tls_ca_cert}}").
 					WithTlsClientKey("{{tls_client_key}}").
 					WithTlsClientCert("{{tls_client_cert}}").
-					WithVerbose(true),
+					WithVerbose(true).
+					WithFail(true),
 				Diagnostics: otelcli.Diagnostics{
 					IsRecording:       true,
 					NumArgs:           11,
@@ -399,6 +411,21 @@ var suites = []FixtureSuite{
 					"\"lmao\" as an bool: strconv.ParseBool: parsing \"lmao\": invalid syntax\n",
 			},
 		},
+		{
+			Name: "unknown feature names cause the command to fail",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--fail", "--verbose", "--feature", "strict-exec-argv,strict-exec-arvg"},
+				Env: map[string]string{
+					"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}",
+				},
+			},
+			Expect: Results{
+				Config:      otelcli.DefaultConfig(),
+				ExitCode:    1,
+				CliOutputRe: regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `),
+				CliOutput:   "unknown feature(s) strict-exec-arvg, expected one of strict-exec-argv, no-auto-insecure\n",
+			},
+		},
 		{
 			Name: "https:// should fail when TLS is not available",
 			Config: FixtureConfig{
@@ -566,6 +593,65 @@ var suites = []FixtureSuite{
 				},
 			},
 		},
+		{
+			Name: "exec --rusage records the child's resource usage",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--verbose", "--fail",
+					"--rusage",
+					"--", "/bin/echo", "a", "z",
+				},
+			},
+			Expect: Results{
+				SpanCount: 1,
+				CliOutput: "a z\n",
+				SpanData: map[string]string{
+					"attributes": "/^process.command=/bin/echo,process.command_args=/bin/echo,a,z,process.context_switches.involuntary=\\d+,process.context_switches.voluntary=\\d+,process.cpu_time=[\\d.e-]+,process.io.read_blocks=\\d+,process.io.write_blocks=\\d+,process.memory.max_rss=\\d+,process.owner=\\w+,process.page_faults.major=\\d+,process.page_faults.minor=\\d+,process.parent_pid=\\d+,process.pid=\\d+,process.system_time=[\\d.e-]+,process.user_time=[\\d.e-]+$/",
+				},
+			},
+		},
+		{
+			Name: "exec passes arguments verbatim, without a shell",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--", "printf", "%s|", "arg with spaces", "*", "$HOME", "it's",
+				},
+			},
+			Expect: Results{
+				SpanCount: 1,
+				CliOutput: "arg with spaces|*|$HOME|it's|",
+			},
+		},
+		{
+			Name: "exec --shell runs the command line with sh -c",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--shell", "--", "echo", "$((1 + 2))", "|", "tr", "3", "x",
+				},
+			},
+			Expect: Results{
+				SpanCount: 1,
+				CliOutput: "x\n",
+			},
+		}, {
+			Name: "exec --inject replaces the default TRACEPARENT injection",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--force-trace-id", "0102030405060708090a0b0c0d0e0f10", "--force-span-id", "0101010101010101",
+					"--inject", "b3", "--inject", "env:REQUEST_ID={{trace_id}}",
+					"--", "sh", "-c", "echo \"[$B3] [$REQUEST_ID] [$TRACEPARENT]\"",
+				},
+				Env: map[string]string{"TRACEPARENT": "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01"},
+			},
+			Expect: Results{
+				SpanCount: 1,
+				CliOutput: "[0102030405060708090a0b0c0d0e0f10-0101010101010101-1] [0102030405060708090a0b0c0d0e0f10] []\n",
+			},
+		},
 	},
 	// otel-cli span with no OTLP config should do and print nothing
 	{
@@ -593,12 +679,14 @@ var suites = []FixtureSuite{
 				SpanCount: 1,
 				Diagnostics: otelcli.Diagnostics{
 					IsRecording:       true,
+					ConfigFileLoaded:  true,
 					NumArgs:           3,
 					ParsedTimeoutMs:   1000,
 					Endpoint:          "*",
 					EndpointSource:    "*",
 					DetectedLocalhost: true,
 					Error:             "could not open file '/tmp/traceparent.txt' for read: open /tmp/traceparent.txt: no such file or directory",
+					Normalized:        []string{`--status-code "0" to "unset"`},
 				},
 				Env: map[string]string{
 					"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}",
@@ -617,7 +705,7 @@ var suites = []FixtureSuite{
 					WithSpanName("config_file_span").
 					WithKind("server").
 					WithAttributes(map[string]string{"attr1": "value1"}).
-					WithStatusCode("0").
+					WithStatusCode("unset"). // "0" in the file
 					WithStatusDescription("status description").
 					WithTraceparentCarrierFile("/tmp/traceparent.txt").
 					WithTraceparentIgnoreEnv(true).
@@ -698,6 +786,225 @@ var suites = []FixtureSuite{
 			},
 		},
 	},
+	// OTEL_CLI_FAKE_CLOCK / --fake-now pin timestamps for reproducible output
+	{
+		{
+			Name: "otel-cli exec with a fake clock",
+			Config: FixtureConfig{
+				CliArgs:       []string{"exec", "--endpoint", "{{endpoint}}", "--fake-now", "2021-04-06T13:07:54Z", "true"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"start": "1617714474000000000",
+					"end":   "1617714474000000000",
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli span with OTEL_CLI_FAKE_CLOCK",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--end", "1617739615.759793032"},
+				Env: map[string]string{
+					"OTEL_CLI_FAKE_CLOCK": "1617739561",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"start": "1617739561000000000",
+					"end":   "1617739615759793032",
+				},
+				SpanCount: 1,
+			},
+		},
+	},
+	// --print-json prints the span for scripts, pinned here with a fake clock and forced ids
+	{
+		{
+			Name: "otel-cli span --print-json",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--name", "json",
+					"--fake-now", "2024-01-01T00:00:00Z", "--end", "1704067201.500000000",
+					"--force-trace-id", "0102030405060708090a0b0c0d0e0f10", "--force-span-id", "0101010101010101",
+					"--attrs", "env=prod", "--print-json"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				CliOutput: `{"trace_id":"0102030405060708090a0b0c0d0e0f10","span_id":"0101010101010101",` +
+					`"traceparent":"00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01","name":"json","kind":"client",` +
+					`"start_time":"2024-01-01T00:00:00Z","end_time":"2024-01-01T00:00:01.5Z",` +
+					`"start_time_unix_nano":1704067200000000000,"end_time_unix_nano":1704067201500000000,"duration_ms":1500,` +
+					`"attributes":{"env":"prod","forced_ids":true},"status_code":"unset"}` + "\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// --print-url prints a link to the trace once the span is sent
+	{
+		{
+			Name: "otel-cli span --print-url",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--name", "url",
+					"--force-trace-id", "0102030405060708090a0b0c0d0e0f10", "--force-span-id", "0101010101010101",
+					"--print-url", "jaeger=https://jaeger.example.com"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig(),
+				CliOutput: "https://jaeger.example.com/trace/0102030405060708090a0b0c0d0e0f10\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// --dry-run prints the payload instead of sending it, even without an endpoint
+	{
+		{
+			Name: "otel-cli span --dry-run",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--name", "dry", "--dry-run",
+					"--fake-now", "2024-01-01T00:00:00Z",
+					"--force-trace-id", "0102030405060708090a0b0c0d0e0f10", "--force-span-id", "0101010101010101"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				CliOutput: `{"resourceSpans":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"otel-cli"}}]},` +
+					`"schemaUrl":"https://opentelemetry.io/schemas/1.17.0","scopeSpans":[{"schemaUrl":"https://opentelemetry.io/schemas/1.17.0",` +
+					`"scope":{"name":"github.com/equinix-labs/otel-cli","version":"unknown"},"spans":[{"attributes":[{"key":"forced_ids","value":{"boolValue":true}}],` +
+					`"endTimeUnixNano":"1704067200000000000","kind":"SPAN_KIND_CLIENT","name":"dry","spanId":"0101010101010101",` +
+					`"startTimeUnixNano":"1704067200000000000","status":{},"traceId":"0102030405060708090a0b0c0d0e0f10"}]}]}]}` + "\n",
+			},
+		},
+	},
+	// --link
+	{
+		{
+			Name: "otel-cli span --link links to other traces",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "--endpoint", "{{endpoint}}",
+					"--link", "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+					"--link", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01:shard=2,job=fanout",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"links": "f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137;0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331:job=fanout,shard=2",
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli span --link with an invalid traceparent",
+			Config: FixtureConfig{
+				CliArgs:       []string{"span", "--endpoint", "{{endpoint}}", "--link", "not-a-traceparent", "--fail", "--verbose"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config:      otelcli.DefaultConfig(),
+				CliOutputRe: regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `),
+				CliOutput:   "invalid link: could not parse invalid traceparent \"not-a-traceparent\": not in version-traceid-spanid-flags format\n",
+				SpanCount:   0,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if r.ExitCode != 1 {
+						t.Errorf("expected exit code 1 with --fail but got %d", r.ExitCode)
+					}
+				},
+			},
+		},
+	},
+	// --warn-if-longer-than and --error-if-longer-than
+	{
+		{
+			Name: "otel-cli span --error-if-longer-than marks slow spans as errors",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "--endpoint", "{{endpoint}}",
+					"--start", "1617739561", "--end", "1617739571",
+					"--warn-if-longer-than", "5s", "--error-if-longer-than", "8s",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"attributes":         "otel-cli.error_if_longer_than=8s,otel-cli.warn_if_longer_than=5s,otel-cli.warn_threshold_exceeded=true",
+					"status_code":        "2",
+					"status_description": "span took 10s, longer than the 8s limit",
+				},
+				SpanCount: 1,
+			},
+		},
+	},
+	// otel-cli span push/pop nest spans through a stack file
+	{
+		{
+			Name: "otel-cli span push",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "push", "--endpoint", "{{endpoint}}", "--stack-file", "./otel-cli-test-span-stack.json",
+					"--name", "outer", "--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig(),
+				SpanCount: 0,
+			},
+		},
+		{
+			Name: "otel-cli span push (nested)",
+			Config: FixtureConfig{
+				CliArgs:       []string{"span", "push", "--endpoint", "{{endpoint}}", "--stack-file", "./otel-cli-test-span-stack.json", "--name", "inner"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig(),
+				SpanCount: 0,
+			},
+		},
+		{
+			Name: "otel-cli span pop sends the inner span",
+			Config: FixtureConfig{
+				CliArgs:       []string{"span", "pop", "--endpoint", "{{endpoint}}", "--stack-file", "./otel-cli-test-span-stack.json", "--attrs", "abc=def"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"trace_id":       "e39280f2980af3a8600ae98c74f2dabf",
+					"span_id":        "*",
+					"parent_span_id": "023eee2731392b4d",
+					"attributes":     "abc=def",
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli span pop sends the outer span",
+			Config: FixtureConfig{
+				CliArgs:       []string{"span", "pop", "--endpoint", "{{endpoint}}", "--stack-file", "./otel-cli-test-span-stack.json"},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"trace_id": "e39280f2980af3a8600ae98c74f2dabf",
+					"span_id":  "023eee2731392b4d",
+				},
+				SpanCount: 1,
+			},
+		},
+	},
 	// otel-cli span --print-tp actually prints
 	{
 		{
@@ -715,6 +1022,55 @@ var suites = []FixtureSuite{
 			},
 		},
 	},
+	// otel-cli span --tp-print with a closed stdout still sends the span and
+	// exits cleanly instead of dying of SIGPIPE
+	{
+		{
+			Name: "otel-cli span --tp-print to a closed pipe",
+			Config: FixtureConfig{
+				CliArgs:      []string{"span", "--endpoint", "{{endpoint}}", "--tp-print", "--name", "broken pipe"},
+				ClosedStdout: true,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+					"name":     "broken pipe",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if r.ExitCode != 0 {
+						t.Errorf("[%s] expected exit code 0 but got %d", f.Name, r.ExitCode)
+					}
+				},
+			},
+		},
+		{
+			Name: "otel-cli span --tp-print --fail to a closed pipe exits 1",
+			Config: FixtureConfig{
+				CliArgs:      []string{"span", "--endpoint", "{{endpoint}}", "--tp-print", "--fail", "--name", "broken pipe"},
+				ClosedStdout: true,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if r.ExitCode != 1 {
+						t.Errorf("[%s] expected exit code 1 but got %d", f.Name, r.ExitCode)
+					}
+				},
+			},
+		},
+	},
 	// otel-cli span --print-tp propagates traceparent even when not recording
 	{
 		{
@@ -802,12 +1158,33 @@ var suites = []FixtureSuite{
 						t.Errorf("got wrong string for status description: %q", r.Span.Status.GetMessage())
 					}
 				},
+				// event attributes get the same types as span attributes
+				func(t *testing.T, f Fixture, r Results) {
+					if len(r.SpanEvents) != 1 {
+						t.Fatalf("expected 1 event but got %d", len(r.SpanEvents))
+					}
+					got := map[string]string{}
+					for _, attr := range r.SpanEvents[0].Attributes {
+						got[attr.Key] = fmt.Sprintf("%T", attr.Value.Value)
+					}
+					want := map[string]string{
+						"ima":    "*v1.AnyValue_StringValue",
+						"count":  "*v1.AnyValue_IntValue",
+						"ratio":  "*v1.AnyValue_DoubleValue",
+						"ok":     "*v1.AnyValue_BoolValue",
+						"digest": "*v1.AnyValue_BytesValue",
+					}
+					if diff := cmp.Diff(want, got); diff != "" {
+						t.Errorf("event attribute types did not match (-want +got):\n%s", diff)
+					}
+				},
 			},
 		},
 		{
 			Name: "otel-cli span event",
 			Config: FixtureConfig{
-				CliArgs: []string{"span", "event", "--name", "an event happened", "--attrs", "ima=now,mondai=problem", "--sockdir", "."},
+				CliArgs: []string{"span", "event", "--name", "an event happened", "--sockdir", ".",
+					"--attrs", "ima=now,count=3,ratio=0.5,ok=true", "--attr-bytes", "digest=AAEC"},
 			},
 			Expect: Results{Config: otelcli.DefaultConfig()},
 		},
@@ -872,12 +1249,15 @@ var suites = []FixtureSuite{
 			Expect: Results{Config: otelcli.DefaultConfig()},
 		},
 	},
-	// otel-cli span background with attrs, append attrs on span end
+	// otel-cli span update changes the background span while it runs
 	{
 		{
-			Name: "otel-cli span background (recording) with attrs append on end",
+			Name: "otel-cli span background (recording) updated while running",
 			Config: FixtureConfig{
-				CliArgs:       []string{"span", "background", "--timeout", "1s", "--sockdir", ".", "--attrs", "abc=def"},
+				CliArgs: []string{
+					"span", "background", "--timeout", "1s", "--sockdir", ".",
+					"--name", "starting", "--attrs", "stage=init,abc=def",
+				},
 				Env:           map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}"},
 				TestTimeoutMs: 2000,
 				Background:    true,
@@ -888,14 +1268,167 @@ var suites = []FixtureSuite{
 				SpanData: map[string]string{
 					"span_id":    "*",
 					"trace_id":   "*",
-					"attributes": `abc=def,ghi=jkl`, // weird format because of limitation in OTLP server
+					"name":       "deploying",
+					"attributes": `abc=def,hosts=3,stage=done`,
 				},
 				SpanCount: 1,
 			},
-		},
-		{
-			Name: "otel-cli span end",
-			Config: FixtureConfig{
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if len(r.Span.Links) != 1 {
+						t.Errorf("expected the link added by span update, got %d links", len(r.Span.Links))
+					}
+				},
+			},
+		},
+		{
+			Name: "otel-cli span update",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "update", "--sockdir", ".",
+					"--name", "deploying",
+					"--attrs", "stage=running,hosts=3",
+					"--link", "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+				},
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+		{
+			Name: "otel-cli span end",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "end", "--sockdir", ".", "--attrs", "stage=done"},
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+		{
+			Name: "otel-cli span background (recording) updated while running",
+			Config: FixtureConfig{
+				Foreground: true, // fg
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+	},
+	// otel-cli span background with a --span-handle, so its socket is named
+	// after the handle and only commands with the same handle can reach it
+	{
+		{
+			Name: "otel-cli span background (recording) with a span handle",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "background", "--timeout", "1s", "--sockdir", ".",
+					"--span-handle", "compile", "--name", "compile",
+				},
+				Env:           map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}"},
+				TestTimeoutMs: 2000,
+				Background:    true,
+				Foreground:    false,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+					"name":     "compile",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if len(r.Span.Events) != 1 {
+						t.Errorf("expected 1 event sent to the handle, got %d", len(r.Span.Events))
+					}
+				},
+			},
+		},
+		{
+			Name: "otel-cli span event to a span handle",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "event", "--sockdir", ".", "--span-handle", "compile", "--name", "compiled"},
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+		{
+			Name: "otel-cli span end a span handle",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "end", "--sockdir", ".", "--span-handle", "compile"},
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+		{
+			Name: "otel-cli span background (recording) with a span handle",
+			Config: FixtureConfig{
+				Foreground: true, // fg
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+	},
+	// otel-cli span background, add links on span end
+	{
+		{
+			Name: "otel-cli span background (recording) with links added on end",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "background", "--timeout", "1s", "--sockdir", ".",
+					"--link", "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+				},
+				Env:           map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}"},
+				TestTimeoutMs: 2000,
+				Background:    true,
+				Foreground:    false,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+					"links":    "f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137;0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331:why=retry",
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli span end --link",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "end",
+					"--sockdir", ".",
+					"--link", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01:why=retry",
+				},
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+		{
+			Name: "otel-cli span background (recording) with links added on end",
+			Config: FixtureConfig{
+				Foreground: true, // fg
+			},
+			Expect: Results{Config: otelcli.DefaultConfig()},
+		},
+	},
+	// otel-cli span background with attrs, append attrs on span end
+	{
+		{
+			Name: "otel-cli span background (recording) with attrs append on end",
+			Config: FixtureConfig{
+				CliArgs:       []string{"span", "background", "--timeout", "1s", "--sockdir", ".", "--attrs", "abc=def"},
+				Env:           map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "{{endpoint}}"},
+				TestTimeoutMs: 2000,
+				Background:    true,
+				Foreground:    false,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":    "*",
+					"trace_id":   "*",
+					"attributes": `abc=def,ghi=jkl`, // weird format because of limitation in OTLP server
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli span end",
+			Config: FixtureConfig{
 				CliArgs: []string{
 					"span", "end",
 					"--sockdir", ".",
@@ -967,78 +1500,644 @@ var suites = []FixtureSuite{
 			Expect: Results{
 				Config: otelcli.DefaultConfig(),
 				SpanData: map[string]string{
-					"span_id":  "*",
-					"trace_id": "edededededededededededededed9000",
+					"span_id":  "*",
+					"trace_id": "edededededededededededededed9000",
+				},
+				CliOutput: "\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// otel-cli exec runs otel-cli exec
+	{
+		{
+			Name: "otel-cli exec (nested)",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--name", "outer", "--endpoint", "{{endpoint}}", "--fail", "--verbose", "--",
+					"./otel-cli", "exec", "--name", "inner", "--endpoint", "{{endpoint}}", "--tp-required", "--fail", "--verbose", "echo", "hello world"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig(),
+				CliOutput: "hello world\n",
+				SpanCount: 2,
+			},
+		},
+	},
+	// otel-cli exec echo "{{traceparent}}" and otel-cli exec --tp-disable-inject
+	{
+		{
+			Name: "otel-cli exec with arg injection injects the traceparent",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+					"--",
+					"echo", "{{traceparent}}"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				CliOutput: "00-e39280f2980af3a8600ae98c74f2dabf-023eee2731392b4d-01\n",
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli exec --tp-disable-inject returns the {{traceparent}} tag unmodified",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+					"--tp-disable-inject",
+					"--",
+					"echo", "{{traceparent}}"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				CliOutput: "{{traceparent}}\n",
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli exec returns the {{traceparent}} tag unmodified with OTEL_CLI_EXEC_TP_DISABLE_INJECT",
+			Config: FixtureConfig{
+				Env: map[string]string{
+					"OTEL_CLI_EXEC_TP_DISABLE_INJECT": "true",
+				},
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+					"--",
+					"echo", "{{traceparent}}"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				CliOutput: "{{traceparent}}\n",
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli exec --feature strict-exec-argv returns the {{traceparent}} tag unmodified",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+					"--feature", "strict-exec-argv",
+					"--",
+					"echo", "{{traceparent}}"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}").WithFeatures(map[string]bool{"strict-exec-argv": true}),
+				CliOutput: "{{traceparent}}\n",
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli exec --inject-flag-template adds flags after the command",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
+					"--inject-flag-template", "--trace {{trace_id}}",
+					"--",
+					"echo", "hello"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}").WithExecFlagTemplates([]string{"--trace {{trace_id}}"}),
+				CliOutput: "--trace e39280f2980af3a8600ae98c74f2dabf hello\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// otel-cli exec --pty gives the child a terminal
+	{
+		{
+			Name: "otel-cli exec --pty runs the command with a tty",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}", "--pty",
+					"--",
+					"sh", "-c", "test -t 0 && test -t 1 && echo tty"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				// the pty's line discipline turns \n into \r\n
+				CliOutput: "tty\r\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// otel-cli exec --sample-resources puts resource usage on the span
+	{
+		{
+			Name: "otel-cli exec --sample-resources adds resource samples",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}", "--sample-resources", "10ms",
+					"--",
+					"sleep", "0.2"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if runtime.GOOS != "linux" {
+						return // sampling is only implemented on Linux
+					}
+					attrs := otlpclient.SpanAttributesToStringMap(r.Span)
+					if _, ok := attrs["process.memory.rss.max"]; !ok {
+						t.Errorf("[%s] expected resource sample attributes but got %v", f.Name, attrs)
+					}
+					if len(r.SpanEvents) == 0 {
+						t.Errorf("[%s] expected resource sample events but got none", f.Name)
+					}
+				},
+			},
+		},
+	},
+	// otel-cli exec --spans-from-output turns output markers into events and spans
+	{
+		{
+			Name: "otel-cli exec --spans-from-output adds events and child spans",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}", "--spans-from-output",
+					"--",
+					"sh", "-c", "echo 'OTEL_CLI_EVENT: name=hello'; echo 'OTEL_CLI_SPAN_START: name=step'; echo 'OTEL_CLI_SPAN_END: name=step status=ok'"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				CliOutput: "OTEL_CLI_EVENT: name=hello\nOTEL_CLI_SPAN_START: name=step\nOTEL_CLI_SPAN_END: name=step status=ok\n",
+				SpanCount: 2,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if r.EventCount != 1 {
+						t.Errorf("[%s] expected 1 event but got %d", f.Name, r.EventCount)
+					}
+				},
+			},
+		},
+	},
+	// otel-cli exec --per-line-events records output lines as events
+	{
+		{
+			Name: "otel-cli exec --per-line-events records matching lines",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"exec", "--endpoint", "{{endpoint}}",
+					"--per-line-events", "--event-match", "^step", "--max-events", "2",
+					"--",
+					"sh", "-c", "echo step 1; echo noise; echo step 2; echo step 3"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				CliOutput: "step 1\nnoise\nstep 2\nstep 3\n",
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if r.EventCount != 2 {
+						t.Errorf("[%s] expected 2 events but got %d", f.Name, r.EventCount)
+					}
+					if r.Span.DroppedEventsCount != 1 {
+						t.Errorf("[%s] expected 1 dropped event but got %d", f.Name, r.Span.DroppedEventsCount)
+					}
+				},
+			},
+		},
+	},
+	// otel-cli exec --provenance records what was run
+	{
+		{
+			Name: "otel-cli exec --provenance adds executable attributes",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec", "--endpoint", "{{endpoint}}", "--provenance", "--", "true"},
+			},
+			Expect: Results{
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					attrs := otlpclient.SpanAttributesToStringMap(r.Span)
+					for _, key := range []string{"process.working_directory", "process.executable.path", "otel-cli.executable.sha256", "otel-cli.executable.size", "otel-cli.executable.mtime"} {
+						if attrs[key] == "" {
+							t.Errorf("[%s] expected attribute %s but got %v", f.Name, key, attrs)
+						}
+					}
+				},
+			},
+		},
+	},
+	// otel-cli exec --status-map sets the span status from the exit code
+	{
+		{
+			Name: "otel-cli exec --status-map maps the exit code to an error",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec", "--endpoint", "{{endpoint}}", "--status-map", "0=ok,1-127=error", "--", "sh", "-c", "exit 3"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"status_code":        "2",
+					"status_description": "exited with code 3",
+				},
+				SpanCount: 1,
+				ExitCode:  3,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					attrs := otlpclient.SpanAttributesToStringMap(r.Span)
+					if attrs["process.exit_code"] != "3" {
+						t.Errorf("[%s] expected process.exit_code 3 but got %v", f.Name, attrs)
+					}
+				},
+			},
+		},
+		{
+			Name: "otel-cli exec --status-map can treat a non-zero exit as ok",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec", "--endpoint", "{{endpoint}}", "--status-map", "0-1=ok", "--", "sh", "-c", "exit 1"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"status_code":        "1",
+					"status_description": "",
+				},
+				SpanCount: 1,
+				ExitCode:  1,
+			},
+		},
+	},
+	// W3C tracestate is propagated and can be updated with --tracestate
+	{
+		{
+			Name: "otel-cli exec --tracestate updates TRACESTATE for the span and child",
+			Config: FixtureConfig{
+				Env: map[string]string{
+					"TRACEPARENT": "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+					"TRACESTATE":  "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+				},
+				CliArgs: []string{"exec", "--endpoint", "{{endpoint}}", "--tracestate", "rojo=updated", "--", "sh", "-c", "echo $TRACESTATE"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"trace_id":       "f61fc53f926e07a9c3893b1a722e1b65",
+					"parent_span_id": "7a2d6a804f3de137",
+					"trace_state":    "rojo=updated,congo=t61rcWkgMzE",
+				},
+				CliOutput: "rojo=updated,congo=t61rcWkgMzE\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// --propagation-format reads and writes B3 and Jaeger instead of W3C
+	{
+		{
+			Name: "otel-cli span --propagation-format jaeger",
+			Config: FixtureConfig{
+				Env: map[string]string{
+					"UBER_TRACE_ID": "a3ce929d0e0e4736:f067aa0ba902b7:0:1",
+				},
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--propagation-format", "jaeger"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}").WithPropagationFormat("jaeger"),
+				SpanData: map[string]string{
+					"trace_id":       "0000000000000000a3ce929d0e0e4736",
+					"parent_span_id": "00f067aa0ba902b7",
+				},
+				SpanCount: 1,
+			},
+		},
+		{
+			Name: "otel-cli exec --propagation-format b3 passes B3 to the child",
+			Config: FixtureConfig{
+				Env: map[string]string{
+					"B3":          "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-1",
+					"TRACEPARENT": "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+				},
+				CliArgs: []string{"exec", "--endpoint", "{{endpoint}}", "--propagation-format", "b3", "--",
+					"sh", "-c", "echo ${B3%%-*} ${TRACEPARENT:-unset}"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}").WithPropagationFormat("b3"),
+				SpanData: map[string]string{
+					"trace_id":       "80f198ee56343ba864fe8b2a57d3eff7",
+					"parent_span_id": "e457b5a2e4d86bd1",
+				},
+				CliOutput: "80f198ee56343ba864fe8b2a57d3eff7 unset\n",
+				SpanCount: 1,
+			},
+		},
+	},
+	// attributes that aren't valid UTF-8 are sent as bytes instead of being
+	// rejected by the collector, and --attr-bytes sends bytes on purpose
+	{
+		{
+			Name: "otel-cli span with invalid UTF-8 and --attr-bytes",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}",
+					"--attrs", "name=caf\u00e9 \u2615,bad=\xff\xfe",
+					"--attr-bytes", "blob=AAEC",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"attributes": "bad=//4=,blob=AAEC,name=caf\u00e9 \u2615",
+				},
+				SpanCount: 1,
+			},
+		},
+	},
+	// span event without --sockdir sends the event on a span of its own
+	{
+		{
+			Name: "otel-cli span event without a background span",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "event", "--endpoint", "{{endpoint}}",
+					"--tp", "00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01",
+					"--name", "cache-flushed", "--time", "1704067200", "--attrs", "entries=42",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"trace_id":       "0102030405060708090a0b0c0d0e0f10",
+					"span_id":        "*",
+					"parent_span_id": "0101010101010101",
+					"name":           "cache-flushed",
+					"start":          "1704067200000000000",
+					"end":            "1704067200000000000",
+				},
+				SpanCount:  1,
+				EventCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					if len(r.SpanEvents) != 1 {
+						t.Fatalf("expected 1 event but got %d", len(r.SpanEvents))
+					}
+					event := r.SpanEvents[0]
+					if event.Name != "cache-flushed" || event.TimeUnixNano != 1704067200000000000 {
+						t.Errorf("expected the cache-flushed event at the span's time, got %q at %d", event.Name, event.TimeUnixNano)
+					}
+					if len(event.Attributes) != 1 || event.Attributes[0].Value.GetIntValue() != 42 {
+						t.Errorf("expected the attributes on the event, got %v", event.Attributes)
+					}
+				},
+			},
+		},
+	},
+	// typed attribute flags skip guessing the type from the value
+	{
+		{
+			Name: "otel-cli span with typed attributes",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}",
+					"--attrs", "build=0123", "--attr-str", "build=0123", "--attr-float", "ratio=2",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"attributes": "build=0123,ratio=2",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					got := map[string]string{}
+					for _, attr := range r.Span.Attributes {
+						got[attr.Key] = fmt.Sprintf("%T", attr.Value.Value)
+					}
+					want := map[string]string{
+						"build": "*v1.AnyValue_StringValue",
+						"ratio": "*v1.AnyValue_DoubleValue",
+					}
+					if diff := cmp.Diff(want, got); diff != "" {
+						t.Errorf("attribute types did not match (-want +got):\n%s", diff)
+					}
+				},
+			},
+		},
+	},
+	// --resource-attrs override OTEL_RESOURCE_ATTRIBUTES, --resource-detectors adds more
+	{
+		{
+			Name: "otel-cli span with resource attrs and detectors",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "--endpoint", "{{endpoint}}", "--name", "resourceful",
+					"--resource-attrs", "deployment.environment=prod,team=infra",
+					"--resource-detectors", "os,process",
+				},
+				Env: map[string]string{
+					"OTEL_RESOURCE_ATTRIBUTES": "deployment.environment=dev,region=east",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithSpanName("resourceful").
+					WithResourceAttributes(map[string]string{"deployment.environment": "prod", "team": "infra"}).
+					WithResourceDetectors("os,process"),
+				Env: map[string]string{
+					"OTEL_RESOURCE_ATTRIBUTES": "deployment.environment=dev,region=east",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					attrs := otlpclient.ResourceAttributesToStringMap(r.ResourceSpans)
+					for key, want := range map[string]string{
+						"service.name":           "otel-cli",
+						"deployment.environment": "prod",
+						"team":                   "infra",
+						"region":                 "east",
+						"os.type":                runtime.GOOS,
+					} {
+						if attrs[key] != want {
+							t.Errorf("[%s] expected resource attribute %s=%q but got %q", f.Name, key, want, attrs[key])
+						}
+					}
+					if _, ok := attrs["process.pid"]; !ok {
+						t.Errorf("[%s] expected process detector attributes but got %v", f.Name, attrs)
+					}
+					if args, ok := attrs["process.command_args"]; ok {
+						t.Errorf("[%s] expected otel-cli's command line to be left out but got %q", f.Name, args)
+					}
+				},
+			},
+		},
+		{
+			Name: "otel-cli span with --resource-detectors none",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "--endpoint", "{{endpoint}}", "--name", "resourceless",
+					"--resource-detectors", "none",
+				},
+				Env: map[string]string{
+					"OTEL_RESOURCE_ATTRIBUTES": "region=east",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithSpanName("resourceless").
+					WithResourceDetectors("none"),
+				Env: map[string]string{
+					"OTEL_RESOURCE_ATTRIBUTES": "region=east",
+				},
+				SpanCount: 1,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, f Fixture, r Results) {
+					attrs := otlpclient.ResourceAttributesToStringMap(r.ResourceSpans)
+					want := map[string]string{"service.name": "otel-cli"}
+					if diff := cmp.Diff(want, attrs); diff != "" {
+						t.Errorf("[%s] resource attributes did not match (-want +got):\n%s", f.Name, diff)
+					}
+				},
+			},
+		},
+	},
+	// samplers drop spans but still propagate the traceparent, unsampled
+	{
+		{
+			Name: "otel-cli span --sampler always_off",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span", "--endpoint", "{{endpoint}}", "--sampler", "always_off", "--tp-print",
+					"--force-trace-id", "00000000000000000000000000000001",
+					"--force-span-id", "0000000000000002",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithSampler("always_off").
+					WithTraceparentPrint(true).
+					WithForceTraceId("00000000000000000000000000000001").
+					WithForceSpanId("0000000000000002"),
+				CliOutput: "" +
+					"# trace id: 00000000000000000000000000000001\n" +
+					"#  span id: 0000000000000002\n" +
+					"TRACEPARENT=00-00000000000000000000000000000001-0000000000000002-00\n",
+				SpanCount: 0,
+			},
+		},
+		{
+			Name: "parentbased sampler follows a sampled parent",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--name", "kept by parent"},
+				Env: map[string]string{
+					"OTEL_TRACES_SAMPLER":     "parentbased_traceidratio",
+					"OTEL_TRACES_SAMPLER_ARG": "0",
+					"TRACEPARENT":             "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01",
+				},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithSpanName("kept by parent").
+					WithSampler("parentbased_traceidratio").
+					WithSamplerArg("0"),
+				Env: map[string]string{
+					"OTEL_TRACES_SAMPLER":     "parentbased_traceidratio",
+					"OTEL_TRACES_SAMPLER_ARG": "0",
+					"TRACEPARENT":             "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01",
+				},
+				SpanData: map[string]string{
+					"trace_id":       "f6c109f48195b451c4def6ab32f47b61",
+					"parent_span_id": "a5d2a35f2483004e",
 				},
-				CliOutput: "\n",
 				SpanCount: 1,
 			},
 		},
-	},
-	// otel-cli exec runs otel-cli exec
-	{
 		{
-			Name: "otel-cli exec (nested)",
+			Name: "--respect-sampling drops spans under an unsampled parent",
 			Config: FixtureConfig{
 				CliArgs: []string{
-					"exec", "--name", "outer", "--endpoint", "{{endpoint}}", "--fail", "--verbose", "--",
-					"./otel-cli", "exec", "--name", "inner", "--endpoint", "{{endpoint}}", "--tp-required", "--fail", "--verbose", "echo", "hello world"},
+					"span", "--endpoint", "{{endpoint}}", "--respect-sampling", "--tp-print",
+					"--force-span-id", "0000000000000002",
+				},
+				Env: map[string]string{
+					"TRACEPARENT": "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-00",
+				},
+				TestTimeoutMs: 1000,
 			},
 			Expect: Results{
-				Config:    otelcli.DefaultConfig(),
-				CliOutput: "hello world\n",
-				SpanCount: 2,
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithRespectSampling(true).
+					WithTraceparentPrint(true).
+					WithForceSpanId("0000000000000002"),
+				Env: map[string]string{
+					"TRACEPARENT": "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-00",
+				},
+				CliOutput: "" +
+					"# trace id: f6c109f48195b451c4def6ab32f47b61\n" +
+					"#  span id: 0000000000000002\n" +
+					"TRACEPARENT=00-f6c109f48195b451c4def6ab32f47b61-0000000000000002-00\n",
+				SpanCount: 0,
 			},
 		},
 	},
-	// otel-cli exec echo "{{traceparent}}" and otel-cli exec --tp-disable-inject
+	// --recording overrides the endpoint-based default
 	{
 		{
-			Name: "otel-cli exec with arg injection injects the traceparent",
+			Name: "--recording=false with an endpoint",
 			Config: FixtureConfig{
-				CliArgs: []string{
-					"exec", "--endpoint", "{{endpoint}}",
-					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
-					"--",
-					"echo", "{{traceparent}}"},
+				CliArgs: []string{"status", "--endpoint", "{{endpoint}}"},
+				Env:     map[string]string{"OTEL_CLI_RECORDING": "false"},
 			},
 			Expect: Results{
-				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
-				CliOutput: "00-e39280f2980af3a8600ae98c74f2dabf-023eee2731392b4d-01\n",
-				SpanCount: 1,
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}").WithRecording("false"),
+				Env:    map[string]string{"OTEL_CLI_RECORDING": "false"},
+				Diagnostics: otelcli.Diagnostics{
+					IsRecording:     false,
+					NumArgs:         3,
+					ParsedTimeoutMs: 1000,
+				},
+				SpanCount: 0,
 			},
 		},
 		{
-			Name: "otel-cli exec --tp-disable-inject returns the {{traceparent}} tag unmodified",
+			Name: "--recording=require without an endpoint",
 			Config: FixtureConfig{
-				CliArgs: []string{
-					"exec", "--endpoint", "{{endpoint}}",
-					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
-					"--tp-disable-inject",
-					"--",
-					"echo", "{{traceparent}}"},
+				CliArgs: []string{"span", "--recording", "require"},
 			},
 			Expect: Results{
-				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
-				CliOutput: "{{traceparent}}\n",
-				SpanCount: 1,
+				ExitCode:    1,
+				CliOutputRe: regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `),
+				CliOutput:   "--recording=require but no endpoint is configured, set --endpoint or OTEL_EXPORTER_OTLP_ENDPOINT\n",
+				Config:      otelcli.DefaultConfig(),
 			},
 		},
+	},
+	// --attr-count-limit keeps attributes in key order and lists what it
+	// dropped, --attr-value-length-limit truncates long values
+	{
 		{
-			Name: "otel-cli exec returns the {{traceparent}} tag unmodified with OTEL_CLI_EXEC_TP_DISABLE_INJECT",
+			Name: "otel-cli span with attribute limits",
 			Config: FixtureConfig{
-				Env: map[string]string{
-					"OTEL_CLI_EXEC_TP_DISABLE_INJECT": "true",
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}",
+					"--attrs", "d=dee,a=aaaaaaaa,c=cee,b=bee",
+					"--attr-count-limit", "3", "--attr-value-length-limit", "4",
 				},
-				CliArgs: []string{
-					"exec", "--endpoint", "{{endpoint}}",
-					"--force-trace-id", "e39280f2980af3a8600ae98c74f2dabf", "--force-span-id", "023eee2731392b4d",
-					"--",
-					"echo", "{{traceparent}}"},
 			},
 			Expect: Results{
-				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
-				CliOutput: "{{traceparent}}\n",
+				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				SpanData: map[string]string{
+					"attributes": "a=aaaa,b=bee,otel-cli.dropped_attributes=c,d",
+				},
 				SpanCount: 1,
 			},
 		},
@@ -1096,6 +2195,34 @@ var suites = []FixtureSuite{
 				SpanCount: 1,
 			},
 		},
+		{
+			Name: "--protocol http/json",
+			Config: FixtureConfig{
+				ServerProtocol: httpProtocol,
+				CliArgs: []string{"span", "--endpoint", "http://{{endpoint}}", "--protocol", "http/json",
+					"--name", "as json", "--attrs", "abc=123",
+					"--force-trace-id", "0102030405060708090a0b0c0d0e0f10", "--force-span-id", "0101010101010101",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithEndpoint("http://{{endpoint}}").WithProtocol("http/json"),
+				ServerMeta: map[string]string{
+					"content-type": "application/json",
+					"host":         "{{endpoint}}",
+					"method":       "POST",
+					"proto":        "HTTP/1.1",
+					"uri":          "/v1/traces",
+				},
+				SpanData: map[string]string{
+					"trace_id":   "0102030405060708090a0b0c0d0e0f10",
+					"span_id":    "0101010101010101",
+					"name":       "as json",
+					"attributes": "abc=123,forced_ids=true",
+				},
+				SpanCount: 1,
+			},
+		},
 		{
 			Name: "protocol: bad config",
 			Config: FixtureConfig{
@@ -1224,7 +2351,12 @@ var suites = []FixtureSuite{
 				},
 			},
 			Expect: Results{
-				Config: otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithFail(true).
+					WithForceTraceId("00112233445566778899aabbccddeeff").
+					WithForceSpanId("beefcafefacedead").
+					WithForceParentSpanId("e4e3eeb33fc4f3d3"),
 				SpanData: map[string]string{
 					"trace_id":       "00112233445566778899aabbccddeeff",
 					"span_id":        "beefcafefacedead",
@@ -1261,11 +2393,13 @@ var suites = []FixtureSuite{
 					WithEndpoint("{{endpoint}}").
 					WithProtocol("grpc").
 					WithHeaders(map[string]string{
-						"x-otel-cli-otlpserver-token": "abcdefgabcdefg",
+						// status masks secrets, they're still sent as is
+						"x-otel-cli-otlpserver-token": "--- masked ---",
 					}),
 				Headers: map[string]string{
 					":authority":                  "{{endpoint}}\n",
 					"content-type":                "application/grpc\n",
+					"grpc-accept-encoding":        "\"gzip,zstd\"\n",
 					"user-agent":                  "*",
 					"x-otel-cli-otlpserver-token": "abcdefgabcdefg\n",
 				},
@@ -1296,7 +2430,8 @@ var suites = []FixtureSuite{
 					WithEndpoint("http://{{endpoint}}").
 					WithProtocol("http/protobuf").
 					WithHeaders(map[string]string{
-						"x-otel-cli-otlpserver-token": "abcdefgabcdefg",
+						// status masks secrets, they're still sent as is
+						"x-otel-cli-otlpserver-token": "--- masked ---",
 					}),
 				Headers: map[string]string{
 					"Content-Type":                "application/x-protobuf",
@@ -1314,6 +2449,117 @@ var suites = []FixtureSuite{
 				},
 			},
 		},
+		{
+			Name: "header values can come from envvars",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span",
+					"--endpoint", "{{endpoint}}",
+					"--otlp-headers", "x-otel-cli-otlpserver-token=env:OTLP_TOKEN",
+				},
+				Env:            map[string]string{"OTLP_TOKEN": "abcdefgabcdefg"},
+				ServerProtocol: grpcProtocol,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Headers: map[string]string{
+					":authority":                  "{{endpoint}}\n",
+					"content-type":                "application/grpc\n",
+					"grpc-accept-encoding":        "\"gzip,zstd\"\n",
+					"user-agent":                  "*",
+					"x-otel-cli-otlpserver-token": "abcdefgabcdefg\n",
+				},
+			},
+		},
+		{
+			Name: "status --show-secrets doesn't mask headers",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"status",
+					"--endpoint", "{{endpoint}}",
+					"--otlp-headers", "x-otel-cli-otlpserver-token=abcdefgabcdefg",
+					"--show-secrets",
+				},
+				ServerProtocol: grpcProtocol,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithShowSecrets(true).
+					WithHeaders(map[string]string{
+						"x-otel-cli-otlpserver-token": "abcdefgabcdefg",
+					}),
+				Diagnostics: otelcli.Diagnostics{
+					IsRecording:       true,
+					DetectedLocalhost: true,
+					NumArgs:           6,
+					ParsedTimeoutMs:   1000,
+					Endpoint:          "grpc://{{endpoint}}",
+					EndpointSource:    "general",
+				},
+			},
+		},
+	},
+	// --otlp-compression payloads are decompressed by the grpc/http servers
+	{
+		{
+			Name: "gRPC export with zstd compression",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span",
+					"--endpoint", "{{endpoint}}",
+					"--protocol", "grpc",
+					"--name", "squeezed",
+					"--otlp-compression", "zstd",
+				},
+				ServerProtocol: grpcProtocol,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Config:    otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+					"name":     "squeezed",
+				},
+				// grpc-go strips grpc-encoding before handlers see it
+				Headers: map[string]string{
+					":authority":           "{{endpoint}}\n",
+					"content-type":         "application/grpc\n",
+					"grpc-accept-encoding": "\"gzip,zstd\"\n",
+					"user-agent":           "*",
+				},
+			},
+		},
+		{
+			Name: "http export with gzip compression",
+			Config: FixtureConfig{
+				CliArgs: []string{
+					"span",
+					"--endpoint", "http://{{endpoint}}",
+					"--protocol", "http/protobuf",
+					"--name", "squeezed",
+					"--otlp-compression", "gzip",
+				},
+				ServerProtocol: httpProtocol,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Config:    otelcli.DefaultConfig(),
+				SpanData: map[string]string{
+					"span_id":  "*",
+					"trace_id": "*",
+					"name":     "squeezed",
+				},
+				Headers: map[string]string{
+					"Content-Type":     "application/x-protobuf",
+					"Content-Encoding": "gzip",
+					"Accept-Encoding":  "gzip",
+					"User-Agent":       "Go-http-client/1.1",
+				},
+			},
+		},
 	},
 	// exec signal and timeout behavior
 	{
@@ -1331,7 +2577,8 @@ var suites = []FixtureSuite{
 				ExitCode:  2,
 				SpanData: map[string]string{
 					"status_code":        "2",
-					"status_description": "exec command failed: signal: interrupt",
+					"status_description": "exec command killed by signal SIGINT (2)",
+					"attributes":         "/process.exit.signal=SIGINT,process.exit.signal_number=2/",
 				},
 			},
 		},
@@ -1341,9 +2588,32 @@ var suites = []FixtureSuite{
 				CliArgs: []string{"exec",
 					"--endpoint", "{{endpoint}}",
 					"--command-timeout", "20ms",
-					"sleep", "1",
+					"sleep", "5",
+				},
+				// the command timeout is what kills sleep, this only catches hangs
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				ExitCode:  2,
+				SpanData: map[string]string{
+					"status_code":        "2",
+					"status_description": "exec command timed out after 20ms, killed by signal SIGTERM (15)",
+					"attributes":         "/process.exit.signal=SIGTERM,process.exit.signal_number=15,.*timeout=true/",
+				},
+			},
+		},
+		{
+			Name: "exec --command-timeout kills processes that ignore SIGTERM after --kill-grace",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--command-timeout", "20ms",
+					"--kill-grace", "50ms",
+					"--", "sh", "-c", "trap '' TERM; while :; do :; done",
 				},
-				TestTimeoutMs: 50,
+				TestTimeoutMs: 500,
 			},
 			Expect: Results{
 				SpanCount: 1,
@@ -1351,7 +2621,30 @@ var suites = []FixtureSuite{
 				ExitCode:  2,
 				SpanData: map[string]string{
 					"status_code":        "2",
-					"status_description": "exec command failed: signal: killed",
+					"status_description": "exec command timed out after 20ms, killed by signal SIGKILL (9)",
+					"attributes":         "/process.exit.signal=SIGKILL,process.exit.signal_number=9,.*timeout=true/",
+				},
+			},
+		},
+		{
+			Name: "exec --kill-grace 0 kills processes right away",
+			Config: FixtureConfig{
+				CliArgs: []string{"exec",
+					"--endpoint", "{{endpoint}}",
+					"--command-timeout", "20ms",
+					"--kill-grace", "0",
+					"sleep", "5",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				SpanCount: 1,
+				Config:    otelcli.DefaultConfig().WithEndpoint("{{endpoint}}"),
+				ExitCode:  2,
+				SpanData: map[string]string{
+					"status_code":        "2",
+					"status_description": "exec command timed out after 20ms, killed by signal SIGKILL (9)",
+					"attributes":         "/process.exit.signal=SIGKILL,process.exit.signal_number=9,.*timeout=true/",
 				},
 			},
 		},
@@ -1373,4 +2666,78 @@ var suites = []FixtureSuite{
 			},
 		},
 	},
+	// otel-cli status --canary-max-failures reports on the canaries and sets the exit code
+	{
+		{
+			Name: "otel-cli status canary report",
+			Config: FixtureConfig{
+				ServerProtocol: grpcProtocol,
+				CliArgs: []string{"status",
+					"--endpoint", "{{endpoint}}",
+					"--canary-count", "3",
+					"--canary-max-failures", "10%",
+				},
+				TestTimeoutMs: 1000,
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().
+					WithEndpoint("{{endpoint}}").
+					WithStatusCanaryCount(3).
+					WithStatusCanaryMaxFailures("10%"),
+				Diagnostics: otelcli.Diagnostics{
+					IsRecording:       true,
+					NumArgs:           7,
+					DetectedLocalhost: true,
+					ParsedTimeoutMs:   1000,
+					Endpoint:          "*",
+					EndpointSource:    "*",
+				},
+				SpanCount: 3,
+			},
+			CheckFuncs: []CheckFunc{
+				func(t *testing.T, fixture Fixture, results Results) {
+					var status otelcli.StatusOutput
+					if err := json.Unmarshal([]byte(results.CliOutput), &status); err != nil {
+						t.Fatalf("[%s] unable to parse status output: %s", fixture.Name, err)
+					}
+					canary := status.Canary
+					if canary == nil || canary.Sent != 3 || canary.Succeeded != 3 || canary.Failed != 0 || len(canary.Attempts) != 3 {
+						t.Errorf("[%s] expected 3 successful canaries, got %+v", fixture.Name, canary)
+					} else if canary.P95Ms < canary.P50Ms || canary.P50Ms <= 0 {
+						t.Errorf("[%s] unexpected latency percentiles p50=%f p95=%f", fixture.Name, canary.P50Ms, canary.P95Ms)
+					}
+				},
+			},
+		},
+	},
+	// --kind and --status-code are case-insensitive and take common synonyms
+	{
+		{
+			Name: "--kind and --status-code are normalized",
+			Config: FixtureConfig{
+				CliArgs: []string{"status", "--kind", "SERVER", "--status-code", "Err"},
+			},
+			Expect: Results{
+				Config: otelcli.DefaultConfig().WithKind("server").WithStatusCode("error"),
+				Diagnostics: otelcli.Diagnostics{
+					IsRecording:     false,
+					NumArgs:         5,
+					ParsedTimeoutMs: 1000,
+					Normalized:      []string{`--kind "SERVER" to "server"`, `--status-code "Err" to "error"`},
+				},
+			},
+		},
+		{
+			Name: "unknown --kind fails with the valid kinds",
+			Config: FixtureConfig{
+				CliArgs: []string{"span", "--endpoint", "{{endpoint}}", "--kind", "sever", "--fail", "--verbose"},
+			},
+			Expect: Results{
+				ExitCode:    1,
+				CliOutputRe: regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `),
+				CliOutput:   "invalid --kind: unknown span kind \"sever\", expected one of client, server, producer, consumer, internal, unspecified\n",
+				Config:      otelcli.DefaultConfig(),
+			},
+		},
+	},
 }
@@ -14,6 +14,5 @@ var (
 )
 
 func main() {
-	otelcli.Execute(otelcli.FormatVersion(version, commit, date))
-	os.Exit(otelcli.GetExitCode())
+	os.Exit(otelcli.Execute(otelcli.FormatVersion(version, commit, date)))
 }
@@ -17,9 +17,11 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/equinix-labs/otel-cli/otelcli"
 	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/equinix-labs/otel-cli/otlpserver"
 	"github.com/google/go-cmp/cmp"
@@ -32,13 +34,22 @@ const minimumPath = `/bin:/usr/bin`
 const defaultTestTimeout = time.Second
 
 func TestMain(m *testing.M) {
-	// wipe out this process's envvars right away to avoid pollution & leakage
+	// wipe out this process's envvars right away to avoid pollution & leakage,
+	// leaving what fixtures run in-process get, same as the built binary
 	os.Clearenv()
+	os.Setenv("PATH", minimumPath)
+	devNull, err := os.Open(os.DevNull)
+	if err != nil {
+		log.Fatalf("failed to open %s for stdin: %s", os.DevNull, err)
+	}
+	os.Stdin = devNull
 	result := m.Run()
 	os.Exit(result)
 }
 
 // TestOtelCli iterates over all defined fixtures and executes the tests.
+// Fixtures run in-process with otelcli.Run unless they need the built binary,
+// see runsInProcess.
 func TestOtelCli(t *testing.T) {
 	_, err := os.Stat("./otel-cli")
 	if os.IsNotExist(err) {
@@ -473,8 +484,6 @@ func runOtelCli(t *testing.T, fixture Fixture) (string, Results) {
 		listener.Close()
 	}
 
-	// TODO: figure out the best way to build the binary and detect if the build is stale
-	// ^^ probably doesn't matter much in CI, can auto-build, but for local workflow it matters
 	// TODO: should all otel-cli commands be able to dump status? e.g. otel-cli span --status
 	args := []string{}
 	if len(fixture.Config.CliArgs) > 0 {
@@ -482,70 +491,27 @@ func runOtelCli(t *testing.T, fixture Fixture) (string, Results) {
 			args = append(args, injectVars(v, endpoint, fixture.TlsData))
 		}
 	}
-	statusCmd := exec.Command("./otel-cli", args...)
-	statusCmd.Env = mkEnviron(endpoint, fixture.Config.Env, fixture.TlsData)
 
-	// have command write output into string buffers
-	var cliOut bytes.Buffer
-	statusCmd.Stdout = &cliOut
-	statusCmd.Stderr = &cliOut
-
-	err = statusCmd.Start()
-	if err != nil {
-		t.Fatalf("[%s] error starting otel-cli: %s", fixture.Name, err)
-	}
-
-	stopKiller := make(chan struct{}, 1)
-	if fixture.Config.KillAfter != 0 {
-		go func() {
-			select {
-			case <-time.After(fixture.Config.KillAfter):
-				err := statusCmd.Process.Signal(fixture.Config.KillSignal)
-				if err != nil {
-					log.Fatalf("[%s] error sending signal %s to pid %d: %s", fixture.Name, fixture.Config.KillSignal, statusCmd.Process.Pid, err)
-				}
-			case <-stopKiller:
-				return
-			}
-		}()
+	var cliOut []byte
+	if runsInProcess(fixture) {
+		cliOut = runInProcess(t, fixture, args, serverTimeout, &results)
 	} else {
-		go func() {
-			select {
-			case <-time.After(serverTimeout):
-				t.Logf("[%s] timeout, killing process...", fixture.Name)
-				results.TimedOut = true
-				err = statusCmd.Process.Kill()
-				if err != nil {
-					// TODO: this might be a bit fragile, soften this up later if it ends up problematic
-					log.Fatalf("[%s] %d timeout process kill failed: %s", fixture.Name, serverTimeout, err)
-				}
-			case <-stopKiller:
-				return
-			}
-		}()
-	}
-
-	// grab stderr & stdout comingled so that if otel-cli prints anything to either it's not
-	// supposed to it will cause e.g. status json parsing and other tests to fail
-	t.Logf("[%s] going to exec 'env -i %s %s'", fixture.Name, strings.Join(statusCmd.Env, " "), strings.Join(statusCmd.Args, " "))
-	err = statusCmd.Wait()
-
-	results.CliOutput = cliOut.String()
-	results.ExitCode = statusCmd.ProcessState.ExitCode()
-	results.CommandFailed = !statusCmd.ProcessState.Exited()
-	if err != nil {
-		t.Logf("[%s] command exited: %s", fixture.Name, err)
+		cliOut = runBinary(t, fixture, endpoint, args, serverTimeout, &results)
 	}
 
-	// send stop signals to the timeouts and OTLP server
+	// send stop signals to the timeouts and let the OTLP server finish any
+	// exports that are still in flight before reading results
 	cancelServerTimeout <- struct{}{}
-	stopKiller <- struct{}{}
-	cs.Stop()
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), time.Second)
+	if stats, err := cs.Shutdown(drainCtx); err != nil {
+		t.Logf("[%s] OTLP server shutdown: %s", fixture.Name, stats)
+	}
+	cancelDrain()
 
 	// only try to parse status json if it was a status command
 	// TODO: support variations on otel-cli where status isn't the first arg
 	if len(args) > 0 && args[0] == "status" && results.ExitCode == 0 {
-		err = json.Unmarshal(cliOut.Bytes(), &results)
+		err = json.Unmarshal(cliOut, &results)
 		if err != nil {
 			t.Errorf("[%s] parsing otel-cli status output failed: %s", fixture.Name, err)
 			t.Logf("[%s] output received: %q", fixture.Name, cliOut)
@@ -592,6 +558,134 @@ gather:
 	return endpoint, results
 }
 
+// runsInProcess returns true when the fixture can run with otelcli.Run.
+// Fixtures that set envvars, send signals, run in the background, or need a
+// closed stdout run the built binary, since those would change the test
+// process. So do servers, which exit the process when they fail.
+func runsInProcess(fixture Fixture) bool {
+	config := fixture.Config
+	if len(config.Env) > 0 || config.KillAfter != 0 || config.ClosedStdout || config.Background {
+		return false
+	}
+	return len(config.CliArgs) == 0 || config.CliArgs[0] != "server"
+}
+
+// lockedBuffer is a bytes.Buffer that's safe for otel-cli to write its
+// stdout and stderr to from more than one goroutine.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (lb *lockedBuffer) Write(p []byte) (int, error) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return lb.buf.Write(p)
+}
+
+func (lb *lockedBuffer) Bytes() []byte {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	return append([]byte{}, lb.buf.Bytes()...)
+}
+
+// runInProcess runs otel-cli with otelcli.Run and returns its output. Run
+// can't be killed, so when it times out it's left running and the fixture
+// fails the same as a binary that had to be killed.
+func runInProcess(t *testing.T, fixture Fixture, args []string, timeout time.Duration, results *Results) []byte {
+	t.Logf("[%s] going to run otelcli.Run(%q)", fixture.Name, args)
+
+	var cliOut lockedBuffer
+	codes := make(chan int, 1)
+	go func() { codes <- otelcli.Run(args, &cliOut, &cliOut) }()
+
+	select {
+	case results.ExitCode = <-codes:
+	case <-time.After(timeout):
+		t.Logf("[%s] timeout, abandoning otelcli.Run...", fixture.Name)
+		results.TimedOut = true
+		results.CommandFailed = true
+		results.ExitCode = -1
+	}
+
+	out := cliOut.Bytes()
+	results.CliOutput = string(out)
+	return out
+}
+
+// runBinary runs the built ./otel-cli with the fixture's envvars and returns
+// its output, killing it if it's still running after timeout.
+func runBinary(t *testing.T, fixture Fixture, endpoint string, args []string, timeout time.Duration, results *Results) []byte {
+	// TODO: figure out the best way to build the binary and detect if the build is stale
+	// ^^ probably doesn't matter much in CI, can auto-build, but for local workflow it matters
+	statusCmd := exec.Command("./otel-cli", args...)
+	statusCmd.Env = mkEnviron(endpoint, fixture.Config.Env, fixture.TlsData)
+
+	// have command write output into string buffers
+	var cliOut bytes.Buffer
+	statusCmd.Stdout = &cliOut
+	statusCmd.Stderr = &cliOut
+	if fixture.Config.ClosedStdout {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatalf("[%s] failed to create pipe for stdout: %s", fixture.Name, err)
+		}
+		r.Close()
+		defer w.Close()
+		statusCmd.Stdout = w
+	}
+
+	err := statusCmd.Start()
+	if err != nil {
+		t.Fatalf("[%s] error starting otel-cli: %s", fixture.Name, err)
+	}
+
+	stopKiller := make(chan struct{}, 1)
+	if fixture.Config.KillAfter != 0 {
+		go func() {
+			select {
+			case <-time.After(fixture.Config.KillAfter):
+				err := statusCmd.Process.Signal(fixture.Config.KillSignal)
+				if err != nil {
+					log.Fatalf("[%s] error sending signal %s to pid %d: %s", fixture.Name, fixture.Config.KillSignal, statusCmd.Process.Pid, err)
+				}
+			case <-stopKiller:
+				return
+			}
+		}()
+	} else {
+		go func() {
+			select {
+			case <-time.After(timeout):
+				t.Logf("[%s] timeout, killing process...", fixture.Name)
+				results.TimedOut = true
+				err := statusCmd.Process.Kill()
+				if err != nil {
+					// TODO: this might be a bit fragile, soften this up later if it ends up problematic
+					log.Fatalf("[%s] %d timeout process kill failed: %s", fixture.Name, timeout, err)
+				}
+			case <-stopKiller:
+				return
+			}
+		}()
+	}
+
+	// grab stderr & stdout comingled so that if otel-cli prints anything to either it's not
+	// supposed to it will cause e.g. status json parsing and other tests to fail
+	t.Logf("[%s] going to exec 'env -i %s %s'", fixture.Name, strings.Join(statusCmd.Env, " "), strings.Join(statusCmd.Args, " "))
+	err = statusCmd.Wait()
+	stopKiller <- struct{}{}
+
+	results.CliOutput = cliOut.String()
+	results.ExitCode = statusCmd.ProcessState.ExitCode()
+	results.CommandFailed = !statusCmd.ProcessState.Exited()
+	if err != nil {
+		t.Logf("[%s] command exited: %s", fixture.Name, err)
+	}
+
+	return cliOut.Bytes()
+}
+
 // mkEnviron converts a string map to a list of k=v strings and tacks on PATH.
 func mkEnviron(endpoint string, env map[string]string, tlsData TlsSettings) []string {
 	mapped := make([]string, len(env)+1)
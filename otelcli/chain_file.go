@@ -0,0 +1,61 @@
+package otelcli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+// The chain file used by --chain-file has one line per span, appended as
+// each otel-cli invocation sends its span:
+//
+//	00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01 checkout
+//	00-3433d5ae39bdfee397f44be5146867b3-c2b1f6c3a3e5c9d1-01 build
+//
+// The traceparent comes first and the span name after it is only there for
+// people reading the file. Each new span is a child of the last entry, so a
+// script builds a sequential chain of spans without rewriting carrier files.
+
+// loadChainFile returns the traceparent of the last entry in the chain file.
+// A missing or empty file returns an uninitialized traceparent.
+func loadChainFile(file string, mode traceparent.ParseMode) (traceparent.Traceparent, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return traceparent.Traceparent{}, nil
+	} else if err != nil {
+		return traceparent.Traceparent{}, fmt.Errorf("failed to read chain file %q: %w", file, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" {
+		return traceparent.Traceparent{}, nil
+	}
+
+	tpString, _, _ := strings.Cut(last, " ")
+	tp, err := traceparent.ParseWithMode(tpString, mode)
+	if err != nil {
+		return traceparent.Traceparent{}, fmt.Errorf("chain file %q has an invalid last entry: %w", file, err)
+	}
+	return tp, nil
+}
+
+// appendChainFile adds an entry for the span to the chain file, creating it
+// if needed. Entries are written with a single append so invocations running
+// at the same time don't interleave lines.
+func appendChainFile(file string, tp traceparent.Traceparent, name string) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open chain file %q: %w", file, err)
+	}
+	defer f.Close()
+
+	// keep each entry on one line whatever the span is called
+	name = strings.Join(strings.Fields(name), " ")
+	if _, err := fmt.Fprintf(f, "%s %s\n", tp.Encode(), name); err != nil {
+		return fmt.Errorf("failed to write chain file %q: %w", file, err)
+	}
+	return nil
+}
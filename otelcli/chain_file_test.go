@@ -0,0 +1,58 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+func TestChainFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "chain")
+
+	tp, err := loadChainFile(file, traceparent.Lenient)
+	if err != nil || tp.Initialized {
+		t.Errorf("a missing chain file should be empty, got %v, %v", tp, err)
+	}
+
+	first, _ := traceparent.Parse("00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01")
+	second, _ := traceparent.Parse("00-3433d5ae39bdfee397f44be5146867b3-c2b1f6c3a3e5c9d1-01")
+	if err := appendChainFile(file, first, "check\nout"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := appendChainFile(file, second, "build"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, _ := os.ReadFile(file)
+	want := first.Encode() + " check out\n" + second.Encode() + " build\n"
+	if string(data) != want {
+		t.Errorf("expected chain file %q but got %q", want, string(data))
+	}
+
+	tp, err = loadChainFile(file, traceparent.Lenient)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tp.Encode() != second.Encode() {
+		t.Errorf("expected the last entry %s but got %s", second.Encode(), tp.Encode())
+	}
+
+	os.WriteFile(file, []byte("garbage\n"), 0600)
+	if _, err := loadChainFile(file, traceparent.Lenient); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+}
+
+func TestLoadTraceparentChainFile(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01")
+	file := filepath.Join(t.TempDir(), "chain")
+	os.WriteFile(file, []byte("00-11111111111111111111111111111111-2222222222222222-01 prev\n"), 0600)
+
+	config := DefaultConfig().WithChainFile(file)
+	tp := config.LoadTraceparent()
+	if tp.Encode() != "00-11111111111111111111111111111111-2222222222222222-01" {
+		t.Errorf("the chain file should override TRACEPARENT, got %s", tp.Encode())
+	}
+}
@@ -1,17 +1,18 @@
 package otelcli
 
 import (
-	"log"
-	"os"
-
 	"github.com/spf13/cobra"
 )
 
-func completionCmd(*Config) *cobra.Command {
+func completionCmd(config *Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "completion [bash|zsh|fish|powershell]",
 		Short: "Generate completion script",
-		Long: `To load completions:
+		Long: `Completes commands and flags, the values of flags like --kind,
+--status-code, and --protocol, JSON, YAML, and TOML files for --config, and for
+other flags, the value the --config file (or OTEL_CLI_CONFIG_FILE) already has.
+
+To load completions:
 
 Bash:
 
@@ -56,24 +57,24 @@ PowerShell:
 		Run: func(cmd *cobra.Command, args []string) {
 			switch args[0] {
 			case "bash":
-				err := cmd.Root().GenBashCompletion(os.Stdout)
+				err := cmd.Root().GenBashCompletion(config.getStdout())
 				if err != nil {
-					log.Fatalf("failed to write completion to stdout")
+					config.Fatal("failed to write completion to stdout: %s", err)
 				}
 			case "zsh":
-				err := cmd.Root().GenZshCompletion(os.Stdout)
+				err := cmd.Root().GenZshCompletion(config.getStdout())
 				if err != nil {
-					log.Fatalf("failed to write completion to stdout")
+					config.Fatal("failed to write completion to stdout: %s", err)
 				}
 			case "fish":
-				err := cmd.Root().GenFishCompletion(os.Stdout, true)
+				err := cmd.Root().GenFishCompletion(config.getStdout(), true)
 				if err != nil {
-					log.Fatalf("failed to write completion to stdout")
+					config.Fatal("failed to write completion to stdout: %s", err)
 				}
 			case "powershell":
-				err := cmd.Root().GenPowerShellCompletionWithDesc(os.Stdout)
+				err := cmd.Root().GenPowerShellCompletionWithDesc(config.getStdout())
 				if err != nil {
-					log.Fatalf("failed to write completion to stdout")
+					config.Fatal("failed to write completion to stdout: %s", err)
 				}
 			}
 		},
@@ -0,0 +1,161 @@
+package otelcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// completionFunc is the signature of cobra's flag completion functions.
+type completionFunc = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective)
+
+// flagValues are the values shell completion offers for flags that take one
+// of a fixed set.
+func flagValues() map[string][]string {
+	formats := []string{}
+	for _, f := range traceparent.Formats {
+		formats = append(formats, string(f))
+	}
+
+	return map[string][]string{
+		"kind":               {"internal", "server", "client", "producer", "consumer"},
+		"status-code":        {"unset", "ok", "error"},
+		"protocol":           {"grpc", "http/protobuf", otlpclient.HttpJsonProtocol},
+		"otlp-compression":   {"gzip", "zstd", "none"},
+		"recording":          recordingModes,
+		"sampler":            samplers,
+		"propagation-format": formats,
+		"config-format":      configFormats,
+		"from":               configFormats,
+		"to":                 configFormats,
+		"print-url":          printUrlNames(),
+	}
+}
+
+// registerCompletions adds shell completion for flag values to every command
+// under root: the valid values of enum-like flags, config files for --config,
+// and for the rest, the value the config file in --config or
+// OTEL_CLI_CONFIG_FILE already has for them, if any. Client commands'
+// --endpoint and --traces-endpoint also get the endpoint history.
+func registerCompletions(root *cobra.Command, config *Config) {
+	values := flagValues()
+	keys := configJsonKeys(config)
+
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			var fn completionFunc
+			if vals, ok := values[flag.Name]; ok {
+				fn = cobra.FixedCompletions(vals, cobra.ShellCompDirectiveNoFileComp)
+			} else if flag.Name == "config" {
+				fn = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+					return []string{"json", "yaml", "yml", "toml"}, cobra.ShellCompDirectiveFilterFileExt
+				}
+			} else if key, ok := keys[flagTarget(flag)]; ok {
+				fn = configValueCompletion(key)
+				// client commands also offer the endpoints they've used
+				isEndpoint := flag.Name == "endpoint" || flag.Name == "traces-endpoint"
+				if isEndpoint && cmd.Flags().Lookup("endpoint-history") != nil {
+					fn = endpointCompletion(fn, flag.Name)
+				}
+			}
+			if fn != nil {
+				cmd.RegisterFlagCompletionFunc(flag.Name, fn)
+			}
+		})
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	walk(root)
+}
+
+// configJsonKeys maps the address of each of the config's fields to its key
+// in JSON config files, so flags can be matched to their keys by the variable
+// they set.
+func configJsonKeys(config *Config) map[uintptr]string {
+	keys := map[uintptr]string{}
+	cv := reflect.ValueOf(config).Elem()
+	ct := cv.Type()
+	for i := 0; i < ct.NumField(); i++ {
+		key, _, _ := strings.Cut(ct.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		keys[cv.Field(i).Addr().Pointer()] = key
+	}
+	return keys
+}
+
+// flagTarget returns the address of the variable a flag sets. pflag's values
+// for strings, ints, bools, and the like are pointers to the variable itself,
+// while values for maps and slices, and featuresValue, are structs that hold
+// a pointer to it in their first field. Returns 0 for anything else.
+func flagTarget(flag *pflag.Flag) uintptr {
+	fv := reflect.ValueOf(flag.Value)
+	if fv.Kind() == reflect.Pointer && fv.Elem().Kind() != reflect.Struct {
+		return fv.Pointer()
+	}
+	if fv.Kind() == reflect.Pointer {
+		fv = fv.Elem()
+	}
+	if fv.Kind() == reflect.Struct && fv.NumField() > 0 && fv.Field(0).Kind() == reflect.Pointer {
+		return fv.Field(0).Pointer()
+	}
+	return 0
+}
+
+// configValueCompletion completes a flag with the value under key in the
+// config file given with --config or OTEL_CLI_CONFIG_FILE, in the format from
+// --config-format or its extension, falling back to the shell's default
+// completion when there isn't one.
+func configValueCompletion(key string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		path := os.Getenv("OTEL_CLI_CONFIG_FILE")
+		if flag := cmd.Flags().Lookup("config"); flag != nil && flag.Changed {
+			path = flag.Value.String()
+		}
+		if path == "" {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		format := ""
+		if flag := cmd.Flags().Lookup("config-format"); flag != nil && flag.Changed {
+			format = flag.Value.String()
+		}
+		format, err := configFileFormat(path, format)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		js, err := configToJson(data, format)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		values := map[string]interface{}{}
+		if err := json.Unmarshal(js, &values); err != nil {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+
+		switch v := values[key].(type) {
+		case string:
+			if v != "" {
+				return []string{v}, cobra.ShellCompDirectiveNoFileComp
+			}
+		case float64, bool:
+			return []string{fmt.Sprint(v)}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+}
@@ -0,0 +1,50 @@
+package otelcli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompletions(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(cfg, []byte(`{"endpoint": "https://api.example.com", "timeout": "5s", "verbose": true}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlCfg := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(yamlCfg, []byte("endpoint: https://yaml.example.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want []string
+	}{
+		{args: []string{"span", "--kind", ""}, want: []string{"internal", "server", "client", "producer", "consumer"}},
+		{args: []string{"span", "end", "--status-code", ""}, want: []string{"unset", "ok", "error"}},
+		{args: []string{"exec", "--protocol", ""}, want: []string{"grpc", "http/protobuf", "http/json"}},
+		{args: []string{"span", "--config", ""}, want: []string{"json", "yaml", "toml", ":8"}},
+		{args: []string{"span", "--config-format", ""}, want: []string{"json", "yaml", "toml"}},
+		{args: []string{"span", "--config", yamlCfg, "--endpoint", ""}, want: []string{"https://yaml.example.com"}},
+		{args: []string{"span", "--config", cfg, "--endpoint", ""}, want: []string{"https://api.example.com", ":4"}},
+		{args: []string{"span", "--config", cfg, "--timeout", ""}, want: []string{"5s"}},
+		// nothing in the config, so the shell completes files like before
+		{args: []string{"span", "--config", cfg, "--name", ""}, want: []string{":0"}},
+	} {
+		var stdout, stderr bytes.Buffer
+		Run(append([]string{"__complete"}, tc.args...), &stdout, &stderr)
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		for _, want := range tc.want {
+			found := false
+			for _, line := range lines {
+				found = found || line == want
+			}
+			if !found {
+				t.Errorf("%q: expected %q in completions, got %q", tc.args, want, lines)
+			}
+		}
+	}
+}
@@ -1,86 +1,228 @@
 package otelcli
 
 import (
+	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
 )
 
 var detectBrokenRFC3339PrefixRe *regexp.Regexp
 var epochNanoTimeRE *regexp.Regexp
+var spanHandleRe *regexp.Regexp
 
 func init() {
 	detectBrokenRFC3339PrefixRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2} `)
 	epochNanoTimeRE = regexp.MustCompile(`^\d+\.\d+$`)
+	spanHandleRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 }
 
 // DefaultConfig returns a Config with all defaults set.
 func DefaultConfig() Config {
 	return Config{
 		Endpoint:                     "",
+		LogsEndpoint:                 "",
+		MetricsEndpoint:              "",
+		EndpointTemplate:             "",
+		Region:                       "",
+		Vendor:                       "",
 		Protocol:                     "",
 		Timeout:                      "1s",
 		Headers:                      map[string]string{},
+		HeadersFile:                  "",
+		Routes:                       []otlpclient.Route{},
+		Fallback:                     "",
+		QueueDir:                     "",
+		DryRun:                       false,
+		Recording:                    "auto",
+		Sampler:                      "",
+		SamplerArg:                   "",
+		RespectSampling:              false,
+		HealthFile:                   "",
+		EndpointHistory:              false,
 		Insecure:                     false,
 		Blocking:                     false,
 		TlsNoVerify:                  false,
 		TlsCACert:                    "",
 		TlsClientKey:                 "",
 		TlsClientCert:                "",
+		SigningKeyFile:               "",
+		WireDebugFile:                "",
+		Proxy:                        "",
+		Compression:                  "",
+		OtlpRetries:                  -1,
+		OtlpRetrySleep:               "100ms",
+		OtlpRetryTimeout:             "",
 		ServiceName:                  "otel-cli",
+		ResourceAttributes:           map[string]string{},
+		ResourceDetectors:            "",
+		LogBody:                      "",
+		LogSeverity:                  "info",
 		SpanName:                     "todo-generate-default-span-names",
 		Kind:                         "client",
 		ForceTraceId:                 "",
 		ForceSpanId:                  "",
 		ForceParentSpanId:            "",
+		ForceIdCache:                 "",
+		SpanJsonOut:                  "",
+		Tracestate:                   "",
 		Attributes:                   map[string]string{},
+		AttributesBytes:              map[string]string{},
+		AttributesFile:               "",
+		AttributesStr:                map[string]string{},
+		AttributesInt:                map[string]string{},
+		AttributesFloat:              map[string]string{},
+		AttributesBool:               map[string]string{},
+		AttrCountLimit:               0,
+		AttrValueLengthLimit:         0,
+		Links:                        []string{},
 		TraceparentCarrierFile:       "",
+		ChainFile:                    "",
 		TraceparentIgnoreEnv:         false,
 		TraceparentPrint:             false,
 		TraceparentPrintExport:       false,
+		TraceparentPrintFd:           0,
+		TraceparentPrintFile:         "",
+		PrintJson:                    false,
+		PrintJsonFd:                  0,
+		PrintUrl:                     "",
+		Traceparent:                  "",
+		TraceparentStdin:             false,
+		TraceparentHttpStdin:         false,
+		TraceparentStdout:            false,
 		TraceparentRequired:          false,
+		TraceparentStrict:            false,
+		PropagationFormat:            "w3c",
 		BackgroundParentPollMs:       10,
 		BackgroundSockdir:            "",
 		BackgroundWait:               false,
 		BackgroundSkipParentPidCheck: false,
+		BackgroundUnder:              "background",
+		BackgroundChildSpanId:        "",
+		BackgroundSpanHandle:         "",
+		BackgroundJournal:            "",
+		BackgroundResume:             false,
+		SpanSendFile:                 "",
+		SpanStackFile:                "",
+		AlsoLog:                      false,
+		AlsoLogAttrs:                 "",
+		ServerDedupeWindow:           "",
+		ServerBufferSpans:            10000,
+		ServerBufferSize:             "64MB",
+		ServerMetricsAddr:            "",
+		ServerCorsOrigins:            "",
+		ServerTlsCert:                "",
+		ServerTlsKey:                 "",
+		ServerTlsCA:                  "",
+		ServerFilterServices:         []string{},
+		ServerFilterSpanName:         "",
+		ServerFilterAttrs:            map[string]string{},
 		ExecCommandTimeout:           "",
+		ExecKillGrace:                "10s",
+		ExecForwardSignals:           defaultForwardSignals,
 		ExecTpDisableInject:          false,
+		ExecPty:                      false,
+		ExecLoginShell:               false,
+		ExecShell:                    false,
+		ExecInjectors:                []string{},
+		ExecDockerEnv:                false,
+		ExecInjectOtelEnv:            false,
+		ExecFlagTemplates:            []string{},
+		ExecPerLineEvents:            false,
+		ExecMaxEvents:                0,
+		ExecEventMatch:               "",
+		ExecSampleResources:          "",
+		ExecHeartbeat:                "",
+		ExecSpansFromOutput:          false,
+		ExecProvenance:               false,
+		ExecRusage:                   false,
+		ExecStatusFromExitCode:       false,
+		ExecStatusMap:                "",
 		StatusCanaryCount:            1,
 		StatusCanaryInterval:         "",
+		StatusProbeMaxPayload:        false,
+		StatusCanaryMaxFailures:      "",
 		SpanStartTime:                "now",
 		SpanEndTime:                  "now",
+		SpanStartFromFile:            "",
+		SpanEndFromFile:              "",
 		EventName:                    "todo-generate-default-event-names",
 		EventTime:                    "now",
 		CfgFile:                      "",
+		CfgFormat:                    "",
+		ShowSecrets:                  false,
 		Verbose:                      false,
 		Fail:                         false,
+		FakeNow:                      "",
+		Features:                     map[string]bool{},
 		StatusCode:                   "unset",
 		StatusDescription:            "",
+		WarnIfLongerThan:             "",
+		ErrorIfLongerThan:            "",
 		Version:                      "unset",
 	}
 }
 
+//go:generate go run ./internal/configgen -in config.go -out config_generated.go
+
 // Config stores the runtime configuration for otel-cli.
 // Data structure is public so that it can serialize to json easily.
+// With* methods and ToStringMap are generated from this struct, see
+// internal/configgen. Run go generate after adding a field.
 type Config struct {
-	Endpoint       string            `json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
-	TracesEndpoint string            `json:"traces_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
-	Protocol       string            `json:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL,OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"`
-	Timeout        string            `json:"timeout" env:"OTEL_EXPORTER_OTLP_TIMEOUT,OTEL_EXPORTER_OTLP_TRACES_TIMEOUT"`
-	Headers        map[string]string `json:"otlp_headers" env:"OTEL_EXPORTER_OTLP_HEADERS"` // TODO: needs json marshaler hook to mask tokens
-	Insecure       bool              `json:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
-	Blocking       bool              `json:"otlp_blocking" env:"OTEL_EXPORTER_OTLP_BLOCKING"`
+	Endpoint         string            `json:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	TracesEndpoint   string            `json:"traces_endpoint" env:"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"`
+	LogsEndpoint     string            `json:"logs_endpoint" env:"OTEL_EXPORTER_OTLP_LOGS_ENDPOINT"`
+	MetricsEndpoint  string            `json:"metrics_endpoint" env:"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"`
+	EndpointTemplate string            `json:"endpoint_template" env:"OTEL_CLI_ENDPOINT_TEMPLATE"`
+	Region           string            `json:"region" env:"OTEL_CLI_REGION"`
+	Vendor           string            `json:"vendor" env:"OTEL_CLI_VENDOR"`
+	Protocol         string            `json:"protocol" env:"OTEL_EXPORTER_OTLP_PROTOCOL,OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"`
+	Timeout          string            `json:"timeout" env:"OTEL_EXPORTER_OTLP_TIMEOUT,OTEL_EXPORTER_OTLP_TRACES_TIMEOUT"`
+	Headers          map[string]string `json:"otlp_headers" env:"OTEL_EXPORTER_OTLP_HEADERS"` // masked in output, see MarshalJSON
+	// key=value lines of headers, so tokens don't have to be in argv
+	HeadersFile string `json:"otlp_headers_file" env:"OTEL_EXPORTER_OTLP_HEADERS_FILE"`
+	Insecure    bool   `json:"insecure" env:"OTEL_EXPORTER_OTLP_INSECURE"`
+	Blocking    bool   `json:"otlp_blocking" env:"OTEL_EXPORTER_OTLP_BLOCKING"`
+	Compression string `json:"otlp_compression" env:"OTEL_EXPORTER_OTLP_COMPRESSION,OTEL_EXPORTER_OTLP_TRACES_COMPRESSION"`
+
+	OtlpRetries      int    `json:"otlp_retries" env:"OTEL_CLI_OTLP_RETRIES"`
+	OtlpRetrySleep   string `json:"otlp_retry_sleep" env:"OTEL_CLI_OTLP_RETRY_SLEEP"`
+	OtlpRetryTimeout string `json:"otlp_retry_timeout" env:"OTEL_CLI_OTLP_RETRY_TIMEOUT"`
+
+	// config file only, sends matching spans to other endpoints
+	Routes   []otlpclient.Route `json:"routes"`
+	Fallback string             `json:"fallback" env:"OTEL_CLI_FALLBACK"`
+	QueueDir string             `json:"queue_dir" env:"OTEL_CLI_QUEUE_DIR"`
+	DryRun   bool               `json:"dry_run" env:"OTEL_CLI_DRY_RUN"`
+	// auto records when there's an endpoint, false never does, require fails without one
+	Recording string `json:"recording" env:"OTEL_CLI_RECORDING"`
+	// which traces are sent, see sampler.go
+	Sampler    string `json:"traces_sampler" env:"OTEL_TRACES_SAMPLER"`
+	SamplerArg string `json:"traces_sampler_arg" env:"OTEL_TRACES_SAMPLER_ARG"`
+	// an unsampled parent suppresses the export whatever the sampler says
+	RespectSampling bool `json:"respect_sampling" env:"OTEL_CLI_RESPECT_SAMPLING"`
+	// shared by otel-cli processes on a host to back off together
+	HealthFile string `json:"health_file" env:"OTEL_CLI_HEALTH_FILE"`
+	// opt-in history of endpoints for shell completion, see endpoint_history.go
+	EndpointHistory bool `json:"endpoint_history" env:"OTEL_CLI_ENDPOINT_HISTORY"`
 
 	TlsCACert     string `json:"tls_ca_cert" env:"OTEL_EXPORTER_OTLP_CERTIFICATE,OTEL_EXPORTER_OTLP_TRACES_CERTIFICATE"`
 	TlsClientKey  string `json:"tls_client_key" env:"OTEL_EXPORTER_OTLP_CLIENT_KEY,OTEL_EXPORTER_OTLP_TRACES_CLIENT_KEY"`
@@ -88,65 +230,230 @@ type Config struct {
 	// OTEL_CLI_NO_TLS_VERIFY is deprecated and will be removed for 1.0
 	TlsNoVerify bool `json:"tls_no_verify" env:"OTEL_CLI_TLS_NO_VERIFY,OTEL_CLI_NO_TLS_VERIFY"`
 
-	ServiceName       string            `json:"service_name" env:"OTEL_CLI_SERVICE_NAME,OTEL_SERVICE_NAME"`
-	SpanName          string            `json:"span_name" env:"OTEL_CLI_SPAN_NAME"`
-	Kind              string            `json:"span_kind" env:"OTEL_CLI_TRACE_KIND"`
-	Attributes        map[string]string `json:"span_attributes" env:"OTEL_CLI_ATTRIBUTES"`
-	StatusCode        string            `json:"span_status_code" env:"OTEL_CLI_STATUS_CODE"`
-	StatusDescription string            `json:"span_status_description" env:"OTEL_CLI_STATUS_DESCRIPTION"`
-	ForceSpanId       string            `json:"force_span_id" env:"OTEL_CLI_FORCE_SPAN_ID"`
-	ForceParentSpanId string            `json:"force_parent_span_id" env:"OTEL_CLI_FORCE_PARENT_SPAN_ID"`
-	ForceTraceId      string            `json:"force_trace_id" env:"OTEL_CLI_FORCE_TRACE_ID"`
+	SigningKeyFile string `json:"signing_key_file" env:"OTEL_CLI_SIGNING_KEY_FILE"`
+	WireDebugFile  string `json:"wire_debug_file" env:"OTEL_CLI_WIRE_DEBUG_FILE"`
+	// when empty, HTTPS_PROXY and friends are used like usual
+	Proxy string `json:"proxy" env:"OTEL_CLI_PROXY"`
+
+	// added to the resource last, so they override OTEL_RESOURCE_ATTRIBUTES
+	ResourceAttributes map[string]string `json:"resource_attributes" env:"OTEL_CLI_RESOURCE_ATTRIBUTES"`
+	// a comma-separated list of otlpclient.ResourceDetectors, or none
+	ResourceDetectors string `json:"resource_detectors" env:"OTEL_CLI_RESOURCE_DETECTORS"`
+
+	ServiceName     string            `json:"service_name" env:"OTEL_CLI_SERVICE_NAME,OTEL_SERVICE_NAME"`
+	SpanName        string            `json:"span_name" env:"OTEL_CLI_SPAN_NAME"`
+	LogBody         string            `json:"log_body"`
+	LogSeverity     string            `json:"log_severity" env:"OTEL_CLI_LOG_SEVERITY"`
+	Kind            string            `json:"span_kind" env:"OTEL_CLI_TRACE_KIND"`
+	Attributes      map[string]string `json:"span_attributes" env:"OTEL_CLI_ATTRIBUTES"`
+	AttributesBytes map[string]string `json:"span_attributes_bytes" env:"OTEL_CLI_ATTRIBUTES_BYTES"`
+	// attributes with the types given instead of guessed ones
+	AttributesFile  string            `json:"span_attributes_file" env:"OTEL_CLI_ATTRIBUTES_FILE"`
+	AttributesStr   map[string]string `json:"span_attributes_str" env:"OTEL_CLI_ATTRIBUTES_STR"`
+	AttributesInt   map[string]string `json:"span_attributes_int" env:"OTEL_CLI_ATTRIBUTES_INT"`
+	AttributesFloat map[string]string `json:"span_attributes_float" env:"OTEL_CLI_ATTRIBUTES_FLOAT"`
+	AttributesBool  map[string]string `json:"span_attributes_bool" env:"OTEL_CLI_ATTRIBUTES_BOOL"`
+	// the span-specific envvars come last so they win, like the spec says
+	AttrCountLimit       int      `json:"attribute_count_limit" env:"OTEL_ATTRIBUTE_COUNT_LIMIT,OTEL_SPAN_ATTRIBUTE_COUNT_LIMIT"`
+	AttrValueLengthLimit int      `json:"attribute_value_length_limit" env:"OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT,OTEL_SPAN_ATTRIBUTE_VALUE_LENGTH_LIMIT"`
+	Links                []string `json:"span_links"`
+	StatusCode           string   `json:"span_status_code" env:"OTEL_CLI_STATUS_CODE"`
+	StatusDescription    string   `json:"span_status_description" env:"OTEL_CLI_STATUS_DESCRIPTION"`
+	WarnIfLongerThan     string   `json:"warn_if_longer_than" env:"OTEL_CLI_WARN_IF_LONGER_THAN"`
+	ErrorIfLongerThan    string   `json:"error_if_longer_than" env:"OTEL_CLI_ERROR_IF_LONGER_THAN"`
+	ForceSpanId          string   `json:"force_span_id" env:"OTEL_CLI_FORCE_SPAN_ID"`
+	ForceParentSpanId    string   `json:"force_parent_span_id" env:"OTEL_CLI_FORCE_PARENT_SPAN_ID"`
+	ForceTraceId         string   `json:"force_trace_id" env:"OTEL_CLI_FORCE_TRACE_ID"`
+	ForceIdCache         string   `json:"force_id_cache" env:"OTEL_CLI_FORCE_ID_CACHE"`
+	SpanJsonOut          string   `json:"span_json_out" env:"OTEL_CLI_SPAN_JSON_OUT"`
+	Tracestate           string   `json:"tracestate" env:""`
 
 	TraceparentCarrierFile string `json:"traceparent_carrier_file" env:"OTEL_CLI_CARRIER_FILE"`
+	ChainFile              string `json:"chain_file" env:"OTEL_CLI_CHAIN_FILE"`
 	TraceparentIgnoreEnv   bool   `json:"traceparent_ignore_env" env:"OTEL_CLI_IGNORE_ENV"`
 	TraceparentPrint       bool   `json:"traceparent_print" env:"OTEL_CLI_PRINT_TRACEPARENT"`
 	TraceparentPrintExport bool   `json:"traceparent_print_export" env:"OTEL_CLI_EXPORT_TRACEPARENT"`
+	TraceparentPrintFd     int    `json:"traceparent_print_fd" env:"OTEL_CLI_PRINT_TRACEPARENT_FD"`
+	TraceparentPrintFile   string `json:"traceparent_print_file" env:"OTEL_CLI_PRINT_TRACEPARENT_FILE"`
+	PrintJson              bool   `json:"print_json" env:"OTEL_CLI_PRINT_JSON"`
+	PrintJsonFd            int    `json:"print_json_fd" env:"OTEL_CLI_PRINT_JSON_FD"`
+	PrintUrl               string `json:"print_url" env:"OTEL_CLI_PRINT_URL"`
+	Traceparent            string `json:"traceparent" env:""`
+	TraceparentStdin       bool   `json:"traceparent_stdin" env:""`
+	TraceparentHttpStdin   bool   `json:"traceparent_http_stdin" env:""`
+	TraceparentStdout      bool   `json:"traceparent_stdout" env:""`
 	TraceparentRequired    bool   `json:"traceparent_required" env:"OTEL_CLI_TRACEPARENT_REQUIRED"`
+	TraceparentStrict      bool   `json:"traceparent_strict" env:"OTEL_CLI_TRACEPARENT_STRICT"`
+	PropagationFormat      string `json:"propagation_format" env:"OTEL_CLI_PROPAGATION_FORMAT"`
 
 	BackgroundParentPollMs       int    `json:"background_parent_poll_ms" env:""`
 	BackgroundSockdir            string `json:"background_socket_directory" env:""`
 	BackgroundWait               bool   `json:"background_wait" env:""`
 	BackgroundSkipParentPidCheck bool   `json:"background_skip_parent_pid_check"`
-
-	ExecCommandTimeout  string `json:"exec_command_timeout" env:"OTEL_CLI_EXEC_CMD_TIMEOUT"`
-	ExecTpDisableInject bool   `json:"exec_tp_disable_inject" env:"OTEL_CLI_EXEC_TP_DISABLE_INJECT"`
-
-	StatusCanaryCount    int    `json:"status_canary_count"`
-	StatusCanaryInterval string `json:"status_canary_interval"`
+	BackgroundUnder              string `json:"background_under" env:""`
+	BackgroundChildSpanId        string `json:"background_child_span_id" env:""`
+	BackgroundSpanHandle         string `json:"background_span_handle" env:""`
+	BackgroundJournal            string `json:"background_journal" env:"OTEL_CLI_BACKGROUND_JOURNAL"`
+	BackgroundResume             bool   `json:"background_resume" env:""`
+
+	SpanSendFile string `json:"span_send_file" env:""`
+
+	SpanStackFile string `json:"span_stack_file" env:"OTEL_CLI_SPAN_STACK_FILE"`
+
+	AlsoLog      bool   `json:"also_log" env:"OTEL_CLI_ALSO_LOG"`
+	AlsoLogAttrs string `json:"also_log_attrs" env:"OTEL_CLI_ALSO_LOG_ATTRS"`
+
+	ServerDedupeWindow string `json:"server_dedupe_window" env:"OTEL_CLI_SERVER_DEDUPE_WINDOW"`
+	ServerBufferSpans  int    `json:"server_buffer_spans" env:"OTEL_CLI_SERVER_BUFFER_SPANS"`
+	ServerBufferSize   string `json:"server_buffer_size" env:"OTEL_CLI_SERVER_BUFFER_SIZE"`
+	ServerMetricsAddr  string `json:"server_metrics_addr" env:"OTEL_CLI_SERVER_METRICS_ADDR"`
+	ServerCorsOrigins  string `json:"server_cors_origins" env:"OTEL_CLI_SERVER_CORS_ORIGINS"`
+	ServerTlsCert      string `json:"server_tls_cert" env:"OTEL_CLI_SERVER_TLS_CERT"`
+	ServerTlsKey       string `json:"server_tls_key" env:"OTEL_CLI_SERVER_TLS_KEY"`
+	ServerTlsCA        string `json:"server_tls_ca" env:"OTEL_CLI_SERVER_TLS_CA"`
+
+	ServerFilterServices []string          `json:"server_filter_services"`
+	ServerFilterSpanName string            `json:"server_filter_span_name" env:"OTEL_CLI_SERVER_FILTER_SPAN_NAME"`
+	ServerFilterAttrs    map[string]string `json:"server_filter_attrs" env:"OTEL_CLI_SERVER_FILTER_ATTRS"`
+
+	ExecCommandTimeout     string `json:"exec_command_timeout" env:"OTEL_CLI_EXEC_CMD_TIMEOUT"`
+	ExecKillGrace          string `json:"exec_kill_grace" env:"OTEL_CLI_EXEC_KILL_GRACE"`
+	ExecForwardSignals     string `json:"exec_forward_signals" env:"OTEL_CLI_EXEC_FORWARD_SIGNALS"`
+	ExecTpDisableInject    bool   `json:"exec_tp_disable_inject" env:"OTEL_CLI_EXEC_TP_DISABLE_INJECT"`
+	ExecPty                bool   `json:"exec_pty" env:"OTEL_CLI_EXEC_PTY"`
+	ExecLoginShell         bool   `json:"exec_login_shell" env:"OTEL_CLI_EXEC_LOGIN_SHELL"`
+	ExecShell              bool   `json:"exec_shell" env:"OTEL_CLI_EXEC_SHELL"`
+	ExecPerLineEvents      bool   `json:"exec_per_line_events" env:"OTEL_CLI_EXEC_PER_LINE_EVENTS"`
+	ExecMaxEvents          int    `json:"exec_max_events" env:"OTEL_CLI_EXEC_MAX_EVENTS"`
+	ExecEventMatch         string `json:"exec_event_match" env:"OTEL_CLI_EXEC_EVENT_MATCH"`
+	ExecSampleResources    string `json:"exec_sample_resources" env:"OTEL_CLI_EXEC_SAMPLE_RESOURCES"`
+	ExecHeartbeat          string `json:"exec_heartbeat" env:"OTEL_CLI_EXEC_HEARTBEAT"`
+	ExecSpansFromOutput    bool   `json:"exec_spans_from_output" env:"OTEL_CLI_EXEC_SPANS_FROM_OUTPUT"`
+	ExecProvenance         bool   `json:"exec_provenance" env:"OTEL_CLI_EXEC_PROVENANCE"`
+	ExecRusage             bool   `json:"exec_rusage" env:"OTEL_CLI_EXEC_RUSAGE"`
+	ExecStatusFromExitCode bool   `json:"exec_status_from_exit_code" env:"OTEL_CLI_EXEC_STATUS_FROM_EXIT_CODE"`
+	ExecStatusMap          string `json:"exec_status_map" env:"OTEL_CLI_EXEC_STATUS_MAP"`
+
+	// --inject can be repeated, so like links it's only set by flag or config file
+	ExecInjectors []string `json:"exec_injectors"`
+
+	ExecDockerEnv     bool `json:"exec_docker_env" env:"OTEL_CLI_EXEC_DOCKER_ENV"`
+	ExecInjectOtelEnv bool `json:"exec_inject_otel_env" env:"OTEL_CLI_EXEC_INJECT_OTEL_ENV"`
+	// --inject-flag-template can be repeated too
+	ExecFlagTemplates []string `json:"exec_flag_templates"`
+
+	StatusCanaryCount     int    `json:"status_canary_count"`
+	StatusCanaryInterval  string `json:"status_canary_interval"`
+	StatusProbeMaxPayload bool   `json:"status_probe_max_payload"`
+	// a ratio or percentage, empty for no threshold
+	StatusCanaryMaxFailures string `json:"status_canary_max_failures"`
 
 	SpanStartTime string `json:"span_start_time" env:""`
 	SpanEndTime   string `json:"span_end_time" env:""`
-	EventName     string `json:"event_name" env:""`
-	EventTime     string `json:"event_time" env:""`
+	// take the start/end time from a file's modification time instead
+	SpanStartFromFile string `json:"span_start_from_file" env:""`
+	SpanEndFromFile   string `json:"span_end_from_file" env:""`
+	EventName         string `json:"event_name" env:""`
+	EventTime         string `json:"event_time" env:""`
 
 	CfgFile string `json:"config_file" env:"OTEL_CLI_CONFIG_FILE"`
-	Verbose bool   `json:"verbose" env:"OTEL_CLI_VERBOSE"`
-	Fail    bool   `json:"fail" env:"OTEL_CLI_FAIL"`
+	// json, yaml, or toml, by default from the config file's extension
+	CfgFormat string `json:"config_format" env:"OTEL_CLI_CONFIG_FORMAT"`
+	Verbose   bool   `json:"verbose" env:"OTEL_CLI_VERBOSE"`
+	Fail      bool   `json:"fail" env:"OTEL_CLI_FAIL"`
+	// turns off masking secrets in status and config show output
+	ShowSecrets bool `json:"show_secrets" env:"OTEL_CLI_SHOW_SECRETS"`
+	// pins the clock for reproducible output, mostly for tests
+	FakeNow string `json:"fake_now" env:"OTEL_CLI_FAKE_CLOCK"`
+
+	// experimental behaviors turned on or off by name, see features.go
+	Features map[string]bool `json:"features" env:"OTEL_CLI_FEATURES"`
 
 	// not exported, used to get data from cobra to otlpclient internals
 	Version string `json:"-"`
+
+	// this invocation's diagnostics, shared by copies of the config
+	diag *diagnostics
+	// what --tp-stdin and --tp-http-stdin read, see stdinCache
+	stdin *stdinCache
+	// when otel-cli started, where every command's --timeout budget begins
+	startupTime time.Time
+	// set by Run so otel-cli can be embedded, see getStdout and exit
+	stdout   io.Writer
+	stderr   io.Writer
+	exitFunc func(int)
 }
 
 // LoadFile reads the file specified by -c/--config and overwrites the
-// current config values with any found in the file.
+// current config values with any found in the file. YAML and TOML files are
+// converted to JSON first, see configFileFormat for how the format is picked.
 func (c *Config) LoadFile() error {
 	if c.CfgFile == "" {
 		return nil
 	}
 
-	js, err := os.ReadFile(c.CfgFile)
+	format, err := configFileFormat(c.CfgFile, c.CfgFormat)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(c.CfgFile)
 	if err != nil {
 		return fmt.Errorf("failed to read file '%s': %w", c.CfgFile, err)
 	}
 
+	js, err := configToJson(data, format)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s data in file '%s': %w", format, c.CfgFile, err)
+	}
+
 	if err := json.Unmarshal(js, c); err != nil {
-		return fmt.Errorf("failed to parse json data in file '%s': %w", c.CfgFile, err)
+		return fmt.Errorf("failed to parse %s data in file '%s': %w", format, c.CfgFile, err)
+	}
+
+	// unknown keys are most likely typos, which would otherwise be ignored
+	// without a trace, so warn about them instead of failing
+	unknown, err := unknownConfigKeys(js)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s data in file '%s': %w", format, c.CfgFile, err)
+	}
+	for _, key := range unknown {
+		c.SoftLog("ignoring unknown key %q in config file '%s'", key, c.CfgFile)
+	}
+	c.diag.update(func(d *Diagnostics) {
+		d.ConfigFileLoaded = true
+		d.UnknownConfigKeys = unknown
+	})
+
+	if err := validateRoutes(c.Routes); err != nil {
+		return fmt.Errorf("invalid routes in file '%s': %w", c.CfgFile, err)
 	}
 
 	return nil
 }
 
+// unknownConfigKeys returns the top-level keys in the json config that don't
+// match a Config field, sorted.
+func unknownConfigKeys(js []byte) ([]string, error) {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(js, &doc); err != nil {
+		return nil, err
+	}
+
+	structType := reflect.TypeOf(Config{})
+	for i := 0; i < structType.NumField(); i++ {
+		key, _, _ := strings.Cut(structType.Field(i).Tag.Get("json"), ",")
+		delete(doc, key)
+	}
+
+	unknown := make([]string, 0, len(doc))
+	for key := range doc {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
 // LoadEnv loads environment variables into the config, overwriting current
 // values. Environment variable to config key mapping is tagged on the
 // Config struct. Multiple names for envvars is supported, comma-separated.
@@ -196,6 +503,12 @@ func (c *Config) LoadEnv(getenv func(string) string) error {
 				}
 				mapValVal := reflect.ValueOf(mapVal)
 				target.Set(mapValVal)
+			case map[string]bool:
+				featuresVal, err := parseFeatures(envVal)
+				if err != nil {
+					return fmt.Errorf("could not parse %s value %q: %w", envVar, envVal, err)
+				}
+				target.Set(reflect.ValueOf(featuresVal))
 			}
 		}
 	}
@@ -203,62 +516,75 @@ func (c *Config) LoadEnv(getenv func(string) string) error {
 	return nil
 }
 
-// ToStringMap flattens the configuration into a stringmap that is easy to work
-// with in tests especially with cmp.Diff. See test_main.go.
-func (c Config) ToStringMap() map[string]string {
-	return map[string]string{
-		"endpoint":                    c.Endpoint,
-		"protocol":                    c.Protocol,
-		"timeout":                     c.Timeout,
-		"headers":                     flattenStringMap(c.Headers, "{}"),
-		"insecure":                    strconv.FormatBool(c.Insecure),
-		"blocking":                    strconv.FormatBool(c.Blocking),
-		"tls_no_verify":               strconv.FormatBool(c.TlsNoVerify),
-		"tls_ca_cert":                 c.TlsCACert,
-		"tls_client_key":              c.TlsClientKey,
-		"tls_client_cert":             c.TlsClientCert,
-		"service_name":                c.ServiceName,
-		"span_name":                   c.SpanName,
-		"span_kind":                   c.Kind,
-		"span_attributes":             flattenStringMap(c.Attributes, "{}"),
-		"span_status_code":            c.StatusCode,
-		"span_status_description":     c.StatusDescription,
-		"traceparent_carrier_file":    c.TraceparentCarrierFile,
-		"traceparent_ignore_env":      strconv.FormatBool(c.TraceparentIgnoreEnv),
-		"traceparent_print":           strconv.FormatBool(c.TraceparentPrint),
-		"traceparent_print_export":    strconv.FormatBool(c.TraceparentPrintExport),
-		"traceparent_required":        strconv.FormatBool(c.TraceparentRequired),
-		"background_parent_poll_ms":   strconv.Itoa(c.BackgroundParentPollMs),
-		"background_socket_directory": c.BackgroundSockdir,
-		"background_wait":             strconv.FormatBool(c.BackgroundWait),
-		"background_skip_pid_check":   strconv.FormatBool(c.BackgroundSkipParentPidCheck),
-		"exec_command_timeout":        c.ExecCommandTimeout,
-		"exec_tp_disable_inject":      strconv.FormatBool(c.ExecTpDisableInject),
-		"span_start_time":             c.SpanStartTime,
-		"span_end_time":               c.SpanEndTime,
-		"event_name":                  c.EventName,
-		"event_time":                  c.EventTime,
-		"config_file":                 c.CfgFile,
-		"verbose":                     strconv.FormatBool(c.Verbose),
+// attrEnvPrefix starts envvars that each set one span attribute, as an
+// alternative to escaping a whole OTEL_CLI_ATTRIBUTES list.
+const attrEnvPrefix = "OTEL_CLI_ATTR_"
+
+// LoadAttrEnv sets a span attribute for each OTEL_CLI_ATTR_<key>=<value> in
+// environ, which is usually os.Environ(). Envvar names can't have dots, so
+// double underscores in the key become dots, e.g. OTEL_CLI_ATTR_deploy__env
+// sets deploy.env. They're merged into --attrs/OTEL_CLI_ATTRIBUTES, replacing
+// attributes with the same key, and are parsed the same way, so values like
+// 42 or true get typed.
+func (c *Config) LoadAttrEnv(environ []string) error {
+	attrs := map[string]string{}
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(name, attrEnvPrefix)
+		if !ok || value == "" {
+			continue
+		}
+		if key == "" {
+			return fmt.Errorf("%s needs an attribute name after the prefix", name)
+		}
+		attrs[strings.ReplaceAll(key, "__", ".")] = value
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(c.Attributes)+len(attrs))
+	for k, v := range c.Attributes {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	c.Attributes = merged
+	return nil
+}
+
+// recordingModes are the valid values of --recording.
+var recordingModes = []string{"auto", "false", "require"}
+
+// CheckRecording returns an error when --recording isn't valid, or when it's
+// require and otel-cli would run inert.
+func (c Config) CheckRecording() error {
+	if c.Recording != "" && !slices.Contains(recordingModes, c.Recording) {
+		return fmt.Errorf("invalid --recording %q, expected one of %s", c.Recording, strings.Join(recordingModes, ", "))
+	} else if c.Recording == "require" && c.Endpoint == "" && c.TracesEndpoint == "" && !c.DryRun {
+		return fmt.Errorf("--recording=require but no endpoint is configured, set --endpoint or OTEL_EXPORTER_OTLP_ENDPOINT")
 	}
+	return nil
 }
 
 // GetIsRecording returns true if an endpoint is set and otel-cli expects to send real
-// spans. Returns false if unconfigured and going to run inert.
+// spans, or --dry-run is set and it would. Returns false if unconfigured and
+// going to run inert. --recording=false turns recording off even with an
+// endpoint. --recording is checked by CheckRecording before commands run.
 func (c Config) GetIsRecording() bool {
-	if c.Endpoint == "" && c.TracesEndpoint == "" {
-		Diag.IsRecording = false
-		return false
+	isRecording := c.Endpoint != "" || c.TracesEndpoint != "" || c.DryRun
+	if c.Recording == "false" {
+		isRecording = false
 	}
-
-	Diag.IsRecording = true
-	return true
+	c.diag.update(func(d *Diagnostics) { d.IsRecording = isRecording })
+	return isRecording
 }
 
 // ParseCliTimeout parses the --timeout string value to a time.Duration.
 func (c Config) ParseCliTimeout() time.Duration {
 	out, err := parseDuration(c.Timeout)
-	Diag.ParsedTimeoutMs = out.Milliseconds()
+	c.diag.update(func(d *Diagnostics) { d.ParsedTimeoutMs = out.Milliseconds() })
 	c.SoftFailIfErr(err)
 	return out
 }
@@ -274,6 +600,80 @@ func (c Config) ParseExecCommandTimeout() time.Duration {
 	return out
 }
 
+// ParseExecKillGrace parses the --kill-grace string value to a time.Duration.
+// Zero means the command is killed right away when it times out.
+func (c Config) ParseExecKillGrace() time.Duration {
+	out, err := parseDuration(c.ExecKillGrace)
+	c.SoftFailIfErr(err)
+	return out
+}
+
+// ParseExecForwardSignals parses the --forward-signals list of signal names.
+func (c Config) ParseExecForwardSignals() []os.Signal {
+	out, err := parseSignalList(c.ExecForwardSignals)
+	if err != nil {
+		c.SoftFail("invalid --forward-signals: %s", err)
+	}
+	return out
+}
+
+// ParseExecSampleResources parses the --sample-resources string value to a
+// time.Duration. Zero means sampling is off.
+func (c Config) ParseExecSampleResources() time.Duration {
+	out, err := parseDuration(c.ExecSampleResources)
+	c.SoftFailIfErr(err)
+	return out
+}
+
+// ParseExecHeartbeat parses the --heartbeat string value to a time.Duration.
+// Zero means no heartbeats.
+func (c Config) ParseExecHeartbeat() time.Duration {
+	out, err := parseDuration(c.ExecHeartbeat)
+	c.SoftFailIfErr(err)
+	return out
+}
+
+// ParseServerDedupeWindow parses the --dedupe-window string value to a time.Duration.
+// Zero means deduplication is off.
+func (c Config) ParseServerDedupeWindow() time.Duration {
+	out, err := parseDuration(c.ServerDedupeWindow)
+	c.SoftFailIfErr(err)
+	return out
+}
+
+// ParseServerCorsOrigins splits the --cors-origins list, returning nil when
+// it's empty.
+func (c Config) ParseServerCorsOrigins() []string {
+	var out []string
+	for _, origin := range strings.Split(c.ServerCorsOrigins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			out = append(out, origin)
+		}
+	}
+	return out
+}
+
+// ParseServerFilterSpanName compiles the --filter-span-name regular
+// expression, returning nil when it's empty.
+func (c Config) ParseServerFilterSpanName() *regexp.Regexp {
+	if c.ServerFilterSpanName == "" {
+		return nil
+	}
+	out, err := regexp.Compile(c.ServerFilterSpanName)
+	if err != nil {
+		c.SoftFail("invalid --filter-span-name %q: %s", c.ServerFilterSpanName, err)
+	}
+	return out
+}
+
+// ParseServerBufferSize parses the --buffer-size string value to a number of
+// bytes. Zero means the size isn't limited.
+func (c Config) ParseServerBufferSize() int {
+	out, err := parseByteSize(c.ServerBufferSize)
+	c.SoftFailIfErr(err)
+	return int(out)
+}
+
 // ParseStatusCanaryInterval parses the --canary-interval string value to a time.Duration.
 func (c Config) ParseStatusCanaryInterval() time.Duration {
 	out, err := parseDuration(c.StatusCanaryInterval)
@@ -281,6 +681,25 @@ func (c Config) ParseStatusCanaryInterval() time.Duration {
 	return out
 }
 
+// ParseStatusCanaryMaxFailures parses --canary-max-failures, a ratio like
+// 0.1 or a percentage like 10%, returning nil when it isn't set.
+func (c Config) ParseStatusCanaryMaxFailures() *float64 {
+	if c.StatusCanaryMaxFailures == "" {
+		return nil
+	}
+
+	in := strings.TrimSpace(c.StatusCanaryMaxFailures)
+	percent := strings.HasSuffix(in, "%")
+	ratio, err := strconv.ParseFloat(strings.TrimSuffix(in, "%"), 64)
+	if err == nil && percent {
+		ratio /= 100
+	}
+	if err != nil || ratio < 0 || ratio > 1 {
+		c.SoftFail("invalid --canary-max-failures %q, expected a ratio from 0 to 1 or a percentage", c.StatusCanaryMaxFailures)
+	}
+	return &ratio
+}
+
 // parseDuration parses a string duration into a time.Duration.
 // When no duration letter is provided (e.g. ms, s, m, h), seconds are assumed.
 // It logs an error and returns time.Duration(0) if the string is empty or unparseable.
@@ -328,11 +747,16 @@ func (config Config) ParseEndpoint() (*url.URL, string) {
 	} else if len(parts) > 1 { // could be URI or host:port
 		// actual URIs
 		// grpc:// is only an otel-cli thing, maybe should drop it?
-		if parts[0] == "grpc" || parts[0] == "http" || parts[0] == "https" {
+		// udp:// is only used by otel-cli server
+		// unix:///path/to.sock is a Unix domain socket for gRPC or HTTP
+		if parts[0] == "grpc" || parts[0] == "http" || parts[0] == "https" || parts[0] == "udp" || parts[0] == "unix" {
 			epUrl, err = url.Parse(endpoint)
 			if err != nil {
 				config.SoftFail("error parsing provided %s URI '%s': %s", source, endpoint, err)
 			}
+			if epUrl.Scheme == "unix" && (epUrl.Host != "" || !path.IsAbs(epUrl.Path)) {
+				config.SoftFail("%s unix endpoint '%s' must be an absolute socket path, e.g. unix:///run/otel.sock", source, endpoint)
+			}
 		} else {
 			// gRPC host:port
 			epUrl, err = url.Parse("grpc://" + endpoint)
@@ -348,8 +772,10 @@ func (config Config) ParseEndpoint() (*url.URL, string) {
 		epUrl.Path = path.Join(epUrl.Path, "/v1/traces")
 	}
 
-	Diag.EndpointSource = source
-	Diag.Endpoint = epUrl.String()
+	config.diag.update(func(d *Diagnostics) {
+		d.EndpointSource = source
+		d.Endpoint = epUrl.String()
+	})
 	return epUrl, source
 }
 
@@ -360,7 +786,7 @@ func (c Config) SoftLog(format string, a ...interface{}) {
 	if !c.Verbose {
 		return
 	}
-	log.Printf(format, a...)
+	log.New(c.getStderr(), log.Prefix(), log.Flags()).Printf(format, a...)
 }
 
 // SoftLogIfErr calls SoftLog only if err != nil.
@@ -372,6 +798,13 @@ func (c Config) SoftLogIfErr(err error) {
 	}
 }
 
+// Fatal logs to stderr and exits 1, whatever --fail and --verbose are, for
+// errors the user asked otel-cli to fail on.
+func (c Config) Fatal(format string, a ...interface{}) {
+	log.New(c.getStderr(), log.Prefix(), log.Flags()).Printf(format, a...)
+	c.exit(1)
+}
+
 // SoftFail calls through to softLog (which logs only if otel-cli was run with the --verbose
 // flag), then immediately exits - with status -1 by default, or 1 if --fail was
 // set (a la `curl --fail`)
@@ -379,12 +812,39 @@ func (c Config) SoftFail(format string, a ...interface{}) {
 	c.SoftLog(format, a...)
 
 	if c.Fail {
-		os.Exit(1)
+		c.exit(1)
 	} else {
-		os.Exit(0)
+		c.exit(0)
 	}
 }
 
+// exit ends the invocation with the exit code. That's os.Exit unless Run
+// swapped it out so the embedding program keeps running.
+func (c Config) exit(code int) {
+	if c.exitFunc != nil {
+		c.exitFunc(code)
+	}
+	os.Exit(code)
+}
+
+// getStdout returns where commands write their output, os.Stdout unless Run
+// was given something else.
+func (c Config) getStdout() io.Writer {
+	if c.stdout == nil {
+		return os.Stdout
+	}
+	return c.stdout
+}
+
+// getStderr returns where logs and errors go, os.Stderr unless Run was
+// given something else.
+func (c Config) getStderr() io.Writer {
+	if c.stderr == nil {
+		return os.Stderr
+	}
+	return c.stderr
+}
+
 // SoftFailIfErr calls SoftFail only if err != nil.
 // Written as an interim step to pushing errors up the stack instead of calling
 // SoftLog/SoftFail directly in methods that don't need a config handle.
@@ -422,6 +882,16 @@ func flattenStringMap(mp map[string]string, emptyValue string) string {
 	return out
 }
 
+// jsonString returns the json encoding of the value as a string, for
+// ToStringMap fields that aren't simple values.
+func jsonString(v interface{}) string {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return err.Error()
+	}
+	return string(js)
+}
+
 // parseCkvStringMap parses key=value,foo=bar formatted strings as a line of CSV
 // and returns it as a string map.
 func parseCkvStringMap(in string) (map[string]string, error) {
@@ -433,9 +903,9 @@ func parseCkvStringMap(in string) (map[string]string, error) {
 
 	out := make(map[string]string)
 	for _, pair := range pairs {
-		parts := strings.SplitN(pair, "=", 2)
-		if parts[0] != "" && parts[1] != "" {
-			out[parts[0]] = parts[1]
+		key, value, _ := strings.Cut(pair, "=")
+		if key != "" && value != "" {
+			out[key] = value
 		} else {
 			return map[string]string{}, fmt.Errorf("kv pair %s must be in key=value format", pair)
 		}
@@ -444,20 +914,63 @@ func parseCkvStringMap(in string) (map[string]string, error) {
 	return out, nil
 }
 
-// ParseSpanStartTime returns config.SpanStartTime as time.Time.
+// ParseFakeNow parses --fake-now / OTEL_CLI_FAKE_CLOCK into a time.Time.
+// "now" is not accepted since it would be circular.
+func (c Config) ParseFakeNow() (time.Time, error) {
+	if c.FakeNow == "now" {
+		return time.Time{}, fmt.Errorf("fake clock must be an actual timestamp, not %q", c.FakeNow)
+	}
+	return c.parseTime(c.FakeNow, "fake clock")
+}
+
+// now returns the time to stamp spans, events, and logs with, which is the
+// --fake-now time when that's set. Deadlines and timeouts always use the real
+// clock.
+func (c Config) now() time.Time {
+	if c.FakeNow != "" {
+		if t, err := c.ParseFakeNow(); err == nil {
+			return t
+		}
+	}
+	return time.Now()
+}
+
+// ParseSpanStartTime returns config.SpanStartTime as time.Time, or the
+// modification time of config.SpanStartFromFile when that's set.
 func (c Config) ParseSpanStartTime() time.Time {
+	if c.SpanStartFromFile != "" {
+		t, err := fileModTime(c.SpanStartFromFile, "start")
+		c.SoftFailIfErr(err)
+		return t
+	}
 	t, err := c.parseTime(c.SpanStartTime, "start")
 	c.SoftFailIfErr(err)
 	return t
 }
 
-// ParseSpanEndTime returns config.SpanEndTime as time.Time.
+// ParseSpanEndTime returns config.SpanEndTime as time.Time, or the
+// modification time of config.SpanEndFromFile when that's set.
 func (c Config) ParseSpanEndTime() time.Time {
+	if c.SpanEndFromFile != "" {
+		t, err := fileModTime(c.SpanEndFromFile, "end")
+		c.SoftFailIfErr(err)
+		return t
+	}
 	t, err := c.parseTime(c.SpanEndTime, "end")
 	c.SoftFailIfErr(err)
 	return t
 }
 
+// fileModTime returns the modification time of the file at path, for
+// backfilling spans from files left behind by tools otel-cli didn't wrap.
+func fileModTime(path, which string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not get span %s time from file: %w", which, err)
+	}
+	return fi.ModTime(), nil
+}
+
 // ParsedEventTime returns config.EventTime as time.Time.
 func (c Config) ParsedEventTime() time.Time {
 	t, err := c.parseTime(c.EventTime, "event")
@@ -472,7 +985,7 @@ func (c Config) parseTime(ts, which string) (time.Time, error) {
 	errs := []error{}
 
 	if ts == "now" {
-		return time.Now(), nil
+		return c.now(), nil
 	}
 
 	// Unix epoch time
@@ -526,22 +1039,9 @@ func (c Config) GetEndpoint() *url.URL {
 	return ep
 }
 
-// WithEndpoint returns the config with Endpoint set to the provided value.
-func (c Config) WithEndpoint(with string) Config {
-	c.Endpoint = with
-	return c
-}
-
-// WithTracesEndpoint returns the config with TracesEndpoint set to the provided value.
-func (c Config) WithTracesEndpoint(with string) Config {
-	c.TracesEndpoint = with
-	return c
-}
-
-// WithProtocol returns the config with protocol set to the provided value.
-func (c Config) WithProtocol(with string) Config {
-	c.Protocol = with
-	return c
+// GetProtocol returns the --protocol value.
+func (c Config) GetProtocol() string {
+	return c.Protocol
 }
 
 // GetTimeout returns the parsed --timeout value as a time.Duration.
@@ -549,10 +1049,36 @@ func (c Config) GetTimeout() time.Duration {
 	return c.ParseCliTimeout()
 }
 
-// WithTimeout returns the config with Timeout set to the provided value.
-func (c Config) WithTimeout(with string) Config {
-	c.Timeout = with
-	return c
+// timeoutContext returns ctx with the deadline for the --timeout budget,
+// which every command counts from when otel-cli started, so setup and
+// connecting to the endpoint are included. Time that isn't otel-cli's own,
+// like exec's child process or how long span background was running, is
+// passed as excluded and moves the deadline out by that much, as does time
+// spent in readStdin. The deadline is recorded in the diagnostics for
+// otel-cli status.
+func (c Config) timeoutContext(ctx context.Context, excluded time.Duration) (context.Context, context.CancelFunc) {
+	startup := c.startupTime
+	if startup.IsZero() {
+		startup = time.Now() // e.g. a Config made in a test
+	}
+	excluded += c.diag.snapshot().stdinWait
+	deadline := startup.Add(c.GetTimeout()).Add(excluded)
+	c.diag.update(func(d *Diagnostics) {
+		d.StartupTime = startup.Format(time.RFC3339Nano)
+		d.TimeoutDeadline = deadline.Format(time.RFC3339Nano)
+		d.TimeoutExcludedMs = excluded.Milliseconds()
+	})
+	return context.WithDeadline(ctx, deadline)
+}
+
+// readStdin calls read, which reads from stdin, and keeps the time it took
+// out of the --timeout budget, since that's up to whatever writes to stdin.
+// Stdin has to be read before timeoutContext is called for this to count.
+func (c Config) readStdin(read func()) {
+	started := time.Now()
+	read()
+	waited := time.Since(started)
+	c.diag.update(func(d *Diagnostics) { d.stdinWait += waited })
 }
 
 // GetHeaders returns the stringmap of configured headers.
@@ -560,204 +1086,142 @@ func (c Config) GetHeaders() map[string]string {
 	return c.Headers
 }
 
-// WithHeades returns the config with Heades set to the provided value.
-func (c Config) WithHeaders(with map[string]string) Config {
-	c.Headers = with
-	return c
-}
-
-// WithInsecure returns the config with Insecure set to the provided value.
-func (c Config) WithInsecure(with bool) Config {
-	c.Insecure = with
-	return c
-}
-
-// WithBlocking returns the config with Blocking set to the provided value.
-func (c Config) WithBlocking(with bool) Config {
-	c.Blocking = with
-	return c
-}
-
-// WithTlsNoVerify returns the config with NoTlsVerify set to the provided value.
-func (c Config) WithTlsNoVerify(with bool) Config {
-	c.TlsNoVerify = with
-	return c
-}
-
-// WithTlsCACert returns the config with TlsCACert set to the provided value.
-func (c Config) WithTlsCACert(with string) Config {
-	c.TlsCACert = with
-	return c
-}
-
-// WithTlsClientKey returns the config with NoTlsClientKey set to the provided value.
-func (c Config) WithTlsClientKey(with string) Config {
-	c.TlsClientKey = with
-	return c
-}
-
-// WithTlsClientCert returns the config with NoTlsClientCert set to the provided value.
-func (c Config) WithTlsClientCert(with string) Config {
-	c.TlsClientCert = with
-	return c
-}
-
-// GetServiceName returns the configured OTel service name.
-func (c Config) GetServiceName() string {
-	return c.ServiceName
-}
-
-// WithServiceName returns the config with ServiceName set to the provided value.
-func (c Config) WithServiceName(with string) Config {
-	c.ServiceName = with
-	return c
-}
-
-// WithSpanName returns the config with SpanName set to the provided value.
-func (c Config) WithSpanName(with string) Config {
-	c.SpanName = with
-	return c
-}
-
-// WithKind returns the config with Kind set to the provided value.
-func (c Config) WithKind(with string) Config {
-	c.Kind = with
-	return c
-}
+// GetSigningKey reads the key from SigningKeyFile, with surrounding whitespace
+// trimmed so keys written by echo work. Returns nil when signing is off.
+func (c Config) GetSigningKey() []byte {
+	if c.SigningKeyFile == "" {
+		return nil
+	}
 
-// WithAttributes returns the config with Attributes set to the provided value.
-func (c Config) WithAttributes(with map[string]string) Config {
-	c.Attributes = with
-	return c
-}
+	data, err := os.ReadFile(c.SigningKeyFile)
+	if err != nil {
+		c.SoftFail("failed to read signing key file %q: %s", c.SigningKeyFile, err)
+	}
 
-// WithStatusCode returns the config with StatusCode set to the provided value.
-func (c Config) WithStatusCode(with string) Config {
-	c.StatusCode = with
-	return c
-}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		c.SoftFail("signing key file %q is empty", c.SigningKeyFile)
+	}
 
-// WithStatusDescription returns the config with StatusDescription set to the provided value.
-func (c Config) WithStatusDescription(with string) Config {
-	c.StatusDescription = with
-	return c
+	return key
 }
 
-// WithTraceparentCarrierFile returns the config with TraceparentCarrierFile set to the provided value.
-func (c Config) WithTraceparentCarrierFile(with string) Config {
-	c.TraceparentCarrierFile = with
-	return c
+// GetCompression returns the compression for OTLP payloads, gzip or zstd,
+// or an empty string for none.
+func (c Config) GetCompression() string {
+	if c.Compression == "none" {
+		return ""
+	}
+	return c.Compression
 }
 
-// WithTraceparentIgnoreEnv returns the config with TraceparentIgnoreEnv set to the provided value.
-func (c Config) WithTraceparentIgnoreEnv(with bool) Config {
-	c.TraceparentIgnoreEnv = with
-	return c
+// GetRetries returns how many times a failed export is retried, or a
+// negative number to retry until the timeout.
+func (c Config) GetRetries() int {
+	return c.OtlpRetries
 }
 
-// WithTraceparentPrint returns the config with TraceparentPrint set to the provided value.
-func (c Config) WithTraceparentPrint(with bool) Config {
-	c.TraceparentPrint = with
-	return c
+// GetRetrySleep parses --otlp-retry-sleep, how much longer to sleep before
+// each retry than the last.
+func (c Config) GetRetrySleep() time.Duration {
+	out, err := parseDuration(c.OtlpRetrySleep)
+	c.SoftFailIfErr(err)
+	return out
 }
 
-// WithTraceparentPrintExport returns the config with TraceparentPrintExport set to the provided value.
-func (c Config) WithTraceparentPrintExport(with bool) Config {
-	c.TraceparentPrintExport = with
-	return c
+// GetRetryTimeout parses --otlp-retry-timeout, the most time to spend on one
+// export and its retries. Zero leaves it to --timeout.
+func (c Config) GetRetryTimeout() time.Duration {
+	out, err := parseDuration(c.OtlpRetryTimeout)
+	c.SoftFailIfErr(err)
+	return out
 }
 
-// WithTraceparentRequired returns the config with TraceparentRequired set to the provided value.
-func (c Config) WithTraceparentRequired(with bool) Config {
-	c.TraceparentRequired = with
-	return c
+// GetWireDebugFile returns the file OTLP requests and responses are dumped
+// to, or an empty string when that's off.
+func (c Config) GetWireDebugFile() string {
+	return c.WireDebugFile
 }
 
-// WithBackgroundParentPollMs returns the config with BackgroundParentPollMs set to the provided value.
-func (c Config) WithBackgroundParentPollMs(with int) Config {
-	c.BackgroundParentPollMs = with
-	return c
+// GetProxy returns the --proxy URL exports go through, or an empty string
+// to use the proxy envvars.
+func (c Config) GetProxy() string {
+	return c.Proxy
 }
 
-// WithBackgroundSockdir returns the config with BackgroundSockdir set to the provided value.
-func (c Config) WithBackgroundSockdir(with string) Config {
-	c.BackgroundSockdir = with
-	return c
+// GetServiceName returns the configured OTel service name.
+func (c Config) GetServiceName() string {
+	return c.ServiceName
 }
 
-// WithBackgroundWait returns the config with BackgroundWait set to the provided value.
-func (c Config) WithBackgroundWait(with bool) Config {
-	c.BackgroundWait = with
-	return c
+// GetResourceAttributes returns the --resource-attrs to put on the resource.
+func (c Config) GetResourceAttributes() map[string]string {
+	return c.ResourceAttributes
 }
 
-// WithBackgroundSkipParentPidCheck returns the config with BackgroundSkipParentPidCheck set to the provided value.
-func (c Config) WithBackgroundSkipParentPidCheck(with bool) Config {
-	c.BackgroundSkipParentPidCheck = with
-	return c
-}
+// GetResourceDetectors parses --resource-detectors into a list of detector
+// names. CheckResourceDetectors has made sure they're all valid.
+func (c Config) GetResourceDetectors() []string {
+	if c.ResourceDetectors == "" {
+		return nil
+	}
 
-// WithStatusCanaryCount returns the config with StatusCanaryCount set to the provided value.
-func (c Config) WithStatusCanaryCount(with int) Config {
-	c.StatusCanaryCount = with
-	return c
-}
+	names := strings.Split(c.ResourceDetectors, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+	}
 
-// WithStatusCanaryInterval returns the config with StatusCanaryInterval set to the provided value.
-func (c Config) WithStatusCanaryInterval(with string) Config {
-	c.StatusCanaryInterval = with
-	return c
+	return names
 }
 
-// WithSpanStartTime returns the config with SpanStartTime set to the provided value.
-func (c Config) WithSpanStartTime(with string) Config {
-	c.SpanStartTime = with
-	return c
-}
+// CheckResourceDetectors returns an error when --resource-detectors names a
+// detector that doesn't exist, or combines "none" with detectors since it
+// would turn them off.
+func (c Config) CheckResourceDetectors() error {
+	names := c.GetResourceDetectors()
+	for _, name := range names {
+		if _, ok := otlpclient.ResourceDetectors[name]; !ok && name != "none" {
+			valid := []string{}
+			for name := range otlpclient.ResourceDetectors {
+				valid = append(valid, name)
+			}
+			sort.Strings(valid)
+			return fmt.Errorf("invalid --resource-detectors %q, expected a list of %s, or none", name, strings.Join(valid, ", "))
+		}
+	}
+	if len(names) > 1 && slices.Contains(names, "none") {
+		return fmt.Errorf("--resource-detectors none can't be combined with other detectors")
+	}
 
-// WithSpanEndTime returns the config with SpanEndTime set to the provided value.
-func (c Config) WithSpanEndTime(with string) Config {
-	c.SpanEndTime = with
-	return c
+	return nil
 }
 
-// WithEventName returns the config with EventName set to the provided value.
-func (c Config) WithEventName(with string) Config {
-	c.EventName = with
-	return c
-}
+// GetSpanStackFile returns the configured span stack file, or a file in the
+// temp directory named after the parent process id so each shell gets its own.
+func (c Config) GetSpanStackFile() string {
+	if c.SpanStackFile != "" {
+		return c.SpanStackFile
+	}
 
-// WithEventTIme returns the config with EventTIme set to the provided value.
-func (c Config) WithEventTime(with string) Config {
-	c.EventTime = with
-	return c
+	return filepath.Join(os.TempDir(), fmt.Sprintf("otel-cli-span-stack-%d.json", os.Getppid()))
 }
 
-// WithCfgFile returns the config with CfgFile set to the provided value.
-func (c Config) WithCfgFile(with string) Config {
-	c.CfgFile = with
-	return c
-}
+// GetBackgroundSockfile returns the path to the span background socket in
+// the sockdir. Each --span-handle gets its own socket so several background
+// spans can run out of one sockdir at the same time.
+func (c Config) GetBackgroundSockfile() string {
+	if c.BackgroundSpanHandle == "" {
+		return path.Join(c.BackgroundSockdir, spanBgSockfilename)
+	}
 
-// WithVerbose returns the config with Verbose set to the provided value.
-func (c Config) WithVerbose(with bool) Config {
-	c.Verbose = with
-	return c
-}
+	if !spanHandleRe.MatchString(c.BackgroundSpanHandle) {
+		c.SoftFail("invalid --span-handle %q: only letters, numbers, '.', '_', and '-' are allowed", c.BackgroundSpanHandle)
+	}
 
-// WithFail returns the config with Fail set to the provided value.
-func (c Config) WithFail(with bool) Config {
-	c.Fail = with
-	return c
+	return path.Join(c.BackgroundSockdir, fmt.Sprintf("otel-cli-background-%s.sock", c.BackgroundSpanHandle))
 }
 
 // Version returns the program version stored in the config.
 func (c Config) GetVersion() string {
 	return c.Version
 }
-
-// WithVersion returns the config with Version set to the provided value.
-func (c Config) WithVersion(with string) Config {
-	c.Version = with
-	return c
-}
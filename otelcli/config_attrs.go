@@ -0,0 +1,197 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// parseAttributeSources merges every source of span attributes. Later ones
+// replace earlier ones with the same key: --attrs-file, then --attrs and
+// --attr-bytes, then --attr-str, --attr-int, --attr-float, and --attr-bool.
+func (c Config) parseAttributeSources() ([]*commonpb.KeyValue, error) {
+	fileAttrs, err := readAttrsFile(c.AttributesFile)
+	if err != nil {
+		return nil, err
+	}
+	cliAttrs, err := attrsToProtobuf(c.Attributes, c.AttributesBytes)
+	if err != nil {
+		return nil, err
+	}
+	typedAttrs, err := parseTypedAttrs(c.AttributesStr, c.AttributesInt, c.AttributesFloat, c.AttributesBool)
+	if err != nil {
+		return nil, err
+	}
+	return mergeAttrs(fileAttrs, cliAttrs, typedAttrs), nil
+}
+
+// withoutAttributes returns the config with every source of attributes
+// cleared, for spans whose attributes go somewhere else.
+func (c Config) withoutAttributes() Config {
+	c.Attributes = map[string]string{}
+	c.AttributesBytes = map[string]string{}
+	c.AttributesFile = ""
+	c.AttributesStr, c.AttributesInt, c.AttributesFloat, c.AttributesBool = nil, nil, nil, nil
+	return c
+}
+
+// mergeAttrs concatenates lists of attributes, keeping the position of the
+// first attribute with a key and the value of the last.
+func mergeAttrs(lists ...[]*commonpb.KeyValue) []*commonpb.KeyValue {
+	out := []*commonpb.KeyValue{}
+	seen := map[string]int{}
+	for _, list := range lists {
+		for _, attr := range list {
+			if i, ok := seen[attr.Key]; ok {
+				out[i] = attr
+			} else {
+				seen[attr.Key] = len(out)
+				out = append(out, attr)
+			}
+		}
+	}
+	return out
+}
+
+// readAttrsFile reads --attrs-file, a JSON object whose values are sent with
+// their JSON types. Numbers without a fraction or exponent are ints, the rest
+// are doubles. Arrays and objects become array and kvlist values.
+func readAttrsFile(path string) ([]*commonpb.KeyValue, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read --attrs-file: %w", err)
+	}
+
+	var doc map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid --attrs-file %q, expected a JSON object: %w", path, err)
+	}
+
+	out := make([]*commonpb.KeyValue, 0, len(doc))
+	for key, value := range doc {
+		av, err := jsonToAnyValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attrs-file value for %q: %w", key, err)
+		}
+		out = append(out, &commonpb.KeyValue{Key: otlpclient.ValidUTF8(key), Value: av})
+	}
+	return out, nil
+}
+
+// jsonToAnyValue converts a value decoded with json.Decoder.UseNumber.
+func jsonToAnyValue(in interface{}) (*commonpb.AnyValue, error) {
+	switch v := in.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: otlpclient.ValidUTF8(v)}}, nil
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}, nil
+	case json.Number:
+		if i, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}, nil
+	case []interface{}:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i, elem := range v {
+			av, err := jsonToAnyValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = av
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		kvs := make([]*commonpb.KeyValue, len(keys))
+		for i, key := range keys {
+			av, err := jsonToAnyValue(v[key])
+			if err != nil {
+				return nil, err
+			}
+			kvs[i] = &commonpb.KeyValue{Key: otlpclient.ValidUTF8(key), Value: av}
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: kvs}}}, nil
+	}
+	return nil, fmt.Errorf("null is not a valid attribute value")
+}
+
+// parseTypedAttrs converts --attr-str, --attr-int, --attr-float, and
+// --attr-bool to attributes of those types, failing on values that aren't.
+// Invalid UTF-8 is replaced the same as in --attrs keys.
+func parseTypedAttrs(strs, ints, floats, bools map[string]string) ([]*commonpb.KeyValue, error) {
+	out := []*commonpb.KeyValue{}
+	for key, value := range strs {
+		out = append(out, stringAttr(otlpclient.ValidUTF8(key), otlpclient.ValidUTF8(value)))
+	}
+	for key, value := range ints {
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attr-int value for %q: %w", key, err)
+		}
+		out = append(out, intAttr(otlpclient.ValidUTF8(key), i))
+	}
+	for key, value := range floats {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attr-float value for %q: %w", key, err)
+		}
+		out = append(out, doubleAttr(otlpclient.ValidUTF8(key), f))
+	}
+	for key, value := range bools {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attr-bool value for %q: %w", key, err)
+		}
+		out = append(out, boolAttr(otlpclient.ValidUTF8(key), b))
+	}
+	return out, nil
+}
+
+// stringAttr, boolAttr, intAttr, and doubleAttr build a single typed attribute.
+func stringAttr(key string, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func boolAttr(key string, value bool) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: value}},
+	}
+}
+
+func intAttr(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}},
+	}
+}
+
+func doubleAttr(key string, value float64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: value}},
+	}
+}
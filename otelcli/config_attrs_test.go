@@ -0,0 +1,84 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func TestParseAttributeSources(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "attrs.json")
+	os.WriteFile(file, []byte(`{
+		"build": 123, "ratio": 1.5, "big": 1e3, "ok": true, "name": "file",
+		"tags": ["a", 1], "nested": {"z": false, "a": "b"}
+	}`), 0644)
+
+	config := DefaultConfig().
+		WithAttributesFile(file).
+		WithAttributes(map[string]string{"name": "attrs", "guessed": "0123"}).
+		WithAttributesStr(map[string]string{"build": "0123"}).
+		WithAttributesInt(map[string]string{"count": "-4"}).
+		WithAttributesFloat(map[string]string{"temp": "21"}).
+		WithAttributesBool(map[string]string{"ok": "false"})
+
+	attrs, err := config.parseAttributeSources()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got := map[string]string{}
+	for _, attr := range attrs {
+		js, _ := protojson.Marshal(attr.Value)
+		got[attr.Key] = strings.ReplaceAll(string(js), " ", "")
+	}
+	// the typed flags win over --attrs, which wins over the file
+	want := map[string]string{
+		"build":   `{"stringValue":"0123"}`,
+		"ratio":   `{"doubleValue":1.5}`,
+		"big":     `{"doubleValue":1000}`,
+		"ok":      `{"boolValue":false}`,
+		"name":    `{"stringValue":"attrs"}`,
+		"tags":    `{"arrayValue":{"values":[{"stringValue":"a"},{"intValue":"1"}]}}`,
+		"nested":  `{"kvlistValue":{"values":[{"key":"a","value":{"stringValue":"b"}},{"key":"z","value":{"boolValue":false}}]}}`,
+		"guessed": `{"intValue":"123"}`, // why --attr-str exists
+		"count":   `{"intValue":"-4"}`,
+		"temp":    `{"doubleValue":21}`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("attributes did not match (-want +got):\n%s", diff)
+	}
+	if len(attrs) != len(want) {
+		t.Errorf("expected each key once, got %d attributes", len(attrs))
+	}
+
+	for _, tc := range []struct {
+		config  Config
+		wantErr string
+	}{
+		{DefaultConfig().WithAttributesInt(map[string]string{"n": "1.5"}), `invalid --attr-int value for "n"`},
+		{DefaultConfig().WithAttributesBool(map[string]string{"b": "maybe"}), `invalid --attr-bool value for "b"`},
+		{DefaultConfig().WithAttributesFile(filepath.Join(t.TempDir(), "missing.json")), "failed to read --attrs-file"},
+	} {
+		if _, err := tc.config.parseAttributeSources(); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("expected an error containing %q, got %v", tc.wantErr, err)
+		}
+	}
+
+	// OTLP strings have to be valid UTF-8
+	attrs, _ = DefaultConfig().WithAttributesStr(map[string]string{"bad\xff": "v\xff"}).parseAttributeSources()
+	if len(attrs) != 1 || attrs[0].Key != "bad\uFFFD" || attrs[0].Value.GetStringValue() != "v\uFFFD" {
+		t.Errorf("expected invalid UTF-8 to be replaced, got %v", attrs)
+	}
+
+	os.WriteFile(file, []byte(`{"gone": null}`), 0644)
+	if _, err := readAttrsFile(file); err == nil {
+		t.Error("expected an error for a null value")
+	}
+	os.WriteFile(file, []byte(`["not", "an", "object"]`), 0644)
+	if _, err := readAttrsFile(file); err == nil {
+		t.Error("expected an error for a file that isn't a JSON object")
+	}
+}
@@ -0,0 +1,511 @@
+package otelcli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configConvertOpts holds the command-line settings for one run of otel-cli
+// config convert
+type configConvertOpts struct {
+	from string
+	to   string
+}
+
+// configInitOpts holds the command-line settings for one run of otel-cli
+// config init
+type configInitOpts struct {
+	format string
+	all    bool
+	force  bool
+}
+
+// configCmd is the parent of commands that work with config files.
+func configCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "config",
+		Short: "work with otel-cli config files",
+		Long: `Config files can be JSON, YAML, or TOML, all with the same keys. The format
+comes from the file extension, .yaml/.yml for YAML and .toml for TOML with
+anything else read as JSON, or from --config-format.`,
+	}
+
+	cmd.AddCommand(configShowCmd(config))
+	cmd.AddCommand(configValidateCmd(config))
+	cmd.AddCommand(configInitCmd(config))
+	cmd.AddCommand(configConvertCmd(config))
+
+	return &cmd
+}
+
+func configShowCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "show",
+		Short: "print the config after flags, the config file, and envvars are applied",
+		Long: `Prints every setting by its config file key, with the value otel-cli ends up
+using and where it came from: a flag, the config file, an envvar, --vendor or
+--endpoint-template, or the default. Flags are applied first, then the config
+file, then envvars, so the last of those that sets something wins.
+
+Takes the same flags as client commands like otel-cli exec, so it can be run
+with the same command line and environment to see what they would use.
+Secrets are masked like they are in otel-cli status unless --show-secrets is
+set.
+
+Example:
+	OTEL_EXPORTER_OTLP_ENDPOINT=localhost:4317 otel-cli config show --config otel-cli.yaml
+`,
+		Args: cobra.NoArgs,
+		Run:  doConfigShow,
+	}
+
+	defaults := DefaultConfig()
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the traces")
+	cmd.Flags().BoolVar(&config.ShowSecrets, "show-secrets", defaults.ShowSecrets, "don't mask secret-looking header values and attributes, and URL passwords")
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doConfigShow(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	sources := configSources(cmd, getConfigRef(cmd.Context()), os.Getenv, os.Environ())
+
+	values := config.ToStringMap()
+	if !config.ShowSecrets {
+		values = config.masked().ToStringMap()
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(config.getStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, values[key], sources[key])
+	}
+	tw.Flush()
+}
+
+// configSources returns where each of the config's settings came from, by
+// config file key: "flag --name", "file path", "env NAME", "vendor name" or
+// "endpoint template" for what those fill in, or "default". config must be
+// the one the command's flags were bound to, so flags can be matched to
+// settings by the variable they set.
+func configSources(cmd *cobra.Command, config *Config, getenv func(string) string, environ []string) map[string]string {
+	sources := map[string]string{}
+	for key := range config.ToStringMap() {
+		sources[key] = "default"
+	}
+
+	// same order as PersistentPreRun loads them in, so later ones win
+	keys := configJsonKeys(config)
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		if key, ok := keys[flagTarget(flag)]; ok {
+			sources[key] = "flag --" + flag.Name
+		}
+	})
+
+	if config.CfgFile != "" {
+		format, err := configFileFormat(config.CfgFile, config.CfgFormat)
+		data, _ := os.ReadFile(config.CfgFile)
+		if err == nil && data != nil {
+			doc := map[string]json.RawMessage{}
+			if js, err := configToJson(data, format); err == nil && json.Unmarshal(js, &doc) == nil {
+				for key := range doc {
+					if _, ok := sources[key]; !ok {
+						continue
+					}
+					// json merges maps in the file into maps from flags
+					if field, _ := configField(key); field.Type.Kind() == reflect.Map && sources[key] != "default" {
+						sources[key] += " + file " + config.CfgFile
+					} else {
+						sources[key] = "file " + config.CfgFile
+					}
+				}
+			}
+		}
+	}
+
+	structType := reflect.TypeOf(Config{})
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		for _, envVar := range strings.Split(field.Tag.Get("env"), ",") {
+			if envVar != "" && getenv(envVar) != "" {
+				sources[key] = "env " + envVar
+			}
+		}
+	}
+
+	if config.HeadersFile != "" {
+		if sources["otlp_headers"] == "default" {
+			sources["otlp_headers"] = "headers file " + config.HeadersFile
+		} else {
+			sources["otlp_headers"] += " + headers file " + config.HeadersFile
+		}
+	}
+
+	for _, kv := range environ {
+		if strings.HasPrefix(kv, attrEnvPrefix) {
+			if sources["span_attributes"] == "default" {
+				sources["span_attributes"] = "env " + attrEnvPrefix + "*"
+			} else {
+				sources["span_attributes"] += " + env " + attrEnvPrefix + "*"
+			}
+			break
+		}
+	}
+
+	// anything else that isn't the default was filled in by a preset
+	defaults := DefaultConfig().ToStringMap()
+	for key, value := range config.ToStringMap() {
+		if sources[key] != "default" || value == defaults[key] {
+			continue
+		}
+		if config.Vendor != "" {
+			sources[key] = "vendor " + config.Vendor
+		} else if config.EndpointTemplate != "" {
+			sources[key] = "endpoint template"
+		}
+	}
+
+	return sources
+}
+
+// configField returns the Config field for a config file key.
+func configField(key string) (reflect.StructField, bool) {
+	return reflect.TypeOf(Config{}).FieldByNameFunc(func(name string) bool {
+		field, _ := reflect.TypeOf(Config{}).FieldByName(name)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		return tag == key
+	})
+}
+
+func configValidateCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "validate FILE",
+		Short: "check a config file for mistakes",
+		Long: `Loads a config file the way --config does and checks that it parses, that all
+of its keys are known, and that settings like durations, the endpoint, the
+protocol, and the sampler have valid values. Prints each problem found and
+exits 1 if there were any, or prints ok and exits 0. The format comes from
+the file's extension or --config-format.
+
+Example:
+	otel-cli config validate /etc/otel-cli.yaml
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  doConfigValidate,
+	}
+
+	addCommonParams(&cmd, config)
+
+	return &cmd
+}
+
+func doConfigValidate(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+
+	problems := validateConfigFile(args[0], config.CfgFormat)
+	for _, err := range problems {
+		fmt.Fprintf(config.getStdout(), "%s: %s\n", args[0], err)
+	}
+	if len(problems) > 0 {
+		config.exit(1)
+	}
+	fmt.Fprintf(config.getStdout(), "%s: ok\n", args[0])
+}
+
+// validateConfigFile loads the config file at path and returns everything
+// wrong with it.
+func validateConfigFile(path, format string) []error {
+	format, err := configFileFormat(path, format)
+	if err != nil {
+		return []error{err}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{err}
+	}
+	js, err := configToJson(data, format)
+	if err != nil {
+		return []error{fmt.Errorf("failed to parse %s: %w", format, err)}
+	}
+
+	problems := []error{}
+	unknown, err := unknownConfigKeys(js)
+	if err != nil {
+		return []error{fmt.Errorf("failed to parse %s: %w", format, err)}
+	}
+	for _, key := range unknown {
+		problems = append(problems, fmt.Errorf("unknown key %q", key))
+	}
+
+	config := DefaultConfig()
+	if err := json.Unmarshal(js, &config); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			err = fmt.Errorf("%q must be of type %s, not %s", typeErr.Field, typeErr.Type, typeErr.Value)
+		}
+		return append(problems, err)
+	}
+
+	return append(problems, config.validate()...)
+}
+
+// validate checks the settings otel-cli would otherwise only complain about
+// when a command gets around to using them, without failing.
+func (c Config) validate() []error {
+	problems := []error{}
+
+	durations := map[string]string{
+		"timeout":                c.Timeout,
+		"otlp_retry_sleep":       c.OtlpRetrySleep,
+		"otlp_retry_timeout":     c.OtlpRetryTimeout,
+		"exec_command_timeout":   c.ExecCommandTimeout,
+		"exec_kill_grace":        c.ExecKillGrace,
+		"exec_sample_resources":  c.ExecSampleResources,
+		"exec_heartbeat":         c.ExecHeartbeat,
+		"server_dedupe_window":   c.ServerDedupeWindow,
+		"status_canary_interval": c.StatusCanaryInterval,
+		"warn_if_longer_than":    c.WarnIfLongerThan,
+		"error_if_longer_than":   c.ErrorIfLongerThan,
+	}
+	for _, key := range sortedKeys(durations) {
+		if _, err := parseDuration(durations[key]); err != nil {
+			problems = append(problems, fmt.Errorf("invalid %s: %w", key, err))
+		}
+	}
+
+	endpoints := map[string]string{
+		"endpoint":         c.Endpoint,
+		"traces_endpoint":  c.TracesEndpoint,
+		"logs_endpoint":    c.LogsEndpoint,
+		"metrics_endpoint": c.MetricsEndpoint,
+	}
+	for _, key := range sortedKeys(endpoints) {
+		if strings.Contains(endpoints[key], "://") {
+			if _, err := url.Parse(endpoints[key]); err != nil {
+				problems = append(problems, fmt.Errorf("invalid %s: %w", key, err))
+			}
+		}
+	}
+
+	if c.PrintUrl != "" {
+		if _, err := c.GetPrintUrlTemplate(); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	if _, err := parseSignalList(c.ExecForwardSignals); err != nil {
+		problems = append(problems, fmt.Errorf("invalid exec_forward_signals: %w", err))
+	}
+
+	if c.Proxy != "" {
+		if _, err := otlpclient.ParseProxy(c.Proxy); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	protocols := []string{"grpc", "http/protobuf", otlpclient.HttpJsonProtocol}
+	if c.Protocol != "" && !slices.Contains(protocols, c.Protocol) {
+		problems = append(problems, fmt.Errorf("invalid protocol %q, expected one of %s", c.Protocol, strings.Join(protocols, ", ")))
+	}
+	if compressions := flagValues()["otlp-compression"]; c.Compression != "" && !slices.Contains(compressions, c.Compression) {
+		problems = append(problems, fmt.Errorf("invalid otlp_compression %q, expected one of %s", c.Compression, strings.Join(compressions, ", ")))
+	}
+	if c.Recording != "" && !slices.Contains(recordingModes, c.Recording) {
+		problems = append(problems, fmt.Errorf("invalid recording %q, expected one of %s", c.Recording, strings.Join(recordingModes, ", ")))
+	}
+	if c.Vendor != "" {
+		if _, ok := vendorPresets[strings.ToLower(c.Vendor)]; !ok {
+			problems = append(problems, fmt.Errorf("unknown vendor %q, must be one of %s", c.Vendor, vendorNames()))
+		}
+	}
+
+	sampler := strings.ToLower(strings.TrimSpace(c.Sampler))
+	if ratio, ok := strings.CutPrefix(sampler, "ratio:"); ok {
+		if r, err := strconv.ParseFloat(ratio, 64); err != nil || r < 0 || r > 1 {
+			problems = append(problems, fmt.Errorf("invalid traces_sampler %q, expected a ratio from 0 to 1", c.Sampler))
+		}
+	} else if sampler != "" && !slices.Contains(samplers, sampler) {
+		problems = append(problems, fmt.Errorf("invalid traces_sampler %q, expected ratio:<0..1> or one of %s", c.Sampler, strings.Join(samplers, ", ")))
+	}
+	if c.SamplerArg != "" {
+		if r, err := strconv.ParseFloat(strings.TrimSpace(c.SamplerArg), 64); err != nil || r < 0 || r > 1 {
+			problems = append(problems, fmt.Errorf("invalid traces_sampler_arg %q, expected a ratio from 0 to 1", c.SamplerArg))
+		}
+	}
+
+	if err := c.NormalizeSpanEnums(); err != nil {
+		problems = append(problems, err)
+	}
+	if err := c.CheckFeatures(); err != nil {
+		problems = append(problems, err)
+	}
+
+	return problems
+}
+
+// sortedKeys returns the keys of m, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// exampleConfig is what otel-cli config init writes without --all: the
+// settings most setups change, with values to edit.
+var exampleConfig = map[string]interface{}{
+	"endpoint":            "localhost:4317",
+	"protocol":            "grpc",
+	"insecure":            false,
+	"timeout":             "1s",
+	"otlp_headers":        map[string]interface{}{},
+	"service_name":        "otel-cli",
+	"resource_attributes": map[string]interface{}{},
+	"verbose":             false,
+	"fail":                false,
+}
+
+func configInitCmd(config *Config) *cobra.Command {
+	opts := &configInitOpts{}
+	cmd := cobra.Command{
+		Use:   "init [FILE]",
+		Short: "write an example config file to edit",
+		Long: `Writes an example config file with the settings most setups change, or every
+setting with its default value with --all. Without FILE it's written to
+stdout. The format comes from FILE's extension or --format, and an existing
+FILE is only replaced with --force.
+
+Example:
+	otel-cli config init otel-cli.yaml
+	otel-cli config init --all --format toml > otel-cli.toml
+`,
+		Args: cobra.MaximumNArgs(1),
+		Run:  opts.run,
+	}
+
+	formats := strings.Join(configFormats, ", ")
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVar(&opts.format, "format", "", "the format to write, one of "+formats+", by default from FILE's extension or json")
+	cmd.Flags().BoolVar(&opts.all, "all", false, "include every setting with its default value")
+	cmd.Flags().BoolVar(&opts.force, "force", false, "replace FILE if it already exists")
+
+	return &cmd
+}
+
+func (opts *configInitOpts) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+	format, err := configFileFormat(path, opts.format)
+	config.SoftFailIfErr(err)
+
+	var js []byte
+	if opts.all {
+		js, err = json.Marshal(DefaultConfig())
+	} else {
+		js, err = json.Marshal(exampleConfig)
+	}
+	config.SoftFailIfErr(err)
+	out, err := jsonToConfig(js, format)
+	config.SoftFailIfErr(err)
+	if format != "json" {
+		header := "# otel-cli config, see otel-cli config show for what each setting ends up as\n"
+		out = append([]byte(header), out...)
+	}
+
+	if path == "" {
+		config.getStdout().Write(out)
+		return
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if opts.force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		config.Fatal("%s already exists, use --force to overwrite it", path)
+	} else if err != nil {
+		config.Fatal("could not create config file: %s", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(out); err != nil {
+		config.Fatal("could not write config file: %s", err)
+	}
+}
+
+func configConvertCmd(config *Config) *cobra.Command {
+	opts := &configConvertOpts{}
+	cmd := cobra.Command{
+		Use:   "convert FILE",
+		Short: "convert a config file between JSON, YAML, and TOML",
+		Long: `Reads a config file and writes it to stdout in the format given with --to.
+Keys are written sorted. Use - to read from stdin, which is JSON unless --from
+says otherwise.
+
+Example:
+	otel-cli config convert --to yaml otel-cli.json > otel-cli.yaml
+	otel-cli config convert --from yaml --to toml - < otel-cli.yaml
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  opts.run,
+	}
+
+	formats := strings.Join(configFormats, ", ")
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVar(&opts.from, "from", "", "the format of FILE, one of "+formats+", by default from its extension")
+	cmd.Flags().StringVar(&opts.to, "to", "", "the format to write, one of "+formats)
+	cmd.MarkFlagRequired("to")
+
+	return &cmd
+}
+
+func (opts *configConvertOpts) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+
+	from, err := configFileFormat(args[0], opts.from)
+	config.SoftFailIfErr(err)
+	to, err := configFileFormat("", opts.to)
+	config.SoftFailIfErr(err)
+
+	var data []byte
+	if args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	config.SoftFailIfErr(err)
+
+	out, err := convertConfig(data, from, to)
+	if err != nil {
+		config.SoftFail("could not convert '%s': %s", args[0], err)
+	}
+	config.getStdout().Write(out)
+}
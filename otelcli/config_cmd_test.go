@@ -0,0 +1,126 @@
+package otelcli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigShow(t *testing.T) {
+	cfg := filepath.Join(t.TempDir(), "otel-cli.yaml")
+	err := os.WriteFile(cfg, []byte("endpoint: localhost:4317\ntimeout: 2s\notlp_headers: {x-tenant: ops}\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("OTEL_EXPORTER_OTLP_TIMEOUT", "3s")
+	t.Setenv("OTEL_CLI_ATTR_deploy__env", "prod")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"config", "show", "--config", cfg, "--protocol", "grpc", "--otlp-headers", "x-team=infra"}, &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	got := map[string][]string{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 {
+			got[fields[0]] = fields[1:]
+		}
+	}
+	for key, want := range map[string]string{
+		"endpoint":        "localhost:4317 file " + cfg,
+		"timeout":         "3s env OTEL_EXPORTER_OTLP_TIMEOUT",
+		"protocol":        "grpc flag --protocol",
+		"otlp_headers":    "x-team=infra,x-tenant=ops flag --otlp-headers + file " + cfg,
+		"span_attributes": "deploy.env=prod env OTEL_CLI_ATTR_*",
+		"otlp_retries":    "-1 default",
+	} {
+		if strings.Join(got[key], " ") != want {
+			t.Errorf("expected %s to be %q, got %q", key, want, strings.Join(got[key], " "))
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "ok.toml",
+			data: "endpoint = \"https://otlp.example.com\"\ntimeout = \"5s\"\ntraces_sampler = \"ratio:0.5\"\n",
+			want: []string{"ok"},
+		},
+		{
+			name: "typos.json",
+			data: `{"endpont": "localhost:4317", "timeout": "5x", "protocol": "grpcs", "span_kind": "severe", "proxy": "localhost:1080"}`,
+			want: []string{`unknown key "endpont"`, `invalid timeout`, `invalid protocol "grpcs"`, `invalid --kind`, `invalid proxy "localhost:1080"`},
+		},
+		{
+			name: "types.yaml",
+			data: "otlp_retries: three\n",
+			want: []string{`"otlp_retries" must be of type int, not string`},
+		},
+		{
+			name: "broken.yaml",
+			data: "endpoint: [\n",
+			want: []string{"failed to parse yaml"},
+		},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var stdout, stderr bytes.Buffer
+		code := Run([]string{"config", "validate", path}, &stdout, &stderr)
+		if ok := tc.want[0] == "ok"; ok != (code == 0) {
+			t.Errorf("%s: unexpected exit code %d", tc.name, code)
+		}
+		for _, want := range tc.want {
+			if !strings.Contains(stdout.String(), path+": "+want) {
+				t.Errorf("%s: expected %q in the output, got %q", tc.name, want, stdout.String())
+			}
+		}
+	}
+}
+
+// the example configs init writes are valid in every format
+func TestConfigInit(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range configFormats {
+		for _, all := range []bool{false, true} {
+			path := filepath.Join(dir, "otel-cli."+format)
+			args := []string{"config", "init", "--force", path}
+			if all {
+				args = append(args, "--all")
+			}
+			var stdout, stderr bytes.Buffer
+			if code := Run(args, &stdout, &stderr); code != 0 {
+				t.Fatalf("%q: expected exit code 0, got %d: %s", args, code, stderr.String())
+			}
+			if problems := validateConfigFile(path, ""); len(problems) > 0 {
+				t.Errorf("%q: wrote an invalid config: %v", args, problems)
+			}
+		}
+	}
+
+	// existing files are left alone without --force
+	path := filepath.Join(dir, "otel-cli.json")
+	os.WriteFile(path, []byte(`{"endpoint": "mine"}`), 0644)
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"config", "init", path}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit code 1 for an existing config, got %d", code)
+	}
+	if !strings.Contains(stderr.String(), "already exists") {
+		t.Errorf("expected an error about the existing config, got %q", stderr.String())
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"endpoint": "mine"}` {
+		t.Errorf("expected the existing config to be left alone, got %s", data)
+	}
+}
@@ -0,0 +1,157 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFormats are the config file formats otel-cli can read and convert
+// between. All of them use the same keys as the JSON config.
+var configFormats = []string{"json", "yaml", "toml"}
+
+// configFileFormat returns the format of the config file at path: format
+// when it's set, e.g. from --config-format, otherwise yaml for .yaml and .yml
+// files, toml for .toml files, and json for everything else.
+func configFileFormat(path, format string) (string, error) {
+	if format != "" {
+		format = strings.ToLower(format)
+		if !slices.Contains(configFormats, format) {
+			return "", fmt.Errorf("invalid config format %q, must be one of %s", format, strings.Join(configFormats, ", "))
+		}
+		return format, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml", nil
+	case ".toml":
+		return "toml", nil
+	default:
+		return "json", nil
+	}
+}
+
+// configToJson converts config file data in format to JSON, so every format
+// goes through the same json tags on Config and the same check for unknown
+// keys.
+func configToJson(data []byte, format string) ([]byte, error) {
+	var doc map[string]interface{}
+	switch format {
+	case "json":
+		return data, nil
+	case "yaml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	case "toml":
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	if doc == nil {
+		// an empty YAML file is valid and sets nothing
+		doc = map[string]interface{}{}
+	}
+	return json.Marshal(doc)
+}
+
+// jsonToConfig converts a JSON config to format. Keys are sorted. TOML has no
+// null, so keys set to null are left out of TOML output, which loads the
+// same since null doesn't change a setting either.
+func jsonToConfig(js []byte, format string) ([]byte, error) {
+	// keep numbers as they were written so ints don't turn into floats,
+	// which would no longer load into int settings after a trip through TOML
+	dec := json.NewDecoder(bytes.NewReader(js))
+	dec.UseNumber()
+	var doc map[string]interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+	normalized := normalizeConfigValue(doc, format == "toml").(map[string]interface{})
+
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(normalized); err != nil {
+			return nil, err
+		}
+	case "yaml":
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(normalized); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+	case "toml":
+		if err := toml.NewEncoder(&buf).Encode(normalized); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeConfigValue turns json.Numbers back into int64 or float64 so the
+// encoders write them as numbers, and drops nulls when dropNull is set.
+func normalizeConfigValue(v interface{}, dropNull bool) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			if val == nil && dropNull {
+				continue
+			}
+			out[key] = normalizeConfigValue(val, dropNull)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, val := range v {
+			if val == nil && dropNull {
+				continue
+			}
+			out = append(out, normalizeConfigValue(val, dropNull))
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// convertConfig converts config file data from one format to another.
+func convertConfig(data []byte, from, to string) ([]byte, error) {
+	js, err := configToJson(data, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s data: %w", from, err)
+	}
+	out, err := jsonToConfig(js, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", to, err)
+	}
+	return out, nil
+}
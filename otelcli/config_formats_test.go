@@ -0,0 +1,146 @@
+package otelcli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestConfigFileFormat(t *testing.T) {
+	for _, tc := range []struct {
+		path   string
+		format string
+		want   string
+		err    bool
+	}{
+		{path: "otel-cli.json", want: "json"},
+		{path: "otel-cli.yaml", want: "yaml"},
+		{path: "otel-cli.YML", want: "yaml"},
+		{path: "otel-cli.toml", want: "toml"},
+		{path: "otel-cli.conf", want: "json"},
+		{path: "otel-cli.conf", format: "TOML", want: "toml"},
+		{path: "otel-cli.json", format: "yaml", want: "yaml"},
+		{path: "otel-cli.json", format: "ini", err: true},
+	} {
+		got, err := configFileFormat(tc.path, tc.format)
+		if (err != nil) != tc.err {
+			t.Errorf("%s %q: unexpected error: %v", tc.path, tc.format, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s %q: expected %q, got %q", tc.path, tc.format, tc.want, got)
+		}
+	}
+}
+
+// a config converted to each format loads back into the same config
+func TestConfigFormatsRoundTrip(t *testing.T) {
+	want := DefaultConfig().
+		WithEndpoint("https://otlp.example.com:4318").
+		WithHeaders(map[string]string{"x-api-key": "abc123", "x-tenant": "ops"}).
+		WithAttributes(map[string]string{"deploy.env": "prod"}).
+		WithServiceName("round-trip").
+		WithTimeout("5s").
+		WithInsecure(true).
+		WithOtlpRetries(3).
+		WithAttrValueLengthLimit(128).
+		WithShowSecrets(true) // so the api key isn't masked
+	js, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for _, format := range configFormats {
+		t.Run(format, func(t *testing.T) {
+			data, err := convertConfig(js, "json", format)
+			if err != nil {
+				t.Fatalf("failed to convert to %s: %s", format, err)
+			}
+			path := filepath.Join(dir, "otel-cli."+format)
+			if err := os.WriteFile(path, data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got := DefaultConfig().WithCfgFile(path)
+			if err := got.LoadFile(); err != nil {
+				t.Fatalf("failed to load %s: %s\n%s", format, err, data)
+			}
+			if diff := cmp.Diff(want, got, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+				t.Errorf("config loaded from %s didn't match (-want +got):\n%s", format, diff)
+			}
+
+			// and converting back gives the same JSON config
+			back, err := convertConfig(data, format, "json")
+			if err != nil {
+				t.Fatalf("failed to convert %s back to json: %s", format, err)
+			}
+			again := DefaultConfig()
+			if err := json.Unmarshal(back, &again); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, again, cmpopts.IgnoreUnexported(Config{})); diff != "" {
+				t.Errorf("config converted back from %s didn't match (-want +got):\n%s", format, diff)
+			}
+		})
+	}
+}
+
+func TestLoadFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name   string
+		data   string
+		format string
+	}{
+		{name: "config.yaml", data: "endpoint: localhost:4317\nservice_name: from-yaml\nspan_attributes:\n  team: ops\n"},
+		{name: "config.toml", data: "endpoint = \"localhost:4317\"\nservice_name = \"from-toml\"\n[span_attributes]\nteam = \"ops\"\n"},
+		{name: "config.conf", data: "endpoint: localhost:4317\nservice_name: from-flag\nspan_attributes: {team: ops}\n", format: "yaml"},
+	} {
+		path := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(path, []byte(tc.data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		config := DefaultConfig().WithCfgFile(path).WithCfgFormat(tc.format)
+		if err := config.LoadFile(); err != nil {
+			t.Fatalf("%s: failed to load: %s", tc.name, err)
+		}
+		if config.Endpoint != "localhost:4317" || config.ServiceName == "" || config.Attributes["team"] != "ops" {
+			t.Errorf("%s: config wasn't loaded: %q %q %v", tc.name, config.Endpoint, config.ServiceName, config.Attributes)
+		}
+	}
+
+	// JSON is still the default, so YAML in a .json file is an error
+	path := filepath.Join(dir, "config.json")
+	os.WriteFile(path, []byte("endpoint: localhost:4317\n"), 0644)
+	config := DefaultConfig().WithCfgFile(path)
+	if err := config.LoadFile(); err == nil {
+		t.Error("expected an error loading YAML from a .json file")
+	}
+}
+
+func TestLoadFileRoutes(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		data    string
+		wantErr string
+	}{
+		{data: `{"routes": [{"service": "infra-*", "endpoint": "collector.internal:4317", "protocol": "http/json"}]}`},
+		{data: `{"routes": [{"service": "infra-*"}]}`, wantErr: "route 1 has no endpoint"},
+		{data: `{"routes": [{"endpoint": "a:4317"}, {"endpoint": "b:4317", "protocol": "carrier-pigeon"}]}`, wantErr: `route 2 has invalid protocol "carrier-pigeon"`},
+	} {
+		path := filepath.Join(dir, "config.json")
+		os.WriteFile(path, []byte(tc.data), 0644)
+		config := DefaultConfig().WithCfgFile(path)
+		err := config.LoadFile()
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("unexpected error loading %s: %s", tc.data, err)
+		} else if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("expected an error containing %q loading %s, got %v", tc.wantErr, tc.data, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package otelcli
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadHeaders adds the headers in --otlp-headers-file to the config's
+// headers, then resolves indirect header values from any source: @/path
+// is replaced with the contents of the file, without trailing newlines, and
+// env:VARNAME with the envvar's value. This keeps tokens out of argv, where
+// other users can see them with ps. Headers that are already set win over
+// the ones in the file. Takes a func(string)string that's usually os.Getenv.
+func (c *Config) LoadHeaders(getenv func(string) string) error {
+	headers := make(map[string]string, len(c.Headers))
+	if c.HeadersFile != "" {
+		fileHeaders, err := readHeadersFile(c.HeadersFile)
+		if err != nil {
+			return err
+		}
+		for k, v := range fileHeaders {
+			headers[k] = v
+		}
+	}
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+
+	for k, v := range headers {
+		resolved, err := resolveHeaderValue(v, getenv)
+		if err != nil {
+			return fmt.Errorf("could not get the value of header %q: %w", k, err)
+		}
+		headers[k] = resolved
+	}
+
+	if len(headers) > 0 || c.Headers != nil {
+		c.Headers = headers
+	}
+	return nil
+}
+
+// readHeadersFile reads a --otlp-headers-file, which has a key=value header
+// on each line. Blank lines and lines starting with # are skipped.
+func readHeadersFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read headers file: %w", err)
+	}
+
+	headers := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, fmt.Errorf("headers file '%s' line %d: expected key=value", path, n)
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers, scanner.Err()
+}
+
+// resolveHeaderValue returns the value of a header that's @/path or
+// env:VARNAME, or the value as is.
+func resolveHeaderValue(value string, getenv func(string) string) (string, error) {
+	if path, ok := strings.CutPrefix(value, "@"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	} else if name, ok := strings.CutPrefix(value, "env:"); ok {
+		resolved := getenv(name)
+		if resolved == "" {
+			return "", fmt.Errorf("%s is not set", name)
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
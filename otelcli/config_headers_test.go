@@ -0,0 +1,88 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadHeaders(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	if err := os.WriteFile(secret, []byte("from-secret-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	headersFile := filepath.Join(dir, "headers")
+	data := "# collector auth\nx-api-key = abc123\n\nx-tenant=from-file\nx-token=@" + secret + "\n"
+	if err := os.WriteFile(headersFile, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	getenv := func(name string) string {
+		return map[string]string{"OTLP_TOKEN": "from-env"}[name]
+	}
+
+	for _, tc := range []struct {
+		name    string
+		headers map[string]string
+		file    string
+		want    map[string]string
+		err     string
+	}{
+		{
+			name:    "values as is",
+			headers: map[string]string{"x-tenant": "ops"},
+			want:    map[string]string{"x-tenant": "ops"},
+		},
+		{
+			name:    "indirect values",
+			headers: map[string]string{"x-env": "env:OTLP_TOKEN", "x-file": "@" + secret},
+			want:    map[string]string{"x-env": "from-env", "x-file": "from-secret-file"},
+		},
+		{
+			name:    "headers file, with headers already set winning",
+			headers: map[string]string{"x-tenant": "ops"},
+			file:    headersFile,
+			want:    map[string]string{"x-api-key": "abc123", "x-tenant": "ops", "x-token": "from-secret-file"},
+		},
+		{
+			name:    "unset envvar",
+			headers: map[string]string{"x-env": "env:NOPE"},
+			err:     `header "x-env": NOPE is not set`,
+		},
+		{
+			name:    "missing secret file",
+			headers: map[string]string{"x-file": "@" + filepath.Join(dir, "nope")},
+			err:     "no such file",
+		},
+		{
+			name: "missing headers file",
+			file: filepath.Join(dir, "nope"),
+			err:  "failed to read headers file",
+		},
+	} {
+		config := DefaultConfig().WithHeaders(tc.headers).WithHeadersFile(tc.file)
+		err := config.LoadHeaders(getenv)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s: expected an error with %q, got %v", tc.name, tc.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err)
+		}
+		if diff := cmp.Diff(tc.want, config.Headers); diff != "" {
+			t.Errorf("%s: headers didn't match (-want +got):\n%s", tc.name, diff)
+		}
+	}
+}
+
+func TestReadHeadersFileBadLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "headers")
+	os.WriteFile(path, []byte("x-api-key=abc\nnot a header\n"), 0600)
+	if _, err := readHeadersFile(path); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected an error for line 2, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package otelcli
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+)
+
+// MarshalJSON masks secrets in the config unless --show-secrets is set, so
+// output with the config in it, like otel-cli status, can be pasted into a
+// ticket. See masked for what's masked.
+func (c Config) MarshalJSON() ([]byte, error) {
+	// plainConfig has Config's fields but not its methods, so it doesn't
+	// call back into MarshalJSON
+	type plainConfig Config
+	if !c.ShowSecrets {
+		c = c.masked()
+	}
+	return json.Marshal(plainConfig(c))
+}
+
+// masked returns a copy of the config with secrets replaced by
+// otlpclient.MaskedValue: header values and span and resource attribute
+// values, of every type, whose names look like they carry a secret, e.g.
+// x-api-key, Authorization, or db.password, and passwords in endpoint and
+// proxy URLs. Routes from the config file get the same for their headers
+// and endpoints.
+func (c Config) masked() Config {
+	c.Headers = maskStringMap(c.Headers)
+	c.Attributes = maskStringMap(c.Attributes)
+	c.AttributesStr = maskStringMap(c.AttributesStr)
+	c.AttributesBytes = maskStringMap(c.AttributesBytes)
+	c.AttributesBool = maskStringMap(c.AttributesBool)
+	c.AttributesInt = maskStringMap(c.AttributesInt)
+	c.AttributesFloat = maskStringMap(c.AttributesFloat)
+	c.ResourceAttributes = maskStringMap(c.ResourceAttributes)
+	c.Endpoint = otlpclient.MaskUrlPassword(c.Endpoint)
+	c.TracesEndpoint = otlpclient.MaskUrlPassword(c.TracesEndpoint)
+	c.LogsEndpoint = otlpclient.MaskUrlPassword(c.LogsEndpoint)
+	c.MetricsEndpoint = otlpclient.MaskUrlPassword(c.MetricsEndpoint)
+	c.Proxy = otlpclient.MaskUrlPassword(c.Proxy)
+	if len(c.Routes) > 0 {
+		routes := make([]otlpclient.Route, len(c.Routes))
+		for i, route := range c.Routes {
+			route.Headers = maskStringMap(route.Headers)
+			route.Endpoint = otlpclient.MaskUrlPassword(route.Endpoint)
+			routes[i] = route
+		}
+		c.Routes = routes
+	}
+	return c
+}
+
+// maskStringMap returns a copy of the map with values masked where the key
+// looks like a secret, or the map as is when there's nothing to mask.
+func maskStringMap(in map[string]string) map[string]string {
+	var out map[string]string
+	for k, v := range in {
+		masked := otlpclient.MaskHeaderValue(k, v)
+		if masked == v {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(in))
+			for k, v := range in {
+				out[k] = v
+			}
+		}
+		out[k] = masked
+	}
+	if out == nil {
+		return in
+	}
+	return out
+}
+
+// maskKvList masks the secret values in a key=value,key=value list like
+// --otlp-headers or OTEL_EXPORTER_OTLP_HEADERS take. Anything that doesn't
+// look like a list is returned as is.
+func maskKvList(in string) string {
+	pairs := strings.Split(in, ",")
+	for i, pair := range pairs {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			pairs[i] = k + "=" + otlpclient.MaskHeaderValue(strings.TrimSpace(k), v)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// maskCliArgs returns a copy of the command line with secrets in key=value
+// arguments masked, e.g. the value of --otlp-headers x-api-key=abc, for the
+// cli_args in status diagnostics. Passwords in URLs are masked too.
+func maskCliArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		if flag, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(flag, "--") {
+			out[i] = flag + "=" + otlpclient.MaskUrlPassword(maskKvList(value))
+		} else {
+			out[i] = otlpclient.MaskUrlPassword(maskKvList(arg))
+		}
+	}
+	return out
+}
+
+// maskEnvValue masks an envvar's value for status output: lists of headers
+// and attributes have their secret values masked, envvars whose names look
+// like secrets, e.g. HONEYCOMB_API_KEY, are masked entirely, and passwords in
+// URLs, e.g. in OTEL_EXPORTER_OTLP_ENDPOINT, are masked.
+func maskEnvValue(name, value string) string {
+	if strings.HasSuffix(name, "_HEADERS") || strings.HasSuffix(name, "_ATTRIBUTES") {
+		return maskKvList(value)
+	} else if strings.HasPrefix(name, attrEnvPrefix) {
+		return otlpclient.MaskHeaderValue(strings.TrimPrefix(name, attrEnvPrefix), value)
+	}
+	return otlpclient.MaskUrlPassword(otlpclient.MaskHeaderValue(name, value))
+}
@@ -1,13 +1,24 @@
 package otelcli
 
 import (
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
@@ -21,17 +32,18 @@ func (c Config) NewProtobufSpan() *tracepb.Span {
 	}
 	span.Name = c.SpanName
 	span.Kind = otlpclient.SpanKindStringToInt(c.Kind)
-	span.Attributes = otlpclient.StringMapAttrsToProtobuf(c.Attributes)
+	span.Attributes, span.DroppedAttributesCount = c.parseLimitedAttributes()
+	span.Links = c.ParseLinks()
 
-	now := time.Now()
-	if c.SpanStartTime != "" {
+	now := c.now()
+	if c.SpanStartTime != "" || c.SpanStartFromFile != "" {
 		st := c.ParseSpanStartTime()
 		span.StartTimeUnixNano = uint64(st.UnixNano())
 	} else {
 		span.StartTimeUnixNano = uint64(now.UnixNano())
 	}
 
-	if c.SpanEndTime != "" {
+	if c.SpanEndTime != "" || c.SpanEndFromFile != "" {
 		et := c.ParseSpanEndTime()
 		span.EndTimeUnixNano = uint64(et.UnixNano())
 	} else {
@@ -44,6 +56,9 @@ func (c Config) NewProtobufSpan() *tracepb.Span {
 			span.TraceId = tp.TraceId
 			span.ParentSpanId = tp.SpanId
 		}
+		ts, err := c.ApplyTracestate(tp.Tracestate)
+		c.SoftFailIfErr(err)
+		span.TraceState = ts.Encode()
 	} else {
 		span.TraceId = otlpclient.GetEmptyTraceId()
 		span.SpanId = otlpclient.GetEmptySpanId()
@@ -51,27 +66,270 @@ func (c Config) NewProtobufSpan() *tracepb.Span {
 
 	// --force-trace-id, --force-span-id and --force-parent-span-id let the user set their own trace, span & parent span ids
 	// these work in non-recording mode and will stomp trace id from the traceparent
-	var err error
-	if c.ForceTraceId != "" {
-		span.TraceId, err = parseHex(c.ForceTraceId, 16)
-		c.SoftFailIfErr(err)
+	c.applyForcedIds(span)
+
+	otlpclient.SetSpanStatus(span, c.StatusCode, c.StatusDescription)
+
+	return span
+}
+
+// NormalizeSpanEnums rewrites --kind and --status-code to the names otel-cli
+// uses and records any changes in diagnostics.
+func (c *Config) NormalizeSpanEnums() error {
+	kind, err := otlpclient.NormalizeSpanKind(c.Kind)
+	if err != nil {
+		return fmt.Errorf("invalid --kind: %w", err)
+	}
+	status, err := otlpclient.NormalizeSpanStatus(c.StatusCode)
+	if err != nil {
+		return fmt.Errorf("invalid --status-code: %w", err)
+	}
+
+	normalized := []string{}
+	if c.Kind != "" && kind != c.Kind {
+		normalized = append(normalized, fmt.Sprintf("--kind %q to %q", c.Kind, kind))
+	}
+	if c.StatusCode != "" && status != c.StatusCode {
+		normalized = append(normalized, fmt.Sprintf("--status-code %q to %q", c.StatusCode, status))
+	}
+	if len(normalized) > 0 {
+		c.diag.update(func(d *Diagnostics) { d.Normalized = normalized })
+	}
+
+	c.Kind = kind
+	c.StatusCode = status
+	return nil
+}
+
+// ApplyTracestate applies --tracestate to the parent's tracestate. Keys that
+// are set move to the front in the order given, as the W3C spec asks for,
+// and keys with an empty value are removed.
+func (c Config) ApplyTracestate(ts traceparent.Tracestate) (traceparent.Tracestate, error) {
+	if c.Tracestate == "" {
+		return ts, nil
+	}
+
+	members := strings.Split(c.Tracestate, ",")
+	for i := len(members) - 1; i >= 0; i-- {
+		key, value, ok := strings.Cut(strings.TrimSpace(members[i]), "=")
+		if !ok {
+			return ts, fmt.Errorf("invalid --tracestate %q: expected key=value", members[i])
+		}
+		if value == "" {
+			ts = ts.Delete(key)
+			continue
+		}
+
+		var err error
+		ts, err = ts.Set(key, value)
+		if err != nil {
+			return ts, fmt.Errorf("invalid --tracestate: %w", err)
+		}
+	}
+
+	return ts, nil
+}
+
+// ApplyDurationRules checks the span's duration against --warn-if-longer-than
+// and --error-if-longer-than. Thresholds are recorded as attributes so they
+// can be queried later, and a span over the error threshold gets an error
+// status unless it already has one.
+func (c Config) ApplyDurationRules(span *tracepb.Span) {
+	if c.WarnIfLongerThan == "" && c.ErrorIfLongerThan == "" {
+		return
 	}
-	if c.ForceSpanId != "" {
-		span.SpanId, err = parseHex(c.ForceSpanId, 8)
+
+	took := time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+	attrs := map[string]string{}
+
+	if c.WarnIfLongerThan != "" {
+		warn, err := parseDuration(c.WarnIfLongerThan)
 		c.SoftFailIfErr(err)
+		attrs["otel-cli.warn_if_longer_than"] = warn.String()
+		attrs["otel-cli.warn_threshold_exceeded"] = strconv.FormatBool(took > warn)
 	}
-	if c.ForceParentSpanId != "" {
-		span.ParentSpanId, err = parseHex(c.ForceParentSpanId, 8)
+
+	if c.ErrorIfLongerThan != "" {
+		limit, err := parseDuration(c.ErrorIfLongerThan)
 		c.SoftFailIfErr(err)
+		attrs["otel-cli.error_if_longer_than"] = limit.String()
+
+		if took > limit && span.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+			span.Status = &tracepb.Status{
+				Code:    tracepb.Status_STATUS_CODE_ERROR,
+				Message: fmt.Sprintf("span took %s, longer than the %s limit", took, limit),
+			}
+		}
 	}
 
-	otlpclient.SetSpanStatus(span, c.StatusCode, c.StatusDescription)
+	span.Attributes = append(span.Attributes, otlpclient.StringMapAttrsToProtobuf(attrs)...)
+}
 
-	return span
+// ParseAttributes returns --attrs, --attr-bytes, --attrs-file, and the typed
+// attribute flags as protobuf attributes. Fails if any of the values aren't
+// valid for their flag, e.g. an --attr-bytes value that isn't base64.
+func (c Config) ParseAttributes() []*commonpb.KeyValue {
+	attrs, _ := c.parseLimitedAttributes()
+	return attrs
+}
+
+// droppedAttributesKey is the attribute that lists the keys dropped to stay
+// under --attr-count-limit.
+const droppedAttributesKey = "otel-cli.dropped_attributes"
+
+// parseLimitedAttributes parses the attribute flags, sorted by key so
+// which ones are kept is the same every time, and holds them to
+// --attr-count-limit and --attr-value-length-limit. Past the count limit, the
+// attributes that don't fit are dropped and their keys are listed in one
+// otel-cli.dropped_attributes attribute, which takes the last slot. Returns
+// the attributes and how many were dropped, and records the counts in the
+// diagnostics for otel-cli status.
+func (c Config) parseLimitedAttributes() ([]*commonpb.KeyValue, uint32) {
+	attrs, err := c.parseAttributeSources()
+	c.SoftFailIfErr(err)
+	slices.SortFunc(attrs, func(a, b *commonpb.KeyValue) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+
+	var dropped []string
+	if limit := c.AttrCountLimit; limit > 0 && len(attrs) > limit {
+		for _, attr := range attrs[limit-1:] {
+			dropped = append(dropped, attr.Key)
+		}
+		attrs = append(attrs[:limit-1], &commonpb.KeyValue{
+			Key:   droppedAttributesKey,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Join(dropped, ",")}},
+		})
+	}
+
+	truncated := 0
+	if limit := c.AttrValueLengthLimit; limit > 0 {
+		for _, attr := range attrs {
+			if truncateAnyValue(attr.Value, limit) {
+				truncated++
+			}
+		}
+	}
+
+	if len(dropped) > 0 || truncated > 0 {
+		c.diag.update(func(d *Diagnostics) {
+			d.AttributesDropped = len(dropped)
+			d.AttributesTruncated = truncated
+		})
+	}
+
+	return attrs, uint32(len(dropped))
+}
+
+// truncateAnyValue shortens string values to limit characters and bytes
+// values to limit bytes, returning true when the value was truncated.
+func truncateAnyValue(value *commonpb.AnyValue, limit int) bool {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		if utf8.RuneCountInString(v.StringValue) > limit {
+			runes := []rune(v.StringValue)
+			v.StringValue = string(runes[:limit])
+			return true
+		}
+	case *commonpb.AnyValue_BytesValue:
+		if len(v.BytesValue) > limit {
+			v.BytesValue = v.BytesValue[:limit]
+			return true
+		}
+	}
+	return false
+}
+
+// attrsToProtobuf converts --attrs and --attr-bytes style maps to protobuf
+// attributes. Span, event, and log attributes all go through here so they're
+// typed the same way everywhere.
+func attrsToProtobuf(attrs, attrBytes map[string]string) ([]*commonpb.KeyValue, error) {
+	out := otlpclient.StringMapAttrsToProtobuf(attrs)
+	bytesAttrs, err := parseAttrBytes(attrBytes)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, otlpclient.BytesMapAttrsToProtobuf(bytesAttrs)...), nil
+}
+
+// parseAttrBytes decodes the base64 values of --attr-bytes, padded or not.
+func parseAttrBytes(in map[string]string) (map[string][]byte, error) {
+	out := make(map[string][]byte, len(in))
+	for k, v := range in {
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			data, err = base64.RawStdEncoding.DecodeString(v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid --attr-bytes value for %q: %w", k, err)
+		}
+		out[k] = data
+	}
+	return out, nil
+}
+
+// ParseLinks parses the --link values into span links. Fails if any of them
+// can't be parsed.
+func (c Config) ParseLinks() []*tracepb.Span_Link {
+	links, err := parseSpanLinks(c.Links)
+	c.SoftFailIfErr(err)
+	return links
+}
+
+// parseSpanLinks parses a list of links, stopping at the first bad one.
+func parseSpanLinks(in []string) ([]*tracepb.Span_Link, error) {
+	links := []*tracepb.Span_Link{}
+	for _, l := range in {
+		link, err := parseSpanLink(l)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// parseSpanLink parses a link in the form traceparent[:key=value,key=value].
+func parseSpanLink(in string) (*tracepb.Span_Link, error) {
+	tpString, attrString, _ := strings.Cut(in, ":")
+	tp, err := traceparent.Parse(tpString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %w", err)
+	}
+
+	attrs := map[string]string{}
+	if attrString != "" {
+		attrs, err = parseCkvStringMap(attrString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attributes on link %q: %w", in, err)
+		}
+	}
+
+	return &tracepb.Span_Link{
+		TraceId:    tp.TraceId,
+		SpanId:     tp.SpanId,
+		Attributes: otlpclient.StringMapAttrsToProtobuf(attrs),
+	}, nil
+}
+
+// TraceparentParseMode returns the traceparent parse mode for --tp-strict.
+func (c Config) TraceparentParseMode() traceparent.ParseMode {
+	if c.TraceparentStrict {
+		return traceparent.Strict
+	}
+	return traceparent.Lenient
 }
 
-// LoadTraceparent follows otel-cli's loading rules, start with envvar then file.
-// If both are set, the file will override env.
+// GetPropagationFormat returns the --propagation-format. Fails if it isn't
+// one otel-cli supports.
+func (c Config) GetPropagationFormat() traceparent.Format {
+	f, err := traceparent.ParseFormat(c.PropagationFormat)
+	c.SoftFailIfErr(err)
+	return f
+}
+
+// LoadTraceparent follows otel-cli's loading rules, start with envvar then file,
+// then the last entry of the --chain-file. Later sources override earlier ones.
 // When in non-recording mode, the previous traceparent will be returned if it's
 // available, otherwise, a zero-valued traceparent is returned.
 func (c Config) LoadTraceparent() traceparent.Traceparent {
@@ -83,26 +341,85 @@ func (c Config) LoadTraceparent() traceparent.Traceparent {
 		Initialized: true,
 	}
 
+	// a traceparent that's present but invalid is ignored, the error is kept
+	// so --tp-required can say what was wrong with it
+	var parseErr *traceparent.ParseError
+
 	if !c.TraceparentIgnoreEnv {
 		var err error
-		tp, err = traceparent.LoadFromEnv()
+		tp, err = traceparent.LoadFromEnvWithFormat(c.GetPropagationFormat(), c.TraceparentParseMode())
 		if err != nil {
-			Diag.Error = err.Error()
+			c.diag.setError(err)
+			if errors.As(err, &parseErr) {
+				c.SoftLog("ignoring %s envvar: %s", c.GetPropagationFormat().EnvVars()[0], err)
+			}
 		}
 	}
 
 	if c.TraceparentCarrierFile != "" {
-		fileTp, err := traceparent.LoadFromFile(c.TraceparentCarrierFile)
+		fileTp, err := traceparent.LoadFromFileWithFormat(c.TraceparentCarrierFile, c.GetPropagationFormat(), c.TraceparentParseMode())
 		if err != nil {
-			Diag.Error = err.Error()
+			c.diag.setError(err)
+			if errors.As(err, &parseErr) {
+				c.SoftLog("ignoring traceparent carrier file: %s", err)
+			}
 		} else if fileTp.Initialized {
 			tp = fileTp
 		}
 	}
 
+	if c.ChainFile != "" {
+		chainTp, err := loadChainFile(c.ChainFile, c.TraceparentParseMode())
+		if err != nil {
+			c.diag.setError(err)
+			c.SoftLog("ignoring chain file: %s", err)
+		} else if chainTp.Initialized {
+			tp = chainTp
+		}
+	}
+
+	if c.TraceparentStdin {
+		stdinTp, err := c.readStdinTraceparent()
+		if err != nil {
+			c.diag.setError(err)
+			if errors.As(err, &parseErr) {
+				c.SoftLog("ignoring traceparent from stdin: %s", err)
+			}
+		} else if stdinTp.Initialized {
+			tp = stdinTp
+		}
+	}
+
+	if c.TraceparentHttpStdin {
+		req, err := c.readStdinHttpRequest()
+		if err == nil {
+			var reqTp traceparent.Traceparent
+			if reqTp, err = req.traceparent(c.GetPropagationFormat(), c.TraceparentParseMode()); err == nil && reqTp.Initialized {
+				tp = reqTp
+			}
+		}
+		if err != nil {
+			c.diag.setError(err)
+			c.SoftLog("ignoring traceparent from the HTTP request on stdin: %s", err)
+		}
+	}
+
+	if c.Traceparent != "" {
+		flagTp, err := traceparent.ParseWithMode(c.Traceparent, c.TraceparentParseMode())
+		if err != nil {
+			c.diag.setError(err)
+			errors.As(err, &parseErr)
+			c.SoftLog("ignoring --tp: %s", err)
+		} else {
+			tp = flagTp
+		}
+	}
+
 	if c.TraceparentRequired {
 		if tp.Initialized {
 			return tp
+		} else if parseErr != nil {
+			c.SoftFail("a traceparent is required by --tp-required but the one found is invalid: %s", parseErr)
 		} else {
 			c.SoftFail("failed to find a valid traceparent carrier in either environment for file '%s' while it's required by --tp-required", c.TraceparentCarrierFile)
 		}
@@ -116,19 +433,231 @@ func (c Config) LoadTraceparent() traceparent.Traceparent {
 func (c Config) PropagateTraceparent(span *tracepb.Span, target io.Writer) {
 	var tp traceparent.Traceparent
 	if c.GetIsRecording() {
-		tp = otlpclient.TraceparentFromProtobufSpan(span, c.GetIsRecording())
+		tp = otlpclient.TraceparentFromProtobufSpan(span, c.GetIsSampled(span.TraceId))
 	} else {
 		// when in non-recording mode, and there is a TP available, propagate that
 		tp = c.LoadTraceparent()
 	}
 
 	if c.TraceparentCarrierFile != "" {
-		err := tp.SaveToFile(c.TraceparentCarrierFile, c.TraceparentPrintExport)
+		err := tp.SaveToFileWithFormat(c.TraceparentCarrierFile, c.TraceparentPrintExport, c.GetPropagationFormat())
 		c.SoftFailIfErr(err)
 	}
 
-	if c.TraceparentPrint {
-		tp.Fprint(target, c.TraceparentPrintExport)
+	// only spans that were actually made go in the chain
+	if c.ChainFile != "" && c.GetIsRecording() {
+		err := appendChainFile(c.ChainFile, tp, span.Name)
+		c.SoftFailIfErr(err)
+	}
+
+	if c.TraceparentStdout {
+		// stdout only gets the bare traceparent, or the bare envvars for
+		// other formats, --tp-print can still go to another fd or file
+		var err error
+		if f := c.GetPropagationFormat(); f == traceparent.W3C {
+			_, err = fmt.Fprintln(target, tp.Encode())
+		} else {
+			_, err = fmt.Fprintln(target, strings.Join(f.Env(tp), "\n"))
+		}
+		c.printFailedIfErr(err)
+		if c.TraceparentPrintFd > 0 || c.TraceparentPrintFile != "" {
+			c.PrintTraceparent(tp, target)
+		}
+	} else if c.GetTraceparentPrint() {
+		c.PrintTraceparent(tp, target)
+	}
+}
+
+// printUrlBuiltin is a trace UI --print-url knows how to link to.
+type printUrlBuiltin struct {
+	// base is the UI's URL when it's usually run locally, leave it empty
+	// when the user has to give one
+	base string
+	// example is shown in the error when there's no base URL
+	example string
+	// path is the --print-url template appended to the base URL
+	path string
+}
+
+// printUrlTemplates are the built-ins for --print-url NAME[=BASE_URL].
+var printUrlTemplates = map[string]printUrlBuiltin{
+	"jaeger": {
+		base: "http://localhost:16686",
+		path: "/trace/{{trace_id}}",
+	},
+	// Grafana Explore with a Tempo datasource named tempo, left is the
+	// URL-encoded {"datasource":"tempo","queries":[{"refId":"A",
+	// "queryType":"traceql","query":"TRACE_ID"}],"range":{"from":"MS","to":"MS"}}
+	"grafana": {
+		base: "http://localhost:3000",
+		path: "/explore?left=%7B%22datasource%22:%22tempo%22,%22queries%22:%5B%7B%22refId%22:%22A%22," +
+			"%22queryType%22:%22traceql%22,%22query%22:%22{{trace_id}}%22%7D%5D," +
+			"%22range%22:%7B%22from%22:%22{{start_ms}}%22,%22to%22:%22{{end_ms}}%22%7D%7D",
+	},
+	// the dataset is the service name, and the times narrow down the search
+	"honeycomb": {
+		example: "https://ui.honeycomb.io/TEAM/environments/ENVIRONMENT",
+		path:    "/datasets/{{service_name}}/trace?trace_id={{trace_id}}&span={{span_id}}&trace_start_ts={{start_unix}}&trace_end_ts={{end_unix}}",
+	},
+}
+
+func init() {
+	// Tempo doesn't have a UI of its own, it's browsed with Grafana
+	printUrlTemplates["tempo"] = printUrlTemplates["grafana"]
+}
+
+// printUrlNames returns the --print-url built-in names, sorted, for help
+// and completion.
+func printUrlNames() []string {
+	names := make([]string, 0, len(printUrlTemplates))
+	for name := range printUrlTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetPrintUrlTemplate returns the template for --print-url, which is either
+// a template itself or the name of a built-in with an optional base URL,
+// e.g. jaeger=https://jaeger.example.com.
+func (c Config) GetPrintUrlTemplate() (string, error) {
+	name, base, _ := strings.Cut(c.PrintUrl, "=")
+	if builtin, ok := printUrlTemplates[strings.ToLower(name)]; ok {
+		if base == "" {
+			base = builtin.base
+		}
+		if base == "" {
+			return "", fmt.Errorf("--print-url %s needs the UI's URL, e.g. %s=%s", name, name, builtin.example)
+		}
+		return strings.TrimSuffix(base, "/") + builtin.path, nil
+	}
+
+	if !strings.Contains(c.PrintUrl, "{{") {
+		return "", fmt.Errorf("invalid --print-url %q, expected a template using {{trace_id}} or one of %s", c.PrintUrl, strings.Join(printUrlNames(), ", "))
+	}
+	return c.PrintUrl, nil
+}
+
+// expandPrintUrl fills in the template with the span's ids like
+// expandTraceTemplate, along with {{service_name}}, and the span's start and
+// end as {{start_unix}} and {{end_unix}} seconds or {{start_ms}} and
+// {{end_ms}} milliseconds. The end is rounded up so the range covers the span.
+func expandPrintUrl(template string, span *tracepb.Span, sampled bool, serviceName string) string {
+	tp := otlpclient.TraceparentFromProtobufSpan(span, sampled)
+	start := time.Unix(0, int64(span.StartTimeUnixNano))
+	end := time.Unix(0, int64(span.EndTimeUnixNano))
+	return strings.NewReplacer(
+		"{{service_name}}", url.PathEscape(serviceName),
+		"{{start_unix}}", strconv.FormatInt(start.Unix(), 10),
+		"{{end_unix}}", strconv.FormatInt(end.Add(time.Second-1).Unix(), 10),
+		"{{start_ms}}", strconv.FormatInt(start.UnixMilli(), 10),
+		"{{end_ms}}", strconv.FormatInt(end.Add(time.Millisecond-1).UnixMilli(), 10),
+	).Replace(expandTraceTemplate(template, tp))
+}
+
+// PrintSpanUrl writes a link to the span's trace to target when --print-url
+// is set. Spans that weren't sent, because otel-cli isn't recording or the
+// span wasn't sampled, don't get a link. Like --print-json, a bad template
+// is logged and only fails the command when --fail is set.
+func (c Config) PrintSpanUrl(span *tracepb.Span, target io.Writer) {
+	if c.PrintUrl == "" || !c.GetIsRecording() || !c.GetIsSampled(span.TraceId) {
+		return
+	}
+
+	template, err := c.GetPrintUrlTemplate()
+	if err == nil {
+		_, err = fmt.Fprintln(target, expandPrintUrl(template, span, true, c.ServiceName))
+	}
+
+	if err != nil {
+		c.SoftLog("failed to print trace url: %s", err)
+		if c.Fail {
+			c.exit(1)
+		}
+	}
+}
+
+// stdinCache keeps what --tp-stdin and --tp-http-stdin read, since stdin
+// can only be read once. Like diagnostics, it's per invocation and shared by
+// copies of the Config.
+type stdinCache struct {
+	tpOnce sync.Once
+	tp     traceparent.Traceparent
+	tpErr  error
+
+	httpOnce sync.Once
+	http     httpStdinRequest
+	httpErr  error
+}
+
+// stdinCache returns the invocation's stdin cache. Configs that didn't come
+// from execute, e.g. in tests, get an empty one each time.
+func (c Config) stdinCache() *stdinCache {
+	if c.stdin == nil {
+		return &stdinCache{}
+	}
+	return c.stdin
+}
+
+// readStdinTraceparent reads the traceparent for --tp-stdin the first time
+// it's called and returns the same result after that.
+func (c Config) readStdinTraceparent() (traceparent.Traceparent, error) {
+	sc := c.stdinCache()
+	sc.tpOnce.Do(func() {
+		sc.tp, sc.tpErr = traceparent.LoadFromReaderWithFormat(os.Stdin, c.GetPropagationFormat(), c.TraceparentParseMode())
+	})
+	return sc.tp, sc.tpErr
+}
+
+// GetTraceparentPrint returns true if the traceparent should be printed,
+// either because of --tp-print or because it has somewhere else to go.
+func (c Config) GetTraceparentPrint() bool {
+	return c.TraceparentPrint || c.TraceparentPrintFd > 0 || c.TraceparentPrintFile != ""
+}
+
+// PrintTraceparent writes the traceparent to target, or to --tp-print-fd or
+// --tp-print-file when set, so wrapped commands can keep stdout to themselves.
+// Spans have already been sent by the time this is called, so a closed
+// stdout, e.g. when piped to `head -1`, is logged and only fails the command
+// when --fail is set.
+func (c Config) PrintTraceparent(tp traceparent.Traceparent, target io.Writer) {
+	var err error
+	if c.TraceparentPrintFile != "" {
+		var file *os.File
+		file, err = os.OpenFile(c.TraceparentPrintFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err == nil {
+			defer file.Close()
+			target = file
+		}
+	} else if c.TraceparentPrintFd < 0 {
+		err = fmt.Errorf("invalid --tp-print-fd %d", c.TraceparentPrintFd)
+	} else if c.TraceparentPrintFd == 1 {
+		target = c.getStdout()
+	} else if c.TraceparentPrintFd == 2 {
+		target = c.getStderr()
+	} else if c.TraceparentPrintFd > 0 {
+		var file *os.File
+		file, err = openPrintFd(c.TraceparentPrintFd)
+		if err == nil {
+			defer file.Close()
+			target = file
+		}
+	}
+
+	if err == nil {
+		err = tp.FprintFormat(target, c.TraceparentPrintExport, c.GetPropagationFormat())
+	}
+	c.printFailedIfErr(err)
+}
+
+// printFailedIfErr logs a failure to print the traceparent, only exiting
+// when --fail is set.
+func (c Config) printFailedIfErr(err error) {
+	if err != nil {
+		c.SoftLog("failed to print traceparent: %s", err)
+		if c.Fail {
+			c.exit(1)
+		}
 	}
 }
 
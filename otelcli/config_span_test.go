@@ -2,12 +2,20 @@ package otelcli
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/google/go-cmp/cmp"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
 func TestPropagateTraceparent(t *testing.T) {
@@ -44,6 +52,69 @@ func TestPropagateTraceparent(t *testing.T) {
 	}
 }
 
+func TestPropagateTraceparentClosedPipe(t *testing.T) {
+	config := DefaultConfig().
+		WithTraceparentCarrierFile("").
+		WithTraceparentPrint(true)
+
+	span := otlpclient.NewProtobufSpan()
+	span.TraceId = otlpclient.GenerateTraceId()
+	span.SpanId = otlpclient.GenerateSpanId()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %s", err)
+	}
+	r.Close() // like `otel-cli span --tp-print | head -0`
+	defer w.Close()
+
+	// must return instead of panicking or exiting since --fail isn't set
+	config.PropagateTraceparent(span, w)
+}
+
+func TestPropagateTraceparentElsewhere(t *testing.T) {
+	// not recording, so the traceparent from the environment is propagated
+	t.Setenv("TRACEPARENT", "00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01")
+	span := otlpclient.NewProtobufSpan()
+	expected := "# trace id: 3433d5ae39bdfee397f44be5146867b3\n#  span id: 8a5518f1e5c54d0a\nTRACEPARENT=00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01\n"
+
+	// --tp-print-file replaces the file's contents and stdout gets nothing
+	file := filepath.Join(t.TempDir(), "tp")
+	os.WriteFile(file, []byte("old traceparent\n"), 0600)
+	stdout := bytes.NewBuffer([]byte{})
+	DefaultConfig().WithTraceparentPrintFile(file).PropagateTraceparent(span, stdout)
+	if data, _ := os.ReadFile(file); string(data) != expected {
+		t.Errorf("expected %q in --tp-print-file but got %q", expected, string(data))
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing on stdout but got %q", stdout.String())
+	}
+
+	// --tp-print-fd writes to an inherited descriptor
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %s", err)
+	}
+	defer r.Close()
+	DefaultConfig().WithTraceparentPrintFd(int(w.Fd())).PropagateTraceparent(span, stdout)
+	w.Close()
+	if data, _ := io.ReadAll(r); string(data) != expected {
+		t.Errorf("expected %q on --tp-print-fd but got %q", expected, string(data))
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected nothing on stdout but got %q", stdout.String())
+	}
+
+	// --tp-stdout prints just the traceparent, the rest can still go elsewhere
+	DefaultConfig().WithTraceparentStdout(true).WithTraceparentPrintFile(file).PropagateTraceparent(span, stdout)
+	if stdout.String() != "00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01\n" {
+		t.Errorf("expected a bare traceparent on stdout but got %q", stdout.String())
+	}
+	if data, _ := os.ReadFile(file); string(data) != expected {
+		t.Errorf("expected %q in --tp-print-file but got %q", expected, string(data))
+	}
+}
+
 func TestNewProtobufSpanWithConfig(t *testing.T) {
 	c := DefaultConfig().WithSpanName("test span 123")
 	span := c.NewProtobufSpan()
@@ -52,3 +123,245 @@ func TestNewProtobufSpanWithConfig(t *testing.T) {
 		t.Error("span event attributes must not be nil")
 	}
 }
+
+func TestApplyDurationRules(t *testing.T) {
+	newSpan := func(took time.Duration) *tracepb.Span {
+		span := otlpclient.NewProtobufSpan()
+		span.StartTimeUnixNano = 1617739561000000000
+		span.EndTimeUnixNano = span.StartTimeUnixNano + uint64(took)
+		return span
+	}
+
+	// nothing configured, nothing changes
+	span := newSpan(time.Hour)
+	DefaultConfig().ApplyDurationRules(span)
+	if len(span.Attributes) != 0 || span.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Error("span was modified without any duration rules configured")
+	}
+
+	c := DefaultConfig().WithWarnIfLongerThan("1m").WithErrorIfLongerThan("5m")
+
+	span = newSpan(2 * time.Minute)
+	c.ApplyDurationRules(span)
+	attrs := otlpclient.SpanAttributesToStringMap(span)
+	if attrs["otel-cli.warn_threshold_exceeded"] != "true" {
+		t.Errorf("expected warn threshold to be exceeded, got attributes %v", attrs)
+	}
+	if span.Status.GetCode() != tracepb.Status_STATUS_CODE_UNSET {
+		t.Errorf("span under the error threshold got status %s", span.Status.GetCode())
+	}
+
+	span = newSpan(6 * time.Minute)
+	c.ApplyDurationRules(span)
+	if span.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("span over the error threshold got status %s", span.Status.GetCode())
+	}
+
+	// an existing error status is left alone
+	span = newSpan(6 * time.Minute)
+	span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "exit 1"}
+	c.ApplyDurationRules(span)
+	if span.Status.GetMessage() != "exit 1" {
+		t.Errorf("existing error status was overwritten with %q", span.Status.GetMessage())
+	}
+}
+
+func TestParseSpanLink(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		traceId string
+		spanId  string
+		attrs   map[string]string
+		wantErr bool
+	}{
+		{
+			in:      "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+			traceId: "f61fc53f926e07a9c3893b1a722e1b65",
+			spanId:  "7a2d6a804f3de137",
+			attrs:   map[string]string{},
+		},
+		{
+			in:      `00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-00:a=b,"c=d,e"`,
+			traceId: "f61fc53f926e07a9c3893b1a722e1b65",
+			spanId:  "7a2d6a804f3de137",
+			attrs:   map[string]string{"a": "b", "c": "d,e"},
+		},
+		{in: "f61fc53f926e07a9c3893b1a722e1b65", wantErr: true},
+		{in: "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01:nope", wantErr: true},
+	} {
+		link, err := parseSpanLink(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("expected an error parsing %q", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", tc.in, err)
+			continue
+		}
+
+		if got := hex.EncodeToString(link.TraceId); got != tc.traceId {
+			t.Errorf("got trace id %q, expected %q", got, tc.traceId)
+		}
+		if got := hex.EncodeToString(link.SpanId); got != tc.spanId {
+			t.Errorf("got span id %q, expected %q", got, tc.spanId)
+		}
+		gotAttrs := map[string]string{}
+		for _, kv := range link.Attributes {
+			gotAttrs[kv.Key] = kv.Value.GetStringValue()
+		}
+		if diff := cmp.Diff(tc.attrs, gotAttrs); diff != "" {
+			t.Errorf("link attributes mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestApplyTracestate(t *testing.T) {
+	inherited, _ := traceparent.ParseTracestate("congo=t61rcWkgMzE,rojo=00f067aa0ba902b7")
+
+	for _, tc := range []struct {
+		flag    string
+		want    string
+		wantErr bool
+	}{
+		{flag: "", want: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		{flag: "rojo=updated", want: "rojo=updated,congo=t61rcWkgMzE"},
+		// keys given together keep their order at the front
+		{flag: "me=1,you=2", want: "me=1,you=2,congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		// an empty value removes the key
+		{flag: "congo=", want: "rojo=00f067aa0ba902b7"},
+		{flag: "novalue", wantErr: true},
+		{flag: "BAD=x", wantErr: true},
+	} {
+		ts, err := DefaultConfig().WithTracestate(tc.flag).ApplyTracestate(inherited)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("expected an error for %q", tc.flag)
+			}
+		} else if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.flag, err)
+		} else if ts.Encode() != tc.want {
+			t.Errorf("expected %q for %q but got %q", tc.want, tc.flag, ts.Encode())
+		}
+	}
+}
+
+func TestParseAttrBytes(t *testing.T) {
+	got, err := parseAttrBytes(map[string]string{"padded": "AAECAw==", "raw": "AAECAw"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string][]byte{"padded": {0, 1, 2, 3}, "raw": {0, 1, 2, 3}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("attr bytes did not match (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseAttrBytes(map[string]string{"bad": "not base64!"}); err == nil {
+		t.Error("expected an error for invalid base64")
+	}
+}
+
+func TestParseLimitedAttributes(t *testing.T) {
+	ctx, diag := withDiagnostics(context.Background())
+	config := DefaultConfig().
+		WithAttributes(map[string]string{"d": "four", "a": "caf\u00e9 \u2615 bar", "c": "three", "b": "two", "e": "five"}).
+		WithAttrCountLimit(3).
+		WithAttrValueLengthLimit(6)
+	config.diag = diag
+
+	attrs, dropped := config.parseLimitedAttributes()
+	got := make([]string, len(attrs))
+	for i, attr := range attrs {
+		got[i] = attr.Key + "=" + attr.Value.GetStringValue()
+	}
+	// sorted by key, the last slot lists what didn't fit, truncated by rune
+	want := []string{"a=caf\u00e9 \u2615", "b=two", "otel-cli.dropped_attributes=c,d,e"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("attributes did not match (-want +got):\n%s", diff)
+	}
+	if dropped != 3 {
+		t.Errorf("expected 3 dropped attributes, got %d", dropped)
+	}
+
+	d := GetDiagnostics(ctx)
+	if d.AttributesDropped != 3 || d.AttributesTruncated != 1 {
+		t.Errorf("expected 3 dropped and 1 truncated in diagnostics, got %d and %d", d.AttributesDropped, d.AttributesTruncated)
+	}
+
+	// no limits leaves everything alone
+	attrs, dropped = DefaultConfig().WithAttributes(map[string]string{"b": "2", "a": "1"}).parseLimitedAttributes()
+	if len(attrs) != 2 || dropped != 0 || attrs[0].Key != "a" {
+		t.Errorf("expected both attributes in key order, got %v", attrs)
+	}
+}
+
+func TestPrintSpanUrl(t *testing.T) {
+	span := &tracepb.Span{
+		TraceId:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:            []byte{1, 1, 1, 1, 1, 1, 1, 1},
+		StartTimeUnixNano: 1700000000000000000,
+		EndTimeUnixNano:   1700000001500000000,
+	}
+	recording := DefaultConfig().WithEndpoint("localhost:4317").WithServiceName("nightly build")
+
+	for _, tc := range []struct {
+		config Config
+		want   string
+	}{
+		{
+			config: recording,
+			want:   "",
+		},
+		{
+			// not recording, so there's no trace to link to
+			config: DefaultConfig().WithPrintUrl("jaeger"),
+			want:   "",
+		},
+		{
+			config: recording.WithPrintUrl("https://jaeger.example.com/trace/{{trace_id}}?uiFind={{span_id}}"),
+			want:   "https://jaeger.example.com/trace/0102030405060708090a0b0c0d0e0f10?uiFind=0101010101010101\n",
+		},
+		{
+			config: recording.WithPrintUrl("jaeger"),
+			want:   "http://localhost:16686/trace/0102030405060708090a0b0c0d0e0f10\n",
+		},
+		{
+			config: recording.WithPrintUrl("Jaeger=https://jaeger.example.com/"),
+			want:   "https://jaeger.example.com/trace/0102030405060708090a0b0c0d0e0f10\n",
+		},
+		{
+			config: recording.WithPrintUrl("tempo=https://grafana.example.com"),
+			want: "https://grafana.example.com/explore?left=%7B%22datasource%22:%22tempo%22,%22queries%22:%5B%7B%22refId%22:%22A%22," +
+				"%22queryType%22:%22traceql%22,%22query%22:%220102030405060708090a0b0c0d0e0f10%22%7D%5D," +
+				"%22range%22:%7B%22from%22:%221700000000000%22,%22to%22:%221700000001500%22%7D%7D\n",
+		},
+		{
+			config: recording.WithPrintUrl("honeycomb=https://ui.honeycomb.io/ops/environments/prod"),
+			want: "https://ui.honeycomb.io/ops/environments/prod/datasets/nightly%20build/trace?trace_id=0102030405060708090a0b0c0d0e0f10" +
+				"&span=0101010101010101&trace_start_ts=1700000000&trace_end_ts=1700000002\n",
+		},
+	} {
+		var out bytes.Buffer
+		tc.config.PrintSpanUrl(span, &out)
+		if out.String() != tc.want {
+			t.Errorf("--print-url %q: expected %q, got %q", tc.config.PrintUrl, tc.want, out.String())
+		}
+	}
+}
+
+func TestGetPrintUrlTemplateErrors(t *testing.T) {
+	for _, tc := range []struct {
+		printUrl string
+		want     string
+	}{
+		{printUrl: "honeycomb", want: "needs the UI's URL"},
+		{printUrl: "zipkin", want: "expected a template"},
+		{printUrl: "https://jaeger.example.com/trace/", want: "expected a template"},
+	} {
+		_, err := DefaultConfig().WithPrintUrl(tc.printUrl).GetPrintUrlTemplate()
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("--print-url %q: expected an error with %q, got %v", tc.printUrl, tc.want, err)
+		}
+	}
+}
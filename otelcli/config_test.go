@@ -1,6 +1,9 @@
 package otelcli
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -15,12 +18,12 @@ func TestConfig_ToStringMap(t *testing.T) {
 
 	fsm := c.ToStringMap()
 
-	if _, ok := fsm["headers"]; !ok {
-		t.Errorf("missing key 'headers' in returned string map: %q", fsm)
+	if _, ok := fsm["otlp_headers"]; !ok {
+		t.Errorf("missing key 'otlp_headers' in returned string map: %q", fsm)
 		t.Fail()
 	}
 
-	if fsm["headers"] != "123test=deadbeefcafe" {
+	if fsm["otlp_headers"] != "123test=deadbeefcafe" {
 		t.Errorf("expected header value not found in flattened string map: %q", fsm)
 		t.Fail()
 	}
@@ -36,6 +39,99 @@ func TestIsRecording(t *testing.T) {
 	if !c.GetIsRecording() {
 		t.Fail()
 	}
+
+	// --recording=false wins over the endpoint and --dry-run
+	if c.WithDryRun(true).WithRecording("false").GetIsRecording() {
+		t.Error("expected --recording=false to turn recording off")
+	}
+	if !c.WithRecording("require").GetIsRecording() {
+		t.Error("expected --recording=require to record with an endpoint")
+	}
+
+	for _, tc := range []struct {
+		config Config
+		valid  bool
+	}{
+		{c.WithRecording("require"), true},
+		{DefaultConfig().WithRecording("require"), false},
+		{DefaultConfig().WithRecording("require").WithDryRun(true), true},
+		{DefaultConfig().WithRecording("false"), true},
+		{DefaultConfig().WithRecording("yes"), false},
+	} {
+		if err := tc.config.CheckRecording(); (err == nil) != tc.valid {
+			t.Errorf("expected --recording %q valid to be %t, got %v", tc.config.Recording, tc.valid, err)
+		}
+	}
+}
+
+func TestTimeoutContext(t *testing.T) {
+	ctx, diag := withDiagnostics(context.Background())
+	config := DefaultConfig().WithTimeout("2s")
+	config.diag = diag
+	config.startupTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	// the budget starts at startup, excluded time moves the deadline out
+	tctx, cancel := config.timeoutContext(ctx, 3*time.Second)
+	defer cancel()
+	deadline, ok := tctx.Deadline()
+	want := time.Date(2024, 1, 2, 3, 4, 10, 0, time.UTC)
+	if !ok || !deadline.Equal(want) {
+		t.Errorf("expected deadline %s, got %s", want, deadline)
+	}
+
+	got := GetDiagnostics(ctx)
+	if got.StartupTime != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected startup time in diagnostics: %q", got.StartupTime)
+	}
+	if got.TimeoutDeadline != "2024-01-02T03:04:10Z" {
+		t.Errorf("unexpected deadline in diagnostics: %q", got.TimeoutDeadline)
+	}
+	if got.TimeoutExcludedMs != 3000 {
+		t.Errorf("expected 3000ms excluded in diagnostics, got %d", got.TimeoutExcludedMs)
+	}
+
+	// time spent waiting on stdin moves it out too
+	diag.update(func(d *Diagnostics) { d.stdinWait = time.Second })
+	tctx, cancel = config.timeoutContext(ctx, 3*time.Second)
+	defer cancel()
+	deadline, _ = tctx.Deadline()
+	if want := want.Add(time.Second); !deadline.Equal(want) {
+		t.Errorf("expected deadline %s with stdin wait, got %s", want, deadline)
+	}
+}
+
+func TestGetResourceDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want []string
+	}{
+		{in: "", want: nil},
+		{in: "none", want: []string{"none"}},
+		{in: "host, os,container", want: []string{"host", "os", "container"}},
+	} {
+		got := DefaultConfig().WithResourceDetectors(tc.in).GetResourceDetectors()
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("detectors for %q did not match (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
+
+func TestCheckResourceDetectors(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		valid bool
+	}{
+		{in: "", valid: true},
+		{in: "none", valid: true},
+		{in: "host, os,process,container", valid: true},
+		{in: "host,bogus", valid: false},
+		{in: "none,host", valid: false},
+	} {
+		err := DefaultConfig().WithResourceDetectors(tc.in).CheckResourceDetectors()
+		if (err == nil) != tc.valid {
+			t.Errorf("expected --resource-detectors %q valid to be %t, got %v", tc.in, tc.valid, err)
+		}
+	}
 }
 
 func TestFlattenStringMap(t *testing.T) {
@@ -72,6 +168,36 @@ func TestParseCkvStringMap(t *testing.T) {
 	}
 }
 
+func TestLoadAttrEnv(t *testing.T) {
+	config := DefaultConfig().WithAttributes(map[string]string{"env": "staging", "team": "sre"})
+	err := config.LoadAttrEnv([]string{
+		"OTEL_CLI_ATTRIBUTES=ignored=here",
+		"OTEL_CLI_ATTR_env=prod",
+		"OTEL_CLI_ATTR_deploy__id=1234",
+		"OTEL_CLI_ATTR_empty=",
+		"OTEL_CLI_ATTR_url=https://example.com/?a=b",
+		"PATH=/usr/bin",
+	})
+	if err != nil {
+		t.Fatalf("error on valid input: %s", err)
+	}
+
+	expect := map[string]string{
+		"env":       "prod",
+		"team":      "sre",
+		"deploy.id": "1234",
+		"url":       "https://example.com/?a=b",
+	}
+	if diff := cmp.Diff(expect, config.Attributes); diff != "" {
+		t.Errorf("attributes didn't match (-want +got):\n%s", diff)
+	}
+
+	config = DefaultConfig()
+	if err := config.LoadAttrEnv([]string{"OTEL_CLI_ATTR_=oops"}); err == nil {
+		t.Error("expected an error for an envvar without an attribute name")
+	}
+}
+
 func TestParseTime(t *testing.T) {
 	mustParse := func(layout, value string) time.Time {
 		out, err := time.Parse(layout, value)
@@ -171,6 +297,54 @@ func TestParseCliTime(t *testing.T) {
 	}
 }
 
+func TestSpanTimesFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	finished := started.Add(90 * time.Second)
+
+	input := filepath.Join(dir, "input.log")
+	output := filepath.Join(dir, "output.tar")
+	for path, mtime := range map[string]time.Time{input: started, output: finished} {
+		if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the files override --start and --end
+	config := DefaultConfig().
+		WithSpanStartTime("1979-10-12T23:59:59Z").
+		WithSpanStartFromFile(input).
+		WithSpanEndFromFile(output)
+	span := config.NewProtobufSpan()
+
+	if got := time.Unix(0, int64(span.StartTimeUnixNano)); !got.Equal(started) {
+		t.Errorf("expected start time %s from the input file, got %s", started, got)
+	}
+	if got := time.Unix(0, int64(span.EndTimeUnixNano)); !got.Equal(finished) {
+		t.Errorf("expected end time %s from the output file, got %s", finished, got)
+	}
+
+	if _, err := fileModTime(filepath.Join(dir, "missing"), "start"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestGetBackgroundSockfile(t *testing.T) {
+	config := DefaultConfig().WithBackgroundSockdir("/tmp/sd")
+
+	if got := config.GetBackgroundSockfile(); got != "/tmp/sd/otel-cli-background.sock" {
+		t.Errorf("expected the default socket without a handle, got %q", got)
+	}
+
+	got := config.WithBackgroundSpanHandle("compile").GetBackgroundSockfile()
+	if got != "/tmp/sd/otel-cli-background-compile.sock" {
+		t.Errorf("expected a socket named after the handle, got %q", got)
+	}
+}
+
 func TestParseEndpoint(t *testing.T) {
 	// func parseEndpoint(config Config) (*url.URL, string) {
 
@@ -227,6 +401,12 @@ func TestParseEndpoint(t *testing.T) {
 			wantEndpoint: "http://localhost",
 			wantSource:   "signal",
 		},
+		// unix socket, general, the socket path isn't a URL path so nothing is appended
+		{
+			config:       DefaultConfig().WithEndpoint("unix:///run/otel.sock").WithProtocol("http/protobuf"),
+			wantEndpoint: "unix:///run/otel.sock",
+			wantSource:   "general",
+		},
 	} {
 		u, src := tc.config.ParseEndpoint()
 
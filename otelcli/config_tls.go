@@ -15,7 +15,7 @@ func (config Config) GetTlsConfig() *tls.Config {
 	tlsConfig := &tls.Config{}
 
 	if config.TlsNoVerify {
-		Diag.InsecureSkipVerify = true
+		config.diag.update(func(d *Diagnostics) { d.InsecureSkipVerify = true })
 		tlsConfig.InsecureSkipVerify = true
 	}
 
@@ -56,21 +56,62 @@ func (config Config) GetTlsConfig() *tls.Config {
 	return tlsConfig
 }
 
+// GetServerTlsConfig returns the tls.Config for otel-cli server to serve TLS
+// with --tls-cert and --tls-key, requiring client certificates signed by
+// --tls-ca when it's set. Returns nil when the server is plaintext.
+func (c Config) GetServerTlsConfig() (*tls.Config, error) {
+	if c.ServerTlsCert == "" && c.ServerTlsKey == "" {
+		if c.ServerTlsCA != "" {
+			return nil, fmt.Errorf("--tls-ca needs --tls-cert and --tls-key")
+		}
+		return nil, nil
+	} else if c.ServerTlsCert == "" || c.ServerTlsKey == "" {
+		return nil, fmt.Errorf("server cert and key must be specified together")
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.ServerTlsCert, c.ServerTlsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server cert pair: %w", err)
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	if c.ServerTlsCA != "" {
+		data, err := os.ReadFile(c.ServerTlsCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA certificate: %w", err)
+		}
+		certpool := x509.NewCertPool()
+		if !certpool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", c.ServerTlsCA)
+		}
+		tlsConfig.ClientCAs = certpool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
+}
+
 // GetInsecure returns true if the configuration expects a non-TLS connection.
 func (c Config) GetInsecure() bool {
 	endpointURL := c.GetEndpoint()
+	if endpointURL.Scheme == "unix" {
+		return true // sockets never leave the host
+	}
 
 	isLoopback, err := isLoopbackAddr(endpointURL)
 	c.SoftFailIfErr(err)
+	c.diag.update(func(d *Diagnostics) { d.DetectedLocalhost = isLoopback })
 
 	// Go's TLS does the right thing and forces us to say we want to disable encryption,
 	// but I expect most users of this program to point at a localhost endpoint that might not
 	// have any encryption available, or setting it up raises the bar of entry too high.
 	// The compromise is to automatically flip this flag to true when endpoint contains an
-	// an obvious "localhost", "127.0.0.x", or "::1" address.
-	if c.Insecure || (isLoopback && endpointURL.Scheme != "https") {
+	// an obvious "localhost", "127.0.0.x", or "::1" address, unless the
+	// no-auto-insecure feature is on.
+	autoInsecure := isLoopback && !c.FeatureEnabled(featureNoAutoInsecure)
+	if c.Insecure || (autoInsecure && endpointURL.Scheme != "https") {
 		return true
-	} else if endpointURL.Scheme == "http" || endpointURL.Scheme == "unix" {
+	} else if endpointURL.Scheme == "http" {
 		return true
 	}
 
@@ -86,7 +127,6 @@ func isLoopbackAddr(u *url.URL) (bool, error) {
 	hostname := u.Hostname()
 
 	if hostname == "localhost" || hostname == "127.0.0.1" || hostname == "::1" {
-		Diag.DetectedLocalhost = true
 		return true, nil
 	}
 
@@ -104,6 +144,5 @@ func isLoopbackAddr(u *url.URL) (bool, error) {
 		}
 	}
 
-	Diag.DetectedLocalhost = allAreLoopback
 	return allAreLoopback, nil
 }
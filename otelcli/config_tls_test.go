@@ -0,0 +1,100 @@
+package otelcli
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert writes a cert and key pair as PEM files into dir
+// and returns their paths.
+func writeSelfSignedCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %s", err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)
+	return certFile, keyFile
+}
+
+func TestGetServerTlsConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSignedCert(t, dir)
+	notPem := filepath.Join(dir, "not.pem")
+	os.WriteFile(notPem, []byte("nope"), 0600)
+
+	for _, tc := range []struct {
+		name       string
+		cert       string
+		key        string
+		ca         string
+		wantNil    bool
+		wantErr    bool
+		wantClient tls.ClientAuthType
+	}{
+		{name: "plaintext", wantNil: true},
+		{name: "cert and key", cert: certFile, key: keyFile, wantClient: tls.NoClientCert},
+		{name: "client CA", cert: certFile, key: keyFile, ca: certFile, wantClient: tls.RequireAndVerifyClientCert},
+		{name: "cert without key", cert: certFile, wantErr: true},
+		{name: "key without cert", key: keyFile, wantErr: true},
+		{name: "CA without cert", ca: certFile, wantErr: true},
+		{name: "CA without certificates", cert: certFile, key: keyFile, ca: notPem, wantErr: true},
+		{name: "missing cert file", cert: filepath.Join(dir, "missing.pem"), key: keyFile, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.ServerTlsCert = tc.cert
+			config.ServerTlsKey = tc.key
+			config.ServerTlsCA = tc.ca
+
+			got, err := config.GetServerTlsConfig()
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.wantNil {
+				if got != nil {
+					t.Errorf("expected no TLS config, got %+v", got)
+				}
+				return
+			}
+			if len(got.Certificates) != 1 {
+				t.Errorf("expected 1 certificate, got %d", len(got.Certificates))
+			}
+			if got.ClientAuth != tc.wantClient {
+				t.Errorf("expected client auth %s, got %s", tc.wantClient, got.ClientAuth)
+			}
+		})
+	}
+}
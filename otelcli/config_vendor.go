@@ -0,0 +1,178 @@
+package otelcli
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// vendorPreset is what --vendor fills in to export to a SaaS backend: the
+// endpoint, the protocol, and the headers it requires.
+type vendorPreset struct {
+	// endpoint is an --endpoint-template, {region} is replaced with --region
+	endpoint string
+	// tracesEndpoint is set when the endpoint is the full traces URL and goes
+	// in --traces-endpoint, so /v1/traces isn't appended
+	tracesEndpoint bool
+	// defaultRegion is used when --region isn't set, leave it empty when the
+	// user has to pick one
+	defaultRegion string
+	// regions maps short region names to what's put in the template, other
+	// region names are used as-is
+	regions  map[string]string
+	protocol string
+	// headers returns the headers the backend requires, read from the
+	// vendor's usual env vars
+	headers func(getenv func(string) string) (map[string]string, error)
+}
+
+// vendorPresets are the backends supported by --vendor.
+var vendorPresets = map[string]vendorPreset{
+	"honeycomb": {
+		endpoint:      "https://{region}",
+		defaultRegion: "us",
+		regions: map[string]string{
+			"us":  "api.honeycomb.io",
+			"eu":  "api.eu1.honeycomb.io",
+			"eu1": "api.eu1.honeycomb.io",
+		},
+		protocol: "http/protobuf",
+		headers: func(getenv func(string) string) (map[string]string, error) {
+			key, err := requireEnv(getenv, "HONEYCOMB_API_KEY")
+			return map[string]string{"x-honeycomb-team": key}, err
+		},
+	},
+	"grafana-cloud": {
+		// the region is the zone in the stack's OTLP endpoint, e.g. prod-us-east-0
+		endpoint: "https://otlp-gateway-{region}.grafana.net/otlp",
+		protocol: "http/protobuf",
+		headers: func(getenv func(string) string) (map[string]string, error) {
+			id, err := requireEnv(getenv, "GRAFANA_CLOUD_INSTANCE_ID")
+			token, tokenErr := requireEnv(getenv, "GRAFANA_CLOUD_API_KEY")
+			if err == nil {
+				err = tokenErr
+			}
+			auth := base64.StdEncoding.EncodeToString([]byte(id + ":" + token))
+			return map[string]string{"Authorization": "Basic " + auth}, err
+		},
+	},
+	"datadog": {
+		// the region is the Datadog site, e.g. datadoghq.eu or us5
+		endpoint:       "https://trace.agent.{region}/api/v0.2/traces",
+		tracesEndpoint: true,
+		defaultRegion:  "us1",
+		regions: map[string]string{
+			"us1": "datadoghq.com",
+			"us3": "us3.datadoghq.com",
+			"us5": "us5.datadoghq.com",
+			"eu1": "datadoghq.eu",
+			"ap1": "ap1.datadoghq.com",
+		},
+		protocol: "http/protobuf",
+		headers: func(getenv func(string) string) (map[string]string, error) {
+			key, err := requireEnv(getenv, "DD_API_KEY")
+			return map[string]string{"dd-api-key": key, "dd-protocol": "otlp"}, err
+		},
+	},
+}
+
+// vendorNames returns the --vendor names, sorted, for help and errors.
+func vendorNames() string {
+	names := make([]string, 0, len(vendorPresets))
+	for name := range vendorPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+// requireEnv returns the env var's value or an error naming it.
+func requireEnv(getenv func(string) string, name string) (string, error) {
+	if value := getenv(name); value != "" {
+		return value, nil
+	}
+	return "", fmt.Errorf("%s must be set", name)
+}
+
+// ApplyEndpointTemplate fills in the endpoint from --endpoint-template and
+// --vendor, expanding {region} with --region. Anything that was set
+// explicitly, the endpoint, protocol, or a header, is left alone so presets
+// can be overridden one setting at a time.
+func (c *Config) ApplyEndpointTemplate(getenv func(string) string) error {
+	if c.Vendor == "" && c.EndpointTemplate == "" {
+		return nil
+	}
+
+	var preset vendorPreset
+	if c.Vendor != "" {
+		var ok bool
+		if preset, ok = vendorPresets[strings.ToLower(c.Vendor)]; !ok {
+			return fmt.Errorf("unknown --vendor %q, must be one of %s", c.Vendor, vendorNames())
+		}
+	}
+
+	template := preset.endpoint
+	if c.EndpointTemplate != "" {
+		template = c.EndpointTemplate
+		preset.tracesEndpoint = false
+	}
+
+	if c.Endpoint == "" && c.TracesEndpoint == "" {
+		endpoint, err := expandEndpointTemplate(template, c.Region, preset)
+		if err != nil {
+			return err
+		}
+		if preset.tracesEndpoint {
+			c.TracesEndpoint = endpoint
+		} else {
+			c.Endpoint = endpoint
+		}
+	}
+
+	if c.Vendor == "" {
+		return nil
+	}
+
+	if c.Protocol == "" {
+		c.Protocol = preset.protocol
+	}
+
+	// headers already on the command line or in the env are kept, and their
+	// env vars aren't required
+	required, err := preset.headers(getenv)
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
+	}
+	for key, value := range required {
+		if _, ok := c.Headers[key]; ok {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("--vendor %s needs the %s header: %w", c.Vendor, key, err)
+		}
+		c.Headers[key] = value
+	}
+
+	return nil
+}
+
+// expandEndpointTemplate replaces {region} in the template with the region,
+// or the preset's default region, translated through the preset's regions.
+func expandEndpointTemplate(template, region string, preset vendorPreset) (string, error) {
+	if !strings.Contains(template, "{region}") {
+		return template, nil
+	}
+
+	if region == "" {
+		region = preset.defaultRegion
+	}
+	if region == "" {
+		return "", fmt.Errorf("endpoint %q needs a --region", template)
+	}
+	if value, ok := preset.regions[strings.ToLower(region)]; ok {
+		region = value
+	}
+
+	return strings.ReplaceAll(template, "{region}", region), nil
+}
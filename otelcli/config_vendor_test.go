@@ -0,0 +1,131 @@
+package otelcli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyEndpointTemplate(t *testing.T) {
+	env := map[string]string{
+		"HONEYCOMB_API_KEY":         "hc-key",
+		"GRAFANA_CLOUD_INSTANCE_ID": "123",
+		"GRAFANA_CLOUD_API_KEY":     "token",
+		"DD_API_KEY":                "dd-key",
+	}
+	getenv := func(name string) string { return env[name] }
+	noenv := func(string) string { return "" }
+
+	for _, tc := range []struct {
+		name   string
+		config Config
+		getenv func(string) string
+		want   Config
+		err    bool
+	}{
+		{
+			name:   "nothing to do",
+			config: DefaultConfig().WithEndpoint("localhost:4317"),
+			getenv: getenv,
+			want:   DefaultConfig().WithEndpoint("localhost:4317"),
+		},
+		{
+			name:   "template",
+			config: DefaultConfig().WithEndpointTemplate("https://otlp.{region}.example.com:4318").WithRegion("eu-west-1"),
+			getenv: noenv,
+			want: DefaultConfig().WithEndpointTemplate("https://otlp.{region}.example.com:4318").WithRegion("eu-west-1").
+				WithEndpoint("https://otlp.eu-west-1.example.com:4318"),
+		},
+		{
+			name:   "template without a region",
+			config: DefaultConfig().WithEndpointTemplate("https://otlp.{region}.example.com:4318"),
+			getenv: noenv,
+			err:    true,
+		},
+		{
+			name:   "honeycomb defaults to us",
+			config: DefaultConfig().WithVendor("honeycomb"),
+			getenv: getenv,
+			want: DefaultConfig().WithVendor("honeycomb").
+				WithEndpoint("https://api.honeycomb.io").
+				WithProtocol("http/protobuf").
+				WithHeaders(map[string]string{"x-honeycomb-team": "hc-key"}),
+		},
+		{
+			name:   "honeycomb eu with the endpoint protocol and key overridden",
+			config: DefaultConfig().WithVendor("Honeycomb").WithRegion("eu").WithProtocol("grpc").WithHeaders(map[string]string{"x-honeycomb-team": "other"}),
+			getenv: noenv,
+			want: DefaultConfig().WithVendor("Honeycomb").WithRegion("eu").
+				WithEndpoint("https://api.eu1.honeycomb.io").
+				WithProtocol("grpc").
+				WithHeaders(map[string]string{"x-honeycomb-team": "other"}),
+		},
+		{
+			name:   "honeycomb without a key",
+			config: DefaultConfig().WithVendor("honeycomb"),
+			getenv: noenv,
+			err:    true,
+		},
+		{
+			name:   "grafana cloud",
+			config: DefaultConfig().WithVendor("grafana-cloud").WithRegion("prod-us-east-0"),
+			getenv: getenv,
+			want: DefaultConfig().WithVendor("grafana-cloud").WithRegion("prod-us-east-0").
+				WithEndpoint("https://otlp-gateway-prod-us-east-0.grafana.net/otlp").
+				WithProtocol("http/protobuf").
+				WithHeaders(map[string]string{"Authorization": "Basic MTIzOnRva2Vu"}),
+		},
+		{
+			name:   "grafana cloud needs a region",
+			config: DefaultConfig().WithVendor("grafana-cloud"),
+			getenv: getenv,
+			err:    true,
+		},
+		{
+			name:   "grafana cloud without a token",
+			config: DefaultConfig().WithVendor("grafana-cloud").WithRegion("prod-us-east-0"),
+			getenv: func(name string) string { return map[string]string{"GRAFANA_CLOUD_INSTANCE_ID": "123"}[name] },
+			err:    true,
+		},
+		{
+			name:   "datadog site",
+			config: DefaultConfig().WithVendor("datadog").WithRegion("eu1"),
+			getenv: getenv,
+			want: DefaultConfig().WithVendor("datadog").WithRegion("eu1").
+				WithTracesEndpoint("https://trace.agent.datadoghq.eu/api/v0.2/traces").
+				WithProtocol("http/protobuf").
+				WithHeaders(map[string]string{"dd-api-key": "dd-key", "dd-protocol": "otlp"}),
+		},
+		{
+			name:   "vendor with a template",
+			config: DefaultConfig().WithVendor("datadog").WithEndpointTemplate("http://{region}:4318").WithRegion("localhost"),
+			getenv: getenv,
+			want: DefaultConfig().WithVendor("datadog").WithEndpointTemplate("http://{region}:4318").WithRegion("localhost").
+				WithEndpoint("http://localhost:4318").
+				WithProtocol("http/protobuf").
+				WithHeaders(map[string]string{"dd-api-key": "dd-key", "dd-protocol": "otlp"}),
+		},
+		{
+			name:   "unknown vendor",
+			config: DefaultConfig().WithVendor("acme"),
+			getenv: getenv,
+			err:    true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.ApplyEndpointTemplate(tc.getenv)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", tc.config)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.want, tc.config, cmp.AllowUnexported(Config{})); diff != "" {
+				t.Errorf("config did not match (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
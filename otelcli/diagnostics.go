@@ -1,14 +1,14 @@
 package otelcli
 
 import (
+	"context"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// package global Diagnostics handle, written to from all over otel-cli
-// and used in e.g. otel-cli status to show internal state
-var Diag Diagnostics
-
 // Diagnostics is a place to put things that are useful for testing and
 // diagnosing issues with otel-cli. The only user-facing feature that should be
 // using these is otel-cli status.
@@ -25,34 +25,99 @@ type Diagnostics struct {
 	Error              string   `json:"error"`
 	ExecExitCode       int      `json:"exec_exit_code"`
 	Retries            int      `json:"retries"`
+	Features           []string `json:"features"`
+	UnknownConfigKeys  []string `json:"unknown_config_keys,omitempty"`
+	Normalized         []string `json:"normalized,omitempty"`
+	// AttributesDropped and AttributesTruncated count what the attribute
+	// limits did to --attrs
+	AttributesDropped   int `json:"attributes_dropped"`
+	AttributesTruncated int `json:"attributes_truncated"`
+	// the --timeout budget, from when otel-cli started to the deadline, with
+	// the time excluded from it. these change every run so they're left out
+	// of ToStringMap, like ExecExitCode
+	StartupTime       string `json:"startup_time,omitempty"`
+	TimeoutDeadline   string `json:"timeout_deadline,omitempty"`
+	TimeoutExcludedMs int64  `json:"timeout_excluded_ms,omitempty"`
+	// the sampler dropped the trace, left out of ToStringMap so status
+	// fixtures don't depend on it
+	Unsampled bool `json:"unsampled,omitempty"`
+	// how long otel-cli waited on stdin, excluded from the --timeout budget
+	stdinWait time.Duration
 }
 
 // ToMap returns the Diag struct as a string map for testing.
 func (d *Diagnostics) ToStringMap() map[string]string {
 	return map[string]string{
-		"cli_args":           strings.Join(d.CliArgs, " "),
-		"is_recording":       strconv.FormatBool(d.IsRecording),
-		"config_file_loaded": strconv.FormatBool(d.ConfigFileLoaded),
-		"number_of_args":     strconv.Itoa(d.NumArgs),
-		"detected_localhost": strconv.FormatBool(d.DetectedLocalhost),
-		"parsed_timeout_ms":  strconv.FormatInt(d.ParsedTimeoutMs, 10),
-		"endpoint":           d.Endpoint,
-		"endpoint_source":    d.EndpointSource,
-		"error":              d.Error,
+		"cli_args":             strings.Join(d.CliArgs, " "),
+		"is_recording":         strconv.FormatBool(d.IsRecording),
+		"config_file_loaded":   strconv.FormatBool(d.ConfigFileLoaded),
+		"number_of_args":       strconv.Itoa(d.NumArgs),
+		"detected_localhost":   strconv.FormatBool(d.DetectedLocalhost),
+		"parsed_timeout_ms":    strconv.FormatInt(d.ParsedTimeoutMs, 10),
+		"endpoint":             d.Endpoint,
+		"endpoint_source":      d.EndpointSource,
+		"error":                d.Error,
+		"retries":              strconv.Itoa(d.Retries),
+		"features":             strings.Join(d.Features, ","),
+		"unknown_config_keys":  strings.Join(d.UnknownConfigKeys, ","),
+		"normalized":           strings.Join(d.Normalized, ", "),
+		"attributes_dropped":   strconv.Itoa(d.AttributesDropped),
+		"attributes_truncated": strconv.Itoa(d.AttributesTruncated),
 	}
 }
 
-// SetError sets the diagnostics Error to the error's string if it's
-// not nil and returns the same error so it can be inlined in return.
-func (d *Diagnostics) SetError(err error) error {
-	if err != nil {
-		Diag.Error = err.Error()
+// diagnosticsContextKey is the context key for an invocation's diagnostics.
+type diagnosticsContextKey struct{}
+
+// diagnostics holds the Diagnostics for one otel-cli invocation. It's written
+// from all over otel-cli, sometimes from several goroutines, so access goes
+// through a lock. Copies of a Config share the same diagnostics.
+type diagnostics struct {
+	mu   sync.Mutex
+	diag Diagnostics
+}
+
+// withDiagnostics returns a context carrying fresh diagnostics, along with
+// the diagnostics so they can be given to the Config.
+func withDiagnostics(ctx context.Context) (context.Context, *diagnostics) {
+	d := &diagnostics{}
+	return context.WithValue(ctx, diagnosticsContextKey{}, d), d
+}
+
+// GetDiagnostics returns a copy of the diagnostics recorded so far for the
+// invocation in ctx, or empty Diagnostics if there aren't any.
+func GetDiagnostics(ctx context.Context) Diagnostics {
+	d, _ := ctx.Value(diagnosticsContextKey{}).(*diagnostics)
+	return d.snapshot()
+}
+
+// update calls fn with the Diagnostics while holding the lock. Does nothing
+// on nil diagnostics, e.g. for a Config that was made in a test.
+func (d *diagnostics) update(fn func(*Diagnostics)) {
+	if d == nil {
+		return
 	}
-	return err
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn(&d.diag)
 }
 
-// GetExitCode() is a helper for Cobra to retrieve the exit code, mainly
-// used by exec to make otel-cli return the child program's exit code.
-func GetExitCode() int {
-	return Diag.ExecExitCode
+// snapshot returns a copy of the Diagnostics that's safe to read while
+// updates continue.
+func (d *diagnostics) snapshot() Diagnostics {
+	if d == nil {
+		return Diagnostics{}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := d.diag
+	out.CliArgs = slices.Clone(d.diag.CliArgs)
+	return out
+}
+
+// setError records the error's string if it's not nil.
+func (d *diagnostics) setError(err error) {
+	if err != nil {
+		d.update(func(diag *Diagnostics) { diag.Error = err.Error() })
+	}
 }
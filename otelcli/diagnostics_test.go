@@ -0,0 +1,57 @@
+package otelcli
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestDiagnosticsPerInvocation(t *testing.T) {
+	ctx1, diag1 := withDiagnostics(context.Background())
+	ctx2, diag2 := withDiagnostics(context.Background())
+	c1 := DefaultConfig().WithEndpoint("localhost:4317")
+	c1.diag = diag1
+	c2 := DefaultConfig()
+	c2.diag = diag2
+
+	// copies made by With* share the invocation's diagnostics
+	c1.WithTimeout("2s").ParseCliTimeout()
+	c1.GetIsRecording()
+	c2.GetIsRecording()
+
+	d1, d2 := GetDiagnostics(ctx1), GetDiagnostics(ctx2)
+	if !d1.IsRecording || d1.ParsedTimeoutMs != 2000 {
+		t.Errorf("expected the first invocation to be recording with a 2s timeout, got %+v", d1)
+	}
+	if d2.IsRecording || d2.ParsedTimeoutMs != 0 {
+		t.Errorf("expected the second invocation to be untouched by the first, got %+v", d2)
+	}
+
+	// no diagnostics in context or config is fine, e.g. in tests
+	if d := GetDiagnostics(context.Background()); d.IsRecording {
+		t.Errorf("expected empty diagnostics, got %+v", d)
+	}
+	DefaultConfig().GetIsRecording()
+}
+
+func TestDiagnosticsConcurrent(t *testing.T) {
+	ctx, diag := withDiagnostics(context.Background())
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	config.diag = diag
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config.GetIsRecording()
+			config.ParseEndpoint()
+			GetDiagnostics(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if d := GetDiagnostics(ctx); d.Endpoint != "grpc://localhost:4317" || d.EndpointSource != "general" {
+		t.Errorf("unexpected endpoint diagnostics %+v", d)
+	}
+}
@@ -0,0 +1,73 @@
+package otelcli
+
+import (
+	"context"
+	"io"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// dryRunClient is the OTLP client for --dry-run. It writes each export
+// request to out as one line of OTLP/JSON instead of sending it, so
+// everything up to the network can be checked without a collector.
+type dryRunClient struct {
+	out io.Writer
+}
+
+// newDryRunClient returns a dryRunClient that writes to out.
+func newDryRunClient(out io.Writer) *dryRunClient {
+	return &dryRunClient{out: out}
+}
+
+// Start fulfills the interface and does nothing.
+func (dc *dryRunClient) Start(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// UploadTraces writes the spans as an OTLP/JSON trace export request, with
+// hex ids like the collector's file exporter.
+func (dc *dryRunClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	js, err := otlpclient.MarshalOtlpJson(&coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+	if err != nil {
+		return ctx, err
+	}
+	return ctx, dc.write(js)
+}
+
+// UploadLogs writes the logs as an OTLP/JSON logs export request.
+func (dc *dryRunClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return ctx, dc.writeProto(&collogspb.ExportLogsServiceRequest{ResourceLogs: rls})
+}
+
+// UploadMetrics writes the metrics as an OTLP/JSON metrics export request.
+func (dc *dryRunClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	return ctx, dc.writeProto(&colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: rms})
+}
+
+// Stop fulfills the interface and does nothing.
+func (dc *dryRunClient) Stop(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// writeProto encodes msg with the protobuf JSON mapping and writes it.
+func (dc *dryRunClient) writeProto(msg proto.Message) error {
+	js, err := protojson.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return dc.write(js)
+}
+
+// write writes one line of JSON.
+func (dc *dryRunClient) write(js []byte) error {
+	_, err := dc.out.Write(append(js, '\n'))
+	return err
+}
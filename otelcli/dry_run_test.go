@@ -0,0 +1,39 @@
+package otelcli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestDryRunClient(t *testing.T) {
+	config := DefaultConfig().WithDryRun(true).WithSpanName("dry")
+	if !config.GetIsRecording() {
+		t.Error("expected --dry-run to record without an endpoint")
+	}
+
+	span := config.NewProtobufSpan()
+	rsps, err := otlpclient.NewResourceSpans(context.Background(), config, []*tracepb.Span{span})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	client := newDryRunClient(&out)
+	if _, err := client.UploadTraces(context.Background(), rsps); err != nil {
+		t.Fatalf("unexpected error from UploadTraces: %s", err)
+	}
+
+	got := out.String()
+	if strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, "\n") {
+		t.Errorf("expected one line of json, got %q", got)
+	}
+	tp := otlpclient.TraceparentFromProtobufSpan(span, true)
+	if !strings.Contains(got, `"traceId":"`+tp.TraceIdString()+`"`) || !strings.Contains(got, `"name":"dry"`) {
+		t.Errorf("expected the span with a hex trace id, got %s", got)
+	}
+}
@@ -0,0 +1,276 @@
+package otelcli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// endpointHistoryMax is how many endpoints the history keeps per flag.
+const endpointHistoryMax = 20
+
+// endpointHistoryPath returns where --endpoint-history keeps endpoints that
+// exports succeeded to, in the user's cache directory.
+func endpointHistoryPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "otel-cli", "endpoint-history"), nil
+}
+
+// endpointHistoryDisabled returns true when OTEL_CLI_ENDPOINT_HISTORY turns
+// the history off, which also hides it from shell completion.
+func endpointHistoryDisabled() bool {
+	off, err := strconv.ParseBool(os.Getenv("OTEL_CLI_ENDPOINT_HISTORY"))
+	return err == nil && !off
+}
+
+// readEndpointHistory returns the endpoints in the history file for the flag,
+// e.g. endpoint or traces-endpoint, most recently used first. Each line is a
+// flag name and an endpoint, base64 encoded so tokens that sometimes end up
+// in vendor URLs don't show up in a grep of the home directory. A missing
+// file is an empty history.
+func readEndpointHistory(path, flag string) ([]string, error) {
+	entries, err := readEndpointHistoryEntries(path)
+	out := []string{}
+	for _, entry := range entries {
+		if entry[0] == flag {
+			out = append(out, entry[1])
+		}
+	}
+	return out, err
+}
+
+// readEndpointHistoryEntries reads the history file as flag, endpoint pairs.
+// Lines that don't decode are skipped.
+func readEndpointHistoryEntries(path string) ([][2]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	entries := [][2]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, err := base64.RawStdEncoding.DecodeString(scanner.Text())
+		if err != nil {
+			continue
+		}
+		if flag, endpoint, ok := strings.Cut(string(line), "\t"); ok {
+			entries = append(entries, [2]string{flag, endpoint})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// addEndpointHistory moves the endpoint for the flag to the top of the
+// history, dropping the oldest past endpointHistoryMax. Credentials and
+// query strings, where vendors sometimes put API keys, are removed from URLs
+// first. The file is locked against other otel-cli processes while it's
+// updated, replaced atomically, and only readable by the user.
+func addEndpointHistory(path, flag, endpoint string) error {
+	if u, err := url.Parse(endpoint); err == nil && (u.User != nil || u.RawQuery != "" || u.Fragment != "") {
+		u.User = nil
+		u.RawQuery = ""
+		u.ForceQuery = false
+		u.Fragment = ""
+		endpoint = u.String()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	unlock, err := otlpclient.LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	entries, err := readEndpointHistoryEntries(path)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	write := func(flag, endpoint string) {
+		buf.WriteString(base64.RawStdEncoding.EncodeToString([]byte(flag + "\t" + endpoint)))
+		buf.WriteByte('\n')
+	}
+	write(flag, endpoint)
+	kept := 1
+	for _, entry := range entries {
+		if entry[0] == flag && (entry[1] == endpoint || kept >= endpointHistoryMax) {
+			continue
+		} else if entry[0] == flag {
+			kept++
+		}
+		write(entry[0], entry[1])
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".endpoint-history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// endpointCompletion adds the endpoints in the history for the flag to the
+// completions from fn, unless OTEL_CLI_ENDPOINT_HISTORY turns it off.
+func endpointCompletion(fn completionFunc, flag string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		completions, directive := fn(cmd, args, toComplete)
+		if endpointHistoryDisabled() {
+			return completions, directive
+		}
+
+		path, err := endpointHistoryPath()
+		if err != nil {
+			return completions, directive
+		}
+		history, _ := readEndpointHistory(path, flag)
+		for _, endpoint := range history {
+			if !slices.Contains(completions, endpoint) {
+				completions = append(completions, endpoint)
+			}
+		}
+		if len(completions) == 0 {
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// historyClient is an OTLPClient that wraps another client and adds the
+// endpoints it was configured with to the endpoint history after the first
+// export that succeeds. Problems with the history file are saved to the
+// error list but never fail an export.
+type historyClient struct {
+	client    otlpclient.OTLPClient
+	endpoints map[string]string // flag name to endpoint
+	once      sync.Once
+}
+
+// newHistoryClient returns a historyClient for the config's --endpoint and
+// --traces-endpoint.
+func newHistoryClient(client otlpclient.OTLPClient, config Config) *historyClient {
+	endpoints := map[string]string{}
+	if config.Endpoint != "" {
+		endpoints["endpoint"] = config.Endpoint
+	}
+	if config.TracesEndpoint != "" {
+		endpoints["traces-endpoint"] = config.TracesEndpoint
+	}
+	return &historyClient{client: client, endpoints: endpoints}
+}
+
+// Start starts the wrapped client.
+func (hc *historyClient) Start(ctx context.Context) (context.Context, error) {
+	return hc.client.Start(ctx)
+}
+
+// UploadTraces uploads with the wrapped client and records the endpoints
+// when it succeeds.
+func (hc *historyClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	ctx, err := hc.client.UploadTraces(ctx, rsps)
+	return hc.record(ctx, err), err
+}
+
+// UploadLogs uploads with the wrapped client and records the endpoints when
+// it succeeds.
+func (hc *historyClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	ctx, err := hc.client.UploadLogs(ctx, rls)
+	return hc.record(ctx, err), err
+}
+
+// UploadMetrics uploads with the wrapped client and records the endpoints
+// when it succeeds.
+func (hc *historyClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	ctx, err := hc.client.UploadMetrics(ctx, rms)
+	return hc.record(ctx, err), err
+}
+
+// Stop stops the wrapped client.
+func (hc *historyClient) Stop(ctx context.Context) (context.Context, error) {
+	return hc.client.Stop(ctx)
+}
+
+// record adds the endpoints to the history once, after a successful upload.
+func (hc *historyClient) record(ctx context.Context, uploadErr error) context.Context {
+	if uploadErr != nil {
+		return ctx
+	}
+	hc.once.Do(func() {
+		path, err := endpointHistoryPath()
+		for flag, endpoint := range hc.endpoints {
+			if err == nil {
+				err = addEndpointHistory(path, flag, endpoint)
+			}
+		}
+		if err != nil {
+			ctx, _ = otlpclient.SaveError(ctx, otlpclient.Now(ctx), fmt.Errorf("could not save endpoint history: %w", err))
+		}
+	})
+	return ctx
+}
+
+// historyCmd manages the endpoint history.
+func historyCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "history",
+		Short: "manage the history of endpoints used with --endpoint-history",
+		Long: `With --endpoint-history or OTEL_CLI_ENDPOINT_HISTORY=true, endpoints that
+exports succeed to are kept in the user's cache directory and offered by shell
+completion for --endpoint and --traces-endpoint. OTEL_CLI_ENDPOINT_HISTORY=false
+turns it off, including where a config file turns it on.
+
+    otel-cli history clear
+`,
+	}
+
+	clear := cobra.Command{
+		Use:   "clear",
+		Short: "delete the endpoint history",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			config := getConfig(cmd.Context())
+			path, err := endpointHistoryPath()
+			if err != nil {
+				config.SoftFail("could not find the endpoint history: %s", err)
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				config.SoftFail("could not clear the endpoint history: %s", err)
+			}
+			os.Remove(path + ".lock")
+		},
+	}
+	addCommonParams(&clear, config)
+	cmd.AddCommand(&clear)
+
+	return &cmd
+}
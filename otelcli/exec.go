@@ -2,12 +2,17 @@ package otelcli
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/exec"
-	"os/signal"
 	"os/user"
+	"runtime"
+	"slices"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
@@ -24,20 +29,77 @@ func execCmd(config *Config) *cobra.Command {
 		Short: "execute the command provided",
 		Long: `execute the command provided after the subcommand inside a span, measuring
 and reporting how long it took to run. The wrapping span's w3c traceparent is automatically
-passed to the child process's environment as TRACEPARENT.
+passed to the child process's environment as TRACEPARENT, or in the envvars of
+the --propagation-format.
 
 Examples:
 
 otel-cli exec -n my-cool-thing -s interesting-step curl https://cool-service/api/v1/endpoint
 
-otel-cli exec -s "outer span" -- otel-cli exec -s "inner span" sleep 1`,
+otel-cli exec -s "outer span" -- otel-cli exec -s "inner span" sleep 1
+
+The command and its arguments are run exactly as they are given, nothing is
+joined or split by a shell, so otel-cli works in shebangs:
+
+#!/usr/bin/env -S otel-cli exec --name nightly --endpoint localhost:4317 -- /bin/sh
+
+Use --shell to run the arguments as one command line with sh -c instead, for
+pipes, globs, and variables:
+
+otel-cli exec --shell -- 'make 2>&1 | tee build.log'
+
+--inject replaces how the traceparent is passed to the command and can be
+repeated. It takes a propagation format (w3c, b3, b3multi, jaeger), a file
+to write a carrier to, or an envvar template using {{traceparent}},
+{{trace_id}}, {{span_id}}, {{sampled}}, and {{tracestate}}:
+
+otel-cli exec --inject w3c --inject b3 --inject file:/tmp/tp.env \
+	--inject 'env:REQUEST_ID={{trace_id}}-{{span_id}}' -- ./deploy.sh
+
+Containers and remote hosts don't get the command's environment, so
+--docker-env adds the injected envvars to the arguments of docker, podman,
+and nerdctl run/create/exec, kubectl run, kubectl exec (with env after --),
+and ssh (in front of the remote command). --inject-flag-template adds flags
+right after the subcommand of any command, using the same templates as --inject
+env:. Templates are split on spaces before they're filled in:
+
+otel-cli exec --docker-env -- docker run --rm alpine env
+otel-cli exec --docker-env -- ssh deploy@web1 ./release.sh
+otel-cli exec --inject-flag-template '--env TRACEPARENT={{traceparent}}' -- docker run --rm alpine env
+
+--inject-otel-env sets the OTEL_EXPORTER_OTLP_* envvars, OTEL_SERVICE_NAME, and
+OTEL_RESOURCE_ATTRIBUTES from otel-cli's config, so an instrumented command
+exports its own spans to the same place without being configured twice.
+Unix socket endpoints are left out since SDKs can't export to them.
+
+--tp-http-stdin reads an HTTP request from stdin, e.g. a webhook handed over
+by socat or inetd, and uses its traceparent, tracestate, and baggage headers
+as the parent context. The request line is optional and the command gets the
+body on its stdin. Under CGI, the HTTP_* envvars are read instead of stdin:
+
+socat TCP-LISTEN:8080,fork EXEC:'otel-cli exec --tp-http-stdin -- ./webhook.sh'
+
+With --spans-from-output, lines the command prints with these markers add
+events and child spans to the exec span. Markers can be anywhere in a line
+and the output is passed through unchanged. Values with spaces can be quoted.
+
+OTEL_CLI_EVENT: name="cache warmed" attrs=entries=42
+OTEL_CLI_SPAN_START: name=compile attrs=target=linux
+OTEL_CLI_SPAN_END: name=compile status=ok`,
 		Run:  doExec,
 		Args: cobra.MinimumNArgs(1),
 	}
 
 	addCommonParams(&cmd, config)
 	addSpanParams(&cmd, config)
+	addSpanDurationParams(&cmd, config)
 	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addLinkParams(&cmd, config)
+	addPrintJsonParams(&cmd, config)
+	addPrintUrlParams(&cmd, config)
+	addAlsoLogParams(&cmd, config)
 	addClientParams(&cmd, config)
 
 	defaults := DefaultConfig()
@@ -48,6 +110,20 @@ otel-cli exec -s "outer span" -- otel-cli exec -s "inner span" sleep 1`,
 		"timeout for the child process, when 0 otel-cli will wait forever",
 	)
 
+	cmd.Flags().StringVar(
+		&config.ExecKillGrace,
+		"kill-grace",
+		defaults.ExecKillGrace,
+		"when --command-timeout is up, how long the command has to exit after SIGTERM before it gets SIGKILL, 0 to kill it right away",
+	)
+
+	cmd.Flags().StringVar(
+		&config.ExecForwardSignals,
+		"forward-signals",
+		defaults.ExecForwardSignals,
+		"a comma-separated list of signals to pass on to the command, each recorded as a span event",
+	)
+
 	cmd.Flags().BoolVar(
 		&config.ExecTpDisableInject,
 		"tp-disable-inject",
@@ -55,6 +131,132 @@ otel-cli exec -s "outer span" -- otel-cli exec -s "inner span" sleep 1`,
 		"disable automatically replacing {{traceparent}} with a traceparent",
 	)
 
+	cmd.Flags().StringArrayVar(
+		&config.ExecInjectors,
+		"inject",
+		defaults.ExecInjectors,
+		"how to pass the traceparent to the command, a format like w3c or b3, file:PATH, or env:NAME=template, can be repeated",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecDockerEnv,
+		"docker-env",
+		defaults.ExecDockerEnv,
+		"pass the injected envvars through docker, podman, nerdctl, kubectl run/exec, or ssh by adding them to the command's arguments",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecInjectOtelEnv,
+		"inject-otel-env",
+		defaults.ExecInjectOtelEnv,
+		"set OTEL_EXPORTER_OTLP_* envvars, OTEL_SERVICE_NAME, and OTEL_RESOURCE_ATTRIBUTES for the command from otel-cli's config",
+	)
+
+	cmd.Flags().StringArrayVar(
+		&config.ExecFlagTemplates,
+		"inject-flag-template",
+		defaults.ExecFlagTemplates,
+		"add these flags after the command's subcommand, e.g. '--env TRACEPARENT={{traceparent}}' for docker run, can be repeated",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.TraceparentHttpStdin,
+		"tp-http-stdin",
+		defaults.TraceparentHttpStdin,
+		"read an HTTP request, or just its headers, from stdin for the parent traceparent and baggage, the command gets the body on its stdin",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecPty,
+		"pty",
+		defaults.ExecPty,
+		"run the command in a pseudo-terminal, for programs that behave differently without a tty",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecShell,
+		"shell",
+		defaults.ExecShell,
+		"join the arguments into a command line and run it with sh -c (cmd /C on Windows) instead of running them as they are",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecLoginShell,
+		"login-shell",
+		defaults.ExecLoginShell,
+		"find the command using the PATH from your login shell's profile scripts",
+	)
+
+	cmd.Flags().StringVar(
+		&config.ExecSampleResources,
+		"sample-resources",
+		defaults.ExecSampleResources,
+		"sample memory and cpu of the command and its children at this interval, e.g. 1s (Linux only)",
+	)
+
+	cmd.Flags().StringVar(
+		&config.ExecHeartbeat,
+		"heartbeat",
+		defaults.ExecHeartbeat,
+		"send a heartbeat child span at this interval while the command runs, e.g. 30s, so long commands show up before they exit",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecSpansFromOutput,
+		"spans-from-output",
+		defaults.ExecSpansFromOutput,
+		"add events and child spans from OTEL_CLI_EVENT/SPAN_START/SPAN_END markers in the command's output",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecPerLineEvents,
+		"per-line-events",
+		defaults.ExecPerLineEvents,
+		"record each line the command prints as a span event, with the stream in the log.iostream attribute",
+	)
+
+	cmd.Flags().IntVar(
+		&config.ExecMaxEvents,
+		"max-events",
+		defaults.ExecMaxEvents,
+		"with --per-line-events, record at most this many lines and count the rest as dropped, 0 for no limit",
+	)
+
+	cmd.Flags().StringVar(
+		&config.ExecEventMatch,
+		"event-match",
+		defaults.ExecEventMatch,
+		"with --per-line-events, only record lines matching this regular expression",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecProvenance,
+		"provenance",
+		defaults.ExecProvenance,
+		"record the working directory and the SHA-256, size, and mtime of the executable",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecRusage,
+		"rusage",
+		defaults.ExecRusage,
+		"record the command's cpu time, max RSS, I/O, page faults, and context switches once it exits",
+	)
+
+	cmd.Flags().BoolVar(
+		&config.ExecStatusFromExitCode,
+		"status-from-exit-code",
+		defaults.ExecStatusFromExitCode,
+		"set the span status from the command's exit code, using --status-map or "+defaultStatusMap,
+	)
+
+	cmd.Flags().StringVar(
+		&config.ExecStatusMap,
+		"status-map",
+		defaults.ExecStatusMap,
+		"map exit codes to span status, first match wins, e.g. \"0=ok,1-127=error\", implies --status-from-exit-code",
+	)
+
 	return &cmd
 }
 
@@ -63,6 +265,7 @@ func doExec(cmd *cobra.Command, args []string) {
 	config := getConfig(ctx)
 	span := config.NewProtobufSpan()
 	processAttrs := processArgAttrs(args) // might be overwritten in process setup
+	statusRules := config.ParseStatusMap()
 
 	// no deadline if there is no command timeout set
 	cancelCtxDeadline := func() {}
@@ -72,6 +275,10 @@ func doExec(cmd *cobra.Command, args []string) {
 	if cmdTimeout > 0 {
 		cmdCtx, cancelCtxDeadline = context.WithDeadline(ctx, time.Now().Add(cmdTimeout))
 	}
+	// parsed up front since a bad value fails before the child is started,
+	// instead of leaving it running when otel-cli exits
+	sampleInterval := config.ParseExecSampleResources()
+	heartbeatInterval := config.ParseExecHeartbeat()
 
 	// pass the existing env but add the latest TRACEPARENT carrier so e.g.
 	// otel-cli exec 'otel-cli exec sleep 1' will relate the spans automatically
@@ -80,98 +287,301 @@ func doExec(cmd *cobra.Command, args []string) {
 	// set the traceparent to the current span to be available to the child process
 	var tp traceparent.Traceparent
 	if config.GetIsRecording() {
-		tp = otlpclient.TraceparentFromProtobufSpan(span, config.GetIsRecording())
-		childEnv = append(childEnv, fmt.Sprintf("TRACEPARENT=%s", tp.Encode()))
-		// when not recording, and a traceparent is available, pass it through
+		tp = otlpclient.TraceparentFromProtobufSpan(span, config.GetIsSampled(span.TraceId))
 	} else if !config.TraceparentIgnoreEnv {
-		tp := config.LoadTraceparent()
+		// when not recording, and a traceparent is available, pass it through
+		tp = config.LoadTraceparent()
+	}
+
+	// --inject picks how the traceparent gets to the child, by default the
+	// envvars of --propagation-format
+	injectors, err := config.parseExecInjectors()
+	config.SoftFailIfErr(err)
+	stripEnv := traceparent.W3C.EnvVars()
+	for _, inj := range injectors {
+		stripEnv = append(stripEnv, inj.EnvVars()...)
 		if tp.Initialized {
-			childEnv = append(childEnv, fmt.Sprintf("TRACEPARENT=%s", tp.Encode()))
+			env, err := inj.Inject(tp)
+			config.SoftFailIfErr(err)
+			childEnv = append(childEnv, env...)
+		}
+	}
+	// --tp-http-stdin passes the request's baggage along with the traceparent
+	if config.TraceparentHttpStdin {
+		req, _ := config.readStdinHttpRequest()
+		if baggage := req.baggage(); baggage != "" {
+			childEnv = append(childEnv, "BAGGAGE="+baggage)
+			stripEnv = append(stripEnv, "BAGGAGE")
+		}
+	}
+	// only the injected envvars so far, for --docker-env
+	injectedEnv := slices.Clone(childEnv)
+
+	// --inject-otel-env points instrumented commands at otel-cli's endpoint.
+	// these aren't passed through --docker-env, since headers could end up
+	// in the arguments and the endpoint is often only reachable from the host
+	if config.ExecInjectOtelEnv {
+		childEnv = append(childEnv, config.otelEnv(os.Getenv)...)
+		stripEnv = append(stripEnv, otelEnvVars...)
+	}
+
+	argv := make([]string, len(args))
+	copy(argv, args)
+	if len(args) > 1 && !config.ExecTpDisableInject && !config.FeatureEnabled(featureStrictExecArgv) {
+		// loop over the args replacing {{traceparent}} with the current tp
+		for i, arg := range args[1:] {
+			argv[i+1] = strings.Replace(arg, "{{traceparent}}", tp.Encode(), -1)
 		}
+
+		// overwrite process args attributes with the injected values
+		processAttrs = processArgAttrs(argv)
+	}
+
+	// --docker-env and --inject-flag-template carry the trace context through
+	// launchers like docker run, where the child's environment stops
+	if tp.Initialized && (config.ExecDockerEnv || len(config.ExecFlagTemplates) > 0) {
+		argv, err = config.launcherArgv(argv, tp, injectedEnv)
+		config.SoftFailIfErr(err)
+		processAttrs = processArgAttrs(argv)
 	}
 
+	// arguments are passed to the command exactly as they are, execve-style,
+	// only --shell joins them into a command line for the shell to split
 	var child *exec.Cmd
-	if len(args) > 1 {
-		tpArgs := make([]string, len(args)-1)
-
-		if config.ExecTpDisableInject {
-			copy(tpArgs, args[1:])
-		} else {
-			// loop over the args replacing {{traceparent}} with the current tp
-			for i, arg := range args[1:] {
-				tpArgs[i] = strings.Replace(arg, "{{traceparent}}", tp.Encode(), -1)
+	if config.ExecShell {
+		shell := shellArgv(strings.Join(argv, " "))
+		child = exec.CommandContext(cmdCtx, shell[0], shell[1:]...)
+	} else {
+		child = exec.CommandContext(cmdCtx, argv[0], argv[1:]...)
+	}
+
+	// when --command-timeout is up, the command gets --kill-grace to clean up
+	// after SIGTERM before exec kills it. The timeout is recorded right when
+	// it happens, so a command that finishes just in time isn't marked as
+	// timed out by the time its span is put together.
+	var timedOut atomic.Bool
+	if cmdTimeout > 0 {
+		grace := config.ParseExecKillGrace()
+		child.Cancel = func() error {
+			timedOut.Store(errors.Is(cmdCtx.Err(), context.DeadlineExceeded))
+			if grace > 0 {
+				return terminateProcess(child.Process)
 			}
+			return child.Process.Kill()
+		}
+		child.WaitDelay = grace
+	}
+
+	// --spans-from-output watches the output for markers on its way through
+	var stdout, stderr io.Writer = config.getStdout(), config.getStderr()
+	var markers *outputMarkers
+	if config.ExecSpansFromOutput {
+		markers = newOutputMarkers(config, span)
+		stdout = markers.Writer(config.getStdout())
+		stderr = markers.Writer(config.getStderr())
+	}
 
-			// overwrite process args attributes with the injected values
-			processAttrs = processArgAttrs(append([]string{args[0]}, tpArgs...))
+	// --per-line-events records each line of output as an event on the way through
+	var lines *lineEvents
+	if config.ExecPerLineEvents {
+		var err error
+		lines, err = newLineEvents(config.ExecEventMatch, config.ExecMaxEvents)
+		if err != nil {
+			config.SoftFail("invalid --event-match: %s", err)
 		}
+		stdout = lines.Writer(stdout, "stdout", config.now)
+		stderr = lines.Writer(stderr, "stderr", config.now)
+	}
 
-		child = exec.CommandContext(cmdCtx, args[0], tpArgs...)
-	} else {
-		child = exec.CommandContext(cmdCtx, args[0])
+	// the headers were already read for --tp-http-stdin, the command gets
+	// the body, or everything that was read when it wasn't a request
+	var stdin io.Reader = os.Stdin
+	if config.TraceparentHttpStdin {
+		req, _ := config.readStdinHttpRequest()
+		stdin = req.body
 	}
 
-	// attach all stdio to the parent's handles
-	child.Stdin = os.Stdin
-	child.Stdout = os.Stdout
-	child.Stderr = os.Stderr
+	// attach all stdio to the parent's handles, --pty sets up its own
+	if !config.ExecPty {
+		child.Stdin = stdin
+		child.Stdout = stdout
+		child.Stderr = stderr
+	}
 
-	// grab everything BUT the TRACEPARENT envvar
+	// --login-shell resolves the command and sets PATH for the child from the
+	// login shell, for e.g. CI steps that depend on PATH set up in a profile.
+	// The lookup has its own deadline and isn't counted against --timeout,
+	// since profile scripts are often slower than an OTLP export.
+	var loginShellRuntime time.Duration
+	if config.ExecLoginShell {
+		lookupStarted := time.Now()
+		shellPath, err := loginShellPath(ctx, loginShellTimeout)
+		loginShellRuntime = time.Since(lookupStarted)
+		if err != nil {
+			// run the command anyways, it may not need the profile's PATH
+			shellPath = os.Getenv("PATH")
+			log.New(config.getStderr(), log.Prefix(), log.Flags()).Printf("warning: %s, using the current PATH", err)
+		}
+		// with --shell the shell finds the command in the PATH set below
+		if !config.ExecShell {
+			// a command that isn't found fails to start like it would without
+			// --login-shell, replacing the lookup error from exec.CommandContext
+			child.Path, child.Err = lookPathIn(args[0], shellPath)
+		}
+		childEnv = append(childEnv, "PATH="+shellPath)
+	}
+
+	// grab everything BUT the TRACEPARENT and TRACESTATE envvars, or the
+	// injectors', and PATH if it came from the login shell
 	for _, env := range os.Environ() {
-		if !strings.HasPrefix(env, "TRACEPARENT=") {
-			childEnv = append(childEnv, env)
+		name, _, _ := strings.Cut(env, "=")
+		if slices.Contains(stripEnv, name) {
+			continue
+		} else if config.ExecLoginShell && strings.HasPrefix(env, "PATH=") {
+			continue
 		}
+		childEnv = append(childEnv, env)
 	}
 	child.Env = childEnv
 
-	// ctrl-c (sigint) is forwarded to the child process
-	signals := make(chan os.Signal, 10)
-	signalsDone := make(chan struct{})
-	signal.Notify(signals, os.Interrupt)
-	go func() {
-		sig := <-signals
-		child.Process.Signal(sig)
-		// this might not seem necessary but without it, otel-cli exits before sending the span
-		close(signalsDone)
-	}()
-
-	span.StartTimeUnixNano = uint64(time.Now().UnixNano())
-	if err := child.Run(); err != nil {
+	// --provenance records what exactly is about to run, so it's hashed right
+	// before starting instead of after, when it may have been replaced
+	if config.ExecProvenance && child.Err == nil {
+		provAttrs, err := provenanceAttrs(child.Dir, child.Path)
+		config.SoftLogIfErr(err)
+		span.Attributes = append(span.Attributes, provAttrs...)
+	}
+
+	// --forward-signals, ctrl-c and SIGTERM by default, are passed on to the
+	// child process once it's running. this might not seem necessary but
+	// without it, otel-cli exits before sending the span
+	forwarder := newSignalForwarder(config.ParseExecForwardSignals())
+
+	// --sample-resources watches the child's process tree while it runs, and
+	// --heartbeat sends spans to show it's still running
+	var sampler *resourceSampler
+	var beats *heartbeat
+	started := func() {
+		forwarder.Start(child.Process, config.now)
+		if sampleInterval > 0 {
+			sampler = startResourceSampler(child.Process.Pid, sampleInterval)
+		}
+		if heartbeatInterval > 0 {
+			beats = startHeartbeat(config, span, heartbeatInterval)
+		}
+	}
+
+	span.StartTimeUnixNano = uint64(config.now().UnixNano())
+	childStarted := time.Now() // not config.now(), which can be pinned
+	var runErr error
+	if config.ExecPty {
+		runErr = runWithPty(child, stdin, stdout, started)
+	} else if runErr = child.Start(); runErr == nil {
+		started()
+		runErr = child.Wait()
+	}
+	childRuntime := time.Since(childStarted)
+	if beats != nil {
+		span.Attributes = append(span.Attributes, intAttr("heartbeat.count", int64(beats.Stop())))
+	}
+	if runErr != nil {
 		span.Status = &tracev1.Status{
-			Message: fmt.Sprintf("exec command failed: %s", err),
+			Message: fmt.Sprintf("exec command failed: %s", runErr),
 			Code:    tracev1.Status_STATUS_CODE_ERROR,
 		}
 	}
-	span.EndTimeUnixNano = uint64(time.Now().UnixNano())
+	// --status-from-exit-code replaces the status from runErr for commands
+	// that exited, commands that couldn't start or were killed keep theirs
+	if statusRules != nil && child.ProcessState != nil && child.ProcessState.Exited() {
+		code := child.ProcessState.ExitCode()
+		span.Attributes = append(span.Attributes, &commonpb.KeyValue{
+			Key:   "process.exit_code",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(code)}},
+		})
+		if status, ok := statusForExitCode(statusRules, code); ok {
+			span.Status = &tracev1.Status{Code: otlpclient.SpanStatusStringToInt(status)}
+			if status == "error" {
+				span.Status.Message = fmt.Sprintf("exited with code %d", code)
+			}
+		}
+	}
+	// a child killed by a signal gets the signal details, e.g. to find crashes
+	if sigAttrs, message, ok := exitSignalAttrs(child.ProcessState); ok {
+		span.Status.Message = message
+		span.Attributes = append(span.Attributes, sigAttrs...)
+	}
+	// a command that ran out of time failed however it exited, the signal that
+	// ended it, if any, is kept in the message
+	if timedOut.Load() {
+		message := fmt.Sprintf("exec command timed out after %s", cmdTimeout)
+		if _, sigMessage, ok := exitSignalAttrs(child.ProcessState); ok {
+			message += ", " + strings.TrimPrefix(sigMessage, "exec command ")
+		}
+		span.Status = &tracev1.Status{
+			Message: message,
+			Code:    tracev1.Status_STATUS_CODE_ERROR,
+		}
+		span.Attributes = append(span.Attributes, boolAttr("timeout", true))
+	}
+	span.EndTimeUnixNano = uint64(config.now().UnixNano())
+
+	if lines != nil {
+		lines.Finish(span)
+	}
+
+	spans := []*tracev1.Span{span}
+	if markers != nil {
+		spans = append(spans, markers.Finish(config.now())...)
+	}
 
 	// append process attributes
 	span.Attributes = append(span.Attributes, processAttrs...)
-	pidAttrs := processPidAttrs(config, int64(child.Process.Pid), int64(os.Getpid()))
-	span.Attributes = append(span.Attributes, pidAttrs...)
+	// child.Process is nil when the command couldn't be started, e.g. not found
+	if child.Process != nil {
+		pidAttrs := processPidAttrs(config, int64(child.Process.Pid), int64(os.Getpid()))
+		span.Attributes = append(span.Attributes, pidAttrs...)
+	}
+	if config.ExecRusage {
+		span.Attributes = append(span.Attributes, rusageAttrs(child.ProcessState)...)
+	}
+
+	if sampler != nil {
+		samples, err := sampler.Stop()
+		config.SoftLogIfErr(err)
+		addResourceSamples(span, samples)
+	}
 
 	cancelCtxDeadline()
-	close(signals)
-	<-signalsDone
+	span.Events = append(span.Events, forwarder.Stop()...)
 
-	// set --timeout on just the OTLP egress, starting now instead of process start time
-	ctx, cancelCtxDeadline = context.WithDeadline(ctx, time.Now().Add(config.GetTimeout()))
+	// --timeout covers otel-cli's own setup and the OTLP egress but not the
+	// time the child spent running
+	ctx, cancelCtxDeadline = config.timeoutContext(ctx, childRuntime+loginShellRuntime)
 	defer cancelCtxDeadline()
 
+	config.ApplyDurationRules(span)
+
+	spans = config.sampledSpans(spans...)
+	config.WriteSpanJsonOut(ctx, spans...)
 	ctx, client := StartClient(ctx, config)
-	ctx, err := otlpclient.SendSpan(ctx, client, config, span)
+	ctx, err = otlpclient.SendSpans(ctx, client, config, spans)
 	if err != nil {
+		config.SoftLogErrorList(ctx)
 		config.SoftFail("unable to send span: %s", err)
 	}
+	ctx = config.SendAlsoLog(ctx, client, span)
 
 	_, err = client.Stop(ctx)
 	if err != nil {
 		config.SoftFail("client.Stop() failed: %s", err)
 	}
 
-	// set the global exit code so main() can grab it and os.Exit() properly
-	Diag.ExecExitCode = child.ProcessState.ExitCode()
+	// record the exit code so Execute can return it for main() to os.Exit() with
+	config.diag.update(func(d *Diagnostics) { d.ExecExitCode = child.ProcessState.ExitCode() })
 
-	config.PropagateTraceparent(span, os.Stdout)
+	config.PropagateTraceparent(span, config.getStdout())
+	config.PrintSpanJson(span, config.getStdout())
+	config.PrintSpanUrl(span, config.getStderr())
 }
 
 // processArgAttrs turns the provided args list into OTel attributes
@@ -233,3 +643,12 @@ func processPidAttrs(config Config, ppid, pid int64) []*commonpb.KeyValue {
 		},
 	}
 }
+
+// shellArgv returns the arguments that run a command line with the system
+// shell, for exec --shell.
+func shellArgv(line string) []string {
+	if runtime.GOOS == "windows" {
+		return []string{"cmd", "/C", line}
+	}
+	return []string{"/bin/sh", "-c", line}
+}
@@ -0,0 +1,111 @@
+package otelcli
+
+import (
+	"context"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// heartbeat sends a short child span of the exec span every interval while
+// the command runs, so a long or hung command shows up in the trace before
+// it exits. Each heartbeat span covers the time since the last one.
+type heartbeat struct {
+	stop chan struct{}
+	done chan struct{}
+	sent int // only heartbeats the collector accepted
+}
+
+// startHeartbeat starts sending heartbeats for the exec span every interval.
+// Nothing is sent when the exec span won't be either: when otel-cli isn't
+// recording, with --dry-run, or when the span isn't sampled.
+func startHeartbeat(config Config, parent *tracepb.Span, interval time.Duration) *heartbeat {
+	hb := heartbeat{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if !config.GetIsRecording() || config.DryRun || !config.GetIsSampled(parent.TraceId) {
+		close(hb.done)
+		return &hb
+	}
+
+	// the exec span's ids are copied since the span itself keeps changing
+	traceId := append([]byte{}, parent.TraceId...)
+	spanId := append([]byte{}, parent.SpanId...)
+	started := config.now()
+
+	go func() {
+		defer close(hb.done)
+
+		last := started
+		sequence := 0
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hb.stop:
+				return
+			case <-ticker.C:
+				now := config.now()
+				sequence++
+
+				span := otlpclient.NewProtobufSpan()
+				span.Name = "heartbeat"
+				span.Kind = tracepb.Span_SPAN_KIND_INTERNAL
+				span.TraceId = traceId
+				span.SpanId = otlpclient.GenerateSpanId()
+				span.ParentSpanId = spanId
+				span.StartTimeUnixNano = uint64(last.UnixNano())
+				span.EndTimeUnixNano = uint64(now.UnixNano())
+				span.Attributes = append(span.Attributes,
+					intAttr("heartbeat.sequence", int64(sequence)),
+					doubleAttr("heartbeat.elapsed_seconds", now.Sub(started).Seconds()),
+				)
+				// a collector that's down shouldn't get in the way of the command
+				if err := sendHeartbeat(config, span); err != nil {
+					config.SoftLog("failed to send heartbeat: %s", err)
+				} else {
+					hb.sent++
+				}
+				last = now
+			}
+		}
+	}()
+
+	return &hb
+}
+
+// sendHeartbeat sends one heartbeat span with its own client and timeout, so
+// a slow collector only delays the next heartbeat.
+func sendHeartbeat(config Config, span *tracepb.Span) error {
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetTimeout())
+	defer cancel()
+
+	client, err := newOtlpClient(config)
+	if err != nil {
+		return err
+	}
+	if ctx, err = client.Start(ctx); err != nil {
+		return err
+	}
+	ctx, err = otlpclient.SendSpans(ctx, client, config, []*tracepb.Span{span})
+	if err != nil {
+		return err
+	}
+	_, err = client.Stop(ctx)
+	return err
+}
+
+// Stop stops the heartbeats and returns how many were sent successfully.
+// Failed sends still use up a heartbeat.sequence number, so gaps in the
+// sequence show where heartbeats went missing.
+func (hb *heartbeat) Stop() int {
+	select {
+	case <-hb.done:
+	default:
+		close(hb.stop)
+		<-hb.done
+	}
+	return hb.sent
+}
@@ -0,0 +1,121 @@
+package otelcli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var mu sync.Mutex
+	var got []*tracepb.Span
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		msg := coltracepb.ExportTraceServiceRequest{}
+		if err := proto.Unmarshal(body, &msg); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		for _, rs := range msg.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				got = append(got, ss.Spans...)
+			}
+		}
+		mu.Unlock()
+		rw.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	config := DefaultConfig().WithEndpoint(srv.URL + "/v1/traces")
+	parent := config.NewProtobufSpan()
+	beats := startHeartbeat(config, parent, 10*time.Millisecond)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		mu.Lock()
+		n := len(got)
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+	}
+	count := beats.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) < 2 || count != len(got) {
+		t.Fatalf("expected at least 2 heartbeats and a count of them, got %d spans and a count of %d", len(got), count)
+	}
+	for i, span := range got[:2] {
+		if span.Name != "heartbeat" || !bytes.Equal(span.TraceId, parent.TraceId) || !bytes.Equal(span.ParentSpanId, parent.SpanId) {
+			t.Errorf("heartbeat %d isn't a child of the exec span: %v", i, span)
+		}
+		attrs := otlpclient.SpanAttributesToStringMap(span)
+		if want := []string{"1", "2"}[i]; attrs["heartbeat.sequence"] != want {
+			t.Errorf("heartbeat %d: expected sequence %s, got %q", i, want, attrs["heartbeat.sequence"])
+		}
+	}
+	if got[1].StartTimeUnixNano != got[0].EndTimeUnixNano {
+		t.Error("expected each heartbeat to start where the last one ended")
+	}
+}
+
+func TestHeartbeatNotRecording(t *testing.T) {
+	config := DefaultConfig()
+	beats := startHeartbeat(config, config.NewProtobufSpan(), time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	if count := beats.Stop(); count != 0 {
+		t.Errorf("expected no heartbeats when not recording, got %d", count)
+	}
+}
+
+// heartbeats the collector rejects aren't counted, but still use up a
+// sequence number
+func TestHeartbeatFailedSends(t *testing.T) {
+	var mu sync.Mutex
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.Error(rw, "nope", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	config := DefaultConfig().WithEndpoint(srv.URL + "/v1/traces")
+	beats := startHeartbeat(config, config.NewProtobufSpan(), 10*time.Millisecond)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		mu.Lock()
+		n := requests
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+	}
+	if count := beats.Stop(); count != 0 {
+		t.Errorf("expected failed heartbeats not to be counted, got %d", count)
+	}
+}
+
+// a bad --heartbeat fails before the command is started
+func TestHeartbeatInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ran")
+	var stdout, stderr bytes.Buffer
+	if code := Run([]string{"exec", "--fail", "--heartbeat", "bogus", "--", "touch", path}, &stdout, &stderr); code == 0 {
+		t.Error("expected a bad --heartbeat to fail")
+	}
+	time.Sleep(100 * time.Millisecond) // time for a command left running to get there
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the command not to run")
+	}
+}
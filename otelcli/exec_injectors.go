@@ -0,0 +1,143 @@
+package otelcli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+// execInjector passes the exec span's traceparent on to the child process.
+// More than one can be configured with --inject, so a child can get e.g. both
+// W3C and B3 envvars, or a file for tools that don't read the environment.
+type execInjector interface {
+	// Inject returns NAME=value envvars to add to the child's environment,
+	// and may also put the traceparent somewhere else, e.g. a file.
+	Inject(tp traceparent.Traceparent) ([]string, error)
+	// EnvVars returns the names of the envvars Inject sets, which are left
+	// out of the inherited environment so stale values don't leak through.
+	EnvVars() []string
+}
+
+// execInjectorKinds maps the prefix of an --inject spec, the part before the
+// first colon, to a func that builds the injector from the rest. Bare format
+// names like w3c or b3 are handled by parseExecInjector.
+var execInjectorKinds = map[string]func(arg string, config Config) (execInjector, error){
+	"env":  newEnvTemplateInjector,
+	"file": newFileInjector,
+}
+
+// parseExecInjectors returns the injectors for --inject, or one for
+// --propagation-format when none are configured.
+func (c Config) parseExecInjectors() ([]execInjector, error) {
+	if len(c.ExecInjectors) == 0 {
+		return []execInjector{formatInjector(c.GetPropagationFormat())}, nil
+	}
+
+	out := make([]execInjector, len(c.ExecInjectors))
+	for i, spec := range c.ExecInjectors {
+		inj, err := parseExecInjector(spec, c)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = inj
+	}
+	return out, nil
+}
+
+// parseExecInjector parses one --inject spec, either a propagation format
+// name or kind:argument, e.g. file:/tmp/tp.env.
+func parseExecInjector(spec string, config Config) (execInjector, error) {
+	kind, arg, hasArg := strings.Cut(spec, ":")
+	if !hasArg {
+		f, err := traceparent.ParseFormat(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --inject %q: %w", spec, err)
+		}
+		return formatInjector(f), nil
+	}
+
+	newInjector, ok := execInjectorKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("invalid --inject %q: unknown kind %q, expected a propagation format, env:, or file:", spec, kind)
+	}
+	inj, err := newInjector(arg, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --inject %q: %w", spec, err)
+	}
+	return inj, nil
+}
+
+// formatInjector sets the envvars of a propagation format, e.g. TRACEPARENT
+// and TRACESTATE for w3c.
+type formatInjector traceparent.Format
+
+func (fi formatInjector) Inject(tp traceparent.Traceparent) ([]string, error) {
+	return traceparent.Format(fi).Env(tp), nil
+}
+
+func (fi formatInjector) EnvVars() []string {
+	return traceparent.Format(fi).EnvVars()
+}
+
+// envTemplateInjector sets one envvar from a template, for conventions none
+// of the formats cover, e.g. env:REQUEST_ID={{trace_id}}-{{span_id}}.
+type envTemplateInjector struct {
+	name     string
+	template string
+}
+
+func newEnvTemplateInjector(arg string, config Config) (execInjector, error) {
+	name, template, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("expected env:NAME=template")
+	}
+	return envTemplateInjector{name: name, template: template}, nil
+}
+
+func (ei envTemplateInjector) Inject(tp traceparent.Traceparent) ([]string, error) {
+	return []string{ei.name + "=" + expandTraceTemplate(ei.template, tp)}, nil
+}
+
+func (ei envTemplateInjector) EnvVars() []string {
+	return []string{ei.name}
+}
+
+// expandTraceTemplate fills in {{traceparent}}, {{trace_id}}, {{span_id}},
+// {{sampled}}, and {{tracestate}} in the template.
+func expandTraceTemplate(template string, tp traceparent.Traceparent) string {
+	sampled := "0"
+	if tp.Sampling {
+		sampled = "1"
+	}
+	return strings.NewReplacer(
+		"{{traceparent}}", tp.Encode(),
+		"{{trace_id}}", tp.TraceIdString(),
+		"{{span_id}}", tp.SpanIdString(),
+		"{{sampled}}", sampled,
+		"{{tracestate}}", tp.Tracestate.Encode(),
+	).Replace(template)
+}
+
+// fileInjector writes a carrier file in --propagation-format before the
+// child starts, for tools that read a file instead of the environment.
+type fileInjector struct {
+	path   string
+	format traceparent.Format
+	export bool
+}
+
+func newFileInjector(arg string, config Config) (execInjector, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("expected file:PATH")
+	}
+	return fileInjector{path: arg, format: config.GetPropagationFormat(), export: config.TraceparentPrintExport}, nil
+}
+
+func (fi fileInjector) Inject(tp traceparent.Traceparent) ([]string, error) {
+	return nil, tp.SaveToFileWithFormat(fi.path, fi.export, fi.format)
+}
+
+func (fi fileInjector) EnvVars() []string {
+	return nil
+}
@@ -0,0 +1,77 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExecInjectors(t *testing.T) {
+	tp, err := traceparent.Parse("00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	carrier := filepath.Join(t.TempDir(), "tp.env")
+
+	// the default is --propagation-format
+	injectors, err := DefaultConfig().WithPropagationFormat("jaeger").parseExecInjectors()
+	if err != nil || len(injectors) != 1 {
+		t.Fatalf("expected one default injector, got %v: %v", injectors, err)
+	}
+	env, _ := injectors[0].Inject(tp)
+	if diff := cmp.Diff([]string{"UBER_TRACE_ID=0102030405060708090a0b0c0d0e0f10:0101010101010101:0:1"}, env); diff != "" {
+		t.Errorf("default injector env mismatch (-want +got):\n%s", diff)
+	}
+
+	config := DefaultConfig().WithExecInjectors([]string{
+		"w3c",
+		"b3",
+		"env:REQUEST_ID={{trace_id}}-{{span_id}}-{{sampled}}",
+		"file:" + carrier,
+	})
+	injectors, err = config.parseExecInjectors()
+	if err != nil {
+		t.Fatalf("unexpected error parsing injectors: %s", err)
+	}
+
+	env, names := []string{}, []string{}
+	for _, inj := range injectors {
+		got, err := inj.Inject(tp)
+		if err != nil {
+			t.Errorf("unexpected error from %T: %s", inj, err)
+		}
+		env = append(env, got...)
+		names = append(names, inj.EnvVars()...)
+	}
+
+	wantEnv := []string{
+		"TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01",
+		"B3=0102030405060708090a0b0c0d0e0f10-0101010101010101-1",
+		"REQUEST_ID=0102030405060708090a0b0c0d0e0f10-0101010101010101-1",
+	}
+	if diff := cmp.Diff(wantEnv, env); diff != "" {
+		t.Errorf("injected env mismatch (-want +got):\n%s", diff)
+	}
+	wantNames := []string{"TRACEPARENT", "TRACESTATE", "B3", "REQUEST_ID"}
+	if diff := cmp.Diff(wantNames, names); diff != "" {
+		t.Errorf("envvar names mismatch (-want +got):\n%s", diff)
+	}
+
+	data, err := os.ReadFile(carrier)
+	if err != nil {
+		t.Fatalf("expected file: to write a carrier: %s", err)
+	}
+	if !strings.Contains(string(data), "TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01") {
+		t.Errorf("unexpected carrier file contents %q", string(data))
+	}
+
+	for _, spec := range []string{"w4c", "nope:x", "env:=x", "env:NAME", "file:"} {
+		if _, err := DefaultConfig().WithExecInjectors([]string{spec}).parseExecInjectors(); err == nil {
+			t.Errorf("expected an error for --inject %q", spec)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package otelcli
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+// launcher is where trace context can be added to the argv of a command that
+// starts another process somewhere the environment doesn't follow, like a
+// container or a remote host.
+type launcher struct {
+	// flagsAt is the index in argv the launcher's own flags can go at, used
+	// for --inject-flag-template
+	flagsAt int
+	// envAt is the index in argv envvars go at for --docker-env, or -1 when
+	// there's no way to pass them, e.g. docker build
+	envAt int
+	// envArgs returns the arguments that pass the NAME=value envvars
+	envArgs func(env []string) []string
+}
+
+// dockerValueFlags are the global flags of docker and friends that take the
+// next argument as their value, so it isn't mistaken for the subcommand.
+var dockerValueFlags = []string{"-c", "--context", "--config", "-H", "--host", "-l", "--log-level", "--tlscacert", "--tlscert", "--tlskey", "--url", "--connection", "--namespace", "-n", "--address", "-a"}
+
+// kubectlValueFlags are kubectl's common global flags that take the next
+// argument as their value.
+var kubectlValueFlags = []string{"-n", "--namespace", "--context", "--kubeconfig", "--cluster", "--user", "-s", "--server", "--as", "--token", "-v", "--v"}
+
+// sshValueOptions are the ssh options that take a value, either the rest of
+// the argument or the next one, from ssh(1).
+const sshValueOptions = "BbcDEeFIiJLlmOoPpQRSWw"
+
+// findLauncher returns where trace context goes in argv for the launchers
+// otel-cli knows about: docker, podman, and nerdctl run/create/exec, kubectl
+// run/exec, and ssh. ok is false for any other command.
+func findLauncher(argv []string) (l launcher, ok bool) {
+	switch strings.TrimSuffix(filepath.Base(argv[0]), ".exe") {
+	case "docker", "podman", "nerdctl":
+		return dockerLauncher(argv), true
+	case "kubectl":
+		return kubectlLauncher(argv), true
+	case "ssh":
+		return sshLauncher(argv), true
+	}
+	return launcher{flagsAt: 1, envAt: -1}, false
+}
+
+// dockerLauncher adds --env flags right after run, create, or exec, e.g.
+// docker run --env TRACEPARENT=... alpine env.
+func dockerLauncher(argv []string) launcher {
+	l := launcher{flagsAt: 1, envAt: -1}
+	i := firstCommandArg(argv, 1, dockerValueFlags)
+	// docker container run is the same as docker run
+	if i > 0 && argv[i] == "container" {
+		i = firstCommandArg(argv, i+1, dockerValueFlags)
+	}
+	if i < 0 {
+		return l
+	}
+
+	l.flagsAt = i + 1
+	if slices.Contains([]string{"run", "create", "exec"}, argv[i]) {
+		l.envAt = i + 1
+		l.envArgs = func(env []string) []string {
+			out := []string{}
+			for _, kv := range env {
+				out = append(out, "--env", kv)
+			}
+			return out
+		}
+	}
+	return l
+}
+
+// kubectlLauncher adds --env flags to kubectl run. kubectl exec has no way to
+// set envvars, so the command after -- is run with env(1) instead.
+func kubectlLauncher(argv []string) launcher {
+	l := launcher{flagsAt: 1, envAt: -1}
+	i := firstCommandArg(argv, 1, kubectlValueFlags)
+	if i < 0 {
+		return l
+	}
+
+	l.flagsAt = i + 1
+	switch argv[i] {
+	case "run":
+		l.envAt = i + 1
+		l.envArgs = func(env []string) []string {
+			out := make([]string, len(env))
+			for j, kv := range env {
+				out[j] = "--env=" + kv
+			}
+			return out
+		}
+	case "exec":
+		if dashes := slices.Index(argv, "--"); dashes > i && dashes < len(argv)-1 {
+			l.envAt = dashes + 1
+			l.envArgs = func(env []string) []string {
+				return append([]string{"env"}, env...)
+			}
+		}
+	}
+	return l
+}
+
+// sshLauncher puts the envvars in front of the remote command, which ssh
+// runs with the remote user's shell, so the values are quoted for it. ssh
+// without a remote command opens a login shell and there's nowhere for them.
+func sshLauncher(argv []string) launcher {
+	l := launcher{flagsAt: 1, envAt: -1}
+
+	for i := 1; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			i++
+		} else if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			// options can be combined, e.g. -vp 22, and the first one that
+			// takes a value gets the rest of the argument or the next one
+			for j := 1; j < len(arg); j++ {
+				if strings.IndexByte(sshValueOptions, arg[j]) >= 0 {
+					if j == len(arg)-1 {
+						i++
+					}
+					break
+				}
+			}
+			continue
+		}
+
+		// argv[i] is the destination, anything after it is the command
+		if i+1 < len(argv) {
+			l.envAt = i + 1
+			l.envArgs = func(env []string) []string {
+				out := make([]string, len(env))
+				for j, kv := range env {
+					name, value, _ := strings.Cut(kv, "=")
+					out[j] = name + "=" + posixQuote(value)
+				}
+				return out
+			}
+		}
+		break
+	}
+	return l
+}
+
+// firstCommandArg returns the index of the first argument from start on that
+// isn't a flag or a flag's value, or -1 when there isn't one before --.
+func firstCommandArg(argv []string, start int, valueFlags []string) int {
+	for i := start; i < len(argv); i++ {
+		arg := argv[i]
+		if arg == "--" {
+			return -1
+		} else if !strings.HasPrefix(arg, "-") {
+			return i
+		} else if slices.Contains(valueFlags, arg) {
+			i++ // skip the value, --flag=value is one argument
+		}
+	}
+	return -1
+}
+
+// launcherArgv returns argv with the --inject-flag-template flags and, with
+// --docker-env, the injected envvars added where the launcher expects them, so
+// trace context makes it into containers and onto remote hosts. Templates are
+// split into arguments before the trace context is filled in, so a value can
+// never become more than one argument.
+func (c Config) launcherArgv(argv []string, tp traceparent.Traceparent, env []string) ([]string, error) {
+	l, known := findLauncher(argv)
+
+	out := slices.Clone(argv)
+	if c.ExecDockerEnv && len(env) > 0 {
+		if !known {
+			return nil, fmt.Errorf("--docker-env doesn't know how to pass envvars through %q, expected docker, podman, nerdctl, kubectl, or ssh", argv[0])
+		} else if l.envAt < 0 {
+			return nil, fmt.Errorf("--docker-env can't pass envvars through %q, expected run, create, or exec for containers, exec with -- for kubectl, or a remote command for ssh", strings.Join(argv, " "))
+		}
+		out = slices.Insert(out, l.envAt, l.envArgs(env)...)
+	}
+
+	// flagsAt is never after envAt so inserting the flags doesn't move the env
+	flags := []string{}
+	for _, template := range c.ExecFlagTemplates {
+		for _, word := range strings.Fields(template) {
+			flags = append(flags, expandTraceTemplate(word, tp))
+		}
+	}
+	return slices.Insert(out, l.flagsAt, flags...), nil
+}
@@ -0,0 +1,96 @@
+package otelcli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLauncherArgv(t *testing.T) {
+	tp, err := traceparent.Parse("00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := []string{
+		"TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01",
+		"REQUEST_ID=it's 1",
+	}
+	dockerEnv := DefaultConfig().WithExecDockerEnv(true)
+
+	for _, tc := range []struct {
+		config Config
+		argv   string
+		want   string
+		err    bool
+	}{
+		{
+			config: dockerEnv,
+			argv:   "docker --context prod run --rm alpine env",
+			want:   "docker --context prod run --env TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 --env REQUEST_ID=it's 1 --rm alpine env",
+		},
+		{
+			config: dockerEnv,
+			argv:   "/usr/bin/podman container exec web sh",
+			want:   "/usr/bin/podman container exec --env TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 --env REQUEST_ID=it's 1 web sh",
+		},
+		{
+			config: dockerEnv,
+			argv:   "kubectl -n jobs run migrate --image=app",
+			want:   "kubectl -n jobs run --env=TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 --env=REQUEST_ID=it's 1 migrate --image=app",
+		},
+		{
+			config: dockerEnv,
+			argv:   "kubectl exec web -- ./migrate up",
+			want:   "kubectl exec web -- env TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 REQUEST_ID=it's 1 ./migrate up",
+		},
+		{
+			config: dockerEnv,
+			argv:   "ssh -vp 2222 -o BatchMode=yes deploy@web1 ./release.sh v2",
+			want:   "ssh -vp 2222 -o BatchMode=yes deploy@web1 TRACEPARENT='00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01' REQUEST_ID='it'\\''s 1' ./release.sh v2",
+		},
+		// nowhere to put them
+		{config: dockerEnv, argv: "ssh deploy@web1", err: true},
+		{config: dockerEnv, argv: "kubectl exec web", err: true},
+		{config: dockerEnv, argv: "docker build .", err: true},
+		{config: dockerEnv, argv: "make deploy", err: true},
+		// templates go after the subcommand, or the command when it's not a launcher
+		{
+			config: DefaultConfig().WithExecFlagTemplates([]string{"--env TRACEPARENT={{traceparent}}", "--label trace={{trace_id}}"}),
+			argv:   "docker run --rm alpine env",
+			want:   "docker run --env TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 --label trace=0102030405060708090a0b0c0d0e0f10 --rm alpine env",
+		},
+		{
+			config: DefaultConfig().WithExecFlagTemplates([]string{"--trace-id={{trace_id}}"}),
+			argv:   "./deploy.sh prod",
+			want:   "./deploy.sh --trace-id=0102030405060708090a0b0c0d0e0f10 prod",
+		},
+		// both, the flags come first
+		{
+			config: dockerEnv.WithExecFlagTemplates([]string{"--label trace={{trace_id}}"}),
+			argv:   "docker run alpine",
+			want:   "docker run --label trace=0102030405060708090a0b0c0d0e0f10 --env TRACEPARENT=00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01 --env REQUEST_ID=it's 1 alpine",
+		},
+	} {
+		argv := strings.Fields(tc.argv)
+		got, err := tc.config.launcherArgv(argv, tp, env)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for %q, got %q", tc.argv, got)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.argv, err)
+			continue
+		}
+
+		// compare with the env values joined back up, they're one argument each
+		if diff := cmp.Diff(tc.want, strings.Join(got, " ")); diff != "" {
+			t.Errorf("argv for %q did not match (-want +got):\n%s", tc.argv, diff)
+		}
+		if len(got) < len(argv) || got[0] != argv[0] {
+			t.Errorf("expected the command to be kept, got %q", got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package otelcli
+
+import (
+	"io"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// lineEvents records lines of the child's output as span events for exec
+// --per-line-events. Events are kept aside until Finish so they don't race
+// with --spans-from-output adding to the same span.
+type lineEvents struct {
+	match   *regexp.Regexp // nil records every line
+	max     int            // 0 is unlimited
+	events  []*tracepb.Span_Event
+	dropped uint32
+	writers []*lineWriter
+	mu      sync.Mutex
+}
+
+// newLineEvents returns a lineEvents that keeps lines matching match, up to
+// max of them. An empty match keeps every line and max 0 is unlimited.
+func newLineEvents(match string, max int) (*lineEvents, error) {
+	le := lineEvents{max: max, events: []*tracepb.Span_Event{}}
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			return nil, err
+		}
+		le.match = re
+	}
+	return &le, nil
+}
+
+// Writer returns an io.Writer that passes everything through to out and
+// records each line as an event with the stream's name, stdout or stderr,
+// stamped with the time from now.
+func (le *lineEvents) Writer(out io.Writer, stream string, now func() time.Time) io.Writer {
+	lw := &lineWriter{out: out, now: now, handleLine: func(line string, now time.Time) {
+		le.handleLine(stream, line, now)
+	}}
+	le.writers = append(le.writers, lw)
+	return lw
+}
+
+// handleLine adds an event for the line if it matches and there's room.
+// Lines over the limit are counted as dropped events.
+func (le *lineEvents) handleLine(stream, line string, now time.Time) {
+	if line == "" || (le.match != nil && !le.match.MatchString(line)) {
+		return
+	}
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	if le.max > 0 && len(le.events) >= le.max {
+		le.dropped++
+		return
+	}
+
+	event := otlpclient.NewProtobufSpanEvent()
+	event.Name = line
+	event.TimeUnixNano = uint64(now.UnixNano())
+	event.Attributes = []*commonpb.KeyValue{{
+		Key:   "log.iostream",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: stream}},
+	}}
+	le.events = append(le.events, event)
+}
+
+// Finish records output left without a trailing newline, then adds the
+// recorded events to the span, after any already on it.
+func (le *lineEvents) Finish(span *tracepb.Span) {
+	for _, lw := range le.writers {
+		lw.Flush()
+	}
+
+	le.mu.Lock()
+	defer le.mu.Unlock()
+
+	span.Events = append(span.Events, le.events...)
+	span.DroppedEventsCount += le.dropped
+	le.events = nil
+}
@@ -0,0 +1,66 @@
+package otelcli
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestLineEvents(t *testing.T) {
+	le, err := newLineEvents("^(build|test)", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	out := le.Writer(&stdout, "stdout", time.Now)
+	errOut := le.Writer(&stderr, "stderr", time.Now)
+
+	fmt.Fprint(out, "build started\nnoise\n")
+	fmt.Fprint(errOut, "test fail")
+	fmt.Fprint(errOut, "ed\r\n")
+	fmt.Fprint(out, "build done\n")
+
+	if stdout.String() != "build started\nnoise\nbuild done\n" || stderr.String() != "test failed\r\n" {
+		t.Errorf("output was not passed through unchanged: %q, %q", stdout.String(), stderr.String())
+	}
+
+	span := otlpclient.NewProtobufSpan()
+	le.Finish(span)
+
+	if len(span.Events) != 2 || span.DroppedEventsCount != 1 {
+		t.Fatalf("expected 2 events and 1 dropped, got %d and %d", len(span.Events), span.DroppedEventsCount)
+	}
+	for i, want := range []struct{ name, stream string }{{"build started", "stdout"}, {"test failed", "stderr"}} {
+		event := span.Events[i]
+		if event.Name != want.name || eventStream(event) != want.stream {
+			t.Errorf("expected event %q on %s but got %q on %s", want.name, want.stream, event.Name, eventStream(event))
+		}
+	}
+
+	// the last line is recorded even without a newline after it
+	le, _ = newLineEvents("", 0)
+	fmt.Fprint(le.Writer(&stdout, "stdout", time.Now), "one\ntwo")
+	span = otlpclient.NewProtobufSpan()
+	le.Finish(span)
+	if len(span.Events) != 2 || span.Events[1].Name != "two" {
+		t.Errorf("expected the trailing partial line as an event, got %v", span.Events)
+	}
+
+	if _, err := newLineEvents("(", 0); err == nil {
+		t.Error("expected an error for an invalid --event-match")
+	}
+}
+
+func eventStream(event *tracepb.Span_Event) string {
+	for _, attr := range event.Attributes {
+		if attr.Key == "log.iostream" {
+			return attr.Value.GetStringValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package otelcli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// loginShellPathMarker is printed in front of $PATH so it can be picked out
+// of whatever else profile scripts write to stdout.
+const loginShellPathMarker = "__otel_cli_login_shell_path="
+
+// loginShellTimeout is how long --login-shell waits for the login shell to
+// report its PATH. It's separate from --timeout, which is for OTLP exports.
+const loginShellTimeout = 30 * time.Second
+
+// loginShellPath starts the user's login shell and asks it for $PATH, so
+// PATH additions from profile scripts are available to exec. Only PATH is
+// taken from the shell, the command and its arguments never go through it.
+func loginShellPath(ctx context.Context, timeout time.Duration) (string, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	script := fmt.Sprintf(`printf '\n%s%%s\n' "$PATH"`, loginShellPathMarker)
+	out, err := exec.CommandContext(ctx, shell, "-l", "-c", script).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get PATH from login shell %q: %w", shell, err)
+	}
+
+	lines := strings.Split(string(out), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], loginShellPathMarker) {
+			return strings.TrimPrefix(lines[i], loginShellPathMarker), nil
+		}
+	}
+
+	return "", fmt.Errorf("login shell %q did not report a PATH", shell)
+}
+
+// lookPathIn works like exec.LookPath but searches the provided PATH
+// instead of otel-cli's own.
+func lookPathIn(file, path string) (string, error) {
+	if strings.Contains(file, string(filepath.Separator)) {
+		return file, nil
+	}
+
+	for _, dir := range filepath.SplitList(path) {
+		if dir == "" {
+			dir = "." // empty PATH elements mean the current directory
+		}
+
+		candidate := filepath.Join(dir, file)
+		if fi, err := os.Stat(candidate); err == nil && fi.Mode().IsRegular() && fi.Mode().Perm()&0111 != 0 {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("%q: executable file not found in login shell $PATH", file)
+}
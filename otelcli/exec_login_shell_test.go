@@ -0,0 +1,42 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoginShellPath(t *testing.T) {
+	home := t.TempDir()
+	bin := t.TempDir()
+	profile := "echo this output is ignored\nexport PATH=" + bin + ":$PATH\n"
+	if err := os.WriteFile(filepath.Join(home, ".profile"), []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(bin, "only-in-profile"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/sh")
+
+	path, err := loginShellPath(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("failed to get PATH from login shell: %s", err)
+	}
+
+	got, err := lookPathIn("only-in-profile", path)
+	if err != nil {
+		t.Fatalf("command was not found in login shell PATH %q: %s", path, err)
+	}
+	if want := filepath.Join(bin, "only-in-profile"); got != want {
+		t.Errorf("expected %q but got %q", want, got)
+	}
+
+	if _, err := lookPathIn("not-a-real-command-anywhere", path); err == nil {
+		t.Error("expected an error for a command that doesn't exist")
+	}
+}
@@ -0,0 +1,281 @@
+package otelcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// markers exec --spans-from-output looks for in the child's output. They can
+// be anywhere in a line, so output from loggers with a prefix works.
+const (
+	markerEvent     = "OTEL_CLI_EVENT:"
+	markerSpanStart = "OTEL_CLI_SPAN_START:"
+	markerSpanEnd   = "OTEL_CLI_SPAN_END:"
+)
+
+// maxMarkerLine is the longest line that's checked for markers or recorded by
+// --per-line-events. Output with longer lines, e.g. binary data, is passed
+// through without being checked.
+const maxMarkerLine = 64 * 1024
+
+// outputMarkers turns markers in the child's output into span events and
+// child spans. Events go on the innermost open child span, or the exec span
+// when none are open. It's shared by the stdout and stderr writers.
+type outputMarkers struct {
+	config   Config
+	span     *tracepb.Span
+	open     []*tracepb.Span // started and not ended, innermost last
+	children []*tracepb.Span
+	writers  []*lineWriter
+	finished bool
+	mu       sync.Mutex
+}
+
+// newOutputMarkers returns an outputMarkers that adds to span.
+func newOutputMarkers(config Config, span *tracepb.Span) *outputMarkers {
+	return &outputMarkers{
+		config:   config,
+		span:     span,
+		open:     []*tracepb.Span{},
+		children: []*tracepb.Span{},
+	}
+}
+
+// Writer returns an io.Writer that passes everything through to out and
+// checks each line for markers.
+func (om *outputMarkers) Writer(out io.Writer) io.Writer {
+	lw := &lineWriter{out: out, handleLine: om.handleLine, now: om.config.now}
+	om.writers = append(om.writers, lw)
+	return lw
+}
+
+// Finish checks output left without a trailing newline, then ends any child
+// spans still open at now and returns all of them. Markers seen after this
+// are ignored.
+func (om *outputMarkers) Finish(now time.Time) []*tracepb.Span {
+	for _, lw := range om.writers {
+		lw.Flush()
+	}
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	for _, span := range om.open {
+		span.EndTimeUnixNano = uint64(now.UnixNano())
+	}
+	om.open = nil
+	om.finished = true
+
+	return om.children
+}
+
+// handleLine processes one line of output. Bad markers are logged and
+// otherwise ignored so a typo in a script doesn't fail the command.
+func (om *outputMarkers) handleLine(line string, now time.Time) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	if om.finished {
+		return
+	}
+
+	var err error
+	if _, fields, ok := strings.Cut(line, markerEvent); ok {
+		err = om.event(fields, now)
+	} else if _, fields, ok := strings.Cut(line, markerSpanStart); ok {
+		err = om.startSpan(fields, now)
+	} else if _, fields, ok := strings.Cut(line, markerSpanEnd); ok {
+		err = om.endSpan(fields, now)
+	}
+	if err != nil {
+		om.config.SoftLog("ignoring output marker %q: %s", line, err)
+	}
+}
+
+// event adds an event from OTEL_CLI_EVENT: name=... attrs=k=v,k=v
+func (om *outputMarkers) event(in string, now time.Time) error {
+	fields, err := parseMarkerFields(in)
+	if err != nil {
+		return err
+	}
+	if fields["name"] == "" {
+		return fmt.Errorf("events need a name")
+	}
+	attrs, err := markerAttrs(fields)
+	if err != nil {
+		return err
+	}
+
+	event := otlpclient.NewProtobufSpanEvent()
+	event.Name = fields["name"]
+	event.TimeUnixNano = uint64(now.UnixNano())
+	event.Attributes = otlpclient.StringMapAttrsToProtobuf(attrs)
+
+	target := om.span
+	if len(om.open) > 0 {
+		target = om.open[len(om.open)-1]
+	}
+	target.Events = append(target.Events, event)
+
+	return nil
+}
+
+// startSpan opens a child span from OTEL_CLI_SPAN_START: name=... attrs=...
+// It's a child of the innermost open span so markers can nest.
+func (om *outputMarkers) startSpan(in string, now time.Time) error {
+	fields, err := parseMarkerFields(in)
+	if err != nil {
+		return err
+	}
+	if fields["name"] == "" {
+		return fmt.Errorf("spans need a name")
+	}
+	attrs, err := markerAttrs(fields)
+	if err != nil {
+		return err
+	}
+
+	parent := om.span
+	if len(om.open) > 0 {
+		parent = om.open[len(om.open)-1]
+	}
+
+	span := otlpclient.NewProtobufSpan()
+	span.Name = fields["name"]
+	span.Kind = tracepb.Span_SPAN_KIND_INTERNAL
+	span.TraceId = parent.TraceId
+	span.ParentSpanId = parent.SpanId
+	if om.config.GetIsRecording() {
+		span.SpanId = otlpclient.GenerateSpanId()
+	}
+	span.StartTimeUnixNano = uint64(now.UnixNano())
+	span.Attributes = otlpclient.StringMapAttrsToProtobuf(attrs)
+
+	om.open = append(om.open, span)
+	om.children = append(om.children, span)
+
+	return nil
+}
+
+// endSpan ends a child span from OTEL_CLI_SPAN_END: name=... status=...
+// attrs=... The innermost open span with the name is ended, or the
+// innermost open span when there's no name.
+func (om *outputMarkers) endSpan(in string, now time.Time) error {
+	fields, err := parseMarkerFields(in)
+	if err != nil {
+		return err
+	}
+	attrs, err := markerAttrs(fields)
+	if err != nil {
+		return err
+	}
+
+	found := -1
+	for i := len(om.open) - 1; i >= 0; i-- {
+		if fields["name"] == "" || om.open[i].Name == fields["name"] {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		return fmt.Errorf("no open span to end")
+	}
+
+	span := om.open[found]
+	om.open = append(om.open[:found], om.open[found+1:]...)
+	span.EndTimeUnixNano = uint64(now.UnixNano())
+	span.Attributes = append(span.Attributes, otlpclient.StringMapAttrsToProtobuf(attrs)...)
+	if status, ok := fields["status"]; ok {
+		otlpclient.SetSpanStatus(span, status, fields["status_description"])
+	}
+
+	return nil
+}
+
+// markerAttrs parses the attrs field, which uses the same format as --attrs.
+func markerAttrs(fields map[string]string) (map[string]string, error) {
+	if fields["attrs"] == "" {
+		return map[string]string{}, nil
+	}
+	return parseCkvStringMap(fields["attrs"])
+}
+
+// parseMarkerFields parses space-separated key=value fields. Values with
+// spaces can be double quoted, with Go string escapes.
+func parseMarkerFields(in string) (map[string]string, error) {
+	out := map[string]string{}
+	rest := strings.TrimSpace(in)
+	for rest != "" {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("expected key=value but got %q", rest)
+		}
+
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			quoted, err := strconv.QuotedPrefix(after)
+			if err != nil {
+				return nil, fmt.Errorf("bad quoting in value of %q: %w", key, err)
+			}
+			value, _ = strconv.Unquote(quoted)
+			after = after[len(quoted):]
+		} else {
+			value, after, _ = strings.Cut(after, " ")
+		}
+
+		out[key] = value
+		rest = strings.TrimSpace(after)
+	}
+
+	return out, nil
+}
+
+// lineWriter is an io.Writer for one of the child's output streams that
+// passes everything through and calls handleLine for each complete line.
+type lineWriter struct {
+	out        io.Writer
+	handleLine func(line string, now time.Time)
+	now        func() time.Time
+	buf        []byte
+}
+
+// Write passes p through, then handles any complete lines.
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	n, err := lw.out.Write(p)
+
+	now := lw.now()
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		if i < 0 {
+			break
+		}
+		// pty output has \r\n line endings
+		line := strings.TrimRight(string(lw.buf[:i]), "\r")
+		lw.handleLine(line, now)
+		lw.buf = lw.buf[i+1:]
+	}
+	if len(lw.buf) > maxMarkerLine {
+		lw.buf = nil
+	}
+
+	return n, err
+}
+
+// Flush handles whatever is left after the last newline as a line, for
+// output that doesn't end with one.
+func (lw *lineWriter) Flush() {
+	if len(lw.buf) > 0 {
+		line := strings.TrimRight(string(lw.buf), "\r")
+		lw.handleLine(line, lw.now())
+		lw.buf = nil
+	}
+}
@@ -0,0 +1,104 @@
+package otelcli
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/google/go-cmp/cmp"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestParseMarkerFields(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: map[string]string{}},
+		{in: " name=build", want: map[string]string{"name": "build"}},
+		{in: `name="cache warmed" attrs=a=1,b=2`, want: map[string]string{"name": "cache warmed", "attrs": "a=1,b=2"}},
+		{in: `name="say \"hi\""  status=ok `, want: map[string]string{"name": `say "hi"`, "status": "ok"}},
+		{in: "name=", want: map[string]string{"name": ""}},
+		{in: "build", wantErr: true},
+		{in: `name="unterminated`, wantErr: true},
+	} {
+		got, err := parseMarkerFields(tc.in)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("expected an error for %q but got %v", tc.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.in, err)
+		}
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("fields for %q mismatch (-want +got):\n%s", tc.in, diff)
+		}
+	}
+}
+
+func TestOutputMarkers(t *testing.T) {
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	span := config.NewProtobufSpan()
+	markers := newOutputMarkers(config, span)
+
+	stdout := new(bytes.Buffer)
+	w := markers.Writer(stdout)
+	output := "starting\n" +
+		"OTEL_CLI_EVENT: name=begin\n" +
+		"2024-01-01 INFO OTEL_CLI_SPAN_START: name=compile attrs=target=linux\r\n" +
+		"OTEL_CLI_SPAN_START: name=link\n" +
+		"OTEL_CLI_EVENT: name=\"linking libs\" attrs=count=3\n" +
+		"OTEL_CLI_SPAN_END:\n" +
+		"OTEL_CLI_SPAN_END: name=missing\n" +
+		"OTEL_CLI_SPAN_END: name=compile status=error status_description=oops\n" +
+		"OTEL_CLI_SPAN_START: name=never-ended\n" +
+		"OTEL_CLI_EVENT: name=partial"
+	// write in small pieces to split lines across writes
+	for i := 0; i < len(output); i += 7 {
+		end := i + 7
+		if end > len(output) {
+			end = len(output)
+		}
+		fmt.Fprint(w, output[i:end])
+	}
+
+	if stdout.String() != output {
+		t.Errorf("output should pass through unchanged, got %q", stdout.String())
+	}
+
+	children := markers.Finish(time.Now())
+	w.Write([]byte("\nOTEL_CLI_EVENT: name=too-late\n"))
+
+	names := []string{}
+	for _, child := range children {
+		names = append(names, child.Name)
+		if child.EndTimeUnixNano == 0 {
+			t.Errorf("span %q was not ended", child.Name)
+		}
+	}
+	if diff := cmp.Diff([]string{"compile", "link", "never-ended"}, names); diff != "" {
+		t.Errorf("child spans mismatch (-want +got):\n%s", diff)
+	}
+
+	compile, link := children[0], children[1]
+	if !bytes.Equal(compile.ParentSpanId, span.SpanId) || !bytes.Equal(link.ParentSpanId, compile.SpanId) {
+		t.Error("child spans should nest under the innermost open span")
+	}
+	if compile.Status.Code != tracepb.Status_STATUS_CODE_ERROR || compile.Status.Message != "oops" {
+		t.Errorf("expected compile to have an error status, got %v", compile.Status)
+	}
+	if attrs := otlpclient.SpanAttributesToStringMap(compile); attrs["target"] != "linux" {
+		t.Errorf("expected the target attribute on compile, got %v", attrs)
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "begin" {
+		t.Errorf("expected the begin event on the exec span, got %v", span.Events)
+	}
+	if len(link.Events) != 1 || link.Events[0].Name != "linking libs" {
+		t.Errorf("expected the linking libs event on link, got %v", link.Events)
+	}
+}
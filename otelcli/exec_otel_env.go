@@ -0,0 +1,128 @@
+package otelcli
+
+import (
+	"net/url"
+	"strings"
+)
+
+// otelEnvVars are the envvars --inject-otel-env sets, which are stripped from
+// the environment the command inherits so they're only set once.
+var otelEnvVars = []string{
+	"OTEL_EXPORTER_OTLP_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+	"OTEL_EXPORTER_OTLP_PROTOCOL",
+	"OTEL_EXPORTER_OTLP_HEADERS",
+	"OTEL_EXPORTER_OTLP_INSECURE",
+	"OTEL_EXPORTER_OTLP_CERTIFICATE",
+	"OTEL_EXPORTER_OTLP_CLIENT_KEY",
+	"OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE",
+	"OTEL_SERVICE_NAME",
+	"OTEL_RESOURCE_ATTRIBUTES",
+}
+
+// otelEnv returns the OTel SDK envvars for --inject-otel-env, taken from
+// otel-cli's resolved config, so an instrumented command exports to the same
+// place without being configured twice. OTEL_RESOURCE_ATTRIBUTES from
+// otel-cli's own environment is kept, with --resource-attrs replacing keys
+// they share. Header and resource attribute values are percent-encoded, as
+// the SDKs expect.
+func (c Config) otelEnv(getenv func(string) string) []string {
+	env := append(c.otelEndpointEnv(), "OTEL_SERVICE_NAME="+c.GetServiceName())
+
+	resourceAttrs := map[string]string{}
+	if fromEnv := getenv("OTEL_RESOURCE_ATTRIBUTES"); fromEnv != "" {
+		if parsed, err := parseCkvStringMap(fromEnv); err == nil {
+			// already percent-encoded, so decoded to not encode them twice
+			for k, v := range parsed {
+				if decoded, err := url.PathUnescape(v); err == nil {
+					parsed[k] = decoded
+				}
+			}
+			resourceAttrs = parsed
+		} else {
+			c.SoftLog("not passing invalid OTEL_RESOURCE_ATTRIBUTES to the command: %s", err)
+		}
+	}
+	for k, v := range c.ResourceAttributes {
+		resourceAttrs[k] = v
+	}
+	if len(resourceAttrs) > 0 {
+		env = append(env, "OTEL_RESOURCE_ATTRIBUTES="+flattenEncodedMap(resourceAttrs))
+	}
+
+	return env
+}
+
+// otelEndpointEnv returns the endpoint, protocol, header, and TLS envvars
+// for otelEnv. They're left out when otel-cli doesn't have an endpoint, or
+// it's a unix socket, which SDKs can't export to.
+func (c Config) otelEndpointEnv() []string {
+	env := []string{}
+	if c.Endpoint == "" && c.TracesEndpoint == "" {
+		return env
+	}
+
+	ep, source := c.ParseEndpoint()
+	if ep.Scheme == "unix" {
+		c.SoftLog("not passing unix socket endpoint %s to the command, OTel SDKs can't export to it", ep)
+		return env
+	}
+	protocol := c.resolvedProtocol(ep.Scheme)
+
+	// grpc:// is only an otel-cli thing, SDKs want http or https, and
+	// a general endpoint goes without the path otel-cli added to it
+	if ep.Scheme == "grpc" {
+		ep.Scheme = "https"
+		if c.GetInsecure() {
+			ep.Scheme = "http"
+		}
+	} else if source == "general" {
+		ep.Path = strings.TrimSuffix(ep.Path, "/v1/traces")
+	}
+	if source == "signal" {
+		env = append(env, "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT="+ep.String())
+	} else {
+		env = append(env, "OTEL_EXPORTER_OTLP_ENDPOINT="+ep.String())
+	}
+	env = append(env, "OTEL_EXPORTER_OTLP_PROTOCOL="+protocol)
+
+	if len(c.Headers) > 0 {
+		env = append(env, "OTEL_EXPORTER_OTLP_HEADERS="+flattenEncodedMap(c.Headers))
+	}
+	if c.Insecure {
+		env = append(env, "OTEL_EXPORTER_OTLP_INSECURE=true")
+	}
+	if c.TlsCACert != "" {
+		env = append(env, "OTEL_EXPORTER_OTLP_CERTIFICATE="+c.TlsCACert)
+	}
+	if c.TlsClientKey != "" {
+		env = append(env, "OTEL_EXPORTER_OTLP_CLIENT_KEY="+c.TlsClientKey)
+	}
+	if c.TlsClientCert != "" {
+		env = append(env, "OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE="+c.TlsClientCert)
+	}
+
+	return env
+}
+
+// flattenEncodedMap is flattenStringMap with the values percent-encoded, the
+// W3C baggage format OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES
+// use, so values with commas, spaces, or = in them make it through.
+func flattenEncodedMap(mp map[string]string) string {
+	encoded := make(map[string]string, len(mp))
+	for k, v := range mp {
+		encoded[k] = url.PathEscape(v)
+	}
+	return flattenStringMap(encoded, "")
+}
+
+// resolvedProtocol returns the OTLP protocol otel-cli exports with to an
+// endpoint with the scheme. http/json and http/protobuf use the HTTP client.
+func (c Config) resolvedProtocol(scheme string) string {
+	if c.Protocol == "grpc" || (!strings.HasPrefix(c.Protocol, "http/") && scheme != "http" && scheme != "https") {
+		return "grpc"
+	} else if c.Protocol != "" {
+		return c.Protocol
+	}
+	return "http/protobuf"
+}
@@ -0,0 +1,84 @@
+package otelcli
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOtelEnv(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config Config
+		getenv map[string]string
+		want   []string
+	}{
+		{
+			name:   "no endpoint",
+			config: DefaultConfig().WithServiceName("job"),
+			want:   []string{"OTEL_SERVICE_NAME=job"},
+		},
+		{
+			name:   "grpc host:port",
+			config: DefaultConfig().WithEndpoint("localhost:4317").WithInsecure(true),
+			want: []string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4317",
+				"OTEL_EXPORTER_OTLP_PROTOCOL=grpc",
+				"OTEL_EXPORTER_OTLP_INSECURE=true",
+				"OTEL_SERVICE_NAME=otel-cli",
+			},
+		},
+		{
+			name: "http with headers and resource attributes",
+			config: DefaultConfig().
+				WithEndpoint("https://collector.example.com/otlp").
+				WithHeaders(map[string]string{"x-token": "abc", "tenant": "ops"}).
+				WithResourceAttributes(map[string]string{"team": "sre"}).
+				WithServiceName("deploy"),
+			getenv: map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "team=web,region=us"},
+			want: []string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT=https://collector.example.com/otlp",
+				"OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf",
+				"OTEL_EXPORTER_OTLP_HEADERS=tenant=ops,x-token=abc",
+				"OTEL_SERVICE_NAME=deploy",
+				"OTEL_RESOURCE_ATTRIBUTES=region=us,team=sre",
+			},
+		},
+		{
+			name: "values are percent-encoded",
+			config: DefaultConfig().
+				WithEndpoint("http://localhost:4318").
+				WithHeaders(map[string]string{"authorization": "Basic a2V5=="}).
+				WithResourceAttributes(map[string]string{"build": "a, b"}),
+			getenv: map[string]string{"OTEL_RESOURCE_ATTRIBUTES": "owner=team%20web"},
+			want: []string{
+				"OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318",
+				"OTEL_EXPORTER_OTLP_PROTOCOL=http/protobuf",
+				"OTEL_EXPORTER_OTLP_HEADERS=authorization=Basic%20a2V5==",
+				"OTEL_SERVICE_NAME=otel-cli",
+				"OTEL_RESOURCE_ATTRIBUTES=build=a%2C%20b,owner=team%20web",
+			},
+		},
+		{
+			name:   "unix socket endpoints are left out",
+			config: DefaultConfig().WithEndpoint("unix:///run/otel.sock").WithHeaders(map[string]string{"x-token": "abc"}),
+			want:   []string{"OTEL_SERVICE_NAME=otel-cli"},
+		},
+		{
+			name:   "signal endpoint with http/json",
+			config: DefaultConfig().WithTracesEndpoint("http://localhost:4318/v1/traces").WithProtocol("http/json"),
+			want: []string{
+				"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT=http://localhost:4318/v1/traces",
+				"OTEL_EXPORTER_OTLP_PROTOCOL=http/json",
+				"OTEL_SERVICE_NAME=otel-cli",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.config.otelEnv(func(name string) string { return tc.getenv[name] })
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("envvars didn't match (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package otelcli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// provenanceAttrs returns attributes describing exactly what exec is about to
+// run for --provenance: the working directory, the executable's real path
+// after following symlinks, and its SHA-256, size, and mtime.
+func provenanceAttrs(dir, executable string) ([]*commonpb.KeyValue, error) {
+	if dir == "" {
+		var err error
+		if dir, err = os.Getwd(); err != nil {
+			return nil, fmt.Errorf("could not get the working directory: %w", err)
+		}
+	}
+
+	realPath, err := filepath.EvalSymlinks(executable)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve executable %q: %w", executable, err)
+	}
+	if realPath, err = filepath.Abs(realPath); err != nil {
+		return nil, fmt.Errorf("could not resolve executable %q: %w", executable, err)
+	}
+
+	f, err := os.Open(realPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not open executable for hashing: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("could not stat executable: %w", err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, fmt.Errorf("could not hash executable: %w", err)
+	}
+
+	// typed explicitly so e.g. a hash that's all digits isn't sent as a number
+	return []*commonpb.KeyValue{
+		stringAttr("process.working_directory", dir),
+		stringAttr("process.executable.path", realPath),
+		stringAttr("otel-cli.executable.sha256", hex.EncodeToString(hash.Sum(nil))),
+		stringAttr("otel-cli.executable.mtime", info.ModTime().UTC().Format(time.RFC3339Nano)),
+		{
+			Key:   "otel-cli.executable.size",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: info.Size()}},
+		},
+	}, nil
+}
@@ -0,0 +1,47 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestProvenanceAttrs(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "tool")
+	if err := os.WriteFile(executable, []byte("hello"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "tool-link")
+	if err := os.Symlink(executable, link); err != nil {
+		t.Skipf("can't create symlinks here: %s", err)
+	}
+	realPath, _ := filepath.EvalSymlinks(executable)
+
+	kvs, err := provenanceAttrs("/some/dir", link)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	attrs := otlpclient.SpanAttributesToStringMap(&tracepb.Span{Attributes: kvs})
+
+	for key, want := range map[string]string{
+		"process.working_directory":  "/some/dir",
+		"process.executable.path":    realPath,
+		"otel-cli.executable.sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"otel-cli.executable.size":   "5",
+	} {
+		if attrs[key] != want {
+			t.Errorf("expected %s to be %q but got %q", key, want, attrs[key])
+		}
+	}
+	if attrs["otel-cli.executable.mtime"] == "" {
+		t.Error("expected an mtime attribute")
+	}
+
+	if _, err := provenanceAttrs("", filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing executable")
+	}
+}
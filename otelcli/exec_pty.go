@@ -0,0 +1,73 @@
+package otelcli
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// ptyDrainTimeout is how long to keep copying output after the child exits,
+// in case something it backgrounded is still holding the pty open.
+const ptyDrainTimeout = 250 * time.Millisecond
+
+// runWithPty starts the child attached to a new pseudo-terminal, copies stdio
+// to and from it, and waits for the child to exit. When otel-cli's stdin is a
+// terminal it's put into raw mode so keystrokes (including ctrl-c) go straight
+// to the child, and window size changes are passed along. started is called
+// once the child process is running. The child's input is read from in and
+// its output is written to out.
+func runWithPty(child *exec.Cmd, in io.Reader, out io.Writer, started func()) error {
+	ptmx, err := pty.Start(child)
+	if err != nil {
+		return err
+	}
+	defer ptmx.Close()
+	started()
+
+	// sync the window size now and again every time it changes
+	resize := make(chan os.Signal, 1)
+	notifyWindowResize(resize)
+	defer func() {
+		signal.Stop(resize)
+		close(resize)
+	}()
+	go func() {
+		for range resize {
+			pty.InheritSize(os.Stdin, ptmx)
+		}
+	}()
+	select {
+	case resize <- nil:
+	default: // a real resize is already queued
+	}
+
+	stdinFd := int(os.Stdin.Fd())
+	if term.IsTerminal(stdinFd) {
+		if oldState, err := term.MakeRaw(stdinFd); err == nil {
+			defer term.Restore(stdinFd, oldState)
+		}
+	}
+
+	// the stdin copier may stay blocked on read, it goes away when otel-cli exits
+	go io.Copy(ptmx, in)
+
+	outputDone := make(chan struct{})
+	go func() {
+		io.Copy(out, ptmx) // returns EIO on Linux once the child side closes
+		close(outputDone)
+	}()
+
+	err = child.Wait()
+
+	select {
+	case <-outputDone:
+	case <-time.After(ptyDrainTimeout):
+	}
+
+	return err
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyWindowResize relays terminal size changes to the provided channel.
+func notifyWindowResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
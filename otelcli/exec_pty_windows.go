@@ -0,0 +1,9 @@
+//go:build windows
+
+package otelcli
+
+import "os"
+
+// notifyWindowResize does nothing on Windows, which has no SIGWINCH.
+// --pty isn't supported there anyways and fails at pty.Start.
+func notifyWindowResize(ch chan<- os.Signal) {}
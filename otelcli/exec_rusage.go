@@ -0,0 +1,25 @@
+package otelcli
+
+import (
+	"os"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// rusageAttrs returns the child's resource usage from when it was waited
+// on as attributes: cpu time in seconds on every platform, plus max RSS, I/O,
+// page faults, and context switches where the OS reports them. Like the
+// shell's time builtin, this includes descendants the child waited for.
+func rusageAttrs(state *os.ProcessState) []*commonpb.KeyValue {
+	if state == nil {
+		return nil
+	}
+
+	user, system := state.UserTime(), state.SystemTime()
+	attrs := []*commonpb.KeyValue{
+		doubleAttr("process.cpu_time", (user + system).Seconds()),
+		doubleAttr("process.user_time", user.Seconds()),
+		doubleAttr("process.system_time", system.Seconds()),
+	}
+	return append(attrs, sysUsageAttrs(state)...)
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// sysUsageAttrs returns the getrusage(2) counters that cpu time doesn't
+// cover.
+func sysUsageAttrs(state *os.ProcessState) []*commonpb.KeyValue {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return nil
+	}
+
+	return []*commonpb.KeyValue{
+		intAttr("process.memory.max_rss", maxRssBytes(int64(rusage.Maxrss), runtime.GOOS)),
+		intAttr("process.io.read_blocks", int64(rusage.Inblock)),
+		intAttr("process.io.write_blocks", int64(rusage.Oublock)),
+		intAttr("process.page_faults.major", int64(rusage.Majflt)),
+		intAttr("process.page_faults.minor", int64(rusage.Minflt)),
+		intAttr("process.context_switches.voluntary", int64(rusage.Nvcsw)),
+		intAttr("process.context_switches.involuntary", int64(rusage.Nivcsw)),
+	}
+}
+
+// maxRssBytes converts ru_maxrss to bytes. It's already bytes on macOS and
+// kilobytes everywhere else.
+func maxRssBytes(maxrss int64, goos string) int64 {
+	if goos == "darwin" || goos == "ios" {
+		return maxrss
+	}
+	return maxrss * 1024
+}
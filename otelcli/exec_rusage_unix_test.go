@@ -0,0 +1,54 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"os/exec"
+	"strconv"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestRusageAttrs(t *testing.T) {
+	// burn a little cpu so the times aren't all zero on fast machines
+	child := exec.Command("sh", "-c", "i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done")
+	if err := child.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	span := &tracepb.Span{Attributes: rusageAttrs(child.ProcessState)}
+	got := otlpclient.SpanAttributesToStringMap(span)
+	for _, key := range []string{
+		"process.cpu_time", "process.user_time", "process.system_time",
+		"process.memory.max_rss", "process.io.read_blocks", "process.io.write_blocks",
+		"process.page_faults.major", "process.page_faults.minor",
+		"process.context_switches.voluntary", "process.context_switches.involuntary",
+	} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %s in %v", key, got)
+		}
+	}
+
+	if cpu, _ := strconv.ParseFloat(got["process.cpu_time"], 64); cpu <= 0 {
+		t.Errorf("expected some cpu time, got %q", got["process.cpu_time"])
+	}
+	// even sh takes more than a page
+	if rss, _ := strconv.ParseInt(got["process.memory.max_rss"], 10, 64); rss < 4096 {
+		t.Errorf("expected max rss in bytes, got %q", got["process.memory.max_rss"])
+	}
+
+	if attrs := rusageAttrs(nil); attrs != nil {
+		t.Errorf("expected no attributes when the command never started, got %v", attrs)
+	}
+}
+
+func TestMaxRssBytes(t *testing.T) {
+	if got := maxRssBytes(2048, "linux"); got != 2048*1024 {
+		t.Errorf("expected kilobytes on linux, got %d", got)
+	}
+	if got := maxRssBytes(2048, "darwin"); got != 2048 {
+		t.Errorf("expected bytes on darwin, got %d", got)
+	}
+}
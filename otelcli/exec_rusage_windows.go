@@ -0,0 +1,15 @@
+//go:build windows
+
+package otelcli
+
+import (
+	"os"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// sysUsageAttrs returns nothing on Windows, where the process times are all
+// there is and those are already in rusageAttrs.
+func sysUsageAttrs(state *os.ProcessState) []*commonpb.KeyValue {
+	return nil
+}
@@ -0,0 +1,129 @@
+package otelcli
+
+import (
+	"errors"
+	"io/fs"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// maxResourceSampleEvents caps how many sample events go on a span. Long
+// running commands get their samples thinned out evenly to fit.
+const maxResourceSampleEvents = 120
+
+// resourceSample is one reading of the child process tree's resource usage.
+type resourceSample struct {
+	Time       time.Time
+	RssBytes   int64
+	CpuPercent float64
+}
+
+// resourceSampler periodically reads the resource usage of a process and
+// all of its descendants until stopped or the process goes away.
+type resourceSampler struct {
+	stop    chan struct{}
+	done    chan struct{}
+	samples []resourceSample
+	err     error
+}
+
+// startResourceSampler starts sampling the process tree under pid every interval.
+func startResourceSampler(pid int, interval time.Duration) *resourceSampler {
+	rs := resourceSampler{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(rs.done)
+
+		// the first reading is the baseline for cpu, it doesn't get recorded
+		lastTime := time.Now()
+		_, lastCpu, err := readProcessTreeUsage(pid)
+		if errors.Is(err, fs.ErrNotExist) {
+			return // the command finished before sampling started
+		} else if err != nil {
+			rs.err = err
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-rs.stop:
+				return
+			case now := <-ticker.C:
+				rss, cpu, err := readProcessTreeUsage(pid)
+				if err != nil {
+					return // the process exited between ticks
+				}
+
+				var cpuPercent float64
+				if elapsed := now.Sub(lastTime); elapsed > 0 && cpu > lastCpu {
+					cpuPercent = float64(cpu-lastCpu) / float64(elapsed) * 100
+				}
+				rs.samples = append(rs.samples, resourceSample{
+					Time:       now,
+					RssBytes:   rss,
+					CpuPercent: cpuPercent,
+				})
+				lastTime, lastCpu = now, cpu
+			}
+		}
+	}()
+
+	return &rs
+}
+
+// Stop stops sampling and returns the samples taken.
+func (rs *resourceSampler) Stop() ([]resourceSample, error) {
+	close(rs.stop)
+	<-rs.done
+	return rs.samples, rs.err
+}
+
+// addResourceSamples puts max/avg summary attributes for the samples on the
+// span, plus up to maxResourceSampleEvents events with the individual samples.
+func addResourceSamples(span *tracepb.Span, samples []resourceSample) {
+	if len(samples) == 0 {
+		return
+	}
+
+	var rssMax, rssSum int64
+	var cpuMax, cpuSum float64
+	for _, s := range samples {
+		rssSum += s.RssBytes
+		cpuSum += s.CpuPercent
+		if s.RssBytes > rssMax {
+			rssMax = s.RssBytes
+		}
+		if s.CpuPercent > cpuMax {
+			cpuMax = s.CpuPercent
+		}
+	}
+	count := int64(len(samples))
+
+	span.Attributes = append(span.Attributes,
+		intAttr("process.sample.count", count),
+		intAttr("process.memory.rss.max", rssMax),
+		intAttr("process.memory.rss.avg", rssSum/count),
+		doubleAttr("process.cpu.percent.max", cpuMax),
+		doubleAttr("process.cpu.percent.avg", cpuSum/float64(count)),
+	)
+
+	stride := (len(samples) + maxResourceSampleEvents - 1) / maxResourceSampleEvents
+	for i := 0; i < len(samples); i += stride {
+		event := otlpclient.NewProtobufSpanEvent()
+		event.Name = "resource sample"
+		event.TimeUnixNano = uint64(samples[i].Time.UnixNano())
+		event.Attributes = []*commonpb.KeyValue{
+			intAttr("process.memory.rss", samples[i].RssBytes),
+			doubleAttr("process.cpu.percent", samples[i].CpuPercent),
+		}
+		span.Events = append(span.Events, event)
+	}
+}
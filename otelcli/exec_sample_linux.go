@@ -0,0 +1,97 @@
+//go:build linux
+
+package otelcli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of the cpu times in /proc/<pid>/stat.
+// It's 100 on every Linux architecture Go supports.
+const clockTicks = 100
+
+// procStat is the subset of /proc/<pid>/stat used for sampling.
+type procStat struct {
+	ppid int
+	cpu  time.Duration // utime+stime+cutime+cstime
+	rss  int64         // bytes
+}
+
+// readProcessTreeUsage returns the summed resident memory and cpu time of
+// pid and all of its descendants. Cpu time of reaped children is included
+// via cutime/cstime, so short-lived processes under e.g. make still count.
+func readProcessTreeUsage(pid int) (int64, time.Duration, error) {
+	root, err := readProcStat(pid)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to list processes: %w", err)
+	}
+
+	stats := map[int]procStat{pid: root}
+	children := map[int][]int{}
+	for _, entry := range entries {
+		p, err := strconv.Atoi(entry.Name())
+		if err != nil || p == pid {
+			continue
+		}
+		stat, err := readProcStat(p)
+		if err != nil {
+			continue // exited since ReadDir
+		}
+		stats[p] = stat
+		children[stat.ppid] = append(children[stat.ppid], p)
+	}
+
+	var rss int64
+	var cpu time.Duration
+	queue := []int{pid}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		rss += stats[p].rss
+		cpu += stats[p].cpu
+		queue = append(queue, children[p]...)
+	}
+
+	return rss, cpu, nil
+}
+
+// readProcStat parses /proc/<pid>/stat.
+func readProcStat(pid int) (procStat, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return procStat{}, err
+	}
+
+	// the command name is in parens and can contain spaces and parens, so
+	// the fields start after the last ')'
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("unexpected format in /proc/%d/stat", pid)
+	}
+	fields := bytes.Fields(data[end+1:])
+	if len(fields) < 22 {
+		return procStat{}, fmt.Errorf("unexpected format in /proc/%d/stat", pid)
+	}
+
+	// fields[0] is field 3 (state) in proc(5)
+	field := func(n int) int64 {
+		v, _ := strconv.ParseInt(string(fields[n-3]), 10, 64)
+		return v
+	}
+
+	ticks := field(14) + field(15) + field(16) + field(17)
+	return procStat{
+		ppid: int(field(4)),
+		cpu:  time.Duration(ticks) * time.Second / clockTicks,
+		rss:  field(24) * int64(os.Getpagesize()),
+	}, nil
+}
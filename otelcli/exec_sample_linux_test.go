@@ -0,0 +1,22 @@
+//go:build linux
+
+package otelcli
+
+import (
+	"os"
+	"testing"
+)
+
+func TestReadProcessTreeUsage(t *testing.T) {
+	rss, _, err := readProcessTreeUsage(os.Getpid())
+	if err != nil {
+		t.Fatalf("failed to read own process usage: %s", err)
+	}
+	if rss <= 0 {
+		t.Errorf("expected positive rss for the test process, got %d", rss)
+	}
+
+	if _, _, err := readProcessTreeUsage(-1); err == nil {
+		t.Error("expected an error for a process that doesn't exist")
+	}
+}
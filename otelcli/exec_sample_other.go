@@ -0,0 +1,13 @@
+//go:build !linux
+
+package otelcli
+
+import (
+	"fmt"
+	"time"
+)
+
+// readProcessTreeUsage is only implemented on Linux for now.
+func readProcessTreeUsage(pid int) (int64, time.Duration, error) {
+	return 0, 0, fmt.Errorf("--sample-resources is only supported on Linux")
+}
@@ -0,0 +1,43 @@
+package otelcli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+)
+
+func TestAddResourceSamples(t *testing.T) {
+	span := otlpclient.NewProtobufSpan()
+	addResourceSamples(span, nil)
+	if len(span.Attributes) != 0 || len(span.Events) != 0 {
+		t.Error("span was modified without any samples")
+	}
+
+	start := time.Unix(1700000000, 0)
+	samples := []resourceSample{}
+	for i := 0; i < 300; i++ {
+		samples = append(samples, resourceSample{
+			Time:       start.Add(time.Duration(i) * time.Second),
+			RssBytes:   int64(i+1) * 1024,
+			CpuPercent: float64(i % 100),
+		})
+	}
+
+	addResourceSamples(span, samples)
+	attrs := otlpclient.SpanAttributesToStringMap(span)
+	for key, want := range map[string]string{
+		"process.sample.count":    "300",
+		"process.memory.rss.max":  "307200",
+		"process.memory.rss.avg":  "154112",
+		"process.cpu.percent.max": "99",
+	} {
+		if attrs[key] != want {
+			t.Errorf("expected %s=%s, got %q", key, want, attrs[key])
+		}
+	}
+
+	if len(span.Events) == 0 || len(span.Events) > maxResourceSampleEvents {
+		t.Errorf("expected between 1 and %d events, got %d", maxResourceSampleEvents, len(span.Events))
+	}
+}
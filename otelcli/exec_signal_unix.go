@@ -0,0 +1,80 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"golang.org/x/sys/unix"
+)
+
+// exitSignalAttrs returns process.exit.* attributes and a status message when
+// the child was killed by a signal. ok is false when it exited on its own.
+func exitSignalAttrs(state *os.ProcessState) (attrs []*commonpb.KeyValue, message string, ok bool) {
+	if state == nil {
+		return nil, "", false
+	}
+	ws, isWaitStatus := state.Sys().(syscall.WaitStatus)
+	if !isWaitStatus || !ws.Signaled() {
+		return nil, "", false
+	}
+
+	sig := ws.Signal()
+	name := unix.SignalName(sig)
+	if name == "" {
+		name = fmt.Sprintf("signal %d", int(sig))
+	}
+
+	attrs = []*commonpb.KeyValue{
+		stringAttr("process.exit.signal", name),
+		intAttr("process.exit.signal_number", int64(sig)),
+		boolAttr("process.exit.core_dumped", ws.CoreDump()),
+	}
+
+	message = fmt.Sprintf("exec command killed by signal %s (%d)", name, int(sig))
+	if ws.CoreDump() {
+		message += ", core dumped"
+	}
+
+	return attrs, message, true
+}
+
+// terminateProcess asks the process to exit with SIGTERM.
+func terminateProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// defaultForwardSignals are the signals exec passes on to the child, so
+// cancellation from a terminal, systemd, or CI reaches the command.
+const defaultForwardSignals = "SIGINT,SIGTERM,SIGHUP,SIGQUIT,SIGUSR1,SIGUSR2"
+
+// parseSignal returns the signal for a name like SIGTERM or TERM.
+func parseSignal(name string) (os.Signal, error) {
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return nil, fmt.Errorf("unknown signal %q", name)
+	} else if sig == unix.SIGKILL || sig == unix.SIGSTOP {
+		return nil, fmt.Errorf("%s can't be caught, so it can't be forwarded", name)
+	}
+	return sig, nil
+}
+
+// signalName returns the name of the signal, e.g. SIGTERM, and its number.
+func signalName(sig os.Signal) (string, int64) {
+	num, ok := sig.(syscall.Signal)
+	if !ok {
+		return sig.String(), 0
+	}
+	if name := unix.SignalName(num); name != "" {
+		return name, int64(num)
+	}
+	return fmt.Sprintf("signal %d", int(num)), int64(num)
+}
@@ -0,0 +1,159 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExitSignalAttrs(t *testing.T) {
+	// exits on its own, no signal attributes
+	child := exec.Command("sh", "-c", "exit 3")
+	child.Run()
+	if _, _, ok := exitSignalAttrs(child.ProcessState); ok {
+		t.Error("expected no signal info for a normal exit")
+	}
+
+	child = exec.Command("sh", "-c", "kill -TERM $$")
+	child.Run()
+	attrs, message, ok := exitSignalAttrs(child.ProcessState)
+	if !ok {
+		t.Fatal("expected signal info for a child killed by SIGTERM")
+	}
+	if message != "exec command killed by signal SIGTERM (15)" {
+		t.Errorf("unexpected status message %q", message)
+	}
+
+	span := &tracepb.Span{Attributes: attrs}
+	got := otlpclient.SpanAttributesToStringMap(span)
+	if got["process.exit.signal"] != "SIGTERM" || got["process.exit.signal_number"] != "15" {
+		t.Errorf("unexpected signal attributes %v", got)
+	}
+
+	if _, _, ok := exitSignalAttrs(nil); ok {
+		t.Error("expected no signal info when the command never started")
+	}
+}
+
+func TestParseSignalList(t *testing.T) {
+	got, err := parseSignalList(" SIGTERM, hup,usr1 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []os.Signal{syscall.SIGTERM, syscall.SIGHUP, syscall.SIGUSR1}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+
+	if sigs, err := parseSignalList(""); err != nil || len(sigs) != 0 {
+		t.Errorf("expected no signals for an empty list, got %v, %v", sigs, err)
+	}
+	for _, bad := range []string{"SIGNOPE", "KILL", "SIGSTOP"} {
+		if _, err := parseSignalList(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	if _, err := parseSignalList(defaultForwardSignals); err != nil {
+		t.Errorf("the default signals don't parse: %s", err)
+	}
+}
+
+func TestSignalForwarder(t *testing.T) {
+	child := exec.Command("sleep", "5")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	forwarder := newSignalForwarder([]os.Signal{syscall.SIGUSR1})
+	// signals that come in before the child is running wait for it
+	syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+	forwarder.Start(child.Process, time.Now)
+
+	done := make(chan struct{})
+	go func() { child.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		child.Process.Kill()
+		t.Fatal("the signal wasn't forwarded to the child")
+	}
+
+	events := forwarder.Stop()
+	if len(events) != 1 || events[0].Name != "signal forwarded" {
+		t.Fatalf("expected one signal forwarded event, got %v", events)
+	}
+	got := otlpclient.SpanAttributesToStringMap(&tracepb.Span{Attributes: events[0].Attributes})
+	if got["process.signal"] != "SIGUSR1" || got["process.signal_number"] != strconv.Itoa(int(syscall.SIGUSR1)) {
+		t.Errorf("unexpected event attributes %v", got)
+	}
+	if _, _, ok := exitSignalAttrs(child.ProcessState); !ok {
+		t.Error("expected the child to be killed by the forwarded signal")
+	}
+}
+
+// a command that exits before --command-timeout isn't marked as timed out,
+// even when putting its span together runs past the deadline, here waiting
+// on a slow heartbeat
+func TestCommandTimeoutFinishedInTime(t *testing.T) {
+	var mu sync.Mutex
+	var got []*tracepb.Span
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		msg := coltracepb.ExportTraceServiceRequest{}
+		proto.Unmarshal(body, &msg)
+		for _, rs := range msg.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				for _, span := range ss.Spans {
+					if span.Name == "heartbeat" {
+						time.Sleep(500 * time.Millisecond)
+						continue
+					}
+					mu.Lock()
+					got = append(got, span)
+					mu.Unlock()
+				}
+			}
+		}
+		rw.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"exec", "--endpoint", srv.URL + "/v1/traces", "--timeout", "5s",
+		"--command-timeout", "300ms", "--heartbeat", "50ms", "--", "sh", "-c", "sleep 0.1"}
+	if code := Run(args, &stdout, &stderr); code != 0 {
+		t.Fatalf("exec failed with code %d: %s", code, stderr.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("expected the exec span, got %d spans", len(got))
+	}
+	if attrs := otlpclient.SpanAttributesToStringMap(got[0]); attrs["timeout"] != "" {
+		t.Errorf("expected a command that finished in time not to be marked as timed out, got %v", attrs)
+	}
+	if got[0].Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("expected no error status, got %v", got[0].Status)
+	}
+}
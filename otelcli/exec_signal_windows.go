@@ -0,0 +1,38 @@
+//go:build windows
+
+package otelcli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+)
+
+// exitSignalAttrs always returns ok=false on Windows, which has no signals.
+func exitSignalAttrs(state *os.ProcessState) (attrs []*commonpb.KeyValue, message string, ok bool) {
+	return nil, "", false
+}
+
+// terminateProcess kills the process, Windows can't ask it to exit.
+func terminateProcess(p *os.Process) error {
+	return p.Kill()
+}
+
+// defaultForwardSignals is only ctrl-c on Windows, the only signal there is.
+const defaultForwardSignals = "SIGINT"
+
+// parseSignal returns os.Interrupt for SIGINT or INT, other signals can't be
+// forwarded on Windows.
+func parseSignal(name string) (os.Signal, error) {
+	if name = strings.ToUpper(name); name == "SIGINT" || name == "INT" {
+		return os.Interrupt, nil
+	}
+	return nil, fmt.Errorf("only SIGINT can be forwarded on Windows, not %q", name)
+}
+
+// signalName returns SIGINT for os.Interrupt, the only signal on Windows.
+func signalName(sig os.Signal) (string, int64) {
+	return "SIGINT", 2
+}
@@ -0,0 +1,86 @@
+package otelcli
+
+import (
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// parseSignalList parses a comma-separated list of signal names for
+// --forward-signals. An empty list is no signals.
+func parseSignalList(list string) ([]os.Signal, error) {
+	sigs := []os.Signal{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		sig, err := parseSignal(name)
+		if err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// signalForwarder catches signals sent to otel-cli and passes them on to the
+// child, recording a span event for each one so e.g. a CI job's cancellation
+// shows up in the trace.
+type signalForwarder struct {
+	signals chan os.Signal
+	done    chan struct{}
+	started bool
+	events  []*tracepb.Span_Event
+}
+
+// newSignalForwarder starts catching sigs right away, so otel-cli doesn't exit
+// on them while the child starts up. They're buffered until Start.
+func newSignalForwarder(sigs []os.Signal) *signalForwarder {
+	sf := signalForwarder{
+		signals: make(chan os.Signal, 10),
+		done:    make(chan struct{}),
+	}
+	// Notify with no signals would catch all of them
+	if len(sigs) > 0 {
+		signal.Notify(sf.signals, sigs...)
+	}
+	return &sf
+}
+
+// Start forwards signals to the process until Stop is called, stamping their
+// events with the time from now.
+func (sf *signalForwarder) Start(process *os.Process, now func() time.Time) {
+	sf.started = true
+	go func() {
+		defer close(sf.done)
+		for sig := range sf.signals {
+			name, num := signalName(sig)
+			event := otlpclient.NewProtobufSpanEvent()
+			event.Name = "signal forwarded"
+			event.TimeUnixNano = uint64(now().UnixNano())
+			event.Attributes = append(event.Attributes,
+				stringAttr("process.signal", name),
+				intAttr("process.signal_number", num),
+			)
+			if err := process.Signal(sig); err != nil {
+				event.Name = "signal not forwarded"
+				event.Attributes = append(event.Attributes, stringAttr("exception.message", err.Error()))
+			}
+			sf.events = append(sf.events, event)
+		}
+	}()
+}
+
+// Stop stops catching signals and returns an event for each one forwarded.
+func (sf *signalForwarder) Stop() []*tracepb.Span_Event {
+	signal.Stop(sf.signals)
+	close(sf.signals)
+	if sf.started {
+		<-sf.done
+	}
+	return sf.events
+}
@@ -0,0 +1,80 @@
+package otelcli
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+)
+
+// defaultStatusMap is used by --status-from-exit-code when --status-map isn't set.
+const defaultStatusMap = "0=ok,*=error"
+
+// exitStatusRule maps an inclusive range of exit codes to a span status.
+type exitStatusRule struct {
+	min, max int
+	status   string
+}
+
+// ParseStatusMap parses --status-map, or the default map when it's empty.
+// Returns nil when exit codes aren't mapped at all.
+func (c Config) ParseStatusMap() []exitStatusRule {
+	if !c.ExecStatusFromExitCode && c.ExecStatusMap == "" {
+		return nil
+	}
+
+	in := c.ExecStatusMap
+	if in == "" {
+		in = defaultStatusMap
+	}
+	rules, err := parseStatusMap(in)
+	c.SoftFailIfErr(err)
+	return rules
+}
+
+// parseStatusMap parses a comma-separated list of code=status, where code is
+// a single exit code, a range like 1-127, or * for any code.
+func parseStatusMap(in string) ([]exitStatusRule, error) {
+	rules := []exitStatusRule{}
+	for _, entry := range strings.Split(in, ",") {
+		codes, status, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --status-map entry %q, expected code=status", entry)
+		}
+		status, err := otlpclient.NormalizeSpanStatus(status)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status in --status-map: %w", err)
+		}
+
+		rule := exitStatusRule{status: status}
+		if codes == "*" {
+			rule.min, rule.max = math.MinInt, math.MaxInt
+		} else {
+			first, last, isRange := strings.Cut(codes, "-")
+			var err error
+			if rule.min, err = strconv.Atoi(first); err != nil {
+				return nil, fmt.Errorf("invalid exit code %q in --status-map", codes)
+			}
+			rule.max = rule.min
+			if isRange {
+				if rule.max, err = strconv.Atoi(last); err != nil || rule.max < rule.min {
+					return nil, fmt.Errorf("invalid exit code range %q in --status-map", codes)
+				}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// statusForExitCode returns the status of the first rule matching code.
+func statusForExitCode(rules []exitStatusRule, code int) (string, bool) {
+	for _, rule := range rules {
+		if code >= rule.min && code <= rule.max {
+			return rule.status, true
+		}
+	}
+	return "", false
+}
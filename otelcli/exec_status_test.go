@@ -0,0 +1,34 @@
+package otelcli
+
+import "testing"
+
+func TestParseStatusMap(t *testing.T) {
+	rules, err := parseStatusMap("0=ok, 2=unset,1-127=ERROR,*=Success")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for code, want := range map[int]string{
+		0:   "ok",
+		1:   "error",
+		2:   "unset",
+		127: "error",
+		128: "ok",
+	} {
+		got, ok := statusForExitCode(rules, code)
+		if !ok || got != want {
+			t.Errorf("exit code %d got status %q, expected %q", code, got, want)
+		}
+	}
+
+	rules, _ = parseStatusMap("1=error")
+	if status, ok := statusForExitCode(rules, 0); ok {
+		t.Errorf("expected no match for exit code 0, got %q", status)
+	}
+
+	for _, in := range []string{"", "0", "0=fine", "x=ok", "5-1=error", "1-x=error"} {
+		if _, err := parseStatusMap(in); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package otelcli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// featureStrictExecArgv passes exec's arguments through exactly as they
+	// are, without replacing {{traceparent}} in them.
+	featureStrictExecArgv = "strict-exec-argv"
+	// featureNoAutoInsecure stops otel-cli from turning off TLS on its own
+	// for loopback endpoints, so plain text has to be asked for.
+	featureNoAutoInsecure = "no-auto-insecure"
+)
+
+// feature is an experimental behavior that can be tried out with --feature or
+// the features config key before it becomes the default. Each one is on by
+// default from its defaultSince version, and can still be turned off by name
+// after that, e.g. --feature strict-exec-argv=false.
+type feature struct {
+	name         string
+	defaultSince string // e.g. "1.0.0", empty while there's no date set
+}
+
+// features is every feature otel-cli knows about. Names that aren't here are
+// rejected so typos don't quietly leave a feature off.
+var features = []feature{
+	{
+		name:         featureStrictExecArgv,
+		defaultSince: "1.0.0",
+	},
+	{
+		name:         featureNoAutoInsecure,
+		defaultSince: "1.0.0",
+	},
+}
+
+// FeatureEnabled returns whether the named feature is on, either because it
+// was set with --feature or in the config, or because this version of
+// otel-cli has it on by default.
+func (c Config) FeatureEnabled(name string) bool {
+	if enabled, ok := c.Features[name]; ok {
+		return enabled
+	}
+
+	for _, f := range features {
+		if f.name == name {
+			return versionAtLeast(c.Version, f.defaultSince)
+		}
+	}
+
+	return false
+}
+
+// EnabledFeatures returns the names of the features that are on, sorted.
+func (c Config) EnabledFeatures() []string {
+	out := []string{}
+	for _, f := range features {
+		if c.FeatureEnabled(f.name) {
+			out = append(out, f.name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CheckFeatures returns an error naming any features in the config that
+// otel-cli doesn't know about.
+func (c Config) CheckFeatures() error {
+	unknown := []string{}
+	for name := range c.Features {
+		if !isKnownFeature(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown feature(s) %s, expected one of %s", strings.Join(unknown, ", "), featureNames())
+}
+
+// featureNames returns the names of all the features, for help text.
+func featureNames() string {
+	names := make([]string, len(features))
+	for i, f := range features {
+		names[i] = f.name
+	}
+	return strings.Join(names, ", ")
+}
+
+// isKnownFeature returns whether name is in the features list.
+func isKnownFeature(name string) bool {
+	for _, f := range features {
+		if f.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parseFeatures parses a comma-separated list of feature names, each with an
+// optional =true or =false, e.g. "strict-exec-argv,no-auto-insecure=false".
+// Names are checked later by CheckFeatures so all the unknown ones can be
+// reported at once.
+func parseFeatures(in string) (map[string]bool, error) {
+	out := map[string]bool{}
+	for _, item := range strings.Split(in, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(item, "=")
+		enabled := true
+		if hasValue {
+			var err error
+			enabled, err = strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q for feature %q, expected true or false", value, name)
+			}
+		}
+		out[name] = enabled
+	}
+	return out, nil
+}
+
+// featuresValue is a pflag.Value for --feature that adds to the features map
+// each time the flag is given.
+type featuresValue struct {
+	features *map[string]bool
+}
+
+func (fv featuresValue) Set(in string) error {
+	parsed, err := parseFeatures(in)
+	if err != nil {
+		return err
+	}
+	if *fv.features == nil {
+		*fv.features = map[string]bool{}
+	}
+	for name, enabled := range parsed {
+		(*fv.features)[name] = enabled
+	}
+	return nil
+}
+
+func (fv featuresValue) String() string {
+	if fv.features == nil {
+		return ""
+	}
+	items := []string{}
+	for name, enabled := range *fv.features {
+		items = append(items, name+"="+strconv.FormatBool(enabled))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (fv featuresValue) Type() string {
+	return "feature"
+}
+
+// versionAtLeast returns whether version, e.g. "0.4.5 abc1234 2024-01-01" as
+// built by FormatVersion, is at least min. Versions that don't start with a
+// dotted number, like "unknown" for dev builds, never are.
+func versionAtLeast(version, min string) bool {
+	if min == "" {
+		return false
+	}
+	have, ok := parseVersion(version)
+	if !ok {
+		return false
+	}
+	want, ok := parseVersion(min)
+	if !ok {
+		return false
+	}
+
+	for i := range want {
+		if have[i] != want[i] {
+			return have[i] > want[i]
+		}
+	}
+	return true
+}
+
+// parseVersion parses the major.minor.patch at the start of a version string,
+// ignoring a leading v and anything after the numbers.
+func parseVersion(version string) ([3]int, bool) {
+	var out [3]int
+	fields := strings.Fields(version)
+	if len(fields) == 0 {
+		return out, false
+	}
+
+	numbers, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "v"), "-")
+	parts := strings.Split(numbers, ".")
+	if len(parts) > 3 {
+		return out, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
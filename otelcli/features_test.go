@@ -0,0 +1,98 @@
+package otelcli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFeatureEnabled(t *testing.T) {
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+
+	// off before the version they become the default in
+	for _, version := range []string{"unknown", "0.4.5", "0.4.5 abc1234 2024-01-01", "v0.9.9"} {
+		if config.WithVersion(version).FeatureEnabled(featureStrictExecArgv) {
+			t.Errorf("expected %s to be off by default in version %q", featureStrictExecArgv, version)
+		}
+	}
+	// and on from then
+	for _, version := range []string{"1.0.0", "v1.0", "1.2.3-rc1 abc1234", "2"} {
+		if !config.WithVersion(version).FeatureEnabled(featureStrictExecArgv) {
+			t.Errorf("expected %s to be on by default in version %q", featureStrictExecArgv, version)
+		}
+	}
+
+	// setting it either way wins over the default
+	on := config.WithVersion("0.4.5").WithFeatures(map[string]bool{featureStrictExecArgv: true})
+	if !on.FeatureEnabled(featureStrictExecArgv) {
+		t.Errorf("expected %s to be on when set", featureStrictExecArgv)
+	}
+	off := config.WithVersion("1.0.0").WithFeatures(map[string]bool{featureStrictExecArgv: false})
+	if off.FeatureEnabled(featureStrictExecArgv) {
+		t.Errorf("expected %s to be off when set to false", featureStrictExecArgv)
+	}
+	if diff := cmp.Diff([]string{featureNoAutoInsecure}, off.EnabledFeatures()); diff != "" {
+		t.Errorf("enabled features did not match (-want +got): %s", diff)
+	}
+}
+
+func TestCheckFeatures(t *testing.T) {
+	config := DefaultConfig().WithFeatures(map[string]bool{featureNoAutoInsecure: true})
+	if err := config.CheckFeatures(); err != nil {
+		t.Errorf("unexpected error for a known feature: %s", err)
+	}
+
+	config = config.WithFeatures(map[string]bool{"zzz": true, "aaa": false, featureNoAutoInsecure: true})
+	err := config.CheckFeatures()
+	if err == nil || !strings.HasPrefix(err.Error(), "unknown feature(s) aaa, zzz,") {
+		t.Errorf("expected an error naming both unknown features, got %v", err)
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	got, err := parseFeatures("strict-exec-argv, no-auto-insecure=false,")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]bool{featureStrictExecArgv: true, featureNoAutoInsecure: false}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parsed features did not match (-want +got): %s", diff)
+	}
+
+	if _, err := parseFeatures("strict-exec-argv=maybe"); err == nil {
+		t.Error("expected an error for a value that isn't a bool")
+	}
+
+	// --feature adds to what's there each time it's given
+	features := map[string]bool{}
+	fv := featuresValue{&features}
+	fv.Set(featureStrictExecArgv)
+	fv.Set(featureNoAutoInsecure + "=false")
+	if diff := cmp.Diff(want, features); diff != "" {
+		t.Errorf("--feature values did not match (-want +got): %s", diff)
+	}
+}
+
+func TestNoAutoInsecure(t *testing.T) {
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	if !config.GetInsecure() {
+		t.Error("expected loopback endpoints to be insecure by default")
+	}
+	if config.WithFeatures(map[string]bool{featureNoAutoInsecure: true}).GetInsecure() {
+		t.Errorf("expected loopback endpoints to use TLS with %s", featureNoAutoInsecure)
+	}
+	if !config.WithFeatures(map[string]bool{featureNoAutoInsecure: true}).WithInsecure(true).GetInsecure() {
+		t.Errorf("expected --insecure to still work with %s", featureNoAutoInsecure)
+	}
+}
+
+func TestUnknownConfigKeys(t *testing.T) {
+	got, err := unknownConfigKeys([]byte(`{"endpoint": "localhost:4317", "timout": "2s", "features": {}, "servce_name": "x"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff := cmp.Diff([]string{"servce_name", "timout"}, got); diff != "" {
+		t.Errorf("unknown keys did not match (-want +got): %s", diff)
+	}
+}
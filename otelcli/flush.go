@@ -0,0 +1,65 @@
+package otelcli
+
+import (
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+)
+
+func flushCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "flush",
+		Short: "send spans queued by --queue-dir",
+		Long: `Sends the spans that were saved in the --queue-dir because the collector
+couldn't be reached, oldest first. Each queued export is removed once it's sent,
+and anything that fails stays queued for the next flush. --timeout applies to
+each export on its own. Exports that can never be sent, because the file is
+corrupt or the server rejected it, are moved to the quarantine subdirectory.
+Flushes of the same queue wait for each other, so nothing is sent twice.
+
+Example:
+	otel-cli exec --queue-dir /var/spool/otel-cli --name backup -- ./backup.sh
+	# later, e.g. from cron
+	otel-cli flush --queue-dir /var/spool/otel-cli --endpoint localhost:4317
+`,
+		Run: doFlush,
+	}
+
+	addCommonParams(&cmd, config)
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doFlush(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+
+	if config.QueueDir == "" {
+		config.SoftFail("otel-cli flush needs a --queue-dir")
+	}
+	// the null and dry run clients "send" everything, which would empty the
+	// queue without anything reaching a collector
+	if config.DryRun {
+		config.Fatal("otel-cli flush can't be used with --dry-run, it would remove the queued spans")
+	} else if !config.GetIsRecording() {
+		config.Fatal("otel-cli flush needs an endpoint to send the queued spans to, and recording on")
+	}
+
+	// failures stay in the queue, so they aren't queued again or sent to
+	// the fallback a second time
+	ctx, client := StartClient(ctx, config.WithQueueDir("").WithFallback(""))
+	ctx, result, err := otlpclient.QueueFlush(ctx, client, config.QueueDir, config.GetTimeout())
+	config.SoftFailIfErr(err)
+
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+
+	config.SoftLog("flushed %d queued export(s) from %s", result.Sent, config.QueueDir)
+	if result.Quarantined > 0 {
+		config.SoftLog("quarantined %d queued export(s) that can't be sent", result.Quarantined)
+	}
+	if result.Failed > 0 {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("failed to flush %d of %d queued export(s) from %s", result.Failed, result.Sent+result.Failed, config.QueueDir)
+	}
+}
@@ -0,0 +1,38 @@
+package otelcli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// flush refuses to run when nothing would be sent, instead of emptying the
+// queue into the null or dry run client
+func TestFlushNotRecording(t *testing.T) {
+	dir := t.TempDir()
+	path, err := otlpclient.QueueTraces(dir, []*tracepb.ResourceSpans{{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{
+		{"flush", "--queue-dir", dir},
+		{"flush", "--queue-dir", dir, "--endpoint", "localhost:4317", "--recording=false"},
+		{"flush", "--queue-dir", dir, "--endpoint", "localhost:4317", "--dry-run"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := Run(args, &stdout, &stderr); code != 1 {
+			t.Errorf("%q: expected exit code 1, got %d", args, code)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%q: expected the queued export to be left alone: %s", args, err)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the queued export in %s, got %v", filepath.Base(dir), entries)
+	}
+}
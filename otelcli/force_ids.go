@@ -0,0 +1,109 @@
+package otelcli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// forcedIdCacheSize is how many forced ids --force-id-cache remembers.
+const forcedIdCacheSize = 1000
+
+// forcedIdsAttr marks spans with ids from --force-*-id, so backends can tell
+// synthetic ids from generated ones.
+var forcedIdsAttr = &commonpb.KeyValue{
+	Key:   "forced_ids",
+	Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}},
+}
+
+// parseForcedId parses the value of one of the --force-*-id flags, which has
+// to be valid hex of the right length and can't be all zeros since that's
+// how OTel marks an invalid id.
+func parseForcedId(flag, in string, size int) ([]byte, error) {
+	out, err := parseHex(in, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", flag, err)
+	}
+	if bytes.Equal(out, make([]byte, size)) {
+		return nil, fmt.Errorf("invalid --%s: %q is all zeros, which is not a valid id", flag, in)
+	}
+	return out, nil
+}
+
+// applyForcedIds sets the ids from --force-trace-id, --force-span-id and
+// --force-parent-span-id on the span and marks it with forcedIdsAttr.
+func (c Config) applyForcedIds(span *tracepb.Span) {
+	if c.ForceTraceId == "" && c.ForceSpanId == "" && c.ForceParentSpanId == "" {
+		return
+	}
+
+	var err error
+	if c.ForceTraceId != "" {
+		span.TraceId, err = parseForcedId("force-trace-id", c.ForceTraceId, 16)
+		c.SoftFailIfErr(err)
+	}
+	if c.ForceSpanId != "" {
+		span.SpanId, err = parseForcedId("force-span-id", c.ForceSpanId, 8)
+		c.SoftFailIfErr(err)
+	}
+	if c.ForceParentSpanId != "" {
+		span.ParentSpanId, err = parseForcedId("force-parent-span-id", c.ForceParentSpanId, 8)
+		c.SoftFailIfErr(err)
+	}
+
+	span.Attributes = append(span.Attributes, forcedIdsAttr)
+
+	if c.ForceIdCache != "" && c.ForceSpanId != "" {
+		used, err := checkForcedIdCache(c.ForceIdCache, span.TraceId, span.SpanId, c.now())
+		c.SoftLogIfErr(err)
+		if !used.IsZero() {
+			c.SoftLog("warning: span id %x in trace %x was already forced at %s", span.SpanId, span.TraceId, used.Format(time.RFC3339))
+		}
+	}
+}
+
+// checkForcedIdCache looks for the trace and span id in the cache file and
+// adds them to it, keeping only the most recent forcedIdCacheSize. Returns
+// when the ids were used before, or a zero time if they weren't.
+func checkForcedIdCache(path string, traceId, spanId []byte, now time.Time) (time.Time, error) {
+	var used time.Time
+	key := hex.EncodeToString(traceId) + " " + hex.EncodeToString(spanId)
+
+	lines := []string{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return used, err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// lines are "traceid spanid unixtime"
+		line := scanner.Text()
+		i := strings.LastIndex(line, " ")
+		if i < 0 {
+			continue
+		}
+		if line[:i] == key {
+			sec, err := strconv.ParseInt(line[i+1:], 10, 64)
+			if err == nil {
+				used = time.Unix(sec, 0)
+			}
+		}
+		lines = append(lines, line)
+	}
+
+	lines = append(lines, fmt.Sprintf("%s %d", key, now.Unix()))
+	if len(lines) > forcedIdCacheSize {
+		lines = lines[len(lines)-forcedIdCacheSize:]
+	}
+
+	err = os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	return used, err
+}
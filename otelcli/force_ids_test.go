@@ -0,0 +1,76 @@
+package otelcli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseForcedId(t *testing.T) {
+	if _, err := parseForcedId("force-span-id", "beefcafefacedead", 8); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	for in, want := range map[string]string{
+		"0000000000000000": "all zeros",
+		"beefcafe":         "wrong length",
+		"notahexstringxyz": "error parsing hex",
+	} {
+		_, err := parseForcedId("force-span-id", in, 8)
+		if err == nil || !strings.Contains(err.Error(), want) || !strings.Contains(err.Error(), "--force-span-id") {
+			t.Errorf("expected an error about --force-span-id containing %q for %q, got %v", want, in, err)
+		}
+	}
+}
+
+func TestForcedIdsAttribute(t *testing.T) {
+	span := DefaultConfig().NewProtobufSpan()
+	for _, attr := range span.Attributes {
+		if attr.Key == forcedIdsAttr.Key {
+			t.Error("span without forced ids was marked as forced")
+		}
+	}
+
+	span = DefaultConfig().WithForceSpanId("beefcafefacedead").NewProtobufSpan()
+	if len(span.Attributes) != 1 || span.Attributes[0].Key != "forced_ids" {
+		t.Errorf("expected the forced ids attribute, got %v", span.Attributes)
+	}
+}
+
+func TestCheckForcedIdCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids")
+	traceId := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	spanId := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	first := time.Unix(1711265285, 0)
+
+	used, err := checkForcedIdCache(path, traceId, spanId, first)
+	if err != nil || !used.IsZero() {
+		t.Fatalf("expected a new id on an empty cache, got %s, %v", used, err)
+	}
+
+	used, err = checkForcedIdCache(path, traceId, []byte{8, 7, 6, 5, 4, 3, 2, 1}, first.Add(time.Minute))
+	if err != nil || !used.IsZero() {
+		t.Errorf("expected a different span id to be new, got %s, %v", used, err)
+	}
+
+	used, err = checkForcedIdCache(path, traceId, spanId, first.Add(time.Hour))
+	if err != nil || !used.Equal(first) {
+		t.Errorf("expected the span id to have been used at %s, got %s, %v", first, used, err)
+	}
+
+	// the cache only keeps the most recent ids
+	for i := 0; i < forcedIdCacheSize+10; i++ {
+		if _, err := checkForcedIdCache(path, traceId, []byte{0, 0, 0, 0, 0, 0, byte(i >> 8), byte(i)}, first); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != forcedIdCacheSize {
+		t.Errorf("expected the cache to be trimmed to %d ids, got %d", forcedIdCacheSize, lines)
+	}
+}
@@ -0,0 +1,112 @@
+package otelcli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/textproto"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+// httpRequestLine matches the first line of an HTTP/1.x request.
+var httpRequestLine = regexp.MustCompile(`^[A-Z]+ \S+ HTTP/\d`)
+
+// httpStdinRequest is what --tp-http-stdin read: the request's headers and
+// whatever is left of stdin after them, which is the request body.
+type httpStdinRequest struct {
+	header http.Header
+	body   io.Reader
+}
+
+// traceparent returns the request's trace context in the propagation format,
+// reading the same headers the format's envvars are named after, e.g.
+// traceparent, b3, X-B3-TraceId, or uber-trace-id.
+func (hr httpStdinRequest) traceparent(format traceparent.Format, mode traceparent.ParseMode) (traceparent.Traceparent, error) {
+	tp, err := format.Load(func(name string) string {
+		return hr.header.Get(strings.ReplaceAll(name, "_", "-"))
+	}, mode)
+	if err != nil {
+		return tp, fmt.Errorf("request headers do not contain a valid %s traceparent: %w", format, err)
+	}
+	return tp, nil
+}
+
+// baggage returns the request's W3C baggage header, joined when it was sent
+// more than once.
+func (hr httpStdinRequest) baggage() string {
+	return strings.Join(hr.header.Values("Baggage"), ",")
+}
+
+// readStdinHttpRequest reads the request for --tp-http-stdin the first time
+// it's called and returns the same result after that. CGI servers pass the
+// headers in HTTP_* envvars instead, so when GATEWAY_INTERFACE is set they're
+// read from there and stdin is left alone for the body.
+func (c Config) readStdinHttpRequest() (httpStdinRequest, error) {
+	sc := c.stdinCache()
+	sc.httpOnce.Do(func() {
+		if os.Getenv("GATEWAY_INTERFACE") != "" {
+			sc.http = httpStdinRequest{header: cgiHeaders(os.Environ()), body: os.Stdin}
+		} else {
+			sc.http, sc.httpErr = readHttpRequest(os.Stdin)
+		}
+	})
+	return sc.http, sc.httpErr
+}
+
+// readHttpRequest reads an HTTP/1.x request's headers from r, with or
+// without the request line, up to the blank line that ends them or the end
+// of the input. The body is whatever is left of r. When r doesn't start with
+// a request the body is all of it, including the first line.
+func readHttpRequest(r io.Reader) (httpStdinRequest, error) {
+	br := bufio.NewReader(r)
+	tr := textproto.NewReader(br)
+	out := httpStdinRequest{header: http.Header{}, body: br}
+
+	// textproto wants header lines only, so the first line is handled here
+	raw, err := br.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return out, fmt.Errorf("could not read HTTP request: %w", err)
+	}
+	first := strings.TrimRight(raw, "\r\n")
+	if first == "" {
+		return out, nil
+	}
+
+	if !httpRequestLine.MatchString(first) {
+		key, value, ok := strings.Cut(first, ":")
+		if !ok {
+			out.body = io.MultiReader(strings.NewReader(raw), br)
+			return out, fmt.Errorf("stdin does not start with an HTTP request line or header: %q", first)
+		}
+		out.header.Add(textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(key)), strings.TrimSpace(value))
+	}
+
+	// headers without a blank line after them end at EOF
+	rest, err := tr.ReadMIMEHeader()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return out, fmt.Errorf("could not read HTTP request headers: %w", err)
+	}
+	for key, values := range rest {
+		out.header[key] = append(out.header[key], values...)
+	}
+
+	return out, nil
+}
+
+// cgiHeaders converts CGI's HTTP_* envvars back to request headers.
+func cgiHeaders(environ []string) http.Header {
+	out := http.Header{}
+	for _, env := range environ {
+		name, value, _ := strings.Cut(env, "=")
+		if name, ok := strings.CutPrefix(name, "HTTP_"); ok {
+			out.Add(strings.ReplaceAll(name, "_", "-"), value)
+		}
+	}
+	return out
+}
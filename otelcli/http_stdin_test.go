@@ -0,0 +1,113 @@
+package otelcli
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+)
+
+func TestReadHttpRequest(t *testing.T) {
+	const tpHeader = "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01"
+
+	for _, tc := range []struct {
+		name       string
+		input      string
+		tp         string
+		tracestate string
+		baggage    string
+		body       string
+		err        bool
+	}{
+		{
+			name:       "whole request",
+			input:      "POST /hook HTTP/1.1\r\nHost: example.com\r\nTraceparent: " + tpHeader + "\r\ntracestate: vendor=abc\r\nbaggage: user=1\r\nBaggage: tenant=2\r\n\r\n{\"event\": \"push\"}\n",
+			tp:         tpHeader,
+			tracestate: "vendor=abc",
+			baggage:    "user=1,tenant=2",
+			body:       "{\"event\": \"push\"}\n",
+		},
+		{
+			name:  "just headers",
+			input: "traceparent: " + tpHeader + "\ncontent-type: application/json\n",
+			tp:    tpHeader,
+		},
+		{
+			name:  "headers then body",
+			input: "traceparent: " + tpHeader + "\n\nhello",
+			tp:    tpHeader,
+			body:  "hello",
+		},
+		{
+			name:  "no trace context",
+			input: "GET / HTTP/1.0\r\nHost: example.com\r\n\r\n",
+		},
+		{name: "empty", input: ""},
+		// the command still gets all of the input when it isn't a request
+		{name: "not http", input: "just some text\nmore text\n", body: "just some text\nmore text\n", err: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := readHttpRequest(strings.NewReader(tc.input))
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got headers %v", req.header)
+				}
+				if body, _ := io.ReadAll(req.body); string(body) != tc.body {
+					t.Errorf("expected body %q, got %q", tc.body, body)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			tp, err := req.traceparent(traceparent.W3C, traceparent.Lenient)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if tc.tp == "" {
+				if tp.Initialized {
+					t.Errorf("expected no traceparent, got %q", tp.Encode())
+				}
+			} else if tp.Encode() != tc.tp {
+				t.Errorf("expected traceparent %q, got %q", tc.tp, tp.Encode())
+			}
+			if got := tp.Tracestate.Encode(); got != tc.tracestate {
+				t.Errorf("expected tracestate %q, got %q", tc.tracestate, got)
+			}
+			if got := req.baggage(); got != tc.baggage {
+				t.Errorf("expected baggage %q, got %q", tc.baggage, got)
+			}
+
+			body, _ := io.ReadAll(req.body)
+			if string(body) != tc.body {
+				t.Errorf("expected body %q, got %q", tc.body, body)
+			}
+		})
+	}
+}
+
+func TestHttpRequestFormats(t *testing.T) {
+	req, err := readHttpRequest(strings.NewReader("X-B3-TraceId: f61fc53f926e07a9c3893b1a722e1b65\r\nX-B3-SpanId: 7a2d6a804f3de137\r\nX-B3-Sampled: 1\r\n\r\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tp, err := req.traceparent(traceparent.B3Multi, traceparent.Lenient)
+	if err != nil || tp.Encode() != "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01" {
+		t.Errorf("expected the b3 headers to be read, got %q, %v", tp.Encode(), err)
+	}
+
+	// CGI passes the headers in envvars
+	cgi := httpStdinRequest{header: cgiHeaders([]string{
+		"GATEWAY_INTERFACE=CGI/1.1",
+		"HTTP_UBER_TRACE_ID=f61fc53f926e07a9c3893b1a722e1b65:7a2d6a804f3de137:0:1",
+		"HTTP_BAGGAGE=user=1",
+	})}
+	tp, err = cgi.traceparent(traceparent.Jaeger, traceparent.Lenient)
+	if err != nil || tp.Encode() != "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01" {
+		t.Errorf("expected the CGI jaeger header to be read, got %q, %v", tp.Encode(), err)
+	}
+	if cgi.baggage() != "user=1" {
+		t.Errorf("expected the CGI baggage to be read, got %q", cgi.baggage())
+	}
+}
@@ -0,0 +1,199 @@
+// configgen generates the With* builder methods and ToStringMap for
+// otelcli.Config from the struct definition, so the struct and its tags are
+// the only place a new option has to be added. LoadEnv already reads the env
+// tags at runtime.
+//
+// Run via go generate in the otelcli directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configField is one field of the Config struct.
+type configField struct {
+	Name    string // Go field name
+	Type    string // Go type as written in the source
+	JsonKey string // from the json tag, "-" when not serialized
+}
+
+func main() {
+	in := flag.String("in", "config.go", "file containing the Config struct")
+	out := flag.String("out", "config_generated.go", "file to write generated code to")
+	flag.Parse()
+
+	src, err := os.ReadFile(*in)
+	if err != nil {
+		log.Fatalf("failed to read %s: %s", *in, err)
+	}
+
+	code, err := generate(*in, src)
+	if err != nil {
+		log.Fatalf("failed to generate config code: %s", err)
+	}
+
+	if err := os.WriteFile(*out, code, 0644); err != nil {
+		log.Fatalf("failed to write %s: %s", *out, err)
+	}
+}
+
+// generate parses the Config struct out of src and returns the formatted
+// source for config_generated.go.
+func generate(filename string, src []byte) ([]byte, error) {
+	fields, imports, err := parseConfigFields(filename, src)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	body.WriteString("// ToStringMap flattens the configuration into a stringmap that is easy to work\n")
+	body.WriteString("// with in tests especially with cmp.Diff. See test_main.go. Keys are the json\n")
+	body.WriteString("// config file keys.\n")
+	body.WriteString("func (c Config) ToStringMap() map[string]string {\n")
+	body.WriteString("\treturn map[string]string{\n")
+	for _, f := range fields {
+		if f.JsonKey == "-" {
+			continue
+		}
+		value, err := stringValue(f)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&body, "\t\t%q: %s,\n", f.JsonKey, value)
+	}
+	body.WriteString("\t}\n}\n")
+
+	for _, f := range fields {
+		fmt.Fprintf(&body, "\n// With%s returns the config with %s set to the provided value.\n", f.Name, f.Name)
+		fmt.Fprintf(&body, "func (c Config) With%s(with %s) Config {\n", f.Name, f.Type)
+		fmt.Fprintf(&body, "\tc.%s = with\n\treturn c\n}\n", f.Name)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by configgen from config.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package otelcli\n\n")
+	if bytes.Contains(body.Bytes(), []byte("strconv.")) {
+		imports["strconv"] = "strconv"
+	}
+	used := []string{}
+	for name, path := range imports {
+		if bytes.Contains(body.Bytes(), []byte(name+".")) {
+			used = append(used, path)
+		}
+	}
+	// standard library first, like goimports
+	sort.Slice(used, func(i, j int) bool {
+		iStd, jStd := !strings.Contains(used[i], "."), !strings.Contains(used[j], ".")
+		if iStd != jStd {
+			return iStd
+		}
+		return used[i] < used[j]
+	})
+	if len(used) > 0 {
+		buf.WriteString("import (\n")
+		for i, path := range used {
+			if i > 0 && !strings.Contains(used[i-1], ".") && strings.Contains(path, ".") {
+				buf.WriteString("\n")
+			}
+			fmt.Fprintf(&buf, "\t%q\n", path)
+		}
+		buf.WriteString(")\n\n")
+	}
+	buf.Write(body.Bytes())
+
+	return format.Source(buf.Bytes())
+}
+
+// parseConfigFields returns the exported fields of the Config struct in order,
+// along with the file's imports by package name so field types like
+// otlpclient.Route can be imported by the generated code.
+func parseConfigFields(filename string, src []byte) ([]configField, map[string]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imports := map[string]string{}
+	for _, imp := range file.Imports {
+		path, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := path[strings.LastIndex(path, "/")+1:]
+		if imp.Name != nil {
+			name = imp.Name.Name
+		}
+		imports[name] = path
+	}
+
+	var st *ast.StructType
+	ast.Inspect(file, func(n ast.Node) bool {
+		if ts, ok := n.(*ast.TypeSpec); ok && ts.Name.Name == "Config" {
+			st, _ = ts.Type.(*ast.StructType)
+			return false
+		}
+		return st == nil
+	})
+	if st == nil {
+		return nil, nil, fmt.Errorf("no Config struct found in %s", filename)
+	}
+
+	fields := []configField{}
+	for _, field := range st.Fields.List {
+		var typ bytes.Buffer
+		if err := format.Node(&typ, fset, field.Type); err != nil {
+			return nil, nil, err
+		}
+
+		var jsonKey string
+		if field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, nil, err
+			}
+			jsonKey, _, _ = strings.Cut(reflect.StructTag(tag).Get("json"), ",")
+		}
+
+		for _, name := range field.Names {
+			if !name.IsExported() {
+				continue
+			}
+			if jsonKey == "" {
+				return nil, nil, fmt.Errorf("Config field %s needs a json tag", name.Name)
+			}
+			fields = append(fields, configField{Name: name.Name, Type: typ.String(), JsonKey: jsonKey})
+		}
+	}
+
+	return fields, imports, nil
+}
+
+// stringValue returns the Go expression that converts the field to a string.
+func stringValue(f configField) (string, error) {
+	switch f.Type {
+	case "string":
+		return "c." + f.Name, nil
+	case "bool":
+		return "strconv.FormatBool(c." + f.Name + ")", nil
+	case "int":
+		return "strconv.Itoa(c." + f.Name + ")", nil
+	case "map[string]string":
+		return "flattenStringMap(c." + f.Name + ", \"{}\")", nil
+	default:
+		// anything more complex, e.g. lists of structs, is compared as json
+		return "jsonString(c." + f.Name + ")", nil
+	}
+}
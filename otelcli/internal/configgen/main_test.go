@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+// TestGeneratedIsCurrent fails when config.go changed without re-running
+// go generate, so new Config fields can't be forgotten.
+func TestGeneratedIsCurrent(t *testing.T) {
+	src, err := os.ReadFile("../../config.go")
+	if err != nil {
+		t.Fatalf("failed to read config.go: %s", err)
+	}
+
+	want, err := generate("config.go", src)
+	if err != nil {
+		t.Fatalf("failed to generate: %s", err)
+	}
+
+	got, err := os.ReadFile("../../config_generated.go")
+	if err != nil {
+		t.Fatalf("failed to read config_generated.go: %s", err)
+	}
+
+	if !bytes.Equal(want, got) {
+		t.Error("config_generated.go is out of date, run go generate ./otelcli/...")
+	}
+}
+
+func TestGenerateRejectsUntaggedFields(t *testing.T) {
+	src := []byte("package otelcli\n\ntype Config struct {\n\tEndpoint string\n}\n")
+	if _, err := generate("config.go", src); err == nil {
+		t.Error("expected an error for a Config field without a json tag")
+	}
+}
@@ -0,0 +1,101 @@
+package otelcli
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// logCmd represents the log command
+func logCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "log [message]",
+		Short: "send an OpenTelemetry log record",
+		Long: `Send an OpenTelemetry log record to the same OTLP endpoint as spans. The
+body is the --body flag, or the rest of the command line. When a traceparent
+is available from TRACEPARENT or --tp-carrier, its trace and span ids are put
+on the log record so it shows up with the trace.
+
+Example:
+	otel-cli log --severity warn --attrs "disk.free=$(df --output=avail / | tail -1)" \
+		"disk is almost full"
+`,
+		Run: doLog,
+	}
+
+	cmd.Flags().SortFlags = false
+
+	defaults := DefaultConfig()
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVar(&config.LogsEndpoint, "logs-endpoint", defaults.LogsEndpoint, "HTTP(s) URL for logs, when they go somewhere other than the endpoint")
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the logs")
+	cmd.Flags().StringVar(&config.LogBody, "body", defaults.LogBody, "the log message, instead of passing it as arguments")
+	cmd.Flags().StringVar(&config.LogSeverity, "severity", defaults.LogSeverity, "log severity: trace, debug, info, warn, error, or fatal, with an optional 1-4 suffix e.g. info2")
+	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doLog(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+
+	if config.LogsEndpoint != "" {
+		// signal-specific endpoints are used as-is, same as traces
+		config = config.WithTracesEndpoint(config.LogsEndpoint)
+	}
+
+	record := config.NewProtobufLogRecord(args)
+
+	ctx, client := StartClient(ctx, config)
+	ctx, err := otlpclient.SendLogs(ctx, client, config, []*logspb.LogRecord{record})
+	if err != nil {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("unable to send log: %s", err)
+	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+}
+
+// NewProtobufLogRecord creates a log record from the config, using args as
+// the body when --body isn't set, and the traceparent for correlation.
+func (c Config) NewProtobufLogRecord(args []string) *logspb.LogRecord {
+	record := otlpclient.NewProtobufLogRecord()
+	record.TimeUnixNano = uint64(c.now().UnixNano())
+	record.ObservedTimeUnixNano = record.TimeUnixNano
+
+	severity, err := otlpclient.SeverityStringToNumber(c.LogSeverity)
+	c.SoftFailIfErr(err)
+	record.SeverityNumber = severity
+	record.SeverityText = strings.ToUpper(c.LogSeverity)
+
+	body := c.LogBody
+	if body == "" {
+		body = strings.Join(args, " ")
+	}
+	record.Body.Value = &commonpb.AnyValue_StringValue{StringValue: body}
+	record.Attributes, record.DroppedAttributesCount = c.parseLimitedAttributes()
+
+	if c.GetIsRecording() {
+		// zeroed traceparents come from non-recording parents, skip those
+		tp := c.LoadTraceparent()
+		if tp.Initialized && !bytes.Equal(tp.TraceId, otlpclient.GetEmptyTraceId()) {
+			record.TraceId = tp.TraceId
+			record.SpanId = tp.SpanId
+			if tp.Sampling {
+				record.Flags = 0x01 // the w3c sampled flag
+			}
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,46 @@
+package otelcli
+
+import (
+	"encoding/hex"
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestNewProtobufLogRecordWithConfig(t *testing.T) {
+	t.Setenv("TRACEPARENT", "00-3433d5ae39bdfee397f44be5146867b3-8a5518f1e5c54d0a-01")
+
+	config := DefaultConfig().
+		WithEndpoint("localhost:4317").
+		WithLogSeverity("warn2").
+		WithAttributes(map[string]string{"disk": "sda"})
+
+	record := config.NewProtobufLogRecord([]string{"disk", "is", "full"})
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN2 || record.SeverityText != "WARN2" {
+		t.Errorf("expected severity WARN2, got %s/%s", record.SeverityNumber, record.SeverityText)
+	}
+	if body := record.Body.GetStringValue(); body != "disk is full" {
+		t.Errorf("expected body from args, got %q", body)
+	}
+	if len(record.Attributes) != 1 || record.Attributes[0].Key != "disk" {
+		t.Errorf("expected the disk attribute, got %v", record.Attributes)
+	}
+	if tid := hex.EncodeToString(record.TraceId); tid != "3433d5ae39bdfee397f44be5146867b3" {
+		t.Errorf("expected trace id from TRACEPARENT, got %q", tid)
+	}
+	if sid := hex.EncodeToString(record.SpanId); sid != "8a5518f1e5c54d0a" {
+		t.Errorf("expected span id from TRACEPARENT, got %q", sid)
+	}
+	if record.Flags != 1 {
+		t.Errorf("expected sampled flag to be set, got %d", record.Flags)
+	}
+
+	config = config.WithLogBody("from the flag").WithTraceparentIgnoreEnv(true)
+	record = config.NewProtobufLogRecord([]string{"ignored"})
+	if body := record.Body.GetStringValue(); body != "from the flag" {
+		t.Errorf("expected body from --body, got %q", body)
+	}
+	if len(record.TraceId) != 0 || len(record.SpanId) != 0 {
+		t.Errorf("expected no trace correlation without a traceparent, got %x/%x", record.TraceId, record.SpanId)
+	}
+}
@@ -0,0 +1,104 @@
+package otelcli
+
+import (
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// metricCmd represents the metric command
+func metricCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "metric",
+		Short: "send OpenTelemetry metrics",
+		Long: `Send a single OpenTelemetry metric data point to the same OTLP endpoint as
+spans, e.g. from cron jobs. Counters are sent as an increment (a delta sum)
+and gauges as the current value. Put -- before negative values so they
+aren't taken for flags.
+
+Example:
+	otel-cli metric counter job.runs 1 --attrs status=ok
+	otel-cli metric gauge disk.free 12345
+	otel-cli metric gauge -- temperature -3.5
+`,
+	}
+
+	cmd.AddCommand(metricKindCmd(config, "counter", "add value to a counter"))
+	cmd.AddCommand(metricKindCmd(config, "gauge", "record the current value of a gauge"))
+
+	return &cmd
+}
+
+// metricKindCmd returns the subcommand for one kind of metric. They all take
+// the same flags and arguments.
+func metricKindCmd(config *Config, kind, short string) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   kind + " <name> <value>",
+		Short: short,
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			doMetric(cmd, kind, args[0], args[1])
+		},
+	}
+
+	cmd.Flags().SortFlags = false
+
+	defaults := DefaultConfig()
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVar(&config.MetricsEndpoint, "metrics-endpoint", defaults.MetricsEndpoint, "HTTP(s) URL for metrics, when they go somewhere other than the endpoint")
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the metric")
+	addAttrParams(&cmd, config)
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doMetric(cmd *cobra.Command, kind, name, value string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+
+	if config.MetricsEndpoint != "" {
+		// signal-specific endpoints are used as-is, same as traces
+		config = config.WithTracesEndpoint(config.MetricsEndpoint)
+	}
+
+	var metric *metricspb.Metric
+	var err error
+	switch kind {
+	case "counter":
+		metric, err = otlpclient.NewProtobufCounter(name, value, config.Attributes)
+	case "gauge":
+		metric, err = otlpclient.NewProtobufGauge(name, value, config.Attributes)
+	}
+	config.SoftFailIfErr(err)
+	setMetricTime(metric, config.now())
+
+	ctx, client := StartClient(ctx, config)
+	ctx, err = otlpclient.SendMetrics(ctx, client, config, []*metricspb.Metric{metric})
+	if err != nil {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("unable to send metric: %s", err)
+	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+}
+
+// setMetricTime sets the time on metric's data points to now, so --fake-now
+// pins metrics the same as spans. Counters start when they're sent.
+func setMetricTime(metric *metricspb.Metric, now time.Time) {
+	if metric == nil {
+		return
+	}
+	ts := uint64(now.UnixNano())
+	for _, dp := range metric.GetGauge().GetDataPoints() {
+		dp.TimeUnixNano = ts
+	}
+	for _, dp := range metric.GetSum().GetDataPoints() {
+		dp.StartTimeUnixNano = ts
+		dp.TimeUnixNano = ts
+	}
+}
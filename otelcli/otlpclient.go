@@ -2,42 +2,178 @@ package otelcli
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
 	"strings"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
 )
 
 // StartClient uses the Config to setup and start either a gRPC or HTTP client,
-// and returns the OTLPClient interface to them.
+// and returns the OTLPClient interface to them. When routes are configured the
+// client is wrapped in a RoutingClient that sends matching spans elsewhere,
+// with --health-file in a client that backs off along with other processes,
+// with --queue-dir in a client that saves spans to disk when the export
+// fails, and with --fallback in a client that pushes metrics when that fails.
+// With --dry-run, the client prints payloads to stdout instead.
 func StartClient(ctx context.Context, config Config) (context.Context, otlpclient.OTLPClient) {
 	if !config.GetIsRecording() {
 		return ctx, otlpclient.NewNullClient(config)
 	}
 
-	if config.Protocol != "" && config.Protocol != "grpc" && config.Protocol != "http/protobuf" {
-		err := fmt.Errorf("invalid protocol setting %q", config.Protocol)
-		Diag.Error = err.Error()
+	if config.DryRun {
+		return ctx, newDryRunClient(config.getStdout())
+	}
+
+	client, err := newOtlpClient(config)
+	if err != nil {
+		config.diag.setError(err)
 		config.SoftFail(err.Error())
 	}
 
-	endpointURL := config.GetEndpoint()
+	if config.EndpointHistory {
+		client = newHistoryClient(client, config)
+	}
+
+	if config.HealthFile != "" {
+		client = otlpclient.NewHealthClient(client, config.HealthFile)
+	}
 
-	var client otlpclient.OTLPClient
-	if config.Protocol != "grpc" &&
-		(strings.HasPrefix(config.Protocol, "http/") ||
-			endpointURL.Scheme == "http" ||
-			endpointURL.Scheme == "https") {
-		client = otlpclient.NewHttpClient(config)
-	} else {
-		client = otlpclient.NewGrpcClient(config)
+	if len(config.Routes) > 0 {
+		client = otlpclient.NewRoutingClient(client, config.Routes, config.startRouteClient)
 	}
 
-	ctx, err := client.Start(ctx)
+	if config.QueueDir != "" {
+		client = otlpclient.NewQueueClient(client, config.QueueDir)
+	}
+
+	if config.Fallback != "" {
+		pushURL, err := config.ParseFallback()
+		if err != nil {
+			config.diag.setError(err)
+			config.SoftFail(err.Error())
+		}
+		client = otlpclient.NewPushgatewayClient(client, config, pushURL)
+	}
+
+	ctx, err = client.Start(ctx)
 	if err != nil {
-		Diag.Error = err.Error()
+		config.diag.setError(err)
 		config.SoftFail("Failed to start OTLP client: %s", err)
 	}
 
 	return ctx, client
 }
+
+// ParseFallback parses --fallback, which is kind=url. Only the Prometheus
+// pushgateway is supported for now.
+func (c Config) ParseFallback() (*url.URL, error) {
+	kind, target, _ := strings.Cut(c.Fallback, "=")
+	if kind != "pushgateway" {
+		return nil, fmt.Errorf("invalid --fallback %q, only pushgateway=<url> is supported", c.Fallback)
+	}
+
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid --fallback %q, pushgateway needs an http(s) URL", c.Fallback)
+	}
+
+	return u, nil
+}
+
+// newOtlpClient returns a gRPC or HTTP client based on the protocol and
+// endpoint in the config, without starting it.
+func newOtlpClient(config Config) (otlpclient.OTLPClient, error) {
+	if !isValidProtocol(config.Protocol) {
+		return nil, fmt.Errorf("invalid protocol setting %q", config.Protocol)
+	}
+
+	if !slices.Contains(otlpclient.Compressions, config.GetCompression()) {
+		return nil, fmt.Errorf("invalid compression setting %q, must be gzip, zstd, or none", config.Compression)
+	}
+
+	endpointURL := config.GetEndpoint()
+	if endpointURL.Scheme == "udp" {
+		return nil, fmt.Errorf("udp endpoints are only supported by otel-cli server")
+	}
+
+	if config.resolvedProtocol(endpointURL.Scheme) != "grpc" {
+		return otlpclient.NewHttpClient(config), nil
+	}
+	return otlpclient.NewGrpcClient(config), nil
+}
+
+// isValidProtocol returns true when otel-cli can send with protocol. Empty
+// picks one from the endpoint.
+func isValidProtocol(protocol string) bool {
+	return protocol == "" || protocol == "grpc" || protocol == "http/protobuf" || protocol == otlpclient.HttpJsonProtocol
+}
+
+// validateRoutes checks that every route has an endpoint and a protocol
+// otel-cli can send with, so mistakes in the config file show up when it's
+// loaded instead of when a span first matches the route.
+func validateRoutes(routes []otlpclient.Route) error {
+	errs := []error{}
+	for i, route := range routes {
+		if route.Endpoint == "" {
+			errs = append(errs, fmt.Errorf("route %d has no endpoint", i+1))
+		}
+		if !isValidProtocol(route.Protocol) {
+			errs = append(errs, fmt.Errorf("route %d has invalid protocol %q", i+1, route.Protocol))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// routeHealthFile returns the --health-file for a route's endpoint. It's
+// next to the configured one and named after the endpoint, so a failing
+// route backs off on its own instead of holding up everything else.
+func routeHealthFile(path, endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return path + ".route-" + hex.EncodeToString(sum[:4])
+}
+
+// startRouteClient creates and starts a client for a route's endpoint,
+// wrapped for --endpoint-history and --health-file like the regular one. All
+// other settings, e.g. TLS and timeouts, come from the config.
+func (c Config) startRouteClient(ctx context.Context, route otlpclient.Route) (context.Context, otlpclient.OTLPClient, error) {
+	routeConfig := c.WithEndpoint(route.Endpoint).
+		WithTracesEndpoint("").
+		WithHeaders(route.Headers)
+	if route.Protocol != "" {
+		routeConfig = routeConfig.WithProtocol(route.Protocol)
+	}
+
+	client, err := newOtlpClient(routeConfig)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("route to %q: %w", route.Endpoint, err)
+	}
+
+	if c.EndpointHistory {
+		client = newHistoryClient(client, routeConfig)
+	}
+
+	if c.HealthFile != "" {
+		client = otlpclient.NewHealthClient(client, routeHealthFile(c.HealthFile, route.Endpoint))
+	}
+
+	ctx, err = client.Start(ctx)
+	if err != nil {
+		return ctx, nil, fmt.Errorf("route to %q: failed to start OTLP client: %w", route.Endpoint, err)
+	}
+
+	return ctx, client, nil
+}
+
+// SoftLogErrorList logs every error saved in ctx by the OTLP client, one per
+// line with its timestamp, endpoint, and attempt, so a failed send shows all
+// of the retries that led up to it when --verbose is set.
+func (c Config) SoftLogErrorList(ctx context.Context) {
+	for _, te := range otlpclient.GetErrorList(ctx) {
+		c.SoftLog("OTLP error: %s", te)
+	}
+}
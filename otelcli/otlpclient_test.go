@@ -0,0 +1,95 @@
+package otelcli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/equinix-labs/otel-cli/otlpserver"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestUnixSocketClients(t *testing.T) {
+	for _, protocol := range []string{"grpc", "http/protobuf"} {
+		t.Run(protocol, func(t *testing.T) {
+			sock := filepath.Join(t.TempDir(), "otlp.sock")
+
+			received := make(chan string, 1)
+			cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+				received <- span.Name
+				return false
+			}
+			serverProtocol := "grpc"
+			if protocol != "grpc" {
+				serverProtocol = "http"
+			}
+			cs := otlpserver.NewServer(serverProtocol, cb, func(otlpserver.OtlpServer) {})
+			go cs.ListenAndServe("unix://" + sock)
+			defer cs.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			// the server listens in the background, wait for the socket
+			for i := 0; i < 50; i++ {
+				if _, err := os.Stat(sock); err == nil {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			config := DefaultConfig().WithEndpoint("unix://" + sock).WithProtocol(protocol)
+			client, err := newOtlpClient(config)
+			if err != nil {
+				t.Fatalf("failed to create client: %s", err)
+			}
+			ctx, err = client.Start(ctx)
+			if err != nil {
+				t.Fatalf("failed to start client: %s", err)
+			}
+			span := otlpclient.NewProtobufSpan()
+			span.Name = "over-unix"
+			if _, err = otlpclient.SendSpans(ctx, client, config, []*tracepb.Span{span}); err != nil {
+				t.Fatalf("upload over unix socket failed: %s", err)
+			}
+			client.Stop(ctx)
+
+			select {
+			case name := <-received:
+				if name != "over-unix" {
+					t.Errorf("expected span over-unix, got %q", name)
+				}
+			case <-ctx.Done():
+				t.Fatal("server never received the span")
+			}
+		})
+	}
+}
+
+func TestStartRouteClient(t *testing.T) {
+	// route clients get the same wrappers as the regular one, with their own
+	// health file so a failing route doesn't hold up the regular endpoint
+	healthFile := filepath.Join(t.TempDir(), "health.json")
+	config := DefaultConfig().
+		WithEndpoint("localhost:4317").
+		WithEndpointHistory(true).
+		WithHealthFile(healthFile)
+	route := otlpclient.Route{Endpoint: "http://127.0.0.1:4319"}
+
+	ctx, client, err := config.startRouteClient(context.Background(), route)
+	if err != nil {
+		t.Fatalf("startRouteClient failed: %s", err)
+	}
+	defer client.Stop(ctx)
+
+	if _, ok := client.(*otlpclient.HealthClient); !ok {
+		t.Errorf("expected the route client to be wrapped in a HealthClient, got %T", client)
+	}
+	routeFile := routeHealthFile(healthFile, route.Endpoint)
+	if routeFile == healthFile || filepath.Dir(routeFile) != filepath.Dir(healthFile) {
+		t.Errorf("expected the route to have its own health file next to %s, got %s", healthFile, routeFile)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPrintFd returns a duplicate of an inherited file descriptor, for
+// --tp-print-fd and --print-json-fd. The caller closes it when it's done,
+// which leaves the descriptor itself open for whoever it belongs to, e.g.
+// the shell, or the next span in the same process.
+func openPrintFd(fd int) (*os.File, error) {
+	dup, err := unix.Dup(fd)
+	if err != nil {
+		return nil, fmt.Errorf("could not use fd %d: %w", fd, err)
+	}
+	unix.CloseOnExec(dup)
+	return os.NewFile(uintptr(dup), fmt.Sprintf("fd %d", fd)), nil
+}
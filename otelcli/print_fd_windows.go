@@ -0,0 +1,23 @@
+//go:build windows
+
+package otelcli
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// openPrintFd returns a duplicate of an inherited handle, for --tp-print-fd
+// and --print-json-fd. The caller closes it when it's done, which leaves the
+// handle itself open for whoever it belongs to.
+func openPrintFd(fd int) (*os.File, error) {
+	proc := windows.CurrentProcess()
+	var dup windows.Handle
+	err := windows.DuplicateHandle(proc, windows.Handle(fd), proc, &dup, 0, false, windows.DUPLICATE_SAME_ACCESS)
+	if err != nil {
+		return nil, fmt.Errorf("could not use fd %d: %w", fd, err)
+	}
+	return os.NewFile(uintptr(dup), fmt.Sprintf("fd %d", fd)), nil
+}
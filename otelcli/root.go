@@ -4,8 +4,14 @@ package otelcli
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"runtime"
+	"strings"
+	"time"
 
+	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/spf13/cobra"
 )
 
@@ -37,6 +43,11 @@ func getConfig(ctx context.Context) Config {
 	return *config
 }
 
+func init() {
+	// cobra's setting is global, so it's set once instead of on every Run
+	cobra.EnableCommandSorting = false
+}
+
 // createRootCmd builds up the Cobra command-line, calling through to subcommand
 // builder funcs to build the whole tree.
 func createRootCmd(config *Config) *cobra.Command {
@@ -54,60 +65,190 @@ func createRootCmd(config *Config) *cobra.Command {
 				// will need to specify --fail --verbose flags to see these errors
 				config.SoftFail("Error while loading environment variables: %s", err)
 			}
+			if err := config.LoadAttrEnv(os.Environ()); err != nil {
+				config.SoftFail("Error while loading environment variables: %s", err)
+			}
+			// servers listen on --endpoint, headers, templates, and vendors
+			// are for clients, headers go first so vendors see them
+			if cmd.Flags().Lookup("otlp-headers-file") != nil {
+				if err := config.LoadHeaders(os.Getenv); err != nil {
+					config.diag.setError(err)
+					config.SoftFail("%s", err)
+				}
+			}
+			if cmd.Flags().Lookup("vendor") != nil {
+				if err := config.ApplyEndpointTemplate(os.Getenv); err != nil {
+					config.diag.setError(err)
+					config.SoftFail("%s", err)
+				}
+			}
+			if err := config.CheckFeatures(); err != nil {
+				config.diag.setError(err)
+				config.SoftFail("%s", err)
+			}
+			config.diag.update(func(d *Diagnostics) { d.Features = config.EnabledFeatures() })
+			if err := config.NormalizeSpanEnums(); err != nil {
+				config.diag.setError(err)
+				config.SoftFail("%s", err)
+			}
+			if cmd.Flags().Lookup("resource-detectors") != nil {
+				if err := config.CheckResourceDetectors(); err != nil {
+					config.diag.setError(err)
+					config.SoftFail("%s", err)
+				}
+			}
+			// --recording=require fails regardless of --fail, that's its point
+			if cmd.Flags().Lookup("recording") != nil {
+				if err := config.CheckRecording(); err != nil {
+					config.diag.setError(err)
+					config.Fatal("%s", err)
+				}
+			}
+			// traceparents on stdin are read now, so the wait for them isn't
+			// counted against --timeout, see timeoutContext
+			if cmd.Flags().Lookup("tp-stdin") != nil && config.TraceparentStdin {
+				config.readStdin(func() { config.readStdinTraceparent() })
+			}
+			if cmd.Flags().Lookup("tp-http-stdin") != nil && config.TraceparentHttpStdin {
+				config.readStdin(func() { config.readStdinHttpRequest() })
+			}
+			// pin the clock before anything generates a timestamp, spans get
+			// it from config.now and otlpclient's errors from the context
+			if config.FakeNow != "" {
+				fakeNow, err := config.ParseFakeNow()
+				config.SoftFailIfErr(err)
+				if err == nil {
+					cmd.SetContext(otlpclient.WithFakeNow(cmd.Context(), fakeNow))
+				}
+			}
 		},
 	}
 
-	cobra.EnableCommandSorting = false
 	rootCmd.Flags().SortFlags = false
 
-	Diag.NumArgs = len(os.Args) - 1
-	Diag.CliArgs = []string{}
-	if len(os.Args) > 1 {
-		Diag.CliArgs = os.Args[1:]
-	}
-
 	// add all the subcommands to rootCmd
 	rootCmd.AddCommand(spanCmd(config))
+	rootCmd.AddCommand(traceCmd(config))
 	rootCmd.AddCommand(execCmd(config))
+	rootCmd.AddCommand(logCmd(config))
+	rootCmd.AddCommand(metricCmd(config))
 	rootCmd.AddCommand(statusCmd(config))
 	rootCmd.AddCommand(serverCmd(config))
+	rootCmd.AddCommand(verifyCmd(config))
+	rootCmd.AddCommand(flushCmd(config))
 	rootCmd.AddCommand(versionCmd(config))
 	rootCmd.AddCommand(completionCmd(config))
+	rootCmd.AddCommand(shellhookCmd(config))
+	rootCmd.AddCommand(historyCmd(config))
+	rootCmd.AddCommand(configCmd(config))
+
+	registerCompletions(rootCmd, config)
 
 	return rootCmd
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
-// This is called by main.main(). It only needs to happen once.
-func Execute(version string) {
+// This is called by main.main(). It only needs to happen once. Returns the
+// exit code, which is the child's for otel-cli exec.
+func Execute(version string) int {
+	handleSigpipe()
+
 	config := DefaultConfig()
 	config.Version = version
 
+	return execute(&config, os.Args[1:])
+}
+
+// Run runs otel-cli in-process with args, which don't include the program
+// name, writing output to stdout and logs to stderr. It returns the exit code
+// instead of exiting, so otel-cli can be embedded in other programs and
+// tests can call it without building a binary. Each call has its own config,
+// clock, and what it read from stdin, so calls can run concurrently. Envvars
+// and stdin are still the process's, and an OTLP server that can't listen or
+// can't write to its sink still exits the process.
+//
+// Run returns as soon as otel-cli would have exited, whichever goroutine it
+// exits from, e.g. exec's heartbeats. Like with os.Exit, the first exit's
+// code is the one returned, and goroutines that are still running, e.g. a
+// server's, aren't waited for.
+func Run(args []string, stdout, stderr io.Writer) int {
+	config := DefaultConfig()
+	config.Version = embeddedVersion()
+	config.stdout = stdout
+	config.stderr = stderr
+
+	// the first exit is handed to Run and the goroutine that exited stops
+	// there, running its defers the same as returning would
+	exits := make(chan int, 1)
+	exit := func(code int) {
+		select {
+		case exits <- code:
+		default:
+		}
+	}
+	config.exitFunc = func(code int) {
+		exit(code)
+		runtime.Goexit()
+	}
+
+	go func() { exit(execute(&config, args)) }()
+	return <-exits
+}
+
+// execute runs the command line in args with config, returning the exit code.
+func execute(config *Config, args []string) int {
+	config.startupTime = time.Now()
+
+	// diagnostics are per invocation and travel with the config and context
+	ctx, diag := withDiagnostics(context.Background())
+	diag.update(func(d *Diagnostics) {
+		d.NumArgs = len(args)
+		d.CliArgs = append([]string{}, args...)
+	})
+	config.diag = diag
+	config.stdin = &stdinCache{}
+
 	// Cobra can tunnel config through context, so set that up now
-	ctx := context.WithValue(context.Background(), configContextKey(), &config)
+	ctx = context.WithValue(ctx, configContextKey(), config)
+
+	rootCmd := createRootCmd(config)
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(config.getStdout())
+	rootCmd.SetErr(config.getStderr())
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		// what cobra.CheckErr does, but through config.exit for Run
+		fmt.Fprintln(config.getStderr(), "Error:", err)
+		config.exit(1)
+	}
 
-	rootCmd := createRootCmd(&config)
-	cobra.CheckErr(rootCmd.ExecuteContext(ctx))
+	return GetDiagnostics(ctx).ExecExitCode
 }
 
 // addCommonParams adds the --config and --endpoint params to the command.
 func addCommonParams(cmd *cobra.Command, config *Config) {
 	defaults := DefaultConfig()
 
-	// --config / -c a JSON configuration file
-	cmd.Flags().StringVarP(&config.CfgFile, "config", "c", defaults.CfgFile, "JSON configuration file")
+	// --config / -c a JSON, YAML, or TOML configuration file
+	cmd.Flags().StringVarP(&config.CfgFile, "config", "c", defaults.CfgFile, "JSON, YAML, or TOML configuration file")
+	// --config-format overrides picking the config file's format by extension
+	cmd.Flags().StringVar(&config.CfgFormat, "config-format", defaults.CfgFormat, "the format of the --config file: json, yaml, or toml, by default from its extension")
 	// --endpoint an endpoint to send otlp output to
 	cmd.Flags().StringVar(&config.Endpoint, "endpoint", defaults.Endpoint, "host and port for the desired OTLP/gRPC or OTLP/HTTP endpoint (use http:// or https:// for OTLP/HTTP)")
 	// --traces-endpoint sets the endpoint for the traces signal
 	cmd.Flags().StringVar(&config.TracesEndpoint, "traces-endpoint", defaults.TracesEndpoint, "HTTP(s) URL for traces")
 	// --protocol allows setting the OTLP protocol instead of relying on auto-detection from URI
-	cmd.Flags().StringVar(&config.Protocol, "protocol", defaults.Protocol, "desired OTLP protocol: grpc or http/protobuf")
+	cmd.Flags().StringVar(&config.Protocol, "protocol", defaults.Protocol, "desired OTLP protocol: grpc, http/protobuf, or http/json")
 	// --timeout a default timeout to use in all otel-cli operations (default 1s)
 	cmd.Flags().StringVar(&config.Timeout, "timeout", defaults.Timeout, "timeout for otel-cli operations, all timeouts in otel-cli use this value")
 	// --verbose tells otel-cli to actually log errors to stderr instead of failing silently
 	cmd.Flags().BoolVar(&config.Verbose, "verbose", defaults.Verbose, "print errors on failure instead of always being silent")
 	// --fail causes a non-zero exit status on error
 	cmd.Flags().BoolVar(&config.Fail, "fail", defaults.Fail, "on failure, exit with a non-zero status")
+	// --fake-now pins all generated timestamps, hidden since it's only for testing
+	cmd.Flags().StringVar(&config.FakeNow, "fake-now", defaults.FakeNow, "pin the clock to a Unix epoch or RFC3339 timestamp for reproducible output")
+	cmd.Flags().MarkHidden("fake-now")
+	// --feature turns experimental behavior on or off, repeatable
+	cmd.Flags().Var(featuresValue{&config.Features}, "feature", "turn on an experimental feature, or off with name=false, can be repeated: "+featureNames())
 }
 
 // addClientParams adds the common CLI flags for e.g. span and exec to the command.
@@ -118,8 +259,15 @@ func addClientParams(cmd *cobra.Command, config *Config) {
 	defaults := DefaultConfig()
 	config.Headers = make(map[string]string)
 
+	// --endpoint-template and --vendor fill in the endpoint when it isn't set
+	cmd.Flags().StringVar(&config.EndpointTemplate, "endpoint-template", defaults.EndpointTemplate, "build the endpoint from a template, {region} is replaced with --region, e.g. https://otlp.{region}.example.com:4318")
+	cmd.Flags().StringVar(&config.Region, "region", defaults.Region, "the region for --endpoint-template or --vendor")
+	cmd.Flags().StringVar(&config.Vendor, "vendor", defaults.Vendor, "configure the endpoint, protocol, and required headers for a SaaS backend: "+vendorNames())
+
 	// OTEL_EXPORTER standard env and variable params
-	cmd.Flags().StringToStringVar(&config.Headers, "otlp-headers", defaults.Headers, "a comma-sparated list of key=value headers to send on OTLP connection")
+	cmd.Flags().StringToStringVar(&config.Headers, "otlp-headers", defaults.Headers, "a comma-sparated list of key=value headers to send on OTLP connection, values can be @/path/to/file or env:VARNAME to read them from there")
+	// --otlp-headers-file keeps tokens out of argv, where ps can see them
+	cmd.Flags().StringVar(&config.HeadersFile, "otlp-headers-file", defaults.HeadersFile, "a file of key=value header lines to send on OTLP connection, --otlp-headers take precedence")
 
 	// DEPRECATED
 	// TODO: remove before 1.0
@@ -132,13 +280,48 @@ func addClientParams(cmd *cobra.Command, config *Config) {
 	cmd.Flags().BoolVar(&config.TlsNoVerify, "tls-no-verify", defaults.TlsNoVerify, "insecure! disables verification of the server certificate and name, mostly for self-signed CAs")
 	// --no-tls-verify is deprecated, will remove before 1.0
 	cmd.Flags().BoolVar(&config.TlsNoVerify, "no-tls-verify", defaults.TlsNoVerify, "(deprecated) same as --tls-no-verify")
+	// --signing-key-file enables HMAC signatures on exported payloads
+	cmd.Flags().StringVar(&config.SigningKeyFile, "signing-key-file", defaults.SigningKeyFile, "a file containing a shared key used to sign OTLP payloads with HMAC-SHA256")
+	// --queue-dir spools failed exports to disk for otel-cli flush
+	cmd.Flags().StringVar(&config.QueueDir, "queue-dir", defaults.QueueDir, "when sending spans fails, save them in this directory so otel-cli flush can send them later")
+	// HTTPS_PROXY and NO_PROXY work without it, --proxy overrides them and does SOCKS5
+	cmd.Flags().StringVar(&config.Proxy, "proxy", defaults.Proxy, "send exports through this proxy, e.g. socks5://localhost:1080 or http://proxy:3128, instead of the one in HTTPS_PROXY")
+	// --wire-debug-file dumps OTLP requests and responses for bug reports
+	cmd.Flags().StringVar(&config.WireDebugFile, "wire-debug-file", defaults.WireDebugFile, "append a hex dump of OTLP requests and responses, with headers and secrets masked, to this file")
+	cmd.Flags().StringVar(&config.Compression, "otlp-compression", defaults.Compression, "compress OTLP payloads with gzip or zstd, or none")
+	// retries back off linearly by --otlp-retry-sleep, up to 5s between attempts
+	cmd.Flags().IntVar(&config.OtlpRetries, "otlp-retries", defaults.OtlpRetries, "how many times to retry a failed export, 0 for none or -1 to retry until --timeout")
+	cmd.Flags().StringVar(&config.OtlpRetrySleep, "otlp-retry-sleep", defaults.OtlpRetrySleep, "how much longer to wait before each retry than the one before")
+	cmd.Flags().StringVar(&config.OtlpRetryTimeout, "otlp-retry-timeout", defaults.OtlpRetryTimeout, "give up retrying an export after this long, capped by --timeout")
+	// --dry-run prints what would be sent instead of sending it
+	cmd.Flags().BoolVar(&config.DryRun, "dry-run", defaults.DryRun, "print the OTLP payload as OTLP/JSON to stdout instead of sending it, implies recording even without an endpoint")
+	// --recording overrides deciding whether to record from the endpoint
+	cmd.Flags().StringVar(&config.Recording, "recording", defaults.Recording, "auto records when an endpoint is set, false never records even with one, require fails when there isn't one")
+	// sampling, for spans that are too frequent to send every time
+	cmd.Flags().StringVar(&config.Sampler, "sampler", defaults.Sampler, "send only some traces: ratio:0.1, or an OTEL_TRACES_SAMPLER name e.g. traceidratio, parentbased_always_on, always_off")
+	cmd.Flags().StringVar(&config.SamplerArg, "sampler-arg", defaults.SamplerArg, "the ratio of traces to send for the traceidratio samplers, like OTEL_TRACES_SAMPLER_ARG")
+	cmd.Flags().BoolVar(&config.RespectSampling, "respect-sampling", defaults.RespectSampling, "don't send spans when the parent traceparent's sampled flag is off, whatever the sampler is")
+	// resource attributes are sent with every span, log, and metric
+	cmd.Flags().StringToStringVar(&config.ResourceAttributes, "resource-attrs", defaults.ResourceAttributes, "a comma-separated list of key=value resource attributes, these override OTEL_RESOURCE_ATTRIBUTES")
+	cmd.Flags().StringVar(&config.ResourceDetectors, "resource-detectors", defaults.ResourceDetectors, "comma-separated resource detectors to add attributes from: host,os,process,container, or none to also ignore OTEL_RESOURCE_ATTRIBUTES")
+	// --endpoint-history offers endpoints that worked to shell completion
+	cmd.Flags().BoolVar(&config.EndpointHistory, "endpoint-history", defaults.EndpointHistory, "remember endpoints that exports succeeded to, for shell completion, see otel-cli history")
+	// --health-file coordinates backoff between concurrent otel-cli processes
+	cmd.Flags().StringVar(&config.HealthFile, "health-file", defaults.HealthFile, "a file shared by otel-cli processes to back off together when the endpoint is failing, exports are dropped (or queued) while backing off")
+	// --fallback pushes minimal metrics somewhere else when OTLP export fails
+	cmd.Flags().StringVar(&config.Fallback, "fallback", defaults.Fallback, "when OTLP export fails, push span count and duration metrics instead, e.g. pushgateway=http://localhost:9091")
 
 	// OTEL_CLI trace propagation options
 	cmd.Flags().BoolVar(&config.TraceparentRequired, "tp-required", defaults.TraceparentRequired, "when set to true, fail and log if a traceparent can't be picked up from TRACEPARENT ennvar or a carrier file")
 	cmd.Flags().StringVar(&config.TraceparentCarrierFile, "tp-carrier", defaults.TraceparentCarrierFile, "a file for reading and WRITING traceparent across invocations")
+	cmd.Flags().StringVar(&config.ChainFile, "chain-file", defaults.ChainFile, "a file each span is appended to, new spans are children of the last one to chain them in sequence")
 	cmd.Flags().BoolVar(&config.TraceparentIgnoreEnv, "tp-ignore-env", defaults.TraceparentIgnoreEnv, "ignore the TRACEPARENT envvar even if it's set")
+	cmd.Flags().BoolVar(&config.TraceparentStrict, "tp-strict", defaults.TraceparentStrict, "reject traceparents that don't follow the W3C spec exactly, e.g. upper case hex or all-zero ids")
+	cmd.Flags().StringVar(&config.PropagationFormat, "propagation-format", defaults.PropagationFormat, "the envvars and carrier format traceparents are read and written in, one of w3c|b3|b3multi|jaeger")
 	cmd.Flags().BoolVar(&config.TraceparentPrint, "tp-print", defaults.TraceparentPrint, "print the trace id, span id, and the w3c-formatted traceparent representation of the new span")
 	cmd.Flags().BoolVarP(&config.TraceparentPrintExport, "tp-export", "p", defaults.TraceparentPrintExport, "same as --tp-print but it puts an 'export ' in front so it's more convinenient to source in scripts")
+	cmd.Flags().IntVar(&config.TraceparentPrintFd, "tp-print-fd", defaults.TraceparentPrintFd, "print the traceparent to this file descriptor instead of stdout, e.g. 3, implies --tp-print")
+	cmd.Flags().StringVar(&config.TraceparentPrintFile, "tp-print-file", defaults.TraceparentPrintFile, "write the traceparent to this file instead of stdout, replacing its contents, implies --tp-print")
 }
 
 func addSpanParams(cmd *cobra.Command, config *Config) {
@@ -155,6 +338,13 @@ func addSpanParams(cmd *cobra.Command, config *Config) {
 	cmd.Flags().StringVar(&config.ForceTraceId, "force-trace-id", defaults.ForceTraceId, "expert: force the trace id to be the one provided in hex")
 	cmd.Flags().StringVar(&config.ForceSpanId, "force-span-id", defaults.ForceSpanId, "expert: force the span id to be the one provided in hex")
 	cmd.Flags().StringVar(&config.ForceParentSpanId, "force-parent-span-id", defaults.ForceParentSpanId, "expert: force the parent span id to be the one provided in hex")
+	cmd.Flags().StringVar(&config.ForceIdCache, "force-id-cache", defaults.ForceIdCache, "remember forced ids in this file and warn (with --verbose) when a span id is forced again")
+
+	// --tracestate adds to the vendor state propagated with the traceparent
+	cmd.Flags().StringVar(&config.Tracestate, "tracestate", defaults.Tracestate, "set W3C tracestate keys on the span and what it propagates, e.g. vendor=value, an empty value removes the key")
+
+	// --span-json-out keeps a copy of exactly what was sent
+	cmd.Flags().StringVar(&config.SpanJsonOut, "span-json-out", defaults.SpanJsonOut, "write the span as OTLP/JSON to this file before exporting it, for auditing or replaying with span send")
 
 	addSpanStatusParams(cmd, config)
 }
@@ -167,6 +357,10 @@ func addSpanStartEndParams(cmd *cobra.Command, config *Config) {
 
 	// --end $timestamp
 	cmd.Flags().StringVar(&config.SpanEndTime, "end", defaults.SpanEndTime, "an Unix epoch or RFC3339 timestamp for the end of the span")
+
+	// --start-from-file $path / --end-from-file $path use the file's mtime
+	cmd.Flags().StringVar(&config.SpanStartFromFile, "start-from-file", defaults.SpanStartFromFile, "use this file's modification time for the start of the span, overrides --start")
+	cmd.Flags().StringVar(&config.SpanEndFromFile, "end-from-file", defaults.SpanEndFromFile, "use this file's modification time for the end of the span, overrides --end")
 }
 
 func addSpanStatusParams(cmd *cobra.Command, config *Config) {
@@ -178,9 +372,69 @@ func addSpanStatusParams(cmd *cobra.Command, config *Config) {
 	cmd.Flags().StringVar(&config.StatusDescription, "status-description", defaults.StatusDescription, "set the span status description when a span status code of error is set, e.g. 'cancelled'")
 }
 
+func addSpanDurationParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+
+	// --warn-if-longer-than 1m
+	cmd.Flags().StringVar(&config.WarnIfLongerThan, "warn-if-longer-than", defaults.WarnIfLongerThan, "record on the span whether it took longer than this duration")
+	// --error-if-longer-than 5m
+	cmd.Flags().StringVar(&config.ErrorIfLongerThan, "error-if-longer-than", defaults.ErrorIfLongerThan, "set the span status to error if it took longer than this duration")
+}
+
 func addAttrParams(cmd *cobra.Command, config *Config) {
 	defaults := DefaultConfig()
 	// --attrs key=value,foo=bar
 	config.Attributes = make(map[string]string)
 	cmd.Flags().StringToStringVarP(&config.Attributes, "attrs", "a", defaults.Attributes, "a comma-separated list of key=value attributes")
+	// limits for huge attribute sets, named for the OTel SDK envvars
+	cmd.Flags().IntVar(&config.AttrCountLimit, "attr-count-limit", defaults.AttrCountLimit, "keep at most this many attributes, in key order, listing the keys of the rest in otel-cli.dropped_attributes, 0 for no limit")
+	cmd.Flags().IntVar(&config.AttrValueLengthLimit, "attr-value-length-limit", defaults.AttrValueLengthLimit, "truncate string and bytes attribute values longer than this, 0 for no limit")
+}
+
+func addAttrBytesParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --attr-bytes key=base64,foo=base64
+	config.AttributesBytes = make(map[string]string)
+	cmd.Flags().StringToStringVar(&config.AttributesBytes, "attr-bytes", defaults.AttributesBytes, "a comma-separated list of key=base64 attributes to send as bytes")
+}
+
+func addTypedAttrParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --attrs-file attrs.json, a JSON object with typed values
+	cmd.Flags().StringVar(&config.AttributesFile, "attrs-file", defaults.AttributesFile, "a JSON object of attributes, with string, number, bool, and array values sent as those types")
+	// --attr-str build=0123 etc. skip guessing the type from the value
+	config.AttributesStr = make(map[string]string)
+	cmd.Flags().StringToStringVar(&config.AttributesStr, "attr-str", defaults.AttributesStr, "a comma-separated list of key=value attributes to always send as strings, e.g. numeric-looking ids")
+	config.AttributesInt = make(map[string]string)
+	cmd.Flags().StringToStringVar(&config.AttributesInt, "attr-int", defaults.AttributesInt, "a comma-separated list of key=value attributes to send as integers")
+	config.AttributesFloat = make(map[string]string)
+	cmd.Flags().StringToStringVar(&config.AttributesFloat, "attr-float", defaults.AttributesFloat, "a comma-separated list of key=value attributes to send as floats")
+	config.AttributesBool = make(map[string]string)
+	cmd.Flags().StringToStringVar(&config.AttributesBool, "attr-bool", defaults.AttributesBool, "a comma-separated list of key=value attributes to send as bools")
+}
+
+func addPrintJsonParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --print-json writes the finished span for scripts to parse
+	cmd.Flags().BoolVar(&config.PrintJson, "print-json", defaults.PrintJson, "print the span's ids, times, attributes, and status as a JSON object to stdout")
+	cmd.Flags().IntVar(&config.PrintJsonFd, "print-json-fd", defaults.PrintJsonFd, "print the span JSON to this file descriptor instead of stdout, e.g. 3, implies --print-json")
+}
+
+func addPrintUrlParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --print-url links to the trace in a UI, with a template or a built-in
+	cmd.Flags().StringVar(&config.PrintUrl, "print-url", defaults.PrintUrl, "print a link to the trace to stderr, from a template like 'https://jaeger/trace/{{trace_id}}' or "+strings.Join(printUrlNames(), "|")+"[=BASE_URL]")
+}
+
+func addAlsoLogParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --also-log sends a log record for the span, for log-based alerting
+	cmd.Flags().BoolVar(&config.AlsoLog, "also-log", defaults.AlsoLog, "also send a log record with the span's ids, status message, and attributes")
+	cmd.Flags().StringVar(&config.AlsoLogAttrs, "also-log-attrs", defaults.AlsoLogAttrs, "comma-separated span attribute keys to put on the --also-log record, defaults to those from --attrs")
+}
+
+func addLinkParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	// --link $traceparent:key=value,foo=bar, repeatable
+	cmd.Flags().StringArrayVar(&config.Links, "link", defaults.Links, "link the span to another span by traceparent, with optional attributes e.g. $tp:key=value,foo=bar, can be repeated")
 }
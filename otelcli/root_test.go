@@ -0,0 +1,156 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "version",
+			args:       []string{"version"},
+			wantStdout: "unknown\n",
+		},
+		{
+			name:       "dry run writes the payload to stdout",
+			args:       []string{"span", "--dry-run", "--name", "in-process"},
+			wantStdout: `"name":"in-process"`,
+		},
+		{
+			name:       "soft failures return 0",
+			args:       []string{"span", "--verbose", "--kind", "bogus"},
+			wantStderr: `invalid --kind`,
+		},
+		{
+			name:     "--fail failures return 1",
+			args:     []string{"span", "--fail", "--kind", "bogus"},
+			wantCode: 1,
+		},
+		{
+			name:       "invalid --recording returns 1 without --fail",
+			args:       []string{"span", "--recording", "bogus"},
+			wantCode:   1,
+			wantStderr: `invalid --recording "bogus"`,
+		},
+		{
+			name:       "cobra errors return 1",
+			args:       []string{"no-such-command"},
+			wantCode:   1,
+			wantStderr: `Error: unknown command "no-such-command"`,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Run(tc.args, &stdout, &stderr)
+			if code != tc.wantCode {
+				t.Errorf("expected exit code %d, got %d, stderr: %s", tc.wantCode, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tc.wantStdout) {
+				t.Errorf("expected stdout to contain %q, got %q", tc.wantStdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), tc.wantStderr) {
+				t.Errorf("expected stderr to contain %q, got %q", tc.wantStderr, stderr.String())
+			}
+		})
+	}
+}
+
+func TestRunStatus(t *testing.T) {
+	// the same process can run otel-cli more than once, each with its own
+	// config and diagnostics
+	for _, args := range [][]string{{"status", "--timeout", "1s"}, {"status"}} {
+		var stdout, stderr bytes.Buffer
+		if code := Run(args, &stdout, &stderr); code != 0 {
+			t.Fatalf("expected exit code 0, got %d, stderr: %s", code, stderr.String())
+		}
+
+		var out StatusOutput
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("could not parse status output: %s", err)
+		}
+		if out.Diagnostics.NumArgs != len(args) {
+			t.Errorf("expected %d args in diagnostics, got %d", len(args), out.Diagnostics.NumArgs)
+		}
+	}
+}
+
+func TestRunCommandFlags(t *testing.T) {
+	// subcommand flags don't carry over from one run to the next
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"shellhook", "bash", "--session-name", "first"}, want: "__otel_cli_session_name='first'"},
+		{args: []string{"shellhook", "bash"}, want: "__otel_cli_session_name='shell session'"},
+	} {
+		var stdout, stderr bytes.Buffer
+		if code := Run(tc.args, &stdout, &stderr); code != 0 {
+			t.Fatalf("expected exit code 0, got %d, stderr: %s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tc.want) {
+			t.Errorf("expected %q in the output of %v", tc.want, tc.args)
+		}
+	}
+}
+
+func TestRunFakeNow(t *testing.T) {
+	// --fake-now is per run, so concurrent runs don't see each other's clock
+	var wg sync.WaitGroup
+	for _, epoch := range []int64{1617739561, 1700000000, 0} {
+		wg.Add(1)
+		go func(epoch int64) {
+			defer wg.Done()
+			args := []string{"span", "--dry-run", "--name", "clock"}
+			if epoch != 0 {
+				args = append(args, "--fake-now", fmt.Sprint(epoch))
+			}
+			var stdout, stderr bytes.Buffer
+			if code := Run(args, &stdout, &stderr); code != 0 {
+				t.Errorf("expected exit code 0, got %d, stderr: %s", code, stderr.String())
+				return
+			}
+			want := fmt.Sprintf(`"startTimeUnixNano":"%d000000000"`, epoch)
+			if pinned := strings.Contains(stdout.String(), want); pinned != (epoch != 0) {
+				t.Errorf("expected %s pinned to be %t, got %s", args, epoch != 0, stdout.String())
+			}
+		}(epoch)
+	}
+	wg.Wait()
+}
+
+func TestRunTraceparentStdin(t *testing.T) {
+	// what --tp-stdin read isn't kept for the next run
+	defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+
+	for _, traceId := range []string{"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "cccccccccccccccccccccccccccccccc"} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(w, "00-%s-bbbbbbbbbbbbbbbb-01\n", traceId)
+		w.Close()
+		os.Stdin = r
+
+		var stdout, stderr bytes.Buffer
+		code := Run([]string{"span", "--dry-run", "--tp-stdin"}, &stdout, &stderr)
+		r.Close()
+		if code != 0 {
+			t.Fatalf("expected exit code 0, got %d, stderr: %s", code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), `"traceId":"`+traceId+`"`) {
+			t.Errorf("expected trace id %s from stdin, got %s", traceId, stdout.String())
+		}
+	}
+}
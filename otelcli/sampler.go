@@ -0,0 +1,114 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strconv"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// samplers are the OTEL_TRACES_SAMPLER values otel-cli supports. The
+// parentbased ones follow the parent's sampled flag when there's a parent and
+// fall back to the rest of their name when there isn't.
+var samplers = []string{
+	"always_on",
+	"always_off",
+	"traceidratio",
+	"parentbased_always_on",
+	"parentbased_always_off",
+	"parentbased_traceidratio",
+}
+
+// GetSampler parses --sampler and --sampler-arg into the sampler name and the
+// ratio traceidratio samplers keep. --sampler ratio:0.1 is short for
+// traceidratio with a --sampler-arg of 0.1. Defaults to always_on, and the
+// ratio defaults to 1 like the spec says.
+func (c Config) GetSampler() (string, float64) {
+	name, arg := strings.ToLower(strings.TrimSpace(c.Sampler)), c.SamplerArg
+	if r, ok := strings.CutPrefix(name, "ratio:"); ok {
+		name, arg = "traceidratio", r
+	} else if name == "" {
+		name = "always_on"
+	}
+
+	found := false
+	for _, s := range samplers {
+		found = found || s == name
+	}
+	if !found {
+		c.SoftFail("invalid --sampler %q, expected ratio:<0..1> or one of %s", c.Sampler, strings.Join(samplers, ", "))
+	}
+
+	ratio := 1.0
+	if strings.HasSuffix(name, "traceidratio") && arg != "" {
+		var err error
+		ratio, err = strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			c.SoftFail("invalid --sampler-arg %q, expected a ratio from 0 to 1", arg)
+		}
+	}
+
+	return name, ratio
+}
+
+// GetIsSampled returns whether the sampler keeps the trace. Unsampled spans
+// aren't sent but their traceparent is still propagated, with the sampled
+// flag cleared so children can make the same decision. The decision only
+// depends on the trace id and the parent, so every span otel-cli sends for a
+// trace gets the same one. With --respect-sampling, a parent with its sampled
+// flag off makes the trace unsampled like it would with a parentbased sampler,
+// while a sampled parent leaves the decision to the sampler.
+func (c Config) GetIsSampled(traceId []byte) bool {
+	name, ratio := c.GetSampler()
+
+	var sampled bool
+	base, parentBased := strings.CutPrefix(name, "parentbased_")
+	followParent := false
+	if parentBased || c.RespectSampling {
+		tp := c.LoadTraceparent()
+		hasParent := tp.Initialized && !bytes.Equal(tp.TraceId, otlpclient.GetEmptyTraceId())
+		// --respect-sampling only follows a parent that's unsampled
+		followParent = hasParent && (parentBased || !tp.Sampling)
+		sampled = tp.Sampling
+	}
+	if !followParent {
+		switch base {
+		case "always_on":
+			sampled = true
+		case "always_off":
+			sampled = false
+		case "traceidratio":
+			sampled = traceIdRatioSampled(traceId, ratio)
+		}
+	}
+
+	c.diag.update(func(d *Diagnostics) { d.Unsampled = !sampled })
+	return sampled
+}
+
+// sampledSpans returns the spans the sampler keeps, which is all of them or
+// none since they're all in the same trace.
+func (c Config) sampledSpans(spans ...*tracepb.Span) []*tracepb.Span {
+	if len(spans) == 0 || !c.GetIsSampled(spans[0].TraceId) {
+		return []*tracepb.Span{}
+	}
+	return spans
+}
+
+// traceIdRatioSampled makes the same decision as the OTel SDKs' TraceIDRatioBased
+// sampler, so services and otel-cli sampling the same trace at the same
+// ratio agree: the trace is kept when the low 63 bits of the trace id's last 8
+// bytes are below ratio * 2^63.
+func traceIdRatioSampled(traceId []byte, ratio float64) bool {
+	if ratio >= 1 {
+		return true
+	} else if ratio <= 0 || len(traceId) != 16 {
+		return false
+	}
+
+	bound := uint64(ratio * (1 << 63))
+	return binary.BigEndian.Uint64(traceId[8:16])>>1 < bound
+}
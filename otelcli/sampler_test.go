@@ -0,0 +1,65 @@
+package otelcli
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestGetSampler(t *testing.T) {
+	for _, tc := range []struct {
+		sampler   string
+		arg       string
+		wantName  string
+		wantRatio float64
+	}{
+		{sampler: "", wantName: "always_on", wantRatio: 1},
+		{sampler: "ratio:0.1", wantName: "traceidratio", wantRatio: 0.1},
+		{sampler: "traceidratio", arg: "0.25", wantName: "traceidratio", wantRatio: 0.25},
+		{sampler: "parentbased_traceidratio", wantName: "parentbased_traceidratio", wantRatio: 1},
+		{sampler: "ALWAYS_OFF", arg: "0.5", wantName: "always_off", wantRatio: 1},
+	} {
+		name, ratio := DefaultConfig().WithSampler(tc.sampler).WithSamplerArg(tc.arg).GetSampler()
+		if name != tc.wantName || ratio != tc.wantRatio {
+			t.Errorf("%q %q: expected %s %g, got %s %g", tc.sampler, tc.arg, tc.wantName, tc.wantRatio, name, ratio)
+		}
+	}
+}
+
+func TestGetIsSampled(t *testing.T) {
+	low, _ := hex.DecodeString("00000000000000000000000000000001")
+	high, _ := hex.DecodeString("0000000000000000ffffffffffffffff")
+
+	for _, tc := range []struct {
+		sampler     string
+		respect     bool
+		traceparent string
+		traceId     []byte
+		want        bool
+	}{
+		{sampler: "always_on", traceId: high, want: true},
+		{sampler: "always_off", traceId: low, want: false},
+		{sampler: "ratio:0.5", traceId: low, want: true},
+		{sampler: "ratio:0.5", traceId: high, want: false},
+		{sampler: "ratio:0", traceId: low, want: false},
+		{sampler: "ratio:1", traceId: high, want: true},
+		// parentbased samplers only use the rest of their name without a parent
+		{sampler: "parentbased_always_off", traceId: low, want: false},
+		{sampler: "parentbased_always_on", traceId: low, want: true},
+		{sampler: "parentbased_always_off", traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01", traceId: low, want: true},
+		{sampler: "parentbased_always_on", traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-00", traceId: low, want: false},
+		// the other samplers ignore the parent
+		{sampler: "always_on", traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-00", traceId: low, want: true},
+		// --respect-sampling lets an unsampled parent win over any sampler
+		{sampler: "always_on", respect: true, traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-00", traceId: low, want: false},
+		{sampler: "always_on", respect: true, traceId: low, want: true},
+		// but a sampled parent leaves the decision to the sampler
+		{sampler: "ratio:0.5", respect: true, traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01", traceId: high, want: false},
+		{sampler: "ratio:0.5", respect: true, traceparent: "00-f6c109f48195b451c4def6ab32f47b61-a5d2a35f2483004e-01", traceId: low, want: true},
+	} {
+		t.Setenv("TRACEPARENT", tc.traceparent)
+		got := DefaultConfig().WithSampler(tc.sampler).WithRespectSampling(tc.respect).GetIsSampled(tc.traceId)
+		if got != tc.want {
+			t.Errorf("%s (respect %t) with parent %q: expected %t, got %t", tc.sampler, tc.respect, tc.traceparent, tc.want, got)
+		}
+	}
+}
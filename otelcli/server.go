@@ -1,7 +1,15 @@
 package otelcli
 
 import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpserver"
 	"github.com/spf13/cobra"
@@ -10,6 +18,10 @@ import (
 const defaultOtlpEndpoint = "grpc://localhost:4317"
 const spanBgSockfilename = "otel-cli-background.sock"
 
+// serverDrainTimeout is how long servers wait for in-flight exports when
+// they're interrupted.
+const serverDrainTimeout = 5 * time.Second
+
 func serverCmd(config *Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "server",
@@ -19,30 +31,181 @@ func serverCmd(config *Config) *cobra.Command {
 
 	cmd.AddCommand(serverJsonCmd(config))
 	cmd.AddCommand(serverTuiCmd(config))
+	cmd.AddCommand(serverAssertCmd(config))
+	cmd.AddCommand(serverProxyCmd(config))
 
 	return &cmd
 }
 
-// runServer runs the server on either grpc or http and blocks until the server
-// stops or is killed.
-func runServer(config Config, cb otlpserver.Callback, stop otlpserver.Stopper) {
+// addServerParams adds the flags shared by all of the server subcommands.
+func addServerParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&config.Endpoint, "listen", defaults.Endpoint, "address to listen on, same as --endpoint, e.g. localhost:4317 or unix:///run/otel.sock")
+	cmd.Flags().StringVar(&config.ServerDedupeWindow, "dedupe-window", defaults.ServerDedupeWindow, "drop spans whose trace and span id were already seen within this duration, e.g. 5m")
+	cmd.Flags().IntVar(&config.ServerBufferSpans, "buffer-spans", defaults.ServerBufferSpans, "most spans to keep in memory, the oldest are evicted past this, 0 for no limit")
+	cmd.Flags().StringVar(&config.ServerBufferSize, "buffer-size", defaults.ServerBufferSize, "most bytes of spans to keep in memory, the oldest are evicted past this, 0 for no limit")
+	cmd.Flags().StringVar(&config.ServerMetricsAddr, "metrics-addr", defaults.ServerMetricsAddr, "serve Prometheus metrics about received spans on this address at /metrics, e.g. :9090")
+	cmd.Flags().StringArrayVar(&config.ServerFilterServices, "filter-service", defaults.ServerFilterServices, "only capture spans from this service.name, can be repeated to capture several")
+	cmd.Flags().StringVar(&config.ServerFilterSpanName, "filter-span-name", defaults.ServerFilterSpanName, "only capture spans whose name matches this regular expression")
+	cmd.Flags().StringToStringVar(&config.ServerFilterAttrs, "filter-attr", defaults.ServerFilterAttrs, "only capture spans with this key=value span or resource attribute, all must match when repeated")
+	cmd.Flags().StringVar(&config.ServerCorsOrigins, "cors-origins", defaults.ServerCorsOrigins, "comma-separated origins allowed to export from a browser over OTLP/HTTP, e.g. http://localhost:3000, or * for any")
+	cmd.Flags().StringVar(&config.ServerTlsCert, "tls-cert", defaults.ServerTlsCert, "a file containing the server certificate, serves TLS along with --tls-key")
+	cmd.Flags().StringVar(&config.ServerTlsKey, "tls-key", defaults.ServerTlsKey, "a file containing the server certificate key")
+	cmd.Flags().StringVar(&config.ServerTlsCA, "tls-ca", defaults.ServerTlsCA, "a file containing the CA bundle client certificates must be signed by, requires them when set")
+}
+
+// newServerBuffer returns a BufferSink limited by --buffer-spans and
+// --buffer-size, for servers that keep spans in memory.
+func newServerBuffer(config Config) *otlpserver.BufferSink {
+	return otlpserver.NewBufferSink(config.ServerBufferSpans, config.ParseServerBufferSize())
+}
+
+// logEvictions tells the user when a server's in-memory buffer had to drop
+// spans, so a capture that looks incomplete has an explanation.
+func logEvictions(buffer *otlpserver.BufferSink) {
+	if evicted := buffer.Evicted(); evicted > 0 {
+		log.Printf("evicted %d span(s) from memory to stay under --buffer-spans and --buffer-size", evicted)
+	}
+}
+
+// runServer runs the server on either grpc or http, feeding all received spans
+// to the sink, and blocks until the server stops or is killed.
+func runServer(config Config, sink otlpserver.SpanSink, stop otlpserver.Stopper) {
+	runSignalServer(config, sink, nil, stop)
+}
+
+// runSignalServer is runServer for servers that also take logs and metrics,
+// which are passed to signals when it's not nil. Only the OTLP/gRPC and
+// OTLP/HTTP servers can receive them.
+func runSignalServer(config Config, sink otlpserver.SpanSink, signals otlpserver.SignalCallback, stop otlpserver.Stopper) {
 	// unlike the rest of otel-cli, server should default to localhost:4317
 	if config.Endpoint == "" {
 		config.Endpoint = defaultOtlpEndpoint
 	}
+
+	// retried exports can deliver the same span twice, optionally drop repeats
+	if window := config.ParseServerDedupeWindow(); window > 0 {
+		dedupe := otlpserver.NewDedupeSink(sink, window)
+		defer func() {
+			if dups := dedupe.Duplicates(); dups > 0 {
+				log.Printf("dropped %d duplicate span(s)", dups)
+			}
+		}()
+		sink = dedupe
+	}
+
+	// on a busy shared endpoint, only pass on the spans of interest
+	filter := otlpserver.SpanFilter{
+		Services: config.ServerFilterServices,
+		Name:     config.ParseServerFilterSpanName(),
+		Attrs:    config.ServerFilterAttrs,
+	}
+	if !filter.IsEmpty() {
+		sink = otlpserver.NewFilterSink(sink, filter)
+	}
+
+	// --metrics-addr counts everything received, duplicates included
+	if config.ServerMetricsAddr != "" {
+		metrics := otlpserver.NewMetricsSink()
+		defer serveMetrics(config, metrics)()
+		sink = otlpserver.NewMultiSink(metrics, sink)
+	}
+
+	cb := sink.Consume
+	defer sink.Close()
+
+	// experimental: an endpoint of - reads newline-delimited OTLP/JSON from stdin
+	if config.Endpoint == "-" {
+		cs := otlpserver.NewServer("stdin", cb, stop)
+		serveUntilSignal(cs, "")
+		return
+	}
+
 	endpointURL, _ := config.ParseEndpoint()
+	tlsConfig, err := config.GetServerTlsConfig()
+	if err != nil {
+		config.SoftFail("%s", err)
+	}
 
 	var cs otlpserver.OtlpServer
-	if config.Protocol != "grpc" &&
+	if endpointURL.Scheme == "udp" {
+		// experimental: newline-delimited OTLP/JSON in UDP datagrams
+		cs = otlpserver.NewServer("udp", cb, stop)
+	} else if config.Protocol != "grpc" &&
 		(strings.HasPrefix(config.Protocol, "http/") ||
-			endpointURL.Scheme == "http") {
+			endpointURL.Scheme == "http" || endpointURL.Scheme == "https") {
 		cs = otlpserver.NewServer("http", cb, stop)
-	} else if config.Protocol == "https" || endpointURL.Scheme == "https" {
-		config.SoftFail("https server is not supported yet, please raise an issue")
 	} else {
 		cs = otlpserver.NewServer("grpc", cb, stop)
 	}
 
-	defer cs.Stop()
-	cs.ListenAndServe(endpointURL.Host)
+	if ts, ok := cs.(otlpserver.TlsServer); ok && tlsConfig != nil {
+		ts.SetTlsConfig(tlsConfig)
+	} else if tlsConfig != nil {
+		config.SoftFail("--tls-cert only works with the OTLP/gRPC and OTLP/HTTP servers")
+	} else if endpointURL.Scheme == "https" {
+		config.SoftFail("an https:// address to listen on needs --tls-cert and --tls-key")
+	}
+
+	// browser SDKs need CORS to export to a different origin than the page
+	if hs, ok := cs.(*otlpserver.HttpServer); ok {
+		hs.SetCorsOrigins(config.ParseServerCorsOrigins())
+	}
+	if ss, ok := cs.(otlpserver.SignalServer); ok && signals != nil {
+		ss.SetSignalCallback(signals)
+	}
+
+	addr := endpointURL.Host
+	if endpointURL.Scheme == "unix" {
+		addr = "unix://" + endpointURL.Path
+	}
+
+	serveUntilSignal(cs, addr)
+}
+
+// serveMetrics serves the metrics sink on --metrics-addr at /metrics in the
+// background and returns a func that stops it.
+func serveMetrics(config Config, metrics *otlpserver.MetricsSink) func() {
+	listener, err := net.Listen("tcp", config.ServerMetricsAddr)
+	if err != nil {
+		config.SoftFail("unable to listen on --metrics-addr %q: %s", config.ServerMetricsAddr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+
+	return func() { srv.Close() }
+}
+
+// serveUntilSignal runs the server until it stops on its own, or until
+// SIGINT or SIGTERM shuts it down gracefully. The drain finishes before this
+// returns so exports that were already received make it to the sinks before
+// they're closed. A second signal exits immediately.
+func serveUntilSignal(cs otlpserver.OtlpServer, addr string) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	served := make(chan struct{})
+	go func() {
+		cs.ListenAndServe(addr)
+		close(served)
+	}()
+
+	select {
+	case <-served:
+	case <-signals:
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+
+		ctx, cancel := context.WithTimeout(context.Background(), serverDrainTimeout)
+		defer cancel()
+		stats, err := cs.Shutdown(ctx)
+		if err != nil || stats.Rejected > 0 {
+			log.Printf("server shut down: %s", stats)
+		}
+	}
+
+	cs.Stop()
 }
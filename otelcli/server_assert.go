@@ -0,0 +1,286 @@
+package otelcli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpserver"
+	"github.com/spf13/cobra"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// assertServer holds the command-line configured settings and the state for
+// one run of otel-cli server assert
+type assertServer struct {
+	asserts   []string
+	maxSpans  int
+	buffer    *otlpserver.BufferSink
+	mu        sync.Mutex
+	spansSeen int
+}
+
+func serverAssertCmd(config *Config) *cobra.Command {
+	svr := &assertServer{}
+	cmd := cobra.Command{
+		Use:   "assert",
+		Short: "check timing assertions against the spans received",
+		Long: `Run an OTLP server that collects spans until --max-spans is reached or it is
+interrupted, then checks each --assert against them. One line is printed to
+stdout per assertion, starting with "pass" or "fail", and otel-cli exits
+non-zero when any of them fail.
+
+Assertions refer to spans by name:
+	"<span> ends-before <span>"    every <span> ends before any of the other starts
+	"<span> starts-before <span>"  every <span> starts before any of the other starts
+	"<span> duration < 2s"         every span with the name is shorter than 2s
+	"trace duration < 5s"          every trace, first start to last end, is shorter than 5s
+
+Durations can be compared with <, <=, >, or >=.
+
+Example:
+	otel-cli server assert --max-spans 3 \
+		--assert "build ends-before deploy" \
+		--assert "deploy duration < 2s" \
+		--assert "trace duration < 5s" &
+	./pipeline.sh # sends build, deploy, and a parent span to localhost:4317
+	wait $! || echo "pipeline too slow"
+`,
+		Run: svr.run,
+	}
+
+	addCommonParams(&cmd, config)
+	addServerParams(&cmd, config)
+	cmd.Flags().StringArrayVar(&svr.asserts, "assert", []string{}, "an assertion to check against the spans, may be repeated")
+	cmd.Flags().IntVar(&svr.maxSpans, "max-spans", 0, "exit the server after this many spans come in")
+	cmd.MarkFlagRequired("assert")
+
+	return &cmd
+}
+
+func (svr *assertServer) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+
+	asserts := make([]spanAssertion, len(svr.asserts))
+	for i, in := range svr.asserts {
+		a, err := parseSpanAssertion(in)
+		if err != nil {
+			config.SoftFail("invalid --assert: %s", err)
+		}
+		asserts[i] = a
+	}
+
+	svr.buffer = newServerBuffer(config)
+	stop := func(otlpserver.OtlpServer) {}
+	runServer(config, otlpserver.NewMultiSink(svr.buffer, otlpserver.CallbackSink(svr.countSpans)), stop)
+
+	// assertions can only see the spans that are still in memory
+	logEvictions(svr.buffer)
+	spans := svr.buffer.Spans()
+
+	var failed int
+	for _, a := range asserts {
+		if err := a.check(spans); err != nil {
+			failed++
+			fmt.Fprintf(config.getStdout(), "fail %q: %s\n", a.text, err)
+		} else {
+			fmt.Fprintf(config.getStdout(), "pass %q\n", a.text)
+		}
+	}
+
+	// unlike most of otel-cli this always exits non-zero, failing is the point
+	if failed > 0 {
+		config.WithFail(true).WithVerbose(true).SoftFail("%d of %d assertions failed", failed, len(asserts))
+	}
+}
+
+// countSpans counts spans as they come in and tells the server to exit once
+// --max-spans is reached. The spans themselves are kept in svr.buffer.
+func (svr *assertServer) countSpans(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	svr.mu.Lock()
+	defer svr.mu.Unlock()
+	svr.spansSeen++
+
+	return svr.maxSpans > 0 && svr.spansSeen >= svr.maxSpans
+}
+
+// spanAssertion is a parsed --assert. Ordering assertions set subject and
+// object, duration assertions set subject, op, and limit. A subject of
+// "trace" on a duration assertion means whole traces instead of a span name.
+type spanAssertion struct {
+	text    string
+	subject string
+	kind    string // ends-before, starts-before, or duration
+	object  string
+	op      string
+	limit   time.Duration
+}
+
+// parseSpanAssertion parses the assertion mini-language described in
+// otel-cli server assert --help. Span names may contain spaces.
+func parseSpanAssertion(in string) (spanAssertion, error) {
+	a := spanAssertion{text: in}
+
+	for _, kind := range []string{"ends-before", "starts-before"} {
+		if subject, object, ok := strings.Cut(in, " "+kind+" "); ok {
+			a.kind = kind
+			a.subject = strings.TrimSpace(subject)
+			a.object = strings.TrimSpace(object)
+			if a.subject == "" || a.object == "" {
+				return a, fmt.Errorf("%q needs a span name on both sides of %s", in, kind)
+			}
+			return a, nil
+		}
+	}
+
+	fields := strings.Fields(in)
+	if len(fields) < 4 || fields[len(fields)-3] != "duration" {
+		return a, fmt.Errorf("%q is not a recognized assertion, see otel-cli server assert --help", in)
+	}
+
+	a.kind = "duration"
+	a.subject = strings.Join(fields[:len(fields)-3], " ")
+	a.op = fields[len(fields)-2]
+	switch a.op {
+	case "<", "<=", ">", ">=":
+	default:
+		return a, fmt.Errorf("%q has unknown comparison %q, expected one of <, <=, >, >=", in, a.op)
+	}
+
+	limit, err := time.ParseDuration(fields[len(fields)-1])
+	if err != nil {
+		return a, fmt.Errorf("%q has an invalid duration: %w", in, err)
+	}
+	a.limit = limit
+
+	return a, nil
+}
+
+// check returns an error describing the first way the spans break the
+// assertion, or nil when they satisfy it.
+func (a spanAssertion) check(spans []*tracepb.Span) error {
+	if a.kind == "duration" && a.subject == "trace" {
+		return a.checkTraces(spans)
+	}
+
+	subjects := spansNamed(spans, a.subject)
+	if len(subjects) == 0 {
+		return fmt.Errorf("no span named %q was received", a.subject)
+	}
+
+	if a.kind == "duration" {
+		for _, span := range subjects {
+			d := spanDuration(span)
+			if !compareDuration(d, a.op, a.limit) {
+				return fmt.Errorf("span %q took %s", a.subject, d)
+			}
+		}
+		return nil
+	}
+
+	objects := spansNamed(spans, a.object)
+	if len(objects) == 0 {
+		return fmt.Errorf("no span named %q was received", a.object)
+	}
+
+	// the latest subject has to come before the earliest object start
+	var latest, earliest uint64
+	for _, span := range subjects {
+		t := span.StartTimeUnixNano
+		if a.kind == "ends-before" {
+			t = span.EndTimeUnixNano
+		}
+		if t > latest {
+			latest = t
+		}
+	}
+	for _, span := range objects {
+		if earliest == 0 || span.StartTimeUnixNano < earliest {
+			earliest = span.StartTimeUnixNano
+		}
+	}
+
+	if latest > earliest {
+		verb := "started"
+		if a.kind == "ends-before" {
+			verb = "ended"
+		}
+		return fmt.Errorf("%q %s %s after %q started", a.subject, verb, time.Duration(latest-earliest), a.object)
+	}
+
+	return nil
+}
+
+// checkTraces compares the duration of each trace, from its first span start
+// to its last span end, against the limit.
+func (a spanAssertion) checkTraces(spans []*tracepb.Span) error {
+	if len(spans) == 0 {
+		return fmt.Errorf("no spans were received")
+	}
+
+	type bounds struct{ start, end uint64 }
+	traces := map[string]*bounds{}
+	order := []string{}
+	for _, span := range spans {
+		tid := fmt.Sprintf("%x", span.TraceId)
+		b, ok := traces[tid]
+		if !ok {
+			b = &bounds{start: span.StartTimeUnixNano, end: span.EndTimeUnixNano}
+			traces[tid] = b
+			order = append(order, tid)
+		}
+		if span.StartTimeUnixNano < b.start {
+			b.start = span.StartTimeUnixNano
+		}
+		if span.EndTimeUnixNano > b.end {
+			b.end = span.EndTimeUnixNano
+		}
+	}
+
+	for _, tid := range order {
+		b := traces[tid]
+		d := time.Duration(b.end - b.start)
+		if !compareDuration(d, a.op, a.limit) {
+			return fmt.Errorf("trace %s took %s", tid, d)
+		}
+	}
+
+	return nil
+}
+
+// spansNamed returns the spans with the given name.
+func spansNamed(spans []*tracepb.Span, name string) []*tracepb.Span {
+	out := []*tracepb.Span{}
+	for _, span := range spans {
+		if span.Name == name {
+			out = append(out, span)
+		}
+	}
+	return out
+}
+
+// spanDuration returns how long the span ran.
+func spanDuration(span *tracepb.Span) time.Duration {
+	if span.EndTimeUnixNano < span.StartTimeUnixNano {
+		return 0
+	}
+	return time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+}
+
+// compareDuration applies one of the comparison operators accepted by
+// parseSpanAssertion.
+func compareDuration(d time.Duration, op string, limit time.Duration) bool {
+	switch op {
+	case "<":
+		return d < limit
+	case "<=":
+		return d <= limit
+	case ">":
+		return d > limit
+	case ">=":
+		return d >= limit
+	}
+	return false
+}
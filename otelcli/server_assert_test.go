@@ -0,0 +1,56 @@
+package otelcli
+
+import (
+	"testing"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestSpanAssertions(t *testing.T) {
+	base := uint64(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC).UnixNano())
+	at := func(d time.Duration) uint64 { return base + uint64(d) }
+	tid := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	spans := []*tracepb.Span{
+		{TraceId: tid, Name: "build", StartTimeUnixNano: at(0), EndTimeUnixNano: at(3 * time.Second)},
+		{TraceId: tid, Name: "unit tests", StartTimeUnixNano: at(time.Second), EndTimeUnixNano: at(2 * time.Second)},
+		{TraceId: tid, Name: "deploy", StartTimeUnixNano: at(3 * time.Second), EndTimeUnixNano: at(4 * time.Second)},
+	}
+
+	for _, tc := range []struct {
+		assert string
+		pass   bool
+	}{
+		{"build ends-before deploy", true},
+		{"deploy ends-before build", false},
+		{"build ends-before unit tests", false},
+		{"build starts-before unit tests", true},
+		{"unit tests duration < 2s", true},
+		{"unit tests duration >= 2s", false},
+		{"build duration <= 3s", true},
+		{"trace duration < 5s", true},
+		{"trace duration < 4s", false},
+		{"package ends-before deploy", false},
+	} {
+		t.Run(tc.assert, func(t *testing.T) {
+			a, err := parseSpanAssertion(tc.assert)
+			if err != nil {
+				t.Fatalf("failed to parse: %s", err)
+			}
+
+			err = a.check(spans)
+			if tc.pass && err != nil {
+				t.Errorf("expected the assertion to pass but it failed: %s", err)
+			} else if !tc.pass && err == nil {
+				t.Error("expected the assertion to fail but it passed")
+			}
+		})
+	}
+
+	for _, in := range []string{"", "build", "build duration ~ 2s", "build duration < soon", " ends-before deploy"} {
+		if _, err := parseSpanAssertion(in); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+}
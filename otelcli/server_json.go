@@ -2,12 +2,11 @@ package otelcli
 
 import (
 	"context"
-	"encoding/hex"
-	"encoding/json"
-	"log"
-	"os"
-	"path/filepath"
+	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpserver"
@@ -15,34 +14,50 @@ import (
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
-// jsonSvr holds the command-line configured settings for otel-cli server json
-var jsonSvr struct {
-	outDir    string
-	stdout    bool
-	maxSpans  int
-	spansSeen int
+// jsonServer holds the command-line configured settings and the state for
+// one run of otel-cli server json
+type jsonServer struct {
+	outDir     string
+	logsDir    string
+	metricsDir string
+	stdout     bool
+	ndjson     string
+	maxSize    string
+	maxFiles   int
+	maxSpans   int
+	format     string
+	mu         sync.Mutex
+	spansSeen  int
 }
 
 func serverJsonCmd(config *Config) *cobra.Command {
+	svr := &jsonServer{}
 	cmd := cobra.Command{
 		Use:   "json",
-		Short: "write spans to json or stdout",
+		Short: "write spans, and logs and metrics, to json or stdout",
 		Long:  "",
-		Run:   doServerJson,
+		Run:   svr.run,
 	}
 
 	addCommonParams(&cmd, config)
-	cmd.Flags().StringVar(&jsonSvr.outDir, "dir", "", "write spans to json in the specified directory")
-	cmd.Flags().BoolVar(&jsonSvr.stdout, "stdout", false, "write span jsons to stdout")
-	cmd.Flags().IntVar(&jsonSvr.maxSpans, "max-spans", 0, "exit the server after this many spans come in")
+	addServerParams(&cmd, config)
+	cmd.Flags().StringVar(&svr.outDir, "dir", "", "write spans to json in the specified directory")
+	cmd.Flags().BoolVar(&svr.stdout, "stdout", false, "write span jsons to stdout, and logs and metrics as {\"logs\":...} and {\"metrics\":...} lines")
+	cmd.Flags().StringVar(&svr.ndjson, "ndjson-file", "", "append spans to this file, one json object per line")
+	cmd.Flags().StringVar(&svr.maxSize, "max-size", "", "rotate the --ndjson-file when it reaches this size, e.g. 50MB")
+	cmd.Flags().IntVar(&svr.maxFiles, "max-files", 5, "how many rotated --ndjson-file files to keep")
+	cmd.Flags().IntVar(&svr.maxSpans, "max-spans", 0, "exit the server after this many spans come in")
+	cmd.Flags().StringVar(&svr.logsDir, "logs-dir", "", "append received logs as OTLP/JSON to logs.jsonl in this directory")
+	cmd.Flags().StringVar(&svr.metricsDir, "metrics-dir", "", "append received metrics as OTLP/JSON to metrics.jsonl in this directory")
+	cmd.Flags().StringVar(&svr.format, "format", "", "write --dir and --stdout as whole traces in jaeger (UI upload) or zipkin (v2) json, or as otlp-json export requests that span send can replay")
 
 	return &cmd
 }
 
-func doServerJson(cmd *cobra.Command, args []string) {
+func (svr *jsonServer) run(cmd *cobra.Command, args []string) {
 	config := getConfig(cmd.Context())
 	stop := func(otlpserver.OtlpServer) {}
-	cs := otlpserver.NewGrpcServer(renderJson, stop)
+	cs := otlpserver.NewGrpcServer(svr.countSpans, stop)
 
 	// stops the grpc server after timeout
 	timeout := config.ParseCliTimeout()
@@ -53,71 +68,110 @@ func doServerJson(cmd *cobra.Command, args []string) {
 		}()
 	}
 
-	runServer(config, renderJson, stop)
-}
-
-// writeFile takes the spans and events and writes them out to json files in the
-// tid/sid/span.json and tid/sid/events.json files.
-func renderJson(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, ss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
-	jsonSvr.spansSeen++ // count spans for exiting on --max-spans
-
-	// TODO: check for existence of outdir and error when it doesn't exist
-	var outpath string
-	if jsonSvr.outDir != "" {
-		// create trace directory
-		outpath = filepath.Join(jsonSvr.outDir, hex.EncodeToString(span.TraceId))
-		os.Mkdir(outpath, 0755) // ignore errors for now
-
-		// create span directory
-		outpath = filepath.Join(outpath, hex.EncodeToString(span.SpanId))
-		os.Mkdir(outpath, 0755) // ignore errors for now
+	// spans, logs, and metrics all go to stdout, a line at a time
+	var out io.Writer
+	if svr.stdout {
+		out = otlpserver.NewSyncWriter(config.getStdout())
 	}
 
-	// write span to file
-	// TODO: if a span comes in twice should we continue to overwrite span.json
-	// or attempt some kind of merge? (e.g. of attributes)
-	sjs, err := json.Marshal(span)
-	if err != nil {
-		log.Fatalf("failed to marshal span to json: %s", err)
+	var ndjson otlpserver.SpanSink
+	if svr.ndjson != "" {
+		maxSize, err := parseByteSize(svr.maxSize)
+		if err != nil {
+			config.Fatal("invalid --max-size %q: %s", svr.maxSize, err)
+		}
+		rf, err := otlpserver.OpenRotatingFile(svr.ndjson, maxSize, svr.maxFiles)
+		if err != nil {
+			config.Fatal("failed to open --ndjson-file: %s", err)
+		}
+		ndjson = otlpserver.NewNdjsonSink(rf)
 	}
 
-	// write the span to /path/tid/sid/span.json
-	writeJson(outpath, "span.json", sjs)
-
-	// only write events out if there is at least one
-	for i, e := range events {
-		ejs, err := json.Marshal(e)
+	var traces otlpserver.SpanSink = otlpserver.NewJsonSink(svr.outDir, out)
+	if svr.format == "otlp-json" {
+		ojs, err := otlpserver.NewOtlpJsonSink(svr.outDir, out)
 		if err != nil {
-			log.Fatalf("failed to marshal span event to json: %s", err)
+			config.Fatal("failed to open --dir: %s", err)
 		}
+		traces = ojs
+	} else if svr.format != "" {
+		tfs, err := otlpserver.NewTraceFormatSink(svr.format, svr.outDir, out)
+		if err != nil {
+			config.Fatal("invalid --format: %s", err)
+		}
+		// whole traces are held in memory, so bound them
+		buffer := newServerBuffer(config)
+		tfs.SetBuffer(buffer)
+		defer logEvictions(buffer)
+		traces = tfs
+	}
 
-		// write events to /path/tid/sid/event-%d.json
-		// TODO: ordering might be a problem if people rely on it...
-		filename := "event-" + strconv.Itoa(i) + ".json"
-		writeJson(outpath, filename, ejs)
+	sink := otlpserver.NewMultiSink(
+		traces,
+		ndjson,
+		otlpserver.CallbackSink(svr.countSpans),
+	)
+
+	// logs and metrics are always accepted, and written when there's
+	// somewhere to write them
+	signals, err := otlpserver.NewSignalJsonSink(map[string]string{
+		otlpserver.LogsSignal:    svr.logsDir,
+		otlpserver.MetricsSignal: svr.metricsDir,
+	}, out)
+	if err != nil {
+		config.Fatal("failed to open --logs-dir or --metrics-dir: %s", err)
 	}
+	defer signals.Close()
+
+	runSignalServer(config, sink, signals.Consume, stop)
+}
 
-	if jsonSvr.maxSpans > 0 && jsonSvr.spansSeen >= jsonSvr.maxSpans {
+// countSpans counts spans as they come in and tells the server to exit
+// once --max-spans is reached.
+func (svr *jsonServer) countSpans(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, ss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	svr.mu.Lock()
+	defer svr.mu.Unlock()
+	svr.spansSeen++ // count spans for exiting on --max-spans
+
+	if svr.maxSpans > 0 && svr.spansSeen >= svr.maxSpans {
 		return true // will cause the server loop to exit
 	}
 
 	return false
 }
 
-// writeJson takes a directory path, a filename, and json. When the path is not empty
-// string the json is written to path/filename. If --stdout was specified the json will
-// be printed as a line to stdout.
-func writeJson(path, filename string, js []byte) {
-	if path != "" {
-		spanfile := filepath.Join(path, filename)
-		err := os.WriteFile(spanfile, js, 0644)
-		if err != nil {
-			log.Fatalf("could not write to file %q: %s", spanfile, err)
+// parseByteSize parses a size like 512, 64KB, 50MB, or 1GB. Units are powers
+// of 1024 and an empty string is 0.
+func parseByteSize(in string) (int64, error) {
+	in = strings.TrimSpace(strings.ToUpper(in))
+	if in == "" {
+		return 0, nil
+	}
+
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+		{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+		{"B", 1},
+	}
+	scale := int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(in, unit.suffix) {
+			in = strings.TrimSpace(strings.TrimSuffix(in, unit.suffix))
+			scale = unit.scale
+			break
 		}
 	}
 
-	if jsonSvr.stdout {
-		os.Stdout.Write(js)
-		os.Stdout.WriteString("\n")
+	n, err := strconv.ParseInt(in, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("size cannot be negative")
 	}
+	return n * scale, nil
 }
@@ -0,0 +1,28 @@
+package otelcli
+
+import "testing"
+
+func TestParseByteSize(t *testing.T) {
+	for in, want := range map[string]int64{
+		"":       0,
+		"512":    512,
+		"64KB":   64 << 10,
+		"50MB":   50 << 20,
+		"50 mib": 50 << 20,
+		"1G":     1 << 30,
+		"10b":    10,
+	} {
+		got, err := parseByteSize(in)
+		if err != nil {
+			t.Errorf("unexpected error parsing %q: %s", in, err)
+		} else if got != want {
+			t.Errorf("expected %q to be %d but got %d", in, want, got)
+		}
+	}
+
+	for _, in := range []string{"MB", "-1MB", "1TB", "1.5MB"} {
+		if _, err := parseByteSize(in); err == nil {
+			t.Errorf("expected an error parsing %q", in)
+		}
+	}
+}
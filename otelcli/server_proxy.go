@@ -0,0 +1,133 @@
+package otelcli
+
+import (
+	"context"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/equinix-labs/otel-cli/otlpserver"
+	"github.com/spf13/cobra"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// proxyServer holds the command-line configured settings for one run of
+// otel-cli server proxy
+type proxyServer struct {
+	listen string
+	outDir string
+	stdout bool
+}
+
+func serverProxyCmd(config *Config) *cobra.Command {
+	svr := &proxyServer{}
+	cmd := cobra.Command{
+		Use:   "proxy",
+		Short: "forward spans to another OTLP endpoint, optionally writing them out too",
+		Long: `Run otel-cli as an OTLP server that forwards the spans it receives, unchanged,
+to the collector at --endpoint. Spans can also be written out on the way
+through, which makes it a debugging tap in front of a real collector. Only
+traces are forwarded, logs and metrics are not.
+
+--listen defaults to grpc://localhost:4317, or OTLP/HTTP with an http:// address.
+Each span is forwarded in its own request, with its resource and scope, so
+--filter-* and --dedupe-window only pass on the spans they keep. --endpoint,
+--protocol, --otlp-headers, the TLS flags, and retries work the same as for
+otel-cli span and configure the downstream connection. Each forwarded request
+gets its own --timeout.
+
+Examples:
+
+	# tap a collector on port 4317, printing each span as it goes through
+	otel-cli server proxy --listen localhost:4319 --endpoint localhost:4317 --stdout
+
+	# take OTLP/HTTP and forward it to a collector over gRPC with TLS
+	otel-cli server proxy --listen http://0.0.0.0:4318 --endpoint https://collector:4317 --protocol grpc --dir $dir`,
+		Run: svr.run,
+	}
+
+	addCommonParams(&cmd, config)
+	addClientParams(&cmd, config)
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&svr.listen, "listen", defaultOtlpEndpoint, "address to listen on, e.g. localhost:4319, http://localhost:4318, or unix:///run/otel.sock")
+	cmd.Flags().StringVar(&svr.outDir, "dir", "", "also write spans to json in the specified directory")
+	cmd.Flags().BoolVar(&svr.stdout, "stdout", false, "also write span jsons to stdout")
+	cmd.Flags().StringVar(&config.ServerMetricsAddr, "metrics-addr", defaults.ServerMetricsAddr, "serve Prometheus metrics about received spans on this address at /metrics, e.g. :9090")
+
+	return &cmd
+}
+
+func (svr *proxyServer) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	if config.Endpoint == "" && config.TracesEndpoint == "" {
+		config.SoftFail("otel-cli server proxy needs an --endpoint to forward spans to")
+	}
+
+	ctx, client := StartClient(cmd.Context(), config)
+	proxy := newProxySink(config, client)
+
+	var local otlpserver.SpanSink
+	if svr.outDir != "" || svr.stdout {
+		var out io.Writer
+		if svr.stdout {
+			out = config.getStdout()
+		}
+		local = otlpserver.NewJsonSink(svr.outDir, out)
+	}
+
+	// the server listens on --listen while everything else in the config is
+	// for the downstream client
+	listenConfig := config.WithEndpoint(svr.listen).WithProtocol("")
+	runServer(listenConfig, otlpserver.NewMultiSink(local, proxy), func(otlpserver.OtlpServer) {})
+
+	if _, err := client.Stop(ctx); err != nil {
+		log.Printf("failed to stop the downstream client: %s", err)
+	}
+	if proxy.failed > 0 {
+		log.Printf("forwarded %d span(s), failed to forward %d", proxy.forwarded, proxy.failed)
+	}
+}
+
+// proxySink forwards each span to the downstream client with the resource
+// and scope it was received with. Spans are forwarded before the server
+// responds to the exporter, so a slow or failing collector pushes back on it
+// the same as it would without the proxy in between.
+type proxySink struct {
+	config    Config
+	client    otlpclient.OTLPClient
+	forwarded int
+	failed    int
+	mu        sync.Mutex
+}
+
+func newProxySink(config Config, client otlpclient.OTLPClient) *proxySink {
+	return &proxySink{config: config, client: client}
+}
+
+// Consume forwards the span. Always returns false.
+func (ps *proxySink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	// a fresh context keeps errors from piling up across requests, and each
+	// request gets the whole --timeout
+	fwdCtx, cancel := context.WithTimeout(context.Background(), ps.config.GetTimeout())
+	defer cancel()
+	fwdCtx, err := ps.client.UploadTraces(fwdCtx, []*tracepb.ResourceSpans{otlpserver.SpanResourceSpans(span, rss)})
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	if err != nil {
+		ps.failed++
+		log.Printf("failed to forward span %x to %s: %s", span.SpanId, ps.config.GetEndpoint(), err)
+		ps.config.SoftLogErrorList(fwdCtx)
+	} else {
+		ps.forwarded++
+	}
+
+	return false
+}
+
+// Close fulfills the interface and does nothing, the client is stopped by
+// doServerProxy.
+func (ps *proxySink) Close() error {
+	return nil
+}
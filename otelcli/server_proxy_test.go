@@ -0,0 +1,85 @@
+package otelcli
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// uploadRecorder is an OTLPClient that keeps every ResourceSpans uploaded,
+// failing when err is set.
+type uploadRecorder struct {
+	uploads []*tracepb.ResourceSpans
+	err     error
+}
+
+func (ur *uploadRecorder) Start(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func (ur *uploadRecorder) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	ur.uploads = append(ur.uploads, rsps...)
+	return ctx, ur.err
+}
+
+func (ur *uploadRecorder) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return ctx, nil
+}
+
+func (ur *uploadRecorder) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	return ctx, nil
+}
+
+func (ur *uploadRecorder) Stop(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+func TestProxySink(t *testing.T) {
+	rss := &tracepb.ResourceSpans{
+		SchemaUrl: "https://opentelemetry.io/schemas/1.24.0",
+		ScopeSpans: []*tracepb.ScopeSpans{
+			{Scope: &commonpb.InstrumentationScope{Name: "empty"}},
+			{Scope: &commonpb.InstrumentationScope{Name: "ab"}, Spans: []*tracepb.Span{{Name: "a"}, {Name: "b"}}},
+			{Spans: []*tracepb.Span{{Name: "c"}}},
+		},
+	}
+
+	client := &uploadRecorder{}
+	proxy := newProxySink(DefaultConfig().WithEndpoint("localhost:4317"), client)
+
+	// the server calls the sink once for each span, in order, but a filter
+	// in front of the proxy may have dropped some, like span a here
+	for _, span := range []*tracepb.Span{rss.ScopeSpans[1].Spans[1], rss.ScopeSpans[2].Spans[0]} {
+		if proxy.Consume(context.Background(), span, nil, rss, nil, nil) {
+			t.Error("proxySink should never report done")
+		}
+	}
+
+	if len(client.uploads) != 2 {
+		t.Fatalf("expected each span to be forwarded, got %d uploads", len(client.uploads))
+	}
+	want := &tracepb.ResourceSpans{
+		SchemaUrl: rss.SchemaUrl,
+		ScopeSpans: []*tracepb.ScopeSpans{
+			{Scope: &commonpb.InstrumentationScope{Name: "ab"}, Spans: []*tracepb.Span{{Name: "b"}}},
+		},
+	}
+	if !proto.Equal(want, client.uploads[0]) {
+		t.Errorf("expected only the span with its scope to be forwarded, got %v", client.uploads[0])
+	}
+	if proxy.forwarded != 2 || proxy.failed != 0 {
+		t.Errorf("expected 2 forwarded and 0 failed, got %d and %d", proxy.forwarded, proxy.failed)
+	}
+
+	client.err = fmt.Errorf("connection refused")
+	proxy.Consume(context.Background(), rss.ScopeSpans[1].Spans[0], nil, rss, nil, nil)
+	if proxy.forwarded != 2 || proxy.failed != 1 {
+		t.Errorf("expected 2 forwarded and 1 failed, got %d and %d", proxy.forwarded, proxy.failed)
+	}
+}
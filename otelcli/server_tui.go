@@ -3,10 +3,14 @@ package otelcli
 import (
 	"context"
 	"encoding/hex"
-	"log"
+	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/equinix-labs/otel-cli/otlpserver"
@@ -15,66 +19,119 @@ import (
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
-var tuiServer struct {
-	lines  SpanEventUnionList
-	traces map[string]*tracepb.Span // for looking up top span of trace by trace id
-	area   *pterm.AreaPrinter
+// tuiServer holds the command-line configured settings and the state for
+// one run of otel-cli server tui
+type tuiServer struct {
+	jsonDir     string
+	statsWindow string
+	lines       SpanEventUnionList
+	traces      map[string]*tracepb.Span // for looking up top span of trace by trace id
+	buffer      *otlpserver.BufferSink   // bounds the spans held in lines and traces
+	area        *pterm.AreaPrinter
+	table       string                // last rendered span table
+	stats       *otlpserver.StatsSink // nil when the stats pane is off
+	mu          sync.Mutex            // spans and the stats refresh both render
 }
 
 func serverTuiCmd(config *Config) *cobra.Command {
+	svr := &tuiServer{}
 	cmd := cobra.Command{
 		Use:   "tui",
 		Short: "display spans in a terminal UI",
 		Long: `Run otel-cli as an OTLP server with a terminal UI that displays traces.
 	
 	# run otel-cli as a local server and print spans to the console as a table
-	otel-cli server tui`,
-		Run: doServerTui,
+	otel-cli server tui
+
+	# also capture all spans to json files while displaying them
+	otel-cli server tui --json-dir $dir
+
+	# show stats over the last 10 seconds next to the table, or 0 to hide them
+	otel-cli server tui --stats-window 10s
+
+	# keep fewer spans in memory on a small host, the oldest are evicted first
+	otel-cli server tui --buffer-spans 1000 --buffer-size 16MB`,
+		Run: svr.run,
 	}
 
 	addCommonParams(&cmd, config)
+	addServerParams(&cmd, config)
+	cmd.Flags().StringVar(&svr.jsonDir, "json-dir", "", "also write spans to json in the specified directory")
+	cmd.Flags().StringVar(&svr.statsWindow, "stats-window", "1m", "show spans/sec, error rate, and the slowest spans over this duration, 0 to hide")
 	return &cmd
 }
 
-// doServerTui implements the 'otel-cli server tui' subcommand.
-func doServerTui(cmd *cobra.Command, args []string) {
+// run implements the 'otel-cli server tui' subcommand.
+func (svr *tuiServer) run(cmd *cobra.Command, args []string) {
 	config := getConfig(cmd.Context())
+
+	statsWindow, err := time.ParseDuration(svr.statsWindow)
+	if err != nil {
+		config.Fatal("invalid --stats-window %q: %s", svr.statsWindow, err)
+	}
+
 	area, err := pterm.DefaultArea.Start()
 	if err != nil {
-		log.Fatalf("failed to set up terminal for rendering: %s", err)
+		config.Fatal("failed to set up terminal for rendering: %s", err)
 	}
-	tuiServer.area = area
+	svr.area = area
 
-	tuiServer.lines = []SpanEventUnion{}
-	tuiServer.traces = make(map[string]*tracepb.Span)
+	svr.lines = []SpanEventUnion{}
+	svr.traces = make(map[string]*tracepb.Span)
+	svr.buffer = newServerBuffer(config)
+	svr.buffer.OnEvict(svr.evictSpan)
+
+	sinks := []otlpserver.SpanSink{}
+	refreshDone := make(chan struct{})
+	if statsWindow > 0 {
+		// stats go ahead of the renderer so the pane includes the new span
+		svr.stats = otlpserver.NewStatsSink(statsWindow)
+		sinks = append(sinks, svr.stats)
+		go svr.refreshStats(refreshDone)
+	}
+	sinks = append(sinks, otlpserver.CallbackSink(svr.render))
 
 	stop := func(otlpserver.OtlpServer) {
-		tuiServer.area.Stop()
+		close(refreshDone)
+		svr.mu.Lock()
+		defer svr.mu.Unlock()
+		svr.area.Stop()
+		logEvictions(svr.buffer)
+	}
+
+	if svr.jsonDir != "" {
+		sinks = append(sinks, otlpserver.NewJsonSink(svr.jsonDir, nil))
 	}
 
-	runServer(config, renderTui, stop)
+	runServer(config, otlpserver.NewMultiSink(sinks...), stop)
 }
 
-// renderTui takes the given span and events, appends them to the in-memory
+// render takes the given span and events, appends them to the in-memory
 // event list, sorts that, then prints it as a pterm table.
-func renderTui(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+func (svr *tuiServer) render(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	svr.mu.Lock()
+	defer svr.mu.Unlock()
+
+	// evicts the oldest spans from lines and traces when over the limits
+	svr.buffer.Add(span, events)
+
 	spanTraceId := hex.EncodeToString(span.TraceId)
-	if _, ok := tuiServer.traces[spanTraceId]; !ok {
-		tuiServer.traces[spanTraceId] = span
+	if _, ok := svr.traces[spanTraceId]; !ok {
+		svr.traces[spanTraceId] = span
 	}
 
-	tuiServer.lines = append(tuiServer.lines, SpanEventUnion{Span: span})
+	svr.lines = append(svr.lines, SpanEventUnion{Span: span})
 	for _, e := range events {
-		tuiServer.lines = append(tuiServer.lines, SpanEventUnion{Span: span, Event: e})
+		svr.lines = append(svr.lines, SpanEventUnion{Span: span, Event: e})
 	}
-	sort.Sort(tuiServer.lines)
-	trimTuiEvents()
+	sort.Sort(svr.lines)
+	svr.trimEvents()
 
 	td := pterm.TableData{
 		{"Trace ID", "Span ID", "Parent", "Name", "Kind", "Start", "End", "Elapsed"},
 	}
 
-	for _, line := range tuiServer.lines {
+	for _, line := range svr.lines {
 		var traceId, spanId, parent, name, kind string
 		var startOffset, endOffset, elapsed int64
 		if line.IsSpan() {
@@ -83,7 +140,7 @@ func renderTui(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_E
 			traceId = line.TraceIdString()
 			spanId = line.SpanIdString()
 
-			if tspan, ok := tuiServer.traces[traceId]; ok {
+			if tspan, ok := svr.traces[traceId]; ok {
 				startOffset = roundedDelta(line.Span.StartTimeUnixNano, tspan.StartTimeUnixNano)
 				endOffset = roundedDelta(line.Span.EndTimeUnixNano, tspan.StartTimeUnixNano)
 			} else {
@@ -101,7 +158,7 @@ func renderTui(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_E
 			kind = "event"
 			traceId = "" // hide ids on events to make screen less busy
 			parent = line.SpanIdString()
-			if tspan, ok := tuiServer.traces[traceId]; ok {
+			if tspan, ok := svr.traces[traceId]; ok {
 				startOffset = roundedDelta(line.Event.TimeUnixNano, tspan.StartTimeUnixNano)
 			} else {
 				startOffset = roundedDelta(line.Event.TimeUnixNano, line.Span.StartTimeUnixNano)
@@ -122,10 +179,76 @@ func renderTui(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_E
 		})
 	}
 
-	tuiServer.area.Update(pterm.DefaultTable.WithHasHeader().WithData(td).Srender())
+	table, _ := pterm.DefaultTable.WithHasHeader().WithData(td).Srender()
+	svr.table = table
+	svr.updateArea()
 	return false // keep running until user hits ctrl-c
 }
 
+// refreshStats redraws the screen every second so the stats pane keeps
+// moving between spans, e.g. spans/sec dropping off once a load test ends.
+func (svr *tuiServer) refreshStats(done chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			svr.mu.Lock()
+			svr.updateArea()
+			svr.mu.Unlock()
+		}
+	}
+}
+
+// evictSpan forgets a span the buffer evicted. Called by the buffer from
+// inside render, with svr.mu held.
+func (svr *tuiServer) evictSpan(bspan otlpserver.BufferedSpan) {
+	tid := hex.EncodeToString(bspan.Span.TraceId)
+	if svr.traces[tid] == bspan.Span {
+		delete(svr.traces, tid)
+	}
+
+	svr.lines = slices.DeleteFunc(svr.lines, func(line SpanEventUnion) bool {
+		return line.Span == bspan.Span
+	})
+}
+
+// updateArea draws the last rendered table, with the stats pane to its
+// right when enabled. Must be called with svr.mu held.
+func (svr *tuiServer) updateArea() {
+	table := svr.table
+	if evicted := svr.buffer.Evicted(); evicted > 0 {
+		table += fmt.Sprintf("\n%d span(s) evicted to stay under --buffer-spans and --buffer-size\n", evicted)
+	}
+
+	if svr.stats == nil {
+		svr.area.Update(table)
+		return
+	}
+
+	panels, _ := pterm.DefaultPanel.WithPanels(pterm.Panels{{
+		{Data: table},
+		{Data: renderTuiStats(svr.stats.Snapshot())},
+	}}).Srender()
+	svr.area.Update(panels)
+}
+
+// renderTuiStats formats a stats snapshot for the stats pane.
+func renderTuiStats(snap otlpserver.StatsSnapshot) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Last %s\n", snap.Window)
+	fmt.Fprintf(&sb, "spans/sec   %.1f\n", snap.SpansPerSec)
+	fmt.Fprintf(&sb, "spans       %d\n", snap.Spans)
+	fmt.Fprintf(&sb, "errors      %d (%.1f%%)\n", snap.Errors, snap.ErrorRate*100)
+	sb.WriteString("\nSlowest\n")
+	for _, s := range snap.Slowest {
+		fmt.Fprintf(&sb, "%8s  %s %s\n", s.Duration.Round(time.Millisecond), s.SpanId, s.Name)
+	}
+	return sb.String()
+}
+
 // roundedDelta takes to uint64 nanos values, cuts them down to milliseconds,
 // takes the delta (absolute value, so any order is fine), and returns an int64
 // of ms between the values.
@@ -138,17 +261,17 @@ func roundedDelta(ts1, ts2 uint64) int64 {
 // trimEvents looks to see if there's room on the screen for the number of incoming
 // events and removes the oldest traces until there's room
 // TODO: how to hand really huge traces that would scroll off the screen entirely?
-func trimTuiEvents() {
+func (svr *tuiServer) trimEvents() {
 	maxRows := pterm.GetTerminalHeight() // TODO: allow override of this?
 
-	if len(tuiServer.lines) == 0 || len(tuiServer.lines) < maxRows {
+	if len(svr.lines) == 0 || len(svr.lines) < maxRows {
 		return // plenty of room, nothing to do
 	}
 
-	end := len(tuiServer.lines) - 1              // should never happen but default to all
-	need := (len(tuiServer.lines) - maxRows) + 2 // trim at least this many
-	tid := tuiServer.lines[0].TraceIdString()    // we always remove the whole trace
-	for i, v := range tuiServer.lines {
+	end := len(svr.lines) - 1              // should never happen but default to all
+	need := (len(svr.lines) - maxRows) + 2 // trim at least this many
+	tid := svr.lines[0].TraceIdString()    // we always remove the whole trace
+	for i, v := range svr.lines {
 		if v.TraceIdString() == tid {
 			end = i
 		} else {
@@ -163,7 +286,7 @@ func trimTuiEvents() {
 	}
 
 	// might need to realloc to not leak memory here?
-	tuiServer.lines = tuiServer.lines[end:]
+	svr.lines = svr.lines[end:]
 }
 
 // SpanEventUnion is for server_tui so it can sort spans and events together
@@ -0,0 +1,327 @@
+package otelcli
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+)
+
+// shellhookOpts holds the command-line configured settings for one run of
+// otel-cli shellhook
+type shellhookOpts struct {
+	match       string
+	sessionName string
+}
+
+func shellhookCmd(config *Config) *cobra.Command {
+	opts := &shellhookOpts{}
+	cmd := cobra.Command{
+		Use:   "shellhook bash|zsh|fish",
+		Short: "print shell hooks that trace every interactive command",
+		Long: `Print hook functions that wrap every interactive command in a span, for
+opt-in tracing of terminal sessions. Every command is a child of a session
+span that's sent when the shell exits. The session's ids and start time are
+kept in a state file in $TMPDIR named after the shell's pid, so loading the
+hooks again in the same shell continues the same session.
+
+Spans are sent by otel-cli in the background after each command finishes, so
+the prompt never waits on the collector. The endpoint and everything else
+otel-cli reads from the environment are picked up when each span is sent.
+In bash the span is named after the first simple command on the line, and
+DEBUG and EXIT traps that were set before loading the hooks still run.
+--match is a POSIX extended regular expression because the shell does the
+matching, so RE2 extras like \d or (?i) are rejected.
+
+Example:
+	# ~/.bashrc
+	export OTEL_EXPORTER_OTLP_ENDPOINT=localhost:4317
+	eval "$(otel-cli shellhook bash)"
+
+	# ~/.zshrc, only trace terraform and kubectl
+	eval "$(otel-cli shellhook zsh --match '^(terraform|kubectl) ')"
+
+	# ~/.config/fish/config.fish
+	otel-cli shellhook fish | source
+`,
+		DisableFlagsInUseLine: true,
+		ValidArgs:             []string{"bash", "zsh", "fish"},
+		Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+		Run:                   opts.run,
+	}
+
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&opts.match, "match", "", "only trace commands matching this POSIX extended regular expression")
+	cmd.Flags().StringVar(&opts.sessionName, "session-name", "shell session", "name of the session span that every command is a child of")
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the traces")
+	cmd.Flags().StringVar(&config.TraceparentCarrierFile, "tp-carrier", defaults.TraceparentCarrierFile, "a file with a traceparent the session span should be a child of")
+
+	return &cmd
+}
+
+func (opts *shellhookOpts) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+
+	// the shells match with POSIX ERE (fish with PCRE), so don't allow
+	// anything RE2 has that they don't, like \d or (?i)
+	if _, err := regexp.CompilePOSIX(opts.match); err != nil {
+		config.SoftFail("invalid --match: %s", err)
+	}
+
+	err := writeShellhook(config.getStdout(), args[0], config.newShellhookSession(opts.sessionName, opts.match))
+	config.SoftFailIfErr(err)
+}
+
+// shellhookSession is what the hooks need to know about the session span.
+// These are generated each time the hooks are printed, and only used by the
+// shell when it doesn't already have a state file.
+type shellhookSession struct {
+	traceparent string
+	parent      string
+	start       string
+	name        string
+	service     string
+	match       string
+}
+
+// newShellhookSession makes up the ids for a new session span named name,
+// continuing the trace from the environment or --tp-carrier if there is one.
+func (c Config) newShellhookSession(name, match string) shellhookSession {
+	traceId := otlpclient.GenerateTraceId()
+	var parent string
+	if tp := c.LoadTraceparent(); tp.Initialized {
+		traceId = tp.TraceId
+		parent = hex.EncodeToString(tp.SpanId)
+	}
+
+	now := c.now()
+	return shellhookSession{
+		traceparent: fmt.Sprintf("00-%x-%x-01", traceId, otlpclient.GenerateSpanId()),
+		parent:      parent,
+		start:       fmt.Sprintf("%d.%09d", now.Unix(), now.Nanosecond()),
+		name:        name,
+		service:     c.GetServiceName(),
+		match:       match,
+	}
+}
+
+// writeShellhook writes the hook script for the named shell.
+func writeShellhook(w io.Writer, shell string, s shellhookSession) error {
+	var script string
+	quote := posixQuote
+	switch shell {
+	case "bash":
+		script = bashShellhook
+	case "zsh":
+		script = zshShellhook
+	case "fish":
+		script = fishShellhook
+		quote = fishQuote
+	default:
+		return fmt.Errorf("unsupported shell %q, expected bash, zsh, or fish", shell)
+	}
+
+	r := strings.NewReplacer(
+		"@TRACEPARENT@", quote(s.traceparent),
+		"@PARENT@", quote(s.parent),
+		"@START@", quote(s.start),
+		"@SESSION_NAME@", quote(s.name),
+		"@SERVICE@", quote(s.service),
+		"@MATCH@", quote(s.match),
+	)
+	_, err := io.WriteString(w, r.Replace(script))
+	return err
+}
+
+// posixQuote single-quotes a string for bash and zsh.
+func posixQuote(in string) string {
+	return "'" + strings.ReplaceAll(in, "'", `'\''`) + "'"
+}
+
+// fishQuote single-quotes a string for fish, which escapes inside quotes.
+func fishQuote(in string) string {
+	in = strings.ReplaceAll(in, `\`, `\\`)
+	return "'" + strings.ReplaceAll(in, "'", `\'`) + "'"
+}
+
+const bashShellhook = `# otel-cli shellhook for bash, load with: eval "$(otel-cli shellhook bash)"
+__otel_cli_state="${TMPDIR:-/tmp}/otel-cli-shellhook-$$.sh"
+if [[ ! -s "$__otel_cli_state" ]]; then
+	printf '__otel_cli_tp=%q\n__otel_cli_session_start=%q\n__otel_cli_session_parent=%q\n' \
+		@TRACEPARENT@ @START@ @PARENT@ > "$__otel_cli_state"
+fi
+. "$__otel_cli_state"
+__otel_cli_session_name=@SESSION_NAME@
+__otel_cli_service=@SERVICE@
+__otel_cli_match=@MATCH@
+__otel_cli_armed=
+__otel_cli_cmd=
+
+# EPOCHREALTIME saves forking date on every command in bash 5+, and BSD date
+# has no %N so older bash on macOS gets whole seconds
+__otel_cli_date_fmt=+%s.%N
+[[ $(date +%N) == *N ]] && __otel_cli_date_fmt=+%s
+__otel_cli_now() {
+	if [[ -n "$EPOCHREALTIME" ]]; then
+		__otel_cli_time="${EPOCHREALTIME/,/.}000"
+	else
+		__otel_cli_time=$(date "$__otel_cli_date_fmt")
+	fi
+}
+
+# keeps a DEBUG or EXIT trap that was set before the hooks so ours can run it
+# trap -p has to run out here, functions can't see the DEBUG trap
+__otel_cli_keep_trap() {
+	local t=${1#trap -- \'}
+	t=${t%\' "$2"}
+	t=${t//\'\\\'\'/\'}
+	[[ "$t" == __otel_cli_* ]] || printf -v "$3" '%s' "$t"
+}
+__otel_cli_keep_trap "$(trap -p DEBUG)" DEBUG __otel_cli_prev_debug
+__otel_cli_keep_trap "$(trap -p EXIT)" EXIT __otel_cli_prev_exit
+
+# runs on the DEBUG trap, only the first command after each prompt counts
+__otel_cli_preexec() {
+	[[ -n "$__otel_cli_prev_debug" ]] && eval "$__otel_cli_prev_debug"
+	[[ -n "$__otel_cli_armed" && -z "$COMP_LINE" ]] || return 0
+	__otel_cli_armed=
+	__otel_cli_cmd="$BASH_COMMAND"
+	__otel_cli_now
+	__otel_cli_start="$__otel_cli_time"
+}
+
+__otel_cli_precmd() {
+	local ret=$?
+	if [[ -n "$__otel_cli_cmd" ]] && [[ -z "$__otel_cli_match" || "$__otel_cli_cmd" =~ $__otel_cli_match ]]; then
+		__otel_cli_now
+		local -a extra=()
+		(( ret != 0 )) && extra=(--status-code error)
+		(TRACEPARENT="$__otel_cli_tp" otel-cli span --service "$__otel_cli_service" \
+			--name "$__otel_cli_cmd" --start "$__otel_cli_start" --end "$__otel_cli_time" \
+			--attrs "process.exit_code=$ret" "${extra[@]}" >/dev/null 2>&1 &)
+	fi
+	__otel_cli_cmd=
+	return $ret
+}
+
+__otel_cli_arm() {
+	__otel_cli_armed=1
+}
+
+__otel_cli_session_end() {
+	local -a parent=()
+	[[ -n "$__otel_cli_session_parent" ]] && parent=(--force-parent-span-id "$__otel_cli_session_parent")
+	otel-cli span --tp-ignore-env --service "$__otel_cli_service" --name "$__otel_cli_session_name" \
+		--force-trace-id "${__otel_cli_tp:3:32}" --force-span-id "${__otel_cli_tp:36:16}" "${parent[@]}" \
+		--start "$__otel_cli_session_start" >/dev/null 2>&1
+	rm -f "$__otel_cli_state"
+	[[ -n "$__otel_cli_prev_exit" ]] && eval "$__otel_cli_prev_exit"
+}
+
+if [[ "$PROMPT_COMMAND" != *__otel_cli_precmd* ]]; then
+	PROMPT_COMMAND="__otel_cli_precmd${PROMPT_COMMAND:+;$PROMPT_COMMAND};__otel_cli_arm"
+fi
+trap __otel_cli_preexec DEBUG
+trap __otel_cli_session_end EXIT
+`
+
+const zshShellhook = `# otel-cli shellhook for zsh, load with: eval "$(otel-cli shellhook zsh)"
+zmodload zsh/datetime
+autoload -Uz add-zsh-hook
+__otel_cli_state="${TMPDIR:-/tmp}/otel-cli-shellhook-$$.sh"
+if [[ ! -s "$__otel_cli_state" ]]; then
+	printf '__otel_cli_tp=%q\n__otel_cli_session_start=%q\n__otel_cli_session_parent=%q\n' \
+		@TRACEPARENT@ @START@ @PARENT@ > "$__otel_cli_state"
+fi
+. "$__otel_cli_state"
+__otel_cli_session_name=@SESSION_NAME@
+__otel_cli_service=@SERVICE@
+__otel_cli_match=@MATCH@
+__otel_cli_cmd=
+
+__otel_cli_now() {
+	__otel_cli_time="$epochtime[1].${(l:9::0:)epochtime[2]}"
+}
+
+__otel_cli_preexec() {
+	__otel_cli_cmd="$1"
+	__otel_cli_now
+	__otel_cli_start="$__otel_cli_time"
+}
+
+__otel_cli_precmd() {
+	local ret=$?
+	if [[ -n "$__otel_cli_cmd" ]] && [[ -z "$__otel_cli_match" || "$__otel_cli_cmd" =~ $__otel_cli_match ]]; then
+		__otel_cli_now
+		local -a extra
+		(( ret != 0 )) && extra=(--status-code error)
+		(TRACEPARENT="$__otel_cli_tp" otel-cli span --service "$__otel_cli_service" \
+			--name "$__otel_cli_cmd" --start "$__otel_cli_start" --end "$__otel_cli_time" \
+			--attrs "process.exit_code=$ret" $extra >/dev/null 2>&1 &)
+	fi
+	__otel_cli_cmd=
+}
+
+__otel_cli_session_end() {
+	local -a parent
+	[[ -n "$__otel_cli_session_parent" ]] && parent=(--force-parent-span-id "$__otel_cli_session_parent")
+	otel-cli span --tp-ignore-env --service "$__otel_cli_service" --name "$__otel_cli_session_name" \
+		--force-trace-id "${__otel_cli_tp:3:32}" --force-span-id "${__otel_cli_tp:36:16}" $parent \
+		--start "$__otel_cli_session_start" >/dev/null 2>&1
+	rm -f "$__otel_cli_state"
+}
+
+add-zsh-hook preexec __otel_cli_preexec
+add-zsh-hook precmd __otel_cli_precmd
+add-zsh-hook zshexit __otel_cli_session_end
+`
+
+const fishShellhook = `# otel-cli shellhook for fish, load with: otel-cli shellhook fish | source
+set -l __otel_cli_tmp /tmp
+set -q TMPDIR; and set __otel_cli_tmp $TMPDIR
+set -g __otel_cli_state $__otel_cli_tmp/otel-cli-shellhook-$fish_pid.fish
+if not test -s $__otel_cli_state
+	printf 'set -g __otel_cli_tp %s\nset -g __otel_cli_session_start %s\nset -g __otel_cli_session_parent %s\n' \
+		@TRACEPARENT@ @START@ @PARENT@ > $__otel_cli_state
+end
+source $__otel_cli_state
+set -g __otel_cli_session_name @SESSION_NAME@
+set -g __otel_cli_service @SERVICE@
+set -g __otel_cli_match @MATCH@
+set -g __otel_cli_cmd
+
+# BSD date has no %N, so macOS gets whole seconds
+set -g __otel_cli_date_fmt +%s.%N
+string match -q '*N' -- (date +%N); and set __otel_cli_date_fmt +%s
+
+function __otel_cli_preexec --on-event fish_preexec
+	set -g __otel_cli_cmd $argv[1]
+	set -g __otel_cli_start (date $__otel_cli_date_fmt)
+end
+
+function __otel_cli_postexec --on-event fish_postexec
+	set -l ret $status
+	if test -n "$__otel_cli_cmd"; and begin; test -z "$__otel_cli_match"; or string match -qr -- $__otel_cli_match $__otel_cli_cmd; end
+		set -l extra
+		test $ret -ne 0; and set extra --status-code error
+		TRACEPARENT=$__otel_cli_tp command otel-cli span --service $__otel_cli_service \
+			--name $__otel_cli_cmd --start $__otel_cli_start --end (date $__otel_cli_date_fmt) \
+			--attrs "process.exit_code=$ret" $extra >/dev/null 2>&1 &
+		disown
+	end
+	set -g __otel_cli_cmd
+end
+
+function __otel_cli_session_end --on-event fish_exit
+	set -l parent
+	test -n "$__otel_cli_session_parent"; and set parent --force-parent-span-id $__otel_cli_session_parent
+	command otel-cli span --tp-ignore-env --service $__otel_cli_service --name $__otel_cli_session_name \
+		--force-trace-id (string sub -s 4 -l 32 -- $__otel_cli_tp) --force-span-id (string sub -s 37 -l 16 -- $__otel_cli_tp) $parent \
+		--start $__otel_cli_session_start >/dev/null 2>&1
+	rm -f $__otel_cli_state
+end
+`
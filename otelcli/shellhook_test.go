@@ -0,0 +1,63 @@
+package otelcli
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestWriteShellhook(t *testing.T) {
+	session := shellhookSession{
+		traceparent: "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01",
+		start:       "1711265285.000000000",
+		name:        "shell session",
+		service:     "otel-cli",
+		match:       `^(make|git) 'quoted'`,
+	}
+
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		t.Run(shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeShellhook(&buf, shell, session); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			script := buf.String()
+
+			if regexp.MustCompile(`@[A-Z_]+@`).MatchString(script) {
+				t.Error("script still has placeholders in it")
+			}
+			if !strings.Contains(script, session.traceparent) {
+				t.Error("script is missing the session traceparent")
+			}
+
+			// check the syntax with the shell itself when it's installed
+			flag := "-n"
+			if shell == "fish" {
+				flag = "--no-execute"
+			}
+			if _, err := exec.LookPath(shell); err != nil {
+				return
+			}
+			cmd := exec.Command(shell, flag)
+			cmd.Stdin = &buf
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("%s rejected the script: %s\n%s", shell, err, out)
+			}
+		})
+	}
+
+	if err := writeShellhook(&bytes.Buffer{}, "tcsh", session); err == nil {
+		t.Error("expected an error for an unsupported shell")
+	}
+}
+
+func TestShellQuotes(t *testing.T) {
+	if got := posixQuote(`it's`); got != `'it'\''s'` {
+		t.Errorf("wrong posix quoting: %s", got)
+	}
+	if got := fishQuote(`it's a \`); got != `'it\'s a \\'` {
+		t.Errorf("wrong fish quoting: %s", got)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// handleSigpipe keeps the Go runtime from killing otel-cli when stdout or
+// stderr is a closed pipe, e.g. `otel-cli span --tp-print | head -1`. With
+// SIGPIPE notified, writes return EPIPE instead, which PrintTraceparent
+// handles. Unlike signal.Ignore, this doesn't leak into exec'd children.
+func handleSigpipe() {
+	signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+}
@@ -0,0 +1,7 @@
+//go:build windows
+
+package otelcli
+
+// handleSigpipe does nothing on Windows, which has no SIGPIPE. Writes to a
+// closed pipe already return an error there.
+func handleSigpipe() {}
@@ -1,10 +1,6 @@
 package otelcli
 
 import (
-	"context"
-	"os"
-	"time"
-
 	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/spf13/cobra"
 )
@@ -26,6 +22,9 @@ Example:
 		--end $(date +%s.%N) \
 		--attrs "os.kernel=$(uname -r)" \
 		--tp-print
+
+	# in a pipeline, take the parent on stdin and print only the new one
+	tp=$(echo $TRACEPARENT | otel-cli span --name step --tp-stdin --tp-stdout)
 `,
 		Run: doSpan,
 	}
@@ -35,13 +34,29 @@ Example:
 	addCommonParams(&cmd, config)
 	addSpanParams(&cmd, config)
 	addSpanStartEndParams(&cmd, config)
+	addSpanDurationParams(&cmd, config)
 	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addLinkParams(&cmd, config)
+	addPrintJsonParams(&cmd, config)
+	addPrintUrlParams(&cmd, config)
+	addAlsoLogParams(&cmd, config)
 	addClientParams(&cmd, config)
 
+	defaults := DefaultConfig()
+	cmd.Flags().BoolVar(&config.TraceparentStdin, "tp-stdin", defaults.TraceparentStdin, "read the parent traceparent from stdin, overriding TRACEPARENT and --tp-carrier")
+	cmd.Flags().BoolVar(&config.TraceparentStdout, "tp-stdout", defaults.TraceparentStdout, "print only the new traceparent to stdout, for pipelines and $(...)")
+
 	// subcommands
 	cmd.AddCommand(spanBgCmd(config))
+	cmd.AddCommand(spanStartCmd(config))
 	cmd.AddCommand(spanEventCmd(config))
+	cmd.AddCommand(spanUpdateCmd(config))
 	cmd.AddCommand(spanEndCmd(config))
+	cmd.AddCommand(spanPushCmd(config))
+	cmd.AddCommand(spanPopCmd(config))
+	cmd.AddCommand(spanSendCmd(config))
 
 	return &cmd
 }
@@ -49,13 +64,22 @@ Example:
 func doSpan(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
 	config := getConfig(ctx)
-	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(config.GetTimeout()))
+	ctx, cancel := config.timeoutContext(ctx, 0)
 	defer cancel()
 	ctx, client := StartClient(ctx, config)
 	span := config.NewProtobufSpan()
-	ctx, err := otlpclient.SendSpan(ctx, client, config, span)
-	config.SoftFailIfErr(err)
+	config.ApplyDurationRules(span)
+	spans := config.sampledSpans(span)
+	config.WriteSpanJsonOut(ctx, spans...)
+	ctx, err := otlpclient.SendSpans(ctx, client, config, spans)
+	if err != nil {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("unable to send span: %s", err)
+	}
+	ctx = config.SendAlsoLog(ctx, client, span)
 	_, err = client.Stop(ctx)
 	config.SoftFailIfErr(err)
-	config.PropagateTraceparent(span, os.Stdout)
+	config.PropagateTraceparent(span, config.getStdout())
+	config.PrintSpanJson(span, config.getStdout())
+	config.PrintSpanUrl(span, config.getStderr())
 }
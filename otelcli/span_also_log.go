@@ -0,0 +1,86 @@
+package otelcli
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// NewAlsoLogRecord returns the log record --also-log sends for the span. It
+// has the span's trace and span ids so the two can be joined up, the status
+// message as its body, or the span name when there isn't one, and ERROR
+// severity when the span failed so log-based alerting can match on it.
+func (c Config) NewAlsoLogRecord(span *tracepb.Span) *logspb.LogRecord {
+	record := otlpclient.NewProtobufLogRecord()
+	record.TimeUnixNano = span.EndTimeUnixNano
+	record.TraceId = span.TraceId
+	record.SpanId = span.SpanId
+	if c.GetIsRecording() && c.GetIsSampled(span.TraceId) {
+		record.Flags = 0x01 // the w3c sampled flag, same as the span's traceparent
+	}
+
+	body := span.GetStatus().GetMessage()
+	if body == "" {
+		body = span.Name
+	}
+	record.Body.Value = &commonpb.AnyValue_StringValue{StringValue: body}
+
+	if span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		record.SeverityNumber = logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+		record.SeverityText = "ERROR"
+	}
+
+	keys := c.alsoLogAttrKeys()
+	for _, kv := range span.Attributes {
+		if slices.Contains(keys, kv.Key) {
+			record.Attributes = append(record.Attributes, kv)
+		}
+	}
+	record.Attributes = append(record.Attributes, &commonpb.KeyValue{
+		Key:   "span.name",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: span.Name}},
+	})
+
+	return record
+}
+
+// alsoLogAttrKeys returns the span attributes to copy onto the log record,
+// from --also-log-attrs, or the ones set with --attrs by default so the
+// record isn't cluttered with process details.
+func (c Config) alsoLogAttrKeys() []string {
+	if c.AlsoLogAttrs == "" {
+		keys := make([]string, 0, len(c.Attributes))
+		for key := range c.Attributes {
+			keys = append(keys, key)
+		}
+		return keys
+	}
+
+	keys := []string{}
+	for _, key := range strings.Split(c.AlsoLogAttrs, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// SendAlsoLog sends the --also-log record for the span on the same client,
+// when --also-log is set. Failures are handled the same as for the span.
+func (c Config) SendAlsoLog(ctx context.Context, client otlpclient.OTLPClient, span *tracepb.Span) context.Context {
+	if !c.AlsoLog {
+		return ctx
+	}
+
+	ctx, err := otlpclient.SendLogs(ctx, client, c, []*logspb.LogRecord{c.NewAlsoLogRecord(span)})
+	if err != nil {
+		c.SoftLogErrorList(ctx)
+		c.SoftFail("unable to send log: %s", err)
+	}
+	return ctx
+}
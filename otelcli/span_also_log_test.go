@@ -0,0 +1,78 @@
+package otelcli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// alsoLogAttrsMap flattens the log record's attributes for comparison.
+func alsoLogAttrsMap(record *logspb.LogRecord) map[string]string {
+	out := map[string]string{}
+	for _, kv := range record.Attributes {
+		out[kv.Key] = otlpclient.AnyValueToString(kv.Value)
+	}
+	return out
+}
+
+func TestNewAlsoLogRecord(t *testing.T) {
+	span := &tracepb.Span{
+		TraceId:         []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:          []byte{1, 1, 1, 1, 1, 1, 1, 1},
+		Name:            "deploy",
+		EndTimeUnixNano: 1700000001500000000,
+		Attributes: otlpclient.StringMapAttrsToProtobuf(map[string]string{
+			"env":          "prod",
+			"process.pid":  "1234",
+			"service.zone": "b",
+		}),
+		Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timed out"},
+	}
+
+	config := DefaultConfig().
+		WithEndpoint("localhost:4317").
+		WithAttributes(map[string]string{"env": "prod"})
+
+	record := config.NewAlsoLogRecord(span)
+	if !bytes.Equal(record.TraceId, span.TraceId) || !bytes.Equal(record.SpanId, span.SpanId) {
+		t.Errorf("expected the span's ids, got %x/%x", record.TraceId, record.SpanId)
+	}
+	if record.Flags != 1 {
+		t.Errorf("expected sampled flag to be set, got %d", record.Flags)
+	}
+	if record.TimeUnixNano != span.EndTimeUnixNano {
+		t.Errorf("expected the span's end time, got %d", record.TimeUnixNano)
+	}
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR || record.SeverityText != "ERROR" {
+		t.Errorf("expected ERROR severity for a failed span, got %s/%s", record.SeverityNumber, record.SeverityText)
+	}
+	if body := record.Body.GetStringValue(); body != "timed out" {
+		t.Errorf("expected the status message as the body, got %q", body)
+	}
+	// only --attrs keys by default, plus the span name
+	got := alsoLogAttrsMap(record)
+	if len(got) != 2 || got["env"] != "prod" || got["span.name"] != "deploy" {
+		t.Errorf("unexpected attributes %v", got)
+	}
+
+	// --also-log-attrs picks the keys, the span name is the body without a status
+	span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK}
+	record = config.WithAlsoLogAttrs("process.pid, service.zone").NewAlsoLogRecord(span)
+	if record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("expected INFO severity, got %s", record.SeverityNumber)
+	}
+	if body := record.Body.GetStringValue(); body != "deploy" {
+		t.Errorf("expected the span name as the body, got %q", body)
+	}
+	got = alsoLogAttrsMap(record)
+	if len(got) != 3 || got["process.pid"] != "1234" || got["service.zone"] != "b" {
+		t.Errorf("unexpected attributes %v", got)
+	}
+	// the flags follow the span's sampling decision, not just --no-recording
+	if record = config.WithSampler("always_off").NewAlsoLogRecord(span); record.Flags != 0 {
+		t.Errorf("expected no sampled flag for an unsampled span, got %d", record.Flags)
+	}
+}
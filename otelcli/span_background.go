@@ -4,7 +4,6 @@ import (
 	"context"
 	"os"
 	"os/signal"
-	"path"
 	"strconv"
 	"syscall"
 	"time"
@@ -19,8 +18,8 @@ func spanBgCmd(config *Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "background",
 		Short: "create background span handler",
-		Long: `Creates a background span handler that listens on a Unix socket
-so you can add events to it. The span is closed when the process exits from
+		Long: `Creates a background span handler that listens on a Unix socket,
+or a named pipe on Windows, so you can add events to it. The span is closed when the process exits from
 timeout, (catchable) signals, or deliberate exit.
 
     socket_dir=$(mktemp -d)
@@ -47,15 +46,22 @@ timeout, (catchable) signals, or deliberate exit.
 	// start a background span at the top of a script then let it fall off
 	// at the end to get an easy span
 	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", defaults.BackgroundSockdir, "a directory where a socket can be placed safely")
+	cmd.Flags().StringVar(&config.BackgroundSpanHandle, "span-handle", defaults.BackgroundSpanHandle, "name of the background span to use, so several can share one sockdir")
 
 	cmd.Flags().IntVar(&config.BackgroundParentPollMs, "parent-poll", defaults.BackgroundParentPollMs, "number of milliseconds to wait between checking for whether the parent process exited")
 	cmd.Flags().BoolVar(&config.BackgroundWait, "wait", defaults.BackgroundWait, "wait for background to be fully started and then return")
 	cmd.Flags().BoolVar(&config.BackgroundSkipParentPidCheck, "skip-pid-check", defaults.BackgroundSkipParentPidCheck, "disable checking parent pid")
+	cmd.Flags().StringVar(&config.BackgroundJournal, "journal", defaults.BackgroundJournal, "append everything the background span receives to this file so it can be resumed after a crash")
+	cmd.Flags().BoolVar(&config.BackgroundResume, "resume", defaults.BackgroundResume, "replay the --journal left behind by a crashed span background and continue its span")
 
 	addCommonParams(&cmd, config)
 	addSpanParams(&cmd, config)
+	addSpanDurationParams(&cmd, config)
 	addClientParams(&cmd, config)
 	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addLinkParams(&cmd, config)
 
 	return &cmd
 }
@@ -79,16 +85,45 @@ func doSpanBackground(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	// with --resume, pick up the span from the journal a previous span
+	// background left behind, along with everything it received
 	span := config.NewProtobufSpan()
+	entries := []bgJournalEntry{}
+	resumed := false
+	var journalEnd int64
+	if config.BackgroundResume && config.BackgroundJournal != "" {
+		jspan, jentries, jend, err := readBgJournal(config.BackgroundJournal)
+		if err == nil {
+			span, entries, journalEnd, resumed = jspan, jentries, jend, true
+		} else if !os.IsNotExist(err) {
+			config.SoftFail("could not resume span background: %s", err)
+		}
+	}
 
 	// span background is a bit different from span/exec in that it might be
 	// hanging out while other spans are created, so it does the traceparent
 	// propagation before the server starts, instead of after
-	config.PropagateTraceparent(span, os.Stdout)
+	config.PropagateTraceparent(span, config.getStdout())
 
-	sockfile := path.Join(config.BackgroundSockdir, spanBgSockfilename)
+	sockfile := config.GetBackgroundSockfile()
 	bgs := createBgServer(ctx, sockfile, span)
 
+	var journal *bgJournal
+	if config.BackgroundJournal != "" {
+		var err error
+		if resumed {
+			journal, err = openBgJournal(config.BackgroundJournal, journalEnd)
+		} else {
+			journal, err = createBgJournal(config.BackgroundJournal, span)
+		}
+		if err != nil {
+			config.SoftFail("%s", err)
+		}
+	}
+	if err := bgs.resume(entries, journal); err != nil {
+		config.SoftFail("could not resume span background: %s", err)
+	}
+
 	// set up signal handlers to cleanly exit on SIGINT/SIGTERM etc
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
@@ -98,23 +133,17 @@ func doSpanBackground(cmd *cobra.Command, args []string) {
 	}()
 
 	// in order to exit at the end of scripts this program needs a way to know
-	// when the parent is gone. the most straightforward approach that should
-	// be fine on most Unix-ish operating systems is to poll getppid and exit
-	// when the parent process pid changes
+	// when the parent is gone. on Unix-ish operating systems that's polling
+	// getppid until it changes, on Windows waiting on the parent process
 	if !config.BackgroundSkipParentPidCheck {
-		ppid := os.Getppid()
+		exited := watchParent(time.Duration(config.BackgroundParentPollMs) * time.Millisecond)
 		go func() {
-			for {
-				time.Sleep(time.Duration(config.BackgroundParentPollMs) * time.Millisecond)
-
-				// check if the parent process has changed, exit when it does
-				cppid := os.Getppid()
-				if cppid != ppid {
-					rt := time.Since(started)
-					spanBgEndEvent(ctx, span, "parent_exited", rt)
-					bgs.Shutdown()
-				}
-			}
+			<-exited
+			rt := time.Since(started)
+			unlock := bgs.lockSpan()
+			spanBgEndEvent(ctx, span, "parent_exited", rt)
+			unlock()
+			bgs.Shutdown()
 		}()
 	}
 
@@ -124,23 +153,45 @@ func doSpanBackground(cmd *cobra.Command, args []string) {
 		go func() {
 			time.Sleep(timeout)
 			rt := time.Since(started)
+			unlock := bgs.lockSpan()
 			spanBgEndEvent(ctx, span, "timeout", rt)
+			unlock()
 			bgs.Shutdown()
 		}()
 	}
 
 	// will block until bgs.Shutdown()
+	running := time.Now()
 	bgs.Run()
+	bgRuntime := time.Since(running)
 
-	span.EndTimeUnixNano = uint64(time.Now().UnixNano())
+	// RPCs that were already in flight can still land, so they're held off
+	// until the span has been sent
+	unlock := bgs.lockSpan()
+	defer unlock()
 
-	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(config.GetTimeout()))
+	ended := config.now()
+	span.EndTimeUnixNano = uint64(ended.UnixNano())
+	config.ApplyDurationRules(span)
+
+	// time spent in the background doesn't count against --timeout
+	ctx, cancel := config.timeoutContext(ctx, bgRuntime)
 	defer cancel()
 
-	_, err := otlpclient.SendSpan(ctx, client, config, span)
+	// child spans minted via span start go out in the same batch
+	spans := config.sampledSpans(append([]*tracepb.Span{span}, bgs.ChildSpans(ended)...)...)
+	config.WriteSpanJsonOut(ctx, spans...)
+	ctx, err := otlpclient.SendSpans(ctx, client, config, spans)
 	if err != nil {
+		config.SoftLogErrorList(ctx)
 		config.SoftFail("Sending span failed: %s", err)
 	}
+
+	// the span is out, so there's nothing left to resume
+	if journal != nil {
+		journal.close()
+		os.Remove(config.BackgroundJournal)
+	}
 }
 
 // spanBgEndEvent adds an event with the provided name, to the provided span
@@ -149,6 +200,7 @@ func spanBgEndEvent(ctx context.Context, span *tracepb.Span, name string, elapse
 	config := getConfig(ctx)
 	event := otlpclient.NewProtobufSpanEvent()
 	event.Name = name
+	event.TimeUnixNano = uint64(config.now().UnixNano())
 	event.Attributes = otlpclient.StringMapAttrsToProtobuf(map[string]string{
 		"config.timeout":      config.Timeout,
 		"otel-cli.runtime_ms": strconv.FormatInt(elapsed.Milliseconds(), 10),
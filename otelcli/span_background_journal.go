@@ -0,0 +1,196 @@
+package otelcli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// bgJournal appends everything the span background server receives to a
+// file, so when the background process crashes or is killed with SIGKILL,
+// span background --resume can rebuild the span from it and send it with
+// everything that was observed before.
+type bgJournal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// bgJournalEntry is a line in the journal. The first line has the span as
+// it started, the rest have an RPC's method name and its input, so they can
+// be replayed by calling the same methods again.
+type bgJournalEntry struct {
+	Span   json.RawMessage `json:"span,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Input  json.RawMessage `json:"input,omitempty"`
+}
+
+// createBgJournal starts a new journal for the span at path, replacing any
+// that was there.
+func createBgJournal(path string, span *tracepb.Span) (*bgJournal, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not create span background journal: %w", err)
+	}
+
+	js, err := otlpclient.MarshalOtlpJson(span)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not encode span for the journal: %w", err)
+	}
+
+	journal := bgJournal{file: file}
+	return &journal, journal.write(bgJournalEntry{Span: js})
+}
+
+// openBgJournal opens an existing journal at path to append to it after
+// end, where readBgJournal found the last whole line ended. Anything after
+// that, e.g. a line cut short by a crash, is cut off first so new entries
+// start on a line of their own.
+func openBgJournal(path string, end int64) (*bgJournal, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open span background journal: %w", err)
+	}
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("could not trim span background journal: %w", err)
+	}
+	return &bgJournal{file: file}, nil
+}
+
+// record appends an RPC and its input to the journal. Does nothing when
+// the journal is nil, i.e. there's no --journal or it's being replayed.
+func (j *bgJournal) record(method string, input interface{}) error {
+	if j == nil {
+		return nil
+	}
+
+	js, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("could not encode %s for the journal: %w", method, err)
+	}
+	return j.write(bgJournalEntry{Method: method, Input: js})
+}
+
+// write appends the entry as a line and syncs it to disk, so it survives
+// whatever happens to the process next.
+func (j *bgJournal) write(entry bgJournalEntry) error {
+	js, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(append(js, '\n')); err != nil {
+		return fmt.Errorf("could not write to span background journal: %w", err)
+	}
+	return j.file.Sync()
+}
+
+// close closes the journal file. Does nothing when the journal is nil.
+func (j *bgJournal) close() error {
+	if j == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// readBgJournal reads the journal at path, returning the span as it started,
+// the entries to replay on it, and the offset where the last whole line
+// ends, for openBgJournal. A line cut short by a crash in the middle of
+// writing it is ignored.
+func readBgJournal(path string) (*tracepb.Span, []bgJournalEntry, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer file.Close()
+
+	var span *tracepb.Span
+	var end int64
+	entries := []bgJournalEntry{}
+	reader := bufio.NewReader(file)
+	for {
+		// a last line without its newline was cut short, even when what's
+		// there happens to be valid json
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, nil, 0, fmt.Errorf("could not read span background journal %q: %w", path, err)
+		}
+
+		var entry bgJournalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			break
+		}
+		end += int64(len(line))
+
+		if span == nil {
+			if len(entry.Span) == 0 {
+				return nil, nil, 0, fmt.Errorf("span background journal %q does not start with a span", path)
+			}
+			span = &tracepb.Span{}
+			if err := otlpclient.UnmarshalOtlpJson(entry.Span, span); err != nil {
+				return nil, nil, 0, fmt.Errorf("could not decode the span in span background journal %q: %w", path, err)
+			}
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	if span == nil {
+		return nil, nil, 0, fmt.Errorf("span background journal %q is empty", path)
+	}
+
+	return span, entries, end, nil
+}
+
+// replay calls the RPC methods in the entries on bs again, without writing
+// them to the journal a second time. A replayed End shuts the server down
+// like it did the first time, so the span is sent as soon as it's resumed.
+func (bs BgSpan) replay(entries []bgJournalEntry) error {
+	bs.journal = nil
+	for _, entry := range entries {
+		var err error
+		switch entry.Method {
+		case "AddEvent":
+			var in BgSpanEvent
+			if err = json.Unmarshal(entry.Input, &in); err == nil {
+				err = bs.AddEvent(&in, &BgSpan{})
+			}
+		case "Update":
+			var in BgUpdate
+			if err = json.Unmarshal(entry.Input, &in); err == nil {
+				err = bs.Update(&in, &BgSpan{})
+			}
+		case "StartChild":
+			var in BgChildStart
+			if err = json.Unmarshal(entry.Input, &in); err == nil {
+				err = bs.StartChild(&in, &BgSpan{})
+			}
+		case "EndChild":
+			var in BgChildEnd
+			if err = json.Unmarshal(entry.Input, &in); err == nil {
+				err = bs.EndChild(&in, &BgSpan{})
+			}
+		case "End":
+			var in BgEnd
+			if err = json.Unmarshal(entry.Input, &in); err == nil {
+				err = bs.End(&in, &BgSpan{})
+			}
+		default:
+			err = fmt.Errorf("unknown method %q", entry.Method)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to replay %s from the span background journal: %w", entry.Method, err)
+		}
+	}
+	return nil
+}
@@ -3,16 +3,17 @@ package otelcli
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
 	"os"
-	"path"
 	"sync"
 	"time"
 
 	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
 
@@ -24,28 +25,89 @@ type BgSpan struct {
 	Error       string `json:"error"`
 	config      Config
 	span        *tracepb.Span
+	spanMu      *sync.Mutex // guards span, RPCs run concurrently
+	children    *bgChildren
 	shutdown    func()
+	journal     *bgJournal
+}
+
+// BgChildStart is sent by span start to have the background server create
+// a child span in its trace.
+type BgChildStart struct {
+	Name       string            `json:"name"`
+	Kind       string            `json:"kind"`
+	Under      string            `json:"under"`
+	Timestamp  string            `json:"timestamp"`
+	Attributes map[string]string `json:"span_attributes"`
+	// SpanID is only set when replaying the journal, so children get the
+	// same ids they had before the background server was resumed.
+	SpanID string `json:"span_id,omitempty"`
+}
+
+// BgChildEnd is sent by span end --child to end a child span.
+type BgChildEnd struct {
+	SpanID     string            `json:"span_id"`
+	Timestamp  string            `json:"timestamp"`
+	Attributes map[string]string `json:"span_attributes"`
+	Links      []string          `json:"links"`
+	StatusCode string            `json:"status_code"`
+	StatusDesc string            `json:"status_description"`
+}
+
+// bgChildren holds the child spans the background server minted for other
+// otel-cli invocations, keyed by hex span id. They are sent along with the
+// background span when it ends.
+type bgChildren struct {
+	mu    sync.Mutex
+	spans map[string]*tracepb.Span
+	order []string // preserves creation order for sending
+}
+
+// all returns the child spans in the order they were started.
+func (bc *bgChildren) all() []*tracepb.Span {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	out := make([]*tracepb.Span, len(bc.order))
+	for i, sid := range bc.order {
+		out[i] = bc.spans[sid]
+	}
+	return out
 }
 
 // BgSpanEvent is a span event that the client will send.
 type BgSpanEvent struct {
-	Name       string `json:"name"`
-	Timestamp  string `json:"timestamp"`
-	Attributes map[string]string
+	Name            string `json:"name"`
+	Timestamp       string `json:"timestamp"`
+	Attributes      map[string]string
+	AttributesBytes map[string]string `json:"attributes_bytes"`
+}
+
+// BgUpdate is sent by span update to change the background span while it's
+// still running. Empty fields leave the span as it is.
+type BgUpdate struct {
+	Name            string            `json:"name"`
+	Attributes      map[string]string `json:"span_attributes"`
+	AttributesBytes map[string]string `json:"attributes_bytes"`
+	Links           []string          `json:"links"`
 }
 
 // BgEnd is an empty struct that can be sent to call End().
 type BgEnd struct {
 	Attributes map[string]string `json:"span_attributes" env:"OTEL_CLI_ATTRIBUTES"`
+	Links      []string          `json:"links"`
 	StatusCode string            `json:"status_code"`
 	StatusDesc string            `json:"status_description"`
 }
 
 // AddEvent takes a BgSpanEvent from the client and attaches an event to the span.
 func (bs BgSpan) AddEvent(bse *BgSpanEvent, reply *BgSpan) error {
+	bs.spanMu.Lock()
+	defer bs.spanMu.Unlock()
+
 	reply.TraceID = hex.EncodeToString(bs.span.TraceId)
 	reply.SpanID = hex.EncodeToString(bs.span.SpanId)
-	reply.Traceparent = otlpclient.TraceparentFromProtobufSpan(bs.span, bs.config.GetIsRecording()).Encode()
+	reply.Traceparent = otlpclient.TraceparentFromProtobufSpan(bs.span, bs.config.GetIsRecording() && bs.config.GetIsSampled(bs.span.TraceId)).Encode()
 
 	ts, err := time.Parse(time.RFC3339Nano, bse.Timestamp)
 	if err != nil {
@@ -53,12 +115,183 @@ func (bs BgSpan) AddEvent(bse *BgSpanEvent, reply *BgSpan) error {
 		return err
 	}
 
+	attrs, err := attrsToProtobuf(bse.Attributes, bse.AttributesBytes)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
 	event := otlpclient.NewProtobufSpanEvent()
 	event.Name = bse.Name
 	event.TimeUnixNano = uint64(ts.UnixNano())
-	event.Attributes = otlpclient.StringMapAttrsToProtobuf(bse.Attributes)
+	event.Attributes = attrs
 
 	bs.span.Events = append(bs.span.Events, event)
+	bs.record("AddEvent", bse)
+
+	return nil
+}
+
+// Update renames the span, sets attributes, replacing any with the same
+// key, and adds links, then replies with the usual trace info.
+func (bs BgSpan) Update(in *BgUpdate, reply *BgSpan) error {
+	bs.spanMu.Lock()
+	defer bs.spanMu.Unlock()
+
+	reply.TraceID = hex.EncodeToString(bs.span.TraceId)
+	reply.SpanID = hex.EncodeToString(bs.span.SpanId)
+	reply.Traceparent = otlpclient.TraceparentFromProtobufSpan(bs.span, bs.config.GetIsRecording() && bs.config.GetIsSampled(bs.span.TraceId)).Encode()
+
+	attrs, err := attrsToProtobuf(in.Attributes, in.AttributesBytes)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
+	links, err := parseSpanLinks(in.Links)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
+	if in.Name != "" {
+		bs.span.Name = in.Name
+	}
+
+	setSpanAttributes(bs.span, attrs)
+	bs.span.Links = append(bs.span.Links, links...)
+	bs.record("Update", in)
+
+	return nil
+}
+
+// record writes an RPC that changed the span to the journal, if there is
+// one. A journal that can't be written to is logged but doesn't fail the RPC,
+// since the span itself is still fine.
+func (bs BgSpan) record(method string, in interface{}) {
+	if err := bs.journal.record(method, in); err != nil {
+		bs.config.SoftLog("%s", err)
+	}
+}
+
+// setSpanAttributes adds attrs to the span, replacing any existing attribute
+// with the same key so its type isn't lost to a round trip through strings.
+func setSpanAttributes(span *tracepb.Span, attrs []*commonpb.KeyValue) {
+	for _, attr := range attrs {
+		replaced := false
+		for i, existing := range span.Attributes {
+			if existing.Key == attr.Key {
+				span.Attributes[i] = attr
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			span.Attributes = append(span.Attributes, attr)
+		}
+	}
+}
+
+// StartChild creates a new span in the background span's trace, parented to
+// the background span or to a previously started child, and replies with
+// the new span's ids and traceparent.
+func (bs BgSpan) StartChild(in *BgChildStart, reply *BgSpan) error {
+	ts, err := time.Parse(time.RFC3339Nano, in.Timestamp)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
+	// the span lock is always taken before the children's
+	bs.spanMu.Lock()
+	defer bs.spanMu.Unlock()
+	bs.children.mu.Lock()
+	defer bs.children.mu.Unlock()
+
+	span := otlpclient.NewProtobufSpan()
+	span.TraceId = bs.span.TraceId
+	span.ParentSpanId = bs.span.SpanId
+	if in.Under != "" && in.Under != "background" {
+		parent, ok := bs.children.spans[in.Under]
+		if !ok {
+			err = fmt.Errorf("no child span with id %q in span background", in.Under)
+			reply.Error = err.Error()
+			return err
+		}
+		span.ParentSpanId = parent.SpanId
+	}
+	if in.SpanID != "" {
+		span.SpanId, err = hex.DecodeString(in.SpanID)
+		if err != nil || len(span.SpanId) != 8 {
+			err = fmt.Errorf("invalid child span id %q", in.SpanID)
+			reply.Error = err.Error()
+			return err
+		}
+	} else if bs.config.GetIsRecording() {
+		span.SpanId = otlpclient.GenerateSpanId()
+	}
+	span.Name = in.Name
+	span.Kind = otlpclient.SpanKindStringToInt(in.Kind)
+	span.StartTimeUnixNano = uint64(ts.UnixNano())
+	span.EndTimeUnixNano = 0 // set by EndChild or when the background span ends
+	span.Attributes = otlpclient.StringMapAttrsToProtobuf(in.Attributes)
+
+	sid := hex.EncodeToString(span.SpanId)
+	if _, exists := bs.children.spans[sid]; !exists {
+		bs.children.order = append(bs.children.order, sid)
+	}
+	bs.children.spans[sid] = span
+
+	// journal the id the child got so it gets the same one on replay
+	journaled := *in
+	journaled.SpanID = sid
+	bs.record("StartChild", &journaled)
+
+	reply.TraceID = hex.EncodeToString(span.TraceId)
+	reply.SpanID = sid
+	reply.Traceparent = otlpclient.TraceparentFromProtobufSpan(span, bs.config.GetIsRecording() && bs.config.GetIsSampled(span.TraceId)).Encode()
+
+	return nil
+}
+
+// EndChild ends a child span created by StartChild. The span is held until
+// the background span ends so the whole tree goes out together.
+func (bs BgSpan) EndChild(in *BgChildEnd, reply *BgSpan) error {
+	ts, err := time.Parse(time.RFC3339Nano, in.Timestamp)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
+	bs.spanMu.Lock()
+	defer bs.spanMu.Unlock()
+	bs.children.mu.Lock()
+	defer bs.children.mu.Unlock()
+
+	span, ok := bs.children.spans[in.SpanID]
+	if !ok {
+		err = fmt.Errorf("no child span with id %q in span background", in.SpanID)
+		reply.Error = err.Error()
+		return err
+	}
+
+	links, err := parseSpanLinks(in.Links)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
+	}
+
+	for _, attr := range otlpclient.StringMapAttrsToProtobuf(in.Attributes) {
+		span.Attributes = append(span.Attributes, attr)
+	}
+	span.Links = append(span.Links, links...)
+	otlpclient.SetSpanStatus(span, in.StatusCode, in.StatusDesc)
+	span.EndTimeUnixNano = uint64(ts.UnixNano())
+	bs.record("EndChild", in)
+
+	reply.TraceID = hex.EncodeToString(span.TraceId)
+	reply.SpanID = in.SpanID
+	reply.Traceparent = otlpclient.TraceparentFromProtobufSpan(span, bs.config.GetIsRecording() && bs.config.GetIsSampled(span.TraceId)).Encode()
 
 	return nil
 }
@@ -71,18 +304,28 @@ func (bs BgSpan) Wait(in, reply *struct{}) error {
 // End takes a BgEnd (empty) struct, replies with the usual trace info, then
 // ends the span end exits the background process.
 func (bs BgSpan) End(in *BgEnd, reply *BgSpan) error {
-	// handle --attrs arg to span end by retrieving and merging with/overwriting existing attribtues
-	attrs := make(map[string]string)
-	for k, v := range otlpclient.SpanAttributesToStringMap(bs.span) {
-		attrs[k] = v
+	bs.spanMu.Lock()
+	defer bs.spanMu.Unlock()
+
+	// --link args to span end are added to any the span started with
+	links, err := parseSpanLinks(in.Links)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
 	}
-	for key, value := range in.Attributes {
-		attrs[key] = value
+	bs.span.Links = append(bs.span.Links, links...)
+
+	// --attrs args to span end are merged with/overwrite existing attributes
+	attrs, err := attrsToProtobuf(in.Attributes, nil)
+	if err != nil {
+		reply.Error = err.Error()
+		return err
 	}
+	setSpanAttributes(bs.span, attrs)
+
 	// handle --status-code and --status-description args to span end
-	c := bs.config.WithStatusCode(in.StatusCode).WithStatusDescription(in.StatusDesc).WithAttributes(attrs)
-	otlpclient.SetSpanStatus(bs.span, c.StatusCode, c.StatusDescription)
-	bs.span.Attributes = otlpclient.StringMapAttrsToProtobuf(c.Attributes)
+	otlpclient.SetSpanStatus(bs.span, in.StatusCode, in.StatusDesc)
+	bs.record("End", in)
 
 	// running the shutdown as a goroutine prevents the client from getting an
 	// error here when the server gets closed. defer didn't do the trick.
@@ -95,12 +338,20 @@ type bgServer struct {
 	sockfile string
 	listener net.Listener
 	quit     chan struct{}
+	stopOnce sync.Once
 	wg       sync.WaitGroup
 	config   Config
+	children *bgChildren
+	bgspan   *BgSpan
 }
 
-// createBgServer opens a new span background server on a unix socket and
-// returns with the server ready to go. Not expected to block.
+// errBgNotListening is returned by bgDial when the span background server
+// isn't up yet, so the client keeps trying.
+var errBgNotListening = errors.New("span background server is not listening yet")
+
+// createBgServer opens a new span background server on a unix socket, or a
+// named pipe on Windows, and returns with the server ready to go. Not
+// expected to block.
 func createBgServer(ctx context.Context, sockfile string, span *tracepb.Span) *bgServer {
 	var err error
 	config := getConfig(ctx)
@@ -109,6 +360,7 @@ func createBgServer(ctx context.Context, sockfile string, span *tracepb.Span) *b
 		sockfile: sockfile,
 		quit:     make(chan struct{}),
 		config:   config,
+		children: &bgChildren{spans: make(map[string]*tracepb.Span)},
 	}
 
 	// TODO: be safer?
@@ -121,14 +373,17 @@ func createBgServer(ctx context.Context, sockfile string, span *tracepb.Span) *b
 		SpanID:   hex.EncodeToString(span.SpanId),
 		config:   config,
 		span:     span,
+		spanMu:   &sync.Mutex{},
+		children: bgs.children,
 		shutdown: func() { bgs.Shutdown() },
 	}
 	// makes methods on BgSpan available over RPC
 	rpc.Register(&bgspan)
+	bgs.bgspan = &bgspan
 
-	bgs.listener, err = net.Listen("unix", sockfile)
+	bgs.listener, err = bgListen(sockfile)
 	if err != nil {
-		config.SoftFail("unable to listen on unix socket '%s': %s", sockfile, err)
+		config.SoftFail("unable to listen on socket '%s': %s", sockfile, err)
 	}
 
 	bgs.wg.Add(1) // cleanup will block until this is done
@@ -136,6 +391,16 @@ func createBgServer(ctx context.Context, sockfile string, span *tracepb.Span) *b
 	return &bgs
 }
 
+// resume replays entries from a journal on the background span, then has it
+// record the RPCs it gets from then on to journal. Must be called before Run.
+func (bgs *bgServer) resume(entries []bgJournalEntry, journal *bgJournal) error {
+	if err := bgs.bgspan.replay(entries); err != nil {
+		return err
+	}
+	bgs.bgspan.journal = journal
+	return nil
+}
+
 // Run will block until shutdown, accepting connections and processing them.
 func (bgs *bgServer) Run() {
 	// TODO: add controls to exit loop
@@ -159,44 +424,58 @@ func (bgs *bgServer) Run() {
 	}
 }
 
+// ChildSpans returns the child spans started over RPC. Children that were never
+// ended get the provided end time.
+func (bgs *bgServer) ChildSpans(end time.Time) []*tracepb.Span {
+	children := bgs.children.all()
+	for _, child := range children {
+		if child.EndTimeUnixNano == 0 {
+			child.EndTimeUnixNano = uint64(end.UnixNano())
+		}
+	}
+	return children
+}
+
+// lockSpan holds off RPCs that change the background span until the
+// returned func is called.
+func (bgs *bgServer) lockSpan() func() {
+	bgs.bgspan.spanMu.Lock()
+	return bgs.bgspan.spanMu.Unlock
+}
+
 // Shutdown does a controlled shutdown of the background server. Blocks until
-// the server is turned down cleanly and it's safe to exit.
+// the server is turned down cleanly and it's safe to exit. The span ending,
+// a signal, and the timeout can all call it, so only the first one stops the
+// server.
 func (bgs *bgServer) Shutdown() {
-	os.Remove(bgs.sockfile)
-	close(bgs.quit)
-	bgs.listener.Close()
+	bgs.stopOnce.Do(func() {
+		os.Remove(bgs.sockfile)
+		close(bgs.quit)
+		bgs.listener.Close()
+	})
 	bgs.wg.Wait()
 }
 
-// createBgClient sets up a client connection to the unix socket jsonrpc server
-// and returns the rpc client handle and a shutdown function that should be
-// deferred.
+// createBgClient sets up a client connection to the jsonrpc server on the
+// unix socket or named pipe and returns the rpc client handle and a shutdown
+// function that should be deferred.
 func createBgClient(config Config) (*rpc.Client, func()) {
-	sockfile := path.Join(config.BackgroundSockdir, spanBgSockfilename)
+	sockfile := config.GetBackgroundSockfile()
 	started := time.Now()
 	timeout := config.ParseCliTimeout()
 
-	// wait for the socket file to show up, polling every 25ms until it does or timeout
+	// wait for the server to show up, polling every 25ms until it does or timeout
 	for {
-		_, err := os.Stat(sockfile)
-		if os.IsNotExist(err) {
-			time.Sleep(time.Millisecond * 25) // sleep 25ms between checks
-		} else if err != nil {
-			config.SoftFail("failed to stat file '%s': %s", sockfile, err)
-		} else {
-			break
+		conn, err := bgDial(sockfile)
+		if err == nil {
+			return jsonrpc.NewClient(conn), func() { conn.Close() }
+		} else if !errors.Is(err, errBgNotListening) {
+			config.SoftFail("unable to connect to span background server at '%s': %s", sockfile, err)
 		}
+		time.Sleep(time.Millisecond * 25)
 
 		if timeout > 0 && time.Since(started) > timeout {
-			config.SoftFail("timeout after %s while waiting for span background socket '%s' to appear", config.Timeout, sockfile)
+			config.SoftFail("timeout after %s while waiting for span background server at '%s'", config.Timeout, sockfile)
 		}
 	}
-
-	sock := net.UnixAddr{Name: sockfile, Net: "unix"}
-	conn, err := net.DialUnix(sock.Net, nil, &sock)
-	if err != nil {
-		config.SoftFail("unable to connect to span background server at '%s': %s", config.BackgroundSockdir, err)
-	}
-
-	return jsonrpc.NewClient(conn), func() { conn.Close() }
 }
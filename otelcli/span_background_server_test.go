@@ -0,0 +1,221 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestBgSpanChildren(t *testing.T) {
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	span := config.NewProtobufSpan()
+	bgs := bgServer{children: &bgChildren{spans: make(map[string]*tracepb.Span)}}
+	bs := BgSpan{config: config, span: span, spanMu: &sync.Mutex{}, children: bgs.children}
+	now := time.Now().Format(time.RFC3339Nano)
+
+	outer := BgSpan{}
+	err := bs.StartChild(&BgChildStart{Name: "outer", Kind: "internal", Under: "background", Timestamp: now}, &outer)
+	if err != nil {
+		t.Fatalf("StartChild failed: %s", err)
+	}
+
+	inner := BgSpan{}
+	err = bs.StartChild(&BgChildStart{Name: "inner", Under: outer.SpanID, Timestamp: now}, &inner)
+	if err != nil {
+		t.Fatalf("StartChild failed: %s", err)
+	}
+
+	err = bs.StartChild(&BgChildStart{Name: "orphan", Under: "0000000000000000", Timestamp: now}, &BgSpan{})
+	if err == nil {
+		t.Error("expected an error when starting a child under an unknown span id")
+	}
+
+	err = bs.EndChild(&BgChildEnd{SpanID: inner.SpanID, StatusCode: "error", Timestamp: now}, &BgSpan{})
+	if err != nil {
+		t.Fatalf("EndChild failed: %s", err)
+	}
+
+	children := bgs.ChildSpans(time.Now())
+	if len(children) != 2 {
+		t.Fatalf("expected 2 child spans but got %d", len(children))
+	}
+
+	if !bytes.Equal(children[0].ParentSpanId, span.SpanId) {
+		t.Error("outer child span should be parented to the background span")
+	}
+	if !bytes.Equal(children[1].ParentSpanId, children[0].SpanId) {
+		t.Error("inner child span should be parented to the outer child span")
+	}
+	if !bytes.Equal(children[1].TraceId, span.TraceId) {
+		t.Error("child spans should share the background span's trace id")
+	}
+	if children[0].EndTimeUnixNano == 0 {
+		t.Error("unended child spans should get an end time from ChildSpans")
+	}
+	if children[1].Status.Code != otlpclient.SpanStatusStringToInt("error") {
+		t.Error("EndChild should set the span status")
+	}
+}
+
+func TestBgSpanJournal(t *testing.T) {
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	span := config.NewProtobufSpan()
+	span.Name = "journaled"
+	path := filepath.Join(t.TempDir(), "journal")
+	now := time.Now().Format(time.RFC3339Nano)
+
+	journal, err := createBgJournal(path, span)
+	if err != nil {
+		t.Fatalf("createBgJournal failed: %s", err)
+	}
+	children := &bgChildren{spans: make(map[string]*tracepb.Span)}
+	bs := BgSpan{config: config, span: span, spanMu: &sync.Mutex{}, children: children, journal: journal, shutdown: func() {}}
+
+	child := BgSpan{}
+	calls := []error{
+		bs.AddEvent(&BgSpanEvent{Name: "something happened", Timestamp: now}, &BgSpan{}),
+		bs.Update(&BgUpdate{Name: "renamed", Attributes: map[string]string{"foo": "bar"}}, &BgSpan{}),
+		bs.StartChild(&BgChildStart{Name: "child", Timestamp: now}, &child),
+	}
+	calls = append(calls, bs.EndChild(&BgChildEnd{SpanID: child.SpanID, Timestamp: now}, &BgSpan{}))
+	// simulate a crash by never calling End and abandoning the journal
+	journal.close()
+	for _, err := range calls {
+		if err != nil {
+			t.Fatalf("RPC failed: %s", err)
+		}
+	}
+
+	// a line cut short by the crash is ignored
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	f.WriteString(`{"method":"AddEv`)
+	f.Close()
+
+	rspan, entries, end, err := readBgJournal(path)
+	if err != nil {
+		t.Fatalf("readBgJournal failed: %s", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 journal entries but got %d", len(entries))
+	}
+
+	// resuming cuts the torn line off so what's recorded next can be read back
+	rjournal, err := openBgJournal(path, end)
+	if err != nil {
+		t.Fatalf("openBgJournal failed: %s", err)
+	}
+	if err := rjournal.record("AddEvent", &BgSpanEvent{Name: "resumed", Timestamp: now}); err != nil {
+		t.Fatalf("record failed: %s", err)
+	}
+	rjournal.close()
+	if _, rentries, _, err := readBgJournal(path); err != nil || len(rentries) != 5 {
+		t.Fatalf("expected 5 journal entries after resuming but got %d: %v", len(rentries), err)
+	}
+	if rspan.Name != "journaled" || !bytes.Equal(rspan.SpanId, span.SpanId) {
+		t.Errorf("expected the journal to start with the span as it started, got %q %x", rspan.Name, rspan.SpanId)
+	}
+
+	rbgs := bgServer{children: &bgChildren{spans: make(map[string]*tracepb.Span)}}
+	rbgs.bgspan = &BgSpan{config: config, span: rspan, spanMu: &sync.Mutex{}, children: rbgs.children, shutdown: func() {}}
+	if err := rbgs.resume(entries, nil); err != nil {
+		t.Fatalf("resume failed: %s", err)
+	}
+
+	if rspan.Name != "renamed" {
+		t.Errorf("expected replayed update to rename the span, got %q", rspan.Name)
+	}
+	if len(rspan.Events) != 1 || rspan.Events[0].Name != "something happened" {
+		t.Errorf("expected the replayed event on the span, got %v", rspan.Events)
+	}
+	if attrs := otlpclient.SpanAttributesToStringMap(rspan); attrs["foo"] != "bar" {
+		t.Errorf("expected replayed attributes on the span, got %v", attrs)
+	}
+	rchildren := rbgs.ChildSpans(time.Now())
+	if len(rchildren) != 1 || hex.EncodeToString(rchildren[0].SpanId) != child.SpanID {
+		t.Errorf("expected the child span to be replayed with span id %s", child.SpanID)
+	}
+}
+
+// the client side waits on errBgNotListening until the server is up, then
+// bytes go both ways, on a Unix socket or a named pipe depending on the OS
+func TestBgListenDial(t *testing.T) {
+	sockfile := filepath.Join(t.TempDir(), spanBgSockfilename)
+	if _, err := bgDial(sockfile); !errors.Is(err, errBgNotListening) {
+		t.Fatalf("expected errBgNotListening before the server is up, got %v", err)
+	}
+
+	listener, err := bgListen(sockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.Copy(conn, conn)
+	}()
+
+	conn, err := bgDial(sockfile)
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("expected ping back, got %q, %v", buf, err)
+	}
+}
+
+func TestBgSpanConcurrentRPCs(t *testing.T) {
+	// each connection gets its own goroutine, so RPCs can land at once
+	config := DefaultConfig().WithEndpoint("localhost:4317")
+	span := config.NewProtobufSpan()
+	bs := BgSpan{config: config, span: span, spanMu: &sync.Mutex{}, children: &bgChildren{spans: make(map[string]*tracepb.Span)}}
+	now := time.Now().Format(time.RFC3339Nano)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bs.AddEvent(&BgSpanEvent{Name: "event", Timestamp: now}, &BgSpan{})
+			bs.Update(&BgUpdate{Attributes: map[string]string{fmt.Sprint("attr", i): "set"}}, &BgSpan{})
+			bs.StartChild(&BgChildStart{Name: "child", Timestamp: now}, &BgSpan{})
+		}(i)
+	}
+	wg.Wait()
+
+	if len(span.Events) != 10 || len(bs.children.all()) != 10 {
+		t.Errorf("expected every RPC's changes on the span, got %d events and %d children", len(span.Events), len(bs.children.all()))
+	}
+	if attrs := otlpclient.SpanAttributesToStringMap(span); len(attrs) < 10 {
+		t.Errorf("expected every update's attribute on the span, got %v", attrs)
+	}
+}
+
+func TestBgServerShutdownTwice(t *testing.T) {
+	// the span ending, a signal, and the timeout can all shut down the server
+	sockfile := filepath.Join(t.TempDir(), spanBgSockfilename)
+	listener, err := bgListen(sockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bgs := bgServer{sockfile: sockfile, listener: listener, quit: make(chan struct{})}
+	bgs.Shutdown()
+	bgs.Shutdown()
+}
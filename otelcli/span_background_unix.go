@@ -0,0 +1,49 @@
+//go:build !windows
+
+package otelcli
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// bgListen listens for span background clients on a Unix socket at sockfile.
+func bgListen(sockfile string) (net.Listener, error) {
+	return net.Listen("unix", sockfile)
+}
+
+// bgDial connects to the span background server's Unix socket. The error
+// is errBgNotListening when the server isn't up yet.
+func bgDial(sockfile string) (net.Conn, error) {
+	if _, err := os.Stat(sockfile); os.IsNotExist(err) {
+		return nil, errBgNotListening
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to stat file '%s': %w", sockfile, err)
+	}
+
+	conn, err := net.Dial("unix", sockfile)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		// the socket file shows up a moment before the server is listening
+		return nil, errBgNotListening
+	}
+	return conn, err
+}
+
+// watchParent returns a channel that's closed when otel-cli's parent process
+// exits. Orphans get a new parent on Unix-ish systems, so getppid is polled
+// every interval until it changes.
+func watchParent(interval time.Duration) <-chan struct{} {
+	exited := make(chan struct{})
+	ppid := os.Getppid()
+	go func() {
+		for os.Getppid() == ppid {
+			time.Sleep(interval)
+		}
+		close(exited)
+	}()
+	return exited
+}
@@ -0,0 +1,70 @@
+//go:build windows
+
+package otelcli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Microsoft/go-winio"
+	"golang.org/x/sys/windows"
+)
+
+// bgPipeName returns the named pipe for the socket file's path. Pipes don't
+// live in the filesystem, so the path is hashed to keep each sockdir and
+// --span-handle apart.
+func bgPipeName(sockfile string) string {
+	if abs, err := filepath.Abs(sockfile); err == nil {
+		sockfile = abs
+	}
+	sum := sha256.Sum256([]byte(sockfile))
+	return `\\.\pipe\otel-cli-background-` + hex.EncodeToString(sum[:8])
+}
+
+// bgListen listens for span background clients on a named pipe only the
+// current user can connect to, like a Unix socket in a private sockdir.
+func bgListen(sockfile string) (net.Listener, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the current user: %w", err)
+	}
+	return winio.ListenPipe(bgPipeName(sockfile), &winio.PipeConfig{
+		SecurityDescriptor: fmt.Sprintf("D:P(A;;GA;;;%s)", user.User.Sid),
+	})
+}
+
+// bgDial connects to the span background server's named pipe. The error is
+// errBgNotListening when the server isn't up yet.
+func bgDial(sockfile string) (net.Conn, error) {
+	conn, err := winio.DialPipe(bgPipeName(sockfile), nil)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errBgNotListening
+	}
+	return conn, err
+}
+
+// watchParent returns a channel that's closed when otel-cli's parent process
+// exits. Windows doesn't reparent orphans, so instead of polling getppid this
+// waits on a handle to the parent and the interval isn't used.
+func watchParent(interval time.Duration) <-chan struct{} {
+	exited := make(chan struct{})
+	handle, err := windows.OpenProcess(windows.SYNCHRONIZE, false, uint32(os.Getppid()))
+	if err != nil {
+		// the parent is already gone
+		close(exited)
+		return exited
+	}
+	go func() {
+		defer windows.CloseHandle(handle)
+		windows.WaitForSingleObject(handle, windows.INFINITE)
+		close(exited)
+	}()
+	return exited
+}
@@ -1,7 +1,7 @@
 package otelcli
 
 import (
-	"os"
+	"time"
 
 	"github.com/equinix-labs/otel-cli/w3c/traceparent"
 	"github.com/spf13/cobra"
@@ -18,6 +18,11 @@ See: otel-cli span background
 
 	otel-cli span end --sockdir $sockdir \
 		--attrs "output.length=$(wc -l < output.txt | sed -e 's/^[[:space:]]*//')
+
+Child spans created with otel-cli span start are ended with --child, which
+leaves the background span running:
+
+	otel-cli span end --sockdir $sockdir --child $span_id --status-code ok
 `,
 		Run: doSpanEnd,
 	}
@@ -28,22 +33,31 @@ See: otel-cli span background
 	// TODO
 	//cmd.Flags().StringVar(&config.Timeout, "timeout", defaults.Timeout, "timeout for otel-cli operations, all timeouts in otel-cli use this value")
 	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", defaults.BackgroundSockdir, "a directory where a socket can be placed safely")
+	cmd.Flags().StringVar(&config.BackgroundSpanHandle, "span-handle", defaults.BackgroundSpanHandle, "name of the background span to use, so several can share one sockdir")
 	cmd.MarkFlagRequired("sockdir")
 
 	cmd.Flags().StringVar(&config.SpanEndTime, "end", defaults.SpanEndTime, "an Unix epoch or RFC3339 timestamp for the end of the span")
+	cmd.Flags().StringVar(&config.BackgroundChildSpanId, "child", defaults.BackgroundChildSpanId, "end the child span with this id instead of the background span")
 
 	addSpanStatusParams(&cmd, config)
 	addAttrParams(&cmd, config)
+	addLinkParams(&cmd, config)
 
 	return &cmd
 }
 
 func doSpanEnd(cmd *cobra.Command, args []string) {
 	config := getConfig(cmd.Context())
+	if config.BackgroundChildSpanId != "" {
+		doSpanEndChild(config)
+		return
+	}
+
 	client, shutdown := createBgClient(config)
 
 	rpcArgs := BgEnd{
 		Attributes: config.Attributes,
+		Links:      config.Links,
 		StatusCode: config.StatusCode,
 		StatusDesc: config.StatusDescription,
 	}
@@ -56,7 +70,33 @@ func doSpanEnd(cmd *cobra.Command, args []string) {
 	shutdown()
 
 	tp, _ := traceparent.Parse(res.Traceparent)
-	if config.TraceparentPrint {
-		tp.Fprint(os.Stdout, config.TraceparentPrintExport)
+	if config.GetTraceparentPrint() {
+		config.PrintTraceparent(tp, config.getStdout())
+	}
+}
+
+// doSpanEndChild ends a child span that was created with otel-cli span start.
+func doSpanEndChild(config Config) {
+	client, shutdown := createBgClient(config)
+	defer shutdown()
+
+	rpcArgs := BgChildEnd{
+		SpanID:     config.BackgroundChildSpanId,
+		Timestamp:  config.ParseSpanEndTime().Format(time.RFC3339Nano),
+		Attributes: config.Attributes,
+		Links:      config.Links,
+		StatusCode: config.StatusCode,
+		StatusDesc: config.StatusDescription,
+	}
+
+	res := BgSpan{}
+	err := client.Call("BgSpan.EndChild", rpcArgs, &res)
+	if err != nil {
+		config.SoftFail("error while calling background server rpc BgSpan.EndChild: %s", err)
+	}
+
+	tp, _ := traceparent.Parse(res.Traceparent)
+	if config.GetTraceparentPrint() {
+		config.PrintTraceparent(tp, config.getStdout())
 	}
 }
@@ -1,9 +1,10 @@
 package otelcli
 
 import (
-	"os"
+	"context"
 	"time"
 
+	"github.com/equinix-labs/otel-cli/otlpclient"
 	"github.com/equinix-labs/otel-cli/w3c/traceparent"
 	"github.com/spf13/cobra"
 )
@@ -12,9 +13,10 @@ import (
 func spanEventCmd(config *Config) *cobra.Command {
 	cmd := cobra.Command{
 		Use:   "event",
-		Short: "create an OpenTelemetry span event and add it to the background span",
+		Short: "create an OpenTelemetry span event on the background span or a span of its own",
 		Long: `Create an OpenTelemetry span event as specified and send it out.
 
+With --sockdir, the event is added to the background span running there.
 See: otel-cli span background
 
     sd=$(mktemp -d)
@@ -24,6 +26,16 @@ See: otel-cli span background
 		--name "did a cool thing" \
 		--time $(date +%s.%N) \
 		--attrs "os.kernel=$(uname -r)"
+
+Without --sockdir, the event is sent on a zero-duration span of its own, a
+child of the traceparent from --tp, TRACEPARENT, or --tp-carrier, so scripts
+can record a point-in-time occurrence without managing a background span.
+To put it on a span id of your choosing instead, use --force-span-id.
+
+	otel-cli span event \
+		--tp $TRACEPARENT \
+		--name "cache flushed" \
+		--attrs "entries=42"
 `,
 		Run: doSpanEvent,
 	}
@@ -32,26 +44,39 @@ See: otel-cli span background
 
 	cmd.Flags().SortFlags = false
 
-	cmd.Flags().BoolVar(&config.Verbose, "verbose", defaults.Verbose, "print errors on failure instead of always being silent")
-	// TODO
-	//spanEventCmd.Flags().StringVar(&config.Timeout, "timeout", defaults.Timeout, "timeout for otel-cli operations, all timeouts in otel-cli use this value")
 	cmd.Flags().StringVarP(&config.EventName, "name", "e", defaults.EventName, "set the name of the event")
 	cmd.Flags().StringVarP(&config.EventTime, "time", "t", defaults.EventTime, "the precise time of the event in RFC3339Nano or Unix.nano format")
-	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", "", "a directory where a socket can be placed safely")
-	cmd.MarkFlagRequired("sockdir")
+	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", "", "a directory where a socket can be placed safely, leave unset to send the event on its own span")
+	cmd.Flags().StringVar(&config.BackgroundSpanHandle, "span-handle", defaults.BackgroundSpanHandle, "name of the background span to use, so several can share one sockdir")
+
+	// without --sockdir, the event's span is configured like any other
+	cmd.Flags().StringVar(&config.Traceparent, "tp", defaults.Traceparent, "the W3C traceparent of the event span's parent, overriding TRACEPARENT and --tp-carrier")
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the traces")
+	cmd.Flags().StringVar(&config.ForceTraceId, "force-trace-id", defaults.ForceTraceId, "expert: force the trace id to be the one provided in hex")
+	cmd.Flags().StringVar(&config.ForceSpanId, "force-span-id", defaults.ForceSpanId, "expert: send the event on this span id instead of a new one")
+	cmd.Flags().StringVar(&config.ForceParentSpanId, "force-parent-span-id", defaults.ForceParentSpanId, "expert: force the parent span id to be the one provided in hex")
 
+	addCommonParams(&cmd, config)
 	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addClientParams(&cmd, config)
 
 	return &cmd
 }
 
 func doSpanEvent(cmd *cobra.Command, args []string) {
 	config := getConfig(cmd.Context())
+	if config.BackgroundSockdir == "" {
+		doStandaloneSpanEvent(cmd.Context(), config)
+		return
+	}
+
 	timestamp := config.ParsedEventTime()
 	rpcArgs := BgSpanEvent{
-		Name:       config.EventName,
-		Timestamp:  timestamp.Format(time.RFC3339Nano),
-		Attributes: config.Attributes,
+		Name:            config.EventName,
+		Timestamp:       timestamp.Format(time.RFC3339Nano),
+		Attributes:      config.Attributes,
+		AttributesBytes: config.AttributesBytes,
 	}
 
 	res := BgSpan{}
@@ -62,11 +87,42 @@ func doSpanEvent(cmd *cobra.Command, args []string) {
 		config.SoftFail("error while calling background server rpc BgSpan.AddEvent: %s", err)
 	}
 
-	if config.TraceparentPrint {
+	if config.GetTraceparentPrint() {
 		tp, err := traceparent.Parse(res.Traceparent)
 		if err != nil {
 			config.SoftFail("Could not parse traceparent: %s", err)
 		}
-		tp.Fprint(os.Stdout, config.TraceparentPrintExport)
+		config.PrintTraceparent(tp, config.getStdout())
+	}
+}
+
+// doStandaloneSpanEvent sends the event on a zero-duration span named after
+// it, at the event's time. The attributes go on the event, not the span.
+func doStandaloneSpanEvent(ctx context.Context, config Config) {
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+
+	attrs := config.ParseAttributes()
+	timestamp := uint64(config.ParsedEventTime().UnixNano())
+
+	ctx, client := StartClient(ctx, config)
+	span := config.withoutAttributes().NewProtobufSpan()
+	span.Name = config.EventName
+	span.StartTimeUnixNano = timestamp
+	span.EndTimeUnixNano = timestamp
+
+	event := otlpclient.NewProtobufSpanEvent()
+	event.Name = config.EventName
+	event.TimeUnixNano = timestamp
+	event.Attributes = attrs
+	span.Events = append(span.Events, event)
+
+	ctx, err := otlpclient.SendSpans(ctx, client, config, config.sampledSpans(span))
+	if err != nil {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("unable to send span: %s", err)
 	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+	config.PropagateTraceparent(span, config.getStdout())
 }
@@ -0,0 +1,29 @@
+package otelcli
+
+import (
+	"context"
+	"os"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// WriteSpanJsonOut writes the spans to --span-json-out as an OTLP/JSON export
+// request, exactly as they're about to be sent. It's called before export so
+// the file is there even when export fails, and otel-cli span send can send
+// it again later. Nothing is written for spans that weren't sampled.
+func (c Config) WriteSpanJsonOut(ctx context.Context, spans ...*tracepb.Span) {
+	if c.SpanJsonOut == "" || len(spans) == 0 {
+		return
+	}
+
+	rsps, err := otlpclient.NewResourceSpans(ctx, c, spans)
+	c.SoftFailIfErr(err)
+
+	js, err := otlpclient.MarshalOtlpJson(&coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+	c.SoftFailIfErr(err)
+
+	err = os.WriteFile(c.SpanJsonOut, append(js, '\n'), 0600)
+	c.SoftFailIfErr(err)
+}
@@ -0,0 +1,52 @@
+package otelcli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+)
+
+func TestWriteSpanJsonOut(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "span.json")
+	config := DefaultConfig().
+		WithEndpoint("localhost:4317").
+		WithServiceName("audited").
+		WithSpanName("deploy").
+		WithStatusCode("error").
+		WithSpanJsonOut(file)
+	ctx := context.Background()
+
+	span := config.NewProtobufSpan()
+	span.Attributes = otlpclient.StringMapAttrsToProtobuf(map[string]string{"env": "prod"})
+	config.WriteSpanJsonOut(ctx, span)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("expected --span-json-out to be written: %s", err)
+	}
+	// ids are hex in OTLP/JSON, not the base64 of the protobuf JSON mapping
+	if !strings.Contains(string(data), `"traceId":"`+otlpclient.TraceparentFromProtobufSpan(span, true).TraceIdString()+`"`) {
+		t.Errorf("expected a hex trace id in %s", string(data))
+	}
+
+	// what was written can be sent again with span send
+	rsps, err := config.parseSpanFile(ctx, data)
+	if err != nil {
+		t.Fatalf("unexpected error reading back --span-json-out: %s", err)
+	}
+	got := rsps[0].ScopeSpans[0].Spans[0]
+	if !bytes.Equal(got.TraceId, span.TraceId) || !bytes.Equal(got.SpanId, span.SpanId) {
+		t.Errorf("ids didn't survive the round trip, expected %x/%x but got %x/%x", span.TraceId, span.SpanId, got.TraceId, got.SpanId)
+	}
+	if got.Name != "deploy" || got.StartTimeUnixNano != span.StartTimeUnixNano || got.Status.GetCode() != span.Status.GetCode() {
+		t.Errorf("span didn't survive the round trip: %v", got)
+	}
+	if svc := otlpclient.AnyValueToString(rsps[0].Resource.Attributes[0].Value); svc != "audited" {
+		t.Errorf("expected the resource to be kept but got service %q", svc)
+	}
+}
@@ -0,0 +1,131 @@
+package otelcli
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// printedSpan is the JSON object --print-json writes for a span. It's meant
+// for scripts, so unlike --span-json-out it's flat with plain values instead
+// of OTLP/JSON.
+type printedSpan struct {
+	TraceId           string                 `json:"trace_id"`
+	SpanId            string                 `json:"span_id"`
+	ParentSpanId      string                 `json:"parent_span_id,omitempty"`
+	Traceparent       string                 `json:"traceparent"`
+	Name              string                 `json:"name"`
+	Kind              string                 `json:"kind"`
+	StartTime         string                 `json:"start_time"`
+	EndTime           string                 `json:"end_time"`
+	StartTimeUnixNano uint64                 `json:"start_time_unix_nano"`
+	EndTimeUnixNano   uint64                 `json:"end_time_unix_nano"`
+	DurationMs        float64                `json:"duration_ms"`
+	Attributes        map[string]interface{} `json:"attributes"`
+	StatusCode        string                 `json:"status_code"`
+	StatusDescription string                 `json:"status_description,omitempty"`
+}
+
+// newPrintedSpan converts a span to what --print-json writes.
+func newPrintedSpan(span *tracepb.Span, recording bool) printedSpan {
+	out := printedSpan{
+		TraceId:           hex.EncodeToString(span.TraceId),
+		SpanId:            hex.EncodeToString(span.SpanId),
+		Traceparent:       otlpclient.TraceparentFromProtobufSpan(span, recording).Encode(),
+		Name:              span.Name,
+		Kind:              otlpclient.SpanKindIntToString(span.Kind),
+		StartTime:         time.Unix(0, int64(span.StartTimeUnixNano)).UTC().Format(time.RFC3339Nano),
+		EndTime:           time.Unix(0, int64(span.EndTimeUnixNano)).UTC().Format(time.RFC3339Nano),
+		StartTimeUnixNano: span.StartTimeUnixNano,
+		EndTimeUnixNano:   span.EndTimeUnixNano,
+		Attributes:        map[string]interface{}{},
+		StatusCode:        "unset",
+	}
+
+	if len(span.ParentSpanId) > 0 {
+		out.ParentSpanId = hex.EncodeToString(span.ParentSpanId)
+	}
+	if span.EndTimeUnixNano > span.StartTimeUnixNano {
+		out.DurationMs = float64(span.EndTimeUnixNano-span.StartTimeUnixNano) / float64(time.Millisecond)
+	}
+	for _, kv := range span.Attributes {
+		out.Attributes[kv.Key] = anyValueToJson(kv.Value)
+	}
+	if span.Status != nil {
+		out.StatusCode = strings.ToLower(strings.TrimPrefix(span.Status.Code.String(), "STATUS_CODE_"))
+		out.StatusDescription = span.Status.Message
+	}
+
+	return out
+}
+
+// anyValueToJson returns the attribute value as the closest plain JSON type,
+// so numbers and bools don't have to be parsed back out of strings.
+func anyValueToJson(v *commonpb.AnyValue) interface{} {
+	switch tv := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return tv.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return tv.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return tv.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return tv.DoubleValue
+	case *commonpb.AnyValue_ArrayValue:
+		out := make([]interface{}, len(tv.ArrayValue.GetValues()))
+		for i, av := range tv.ArrayValue.GetValues() {
+			out[i] = anyValueToJson(av)
+		}
+		return out
+	default:
+		return otlpclient.AnyValueToString(v)
+	}
+}
+
+// PrintSpanJson writes the span as a single line of JSON to target, or to
+// --print-json-fd when set, if --print-json is on. Like --tp-print, a failed
+// write is logged and only fails the command when --fail is set.
+func (c Config) PrintSpanJson(span *tracepb.Span, target io.Writer) {
+	if !c.PrintJson && c.PrintJsonFd == 0 {
+		return
+	}
+
+	var err error
+	if c.PrintJsonFd < 0 {
+		err = fmt.Errorf("invalid --print-json-fd %d", c.PrintJsonFd)
+	} else if c.PrintJsonFd == 1 {
+		target = c.getStdout()
+	} else if c.PrintJsonFd == 2 {
+		target = c.getStderr()
+	} else if c.PrintJsonFd > 0 {
+		var file *os.File
+		file, err = openPrintFd(c.PrintJsonFd)
+		if err == nil {
+			defer file.Close()
+			target = file
+		}
+	}
+
+	if err == nil {
+		var js []byte
+		js, err = json.Marshal(newPrintedSpan(span, c.GetIsRecording() && c.GetIsSampled(span.TraceId)))
+		if err == nil {
+			_, err = target.Write(append(js, '\n'))
+		}
+	}
+
+	if err != nil {
+		c.SoftLog("failed to print span json: %s", err)
+		if c.Fail {
+			c.exit(1)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package otelcli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestPrintSpanJson(t *testing.T) {
+	span := &tracepb.Span{
+		TraceId:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:            []byte{1, 1, 1, 1, 1, 1, 1, 1},
+		ParentSpanId:      []byte{2, 2, 2, 2, 2, 2, 2, 2},
+		Name:              "deploy",
+		Kind:              tracepb.Span_SPAN_KIND_SERVER,
+		StartTimeUnixNano: 1700000000000000000,
+		EndTimeUnixNano:   1700000001500000000,
+		Attributes: append(otlpclient.StringMapAttrsToProtobuf(map[string]string{"env": "prod"}),
+			&commonpb.KeyValue{Key: "retries", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 2}}},
+			&commonpb.KeyValue{Key: "cached", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+		),
+		Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timed out"},
+	}
+
+	// off by default
+	var out bytes.Buffer
+	DefaultConfig().PrintSpanJson(span, &out)
+	if out.Len() != 0 {
+		t.Errorf("expected nothing printed without --print-json, got %q", out.String())
+	}
+
+	DefaultConfig().WithEndpoint("localhost:4317").WithPrintJson(true).PrintSpanJson(span, &out)
+	if !strings.HasSuffix(out.String(), "}\n") || strings.Count(out.String(), "\n") != 1 {
+		t.Errorf("expected a single line of json, got %q", out.String())
+	}
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("--print-json output is not json: %s", err)
+	}
+	expect := map[string]interface{}{
+		"trace_id":             "0102030405060708090a0b0c0d0e0f10",
+		"span_id":              "0101010101010101",
+		"parent_span_id":       "0202020202020202",
+		"traceparent":          "00-0102030405060708090a0b0c0d0e0f10-0101010101010101-01",
+		"name":                 "deploy",
+		"kind":                 "server",
+		"start_time":           "2023-11-14T22:13:20Z",
+		"end_time":             "2023-11-14T22:13:21.5Z",
+		"start_time_unix_nano": float64(1700000000000000000),
+		"end_time_unix_nano":   float64(1700000001500000000),
+		"duration_ms":          float64(1500),
+		"status_code":          "error",
+		"status_description":   "timed out",
+	}
+	for key, want := range expect {
+		if got[key] != want {
+			t.Errorf("expected %s to be %v but got %v", key, want, got[key])
+		}
+	}
+
+	// attribute values keep their types
+	attrs, _ := got["attributes"].(map[string]interface{})
+	if attrs["env"] != "prod" || attrs["retries"] != float64(2) || attrs["cached"] != true {
+		t.Errorf("unexpected attributes %v", attrs)
+	}
+}
@@ -0,0 +1,259 @@
+package otelcli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// spanFileEntry is one span in the simple format read by span send. Ids are
+// hex and times are strings in any format --start and --end accept.
+type spanFileEntry struct {
+	TraceId           string            `json:"trace_id"`
+	SpanId            string            `json:"span_id"`
+	ParentSpanId      string            `json:"parent_span_id"`
+	Name              string            `json:"name"`
+	Kind              string            `json:"kind"`
+	Start             string            `json:"start"`
+	End               string            `json:"end"`
+	Attributes        map[string]string `json:"attributes"`
+	StatusCode        string            `json:"status_code"`
+	StatusDescription string            `json:"status_description"`
+	Events            []spanFileEvent   `json:"events"`
+}
+
+// spanFileEvent is a span event in the simple format, also used by trace send.
+type spanFileEvent struct {
+	Name       string            `json:"name" yaml:"name"`
+	Time       string            `json:"time" yaml:"time"`
+	Attributes map[string]string `json:"attributes" yaml:"attributes"`
+}
+
+// spanSendCmd represents the span send command
+func spanSendCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "send",
+		Short: "send spans from a JSON file",
+		Long: `Send spans that were recorded earlier, e.g. in an air-gapped build, from a
+file or stdin. Trace and span ids and timestamps are kept as they are, and all
+of the spans are uploaded in one batch.
+
+The file can hold OTLP/JSON export requests, like otel-cli server json or the
+collector's file exporter write, or spans in this simpler format. Either can
+be newline-delimited, and simple spans can also be in a JSON array:
+
+	{"trace_id": "3433d5ae39bdfee397f44be5146867b3",
+	 "span_id": "8a5518f1e5c54d0a",
+	 "parent_span_id": "",
+	 "name": "compile",
+	 "kind": "internal",
+	 "start": "2024-03-24T07:28:05.12345Z",
+	 "end": "1711265290.241980634",
+	 "attributes": {"target": "linux"},
+	 "status_code": "ok",
+	 "status_description": "",
+	 "events": [{"name": "cache miss", "time": "1711265287", "attributes": {}}]}
+
+trace_id, span_id, name, and start are required. Simple spans are sent with
+otel-cli's resource and --service, OTLP/JSON keeps its own resources.
+
+Example:
+	otel-cli span send --from-file spans.jsonl
+	generate-spans | otel-cli span send --from-file -
+`,
+		Run: doSpanSend,
+	}
+
+	cmd.Flags().SortFlags = false
+
+	defaults := DefaultConfig()
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVar(&config.SpanSendFile, "from-file", defaults.SpanSendFile, "file to read spans from, - for stdin")
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on simple spans")
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doSpanSend(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+
+	if config.SpanSendFile == "" {
+		config.SoftFail("span send needs --from-file")
+	}
+
+	var data []byte
+	var err error
+	if config.SpanSendFile == "-" {
+		config.readStdin(func() { data, err = io.ReadAll(os.Stdin) })
+	} else {
+		data, err = os.ReadFile(config.SpanSendFile)
+	}
+	config.SoftFailIfErr(err)
+
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+
+	rsps, err := config.parseSpanFile(ctx, data)
+	config.SoftFailIfErr(err)
+
+	ctx, client := StartClient(ctx, config)
+	if len(rsps) > 0 && config.GetIsRecording() {
+		ctx, err = client.UploadTraces(ctx, rsps)
+		if err != nil {
+			config.SoftLogErrorList(ctx)
+			config.SoftFail("unable to send spans: %s", err)
+		}
+	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+}
+
+// parseSpanFile decodes every JSON document in data, which can be OTLP/JSON
+// export requests, simple spans, or arrays of simple spans. Simple spans are
+// put in one ResourceSpans after any from OTLP/JSON.
+func (c Config) parseSpanFile(ctx context.Context, data []byte) ([]*tracepb.ResourceSpans, error) {
+	rsps := []*tracepb.ResourceSpans{}
+	spans := []*tracepb.Span{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for doc := 1; ; doc++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d is not valid JSON: %w", doc, err)
+		}
+
+		entries := []spanFileEntry{}
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &entries); err != nil {
+				return nil, fmt.Errorf("document %d is not a list of spans: %w", doc, err)
+			}
+		} else {
+			var keys map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &keys); err != nil {
+				return nil, fmt.Errorf("document %d is not a JSON object: %w", doc, err)
+			}
+			_, isOtlp := keys["resourceSpans"]
+			if _, ok := keys["resource_spans"]; ok {
+				isOtlp = true
+			}
+
+			if isOtlp {
+				req := coltracepb.ExportTraceServiceRequest{}
+				if err := otlpclient.UnmarshalOtlpJson(raw, &req); err != nil {
+					return nil, fmt.Errorf("document %d: %w", doc, err)
+				}
+				rsps = append(rsps, req.GetResourceSpans()...)
+				continue
+			}
+
+			entry := spanFileEntry{}
+			if err := json.Unmarshal(raw, &entry); err != nil {
+				return nil, fmt.Errorf("document %d is not a span: %w", doc, err)
+			}
+			entries = append(entries, entry)
+		}
+
+		for i, entry := range entries {
+			span, err := c.spanFromFileEntry(entry)
+			if err != nil {
+				return nil, fmt.Errorf("document %d span %d: %w", doc, i+1, err)
+			}
+			spans = append(spans, span)
+		}
+	}
+
+	if len(spans) > 0 {
+		simple, err := otlpclient.NewResourceSpans(ctx, c, spans)
+		if err != nil {
+			return nil, err
+		}
+		rsps = append(rsps, simple...)
+	}
+
+	return rsps, nil
+}
+
+// spanFromFileEntry converts a simple span to protobuf, checking that the
+// required fields are there.
+func (c Config) spanFromFileEntry(entry spanFileEntry) (*tracepb.Span, error) {
+	span := otlpclient.NewProtobufSpan()
+
+	var err error
+	if span.TraceId, err = parseHex(entry.TraceId, 16); err != nil {
+		return nil, fmt.Errorf("trace_id: %w", err)
+	}
+	if span.SpanId, err = parseHex(entry.SpanId, 8); err != nil {
+		return nil, fmt.Errorf("span_id: %w", err)
+	}
+	if entry.ParentSpanId != "" {
+		if span.ParentSpanId, err = parseHex(entry.ParentSpanId, 8); err != nil {
+			return nil, fmt.Errorf("parent_span_id: %w", err)
+		}
+	}
+
+	if err := c.setSpanFromFileEntry(span, entry); err != nil {
+		return nil, err
+	}
+
+	return span, nil
+}
+
+// setSpanFromFileEntry sets everything but the ids on span from a simple
+// span, so trace send can use the same fields with ids of its own.
+func (c Config) setSpanFromFileEntry(span *tracepb.Span, entry spanFileEntry) error {
+	if entry.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if entry.Start == "" {
+		return fmt.Errorf("start is required")
+	}
+
+	span.Name = entry.Name
+	span.Kind = otlpclient.SpanKindStringToInt(entry.Kind)
+	span.Attributes = otlpclient.StringMapAttrsToProtobuf(entry.Attributes)
+	otlpclient.SetSpanStatus(span, entry.StatusCode, entry.StatusDescription)
+
+	start, err := c.parseTime(entry.Start, "start")
+	if err != nil {
+		return err
+	}
+	span.StartTimeUnixNano = uint64(start.UnixNano())
+	span.EndTimeUnixNano = span.StartTimeUnixNano
+	if entry.End != "" {
+		end, err := c.parseTime(entry.End, "end")
+		if err != nil {
+			return err
+		}
+		span.EndTimeUnixNano = uint64(end.UnixNano())
+	}
+
+	for _, e := range entry.Events {
+		event := otlpclient.NewProtobufSpanEvent()
+		event.Name = e.Name
+		event.TimeUnixNano = uint64(c.now().UnixNano())
+		event.Attributes = otlpclient.StringMapAttrsToProtobuf(e.Attributes)
+		if e.Time != "" {
+			t, err := c.parseTime(e.Time, "event")
+			if err != nil {
+				return err
+			}
+			event.TimeUnixNano = uint64(t.UnixNano())
+		}
+		span.Events = append(span.Events, event)
+	}
+
+	return nil
+}
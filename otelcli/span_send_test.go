@@ -0,0 +1,76 @@
+package otelcli
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestParseSpanFile(t *testing.T) {
+	config := DefaultConfig().WithServiceName("replay")
+	ctx := context.Background()
+
+	simple := `{"trace_id": "3433d5ae39bdfee397f44be5146867b3", "span_id": "8a5518f1e5c54d0a", "name": "compile",
+	 "kind": "client", "start": "1711265285.500000000", "end": "2024-03-24T07:28:10Z", "attributes": {"target": "linux"},
+	 "status_code": "error", "events": [{"name": "cache miss", "time": "1711265287"}]}
+[{"trace_id": "3433d5ae39bdfee397f44be5146867b3", "span_id": "c2b1f6c3a3e5c9d1",
+  "parent_span_id": "8a5518f1e5c54d0a", "name": "link", "start": "1711265289"}]
+`
+	otlp := `{"resourceSpans": [{"resource": {"attributes": [{"key": "service.name", "value": {"stringValue": "ci"}}]},
+	"scopeSpans": [{"spans": [{"traceId": "5bc3c2b1f6c3a3e5c9d1f1a2b3c4d5e6", "spanId": "0102030405060708",
+	"name": "from otlp", "startTimeUnixNano": "1711265285000000000", "endTimeUnixNano": "1711265286000000000"}]}]}]}
+`
+
+	rsps, err := config.parseSpanFile(ctx, []byte(otlp+simple))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rsps) != 2 {
+		t.Fatalf("expected 2 ResourceSpans but got %d", len(rsps))
+	}
+
+	otlpSpan := rsps[0].ScopeSpans[0].Spans[0]
+	if hex.EncodeToString(otlpSpan.TraceId) != "5bc3c2b1f6c3a3e5c9d1f1a2b3c4d5e6" || otlpSpan.StartTimeUnixNano != 1711265285000000000 {
+		t.Errorf("OTLP/JSON span was not kept as is: %v", otlpSpan)
+	}
+
+	spans := rsps[1].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 simple spans but got %d", len(spans))
+	}
+	compile, link := spans[0], spans[1]
+	if hex.EncodeToString(compile.SpanId) != "8a5518f1e5c54d0a" {
+		t.Errorf("span id was not kept, got %x", compile.SpanId)
+	}
+	if compile.StartTimeUnixNano != 1711265285500000000 || compile.EndTimeUnixNano != 1711265290000000000 {
+		t.Errorf("wrong times %d, %d", compile.StartTimeUnixNano, compile.EndTimeUnixNano)
+	}
+	if compile.Kind != tracepb.Span_SPAN_KIND_CLIENT || compile.Status.Code != tracepb.Status_STATUS_CODE_ERROR {
+		t.Errorf("wrong kind or status: %s, %s", compile.Kind, compile.Status.Code)
+	}
+	if len(compile.Events) != 1 || compile.Events[0].TimeUnixNano != 1711265287000000000 {
+		t.Errorf("wrong events: %v", compile.Events)
+	}
+	if hex.EncodeToString(link.ParentSpanId) != "8a5518f1e5c54d0a" || link.EndTimeUnixNano != link.StartTimeUnixNano {
+		t.Errorf("wrong parent or end on span without an end: %v", link)
+	}
+
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{`{"span_id": "8a5518f1e5c54d0a", "name": "x", "start": "now"}`, "document 1 span 1: trace_id"},
+		{`{"trace_id": "3433d5ae39bdfee397f44be5146867b3", "span_id": "8a5518f1e5c54d0a", "start": "now"}`, "name is required"},
+		{`{"trace_id": "3433d5ae39bdfee397f44be5146867b3", "span_id": "8a5518f1e5c54d0a", "name": "x"}`, "start is required"},
+		{"{}\n[{}, {\"name\": \"x\"}]", "document 1 span 1"},
+		{`{"name": `, "not valid JSON"},
+	} {
+		_, err := config.parseSpanFile(ctx, []byte(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("expected an error containing %q for %q but got %v", tc.want, tc.in, err)
+		}
+	}
+}
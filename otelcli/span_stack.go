@@ -0,0 +1,229 @@
+package otelcli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// spanStackEntry is one open span in the stack file. The service name is
+// kept so pop sends the span the same way push would have.
+type spanStackEntry struct {
+	ServiceName string          `json:"service_name"`
+	Span        json.RawMessage `json:"span"`
+}
+
+// spanPushCmd represents the span push command
+func spanPushCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "push",
+		Short: "start a span and push it onto a stack file",
+		Long: `Start a span without sending it and push it onto a stack file, like
+pushd. The span is a child of whatever span is on top of the stack, or of
+the usual TRACEPARENT/carrier when the stack is empty. Use otel-cli span pop
+to end and send it.
+
+The stack file defaults to one per parent process in the temp directory, so
+a shell script gets its own stack without any setup.
+
+Example:
+	otel-cli span push -n build
+	otel-cli span push -n compile
+	make
+	otel-cli span pop --status-code ok # sends compile
+	otel-cli span pop --status-code ok # sends build
+`,
+		Run: doSpanPush,
+	}
+
+	cmd.Flags().SortFlags = false
+
+	addCommonParams(&cmd, config)
+	addSpanParams(&cmd, config)
+	addSpanStackParams(&cmd, config)
+	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addClientParams(&cmd, config)
+
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&config.SpanStartTime, "start", defaults.SpanStartTime, "a Unix epoch or RFC3339 timestamp for the start of the span")
+
+	return &cmd
+}
+
+// spanPopCmd represents the span pop command
+func spanPopCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "pop",
+		Short: "end and send the span on top of a stack file",
+		Long: `End the most recent span started with otel-cli span push, send it, and
+remove it from the stack file, like popd. Attributes and status given to pop
+are added to the span.
+
+See: otel-cli span push
+`,
+		Run: doSpanPop,
+	}
+
+	cmd.Flags().SortFlags = false
+
+	addCommonParams(&cmd, config)
+	addSpanStatusParams(&cmd, config)
+	addSpanDurationParams(&cmd, config)
+	addSpanStackParams(&cmd, config)
+	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addTypedAttrParams(&cmd, config)
+	addClientParams(&cmd, config)
+
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&config.SpanEndTime, "end", defaults.SpanEndTime, "an Unix epoch or RFC3339 timestamp for the end of the span")
+
+	return &cmd
+}
+
+func addSpanStackParams(cmd *cobra.Command, config *Config) {
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&config.SpanStackFile, "stack-file", defaults.SpanStackFile, "file to keep the span stack in, defaults to one per parent process in the temp directory")
+}
+
+func doSpanPush(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	stackFile := config.GetSpanStackFile()
+	stack, err := loadSpanStack(stackFile)
+	config.SoftFailIfErr(err)
+
+	span := config.NewProtobufSpan()
+	span.EndTimeUnixNano = 0 // set by pop
+
+	// nest under the top of the stack, unless ids were forced
+	if len(stack) > 0 && config.GetIsRecording() {
+		parent, err := stack[len(stack)-1].span()
+		config.SoftFailIfErr(err)
+		if config.ForceTraceId == "" {
+			span.TraceId = parent.TraceId
+		}
+		if config.ForceParentSpanId == "" {
+			span.ParentSpanId = parent.SpanId
+		}
+	}
+
+	js, err := protojson.Marshal(span)
+	config.SoftFailIfErr(err)
+	stack = append(stack, spanStackEntry{ServiceName: config.ServiceName, Span: js})
+	config.SoftFailIfErr(saveSpanStack(stackFile, stack))
+
+	config.PropagateTraceparent(span, config.getStdout())
+}
+
+func doSpanPop(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+	stackFile := config.GetSpanStackFile()
+	stack, err := loadSpanStack(stackFile)
+	config.SoftFailIfErr(err)
+
+	if len(stack) == 0 {
+		config.SoftFail("span stack %q is empty, nothing to pop", stackFile)
+	}
+
+	top := stack[len(stack)-1]
+	span, err := top.span()
+	config.SoftFailIfErr(err)
+
+	span.EndTimeUnixNano = uint64(config.ParseSpanEndTime().UnixNano())
+	span.Attributes = append(span.Attributes, config.ParseAttributes()...)
+	otlpclient.SetSpanStatus(span, config.StatusCode, config.StatusDescription)
+	config.ApplyDurationRules(span)
+
+	// remove the span before sending so a failed send doesn't wedge the stack
+	config.SoftFailIfErr(saveSpanStack(stackFile, stack[:len(stack)-1]))
+
+	if top.ServiceName != "" {
+		config = config.WithServiceName(top.ServiceName)
+	}
+
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+	spans := config.sampledSpans(span)
+	config.WriteSpanJsonOut(ctx, spans...)
+	ctx, client := StartClient(ctx, config)
+	ctx, err = otlpclient.SendSpans(ctx, client, config, spans)
+	if err != nil {
+		config.SoftLogErrorList(ctx)
+		config.SoftFail("unable to send span: %s", err)
+	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+}
+
+// span decodes the protobuf span stored in the entry.
+func (e spanStackEntry) span() (*tracepb.Span, error) {
+	span := tracepb.Span{}
+	if err := protojson.Unmarshal(e.Span, &span); err != nil {
+		return nil, fmt.Errorf("failed to decode span from stack file: %w", err)
+	}
+	return &span, nil
+}
+
+// loadSpanStack reads the stack file. A missing file is an empty stack.
+func loadSpanStack(file string) ([]spanStackEntry, error) {
+	stack := []spanStackEntry{}
+
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return stack, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read span stack file %q: %w", file, err)
+	}
+
+	if err := json.Unmarshal(data, &stack); err != nil {
+		return nil, fmt.Errorf("failed to parse span stack file %q: %w", file, err)
+	}
+
+	return stack, nil
+}
+
+// saveSpanStack writes the stack file via a rename so readers never see a
+// partial file. An empty stack removes the file.
+func saveSpanStack(file string, stack []spanStackEntry) error {
+	if len(stack) == 0 {
+		err := os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove empty span stack file %q: %w", file, err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(stack)
+	if err != nil {
+		return fmt.Errorf("failed to encode span stack: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create span stack temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write span stack file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write span stack file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to replace span stack file %q: %w", file, err)
+	}
+
+	return nil
+}
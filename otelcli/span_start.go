@@ -0,0 +1,79 @@
+package otelcli
+
+import (
+	"time"
+
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/spf13/cobra"
+)
+
+// spanStartCmd represents the span start command
+func spanStartCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "start",
+		Short: "start a child span in a background span's trace",
+		Long: `Ask a running span background to start a new child span in its trace.
+The background server keeps track of the whole tree and sends the child spans
+along with the background span when it ends, so scripts don't have to pass
+carrier files around to build nested spans.
+
+See: otel-cli span background
+
+	sd=$(mktemp -d)
+	otel-cli span background --sockdir $sd &
+	build=$(otel-cli span start --sockdir $sd --name build --under background --tp-print | awk '/span id/{print $4}')
+	otel-cli span start --sockdir $sd --name compile --under $build
+	...
+	otel-cli span end --sockdir $sd --child $build --status-code ok
+`,
+		Run: doSpanStart,
+	}
+
+	defaults := DefaultConfig()
+
+	cmd.Flags().SortFlags = false
+
+	cmd.Flags().BoolVar(&config.Verbose, "verbose", defaults.Verbose, "print errors on failure instead of always being silent")
+	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", defaults.BackgroundSockdir, "a directory where a socket can be placed safely")
+	cmd.Flags().StringVar(&config.BackgroundSpanHandle, "span-handle", defaults.BackgroundSpanHandle, "name of the background span to use, so several can share one sockdir")
+	cmd.MarkFlagRequired("sockdir")
+	cmd.Flags().StringVar(&config.BackgroundUnder, "under", defaults.BackgroundUnder, "'background' or the span id of a child span to parent the new span to")
+	cmd.Flags().StringVarP(&config.SpanName, "name", "n", defaults.SpanName, "set the name of the span")
+	cmd.Flags().StringVarP(&config.Kind, "kind", "k", defaults.Kind, "set the trace kind, e.g. internal, server, client, producer, consumer")
+	cmd.Flags().StringVar(&config.SpanStartTime, "start", defaults.SpanStartTime, "a Unix epoch or RFC3339 timestamp for the start of the span")
+	cmd.Flags().BoolVar(&config.TraceparentPrint, "tp-print", defaults.TraceparentPrint, "print the trace id, span id, and the w3c-formatted traceparent representation of the new span")
+	cmd.Flags().BoolVarP(&config.TraceparentPrintExport, "tp-export", "p", defaults.TraceparentPrintExport, "same as --tp-print but it puts an 'export ' in front so it's more convinenient to source in scripts")
+	cmd.Flags().IntVar(&config.TraceparentPrintFd, "tp-print-fd", defaults.TraceparentPrintFd, "print the traceparent to this file descriptor instead of stdout, e.g. 3, implies --tp-print")
+	cmd.Flags().StringVar(&config.TraceparentPrintFile, "tp-print-file", defaults.TraceparentPrintFile, "write the traceparent to this file instead of stdout, replacing its contents, implies --tp-print")
+
+	addAttrParams(&cmd, config)
+
+	return &cmd
+}
+
+func doSpanStart(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	rpcArgs := BgChildStart{
+		Name:       config.SpanName,
+		Kind:       config.Kind,
+		Under:      config.BackgroundUnder,
+		Timestamp:  config.ParseSpanStartTime().Format(time.RFC3339Nano),
+		Attributes: config.Attributes,
+	}
+
+	res := BgSpan{}
+	client, shutdown := createBgClient(config)
+	defer shutdown()
+	err := client.Call("BgSpan.StartChild", rpcArgs, &res)
+	if err != nil {
+		config.SoftFail("error while calling background server rpc BgSpan.StartChild: %s", err)
+	}
+
+	if config.GetTraceparentPrint() || config.TraceparentPrintExport {
+		tp, err := traceparent.Parse(res.Traceparent)
+		if err != nil {
+			config.SoftFail("Could not parse traceparent: %s", err)
+		}
+		config.PrintTraceparent(tp, config.getStdout())
+	}
+}
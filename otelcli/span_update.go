@@ -0,0 +1,74 @@
+package otelcli
+
+import (
+	"github.com/equinix-labs/otel-cli/w3c/traceparent"
+	"github.com/spf13/cobra"
+)
+
+// spanUpdateCmd represents the span update command
+func spanUpdateCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "update",
+		Short: "change the name, attributes, or links of a background span while it runs",
+		Long: `Update a running background span. Long scripts can add what they learn
+along the way instead of saving it all for otel-cli span end. Attributes replace
+any with the same key, links are added to the ones the span already has.
+
+See: otel-cli span background
+
+	otel-cli span update --sockdir $sockdir \
+		--name "deploy $version" \
+		--attrs "deploy.version=$version,deploy.hosts=$(wc -l < hosts.txt)"
+`,
+		Run: doSpanUpdate,
+	}
+
+	defaults := DefaultConfig()
+
+	cmd.Flags().SortFlags = false
+
+	cmd.Flags().BoolVar(&config.Verbose, "verbose", defaults.Verbose, "print errors on failure instead of always being silent")
+	cmd.Flags().StringVarP(&config.SpanName, "name", "n", defaults.SpanName, "rename the span")
+	cmd.Flags().StringVar(&config.BackgroundSockdir, "sockdir", defaults.BackgroundSockdir, "a directory where a socket can be placed safely")
+	cmd.Flags().StringVar(&config.BackgroundSpanHandle, "span-handle", defaults.BackgroundSpanHandle, "name of the background span to use, so several can share one sockdir")
+	cmd.MarkFlagRequired("sockdir")
+
+	addAttrParams(&cmd, config)
+	addAttrBytesParams(&cmd, config)
+	addLinkParams(&cmd, config)
+
+	return &cmd
+}
+
+func doSpanUpdate(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	// --name defaults to the span name used everywhere else, only send it
+	// when it was set so updates don't rename the span by accident
+	name := ""
+	if cmd.Flags().Changed("name") {
+		name = config.SpanName
+	}
+
+	rpcArgs := BgUpdate{
+		Name:            name,
+		Attributes:      config.Attributes,
+		AttributesBytes: config.AttributesBytes,
+		Links:           config.Links,
+	}
+
+	res := BgSpan{}
+	client, shutdown := createBgClient(config)
+	defer shutdown()
+	err := client.Call("BgSpan.Update", rpcArgs, &res)
+	if err != nil {
+		config.SoftFail("error while calling background server rpc BgSpan.Update: %s", err)
+	}
+
+	if config.GetTraceparentPrint() {
+		tp, err := traceparent.Parse(res.Traceparent)
+		if err != nil {
+			config.SoftFail("Could not parse traceparent: %s", err)
+		}
+		config.PrintTraceparent(tp, config.getStdout())
+	}
+}
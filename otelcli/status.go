@@ -1,7 +1,6 @@
 package otelcli
 
 import (
-	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -24,6 +23,10 @@ type StatusOutput struct {
 	Env         map[string]string    `json:"env"`
 	Diagnostics Diagnostics          `json:"diagnostics"`
 	Errors      otlpclient.ErrorList `json:"errors"`
+	// only set with --probe-max-payload
+	PayloadProbe *PayloadProbe `json:"payload_probe,omitempty"`
+	// only set when at least one canary was sent
+	Canary *CanaryReport `json:"canary,omitempty"`
 }
 
 func statusCmd(config *Config) *cobra.Command {
@@ -36,16 +39,43 @@ By default just one canary is sent. When --canary-count is set, that number of c
 are sent. If --canary-interval is set, status will sleep the specified duration
 between canaries, up to --timeout (default 1s).
 
+Each canary's latency and result are reported under "canary" along with
+success and failure counts and p50/p95 latency of the canaries that got through.
+When --canary-max-failures is set to a ratio (0.1) or percentage (10%), status
+exits like a Nagios check: 0 when no more canaries failed than allowed and 2
+when more failed than that, so it can be used as a collector health probe from
+cron or a monitoring system.
+
+--probe-max-payload searches for the largest export the endpoint accepts by
+sending spans padded with a big attribute, up to 64MiB, and reports it along
+with the client's own limit. This helps track down 413 and ResourceExhausted
+errors on spans with lots of attributes. Accepted probes are real spans that
+will show up in your backend. Each probe gets the full --timeout.
+
+Secrets are masked so the output can be pasted into a ticket: header values
+and span and resource attributes with names like x-api-key, Authorization, or
+db.password, envvars with names like HONEYCOMB_API_KEY, and passwords in URLs.
+Names look secret when one of their -, _, ., or camelCase separated words is
+auth, authorization, token, key, apikey, secret, password, passwd, cookie, or
+credential, or a plural of one, or they have honeycomb-team. Other header
+values are left as is. Routes in the config file are masked the same way.
+--show-secrets turns that off.
+
 Example:
 	otel-cli status
 	otel-cli status --canary-count 10 --canary-interval 10 --timeout 10s
+	otel-cli status --canary-count 5 --canary-interval 1s --timeout 10s --canary-max-failures 20%
+	otel-cli status --probe-max-payload --timeout 10s
 `,
 		Run: doStatus,
 	}
 
 	defaults := DefaultConfig()
 	cmd.Flags().IntVar(&config.StatusCanaryCount, "canary-count", defaults.StatusCanaryCount, "number of canaries to send")
-	cmd.Flags().StringVar(&config.StatusCanaryInterval, "canary-interval", defaults.StatusCanaryInterval, "number of milliseconds to wait between canaries")
+	cmd.Flags().StringVar(&config.StatusCanaryInterval, "canary-interval", defaults.StatusCanaryInterval, "how long to wait between canaries, e.g. 500ms or 1s")
+	cmd.Flags().StringVar(&config.StatusCanaryMaxFailures, "canary-max-failures", defaults.StatusCanaryMaxFailures, "exit non-zero when more than this ratio or percentage of canaries fail, e.g. 0.1 or 10%")
+	cmd.Flags().BoolVar(&config.StatusProbeMaxPayload, "probe-max-payload", defaults.StatusProbeMaxPayload, "find the largest payload the endpoint accepts by sending padded spans")
+	cmd.Flags().BoolVar(&config.ShowSecrets, "show-secrets", defaults.ShowSecrets, "don't mask secret-looking header values, attributes, and envvars, and URL passwords in the output")
 
 	addCommonParams(&cmd, config)
 	addClientParams(&cmd, config)
@@ -61,7 +91,7 @@ func doStatus(cmd *cobra.Command, args []string) {
 
 	ctx := cmd.Context()
 	config := getConfig(ctx)
-	ctx, cancel := context.WithDeadline(ctx, time.Now().Add(config.GetTimeout()))
+	ctx, cancel := config.timeoutContext(ctx, 0)
 	defer cancel()
 	ctx, client := StartClient(ctx, config)
 
@@ -69,13 +99,10 @@ func doStatus(cmd *cobra.Command, args []string) {
 	for _, e := range os.Environ() {
 		parts := strings.SplitN(e, "=", 2)
 		if len(parts) == 2 {
-			// TODO: this is just enough so I can sleep tonight.
-			// should be a list at top of file and needs a flag to turn it off
-			// TODO: for sure need to mask OTEL_EXPORTER_OTLP_HEADERS
-			if strings.Contains(strings.ToLower(parts[0]), "token") || parts[0] == "OTEL_EXPORTER_OTLP_HEADERS" {
-				env[parts[0]] = "--- redacted ---"
-			} else {
+			if config.ShowSecrets {
 				env[parts[0]] = parts[1]
+			} else {
+				env[parts[0]] = maskEnvValue(parts[0], parts[1])
 			}
 		} else {
 			config.SoftFail("BUG in otel-cli: this shouldn't happen")
@@ -83,8 +110,11 @@ func doStatus(cmd *cobra.Command, args []string) {
 	}
 
 	var canaryCount int
+	attempts := []CanaryAttempt{}
+	maxFailures := config.ParseStatusCanaryMaxFailures()
 	var lastSpan *tracepb.Span
-	deadline := time.Now().Add(config.GetTimeout())
+	// canaries stop at the same deadline as everything else, see timeoutContext
+	deadline, _ := ctx.Deadline()
 	interval := config.ParseStatusCanaryInterval()
 	for {
 		// should be rare but a caller could request 0 canaries, in which case the
@@ -93,6 +123,8 @@ func doStatus(cmd *cobra.Command, args []string) {
 			// TODO: remove this after SpanData is eliminated
 			lastSpan = otlpclient.NewProtobufSpan()
 			lastSpan.Name = "unsent canary"
+			lastSpan.StartTimeUnixNano = uint64(config.now().UnixNano())
+			lastSpan.EndTimeUnixNano = lastSpan.StartTimeUnixNano
 			break
 		}
 
@@ -111,9 +143,19 @@ func doStatus(cmd *cobra.Command, args []string) {
 		lastSpan = span
 		allSpans = append(allSpans, otlpclient.SpanToStringMap(span, nil))
 
-		// send it to the server. ignore errors here, they'll happen for sure
-		// and the base errors will be tunneled up through otlpclient.GetErrorList()
-		ctx, _ = otlpclient.SendSpan(ctx, client, config, span)
+		// send it to the server. errors are only counted here, they'll happen
+		// for sure and the base errors will be tunneled up through
+		// otlpclient.GetErrorList()
+		sendStart := time.Now()
+		ctx, err = otlpclient.SendSpan(ctx, client, config, span)
+		attempt := CanaryAttempt{
+			SpanId:    hex.EncodeToString(span.SpanId),
+			LatencyMs: latencyMs(time.Since(sendStart)),
+		}
+		if err != nil {
+			attempt.Error = err.Error()
+		}
+		attempts = append(attempts, attempt)
 		canaryCount++
 
 		if canaryCount == config.StatusCanaryCount {
@@ -129,11 +171,30 @@ func doStatus(cmd *cobra.Command, args []string) {
 	if err != nil {
 		config.SoftFail("client.Stop() failed: %s", err)
 	}
+	config.diag.update(func(d *Diagnostics) { d.Retries = otlpclient.GetRetryCount(ctx) })
+
+	// probes get their own timeouts instead of sharing the canaries' deadline
+	var payloadProbe *PayloadProbe
+	if config.StatusProbeMaxPayload && config.GetIsRecording() {
+		payloadProbe = config.ProbeMaxPayload(cmd.Context())
+	}
+
+	var canary *CanaryReport
+	if len(attempts) > 0 {
+		canary = newCanaryReport(attempts, maxFailures)
+		exitCode = canary.ExitCode
+	}
 
 	// otlpclient saves all errors to a key in context so they can be used
 	// to validate assumptions here & in tests
 	errorList := otlpclient.GetErrorList(ctx)
 
+	diagnostics := GetDiagnostics(ctx)
+	if !config.ShowSecrets {
+		diagnostics.CliArgs = maskCliArgs(diagnostics.CliArgs)
+		diagnostics.Endpoint = otlpclient.MaskUrlPassword(diagnostics.Endpoint)
+	}
+
 	// TODO: does it make sense to turn SpanData into a list of spans?
 	outData := StatusOutput{
 		Config: config,
@@ -149,15 +210,16 @@ func doStatus(cmd *cobra.Command, args []string) {
 		},
 		// Diagnostics is deprecated, being replaced by Errors below and eventually
 		// another stringmap of stuff that was tunneled through context.Context
-		Diagnostics: Diag,
-		Errors:      errorList,
+		Diagnostics:  diagnostics,
+		Errors:       errorList,
+		PayloadProbe: payloadProbe,
+		Canary:       canary,
 	}
 
 	js, err := json.MarshalIndent(outData, "", "    ")
 	config.SoftFailIfErr(err)
 
-	os.Stdout.Write(js)
-	os.Stdout.WriteString("\n")
+	config.getStdout().Write(append(js, '\n'))
 
-	os.Exit(exitCode)
+	config.exit(exitCode)
 }
@@ -0,0 +1,86 @@
+package otelcli
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// Exit codes for otel-cli status when --canary-max-failures is set, following
+// the Nagios plugin convention so status can be used as a health check.
+const (
+	canaryExitOk       = 0 // no more canaries failed than allowed
+	canaryExitCritical = 2 // more canaries failed than --canary-max-failures
+)
+
+// CanaryAttempt is how one status canary went.
+type CanaryAttempt struct {
+	SpanId    string  `json:"span_id"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// CanaryReport summarizes the canaries status sent, so it can be used as a
+// collector health probe from cron or a monitoring system. Latency
+// percentiles only count canaries that were accepted, since failures can be
+// anything from an instant connection refused to a timeout.
+type CanaryReport struct {
+	Attempts     []CanaryAttempt `json:"attempts"`
+	Sent         int             `json:"sent"`
+	Succeeded    int             `json:"succeeded"`
+	Failed       int             `json:"failed"`
+	FailureRatio float64         `json:"failure_ratio"`
+	P50Ms        float64         `json:"p50_ms"`
+	P95Ms        float64         `json:"p95_ms"`
+	MaxFailures  *float64        `json:"max_failures,omitempty"`
+	ExitCode     int             `json:"exit_code"`
+}
+
+// newCanaryReport tallies the attempts. maxFailures is the failure ratio
+// from --canary-max-failures, or nil when it wasn't set, in which case the
+// exit code is always 0 like it's always been.
+func newCanaryReport(attempts []CanaryAttempt, maxFailures *float64) *CanaryReport {
+	report := CanaryReport{
+		Attempts:    attempts,
+		Sent:        len(attempts),
+		MaxFailures: maxFailures,
+	}
+
+	latencies := []float64{}
+	for _, attempt := range attempts {
+		if attempt.Error == "" {
+			report.Succeeded++
+			latencies = append(latencies, attempt.LatencyMs)
+		} else {
+			report.Failed++
+		}
+	}
+	if report.Sent > 0 {
+		report.FailureRatio = float64(report.Failed) / float64(report.Sent)
+	}
+
+	slices.Sort(latencies)
+	report.P50Ms = percentile(latencies, 50)
+	report.P95Ms = percentile(latencies, 95)
+
+	if maxFailures != nil && report.FailureRatio > *maxFailures {
+		report.ExitCode = canaryExitCritical
+	}
+
+	return &report
+}
+
+// percentile returns the nearest-rank percentile of the sorted values, or
+// 0 when there aren't any.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[max(rank, 1)-1]
+}
+
+// latencyMs converts the duration to milliseconds, keeping microseconds.
+func latencyMs(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
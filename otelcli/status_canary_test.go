@@ -0,0 +1,70 @@
+package otelcli
+
+import (
+	"testing"
+)
+
+func TestNewCanaryReport(t *testing.T) {
+	attempts := []CanaryAttempt{
+		{LatencyMs: 40},
+		{LatencyMs: 10},
+		{LatencyMs: 5, Error: "connection refused"},
+		{LatencyMs: 30},
+		{LatencyMs: 20},
+	}
+
+	// without --canary-max-failures status always exits 0
+	report := newCanaryReport(attempts, nil)
+	if report.Sent != 5 || report.Succeeded != 4 || report.Failed != 1 {
+		t.Errorf("unexpected counts sent=%d succeeded=%d failed=%d", report.Sent, report.Succeeded, report.Failed)
+	}
+	if report.FailureRatio != 0.2 {
+		t.Errorf("expected a failure ratio of 0.2, got %f", report.FailureRatio)
+	}
+	// the failed attempt's latency isn't counted
+	if report.P50Ms != 20 || report.P95Ms != 40 {
+		t.Errorf("expected p50=20 p95=40, got p50=%f p95=%f", report.P50Ms, report.P95Ms)
+	}
+	if report.ExitCode != canaryExitOk {
+		t.Errorf("expected exit code 0 without a threshold, got %d", report.ExitCode)
+	}
+
+	for _, tc := range []struct {
+		maxFailures float64
+		exitCode    int
+	}{
+		{0.5, canaryExitOk},
+		{0.2, canaryExitOk},
+		{0.1, canaryExitCritical},
+		{0, canaryExitCritical},
+	} {
+		report = newCanaryReport(attempts, &tc.maxFailures)
+		if report.ExitCode != tc.exitCode {
+			t.Errorf("expected exit code %d for max failures %f, got %d", tc.exitCode, tc.maxFailures, report.ExitCode)
+		}
+	}
+
+	zero := 0.0
+	report = newCanaryReport(attempts[:2], &zero)
+	if report.ExitCode != canaryExitOk {
+		t.Errorf("expected exit code 0 when every canary was sent, got %d", report.ExitCode)
+	}
+
+	report = newCanaryReport(attempts[2:3], nil)
+	if report.P50Ms != 0 || report.P95Ms != 0 {
+		t.Errorf("expected 0 latency with no successful canaries, got p50=%f p95=%f", report.P50Ms, report.P95Ms)
+	}
+}
+
+func TestParseStatusCanaryMaxFailures(t *testing.T) {
+	if got := DefaultConfig().ParseStatusCanaryMaxFailures(); got != nil {
+		t.Errorf("expected no threshold by default, got %f", *got)
+	}
+
+	for in, want := range map[string]float64{"0": 0, "0.25": 0.25, "10%": 0.1, " 100% ": 1} {
+		got := DefaultConfig().WithStatusCanaryMaxFailures(in).ParseStatusCanaryMaxFailures()
+		if got == nil || *got != want {
+			t.Errorf("expected %q to parse to %f, got %v", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package otelcli
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// maxPayloadProbe is the largest payload status --probe-max-payload tries,
+	// well over the 4MiB the collector accepts by default.
+	maxPayloadProbe = 64 << 20
+	// payloadProbeResolution is how close the search gets before stopping.
+	payloadProbeResolution = 1 << 10
+)
+
+// PayloadProbe is the result of searching for the largest export request
+// the endpoint accepts. OTLP has no way for a collector to advertise its
+// limit so it can only be found by trying. Sizes are of the whole
+// ExportTraceServiceRequest in protobuf.
+type PayloadProbe struct {
+	Endpoint string `json:"endpoint"`
+	// LocalMaxBytes is the most the client will send, 0 means no limit.
+	LocalMaxBytes    int    `json:"local_max_bytes"`
+	MaxAcceptedBytes int    `json:"max_accepted_bytes"`
+	MinRejectedBytes int    `json:"min_rejected_bytes,omitempty"`
+	Rejection        string `json:"rejection,omitempty"`
+	Probes           int    `json:"probes"`
+}
+
+// ProbeMaxPayload binary searches for the endpoint's max message size by
+// sending spans padded with a large attribute. It uses its own client,
+// without routes or --fallback, so rejected probes only show up here.
+func (c Config) ProbeMaxPayload(ctx context.Context) *PayloadProbe {
+	out := PayloadProbe{Endpoint: c.GetEndpoint().String()}
+
+	client, err := newOtlpClient(c)
+	if err != nil {
+		out.Rejection = err.Error()
+		return &out
+	}
+	if _, ok := client.(*otlpclient.GrpcClient); ok {
+		// grpc-go's default, otel-cli doesn't change it
+		out.LocalMaxBytes = math.MaxInt32
+	}
+
+	startCtx, cancel := context.WithTimeout(ctx, c.GetTimeout())
+	defer cancel()
+	if _, err := client.Start(startCtx); err != nil {
+		out.Rejection = err.Error()
+		return &out
+	}
+	defer client.Stop(ctx)
+
+	// send returns the request size for the padding and whether it was accepted
+	send := func(padding int) (int, error) {
+		span := c.NewProtobufSpan()
+		span.Name = "otel-cli status payload probe"
+		span.Attributes = append(span.Attributes, &commonpb.KeyValue{
+			Key:   "otel-cli.payload_probe.padding",
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: strings.Repeat("x", padding)}},
+		})
+
+		rsps, err := otlpclient.NewResourceSpans(ctx, c, []*tracepb.Span{span})
+		if err != nil {
+			return 0, err
+		}
+		size := proto.Size(&coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+
+		probeCtx, cancel := context.WithTimeout(ctx, c.GetTimeout())
+		defer cancel()
+		_, err = client.UploadTraces(probeCtx, rsps)
+		out.Probes++
+		return size, err
+	}
+
+	// a small span has to work or there is nothing to search
+	size, err := send(0)
+	if err != nil {
+		out.Rejection = err.Error()
+		return &out
+	}
+	out.MaxAcceptedBytes = size
+
+	// lo is padding known to work, hi the padding known to fail
+	lo, hi := 0, maxPayloadProbe
+	size, err = send(hi)
+	if err == nil {
+		out.MaxAcceptedBytes = size
+		return &out
+	}
+	out.MinRejectedBytes = size
+	out.Rejection = err.Error()
+
+	for hi-lo > payloadProbeResolution {
+		mid := lo + (hi-lo)/2
+		size, err := send(mid)
+		if err == nil {
+			lo = mid
+			out.MaxAcceptedBytes = size
+		} else {
+			hi = mid
+			out.MinRejectedBytes = size
+			out.Rejection = err.Error()
+		}
+	}
+
+	return &out
+}
@@ -0,0 +1,38 @@
+package otelcli
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbeMaxPayload(t *testing.T) {
+	const limit = 100_000
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		rw.Header().Set("Content-Type", "application/x-protobuf")
+		if len(body) > limit {
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+	defer srv.Close()
+
+	config := DefaultConfig().WithEndpoint(srv.URL + "/v1/traces")
+	probe := config.ProbeMaxPayload(context.Background())
+
+	if probe.LocalMaxBytes != 0 {
+		t.Errorf("expected no local limit for http, got %d", probe.LocalMaxBytes)
+	}
+	if probe.MaxAcceptedBytes > limit || probe.MaxAcceptedBytes < limit-payloadProbeResolution {
+		t.Errorf("expected max accepted bytes within %d of %d, got %d", payloadProbeResolution, limit, probe.MaxAcceptedBytes)
+	}
+	if probe.MinRejectedBytes <= limit || probe.MinRejectedBytes > limit+payloadProbeResolution {
+		t.Errorf("expected min rejected bytes within %d above %d, got %d", payloadProbeResolution, limit, probe.MinRejectedBytes)
+	}
+	if !strings.Contains(probe.Rejection, "413") {
+		t.Errorf("expected the rejection to mention the 413, got %q", probe.Rejection)
+	}
+}
@@ -0,0 +1,203 @@
+package otelcli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/spf13/cobra"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// traceFile is the declarative trace read by trace send.
+type traceFile struct {
+	TraceId string          `yaml:"trace_id"`
+	Spans   []traceFileSpan `yaml:"spans"`
+}
+
+// traceFileSpan is one span in a traceFile. Spans refer to their parent by
+// the id given in the file, otel-cli generates the real span ids.
+type traceFileSpan struct {
+	Id                string            `yaml:"id"`
+	Parent            string            `yaml:"parent"`
+	Name              string            `yaml:"name"`
+	Kind              string            `yaml:"kind"`
+	Start             string            `yaml:"start"`
+	End               string            `yaml:"end"`
+	Attributes        map[string]string `yaml:"attributes"`
+	StatusCode        string            `yaml:"status_code"`
+	StatusDescription string            `yaml:"status_description"`
+	Events            []spanFileEvent   `yaml:"events"`
+}
+
+// traceCmd represents the trace command
+func traceCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "trace",
+		Short: "create whole traces",
+		Long:  "Create whole traces at once. See subcommands.",
+	}
+
+	cmd.AddCommand(traceSendCmd(config))
+
+	return &cmd
+}
+
+// traceSendCmd represents the trace send command
+func traceSendCmd(config *Config) *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "send FILE",
+		Short: "send a whole trace described in a YAML or JSON file",
+		Long: `Send a trace described in a YAML or JSON file, all spans in one batch. This
+fits post-processing in CI, where the timing of every step is already known.
+
+Spans name their parent by the id they have in the file, and otel-cli makes
+up the real trace and span ids. Spans without a parent are children of the
+traceparent from the environment or --tp-carrier if there is one. Times can
+be in any format --start and --end accept.
+
+	trace_id: 3433d5ae39bdfee397f44be5146867b3 # optional
+	spans:
+	  - id: pipeline
+	    name: pipeline
+	    start: 2024-03-24T07:28:00Z
+	    end: 2024-03-24T07:31:00Z
+	  - id: build
+	    parent: pipeline
+	    name: build
+	    kind: internal
+	    start: 2024-03-24T07:28:05Z
+	    end: "1711265290.241980634"
+	    attributes: {target: linux}
+	    status_code: ok
+	    events:
+	      - {name: cache miss, time: "1711265287"}
+
+Example:
+	otel-cli trace send pipeline.yaml
+	generate-trace | otel-cli trace send -
+`,
+		Args: cobra.ExactArgs(1),
+		Run:  doTraceSend,
+	}
+
+	cmd.Flags().SortFlags = false
+
+	defaults := DefaultConfig()
+	addCommonParams(&cmd, config)
+	cmd.Flags().StringVarP(&config.ServiceName, "service", "s", defaults.ServiceName, "set the name of the application sent on the traces")
+	addClientParams(&cmd, config)
+
+	return &cmd
+}
+
+func doTraceSend(cmd *cobra.Command, args []string) {
+	ctx := cmd.Context()
+	config := getConfig(ctx)
+
+	var data []byte
+	var err error
+	if args[0] == "-" {
+		config.readStdin(func() { data, err = io.ReadAll(os.Stdin) })
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	config.SoftFailIfErr(err)
+
+	spans, err := config.parseTraceFile(data)
+	config.SoftFailIfErr(err)
+
+	ctx, cancel := config.timeoutContext(ctx, 0)
+	defer cancel()
+
+	ctx, client := StartClient(ctx, config)
+	if len(spans) > 0 && config.GetIsRecording() {
+		rsps, err := otlpclient.NewResourceSpans(ctx, config, spans)
+		config.SoftFailIfErr(err)
+		ctx, err = client.UploadTraces(ctx, rsps)
+		if err != nil {
+			config.SoftLogErrorList(ctx)
+			config.SoftFail("unable to send trace: %s", err)
+		}
+	}
+	_, err = client.Stop(ctx)
+	config.SoftFailIfErr(err)
+}
+
+// parseTraceFile decodes a trace file, which can be YAML or JSON since YAML
+// reads JSON too, and returns its spans in the order they're in the file.
+func (c Config) parseTraceFile(data []byte) ([]*tracepb.Span, error) {
+	var tf traceFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true) // catch typos instead of silently dropping fields
+	if err := dec.Decode(&tf); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse trace file: %w", err)
+	}
+
+	traceId := otlpclient.GenerateTraceId()
+	var rootParent []byte
+	if tf.TraceId != "" {
+		var err error
+		if traceId, err = parseHex(tf.TraceId, 16); err != nil {
+			return nil, fmt.Errorf("trace_id: %w", err)
+		}
+	} else if c.GetIsRecording() {
+		if tp := c.LoadTraceparent(); tp.Initialized {
+			traceId = tp.TraceId
+			rootParent = tp.SpanId
+		}
+	}
+
+	// span ids are handed out up front so parents can come after children
+	spanIds := map[string][]byte{}
+	for i, entry := range tf.Spans {
+		if entry.Id == "" {
+			continue
+		}
+		if _, ok := spanIds[entry.Id]; ok {
+			return nil, fmt.Errorf("span %d: id %q is used more than once", i+1, entry.Id)
+		}
+		spanIds[entry.Id] = otlpclient.GenerateSpanId()
+	}
+
+	spans := make([]*tracepb.Span, len(tf.Spans))
+	for i, entry := range tf.Spans {
+		span := otlpclient.NewProtobufSpan()
+		err := c.setSpanFromFileEntry(span, spanFileEntry{
+			Name:              entry.Name,
+			Kind:              entry.Kind,
+			Start:             entry.Start,
+			End:               entry.End,
+			Attributes:        entry.Attributes,
+			StatusCode:        entry.StatusCode,
+			StatusDescription: entry.StatusDescription,
+			Events:            entry.Events,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("span %d: %w", i+1, err)
+		}
+
+		span.TraceId = traceId
+		span.SpanId = otlpclient.GenerateSpanId()
+		if entry.Id != "" {
+			span.SpanId = spanIds[entry.Id]
+		}
+
+		if entry.Parent == "" {
+			span.ParentSpanId = rootParent
+		} else if entry.Parent == entry.Id {
+			return nil, fmt.Errorf("span %d: %q can't be its own parent", i+1, entry.Id)
+		} else if parent, ok := spanIds[entry.Parent]; ok {
+			span.ParentSpanId = parent
+		} else {
+			return nil, fmt.Errorf("span %d: parent %q is not the id of any span in the file", i+1, entry.Parent)
+		}
+
+		spans[i] = span
+	}
+
+	return spans, nil
+}
@@ -0,0 +1,81 @@
+package otelcli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceFile(t *testing.T) {
+	config := DefaultConfig()
+
+	yml := `trace_id: 3433d5ae39bdfee397f44be5146867b3
+spans:
+  - id: build
+    parent: pipeline
+    name: build
+    kind: internal
+    start: 2024-03-24T07:28:05Z
+    end: "1711265290.500000000"
+    attributes: {target: linux, jobs: 4}
+    status_code: error
+    events:
+      - {name: cache miss, time: "1711265287"}
+  - id: pipeline
+    name: pipeline
+    start: 2024-03-24T07:28:00Z
+    end: 2024-03-24T07:31:00Z
+  - name: upload
+    parent: build
+    start: 2024-03-24T07:28:09Z
+`
+
+	spans, err := config.parseTraceFile([]byte(yml))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans but got %d", len(spans))
+	}
+	build, pipeline, upload := spans[0], spans[1], spans[2]
+
+	for _, span := range spans {
+		if !bytes.Equal(span.TraceId, build.TraceId) {
+			t.Errorf("span %q is in a different trace", span.Name)
+		}
+	}
+	if !bytes.Equal(build.ParentSpanId, pipeline.SpanId) || !bytes.Equal(upload.ParentSpanId, build.SpanId) {
+		t.Error("spans were not parented by their ids in the file")
+	}
+	if len(pipeline.ParentSpanId) != 0 {
+		t.Errorf("span without a parent got parent %x", pipeline.ParentSpanId)
+	}
+	if build.StartTimeUnixNano != 1711265285000000000 || build.EndTimeUnixNano != 1711265290500000000 {
+		t.Errorf("wrong times %d, %d", build.StartTimeUnixNano, build.EndTimeUnixNano)
+	}
+	if len(build.Events) != 1 || len(build.Attributes) != 2 {
+		t.Errorf("wrong events or attributes: %v, %v", build.Events, build.Attributes)
+	}
+
+	// JSON is YAML too
+	spans, err = config.parseTraceFile([]byte(`{"spans": [{"name": "x", "start": "1711265285"}]}`))
+	if err != nil || len(spans) != 1 {
+		t.Errorf("expected one span from JSON, got %d and error %v", len(spans), err)
+	}
+
+	for _, tc := range []struct {
+		in   string
+		want string
+	}{
+		{"spans:\n  - {id: a, name: a, start: now}\n  - {id: a, name: b, start: now}", "used more than once"},
+		{"spans:\n  - {name: a, parent: nope, start: '1711265285'}", "parent \"nope\""},
+		{"spans:\n  - {id: a, parent: a, name: a, start: '1711265285'}", "its own parent"},
+		{"spans:\n  - {name: a, strat: '1711265285'}", "field strat not found"},
+		{"trace_id: abc\nspans: []", "trace_id"},
+	} {
+		_, err := config.parseTraceFile([]byte(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("expected an error containing %q for %q but got %v", tc.want, tc.in, err)
+		}
+	}
+}
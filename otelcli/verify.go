@@ -0,0 +1,101 @@
+package otelcli
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/equinix-labs/otel-cli/otlpserver"
+	"github.com/spf13/cobra"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// verifyServer holds the command-line configured settings and the state for
+// one run of otel-cli verify
+type verifyServer struct {
+	key       []byte
+	maxSpans  int
+	out       io.Writer
+	mu        sync.Mutex
+	spansSeen int
+	rejected  int
+}
+
+func verifyCmd(config *Config) *cobra.Command {
+	svr := &verifyServer{}
+	cmd := cobra.Command{
+		Use:   "verify",
+		Short: "run an OTLP server that checks payload signatures",
+		Long: `Run an OTLP server that checks the signature header sent by otel-cli
+clients configured with --signing-key-file. One line is printed to stdout for
+every span received, starting with "verified" or "rejected".
+
+The key file must contain the same shared key the clients use.
+
+Example:
+	otel-cli verify --signing-key-file /etc/otel-cli/key --max-spans 1 &
+	otel-cli exec --signing-key-file /etc/otel-cli/key --endpoint localhost:4317 -- make
+`,
+		Run: svr.run,
+	}
+
+	addCommonParams(&cmd, config)
+	defaults := DefaultConfig()
+	cmd.Flags().StringVar(&config.SigningKeyFile, "signing-key-file", defaults.SigningKeyFile, "a file containing the shared key clients sign OTLP payloads with")
+	cmd.Flags().IntVar(&svr.maxSpans, "max-spans", 0, "exit the server after this many spans come in")
+	cmd.MarkFlagRequired("signing-key-file")
+
+	return &cmd
+}
+
+func (svr *verifyServer) run(cmd *cobra.Command, args []string) {
+	config := getConfig(cmd.Context())
+	svr.key = config.GetSigningKey()
+	svr.out = config.getStdout()
+
+	stop := func(otlpserver.OtlpServer) {}
+	runServer(config, otlpserver.CallbackSink(svr.verifySpan), stop)
+
+	if svr.rejected > 0 {
+		config.SoftFail("%d of %d spans failed signature verification", svr.rejected, svr.spansSeen)
+	}
+}
+
+// verifySpan checks the signature on the request each span arrived in and
+// prints the result.
+func (svr *verifyServer) verifySpan(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	svr.mu.Lock()
+	defer svr.mu.Unlock()
+	svr.spansSeen++
+
+	// otel-cli always sends one ResourceSpans per request, so that's what was signed
+	err := otlpclient.VerifyResourceSpans(svr.key, []*tracepb.ResourceSpans{rss}, signatureFromHeaders(headers))
+
+	tid := hex.EncodeToString(span.GetTraceId())
+	sid := hex.EncodeToString(span.GetSpanId())
+	if err != nil {
+		svr.rejected++
+		fmt.Fprintf(svr.out, "rejected trace_id=%s span_id=%s name=%q: %s\n", tid, sid, span.GetName(), err)
+	} else {
+		fmt.Fprintf(svr.out, "verified trace_id=%s span_id=%s name=%q\n", tid, sid, span.GetName())
+	}
+
+	return svr.maxSpans > 0 && svr.spansSeen >= svr.maxSpans
+}
+
+// signatureFromHeaders finds the signature regardless of header case, which
+// differs between gRPC metadata and HTTP. The gRPC server hands over values
+// CSV-encoded, so trailing whitespace is trimmed.
+func signatureFromHeaders(headers map[string]string) string {
+	for k, v := range headers {
+		if strings.EqualFold(k, otlpclient.SignatureHeader) {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	return ""
+}
@@ -2,7 +2,7 @@ package otelcli
 
 import (
 	"fmt"
-	"os"
+	"runtime/debug"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -22,7 +22,20 @@ func versionCmd(_ *Config) *cobra.Command {
 func doVersion(cmd *cobra.Command, args []string) {
 	ctx := cmd.Context()
 	config := getConfig(ctx)
-	fmt.Fprintln(os.Stdout, config.Version)
+	fmt.Fprintln(config.getStdout(), config.Version)
+}
+
+// embeddedVersion returns otel-cli's module version from the build info of
+// a program embedding it with Run, since main's ldflags don't set it there.
+func embeddedVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "github.com/equinix-labs/otel-cli" {
+				return FormatVersion(dep.Version, "", "")
+			}
+		}
+	}
+	return FormatVersion("", "", "")
 }
 
 // FormatVersion pretty-prints the global version, commit, and date values into
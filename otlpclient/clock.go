@@ -0,0 +1,25 @@
+package otlpclient
+
+import (
+	"context"
+	"time"
+)
+
+// fakeNowContextKey is the context key for a pinned clock.
+type fakeNowContextKey struct{}
+
+// WithFakeNow returns a context where Now returns t, so tests and pipelines
+// that snapshot otel-cli output get the same error timestamps every run.
+// Deadlines and timeouts always use the real clock.
+func WithFakeNow(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, fakeNowContextKey{}, t)
+}
+
+// Now returns the time for timestamps recorded under ctx, which is the real
+// time unless it was pinned with WithFakeNow.
+func Now(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(fakeNowContextKey{}).(time.Time); ok {
+		return t
+	}
+	return time.Now()
+}
@@ -0,0 +1,25 @@
+package otlpclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWithFakeNow(t *testing.T) {
+	pinned := time.Unix(1617739561, 0)
+	ctx := WithFakeNow(context.Background(), pinned)
+
+	if !Now(ctx).Equal(pinned) {
+		t.Errorf("Now should be pinned to %s, got %s", pinned, Now(ctx))
+	}
+
+	ctx, _ = SaveError(ctx, Now(ctx), context.DeadlineExceeded)
+	if errs := GetErrorList(ctx); len(errs) != 1 || !errs[0].Timestamp.Equal(pinned) {
+		t.Errorf("error timestamp should be pinned to %s, got %v", pinned, errs)
+	}
+
+	if Now(context.Background()).Equal(pinned) {
+		t.Error("a context without a fake clock should get the real time")
+	}
+}
@@ -0,0 +1,70 @@
+package otlpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the gzip compressor
+)
+
+// Compressions are the valid compression settings, an empty string is none.
+var Compressions = []string{"", "gzip", "zstd"}
+
+func init() {
+	// gRPC only ships gzip, zstd is registered here for the client
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// compress returns data compressed with the named compression, or data
+// unchanged when name is empty.
+func compress(name string, data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	var err error
+
+	switch name {
+	case "":
+		return data, nil
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zstd":
+		w, err = zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", name)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// zstdCompressor implements grpc's encoding.Compressor with zstd.
+type zstdCompressor struct{}
+
+// Name returns the name used in the grpc-encoding header.
+func (zstdCompressor) Name() string {
+	return "zstd"
+}
+
+// Compress returns a writer that compresses to w.
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+// Decompress returns a reader of the decompressed data in r. A decoder with
+// a concurrency of 1 decodes as it's read and starts no goroutines, so it
+// doesn't need to be closed.
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+}
@@ -0,0 +1,65 @@
+package otlpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress(t *testing.T) {
+	data := bytes.Repeat([]byte("otel-cli "), 100)
+
+	out, err := compress("", data)
+	if err != nil || !bytes.Equal(out, data) {
+		t.Errorf("expected no compression to return data as-is, got %d bytes and %v", len(out), err)
+	}
+
+	if _, err := compress("brotli", data); err == nil {
+		t.Error("expected an error for an unsupported compression")
+	}
+
+	gz, err := compress("gzip", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gr, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(gr); !bytes.Equal(got, data) {
+		t.Errorf("gzip round trip failed, got %q", got)
+	}
+
+	zs, err := compress("zstd", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zs) >= len(data) {
+		t.Errorf("expected zstd to shrink repetitive data, got %d bytes from %d", len(zs), len(data))
+	}
+
+	// the grpc compressor has to read what compress writes
+	zr, err := zstdCompressor{}.Decompress(bytes.NewReader(zs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(zr); !bytes.Equal(got, data) {
+		t.Errorf("zstd round trip failed, got %q", got)
+	}
+
+	var buf bytes.Buffer
+	zw, err := zstdCompressor{}.Compress(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(data)
+	zw.Close()
+	dec, _ := zstd.NewReader(nil)
+	defer dec.Close()
+	if got, err := dec.DecodeAll(buf.Bytes(), nil); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("grpc zstd compressor output didn't decode: %v", err)
+	}
+}
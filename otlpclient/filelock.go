@@ -0,0 +1,24 @@
+package otlpclient
+
+import "os"
+
+// LockFile takes an exclusive lock on path with ".lock" added, waiting for
+// any other process holding it, and returns a func that releases it. State
+// files are replaced by renaming, so the lock needs a file of its own. Locks
+// are advisory and only keep out processes that lock the same path.
+func LockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
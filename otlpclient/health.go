@@ -0,0 +1,219 @@
+package otlpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+const (
+	// healthMinBackoff is how long processes back off after the first failure.
+	healthMinBackoff = time.Second
+	// healthMaxBackoff caps the backoff however many failures there were.
+	healthMaxBackoff = 5 * time.Minute
+)
+
+// ErrEndpointBackingOff is returned by HealthClient when the health file says
+// the endpoint is failing and the export was shed without trying it.
+var ErrEndpointBackingOff = errors.New("endpoint is backing off")
+
+// HealthState is what the health file holds about the endpoint, shared by
+// every otel-cli process that's pointed at the same file.
+type HealthState struct {
+	Failures     int       `json:"failures"`
+	BackoffUntil time.Time `json:"backoff_until"`
+	LastError    string    `json:"last_error,omitempty"`
+}
+
+// HealthClient is an OTLPClient that wraps another client and coordinates
+// backoff with other otel-cli processes through a shared health file. After
+// a failed export, every process sheds its exports until the backoff runs
+// out, then the first one to notice claims a probe by pushing the backoff
+// out again before trying, so hundreds of concurrent CI steps don't all
+// retry a struggling collector at the same moment. Backoff grows with each
+// failure and has jitter so processes that fail together drift apart.
+//
+// The file is advisory. Updates are written atomically while holding a lock
+// on a ".lock" file next to it. Exports the endpoint rejects, like a 400,
+// don't count as failures.
+type HealthClient struct {
+	client OTLPClient
+	path   string
+	now    func() time.Time // swappable for tests
+}
+
+// NewHealthClient returns a HealthClient that keeps its state in path.
+func NewHealthClient(client OTLPClient, path string) *HealthClient {
+	return &HealthClient{client: client, path: path, now: time.Now}
+}
+
+// Start starts the wrapped client.
+func (hc *HealthClient) Start(ctx context.Context) (context.Context, error) {
+	return hc.client.Start(ctx)
+}
+
+// UploadTraces uploads with the wrapped client unless the endpoint is
+// backing off, and records the outcome in the health file.
+func (hc *HealthClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	return hc.upload(ctx, func(ctx context.Context) (context.Context, error) {
+		return hc.client.UploadTraces(ctx, rsps)
+	})
+}
+
+// UploadLogs uploads with the wrapped client unless the endpoint is backing
+// off, and records the outcome in the health file.
+func (hc *HealthClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return hc.upload(ctx, func(ctx context.Context) (context.Context, error) {
+		return hc.client.UploadLogs(ctx, rls)
+	})
+}
+
+// UploadMetrics uploads with the wrapped client unless the endpoint is
+// backing off, and records the outcome in the health file.
+func (hc *HealthClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	return hc.upload(ctx, func(ctx context.Context) (context.Context, error) {
+		return hc.client.UploadMetrics(ctx, rms)
+	})
+}
+
+// Stop stops the wrapped client.
+func (hc *HealthClient) Stop(ctx context.Context) (context.Context, error) {
+	return hc.client.Stop(ctx)
+}
+
+// upload checks the health file, sheds the export if the endpoint is backing
+// off, and otherwise runs it and saves how it went. Problems with the file
+// itself are saved to the error list but never block an export.
+func (hc *HealthClient) upload(ctx context.Context, send func(context.Context) (context.Context, error)) (context.Context, error) {
+	var shed error
+	ctx, err := hc.update(ctx, func(state *HealthState) bool {
+		now := hc.now()
+		if state.Failures == 0 {
+			return false
+		} else if now.Before(state.BackoffUntil) {
+			shed = fmt.Errorf("%w until %s after %d failure(s), see %s", ErrEndpointBackingOff, state.BackoffUntil.Format(time.RFC3339), state.Failures, hc.path)
+			return false
+		}
+
+		// claim the probe so the other processes keep backing off meanwhile
+		state.BackoffUntil = now.Add(healthBackoff(state.Failures, 1))
+		return true
+	})
+	if err != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("ignoring health file: %w", err))
+		return send(ctx)
+	} else if shed != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), shed)
+		return ctx, shed
+	}
+
+	ctx, sendErr := send(ctx)
+
+	// an export the endpoint rejected still means the endpoint is up, so
+	// only transport errors, 5xx, and 429 count as failures
+	failed := sendErr != nil && !IsPermanentError(sendErr)
+	ctx, err = hc.update(ctx, func(state *HealthState) bool {
+		if failed {
+			state.Failures++
+			state.BackoffUntil = hc.now().Add(healthBackoff(state.Failures, 0.5+rand.Float64()))
+			state.LastError = sendErr.Error()
+			return true
+		} else if state.Failures > 0 {
+			*state = HealthState{}
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("failed to update health file: %w", err))
+	}
+	return ctx, sendErr
+}
+
+// update reads the health file while holding its lock, so other processes
+// can't change it in between, and saves it if change returns true.
+func (hc *HealthClient) update(ctx context.Context, change func(*HealthState) bool) (context.Context, error) {
+	unlock, err := LockFile(hc.path)
+	if err != nil {
+		return ctx, err
+	}
+	defer unlock()
+
+	state, err := ReadHealthFile(hc.path)
+	if err != nil {
+		return ctx, err
+	}
+	if change(&state) {
+		ctx = hc.save(ctx, state)
+	}
+	return ctx, nil
+}
+
+// save writes the state, saving any error to the error list.
+func (hc *HealthClient) save(ctx context.Context, state HealthState) context.Context {
+	if err := WriteHealthFile(hc.path, state); err != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("failed to update health file: %w", err))
+	}
+	return ctx
+}
+
+// healthBackoff returns the backoff after the number of failures, doubling
+// from healthMinBackoff up to healthMaxBackoff, scaled by jitter.
+func healthBackoff(failures int, jitter float64) time.Duration {
+	backoff := healthMinBackoff
+	for i := 1; i < failures && backoff < healthMaxBackoff; i++ {
+		backoff *= 2
+	}
+	backoff = min(backoff, healthMaxBackoff)
+	return time.Duration(float64(backoff) * jitter)
+}
+
+// ReadHealthFile reads the health file. A missing or empty file is a
+// healthy endpoint.
+func ReadHealthFile(path string) (HealthState, error) {
+	var state HealthState
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && len(data) == 0) {
+		return state, nil
+	} else if err != nil {
+		return state, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("invalid health file %q: %w", path, err)
+	}
+	return state, nil
+}
+
+// WriteHealthFile replaces the health file with the state. It's written to
+// a temporary file and renamed so readers never see a partial write.
+func WriteHealthFile(path string, state HealthState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
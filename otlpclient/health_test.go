@@ -0,0 +1,87 @@
+package otlpclient
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestHealthClient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	rsps := []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{routeTestSpan("span", nil)}}},
+	}}
+
+	// a failure starts a backoff that's shared through the file
+	down := NewHealthClient(&failingClient{}, path)
+	down.now = clock
+	if _, err := down.UploadTraces(context.Background(), rsps); err == nil {
+		t.Fatal("expected the failing upload to fail")
+	}
+	state, err := ReadHealthFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Failures != 1 || !state.BackoffUntil.After(now) || state.LastError == "" {
+		t.Errorf("expected one failure and a backoff in the health file, got %+v", state)
+	}
+
+	// another process sheds while the backoff lasts, without sending
+	rc := &recordingClient{}
+	up := NewHealthClient(rc, path)
+	up.now = clock
+	ctx, err := up.UploadTraces(context.Background(), rsps)
+	if !errors.Is(err, ErrEndpointBackingOff) || len(rc.names) != 0 {
+		t.Errorf("expected the export to be shed, got %v and %d spans sent", err, len(rc.names))
+	}
+	if len(GetErrorList(ctx)) != 1 {
+		t.Errorf("expected the shed export in the error list, got %v", GetErrorList(ctx))
+	}
+
+	// after the backoff a probe goes through and a success resets the file
+	now = now.Add(healthMaxBackoff)
+	if _, err := up.UploadTraces(context.Background(), rsps); err != nil || len(rc.names) != 1 {
+		t.Errorf("expected the probe to be sent, got %v", err)
+	}
+	if state, _ := ReadHealthFile(path); state.Failures != 0 || !state.BackoffUntil.IsZero() {
+		t.Errorf("expected a healthy file after success, got %+v", state)
+	}
+
+	// a rejected export means the endpoint is up, so it isn't a failure
+	if _, err := NewHealthClient(&rejectingClient{}, path).UploadTraces(context.Background(), rsps); err == nil {
+		t.Error("expected the rejected export's error")
+	}
+	if state, _ := ReadHealthFile(path); state.Failures != 0 {
+		t.Errorf("expected a rejected export not to count as a failure, got %+v", state)
+	}
+
+	// a corrupt file is reported but doesn't block exports
+	os.WriteFile(path, []byte("not json"), 0600)
+	ctx, err = up.UploadTraces(context.Background(), rsps)
+	if err != nil || len(rc.names) != 2 || len(GetErrorList(ctx)) != 1 {
+		t.Errorf("expected a corrupt health file to be ignored, got %v", err)
+	}
+}
+
+func TestHealthBackoff(t *testing.T) {
+	for failures, want := range map[int]time.Duration{
+		1:   time.Second,
+		2:   2 * time.Second,
+		4:   8 * time.Second,
+		100: healthMaxBackoff,
+	} {
+		if got := healthBackoff(failures, 1); got != want {
+			t.Errorf("expected %s backoff after %d failures, got %s", want, failures, got)
+		}
+	}
+	if got := healthBackoff(1, 1.5); got != 1500*time.Millisecond {
+		t.Errorf("expected jitter to scale the backoff, got %s", got)
+	}
+}
@@ -5,14 +5,18 @@ package otlpclient
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/url"
+	"sort"
 	"time"
 
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
 	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 )
@@ -22,6 +26,8 @@ import (
 type OTLPClient interface {
 	Start(context.Context) (context.Context, error)
 	UploadTraces(context.Context, []*tracepb.ResourceSpans) (context.Context, error)
+	UploadLogs(context.Context, []*logspb.ResourceLogs) (context.Context, error)
+	UploadMetrics(context.Context, []*metricspb.ResourceMetrics) (context.Context, error)
 	Stop(context.Context) (context.Context, error)
 }
 
@@ -30,67 +36,198 @@ type OTLPConfig interface {
 	GetTlsConfig() *tls.Config
 	GetIsRecording() bool
 	GetEndpoint() *url.URL
+	GetProtocol() string
 	GetInsecure() bool
 	GetTimeout() time.Duration
 	GetHeaders() map[string]string
 	GetVersion() string
 	GetServiceName() string
+	GetResourceAttributes() map[string]string
+	GetResourceDetectors() []string
+	GetSigningKey() []byte
+	GetWireDebugFile() string
+	GetCompression() string
+	GetRetries() int
+	GetRetrySleep() time.Duration
+	GetRetryTimeout() time.Duration
+	GetProxy() string
 }
 
 // SendSpan connects to the OTLP server, sends the span, and disconnects.
 func SendSpan(ctx context.Context, client OTLPClient, config OTLPConfig, span *tracepb.Span) (context.Context, error) {
+	return SendSpans(ctx, client, config, []*tracepb.Span{span})
+}
+
+// SendSpans sends all of the provided spans in a single ResourceSpans batch.
+// Nothing is sent when there are no spans, e.g. when they weren't sampled.
+func SendSpans(ctx context.Context, client OTLPClient, config OTLPConfig, spans []*tracepb.Span) (context.Context, error) {
+	if !config.GetIsRecording() || len(spans) == 0 {
+		return ctx, nil
+	}
+
+	rsps, err := NewResourceSpans(ctx, config, spans)
+	if err != nil {
+		return ctx, err
+	}
+
+	// UploadTraces saves its own errors, one per attempt, so they aren't
+	// saved again here
+	return client.UploadTraces(ctx, rsps)
+}
+
+// NewResourceSpans wraps spans in the resource and scope otel-cli sends them
+// with, ready for OTLPClient.UploadTraces.
+func NewResourceSpans(ctx context.Context, config OTLPConfig, spans []*tracepb.Span) ([]*tracepb.ResourceSpans, error) {
+	resourceAttrs, err := resourceAttributes(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &tracepb.ResourceSpans{
+		Resource: &resourcepb.Resource{
+			Attributes: resourceAttrs,
+		},
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Scope:     instrumentationScope(config),
+			Spans:     spans,
+			SchemaUrl: semconv.SchemaURL,
+		}},
+		SchemaUrl: semconv.SchemaURL,
+	}
+	ValidUTF8Message(rs)
+
+	return []*tracepb.ResourceSpans{rs}, nil
+}
+
+// SendLogs sends all of the provided log records in a single ResourceLogs
+// batch, with the same resource and scope as spans.
+func SendLogs(ctx context.Context, client OTLPClient, config OTLPConfig, records []*logspb.LogRecord) (context.Context, error) {
 	if !config.GetIsRecording() {
 		return ctx, nil
 	}
 
-	resourceAttrs, err := resourceAttributes(ctx, config.GetServiceName())
+	resourceAttrs, err := resourceAttributes(ctx, config)
 	if err != nil {
 		return ctx, err
 	}
 
-	rsps := []*tracepb.ResourceSpans{
+	rls := []*logspb.ResourceLogs{
 		{
 			Resource: &resourcepb.Resource{
 				Attributes: resourceAttrs,
 			},
-			ScopeSpans: []*tracepb.ScopeSpans{{
-				Scope: &commonpb.InstrumentationScope{
-					Name:                   "github.com/equinix-labs/otel-cli",
-					Version:                config.GetVersion(),
-					Attributes:             []*commonpb.KeyValue{},
-					DroppedAttributesCount: 0,
-				},
-				Spans:     []*tracepb.Span{span},
-				SchemaUrl: semconv.SchemaURL,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      instrumentationScope(config),
+				LogRecords: records,
+				SchemaUrl:  semconv.SchemaURL,
 			}},
 			SchemaUrl: semconv.SchemaURL,
 		},
 	}
 
-	ctx, err = client.UploadTraces(ctx, rsps)
+	ValidUTF8Message(rls[0])
+
+	return client.UploadLogs(ctx, rls)
+}
+
+// SendMetrics sends all of the provided metrics in a single ResourceMetrics
+// batch, with the same resource and scope as spans.
+func SendMetrics(ctx context.Context, client OTLPClient, config OTLPConfig, metrics []*metricspb.Metric) (context.Context, error) {
+	if !config.GetIsRecording() {
+		return ctx, nil
+	}
+
+	resourceAttrs, err := resourceAttributes(ctx, config)
 	if err != nil {
-		return SaveError(ctx, time.Now(), err)
+		return ctx, err
+	}
+
+	rms := []*metricspb.ResourceMetrics{
+		{
+			Resource: &resourcepb.Resource{
+				Attributes: resourceAttrs,
+			},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{
+				Scope:     instrumentationScope(config),
+				Metrics:   metrics,
+				SchemaUrl: semconv.SchemaURL,
+			}},
+			SchemaUrl: semconv.SchemaURL,
+		},
+	}
+
+	ValidUTF8Message(rms[0])
+
+	return client.UploadMetrics(ctx, rms)
+}
+
+// instrumentationScope returns otel-cli's scope for all signals.
+func instrumentationScope(config OTLPConfig) *commonpb.InstrumentationScope {
+	return &commonpb.InstrumentationScope{
+		Name:                   "github.com/equinix-labs/otel-cli",
+		Version:                config.GetVersion(),
+		Attributes:             []*commonpb.KeyValue{},
+		DroppedAttributesCount: 0,
 	}
+}
 
-	return ctx, nil
+// ResourceDetectors are the OTel SDK resource detectors that can be turned on
+// by name with --resource-detectors. "none" is also accepted and turns off
+// reading OTEL_RESOURCE_ATTRIBUTES as well.
+var ResourceDetectors = map[string][]resource.Option{
+	"host": {resource.WithHost()},
+	"os":   {resource.WithOS()},
+	// resource.WithProcess without process.command_args, which would send
+	// otel-cli's own command line, secrets in --otlp-headers and all
+	"process": {
+		resource.WithProcessPID(),
+		resource.WithProcessExecutableName(),
+		resource.WithProcessExecutablePath(),
+		resource.WithProcessOwner(),
+		resource.WithProcessRuntimeName(),
+		resource.WithProcessRuntimeVersion(),
+		resource.WithProcessRuntimeDescription(),
+	},
+	"container": {resource.WithContainer()},
 }
 
 // resourceAttributes calls the OTel SDK to get automatic resource attrs and
 // returns them converted to []*commonpb.KeyValue for use with protobuf.
-func resourceAttributes(ctx context.Context, serviceName string) ([]*commonpb.KeyValue, error) {
+// Detected attributes are overridden by OTEL_RESOURCE_ATTRIBUTES, and
+// everything is overridden by --resource-attrs.
+func resourceAttributes(ctx context.Context, config OTLPConfig) ([]*commonpb.KeyValue, error) {
+	resOpts := []resource.Option{}
+	fromEnv := true
+	for _, name := range config.GetResourceDetectors() {
+		if name == "none" {
+			fromEnv = false
+		} else if detector, ok := ResourceDetectors[name]; ok {
+			resOpts = append(resOpts, detector...)
+		} else {
+			return nil, fmt.Errorf("unknown resource detector %q", name)
+		}
+	}
+
 	// set the service name that will show up in tracing UIs
-	resOpts := []resource.Option{
-		resource.WithAttributes(semconv.ServiceNameKey.String(serviceName)),
-		resource.WithFromEnv(), // maybe switch to manually loading this envvar?
-		// TODO: make these autodetectors configurable
-		//resource.WithHost(),
-		//resource.WithOS(),
-		//resource.WithProcess(),
-		//resource.WithContainer(),
+	resOpts = append(resOpts, resource.WithAttributes(semconv.ServiceNameKey.String(config.GetServiceName())))
+	if fromEnv {
+		resOpts = append(resOpts, resource.WithFromEnv()) // maybe switch to manually loading this envvar?
 	}
 
+	// sorted so the resource is the same every time
+	keys := make([]string, 0, len(config.GetResourceAttributes()))
+	for key := range config.GetResourceAttributes() {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		resOpts = append(resOpts, resource.WithAttributes(attribute.String(key, config.GetResourceAttributes()[key])))
+	}
+
+	// detectors that could only find some of their attributes, e.g. process
+	// owner in a container without a passwd entry, still return what they found
 	res, err := resource.New(ctx, resOpts...)
-	if err != nil {
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
 		return nil, fmt.Errorf("failed to create OpenTelemetry service name resource: %s", err)
 	}
 
@@ -99,7 +236,6 @@ func resourceAttributes(ctx context.Context, serviceName string) ([]*commonpb.Ke
 	for _, attr := range res.Attributes() {
 		av := new(commonpb.AnyValue)
 
-		// does not implement slice types... should be fine?
 		switch attr.Value.Type() {
 		case attribute.BOOL:
 			av.Value = &commonpb.AnyValue_BoolValue{BoolValue: attr.Value.AsBool()}
@@ -109,6 +245,13 @@ func resourceAttributes(ctx context.Context, serviceName string) ([]*commonpb.Ke
 			av.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: attr.Value.AsFloat64()}
 		case attribute.STRING:
 			av.Value = &commonpb.AnyValue_StringValue{StringValue: attr.Value.AsString()}
+		case attribute.STRINGSLICE:
+			// e.g. host.ip from the host detector
+			values := []*commonpb.AnyValue{}
+			for _, s := range attr.Value.AsStringSlice() {
+				values = append(values, &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}})
+			}
+			av.Value = &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}
 		default:
 			return nil, fmt.Errorf("BUG: unable to convert resource attribute, please file an issue")
 		}
@@ -123,13 +266,47 @@ func resourceAttributes(ctx context.Context, serviceName string) ([]*commonpb.Ke
 	return attrs, nil
 }
 
+// PermanentError wraps an export error that will happen again however many
+// times the export is retried, e.g. the server refused the request with an
+// HTTP 4xx or a gRPC InvalidArgument, or it rejected some of the spans. It
+// means the endpoint is up and talking, so it isn't counted against the
+// endpoint's health, and a queued export that gets one can't ever be sent.
+type PermanentError struct {
+	Err error
+}
+
+func (pe *PermanentError) Error() string { return pe.Err.Error() }
+func (pe *PermanentError) Unwrap() error { return pe.Err }
+
+// IsPermanentError returns true if err is or wraps a *PermanentError.
+func IsPermanentError(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
 // otlpClientCtxKey is a type for storing otlp client information in context.Context safely.
 type otlpClientCtxKey string
 
-// TimestampedError is a timestamp + error string, to be stored in an ErrorList
+// TimestampedError is a timestamp + error string, to be stored in an ErrorList.
+// Errors from export attempts also carry the endpoint, with its password
+// masked, and attempt number.
 type TimestampedError struct {
 	Timestamp time.Time `json:"timestamp"`
 	Error     string    `json:"error"`
+	Endpoint  string    `json:"endpoint,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+}
+
+// String formats the error for log output.
+func (te TimestampedError) String() string {
+	out := te.Timestamp.Format(time.RFC3339Nano)
+	if te.Endpoint != "" {
+		out += " " + te.Endpoint
+	}
+	if te.Attempt > 0 {
+		out += fmt.Sprintf(" attempt %d", te.Attempt)
+	}
+	return out + ": " + te.Error
 }
 
 // ErrorList is a list of TimestampedError
@@ -157,15 +334,21 @@ func GetErrorList(ctx context.Context) ErrorList {
 // SaveError writes the provided error to the ErrorList in ctx, returning an
 // updated ctx.
 func SaveError(ctx context.Context, t time.Time, err error) (context.Context, error) {
+	return saveAttemptError(ctx, t, "", 0, err)
+}
+
+// saveAttemptError is SaveError plus the endpoint and attempt number of a
+// failed export, so the list shows every retry and where it went.
+func saveAttemptError(ctx context.Context, t time.Time, endpoint string, attempt int, err error) (context.Context, error) {
 	if err == nil {
 		return ctx, nil
 	}
 
-	//otelcli.Diag.SetError(err) // legacy, will go away when Diag is removed
-
 	te := TimestampedError{
 		Timestamp: t,
 		Error:     err.Error(),
+		Endpoint:  MaskUrlPassword(endpoint),
+		Attempt:   attempt,
 	}
 
 	errorList := GetErrorList(ctx)
@@ -175,54 +358,88 @@ func SaveError(ctx context.Context, t time.Time, err error) (context.Context, er
 	return ctx, err
 }
 
+// retryCountKey returns the typed key used to store the retry count in context.
+func retryCountKey() otlpClientCtxKey {
+	return otlpClientCtxKey("otlp_retries")
+}
+
+// GetRetryCount returns how many times exports were retried, over every
+// export made with ctx.
+func GetRetryCount(ctx context.Context) int {
+	count, _ := ctx.Value(retryCountKey()).(int)
+	return count
+}
+
+// maxRetrySleep caps the sleep between attempts as it grows.
+const maxRetrySleep = 5 * time.Second
+
 // retry calls the provided function and expects it to return (true, wait, err)
 // to keep retrying, and (false, wait, err) to stop retrying and return.
-// The wait value is a time.Duration so the server can recommend a backoff
-// and it will be followed.
+// The wait value is a time.Duration so the server can recommend a backoff,
+// e.g. with HTTP Retry-After or gRPC RetryInfo, and it will be followed
+// instead of the linear backoff as long as it's within the deadline.
 //
-// This is a minimal retry mechanism that backs off linearly, 100ms at a time,
-// up to a maximum of 5 seconds.
+// This is a minimal retry mechanism that backs off linearly, by
+// --otlp-retry-sleep each time (100ms by default), up to a maximum of 5
+// seconds. It gives up after --otlp-retries retries, or when
+// --otlp-retry-timeout or the context's deadline passes, whichever is first.
 // While there are many robust implementations of retries out there, this one
 // is just ~20 LoC and seems to work fine for otel-cli's modest needs. It should
 // be rare for otel-cli to have a long timeout in the first place, and when it
 // does, maybe it's ok to wait a few seconds.
-// TODO: provide --otlp-retries (or something like that) option on CLI
-// TODO: --otlp-retry-sleep? --otlp-retry-timeout?
 // TODO: span events? hmm... feels weird to plumb spans this deep into the client
 // but it's probably fine?
-func retry(ctx context.Context, _ OTLPConfig, fun retryFun) (context.Context, error) {
+func retry(ctx context.Context, config OTLPConfig, fun retryFun) (context.Context, error) {
 	deadline, haveDL := ctx.Deadline()
 	if !haveDL {
-		return ctx, fmt.Errorf("BUG in otel-cli: no deadline set before retry()")
+		return SaveError(ctx, Now(ctx), fmt.Errorf("BUG in otel-cli: no deadline set before retry()"))
+	}
+	// the deadline is turned into a budget so the real clock is only used to
+	// measure elapsed time, and a pinned clock can't stall retries
+	started := time.Now()
+	budget := time.Until(deadline)
+	if timeout := config.GetRetryTimeout(); timeout > 0 && timeout < budget {
+		budget = timeout
 	}
+	maxRetries := config.GetRetries() // negative retries until the deadline
+	endpoint := config.GetEndpoint().String()
 	sleep := time.Duration(0)
-	for {
-		if ctx, keepGoing, wait, err := fun(ctx); err != nil {
-			if keepGoing {
-				if wait > 0 {
-					if time.Now().Add(wait).After(deadline) {
-						// wait will be after deadline, give up now
-						return SaveError(ctx, time.Now(), err)
-					}
-					time.Sleep(wait)
-				} else {
-					time.Sleep(sleep)
-				}
-
-				if time.Now().After(deadline) {
-					return SaveError(ctx, time.Now(), err)
-				}
-
-				// linearly increase sleep time up to 5 seconds
-				if sleep < time.Second*5 {
-					sleep = sleep + time.Millisecond*100
-				}
-			} else {
-				return SaveError(ctx, time.Now(), err)
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			ctx = context.WithValue(ctx, retryCountKey(), GetRetryCount(ctx)+1)
+		}
+
+		var keepGoing bool
+		var wait time.Duration
+		var err error
+		ctx, keepGoing, wait, err = fun(ctx)
+		if err == nil {
+			return ctx, nil
+		}
+
+		// every failed attempt goes in the error list for post-mortems
+		ctx, _ = saveAttemptError(ctx, Now(ctx), endpoint, attempt, err)
+
+		if !keepGoing || (maxRetries >= 0 && attempt > maxRetries) {
+			return ctx, err
+		}
+
+		if wait > 0 {
+			if time.Since(started)+wait > budget {
+				// wait will be after deadline, give up now
+				return ctx, err
 			}
+			time.Sleep(wait)
 		} else {
-			return ctx, nil
+			time.Sleep(sleep)
 		}
+
+		if time.Since(started) > budget {
+			return ctx, err
+		}
+
+		// linearly increase sleep time up to maxRetrySleep
+		sleep = min(sleep+config.GetRetrySleep(), maxRetrySleep)
 	}
 }
 
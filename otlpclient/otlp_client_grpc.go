@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"time"
 
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
@@ -18,9 +22,11 @@ import (
 
 // GrpcClient holds the state for gRPC connections.
 type GrpcClient struct {
-	conn   *grpc.ClientConn
-	client coltracepb.TraceServiceClient
-	config OTLPConfig
+	conn          *grpc.ClientConn
+	client        coltracepb.TraceServiceClient
+	logsClient    collogspb.LogsServiceClient
+	metricsClient colmetricspb.MetricsServiceClient
+	config        OTLPConfig
 }
 
 // NewGrpcClient returns a fresh GrpcClient ready to Start.
@@ -37,6 +43,10 @@ func (gc *GrpcClient) Start(ctx context.Context) (context.Context, error) {
 	if endpointURL.Port() != "" {
 		host = host + ":" + endpointURL.Port()
 	}
+	if IsUnixEndpoint(endpointURL) {
+		// gRPC's unix resolver takes the endpoint as-is
+		host = "unix://" + endpointURL.Path
+	}
 
 	grpcOpts := []grpc.DialOption{}
 
@@ -46,12 +56,37 @@ func (gc *GrpcClient) Start(ctx context.Context) (context.Context, error) {
 		grpcOpts = append(grpcOpts, grpc.WithTransportCredentials(credentials.NewTLS(gc.config.GetTlsConfig())))
 	}
 
+	if proxy := gc.config.GetProxy(); proxy != "" && !IsUnixEndpoint(endpointURL) {
+		proxyURL, err := ParseProxy(proxy)
+		if err != nil {
+			return ctx, err
+		}
+		dialer, err := proxyDialer(proxyURL)
+		if err != nil {
+			return ctx, err
+		}
+		grpcOpts = append(grpcOpts, grpc.WithContextDialer(dialer))
+	}
+
+	if compression := gc.config.GetCompression(); compression != "" {
+		grpcOpts = append(grpcOpts, grpc.WithDefaultCallOptions(grpc.UseCompressor(compression)))
+	}
+
+	if path := gc.config.GetWireDebugFile(); path != "" {
+		if err := openWireDebugFile(path); err != nil {
+			return ctx, err
+		}
+		grpcOpts = append(grpcOpts, grpc.WithChainUnaryInterceptor(wireDebugInterceptor(path)))
+	}
+
 	gc.conn, err = grpc.DialContext(ctx, host, grpcOpts...)
 	if err != nil {
 		return ctx, fmt.Errorf("could not connect to gRPC/OTLP: %w", err)
 	}
 
 	gc.client = coltracepb.NewTraceServiceClient(gc.conn)
+	gc.logsClient = collogspb.NewLogsServiceClient(gc.conn)
+	gc.metricsClient = colmetricspb.NewMetricsServiceClient(gc.conn)
 
 	return ctx, nil
 }
@@ -60,21 +95,64 @@ func (gc *GrpcClient) Start(ctx context.Context) (context.Context, error) {
 // on some errors as needed.
 // TODO: look into grpc.WaitForReady(), esp for status use cases
 func (gc *GrpcClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	req := coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps}
+
 	// add headers onto the request
-	headers := gc.config.GetHeaders()
+	headers, err := signedHeaders(gc.config, &req)
+	if err != nil {
+		return SaveError(ctx, Now(ctx), err)
+	}
 	if len(headers) > 0 {
 		md := metadata.New(headers)
 		ctx = metadata.NewOutgoingContext(ctx, md)
 	}
 
-	req := coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps}
-
 	return retry(ctx, gc.config, func(innerCtx context.Context) (context.Context, bool, time.Duration, error) {
 		etsr, err := gc.client.Export(innerCtx, &req)
 		return processGrpcStatus(innerCtx, etsr, err)
 	})
 }
 
+// UploadLogs sends log records to the server over the same connection as
+// traces, with the same retry behavior.
+func (gc *GrpcClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	req := collogspb.ExportLogsServiceRequest{ResourceLogs: rls}
+
+	headers, err := signedHeaders(gc.config, &req)
+	if err != nil {
+		return SaveError(ctx, Now(ctx), err)
+	}
+	if len(headers) > 0 {
+		md := metadata.New(headers)
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	return retry(ctx, gc.config, func(innerCtx context.Context) (context.Context, bool, time.Duration, error) {
+		_, err := gc.logsClient.Export(innerCtx, &req)
+		return processGrpcStatus(innerCtx, nil, err)
+	})
+}
+
+// UploadMetrics sends metrics to the server over the same connection as
+// traces, with the same retry behavior.
+func (gc *GrpcClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	req := colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: rms}
+
+	headers, err := signedHeaders(gc.config, &req)
+	if err != nil {
+		return SaveError(ctx, Now(ctx), err)
+	}
+	if len(headers) > 0 {
+		md := metadata.New(headers)
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	return retry(ctx, gc.config, func(innerCtx context.Context) (context.Context, bool, time.Duration, error) {
+		_, err := gc.metricsClient.Export(innerCtx, &req)
+		return processGrpcStatus(innerCtx, nil, err)
+	})
+}
+
 // Stop closes the connection to the gRPC server.
 func (gc *GrpcClient) Stop(ctx context.Context) (context.Context, error) {
 	return ctx, gc.conn.Close()
@@ -99,6 +177,13 @@ func processGrpcStatus(ctx context.Context, _ *coltracepb.ExportTraceServiceResp
 		}
 	}
 
+	// when the server sent RetryInfo, pass its delay back to the retry loop
+	// so it sleeps that long instead of the usual backoff
+	var wait time.Duration
+	if ri != nil && ri.RetryDelay != nil {
+		wait = ri.RetryDelay.AsDuration()
+	}
+
 	// handle retriable codes, somewhat lifted from otel collector
 	switch st.Code() {
 	case codes.Aborted,
@@ -107,17 +192,22 @@ func processGrpcStatus(ctx context.Context, _ *coltracepb.ExportTraceServiceResp
 		codes.DeadlineExceeded,
 		codes.OutOfRange,
 		codes.Unavailable:
-		return ctx, true, 0, err
+		return ctx, true, wait, err
 	case codes.ResourceExhausted:
 		// only retry this one if RetryInfo was set
 		if ri != nil && ri.RetryDelay != nil {
-			// when RetryDelay is available, pass it back to the retry loop
-			// so it can sleep that duration
-			wait := time.Duration(ri.RetryDelay.Seconds)*time.Second + time.Duration(ri.RetryDelay.Nanos)*time.Nanosecond
 			return ctx, true, wait, err
 		} else {
 			return ctx, false, 0, err
 		}
+	case codes.InvalidArgument,
+		codes.NotFound,
+		codes.AlreadyExists,
+		codes.PermissionDenied,
+		codes.FailedPrecondition,
+		codes.Unauthenticated:
+		// the gRPC equivalents of an HTTP 4xx
+		return ctx, false, 0, &PermanentError{Err: err}
 	default:
 		// don't retry anything else
 		return ctx, false, 0, err
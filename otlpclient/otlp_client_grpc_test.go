@@ -56,6 +56,13 @@ func TestProcessGrpcStatus(t *testing.T) {
 			err:       retryWithInfo(1),
 			wait:      time.Second,
 		},
+		// RetryInfo is followed on other retriable codes too
+		{
+			etsr:      &coltracepb.ExportTraceServiceResponse{},
+			keepgoing: true,
+			err:       unavailableWithInfo(2),
+			wait:      2 * time.Second,
+		},
 	} {
 		ctx := context.Background()
 		_, kg, wait, err := processGrpcStatus(ctx, tc.etsr, tc.err)
@@ -94,3 +101,13 @@ func retryWithInfo(wait int64) error {
 
 	return st.Err()
 }
+
+func unavailableWithInfo(wait int64) error {
+	st, err := status.New(codes.Unavailable, "Server unavailable").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: &durationpb.Duration{Seconds: wait},
+	})
+	if err != nil {
+		panic("error creating retry info")
+	}
+	return st.Err()
+}
@@ -3,15 +3,19 @@ package otlpclient
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
 	"google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/protobuf/proto"
@@ -32,46 +36,120 @@ func NewHttpClient(config OTLPConfig) *HttpClient {
 // Start sets up the client configuration.
 // TODO: see if there's a way to background start http2 connections?
 func (hc *HttpClient) Start(ctx context.Context) (context.Context, error) {
-	if hc.config.GetInsecure() {
-		hc.client = &http.Client{Timeout: hc.config.GetTimeout()}
-	} else {
+	if path := hc.config.GetWireDebugFile(); path != "" {
+		if err := openWireDebugFile(path); err != nil {
+			return ctx, err
+		}
+	}
+
+	if endpointURL := hc.config.GetEndpoint(); IsUnixEndpoint(endpointURL) {
+		// sockets are local, so there's no TLS or proxy to set up
 		hc.client = &http.Client{
-			Timeout: hc.config.GetTimeout(),
-			Transport: &http.Transport{
-				DialTLS: func(network, addr string) (net.Conn, error) {
-					return tls.Dial(network, addr, hc.config.GetTlsConfig())
-				},
-			},
+			Timeout:   hc.config.GetTimeout(),
+			Transport: &http.Transport{DialContext: unixDialer(endpointURL.Path)},
 		}
+		return ctx, nil
 	}
+
+	proxy, err := httpProxy(hc.config)
+	if err != nil {
+		return ctx, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	if !hc.config.GetInsecure() {
+		transport.TLSClientConfig = hc.config.GetTlsConfig()
+		// stay on HTTP/1.1 like when the TLS dial was done by hand
+		transport.ForceAttemptHTTP2 = false
+	}
+	hc.client = &http.Client{Timeout: hc.config.GetTimeout(), Transport: transport}
 	return ctx, nil
 }
 
 // UploadTraces sends the protobuf spans up to the HTTP server.
 func (hc *HttpClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
 	msg := coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps}
-	protoMsg, err := proto.Marshal(&msg)
+	return hc.post(ctx, hc.endpointURL(), &msg, processHTTPStatus)
+}
+
+// UploadLogs sends the protobuf log records up to the HTTP server. The logs
+// path is used in place of /v1/traces on the configured endpoint.
+func (hc *HttpClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	msg := collogspb.ExportLogsServiceRequest{ResourceLogs: rls}
+	return hc.post(ctx, signalURL(hc.endpointURL(), "/v1/logs"), &msg, processHTTPLogsStatus)
+}
+
+// UploadMetrics sends the protobuf metrics up to the HTTP server, on the
+// metrics path like UploadLogs.
+func (hc *HttpClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	msg := colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: rms}
+	return hc.post(ctx, signalURL(hc.endpointURL(), "/v1/metrics"), &msg, processHTTPMetricsStatus)
+}
+
+// endpointURL returns the URL to send traces to. For Unix socket endpoints
+// that's a placeholder, since the transport dials the socket.
+func (hc *HttpClient) endpointURL() *url.URL {
+	if endpointURL := hc.config.GetEndpoint(); IsUnixEndpoint(endpointURL) {
+		return unixHttpURL()
+	}
+	return hc.config.GetEndpoint()
+}
+
+// signalURL swaps the default /v1/traces path on endpoint for another signal's.
+// Endpoints with any other path are assumed to be configured for the signal
+// already and returned as-is.
+func signalURL(endpoint *url.URL, signalPath string) *url.URL {
+	out := *endpoint
+	if strings.HasSuffix(out.Path, "/v1/traces") {
+		out.Path = strings.TrimSuffix(out.Path, "/v1/traces") + signalPath
+	}
+	return &out
+}
+
+// post marshals the export request, sends it to endpointURL, and hands the
+// response to process, retrying as it says to.
+func (hc *HttpClient) post(ctx context.Context, endpointURL *url.URL, msg proto.Message, process httpStatusFunc) (context.Context, error) {
+	// http/json sends OTLP/JSON, everything else protobuf
+	contentType := protobufContentType
+	marshal := proto.Marshal
+	if hc.config.GetProtocol() == HttpJsonProtocol {
+		contentType = jsonContentType
+		marshal = MarshalOtlpJson
+	}
+	encoded, err := marshal(msg)
+	if err != nil {
+		return SaveError(ctx, Now(ctx), fmt.Errorf("failed to marshal export request: %w", err))
+	}
+	payload, err := compress(hc.config.GetCompression(), encoded)
 	if err != nil {
-		return ctx, fmt.Errorf("failed to marshal trace service request: %w", err)
+		return SaveError(ctx, Now(ctx), fmt.Errorf("failed to compress export request: %w", err))
 	}
-	body := bytes.NewBuffer(protoMsg)
+	body := bytes.NewBuffer(payload)
 
-	endpointURL := hc.config.GetEndpoint()
 	req, err := http.NewRequest("POST", endpointURL.String(), body)
 	if err != nil {
-		return ctx, fmt.Errorf("failed to create HTTP POST request: %w", err)
+		return SaveError(ctx, Now(ctx), fmt.Errorf("failed to create HTTP POST request: %w", err))
 	}
 
-	for k, v := range hc.config.GetHeaders() {
+	headers, err := signedHeaders(hc.config, msg)
+	if err != nil {
+		return SaveError(ctx, Now(ctx), err)
+	}
+	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
-	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Type", contentType)
+	if compression := hc.config.GetCompression(); compression != "" {
+		req.Header.Set("Content-Encoding", compression)
+	}
 
 	return retry(ctx, hc.config, func(context.Context) (context.Context, bool, time.Duration, error) {
 		var body []byte
+		wireDebugHttpRequest(hc.config.GetWireDebugFile(), req, payload)
 		resp, err := hc.client.Do(req)
 		if uerr, ok := err.(*url.Error); ok {
 			// e.g. http on https, un-retriable error, quit now
+			wireDebugHttpResponse(hc.config.GetWireDebugFile(), nil, nil, uerr)
 			return ctx, false, 0, uerr
 		} else {
 			body, err = io.ReadAll(resp.Body)
@@ -79,62 +157,142 @@ func (hc *HttpClient) UploadTraces(ctx context.Context, rsps []*tracepb.Resource
 				return ctx, true, 0, fmt.Errorf("io.Readall of response body failed: %w", err)
 			}
 			resp.Body.Close()
+			wireDebugHttpResponse(hc.config.GetWireDebugFile(), resp, body, nil)
 
-			return processHTTPStatus(ctx, resp, body)
+			return process(ctx, resp, body)
 		}
 	})
 }
 
+// httpStatusFunc checks an HTTP response and returns the same values as retryFun.
+type httpStatusFunc func(ctx context.Context, resp *http.Response, body []byte) (context.Context, bool, time.Duration, error)
+
+// unmarshalFunc decodes a response body in the encoding it was sent in.
+type unmarshalFunc func(data []byte, msg proto.Message) error
+
+// processHTTPLogsStatus is processHTTPStatus for log exports.
+func processHTTPLogsStatus(ctx context.Context, resp *http.Response, body []byte) (context.Context, bool, time.Duration, error) {
+	return processHTTPResponse(ctx, resp, body, func(body []byte, unmarshal unmarshalFunc) error {
+		elsr := collogspb.ExportLogsServiceResponse{}
+		if err := unmarshal(body, &elsr); err != nil {
+			return fmt.Errorf("unmarshal of server response failed: %w", err)
+		}
+		if partial := elsr.GetPartialSuccess(); partial != nil && partial.RejectedLogRecords > 0 {
+			return fmt.Errorf("partial success. %d log records were rejected", partial.GetRejectedLogRecords())
+		}
+		return nil
+	})
+}
+
+// processHTTPMetricsStatus is processHTTPStatus for metric exports.
+func processHTTPMetricsStatus(ctx context.Context, resp *http.Response, body []byte) (context.Context, bool, time.Duration, error) {
+	return processHTTPResponse(ctx, resp, body, func(body []byte, unmarshal unmarshalFunc) error {
+		emsr := colmetricspb.ExportMetricsServiceResponse{}
+		if err := unmarshal(body, &emsr); err != nil {
+			return fmt.Errorf("unmarshal of server response failed: %w", err)
+		}
+		if partial := emsr.GetPartialSuccess(); partial != nil && partial.RejectedDataPoints > 0 {
+			return fmt.Errorf("partial success. %d data points were rejected", partial.GetRejectedDataPoints())
+		}
+		return nil
+	})
+}
+
 // processHTTPStatus takes the http.Response and body, returning the same bool, error
 // as retryFunc. Mostly it's broken out so it can be unit tested.
 func processHTTPStatus(ctx context.Context, resp *http.Response, body []byte) (context.Context, bool, time.Duration, error) {
+	return processHTTPResponse(ctx, resp, body, func(body []byte, unmarshal unmarshalFunc) error {
+		etsr := coltracepb.ExportTraceServiceResponse{}
+		if err := unmarshal(body, &etsr); err != nil {
+			// if the server's sending garbage, no point in retrying
+			return fmt.Errorf("unmarshal of server response failed: %w", err)
+		}
+		if partial := etsr.GetPartialSuccess(); partial != nil && partial.RejectedSpans > 0 {
+			// spec says to stop retrying and drop rejected spans
+			return fmt.Errorf("partial success. %d spans were rejected", partial.GetRejectedSpans())
+		}
+		return nil
+	})
+}
+
+// processHTTPResponse implements the status code handling shared by all
+// signals. checkSuccess decodes a 2xx body and returns an error for partial
+// success, which is never retried.
+func processHTTPResponse(ctx context.Context, resp *http.Response, body []byte, checkSuccess func([]byte, unmarshalFunc) error) (context.Context, bool, time.Duration, error) {
+	// servers respond in the encoding of the request, protobuf unless it was
+	// sent as http/json
+	want, unmarshal := protobufContentType, unmarshalFunc(proto.Unmarshal)
+	if resp.Request != nil && resp.Request.Header.Get("Content-Type") == jsonContentType {
+		want, unmarshal = jsonContentType, UnmarshalOtlpJson
+	}
+
 	// #262 a vendor OTLP server is out of spec and returns JSON instead of protobuf
 	ctype := resp.Header.Get("Content-Type")
+	mediaType, _, _ := strings.Cut(ctype, ";")
 	if ctype == "" {
 		return ctx, false, 0, fmt.Errorf("server is out of specification: Content-Type header is missing or mangled")
-	} else if ctype != "application/x-protobuf" {
-		return ctx, false, 0, fmt.Errorf("server is out of specification: expected content type application/x-protobuf but got %q", ctype)
+	} else if strings.TrimSpace(mediaType) != want {
+		return ctx, false, 0, fmt.Errorf("server is out of specification: expected content type %s but got %q", want, ctype)
 	}
 
 	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 		// success & partial success
 		// spec says server MUST send 200 OK, we'll be generous and accept any 200
-		etsr := coltracepb.ExportTraceServiceResponse{}
-		err := proto.Unmarshal(body, &etsr)
-		if err != nil {
-			// if the server's sending garbage, no point in retrying
-			return ctx, false, 0, fmt.Errorf("unmarshal of server response failed: %w", err)
-		}
-
-		if partial := etsr.GetPartialSuccess(); partial != nil && partial.RejectedSpans > 0 {
-			// spec says to stop retrying and drop rejected spans
-			return ctx, false, 0, fmt.Errorf("partial success. %d spans were rejected", partial.GetRejectedSpans())
-
-		} else {
-			// full success!
-			return ctx, false, 0, nil
+		if err := checkSuccess(body, unmarshal); err != nil {
+			return ctx, false, 0, &PermanentError{Err: err}
 		}
+		return ctx, false, 0, nil
 	} else if resp.StatusCode == 429 || resp.StatusCode == 502 || resp.StatusCode == 503 || resp.StatusCode == 504 {
-		// 429, 502, 503, and 504 must be retried according to spec
-		return ctx, true, 0, fmt.Errorf("server responded with retriable code %d", resp.StatusCode)
+		// 429, 502, 503, and 504 must be retried according to spec, after
+		// the delay in Retry-After when the server sent one
+		wait := parseRetryAfter(resp.Header.Get("Retry-After"), Now(ctx))
+		return ctx, true, wait, fmt.Errorf("server responded with retriable code %d", resp.StatusCode)
 	} else if resp.StatusCode >= 300 && resp.StatusCode < 400 {
 		// spec doesn't say anything about 300's, ignore body and assume they're errors and unretriable
 		return ctx, false, 0, fmt.Errorf("server returned unsupported code %d", resp.StatusCode)
 	} else if resp.StatusCode >= 400 {
 		// https://github.com/open-telemetry/opentelemetry-proto/blob/main/docs/specification.md#failures-1
 		st := status.Status{}
-		err := proto.Unmarshal(body, &st)
+		err := unmarshal(body, &st)
 		if err != nil {
-			return ctx, false, 0, fmt.Errorf("unmarshal of server status failed: %w", err)
+			err = fmt.Errorf("unmarshal of server status failed: %w", err)
 		} else {
-			return ctx, false, 0, fmt.Errorf("server returned unretriable code %d with status: %s", resp.StatusCode, st.GetMessage())
+			err = fmt.Errorf("server returned unretriable code %d with status: %s", resp.StatusCode, st.GetMessage())
 		}
+		// the server refused this request, 5xx are the server's own problem
+		if resp.StatusCode < 500 {
+			err = &PermanentError{Err: err}
+		}
+		return ctx, false, 0, err
 	}
 
 	// should never happen
 	return ctx, false, 0, fmt.Errorf("BUG: fell through error checking with status code %d", resp.StatusCode)
 }
 
+// parseRetryAfter returns how long a Retry-After header asks clients to wait,
+// given either as seconds or as an HTTP date. It returns 0 when the header is
+// missing, invalid, or in the past, so the usual backoff is used instead.
+func parseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+
+	if secs, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
+
 // Stop does nothing for HTTP, for now. It exists to fulfill the interface.
 func (hc *HttpClient) Stop(ctx context.Context) (context.Context, error) {
 	return ctx, nil
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
@@ -23,6 +25,7 @@ func TestProcessHTTPStatus(t *testing.T) {
 		body      []byte
 		keepgoing bool
 		err       error
+		wait      time.Duration
 	}{
 		// simple success
 		{
@@ -91,6 +94,20 @@ func TestProcessHTTPStatus(t *testing.T) {
 			keepgoing: true,
 			err:       fmt.Errorf("server responded with retriable code 504"),
 		},
+		// Retry-After is passed back as the wait
+		{
+			resp: &http.Response{
+				StatusCode: 429,
+				Header: http.Header{
+					"Content-Type": []string{"application/x-protobuf"},
+					"Retry-After":  []string{"3"},
+				},
+			},
+			body:      errorBody(429, "slow down"),
+			keepgoing: true,
+			err:       fmt.Errorf("server responded with retriable code 429"),
+			wait:      3 * time.Second,
+		},
 		// 300's are unsupported
 		{
 			resp: &http.Response{
@@ -130,12 +147,16 @@ func TestProcessHTTPStatus(t *testing.T) {
 		},
 	} {
 		ctx := context.Background()
-		_, kg, _, err := processHTTPStatus(ctx, tc.resp, tc.body)
+		_, kg, wait, err := processHTTPStatus(ctx, tc.resp, tc.body)
 
 		if kg != tc.keepgoing {
 			t.Errorf("keepgoing value returned %t but expected %t", kg, tc.keepgoing)
 		}
 
+		if wait != tc.wait {
+			t.Errorf("expected a wait value of %s but got %s", tc.wait, wait)
+		}
+
 		if tc.err == nil && err != nil {
 			t.Errorf("received an unexpected error")
 		} else if tc.err != nil && err == nil {
@@ -148,6 +169,24 @@ func TestProcessHTTPStatus(t *testing.T) {
 	}
 }
 
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for header, want := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		" 5 ":                           5 * time.Second,
+		"0":                             0,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 23:59:00 GMT": 0, // already passed
+	} {
+		if got := parseRetryAfter(header, now); got != want {
+			t.Errorf("expected Retry-After %q to be %s but got %s", header, want, got)
+		}
+	}
+}
+
 func etsrSuccessBody() []byte {
 	etsr := coltracepb.ExportTraceServiceResponse{
 		PartialSuccess: nil,
@@ -176,3 +215,20 @@ func errorBody(c int32, message string) []byte {
 	b, _ := proto.Marshal(&st)
 	return b
 }
+
+func TestSignalURL(t *testing.T) {
+	for _, tc := range []struct {
+		endpoint string
+		signal   string
+		want     string
+	}{
+		{endpoint: "http://localhost:4318/v1/traces", signal: "/v1/logs", want: "http://localhost:4318/v1/logs"},
+		{endpoint: "https://otlp.example.com/prefix/v1/traces", signal: "/v1/metrics", want: "https://otlp.example.com/prefix/v1/metrics"},
+		{endpoint: "http://localhost:4318/custom/metrics", signal: "/v1/metrics", want: "http://localhost:4318/custom/metrics"},
+	} {
+		endpoint, _ := url.Parse(tc.endpoint)
+		if got := signalURL(endpoint, tc.signal).String(); got != tc.want {
+			t.Errorf("signalURL(%q, %q) = %q, expected %q", tc.endpoint, tc.signal, got, tc.want)
+		}
+	}
+}
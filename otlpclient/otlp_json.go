@@ -0,0 +1,116 @@
+package otlpclient
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// HttpJsonProtocol is the --protocol for OTLP/HTTP with JSON payloads.
+const HttpJsonProtocol = "http/json"
+
+// content types for the two OTLP/HTTP encodings
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+)
+
+// otlpJsonIdKeys are the fields that OTLP/JSON encodes as hex instead of the
+// base64 the protobuf JSON mapping uses for bytes.
+var otlpJsonIdKeys = map[string]bool{
+	"traceId":        true,
+	"spanId":         true,
+	"parentSpanId":   true,
+	"trace_id":       true,
+	"span_id":        true,
+	"parent_span_id": true,
+}
+
+// MarshalOtlpJson encodes an export request of any signal as OTLP/JSON,
+// which is the protobuf JSON mapping with trace and span ids in hex. It's
+// used by the http/json client, otlpserver's sinks, and otel-cli's JSON
+// output alike.
+func MarshalOtlpJson(msg proto.Message) ([]byte, error) {
+	js, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OTLP/JSON: %w", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(js, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP/JSON: %w", err)
+	}
+
+	return json.Marshal(base64IdsToHex(doc))
+}
+
+// UnmarshalOtlpJson decodes an OTLP/JSON encoded message, usually an export
+// request or response, ignoring fields this version of the protos doesn't
+// know about. Trace and span ids are converted from hex to base64 before
+// handing off to protojson.
+func UnmarshalOtlpJson(data []byte, msg proto.Message) error {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse OTLP/JSON: %w", err)
+	}
+
+	fixed, err := json.Marshal(hexIdsToBase64(doc))
+	if err != nil {
+		return fmt.Errorf("failed to re-encode OTLP/JSON: %w", err)
+	}
+
+	err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(fixed, msg)
+	if err != nil {
+		return fmt.Errorf("failed to decode OTLP/JSON: %w", err)
+	}
+
+	return nil
+}
+
+// hexIdsToBase64 walks the decoded JSON and rewrites hex id fields in place.
+func hexIdsToBase64(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if s, ok := val.(string); ok && otlpJsonIdKeys[key] {
+				if id, err := hex.DecodeString(s); err == nil {
+					v[key] = base64.StdEncoding.EncodeToString(id)
+				}
+			} else {
+				v[key] = hexIdsToBase64(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = hexIdsToBase64(val)
+		}
+	}
+
+	return doc
+}
+
+// base64IdsToHex walks the decoded JSON and rewrites base64 id fields in place.
+func base64IdsToHex(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			if s, ok := val.(string); ok && otlpJsonIdKeys[key] {
+				if id, err := base64.StdEncoding.DecodeString(s); err == nil {
+					v[key] = hex.EncodeToString(id)
+				}
+			} else {
+				v[key] = base64IdsToHex(val)
+			}
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = base64IdsToHex(val)
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,146 @@
+package otlpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// jsonTestConfig points retryTestConfig at an HTTP server with a protocol.
+type jsonTestConfig struct {
+	retryTestConfig
+	endpoint *url.URL
+	protocol string
+}
+
+func (c jsonTestConfig) GetEndpoint() *url.URL { return c.endpoint }
+func (c jsonTestConfig) GetProtocol() string   { return c.protocol }
+
+func TestHttpJsonClient(t *testing.T) {
+	span := routeTestSpan("as-json", nil)
+	span.TraceId, _ = hex.DecodeString("0102030405060708090a0b0c0d0e0f10")
+	span.SpanId, _ = hex.DecodeString("0101010101010101")
+	span.ParentSpanId, _ = hex.DecodeString("0202020202020202")
+	span.Links = []*tracepb.Span_Link{{TraceId: span.TraceId, SpanId: span.ParentSpanId}}
+	rsps := []*tracepb.ResourceSpans{routeTestResourceSpans("svc", span)}
+
+	for _, tc := range []struct {
+		name     string
+		protocol string
+		respType string
+		respBody string
+		wantType string
+		wantErr  string
+	}{
+		{
+			name:     "json",
+			protocol: HttpJsonProtocol,
+			respType: "application/json; charset=utf-8",
+			respBody: `{"partialSuccess": {}, "someNewField": true}`,
+			wantType: "application/json",
+		},
+		{
+			name:     "partial success in json",
+			protocol: HttpJsonProtocol,
+			respType: "application/json",
+			respBody: `{"partialSuccess": {"rejectedSpans": "1"}}`,
+			wantType: "application/json",
+			wantErr:  "partial success. 1 spans were rejected",
+		},
+		{
+			name:     "protobuf response to json",
+			protocol: HttpJsonProtocol,
+			respType: "application/x-protobuf",
+			wantType: "application/json",
+			wantErr:  `expected content type application/json but got "application/x-protobuf"`,
+		},
+		{
+			name:     "protobuf",
+			protocol: "http/protobuf",
+			respType: "application/x-protobuf",
+			wantType: "application/x-protobuf",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotType string
+			var got map[string]interface{}
+			srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				gotType = req.Header.Get("Content-Type")
+				body, _ := io.ReadAll(req.Body)
+				json.Unmarshal(body, &got)
+				rw.Header().Set("Content-Type", tc.respType)
+				io.WriteString(rw, tc.respBody)
+			}))
+			defer srv.Close()
+
+			endpoint, _ := url.Parse(srv.URL + "/v1/traces")
+			client := NewHttpClient(jsonTestConfig{
+				retryTestConfig: retryTestConfig{retries: 1},
+				endpoint:        endpoint,
+				protocol:        tc.protocol,
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			ctx, _ = client.Start(ctx)
+			_, err := client.UploadTraces(ctx, rsps)
+
+			if tc.wantErr == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Errorf("expected an error containing %q, got %v", tc.wantErr, err)
+			}
+			if gotType != tc.wantType {
+				t.Errorf("expected request content type %q, got %q", tc.wantType, gotType)
+			}
+			if tc.wantType != "application/json" {
+				return
+			}
+
+			// OTLP/JSON ids are hex, everywhere they appear
+			js, _ := json.Marshal(got)
+			for _, want := range []string{
+				`"traceId":"0102030405060708090a0b0c0d0e0f10"`,
+				`"spanId":"0101010101010101"`,
+				`"parentSpanId":"0202020202020202"`,
+				`"links":[{"spanId":"0202020202020202","traceId":"0102030405060708090a0b0c0d0e0f10"}]`,
+				`"name":"as-json"`,
+			} {
+				if !strings.Contains(string(js), want) {
+					t.Errorf("expected the request to contain %s, got %s", want, js)
+				}
+			}
+		})
+	}
+}
+
+func TestOtlpJsonRoundTrip(t *testing.T) {
+	span := routeTestSpan("round-trip", nil)
+	span.TraceId, _ = hex.DecodeString("0102030405060708090a0b0c0d0e0f10")
+	span.SpanId, _ = hex.DecodeString("0101010101010101")
+
+	js, err := MarshalOtlpJson(span)
+	if err != nil {
+		t.Fatalf("MarshalOtlpJson failed: %s", err)
+	}
+	if !strings.Contains(string(js), `"traceId":"0102030405060708090a0b0c0d0e0f10"`) {
+		t.Errorf("expected a hex trace id, got %s", js)
+	}
+
+	var got tracepb.Span
+	if err := UnmarshalOtlpJson(js, &got); err != nil {
+		t.Fatalf("UnmarshalOtlpJson failed: %s", err)
+	}
+	if !bytes.Equal(got.TraceId, span.TraceId) || !bytes.Equal(got.SpanId, span.SpanId) || got.Name != "round-trip" {
+		t.Errorf("expected the span back, got %v", &got)
+	}
+}
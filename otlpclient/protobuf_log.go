@@ -0,0 +1,58 @@
+package otlpclient
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// NewProtobufLogRecord returns a log record with the timestamps set to now
+// and the severity set to INFO.
+func NewProtobufLogRecord() *logspb.LogRecord {
+	now := uint64(time.Now().UnixNano())
+	return &logspb.LogRecord{
+		TimeUnixNano:         now,
+		ObservedTimeUnixNano: now,
+		SeverityNumber:       logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:         "INFO",
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: ""}},
+		Attributes:           []*commonpb.KeyValue{},
+	}
+}
+
+// severities maps the short names accepted on the command line to the
+// severity number of each range, per the OTel logs data model.
+var severities = map[string]logspb.SeverityNumber{
+	"trace": logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	"debug": logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	"info":  logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"warn":  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"error": logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"fatal": logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+// SeverityStringToNumber converts a severity name like "warn" or "ERROR2" to
+// its severity number. A trailing 1-4 selects a finer level within the range,
+// e.g. info3, like the OTel short names.
+func SeverityStringToNumber(in string) (logspb.SeverityNumber, error) {
+	name := strings.ToLower(in)
+	if name == "warning" {
+		name = "warn"
+	}
+
+	var offset int32
+	if n := len(name); n > 1 && name[n-1] >= '1' && name[n-1] <= '4' {
+		offset = int32(name[n-1] - '1')
+		name = name[:n-1]
+	}
+
+	base, ok := severities[name]
+	if !ok {
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, fmt.Errorf("invalid log severity %q, must be one of trace, debug, info, warn, error, fatal", in)
+	}
+
+	return base + logspb.SeverityNumber(offset), nil
+}
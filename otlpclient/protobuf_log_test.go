@@ -0,0 +1,55 @@
+package otlpclient
+
+import (
+	"testing"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+func TestNewProtobufLogRecord(t *testing.T) {
+	lr := NewProtobufLogRecord()
+	if lr.TimeUnixNano == 0 || lr.TimeUnixNano != lr.ObservedTimeUnixNano {
+		t.Errorf("expected time and observed time to be set to now, got %d and %d", lr.TimeUnixNano, lr.ObservedTimeUnixNano)
+	}
+	if lr.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("expected default severity of INFO, got %s", lr.SeverityNumber)
+	}
+}
+
+func TestSeverityStringToNumber(t *testing.T) {
+	for _, testcase := range []struct {
+		name    string
+		want    logspb.SeverityNumber
+		wantErr bool
+	}{
+		{name: "trace", want: logspb.SeverityNumber_SEVERITY_NUMBER_TRACE},
+		{name: "debug", want: logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG},
+		{name: "info", want: logspb.SeverityNumber_SEVERITY_NUMBER_INFO},
+		{name: "INFO", want: logspb.SeverityNumber_SEVERITY_NUMBER_INFO},
+		{name: "info3", want: logspb.SeverityNumber_SEVERITY_NUMBER_INFO3},
+		{name: "warn", want: logspb.SeverityNumber_SEVERITY_NUMBER_WARN},
+		{name: "warning", want: logspb.SeverityNumber_SEVERITY_NUMBER_WARN},
+		{name: "error4", want: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR4},
+		{name: "fatal", want: logspb.SeverityNumber_SEVERITY_NUMBER_FATAL},
+		{name: "info5", wantErr: true},
+		{name: "4", wantErr: true},
+		{name: "", wantErr: true},
+		{name: "speledwrong", wantErr: true},
+	} {
+		t.Run(testcase.name, func(t *testing.T) {
+			out, err := SeverityStringToNumber(testcase.name)
+			if testcase.wantErr {
+				if err == nil {
+					t.Errorf("expected an error for %q but got %s", testcase.name, out)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if out != testcase.want {
+				t.Errorf("returned the wrong value, '%s', for '%s'", out, testcase.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package otlpclient
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+// NewProtobufCounter returns a monotonic delta sum with a single data point
+// at now, i.e. an increment of the counter by value.
+func NewProtobufCounter(name string, value string, attrs map[string]string) (*metricspb.Metric, error) {
+	dp, err := newProtobufNumberDataPoint(value, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if dp.GetAsInt() < 0 || dp.GetAsDouble() < 0 {
+		return nil, fmt.Errorf("invalid counter value %q, counters can't go down", value)
+	}
+	dp.StartTimeUnixNano = dp.TimeUnixNano
+
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			DataPoints:             []*metricspb.NumberDataPoint{dp},
+			AggregationTemporality: metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+			IsMonotonic:            true,
+		}},
+	}, nil
+}
+
+// NewProtobufGauge returns a gauge with a single data point at now.
+func NewProtobufGauge(name string, value string, attrs map[string]string) (*metricspb.Metric, error) {
+	dp, err := newProtobufNumberDataPoint(value, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{dp},
+		}},
+	}, nil
+}
+
+// newProtobufNumberDataPoint parses value into a data point at now. Values
+// that parse as integers are sent as ints, anything else as doubles.
+func newProtobufNumberDataPoint(value string, attrs map[string]string) (*metricspb.NumberDataPoint, error) {
+	dp := metricspb.NumberDataPoint{
+		TimeUnixNano: uint64(time.Now().UnixNano()),
+		Attributes:   StringMapAttrsToProtobuf(attrs),
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		dp.Value = &metricspb.NumberDataPoint_AsInt{AsInt: i}
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		dp.Value = &metricspb.NumberDataPoint_AsDouble{AsDouble: f}
+	} else {
+		return nil, fmt.Errorf("invalid metric value %q, must be a number", value)
+	}
+
+	return &dp, nil
+}
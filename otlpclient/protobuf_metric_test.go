@@ -0,0 +1,54 @@
+package otlpclient
+
+import (
+	"testing"
+
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+)
+
+func TestNewProtobufCounter(t *testing.T) {
+	m, err := NewProtobufCounter("job.runs", "1", map[string]string{"status": "ok"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sum := m.GetSum()
+	if sum == nil || !sum.IsMonotonic || sum.AggregationTemporality != metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		t.Fatalf("expected a monotonic delta sum, got %v", m)
+	}
+	dp := sum.DataPoints[0]
+	if dp.GetAsInt() != 1 || dp.StartTimeUnixNano != dp.TimeUnixNano || len(dp.Attributes) != 1 {
+		t.Errorf("unexpected data point %v", dp)
+	}
+
+	for _, value := range []string{"-1", "-0.5", "many"} {
+		if _, err := NewProtobufCounter("job.runs", value, nil); err == nil {
+			t.Errorf("expected an error for counter value %q", value)
+		}
+	}
+}
+
+func TestNewProtobufGauge(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  *metricspb.NumberDataPoint
+	}{
+		{value: "12345", want: &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsInt{AsInt: 12345}}},
+		{value: "-3", want: &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsInt{AsInt: -3}}},
+		{value: "0.75", want: &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 0.75}}},
+		{value: "1e3", want: &metricspb.NumberDataPoint{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 1000}}},
+	} {
+		m, err := NewProtobufGauge("disk.free", tc.value, nil)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tc.value, err)
+			continue
+		}
+		dp := m.GetGauge().GetDataPoints()[0]
+		if dp.GetAsInt() != tc.want.GetAsInt() || dp.GetAsDouble() != tc.want.GetAsDouble() {
+			t.Errorf("gauge %q got value %v, expected %v", tc.value, dp.Value, tc.want.Value)
+		}
+	}
+
+	if _, err := NewProtobufGauge("disk.free", "", nil); err == nil {
+		t.Error("expected an error for an empty value")
+	}
+}
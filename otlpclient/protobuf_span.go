@@ -8,15 +8,21 @@ package otlpclient
 
 import (
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
+	"fmt"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/equinix-labs/otel-cli/w3c/traceparent"
 	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 type SpanConfig interface {
@@ -125,6 +131,7 @@ func SpanKindIntToString(kind tracepb.Span_SpanKind) string {
 // SpanKindIntToString takes a string representation of a span kind and
 // returns the OTel protobuf integer/constant.
 func SpanKindStringToInt(kind string) tracepb.Span_SpanKind {
+	kind, _ = NormalizeSpanKind(kind)
 	switch kind {
 	case "client":
 		return tracepb.Span_SPAN_KIND_CLIENT
@@ -144,6 +151,7 @@ func SpanKindStringToInt(kind string) tracepb.Span_SpanKind {
 // SpanStatusStringToInt takes a supported string span status and returns the otel
 // constant for it. Returns default of Unset on no match.
 func SpanStatusStringToInt(status string) tracepb.Status_StatusCode {
+	status, _ = NormalizeSpanStatus(status)
 	switch status {
 	case "unset":
 		return tracepb.Status_STATUS_CODE_UNSET
@@ -156,16 +164,83 @@ func SpanStatusStringToInt(status string) tracepb.Status_StatusCode {
 	}
 }
 
+// spanKindNames are the span kinds otel-cli takes.
+var spanKindNames = []string{"client", "server", "producer", "consumer", "internal", "unspecified"}
+
+// spanStatusNames are the span statuses otel-cli takes.
+var spanStatusNames = []string{"unset", "ok", "error"}
+
+// spanKindSynonyms maps the OTLP enum numbers to the otel-cli names.
+var spanKindSynonyms = map[string]string{
+	"0": "unspecified",
+	"1": "internal",
+	"2": "server",
+	"3": "client",
+	"4": "producer",
+	"5": "consumer",
+}
+
+// spanStatusSynonyms maps OTLP enum numbers and common words to otel-cli names.
+var spanStatusSynonyms = map[string]string{
+	"0":         "unset",
+	"1":         "ok",
+	"2":         "error",
+	"success":   "ok",
+	"succeeded": "ok",
+	"passed":    "ok",
+	"err":       "error",
+	"fail":      "error",
+	"failed":    "error",
+	"failure":   "error",
+}
+
+// NormalizeSpanKind returns the otel-cli name for any case of a span kind,
+// its OTLP enum name, or its number. Empty is unspecified.
+func NormalizeSpanKind(kind string) (string, error) {
+	name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(kind)), "span_kind_")
+	if name == "" {
+		return "unspecified", nil
+	}
+	if synonym, ok := spanKindSynonyms[name]; ok {
+		return synonym, nil
+	}
+	if slices.Contains(spanKindNames, name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown span kind %q, expected one of %s", kind, strings.Join(spanKindNames, ", "))
+}
+
+// NormalizeSpanStatus returns the otel-cli name for any case of a span status
+// code, its OTLP enum name, its number, or a synonym. Empty is unset.
+func NormalizeSpanStatus(status string) (string, error) {
+	name := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(status)), "status_code_")
+	if name == "" {
+		return "unset", nil
+	}
+	if synonym, ok := spanStatusSynonyms[name]; ok {
+		return synonym, nil
+	}
+	if slices.Contains(spanStatusNames, name) {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown span status code %q, expected one of %s", status, strings.Join(spanStatusNames, ", "))
+}
+
 // StringMapAttrsToProtobuf takes a map of string:string, such as that from --attrs
-// and returns them in an []*commonpb.KeyValue
+// and returns them in an []*commonpb.KeyValue. OTLP strings must be valid
+// UTF-8 or the export gets rejected, so invalid sequences in keys are
+// replaced and values that aren't valid UTF-8 are sent as bytes instead.
 func StringMapAttrsToProtobuf(attributes map[string]string) []*commonpb.KeyValue {
 	out := []*commonpb.KeyValue{}
 
 	for k, v := range attributes {
 		av := new(commonpb.AnyValue)
 
-		// try to parse as numbers, and fall through to string
-		if i, err := strconv.ParseInt(v, 0, 64); err == nil {
+		// binary data goes as bytes, otherwise try to parse as numbers,
+		// and fall through to string
+		if !utf8.ValidString(v) {
+			av.Value = &commonpb.AnyValue_BytesValue{BytesValue: []byte(v)}
+		} else if i, err := strconv.ParseInt(v, 10, 64); err == nil {
 			av.Value = &commonpb.AnyValue_IntValue{IntValue: i}
 		} else if f, err := strconv.ParseFloat(v, 64); err == nil {
 			av.Value = &commonpb.AnyValue_DoubleValue{DoubleValue: f}
@@ -176,7 +251,7 @@ func StringMapAttrsToProtobuf(attributes map[string]string) []*commonpb.KeyValue
 		}
 
 		akv := commonpb.KeyValue{
-			Key:   k,
+			Key:   ValidUTF8(k),
 			Value: av,
 		}
 
@@ -186,6 +261,66 @@ func StringMapAttrsToProtobuf(attributes map[string]string) []*commonpb.KeyValue
 	return out
 }
 
+// BytesMapAttrsToProtobuf takes a map of string:[]byte, such as that from
+// --attr-bytes, and returns them as bytes attributes in an []*commonpb.KeyValue.
+func BytesMapAttrsToProtobuf(attributes map[string][]byte) []*commonpb.KeyValue {
+	out := []*commonpb.KeyValue{}
+
+	for k, v := range attributes {
+		out = append(out, &commonpb.KeyValue{
+			Key:   ValidUTF8(k),
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}},
+		})
+	}
+
+	return out
+}
+
+// ValidUTF8 returns the string with any invalid UTF-8 replaced with the
+// unicode replacement character, since OTLP requires strings to be valid.
+func ValidUTF8(in string) string {
+	return strings.ToValidUTF8(in, "\uFFFD")
+}
+
+// ValidUTF8Message runs ValidUTF8 on every string field in the message and
+// the messages inside it, e.g. span names, log bodies, and attribute keys.
+// One invalid string fails the whole export, so this is done to everything
+// right before it's sent.
+func ValidUTF8Message(m proto.Message) {
+	validUTF8Message(m.ProtoReflect())
+}
+
+func validUTF8Message(m protoreflect.Message) {
+	fixed := map[protoreflect.FieldDescriptor]string{}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if fd.Kind() == protoreflect.StringKind {
+					list.Set(i, protoreflect.ValueOfString(ValidUTF8(list.Get(i).String())))
+				} else if fd.Message() != nil {
+					validUTF8Message(list.Get(i).Message())
+				}
+			}
+		case fd.IsMap():
+			// OTLP doesn't use maps
+		case fd.Kind() == protoreflect.StringKind:
+			if !utf8.ValidString(v.String()) {
+				fixed[fd] = ValidUTF8(v.String())
+			}
+		case fd.Message() != nil:
+			validUTF8Message(v.Message())
+		}
+		return true
+	})
+
+	// set after Range, which doesn't allow changing the fields it's visiting
+	for fd, s := range fixed {
+		m.Set(fd, protoreflect.ValueOfString(s))
+	}
+}
+
 // SpanAttributesToStringMap converts the span's attributes to a string map.
 func SpanAttributesToStringMap(span *tracepb.Span) map[string]string {
 	out := make(map[string]string)
@@ -203,7 +338,7 @@ func ResourceAttributesToStringMap(rss *tracepb.ResourceSpans) map[string]string
 	}
 
 	out := make(map[string]string)
-	for _, attr := range rss.Resource.Attributes {
+	for _, attr := range rss.GetResource().GetAttributes() {
 		out[attr.Key] = AnyValueToString(attr.GetValue())
 	}
 	return out
@@ -217,6 +352,8 @@ func AnyValueToString(v *commonpb.AnyValue) string {
 		return strconv.FormatInt(v.GetIntValue(), 10)
 	} else if _, ok := v.Value.(*commonpb.AnyValue_DoubleValue); ok {
 		return strconv.FormatFloat(v.GetDoubleValue(), byte('f'), -1, 64)
+	} else if _, ok := v.Value.(*commonpb.AnyValue_BoolValue); ok {
+		return strconv.FormatBool(v.GetBoolValue())
 	} else if _, ok := v.Value.(*commonpb.AnyValue_ArrayValue); ok {
 		values := v.GetArrayValue().GetValues()
 		strValues := make([]string, len(values))
@@ -225,6 +362,8 @@ func AnyValueToString(v *commonpb.AnyValue) string {
 			strValues[i] = AnyValueToString(v)
 		}
 		return strings.Join(strValues, ",")
+	} else if _, ok := v.Value.(*commonpb.AnyValue_BytesValue); ok {
+		return base64.StdEncoding.EncodeToString(v.GetBytesValue())
 	}
 
 	return ""
@@ -240,6 +379,7 @@ func SpanToStringMap(span *tracepb.Span, rss *tracepb.ResourceSpans) map[string]
 		"trace_id":           hex.EncodeToString(span.GetTraceId()),
 		"span_id":            hex.EncodeToString(span.GetSpanId()),
 		"parent_span_id":     hex.EncodeToString(span.GetParentSpanId()),
+		"trace_state":        span.GetTraceState(),
 		"name":               span.Name,
 		"kind":               SpanKindIntToString(span.GetKind()),
 		"start":              strconv.FormatUint(span.StartTimeUnixNano, 10),
@@ -248,17 +388,42 @@ func SpanToStringMap(span *tracepb.Span, rss *tracepb.ResourceSpans) map[string]
 		"service_attributes": flattenStringMap(ResourceAttributesToStringMap(rss), "{}"),
 		"status_code":        strconv.FormatInt(int64(span.Status.GetCode()), 10),
 		"status_description": span.Status.GetMessage(),
+		"links":              linksToString(span.GetLinks()),
+	}
+}
+
+// linksToString flattens span links into the same traceid-spanid:k=v,k=v form
+// used to pass them in, separated by semicolons. Only used by tests.
+func linksToString(links []*tracepb.Span_Link) string {
+	out := make([]string, len(links))
+	for i, link := range links {
+		out[i] = hex.EncodeToString(link.GetTraceId()) + "-" + hex.EncodeToString(link.GetSpanId())
+		attrs := map[string]string{}
+		for _, kv := range link.GetAttributes() {
+			attrs[kv.Key] = AnyValueToString(kv.Value)
+		}
+		if len(attrs) > 0 {
+			out[i] += ":" + flattenStringMap(attrs, "")
+		}
 	}
+	return strings.Join(out, ";")
 }
 
-// TraceparentFromProtobufSpan builds a Traceparent struct from the provided span.
+// TraceparentFromProtobufSpan builds a Traceparent struct from the provided
+// span, including its tracestate when it's valid.
 func TraceparentFromProtobufSpan(span *tracepb.Span, recording bool) traceparent.Traceparent {
+	ts, err := traceparent.ParseTracestate(span.TraceState)
+	if err != nil {
+		ts = traceparent.Tracestate{}
+	}
+
 	return traceparent.Traceparent{
 		Version:     0,
 		TraceId:     span.TraceId,
 		SpanId:      span.SpanId,
 		Sampling:    recording,
 		Initialized: true,
+		Tracestate:  ts,
 	}
 }
 
@@ -5,7 +5,9 @@ import (
 	"strconv"
 	"testing"
 
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestNewProtobufSpan(t *testing.T) {
@@ -96,6 +98,14 @@ func TestSpanKindStringToInt(t *testing.T) {
 			name: "unspecified",
 			want: tracepb.Span_SPAN_KIND_UNSPECIFIED,
 		},
+		{
+			name: "SERVER",
+			want: tracepb.Span_SPAN_KIND_SERVER,
+		},
+		{
+			name: "SPAN_KIND_PRODUCER",
+			want: tracepb.Span_SPAN_KIND_PRODUCER,
+		},
 		{
 			name: "speledwrong",
 			want: tracepb.Span_SPAN_KIND_UNSPECIFIED,
@@ -168,6 +178,14 @@ func TestSpanStatusStringToInt(t *testing.T) {
 			name: "error",
 			want: tracepb.Status_STATUS_CODE_ERROR,
 		},
+		{
+			name: "Err",
+			want: tracepb.Status_STATUS_CODE_ERROR,
+		},
+		{
+			name: "STATUS_CODE_OK",
+			want: tracepb.Status_STATUS_CODE_OK,
+		},
 		{
 			name: "cromulent",
 			want: tracepb.Status_STATUS_CODE_UNSET,
@@ -182,6 +200,46 @@ func TestSpanStatusStringToInt(t *testing.T) {
 	}
 }
 
+func TestNormalizeSpanKindAndStatus(t *testing.T) {
+	for in, want := range map[string]string{
+		"server":           "server",
+		"SERVER":           "server",
+		" Internal ":       "internal",
+		"span_kind_client": "client",
+		"4":                "producer",
+		"":                 "unspecified",
+	} {
+		got, err := NormalizeSpanKind(in)
+		if err != nil || got != want {
+			t.Errorf("expected kind %q for %q, got %q, %v", want, in, got, err)
+		}
+	}
+	_, err := NormalizeSpanKind("sever")
+	if err == nil || err.Error() != `unknown span kind "sever", expected one of client, server, producer, consumer, internal, unspecified` {
+		t.Errorf("expected an error listing the kinds, got %v", err)
+	}
+
+	for in, want := range map[string]string{
+		"ok":                "ok",
+		"OK":                "ok",
+		"Success":           "ok",
+		"Err":               "error",
+		"FAILED":            "error",
+		"STATUS_CODE_UNSET": "unset",
+		"2":                 "error",
+		"":                  "unset",
+	} {
+		got, err := NormalizeSpanStatus(in)
+		if err != nil || got != want {
+			t.Errorf("expected status %q for %q, got %q, %v", want, in, got, err)
+		}
+	}
+	_, err = NormalizeSpanStatus("cromulent")
+	if err == nil || err.Error() != `unknown span status code "cromulent", expected one of unset, ok, error` {
+		t.Errorf("expected an error listing the statuses, got %v", err)
+	}
+}
+
 func TestCliAttrsToOtel(t *testing.T) {
 
 	testAttrs := map[string]string{
@@ -231,3 +289,72 @@ func TestCliAttrsToOtel(t *testing.T) {
 		}
 	}
 }
+
+func TestAttrsToOtelUTF8(t *testing.T) {
+	attrs := StringMapAttrsToProtobuf(map[string]string{
+		"unicode":      "café ☕",
+		"control":      "tab\there\x1b[0m",
+		"binary":       "\xff\xfe\x00",
+		"bad key \xff": "ok",
+	})
+	attrs = append(attrs, BytesMapAttrsToProtobuf(map[string][]byte{"blob": {0, 1, 2}})...)
+
+	for _, attr := range attrs {
+		switch attr.Key {
+		case "unicode", "control":
+			// valid UTF-8 stays a string, control characters and all
+			if _, ok := attr.Value.Value.(*commonpb.AnyValue_StringValue); !ok {
+				t.Errorf("expected a string value for %q but got %T", attr.Key, attr.Value.Value)
+			}
+		case "binary":
+			if !bytes.Equal(attr.Value.GetBytesValue(), []byte("\xff\xfe\x00")) {
+				t.Errorf("expected the raw bytes for %q but got %v", attr.Key, attr.Value)
+			}
+		case "bad key �":
+			if attr.Value.GetStringValue() != "ok" {
+				t.Errorf("expected value ok for %q but got %v", attr.Key, attr.Value)
+			}
+		case "blob":
+			if AnyValueToString(attr.Value) != "AAEC" {
+				t.Errorf("expected blob to stringify as AAEC but got %q", AnyValueToString(attr.Value))
+			}
+		default:
+			t.Errorf("unexpected attribute key %q", attr.Key)
+		}
+	}
+
+	// protobuf refuses to marshal invalid UTF-8 strings, which is how this
+	// would fail in the real world
+	span := NewProtobufSpan()
+	span.Attributes = attrs
+	if _, err := proto.Marshal(span); err != nil {
+		t.Errorf("failed to marshal span: %s", err)
+	}
+}
+
+func TestValidUTF8Message(t *testing.T) {
+	span := NewProtobufSpan()
+	span.Name = "name \xff"
+	span.Status = &tracepb.Status{Message: "status \xff"}
+	event := NewProtobufSpanEvent()
+	event.Name = "event \xff"
+	span.Events = []*tracepb.Span_Event{event}
+	span.Attributes = []*commonpb.KeyValue{{
+		Key: "list",
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+			Values: []*commonpb.AnyValue{{Value: &commonpb.AnyValue_StringValue{StringValue: "arg \xff"}}},
+		}}},
+	}}
+
+	ValidUTF8Message(span)
+
+	if _, err := proto.Marshal(span); err != nil {
+		t.Fatalf("failed to marshal span: %s", err)
+	}
+	if span.Name != "name �" || span.Status.Message != "status �" || span.Events[0].Name != "event �" {
+		t.Errorf("expected invalid UTF-8 to be replaced, got %q, %q, %q", span.Name, span.Status.Message, span.Events[0].Name)
+	}
+	if got := span.Attributes[0].Value.GetArrayValue().Values[0].GetStringValue(); got != "arg �" {
+		t.Errorf("expected invalid UTF-8 in nested values to be replaced, got %q", got)
+	}
+}
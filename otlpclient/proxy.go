@@ -0,0 +1,137 @@
+package otlpclient
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// ParseProxy parses a --proxy URL, which can be http://, https://,
+// socks5://, or socks5h:// with an optional user:password@.
+func ParseProxy(proxyURL string) (*url.URL, error) {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q, must be an http://, https://, socks5://, or socks5h:// URL", proxyURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q, needs a host and port", proxyURL)
+	}
+	return u, nil
+}
+
+// httpProxy returns the Proxy func for an HTTP client's transport: the
+// --proxy when it's set, otherwise HTTPS_PROXY, HTTP_PROXY, and NO_PROXY
+// from the environment like other Go programs.
+func httpProxy(config OTLPConfig) (func(*http.Request) (*url.URL, error), error) {
+	if config.GetProxy() == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := ParseProxy(config.GetProxy())
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(u), nil
+}
+
+// proxyDialer returns a dialer for gRPC that connects to addr through the
+// proxy. SOCKS5 proxies are dialed with x/net/proxy, HTTP(S) proxies are
+// asked to CONNECT to addr. gRPC already honors HTTPS_PROXY on its own, this
+// is for --proxy.
+func proxyDialer(proxyURL *url.URL) (func(ctx context.Context, addr string) (net.Conn, error), error) {
+	if proxyURL.Scheme == "socks5" || proxyURL.Scheme == "socks5h" {
+		d, err := proxy.FromURL(proxyURL, &net.Dialer{})
+		if err != nil {
+			return nil, err
+		}
+		cd, ok := d.(proxy.ContextDialer)
+		if !ok {
+			return nil, fmt.Errorf("BUG: SOCKS5 dialer does not support contexts")
+		}
+		return func(ctx context.Context, addr string) (net.Conn, error) {
+			return cd.DialContext(ctx, "tcp", addr)
+		}, nil
+	}
+
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		return connectDial(ctx, proxyURL, addr)
+	}, nil
+}
+
+// connectDial connects to addr through an HTTP(S) proxy with CONNECT.
+func connectDial(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyHostPort(proxyURL))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to proxy: %w", err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not connect to proxy: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	// don't hang on a proxy that doesn't answer
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT failed: %w", err)
+	}
+
+	// nothing is sent after the response until gRPC starts talking, so
+	// the reader's buffer can be dropped
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT to %s failed: %s", addr, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// proxyHostPort returns the proxy's host:port, with the default port for
+// its scheme when it doesn't have one.
+func proxyHostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443")
+	}
+	return net.JoinHostPort(u.Hostname(), "80")
+}
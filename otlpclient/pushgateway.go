@@ -0,0 +1,226 @@
+package otlpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// maxPushgatewayLabelValue is where label values are cut off, so a large
+// attribute like a command line doesn't end up in every series.
+const maxPushgatewayLabelValue = 128
+
+// PushgatewayClient is an OTLPClient that wraps another client and, when an
+// upload finally fails, pushes a count and duration of the spans to a
+// Prometheus pushgateway. That way a CI step at least leaves a trace of its
+// existence and duration when the collector is down. The OTLP error is still
+// returned so otel-cli reports it as usual.
+type PushgatewayClient struct {
+	client  OTLPClient
+	config  OTLPConfig
+	pushURL *url.URL
+}
+
+// NewPushgatewayClient returns a PushgatewayClient that pushes to the
+// pushgateway at pushURL when client fails.
+func NewPushgatewayClient(client OTLPClient, config OTLPConfig, pushURL *url.URL) *PushgatewayClient {
+	return &PushgatewayClient{
+		client:  client,
+		config:  config,
+		pushURL: pushURL,
+	}
+}
+
+// Start starts the wrapped client.
+func (pc *PushgatewayClient) Start(ctx context.Context) (context.Context, error) {
+	return pc.client.Start(ctx)
+}
+
+// UploadTraces uploads with the wrapped client, falling back to the
+// pushgateway if that fails. Errors from the push are saved to the error list.
+func (pc *PushgatewayClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	ctx, err := pc.client.UploadTraces(ctx, rsps)
+	if err == nil {
+		return ctx, nil
+	}
+
+	for _, rs := range rsps {
+		job := resourceServiceName(rs.GetResource().GetAttributes())
+		pushErr := pc.push(job, pushgatewayMetrics(rs))
+		if pushErr != nil {
+			ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("pushgateway fallback failed: %w", pushErr))
+		}
+	}
+
+	return ctx, err
+}
+
+// UploadLogs passes logs through to the wrapped client, there is no fallback.
+func (pc *PushgatewayClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return pc.client.UploadLogs(ctx, rls)
+}
+
+// UploadMetrics passes metrics through to the wrapped client, there is no
+// fallback.
+func (pc *PushgatewayClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	return pc.client.UploadMetrics(ctx, rms)
+}
+
+// Stop stops the wrapped client.
+func (pc *PushgatewayClient) Stop(ctx context.Context) (context.Context, error) {
+	return pc.client.Stop(ctx)
+}
+
+// push sends metrics in the text exposition format to the job's group.
+// It gets its own timeout instead of using the upload's context, which has
+// usually run out on retries by the time the fallback is needed.
+func (pc *PushgatewayClient) push(job string, metrics []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pc.config.GetTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, pushgatewayJobURL(pc.pushURL, job), bytes.NewReader(metrics))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	// the OTLP TLS settings, e.g. client certs, are for the collector and
+	// aren't sent to the pushgateway
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("pushgateway %s returned %s", pc.pushURL, resp.Status)
+	}
+	return nil
+}
+
+// pushgatewayJobURL returns the URL for a job's grouping key. Job names
+// with a slash are base64 encoded, which the pushgateway supports for that.
+func pushgatewayJobURL(base *url.URL, job string) string {
+	if job == "" {
+		job = "otel-cli"
+	}
+
+	u := strings.TrimSuffix(base.String(), "/") + "/metrics/job"
+	if strings.Contains(job, "/") {
+		return u + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(job))
+	}
+	return u + "/" + url.PathEscape(job)
+}
+
+// pushgatewayMetrics renders the spans as otel_cli_spans_total and
+// otel_cli_span_duration_seconds. Spans with the same labels are added up
+// since the pushgateway rejects duplicate series.
+func pushgatewayMetrics(rs *tracepb.ResourceSpans) []byte {
+	counts := map[string]int{}
+	durations := map[string]float64{}
+	for _, ss := range rs.GetScopeSpans() {
+		for _, span := range ss.GetSpans() {
+			labels := pushgatewayLabels(span)
+			counts[labels]++
+			durations[labels] += time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano).Seconds()
+		}
+	}
+
+	series := make([]string, 0, len(counts))
+	for labels := range counts {
+		series = append(series, labels)
+	}
+	sort.Strings(series)
+
+	var buf bytes.Buffer
+	buf.WriteString("# HELP otel_cli_spans_total Spans that otel-cli failed to export over OTLP.\n")
+	buf.WriteString("# TYPE otel_cli_spans_total counter\n")
+	for _, labels := range series {
+		fmt.Fprintf(&buf, "otel_cli_spans_total{%s} %d\n", labels, counts[labels])
+	}
+	buf.WriteString("# HELP otel_cli_span_duration_seconds Total duration of spans that otel-cli failed to export over OTLP.\n")
+	buf.WriteString("# TYPE otel_cli_span_duration_seconds gauge\n")
+	for _, labels := range series {
+		fmt.Fprintf(&buf, "otel_cli_span_duration_seconds{%s} %s\n", labels, strconv.FormatFloat(durations[labels], 'f', -1, 64))
+	}
+
+	return buf.Bytes()
+}
+
+// pushgatewayLabels renders the span name, kind, status, and attributes as a
+// Prometheus label set. Attribute keys are reduced to valid label names and
+// dropped when they collide with one already used, or with the labels the
+// pushgateway reserves.
+func pushgatewayLabels(span *tracepb.Span) string {
+	used := map[string]bool{"job": true, "instance": true}
+	labels := []string{}
+	add := func(name, value string) {
+		if used[name] || strings.HasPrefix(name, "__") {
+			return
+		}
+		used[name] = true
+		labels = append(labels, name+`="`+labelValueEscaper.Replace(truncateLabelValue(value))+`"`)
+	}
+
+	add("span_name", span.Name)
+	add("span_kind", SpanKindIntToString(span.Kind))
+	add("status_code", strings.ToLower(strings.TrimPrefix(span.GetStatus().GetCode().String(), "STATUS_CODE_")))
+
+	attrs := SpanAttributesToStringMap(span)
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if name := promLabelName(k); name != "" {
+			add(name, attrs[k])
+		}
+	}
+
+	return strings.Join(labels, ",")
+}
+
+// promLabelName replaces characters that aren't allowed in Prometheus label
+// names with underscores, e.g. http.method becomes http_method.
+func promLabelName(in string) string {
+	out := []rune(in)
+	for i, r := range out {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')
+		if !valid {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
+
+// labelValueEscaper escapes label values for the text exposition format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// truncateLabelValue cuts off long values at a character boundary, and
+// replaces invalid UTF-8, which Prometheus rejects in label values.
+func truncateLabelValue(in string) string {
+	in = strings.ToValidUTF8(in, "�")
+	if len(in) <= maxPushgatewayLabelValue {
+		return in
+	}
+	cut := maxPushgatewayLabelValue
+	for cut > 0 && !utf8.RuneStart(in[cut]) {
+		cut--
+	}
+	return in[:cut]
+}
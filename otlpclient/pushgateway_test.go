@@ -0,0 +1,101 @@
+package otlpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// failingClient is an OTLPClient that fails every upload.
+type failingClient struct{ recordingClient }
+
+func (fc *failingClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	return ctx, fmt.Errorf("collector is down")
+}
+
+func (fc *failingClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return ctx, fmt.Errorf("collector is down")
+}
+
+func TestPushgatewayClient(t *testing.T) {
+	var gotPath, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+		body, _ := io.ReadAll(req.Body)
+		gotBody = string(body)
+	}))
+	defer srv.Close()
+	pushURL, _ := url.Parse(srv.URL)
+
+	span := routeTestSpan("deploy", map[string]string{
+		"http.method": "GET",
+		"job":         "reserved",
+		"msg":         "say \"hi\"\n",
+	})
+	span.StartTimeUnixNano = uint64(time.Second)
+	span.EndTimeUnixNano = uint64(2500 * time.Millisecond)
+	SetSpanStatus(span, "error", "")
+	rsps := []*tracepb.ResourceSpans{routeTestResourceSpans("ci/build", span)}
+
+	client := NewPushgatewayClient(&failingClient{}, retryTestConfig{}, pushURL)
+	ctx, err := client.UploadTraces(context.Background(), rsps)
+	if err == nil {
+		t.Error("expected the OTLP error to be returned")
+	}
+	if len(GetErrorList(ctx)) != 0 {
+		t.Errorf("expected no push errors, got %v", GetErrorList(ctx))
+	}
+
+	if gotPath != "/metrics/job@base64/Y2kvYnVpbGQ" {
+		t.Errorf("pushed to the wrong path %q", gotPath)
+	}
+	labels := `span_name="deploy",span_kind="client",status_code="error",http_method="GET",msg="say \"hi\"\n"`
+	for _, want := range []string{
+		"otel_cli_spans_total{" + labels + "} 1\n",
+		"otel_cli_span_duration_seconds{" + labels + "} 1.5\n",
+	} {
+		if !strings.Contains(gotBody, want) {
+			t.Errorf("expected pushed metrics to contain %q, got:\n%s", want, gotBody)
+		}
+	}
+}
+
+func TestPushgatewayClientPushError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+	pushURL, _ := url.Parse(srv.URL)
+
+	rsps := []*tracepb.ResourceSpans{routeTestResourceSpans("svc", routeTestSpan("x", nil))}
+	client := NewPushgatewayClient(&failingClient{}, retryTestConfig{}, pushURL)
+	ctx, err := client.UploadTraces(context.Background(), rsps)
+	if err == nil {
+		t.Error("expected the OTLP error to be returned")
+	}
+	if errs := GetErrorList(ctx); len(errs) != 1 || !strings.Contains(errs[0].Error, "400 Bad Request") {
+		t.Errorf("expected the push error in the error list, got %v", errs)
+	}
+}
+
+func TestPromLabelName(t *testing.T) {
+	for in, want := range map[string]string{
+		"http.method": "http_method",
+		"9lives":      "_lives",
+		"a9-b":        "a9_b",
+		"ünicode":     "_nicode",
+	} {
+		if got := promLabelName(in); got != want {
+			t.Errorf("promLabelName(%q) = %q, expected %q", in, got, want)
+		}
+	}
+}
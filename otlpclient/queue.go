@@ -0,0 +1,253 @@
+package otlpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// queueFileSuffix marks complete queued exports. Files are written under
+// another name and renamed, so a flush never sees a partial export.
+const queueFileSuffix = ".otlp"
+
+// QueueClient is an OTLPClient that wraps another client and, when uploading
+// traces finally fails, writes the export request to a spool directory so
+// it can be sent later with QueueFlush. A queued export counts as a success
+// since the spans aren't lost, the upload errors are still in the error list.
+type QueueClient struct {
+	client OTLPClient
+	dir    string
+}
+
+// NewQueueClient returns a QueueClient that spools failed exports in dir.
+func NewQueueClient(client OTLPClient, dir string) *QueueClient {
+	return &QueueClient{client: client, dir: dir}
+}
+
+// Start starts the wrapped client.
+func (qc *QueueClient) Start(ctx context.Context) (context.Context, error) {
+	return qc.client.Start(ctx)
+}
+
+// UploadTraces uploads with the wrapped client, queueing the spans to disk if
+// that fails. When only some were sent, e.g. one route failed, only the rest
+// are queued. Only fails if the spans couldn't be queued either.
+func (qc *QueueClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	ctx, err := qc.client.UploadTraces(ctx, rsps)
+	if err == nil {
+		return ctx, nil
+	}
+
+	path, qerr := QueueTraces(qc.dir, UnsentSpans(err, rsps))
+	if qerr != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("failed to queue spans: %w", qerr))
+		return ctx, err
+	}
+	ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("queued spans to %s for otel-cli flush", path))
+
+	return ctx, nil
+}
+
+// UploadLogs passes logs through to the wrapped client, they aren't queued.
+func (qc *QueueClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	return qc.client.UploadLogs(ctx, rls)
+}
+
+// UploadMetrics passes metrics through to the wrapped client, they aren't
+// queued.
+func (qc *QueueClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	return qc.client.UploadMetrics(ctx, rms)
+}
+
+// Stop stops the wrapped client.
+func (qc *QueueClient) Stop(ctx context.Context) (context.Context, error) {
+	return qc.client.Stop(ctx)
+}
+
+// QueueTraces writes the spans as a protobuf ExportTraceServiceRequest to a
+// new file in dir, creating dir if needed, and returns the file's path.
+// Names start with the time so they sort in the order they were queued.
+func QueueTraces(dir string, rsps []*tracepb.ResourceSpans) (string, error) {
+	data, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal export request: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	name := fmt.Sprintf("%020d-%s", time.Now().UnixNano(), hex.EncodeToString(suffix))
+	tmp := filepath.Join(dir, "."+name)
+	path := filepath.Join(dir, name+queueFileSuffix)
+
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeQueued replaces a queued export with rsps, keeping its place in the
+// queue, e.g. to drop the spans a flush did send.
+func writeQueued(path string, rsps []*tracepb.ResourceSpans) error {
+	data, err := proto.Marshal(&coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+	if err != nil {
+		return fmt.Errorf("failed to marshal export request: %w", err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(path), "."+filepath.Base(path))
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// QueueFlushResult counts what happened to queued exports in a flush.
+type QueueFlushResult struct {
+	Sent        int
+	Failed      int
+	Quarantined int
+}
+
+// queueQuarantineDir is the subdirectory of the queue that exports which can
+// never be sent are moved to, so they aren't retried forever but are still
+// around to look at.
+const queueQuarantineDir = "quarantine"
+
+// QueueFlush sends every export queued in dir with client, oldest first,
+// removing each one that was sent. Each export gets its own timeout so one
+// slow export doesn't use up the time for the rest. Exports that fail stay
+// queued for the next flush, without the spans that did get sent, except
+// corrupt ones and ones the server rejected outright, which are moved to the
+// quarantine subdirectory. The queue is locked while it's flushed, so
+// concurrent flushes don't send the same export twice. Errors are saved to
+// the error list.
+func QueueFlush(ctx context.Context, client OTLPClient, dir string, timeout time.Duration) (context.Context, QueueFlushResult, error) {
+	var result QueueFlushResult
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return ctx, result, nil
+	}
+	unlock, err := LockFile(filepath.Join(dir, "flush"))
+	if err != nil {
+		return ctx, result, err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ctx, result, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), queueFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		rsps, err := loadQueuedTraces(path)
+		if errors.Is(err, errQueueCorrupt) {
+			ctx, _ = SaveError(ctx, Now(ctx), err)
+			ctx = quarantineQueued(ctx, dir, name, &result)
+			continue
+		} else if err != nil {
+			ctx, _ = SaveError(ctx, Now(ctx), err)
+			result.Failed++
+			continue
+		}
+
+		itemCtx, cancel := context.WithTimeout(ctx, timeout)
+		itemCtx, uerr := client.UploadTraces(itemCtx, rsps)
+		cancel()
+		// keep the error list from the upload but not its deadline
+		ctx = context.WithoutCancel(itemCtx)
+		var ue *UnsentSpansError
+		if errors.As(uerr, &ue) {
+			// some of it was sent, only the rest stays queued
+			if err := writeQueued(path, ue.Unsent); err != nil {
+				ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("could not remove sent spans from %s, they'll be sent again: %w", path, err))
+			}
+		}
+		if IsPermanentError(uerr) {
+			ctx = quarantineQueued(ctx, dir, name, &result)
+			continue
+		} else if uerr != nil {
+			result.Failed++
+			continue
+		}
+
+		if err := os.Remove(path); err != nil {
+			// it was sent, but will be sent again next time
+			ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("sent %s but could not remove it: %w", path, err))
+		}
+		result.Sent++
+	}
+
+	return ctx, result, nil
+}
+
+// quarantineQueued moves a queued export that can't be sent out of the way.
+// If it can't be moved it stays queued and counts as failed.
+func quarantineQueued(ctx context.Context, dir, name string, result *QueueFlushResult) context.Context {
+	qdir := filepath.Join(dir, queueQuarantineDir)
+	err := os.MkdirAll(qdir, 0700)
+	if err == nil {
+		err = os.Rename(filepath.Join(dir, name), filepath.Join(qdir, name))
+	}
+	if err != nil {
+		ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("failed to quarantine %s: %w", name, err))
+		result.Failed++
+		return ctx
+	}
+
+	ctx, _ = SaveError(ctx, Now(ctx), fmt.Errorf("moved %s to %s, it can't be sent", name, qdir))
+	result.Quarantined++
+	return ctx
+}
+
+// errQueueCorrupt is wrapped by loadQueuedTraces for files that can be read
+// but aren't a queued export.
+var errQueueCorrupt = errors.New("queued export is corrupt")
+
+// loadQueuedTraces reads a file written by QueueTraces.
+func loadQueuedTraces(path string) ([]*tracepb.ResourceSpans, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var req coltracepb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errQueueCorrupt, path, err)
+	}
+
+	return req.GetResourceSpans(), nil
+}
@@ -0,0 +1,200 @@
+package otlpclient
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestQueueClient(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	rsps := func(name string) []*tracepb.ResourceSpans {
+		return []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{routeTestSpan(name, nil)}}},
+		}}
+	}
+
+	// failed uploads are queued and count as sent
+	qc := NewQueueClient(&failingClient{}, dir)
+	for _, name := range []string{"first", "second"} {
+		ctx, err := qc.UploadTraces(context.Background(), rsps(name))
+		if err != nil {
+			t.Fatalf("expected queueing to succeed but got %s", err)
+		}
+		if len(GetErrorList(ctx)) != 1 {
+			t.Errorf("expected the queued file in the error list but got %v", GetErrorList(ctx))
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 queued exports but found %d", len(entries))
+	}
+
+	// flushing to a collector that's still down keeps everything
+	_, result, err := QueueFlush(context.Background(), &failingClient{}, dir, time.Second)
+	if err != nil || result != (QueueFlushResult{Failed: 2}) {
+		t.Errorf("expected 2 failures but got %+v, %v", result, err)
+	}
+
+	// a corrupt file is quarantined and doesn't stop the others
+	os.WriteFile(filepath.Join(dir, "0-corrupt"+queueFileSuffix), []byte("not protobuf"), 0600)
+
+	rc := &recordingClient{}
+	_, result, err = QueueFlush(context.Background(), rc, dir, time.Second)
+	if err != nil || result != (QueueFlushResult{Sent: 2, Quarantined: 1}) {
+		t.Errorf("expected 2 sent and 1 quarantined but got %+v, %v", result, err)
+	}
+	if diff := cmp.Diff([]string{"first", "second"}, rc.names); diff != "" {
+		t.Errorf("flushed spans did not match (-want +got):\n%s", diff)
+	}
+
+	entries, _ = os.ReadDir(dir)
+	if len(entries) != 2 || entries[0].Name() != "flush.lock" || entries[1].Name() != queueQuarantineDir {
+		t.Errorf("expected only the lock file and quarantine directory to be left but found %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(dir, queueQuarantineDir, "0-corrupt"+queueFileSuffix)); err != nil {
+		t.Errorf("expected the corrupt file in quarantine: %s", err)
+	}
+
+	// a queue that was never created is empty
+	_, result, err = QueueFlush(context.Background(), rc, filepath.Join(dir, "nope"), time.Second)
+	if err != nil || result != (QueueFlushResult{}) {
+		t.Errorf("expected nothing from a missing queue but got %+v, %v", result, err)
+	}
+}
+
+// rejectingClient is an OTLPClient whose server refuses every export, and
+// that checks each export has its own deadline.
+type rejectingClient struct {
+	recordingClient
+	deadlines []time.Time
+}
+
+func (rc *rejectingClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	deadline, _ := ctx.Deadline()
+	rc.deadlines = append(rc.deadlines, deadline)
+	time.Sleep(10 * time.Millisecond)
+	return ctx, &PermanentError{Err: fmt.Errorf("server returned unretriable code 400")}
+}
+
+func TestQueueFlushQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	rsps := []*tracepb.ResourceSpans{{}}
+	for i := 0; i < 2; i++ {
+		if _, err := QueueTraces(dir, rsps); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rc := &rejectingClient{}
+	ctx, result, err := QueueFlush(context.Background(), rc, dir, time.Second)
+	if err != nil || result != (QueueFlushResult{Quarantined: 2}) {
+		t.Errorf("expected 2 quarantined but got %+v, %v", result, err)
+	}
+	if len(rc.deadlines) != 2 || !rc.deadlines[1].After(rc.deadlines[0]) {
+		t.Errorf("expected each export to get its own deadline, got %v", rc.deadlines)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("the returned context should not keep an export's deadline")
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(dir, queueQuarantineDir))
+	if len(entries) != 2 {
+		t.Errorf("expected 2 exports in quarantine but found %d", len(entries))
+	}
+}
+
+// only the spans from routes that failed are queued, and a flush that only
+// gets some of them out keeps just the rest
+func TestQueueClientRoutes(t *testing.T) {
+	dir := t.TempDir()
+	down := true
+	newClient := func(ctx context.Context, route Route) (context.Context, OTLPClient, error) {
+		if down {
+			return ctx, &failingClient{}, nil
+		}
+		return ctx, &recordingClient{}, nil
+	}
+	rsps := []*tracepb.ResourceSpans{
+		routeTestResourceSpans("infra-dns", routeTestSpan("routed", nil)),
+		routeTestResourceSpans("checkout", routeTestSpan("sent", nil)),
+	}
+
+	fallback := &recordingClient{}
+	qc := NewQueueClient(NewRoutingClient(fallback, []Route{{Service: "infra-*"}}, newClient), dir)
+	if _, err := qc.UploadTraces(context.Background(), rsps); err != nil {
+		t.Fatalf("expected queueing to succeed but got %s", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 queued export but found %d", len(entries))
+	}
+	queued, err := loadQueuedTraces(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &recordingClient{}
+	got.UploadTraces(context.Background(), queued)
+	if diff := cmp.Diff([]string{"routed"}, got.names); diff != "" {
+		t.Errorf("expected only the failed route's spans queued (-want +got):\n%s", diff)
+	}
+
+	// the queued spans plus one for the fallback, which is down this time
+	QueueTraces(dir, rsps)
+	fallbackDown := NewRoutingClient(&failingClient{}, []Route{{Service: "infra-*"}}, newClient)
+	down = false
+	_, result, err := QueueFlush(context.Background(), fallbackDown, dir, time.Second)
+	if err != nil || result != (QueueFlushResult{Sent: 1, Failed: 1}) {
+		t.Errorf("expected 1 sent and 1 failed but got %+v, %v", result, err)
+	}
+	names := []string{}
+	entries, _ = os.ReadDir(dir)
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != queueFileSuffix {
+			continue
+		}
+		rsps, _ := loadQueuedTraces(filepath.Join(dir, entry.Name()))
+		rc := &recordingClient{}
+		rc.UploadTraces(context.Background(), rsps)
+		names = append(names, rc.names...)
+	}
+	if diff := cmp.Diff([]string{"sent"}, names); diff != "" {
+		t.Errorf("expected only the unsent span left in the queue (-want +got):\n%s", diff)
+	}
+}
+
+// concurrent flushes send each queued export once
+func TestQueueFlushConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		QueueTraces(dir, []*tracepb.ResourceSpans{routeTestResourceSpans("svc", routeTestSpan(fmt.Sprint(i), nil))})
+	}
+
+	var wg sync.WaitGroup
+	clients := make([]*recordingClient, 4)
+	for i := range clients {
+		clients[i] = &recordingClient{}
+		wg.Add(1)
+		go func(rc *recordingClient) {
+			defer wg.Done()
+			QueueFlush(context.Background(), rc, dir, time.Second)
+		}(clients[i])
+	}
+	wg.Wait()
+
+	sent := 0
+	for _, rc := range clients {
+		sent += len(rc.names)
+	}
+	if sent != 20 {
+		t.Errorf("expected 20 spans sent once each, got %d", sent)
+	}
+}
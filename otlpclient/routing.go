@@ -0,0 +1,323 @@
+package otlpclient
+
+import (
+	"context"
+	"errors"
+	"path"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// Route sends spans that match it to a different endpoint than the one
+// configured for otel-cli, e.g. infrastructure spans to an internal collector
+// and product spans to a SaaS. Routes are set in the config file.
+type Route struct {
+	// Service is matched against the service.name resource attribute and
+	// can be a glob, e.g. "infra-*". Empty matches any service.
+	Service string `json:"service"`
+	// Attributes must all be present with the same values on the span, or on
+	// its resource.
+	Attributes map[string]string `json:"attributes"`
+	// Endpoint, Protocol, and Headers are used instead of the otel-cli
+	// configured values for matching spans. Headers are not inherited so
+	// credentials for one backend don't leak to another.
+	Endpoint string            `json:"endpoint"`
+	Protocol string            `json:"protocol"`
+	Headers  map[string]string `json:"headers"`
+}
+
+// Matches returns true if the span and its resource satisfy the route.
+func (r Route) Matches(rs *tracepb.ResourceSpans, span *tracepb.Span) bool {
+	resourceAttrs := ResourceAttributesToStringMap(rs)
+
+	if r.Service != "" {
+		if ok, _ := path.Match(r.Service, resourceAttrs["service.name"]); !ok {
+			return false
+		}
+	}
+
+	spanAttrs := SpanAttributesToStringMap(span)
+	for k, want := range r.Attributes {
+		got, ok := spanAttrs[k]
+		if !ok {
+			got, ok = resourceAttrs[k]
+		}
+		if !ok || got != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UnsentSpansError is returned by RoutingClient.UploadTraces when some of the
+// batches failed but others were sent, along with the spans in the batches
+// that failed, so they can be queued without the ones that were sent.
+type UnsentSpansError struct {
+	Err    error
+	Unsent []*tracepb.ResourceSpans
+}
+
+func (ue *UnsentSpansError) Error() string { return ue.Err.Error() }
+func (ue *UnsentSpansError) Unwrap() error { return ue.Err }
+
+// UnsentSpans returns the spans that weren't sent when err is or wraps an
+// *UnsentSpansError, or rsps, everything that was being sent, when it isn't.
+func UnsentSpans(err error, rsps []*tracepb.ResourceSpans) []*tracepb.ResourceSpans {
+	var ue *UnsentSpansError
+	if errors.As(err, &ue) {
+		return ue.Unsent
+	}
+	return rsps
+}
+
+// RouteClientFunc creates and starts a client for a route.
+type RouteClientFunc func(context.Context, Route) (context.Context, OTLPClient, error)
+
+// RoutingClient is an OTLPClient that picks the destination for each span at
+// send time. Spans go to the first route they match, or to the fallback client
+// when none match. Clients for routes are only started once a span needs them.
+type RoutingClient struct {
+	fallback  OTLPClient
+	routes    []Route
+	newClient RouteClientFunc
+	clients   []OTLPClient // started route clients by route index, nil until used
+}
+
+// NewRoutingClient returns a RoutingClient that sends unmatched spans to fallback.
+func NewRoutingClient(fallback OTLPClient, routes []Route, newClient RouteClientFunc) *RoutingClient {
+	return &RoutingClient{
+		fallback:  fallback,
+		routes:    routes,
+		newClient: newClient,
+		clients:   make([]OTLPClient, len(routes)),
+	}
+}
+
+// Start starts the fallback client. Route clients are started on demand.
+func (rc *RoutingClient) Start(ctx context.Context) (context.Context, error) {
+	return rc.fallback.Start(ctx)
+}
+
+// UploadTraces splits the spans up by route and uploads each batch to its
+// client. Every batch is attempted and all errors are returned together, in
+// an *UnsentSpansError with the spans from the failed batches when some of
+// the others were sent.
+func (rc *RoutingClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	batches := map[int][]*tracepb.ResourceSpans{}
+	order := []int{}
+	for _, rs := range rsps {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				target := rc.route(rs, span)
+				if _, ok := batches[target]; !ok {
+					order = append(order, target)
+				}
+				batches[target] = appendSpan(batches[target], rs, ss, span)
+			}
+		}
+	}
+
+	errs := []error{}
+	unsent := []*tracepb.ResourceSpans{}
+	for _, target := range order {
+		client := rc.fallback
+		if target >= 0 {
+			var err error
+			ctx, client, err = rc.routeClient(ctx, target)
+			if err != nil {
+				errs = append(errs, err)
+				unsent = append(unsent, batches[target]...)
+				continue
+			}
+		}
+
+		var err error
+		ctx, err = client.UploadTraces(ctx, batches[target])
+		if err != nil {
+			errs = append(errs, err)
+			unsent = append(unsent, batches[target]...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return ctx, nil
+	} else if len(errs) < len(order) {
+		return ctx, &UnsentSpansError{Err: errors.Join(errs...), Unsent: unsent}
+	}
+	return ctx, errors.Join(errs...)
+}
+
+// UploadLogs sends logs to the route matching their service name, or the
+// fallback. Routes with attributes never match logs since attributes are
+// only checked on spans.
+func (rc *RoutingClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	batches := map[int][]*logspb.ResourceLogs{}
+	order := []int{}
+	for _, rl := range rls {
+		target := rc.routeService(resourceServiceName(rl.GetResource().GetAttributes()))
+		if _, ok := batches[target]; !ok {
+			order = append(order, target)
+		}
+		batches[target] = append(batches[target], rl)
+	}
+
+	errs := []error{}
+	for _, target := range order {
+		client := rc.fallback
+		if target >= 0 {
+			var err error
+			ctx, client, err = rc.routeClient(ctx, target)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		var err error
+		ctx, err = client.UploadLogs(ctx, batches[target])
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return ctx, errors.Join(errs...)
+}
+
+// UploadMetrics sends metrics to the route matching their service name, or
+// the fallback, the same way as UploadLogs.
+func (rc *RoutingClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	batches := map[int][]*metricspb.ResourceMetrics{}
+	order := []int{}
+	for _, rm := range rms {
+		target := rc.routeService(resourceServiceName(rm.GetResource().GetAttributes()))
+		if _, ok := batches[target]; !ok {
+			order = append(order, target)
+		}
+		batches[target] = append(batches[target], rm)
+	}
+
+	errs := []error{}
+	for _, target := range order {
+		client := rc.fallback
+		if target >= 0 {
+			var err error
+			ctx, client, err = rc.routeClient(ctx, target)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		var err error
+		ctx, err = client.UploadMetrics(ctx, batches[target])
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return ctx, errors.Join(errs...)
+}
+
+// routeService returns the index of the first route without attributes
+// that matches the service name, or -1 for the fallback.
+func (rc *RoutingClient) routeService(service string) int {
+	for i, route := range rc.routes {
+		if len(route.Attributes) > 0 {
+			continue
+		}
+		if ok, _ := path.Match(route.Service, service); ok || route.Service == "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// Stop stops the route clients that were started, in route order, then the
+// fallback client.
+func (rc *RoutingClient) Stop(ctx context.Context) (context.Context, error) {
+	errs := []error{}
+	for _, client := range rc.clients {
+		if client == nil {
+			continue
+		}
+		var err error
+		if ctx, err = client.Stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	ctx, err := rc.fallback.Stop(ctx)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return ctx, errors.Join(errs...)
+}
+
+// route returns the index of the first matching route, or -1 for the fallback.
+func (rc *RoutingClient) route(rs *tracepb.ResourceSpans, span *tracepb.Span) int {
+	for i, route := range rc.routes {
+		if route.Matches(rs, span) {
+			return i
+		}
+	}
+	return -1
+}
+
+// routeClient returns the client for the route, starting it if needed.
+func (rc *RoutingClient) routeClient(ctx context.Context, i int) (context.Context, OTLPClient, error) {
+	if client := rc.clients[i]; client != nil {
+		return ctx, client, nil
+	}
+
+	ctx, client, err := rc.newClient(ctx, rc.routes[i])
+	if err != nil {
+		ctx, err = SaveError(ctx, Now(ctx), err)
+		return ctx, nil, err
+	}
+	rc.clients[i] = client
+
+	return ctx, client, nil
+}
+
+// appendSpan adds the span to the batch, reusing the last ResourceSpans and
+// ScopeSpans in the batch when they came from the same source.
+func appendSpan(batch []*tracepb.ResourceSpans, rs *tracepb.ResourceSpans, ss *tracepb.ScopeSpans, span *tracepb.Span) []*tracepb.ResourceSpans {
+	if n := len(batch); n > 0 && batch[n-1].Resource == rs.Resource {
+		scopes := batch[n-1].ScopeSpans
+		if m := len(scopes); m > 0 && scopes[m-1].Scope == ss.Scope {
+			scopes[m-1].Spans = append(scopes[m-1].Spans, span)
+			return batch
+		}
+		batch[n-1].ScopeSpans = append(scopes, &tracepb.ScopeSpans{
+			Scope:     ss.Scope,
+			SchemaUrl: ss.SchemaUrl,
+			Spans:     []*tracepb.Span{span},
+		})
+		return batch
+	}
+
+	return append(batch, &tracepb.ResourceSpans{
+		Resource:  rs.Resource,
+		SchemaUrl: rs.SchemaUrl,
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Scope:     ss.Scope,
+			SchemaUrl: ss.SchemaUrl,
+			Spans:     []*tracepb.Span{span},
+		}},
+	})
+}
+
+// resourceServiceName returns the service.name from resource attributes.
+func resourceServiceName(attrs []*commonpb.KeyValue) string {
+	for _, attr := range attrs {
+		if attr.GetKey() == "service.name" {
+			return AnyValueToString(attr.GetValue())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,235 @@
+package otlpclient
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// recordingClient is an OTLPClient that keeps the names of the spans it gets.
+type recordingClient struct {
+	started bool
+	stopped bool
+	names   []string
+	name    string    // appended to stops when stopped
+	stops   *[]string // shared between clients to check stop order
+}
+
+func (rc *recordingClient) Start(ctx context.Context) (context.Context, error) {
+	rc.started = true
+	return ctx, nil
+}
+
+func (rc *recordingClient) UploadTraces(ctx context.Context, rsps []*tracepb.ResourceSpans) (context.Context, error) {
+	for _, rs := range rsps {
+		for _, ss := range rs.GetScopeSpans() {
+			for _, span := range ss.GetSpans() {
+				rc.names = append(rc.names, span.Name)
+			}
+		}
+	}
+	return ctx, nil
+}
+
+func (rc *recordingClient) UploadLogs(ctx context.Context, rls []*logspb.ResourceLogs) (context.Context, error) {
+	for _, rl := range rls {
+		for _, sl := range rl.GetScopeLogs() {
+			for _, lr := range sl.GetLogRecords() {
+				rc.names = append(rc.names, lr.GetBody().GetStringValue())
+			}
+		}
+	}
+	return ctx, nil
+}
+
+func (rc *recordingClient) UploadMetrics(ctx context.Context, rms []*metricspb.ResourceMetrics) (context.Context, error) {
+	for _, rm := range rms {
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				rc.names = append(rc.names, m.Name)
+			}
+		}
+	}
+	return ctx, nil
+}
+
+func (rc *recordingClient) Stop(ctx context.Context) (context.Context, error) {
+	rc.stopped = true
+	if rc.stops != nil {
+		*rc.stops = append(*rc.stops, rc.name)
+	}
+	return ctx, nil
+}
+
+func routeTestResourceSpans(service string, spans ...*tracepb.Span) *tracepb.ResourceSpans {
+	return &tracepb.ResourceSpans{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{{
+				Key:   "service.name",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: service}},
+			}},
+		},
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: spans}},
+	}
+}
+
+func routeTestSpan(name string, attrs map[string]string) *tracepb.Span {
+	span := NewProtobufSpan()
+	span.Name = name
+	span.Attributes = StringMapAttrsToProtobuf(attrs)
+	return span
+}
+
+func TestRouteMatches(t *testing.T) {
+	span := routeTestSpan("x", map[string]string{"team": "infra"})
+	rs := routeTestResourceSpans("infra-dns", span)
+
+	for _, tc := range []struct {
+		route Route
+		want  bool
+	}{
+		{route: Route{}, want: true},
+		{route: Route{Service: "infra-dns"}, want: true},
+		{route: Route{Service: "infra-*"}, want: true},
+		{route: Route{Service: "product-*"}, want: false},
+		{route: Route{Attributes: map[string]string{"team": "infra"}}, want: true},
+		{route: Route{Attributes: map[string]string{"team": "product"}}, want: false},
+		{route: Route{Attributes: map[string]string{"service.name": "infra-dns"}}, want: true},
+		{route: Route{Service: "infra-*", Attributes: map[string]string{"missing": ""}}, want: false},
+	} {
+		if got := tc.route.Matches(rs, span); got != tc.want {
+			t.Errorf("route %+v: got %t, expected %t", tc.route, got, tc.want)
+		}
+	}
+}
+
+func TestRoutingClient(t *testing.T) {
+	stops := []string{}
+	fallback := &recordingClient{name: "fallback", stops: &stops}
+	routed := map[string]*recordingClient{}
+	routes := []Route{
+		{Service: "infra-*", Endpoint: "internal"},
+		{Attributes: map[string]string{"team": "product"}, Endpoint: "saas"},
+		{Service: "unused", Endpoint: "unused"},
+	}
+	newClient := func(ctx context.Context, route Route) (context.Context, OTLPClient, error) {
+		client := &recordingClient{name: route.Endpoint, stops: &stops}
+		routed[route.Endpoint] = client
+		ctx, err := client.Start(ctx)
+		return ctx, client, err
+	}
+
+	ctx := context.Background()
+	client := NewRoutingClient(fallback, routes, newClient)
+	ctx, err := client.Start(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error starting client: %s", err)
+	}
+
+	rsps := []*tracepb.ResourceSpans{
+		routeTestResourceSpans("infra-dns", routeTestSpan("dns", nil)),
+		routeTestResourceSpans("checkout",
+			routeTestSpan("pay", map[string]string{"team": "product"}),
+			routeTestSpan("other", nil),
+		),
+	}
+	ctx, err = client.UploadTraces(ctx, rsps)
+	if err != nil {
+		t.Fatalf("unexpected error uploading: %s", err)
+	}
+	ctx, err = client.UploadTraces(ctx, rsps[:1])
+	if err != nil {
+		t.Fatalf("unexpected error uploading: %s", err)
+	}
+	_, err = client.Stop(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error stopping client: %s", err)
+	}
+
+	if got := fmt.Sprint(fallback.names); got != "[other]" {
+		t.Errorf("fallback got spans %s", got)
+	}
+	if got := fmt.Sprint(routed["internal"].names); got != "[dns dns]" {
+		t.Errorf("internal route got spans %s", got)
+	}
+	if got := fmt.Sprint(routed["saas"].names); got != "[pay]" {
+		t.Errorf("saas route got spans %s", got)
+	}
+	if _, ok := routed["unused"]; ok {
+		t.Error("client for unused route should not have been started")
+	}
+	if !fallback.stopped || !routed["internal"].stopped || !routed["saas"].stopped {
+		t.Error("all started clients should be stopped")
+	}
+	if got := fmt.Sprint(stops); got != "[internal saas fallback]" {
+		t.Errorf("expected clients to stop in route order, then the fallback, got %s", got)
+	}
+}
+
+func TestRoutingClientStartError(t *testing.T) {
+	fallback := &recordingClient{}
+	newClient := func(ctx context.Context, route Route) (context.Context, OTLPClient, error) {
+		return ctx, nil, fmt.Errorf("nope")
+	}
+	client := NewRoutingClient(fallback, []Route{{Service: "a"}}, newClient)
+
+	rsps := []*tracepb.ResourceSpans{
+		routeTestResourceSpans("a", routeTestSpan("routed", nil)),
+		routeTestResourceSpans("b", routeTestSpan("fallback", nil)),
+	}
+	ctx, err := client.UploadTraces(context.Background(), rsps)
+	if err == nil {
+		t.Error("expected an error from the failed route client")
+	}
+	if got := fmt.Sprint(fallback.names); got != "[fallback]" {
+		t.Errorf("fallback should still get its spans, got %s", got)
+	}
+	if len(GetErrorList(ctx)) != 1 {
+		t.Errorf("expected the route error in the error list, got %v", GetErrorList(ctx))
+	}
+}
+
+func TestRoutingClientLogs(t *testing.T) {
+	fallback := &recordingClient{}
+	routed := &recordingClient{}
+	routes := []Route{
+		{Attributes: map[string]string{"service.name": "infra-dns"}, Endpoint: "attrs"},
+		{Service: "infra-*", Endpoint: "internal"},
+	}
+	newClient := func(ctx context.Context, route Route) (context.Context, OTLPClient, error) {
+		if route.Endpoint != "internal" {
+			t.Errorf("logs should not be routed to %q", route.Endpoint)
+		}
+		return ctx, routed, nil
+	}
+	client := NewRoutingClient(fallback, routes, newClient)
+
+	resourceLogs := func(service, body string) *logspb.ResourceLogs {
+		return &logspb.ResourceLogs{
+			Resource: routeTestResourceSpans(service).Resource,
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{
+				Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+			}}}},
+		}
+	}
+	_, err := client.UploadLogs(context.Background(), []*logspb.ResourceLogs{
+		resourceLogs("infra-dns", "dns"),
+		resourceLogs("checkout", "pay"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error uploading logs: %s", err)
+	}
+
+	if got := fmt.Sprint(routed.names); got != "[dns]" {
+		t.Errorf("internal route got logs %s", got)
+	}
+	if got := fmt.Sprint(fallback.names); got != "[pay]" {
+		t.Errorf("fallback got logs %s", got)
+	}
+}
@@ -0,0 +1,102 @@
+package otlpclient
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// SignatureHeader is the OTLP header that carries the payload signature.
+// It is lowercase because gRPC metadata keys are always lowercase.
+const SignatureHeader = "otel-cli-signature"
+
+// signaturePrefix names the algorithm so it can be changed later without
+// breaking verifiers that only understand the old one.
+const signaturePrefix = "hmac-sha256="
+
+// SignResourceSpans computes an HMAC-SHA256 over the deterministic protobuf
+// serialization of the ResourceSpans and returns the value to put in the
+// SignatureHeader.
+func SignResourceSpans(key []byte, rsps []*tracepb.ResourceSpans) (string, error) {
+	mac, err := resourceSpansMac(key, rsps)
+	if err != nil {
+		return "", err
+	}
+
+	return signaturePrefix + hex.EncodeToString(mac), nil
+}
+
+// VerifyResourceSpans checks a SignatureHeader value against the ResourceSpans
+// using the provided key. Returns nil when the signature is valid.
+func VerifyResourceSpans(key []byte, rsps []*tracepb.ResourceSpans, signature string) error {
+	if signature == "" {
+		return fmt.Errorf("missing %s header", SignatureHeader)
+	}
+
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return fmt.Errorf("unsupported signature algorithm in %q", signature)
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, signaturePrefix))
+	if err != nil {
+		return fmt.Errorf("failed to decode signature: %w", err)
+	}
+
+	want, err := resourceSpansMac(key, rsps)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal(got, want) {
+		return fmt.Errorf("signature does not match payload")
+	}
+
+	return nil
+}
+
+// signedHeaders returns the configured headers plus the signature header when
+// a signing key is configured. The config's map is copied, never modified.
+// msg is the export request that will be sent.
+func signedHeaders(config OTLPConfig, msg proto.Message) (map[string]string, error) {
+	key := config.GetSigningKey()
+	if len(key) == 0 {
+		return config.GetHeaders(), nil
+	}
+
+	mac, err := payloadMac(key, msg)
+	if err != nil {
+		return nil, err
+	}
+	sig := signaturePrefix + hex.EncodeToString(mac)
+
+	headers := make(map[string]string)
+	for k, v := range config.GetHeaders() {
+		headers[k] = v
+	}
+	headers[SignatureHeader] = sig
+
+	return headers, nil
+}
+
+func resourceSpansMac(key []byte, rsps []*tracepb.ResourceSpans) ([]byte, error) {
+	return payloadMac(key, &coltracepb.ExportTraceServiceRequest{ResourceSpans: rsps})
+}
+
+// payloadMac computes the HMAC over the deterministic serialization of an
+// export request of any signal.
+func payloadMac(key []byte, msg proto.Message) ([]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload for signing: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
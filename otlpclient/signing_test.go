@@ -0,0 +1,40 @@
+package otlpclient
+
+import (
+	"testing"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestSignResourceSpans(t *testing.T) {
+	rsps := []*tracepb.ResourceSpans{{
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{{Name: "signed"}},
+		}},
+	}}
+	key := []byte("sekrit")
+
+	sig, err := SignResourceSpans(key, rsps)
+	if err != nil {
+		t.Fatalf("signing failed: %s", err)
+	}
+
+	if err := VerifyResourceSpans(key, rsps, sig); err != nil {
+		t.Errorf("valid signature was rejected: %s", err)
+	}
+
+	if err := VerifyResourceSpans([]byte("wrong"), rsps, sig); err == nil {
+		t.Error("signature verified with the wrong key")
+	}
+
+	rsps[0].ScopeSpans[0].Spans[0].Name = "tampered"
+	if err := VerifyResourceSpans(key, rsps, sig); err == nil {
+		t.Error("signature verified after the payload changed")
+	}
+
+	for _, bad := range []string{"", "md5=abcd", "hmac-sha256=zz"} {
+		if err := VerifyResourceSpans(key, rsps, bad); err == nil {
+			t.Errorf("signature %q should have been rejected", bad)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package otlpclient
+
+import (
+	"context"
+	"net"
+	"net/url"
+)
+
+// IsUnixEndpoint returns true for unix:///path/to.sock endpoints, which
+// connect to a Unix domain socket instead of TCP.
+func IsUnixEndpoint(u *url.URL) bool {
+	return u.Scheme == "unix"
+}
+
+// unixHttpURL returns the URL HTTP requests are sent to over a Unix socket.
+// The socket path takes the place of host:port, so requests go to the
+// default traces path on a placeholder host.
+func unixHttpURL() *url.URL {
+	return &url.URL{Scheme: "http", Host: "localhost", Path: "/v1/traces"}
+}
+
+// unixDialer returns a DialContext for http.Transport that ignores the
+// address it's given and connects to the socket at path.
+func unixDialer(path string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	}
+}
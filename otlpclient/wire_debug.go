@@ -0,0 +1,115 @@
+package otlpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// wireDebugMu serializes writes so concurrent exports don't interleave.
+var wireDebugMu sync.Mutex
+
+// openWireDebugFile checks that the --wire-debug-file can be written, so a
+// bad path fails when the client starts rather than being silently ignored.
+func openWireDebugFile(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("could not open wire debug file: %w", err)
+	}
+	return file.Close()
+}
+
+// writeWireDebug appends a record to the wire debug file at path: a title
+// line, the headers with secrets masked, and a hex dump of the body. Failing
+// to write debug output shouldn't fail the export, so errors are dropped.
+func writeWireDebug(path, title string, headers map[string][]string, body []byte) {
+	if path == "" {
+		return
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "=== %s %s\n", time.Now().Format("2006-01-02T15:04:05.000000000Z07:00"), title)
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range headers[name] {
+			fmt.Fprintf(&buf, "%s: %s\n", name, MaskHeaderValue(name, value))
+		}
+	}
+
+	fmt.Fprintf(&buf, "body: %d bytes\n", len(body))
+	buf.WriteString(hex.Dump(body))
+	buf.WriteString("\n")
+
+	wireDebugMu.Lock()
+	defer wireDebugMu.Unlock()
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.Write(buf.Bytes())
+}
+
+// wireDebugHttpRequest records an HTTP request and its protobuf body.
+func wireDebugHttpRequest(path string, req *http.Request, body []byte) {
+	writeWireDebug(path, fmt.Sprintf("http request %s %s", req.Method, MaskUrlPassword(req.URL.String())), req.Header, body)
+}
+
+// wireDebugHttpResponse records an HTTP response, or the error in its place.
+func wireDebugHttpResponse(path string, resp *http.Response, body []byte, err error) {
+	if err != nil {
+		writeWireDebug(path, fmt.Sprintf("http error %s", maskUrlError(err)), nil, nil)
+		return
+	}
+	writeWireDebug(path, fmt.Sprintf("http response %s", resp.Status), resp.Header, body)
+}
+
+// wireDebugInterceptor returns a gRPC interceptor that records each request
+// with its metadata, and the response with its headers and trailers.
+func wireDebugInterceptor(path string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		writeWireDebug(path, fmt.Sprintf("grpc request %s %s", MaskUrlPassword(cc.Target()), method), md, marshalForDebug(req))
+
+		var header, trailer metadata.MD
+		opts = append(opts, grpc.Header(&header), grpc.Trailer(&trailer))
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		respMd := metadata.Join(header, trailer)
+		if err != nil {
+			st := status.Convert(err)
+			writeWireDebug(path, fmt.Sprintf("grpc response %s: %s", st.Code(), st.Message()), respMd, nil)
+		} else {
+			writeWireDebug(path, "grpc response OK", respMd, marshalForDebug(reply))
+		}
+		return err
+	}
+}
+
+// marshalForDebug returns the protobuf serialization of msg, or nothing if
+// it isn't a protobuf message.
+func marshalForDebug(msg interface{}) []byte {
+	pm, ok := msg.(proto.Message)
+	if !ok {
+		return nil
+	}
+	data, _ := proto.Marshal(pm)
+	return data
+}
@@ -0,0 +1,99 @@
+package otlpserver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DrainStats counts what happened to export requests during Shutdown.
+type DrainStats struct {
+	// Drained is requests that were in flight when shutdown started and
+	// finished before the deadline.
+	Drained int `json:"drained"`
+	// Rejected is requests that arrived after shutdown started.
+	Rejected int `json:"rejected"`
+	// Abandoned is requests still in flight at the deadline, their spans
+	// may or may not have reached the callback.
+	Abandoned int `json:"abandoned"`
+}
+
+// String returns the stats in a form suitable for logging.
+func (ds DrainStats) String() string {
+	return fmt.Sprintf("drained %d, rejected %d, abandoned %d export request(s)", ds.Drained, ds.Rejected, ds.Abandoned)
+}
+
+// inflight keeps count of export requests being handled so servers can stop
+// taking new ones and wait for the rest when shutting down.
+type inflight struct {
+	mu       sync.Mutex
+	active   int
+	draining bool
+	stats    DrainStats
+	atDrain  int           // active when draining started
+	idle     chan struct{} // closed when draining and active reaches 0
+}
+
+// begin is called as a request comes in and returns false if it should be
+// rejected because the server is draining. Requests that begin must end.
+func (f *inflight) begin() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.draining {
+		f.stats.Rejected++
+		return false
+	}
+	f.active++
+	return true
+}
+
+// end is called when a request is done.
+func (f *inflight) end() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.active--
+	if f.draining && f.active == 0 {
+		close(f.idle)
+	}
+}
+
+// drain stops new requests from beginning. Safe to call more than once.
+func (f *inflight) drain() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.draining {
+		return
+	}
+	f.draining = true
+	f.atDrain = f.active
+	f.idle = make(chan struct{})
+	if f.active == 0 {
+		close(f.idle)
+	}
+}
+
+// wait blocks until in-flight requests are done or ctx is done, then returns
+// the stats and ctx's error if there were requests left.
+func (f *inflight) wait(ctx context.Context) (DrainStats, error) {
+	f.drain()
+
+	var err error
+	select {
+	case <-f.idle:
+	case <-ctx.Done():
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := f.stats
+	stats.Abandoned = f.active
+	stats.Drained = f.atDrain - f.active
+	if f.active > 0 {
+		err = ctx.Err()
+	}
+	return stats, err
+}
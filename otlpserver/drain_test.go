@@ -0,0 +1,96 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHttpServerShutdown(t *testing.T) {
+	exported, _ := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{Name: "slow"}}}},
+		}},
+	})
+
+	for _, tc := range []struct {
+		name    string
+		timeout time.Duration
+		want    DrainStats
+		wantErr error
+	}{
+		{"drains", time.Second, DrainStats{Drained: 1, Rejected: 1}, nil},
+		{"abandons", 50 * time.Millisecond, DrainStats{Abandoned: 1, Rejected: 1}, context.DeadlineExceeded},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			defer close(release)
+			consumed := make(chan struct{}, 1)
+			cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+				close(entered)
+				if tc.wantErr == nil {
+					<-release
+				} else {
+					// hold on until after the deadline
+					time.Sleep(tc.timeout * 4)
+				}
+				consumed <- struct{}{}
+				return false
+			}
+
+			hs := NewHttpServer(cb, func(OtlpServer) {})
+			listener, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %s", err)
+			}
+			go hs.Serve(listener)
+			url := "http://" + listener.Addr().String() + "/v1/traces"
+
+			go http.Post(url, "application/x-protobuf", bytes.NewReader(exported))
+			<-entered
+
+			// requests that arrive while draining are turned away, drain
+			// is the first thing Shutdown does
+			hs.inflight.drain()
+			rec := httptest.NewRecorder()
+			hs.ServeHTTP(rec, httptest.NewRequest("POST", "/v1/traces", bytes.NewReader(exported)))
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Errorf("expected %d while draining but got %d", http.StatusServiceUnavailable, rec.Code)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+			type result struct {
+				stats DrainStats
+				err   error
+			}
+			done := make(chan result)
+			go func() {
+				stats, err := hs.Shutdown(ctx)
+				done <- result{stats, err}
+			}()
+
+			if tc.wantErr == nil {
+				release <- struct{}{}
+				<-consumed
+			}
+			res := <-done
+			if !errors.Is(res.err, tc.wantErr) {
+				t.Errorf("expected error %v but got %v", tc.wantErr, res.err)
+			}
+			if res.stats != tc.want {
+				t.Errorf("expected %+v but got %+v", tc.want, res.stats)
+			}
+		})
+	}
+}
@@ -3,15 +3,23 @@ package otlpserver
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/csv"
 	"log"
 	"net"
 	"sync"
 
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 // GrpcServer is a gRPC/OTLP server handle.
@@ -22,6 +30,9 @@ type GrpcServer struct {
 	stopper  chan struct{}
 	stopdone chan struct{}
 	doneonce sync.Once
+	inflight inflight
+	signals  SignalCallback
+	tls      *tls.Config // nil for plaintext
 	coltracepb.UnimplementedTraceServiceServer
 }
 
@@ -29,7 +40,7 @@ type GrpcServer struct {
 // to run with .Serve().
 func NewGrpcServer(cb Callback, stop Stopper) *GrpcServer {
 	s := GrpcServer{
-		server:   grpc.NewServer(),
+		server:   grpc.NewServer(grpc.Creds(tlsPassthroughCreds{})),
 		callback: cb,
 		stopper:  make(chan struct{}),
 		stopdone: make(chan struct{}, 1),
@@ -55,18 +66,27 @@ func (gs *GrpcServer) Serve(listener net.Listener) error {
 	return err
 }
 
-// ListenAndServeGRPC starts a TCP listener then starts the GRPC server using
+// ListenAndServeGRPC starts a TCP or unix:// socket listener then starts the GRPC server using
 // ServeGRPC for you.
 func (gs *GrpcServer) ListenAndServe(otlpEndpoint string) {
-	listener, err := net.Listen("tcp", otlpEndpoint)
+	listener, err := listen(otlpEndpoint)
 	if err != nil {
 		log.Fatalf("failed to listen on OTLP endpoint %q: %s", otlpEndpoint, err)
 	}
+	if gs.tls != nil {
+		listener = tls.NewListener(listener, gs.tls)
+	}
 	if err := gs.Serve(listener); err != nil {
 		log.Fatalf("failed to serve: %s", err)
 	}
 }
 
+// SetTlsConfig has ListenAndServe serve TLS with conf.
+func (gs *GrpcServer) SetTlsConfig(conf *tls.Config) {
+	gs.tls = conf.Clone()
+	gs.tls.NextProtos = []string{"h2"} // gRPC clients require ALPN
+}
+
 // Stop sends a value to the server shutdown goroutine so it stops GRPC
 // and calls the stop function given to newServer. Safe to call multiple times.
 func (gs *GrpcServer) Stop() {
@@ -83,10 +103,67 @@ func (gs *GrpcServer) StopWait() {
 	})
 }
 
+// Shutdown stops the server gracefully, waiting for in-flight exports until
+// ctx is done and then closing any connections that are left.
+func (gs *GrpcServer) Shutdown(ctx context.Context) (DrainStats, error) {
+	gs.inflight.drain()
+	gs.Stop() // GracefulStop refuses new RPCs and waits for the rest
+	stats, err := gs.inflight.wait(ctx)
+	if err != nil {
+		gs.server.Stop()
+	}
+	return stats, err
+}
+
+// SetSignalCallback registers the OTLP logs and metrics services, which pass
+// what they receive to cb.
+func (gs *GrpcServer) SetSignalCallback(cb SignalCallback) {
+	gs.signals = cb
+	collogspb.RegisterLogsServiceServer(gs.server, &grpcLogsServer{gs: gs})
+	colmetricspb.RegisterMetricsServiceServer(gs.server, &grpcMetricsServer{gs: gs})
+}
+
 // Export implements the gRPC server interface for exporting messages.
 func (gs *GrpcServer) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
-	// OTLP/gRPC headers are passed in metadata, copy them to serverMeta
-	// for now. This isn't ideal but gets them exposed to the test suite.
+	if !gs.inflight.begin() {
+		return nil, status.Error(codes.Unavailable, "server is shutting down")
+	}
+	defer gs.inflight.end()
+
+	done := doCallback(ctx, gs.callback, req, grpcHeaders(ctx), grpcMeta(ctx))
+	if done {
+		go gs.StopWait()
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+// exportSignal passes a logs or metrics export request to the signal callback.
+func (gs *GrpcServer) exportSignal(ctx context.Context, signal string, req proto.Message) error {
+	if !gs.inflight.begin() {
+		return status.Error(codes.Unavailable, "server is shutting down")
+	}
+	defer gs.inflight.end()
+
+	gs.signals(ctx, signal, req, grpcHeaders(ctx), grpcMeta(ctx))
+	return nil
+}
+
+// grpcMeta returns the server meta for a request, with the TLS details when
+// the listener it came in on does TLS.
+func grpcMeta(ctx context.Context) map[string]string {
+	meta := map[string]string{"proto": "grpc"}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			addTlsMeta(meta, &info.State)
+		}
+	}
+	return meta
+}
+
+// grpcHeaders returns the request's metadata as headers.
+// OTLP/gRPC headers are passed in metadata, copy them to serverMeta
+// for now. This isn't ideal but gets them exposed to the test suite.
+func grpcHeaders(ctx context.Context) map[string]string {
 	headers := make(map[string]string)
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		for mdk := range md {
@@ -96,10 +173,34 @@ func (gs *GrpcServer) Export(ctx context.Context, req *coltracepb.ExportTraceSer
 			headers[mdk] = buf.String()
 		}
 	}
+	return headers
+}
 
-	done := doCallback(ctx, gs.callback, req, headers, map[string]string{"proto": "grpc"})
-	if done {
-		go gs.StopWait()
+// grpcLogsServer is the OTLP logs service for a GrpcServer, separate since
+// each service needs its own Export method.
+type grpcLogsServer struct {
+	gs *GrpcServer
+	collogspb.UnimplementedLogsServiceServer
+}
+
+// Export implements the gRPC logs service.
+func (ls *grpcLogsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	if err := ls.gs.exportSignal(ctx, LogsSignal, req); err != nil {
+		return nil, err
 	}
-	return &coltracepb.ExportTraceServiceResponse{}, nil
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// grpcMetricsServer is the OTLP metrics service for a GrpcServer.
+type grpcMetricsServer struct {
+	gs *GrpcServer
+	colmetricspb.UnimplementedMetricsServiceServer
+}
+
+// Export implements the gRPC metrics service.
+func (ms *grpcMetricsServer) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	if err := ms.gs.exportSignal(ctx, MetricsSignal, req); err != nil {
+		return nil, err
+	}
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
 }
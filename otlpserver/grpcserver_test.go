@@ -0,0 +1,107 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestServerSignals(t *testing.T) {
+	logsReq := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{ScopeLogs: []*logspb.ScopeLogs{{
+			LogRecords: []*logspb.LogRecord{{SeverityText: "INFO", TraceId: []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}}},
+		}}}},
+	}
+	metricsReq := &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{ScopeMetrics: []*metricspb.ScopeMetrics{{
+			Metrics: []*metricspb.Metric{{Name: "job.runs"}},
+		}}}},
+	}
+
+	type received struct {
+		signal string
+		req    proto.Message
+	}
+	start := func(t *testing.T, protocol string) (string, chan received) {
+		got := make(chan received, 2)
+		spans := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+			t.Errorf("expected no spans, got %q", span.Name)
+			return false
+		}
+		cs := NewServer(protocol, spans, func(OtlpServer) {})
+		cs.(SignalServer).SetSignalCallback(func(ctx context.Context, signal string, req proto.Message, headers map[string]string, meta map[string]string) {
+			got <- received{signal, req}
+		})
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %s", err)
+		}
+		go cs.Serve(listener)
+		t.Cleanup(cs.Stop)
+		return listener.Addr().String(), got
+	}
+	check := func(t *testing.T, got chan received, signal string, want proto.Message) {
+		select {
+		case r := <-got:
+			if r.signal != signal || !proto.Equal(want, r.req) {
+				t.Errorf("expected %s %v, got %s %v", signal, want, r.signal, r.req)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", signal)
+		}
+	}
+
+	t.Run("grpc", func(t *testing.T) {
+		addr, got := start(t, "grpc")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("failed to connect: %s", err)
+		}
+		defer conn.Close()
+
+		if _, err := collogspb.NewLogsServiceClient(conn).Export(ctx, logsReq); err != nil {
+			t.Fatalf("failed to export logs: %s", err)
+		}
+		check(t, got, LogsSignal, logsReq)
+		if _, err := colmetricspb.NewMetricsServiceClient(conn).Export(ctx, metricsReq); err != nil {
+			t.Fatalf("failed to export metrics: %s", err)
+		}
+		check(t, got, MetricsSignal, metricsReq)
+	})
+
+	t.Run("http", func(t *testing.T) {
+		addr, got := start(t, "http")
+		post := func(path, contentType string, body []byte) {
+			resp, err := http.Post("http://"+addr+path, contentType, bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to post to %s: %s", path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("expected %d from %s, got %d", http.StatusOK, path, resp.StatusCode)
+			}
+		}
+
+		logsJson, _ := otlpclient.MarshalOtlpJson(logsReq)
+		post("/v1/logs", "application/json", logsJson)
+		check(t, got, LogsSignal, logsReq)
+		metricsPb, _ := proto.Marshal(metricsReq)
+		post("/v1/metrics", "application/x-protobuf", metricsPb)
+		check(t, got, MetricsSignal, metricsReq)
+	})
+}
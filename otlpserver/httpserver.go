@@ -1,21 +1,36 @@
 package otlpserver
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	"github.com/klauspost/compress/zstd"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
 	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	"google.golang.org/protobuf/proto"
 )
 
-// HttpServer is a handle for otlp over http/protobuf.
+// HttpServer is a handle for otlp over http/protobuf and http/json.
 type HttpServer struct {
-	server   *http.Server
-	callback Callback
+	server      *http.Server
+	callback    Callback
+	inflight    inflight
+	corsOrigins []string // nil when CORS is off
+	signals     SignalCallback
+	tls         *tls.Config // nil for plaintext
 }
 
 // NewServer takes a callback and stop function and returns a Server ready
@@ -31,22 +46,75 @@ func NewHttpServer(cb Callback, stop Stopper) *HttpServer {
 	return &s
 }
 
+// SetCorsOrigins turns on CORS for the origins, e.g. http://localhost:3000,
+// so browser OTel SDKs on those pages can export to the server. An origin of
+// * allows any page. Empty turns CORS off.
+func (hs *HttpServer) SetCorsOrigins(origins []string) {
+	hs.corsOrigins = origins
+}
+
+// SetSignalCallback routes requests to the OTLP logs and metrics paths,
+// /v1/logs and /v1/metrics, to cb instead of treating them as traces.
+func (hs *HttpServer) SetSignalCallback(cb SignalCallback) {
+	hs.signals = cb
+}
+
 // ServeHTTP processes every request as if it is a trace regardless of
-// method and path or anything else.
+// method and path or anything else, except for logs and metrics when there's
+// a signal callback. With CORS on, browsers' OPTIONS preflight requests are
+// answered instead.
 func (hs *HttpServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if len(hs.corsOrigins) > 0 && hs.handleCors(rw, req) {
+		return
+	}
+
+	if !hs.inflight.begin() {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer hs.inflight.end()
+
 	data, err := io.ReadAll(req.Body)
 	if err != nil {
 		log.Fatalf("Error while reading request body: %s", err)
 	}
 
-	msg := coltracepb.ExportTraceServiceRequest{}
-	switch req.Header.Get("Content-Type") {
+	data, err = decompressBody(req.Header.Get("Content-Encoding"), data)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// SDKs may add parameters, e.g. application/json; charset=utf-8
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	signal := ""
+	if hs.signals != nil {
+		signal = signalForPath(req.URL.Path)
+	}
+
+	var msg proto.Message
+	switch signal {
+	case LogsSignal:
+		msg = &collogspb.ExportLogsServiceRequest{}
+	case MetricsSignal:
+		msg = &colmetricspb.ExportMetricsServiceRequest{}
+	default:
+		msg = &coltracepb.ExportTraceServiceRequest{}
+	}
+
+	switch contentType {
 	case "application/x-protobuf":
-		proto.Unmarshal(data, &msg)
+		err = proto.Unmarshal(data, msg)
 	case "application/json":
-		json.Unmarshal(data, &msg)
+		err = otlpclient.UnmarshalOtlpJson(data, msg)
 	default:
-		rw.WriteHeader(http.StatusNotAcceptable)
+		rw.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	meta := map[string]string{
@@ -56,18 +124,81 @@ func (hs *HttpServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 		"host":         req.Host,
 		"uri":          req.RequestURI,
 	}
+	addTlsMeta(meta, req.TLS)
 
 	headers := make(map[string]string)
 	for k := range req.Header {
 		headers[k] = req.Header.Get(k)
 	}
 
-	done := doCallback(req.Context(), hs.callback, &msg, headers, meta)
+	var done bool
+	if signal != "" {
+		hs.signals(req.Context(), signal, msg, headers, meta)
+	} else {
+		done = doCallback(req.Context(), hs.callback, msg.(*coltracepb.ExportTraceServiceRequest), headers, meta)
+	}
+
+	// clients check the response type, so reply in the format they sent,
+	// the empty responses of all three signals encode the same
+	switch contentType {
+	case "application/x-protobuf":
+		body, _ := proto.Marshal(&coltracepb.ExportTraceServiceResponse{})
+		rw.Header().Set("Content-Type", "application/x-protobuf")
+		rw.Write(body)
+	case "application/json":
+		rw.Header().Set("Content-Type", "application/json")
+		rw.Write([]byte("{}"))
+	}
+
 	if done {
 		go hs.StopWait()
 	}
 }
 
+// signalForPath returns the signal for the OTLP/HTTP logs and metrics paths,
+// or an empty string for traces and anything else.
+func signalForPath(path string) string {
+	switch {
+	case strings.HasSuffix(path, "/v1/logs"):
+		return LogsSignal
+	case strings.HasSuffix(path, "/v1/metrics"):
+		return MetricsSignal
+	}
+	return ""
+}
+
+// handleCors adds the CORS headers for an allowed Origin and returns true
+// when the request was a preflight that has been answered.
+func (hs *HttpServer) handleCors(rw http.ResponseWriter, req *http.Request) bool {
+	origin := req.Header.Get("Origin")
+	allowed := origin != "" && (slices.Contains(hs.corsOrigins, "*") || slices.Contains(hs.corsOrigins, origin))
+	preflight := req.Method == http.MethodOptions && req.Header.Get("Access-Control-Request-Method") != ""
+
+	rw.Header().Add("Vary", "Origin")
+	if allowed {
+		rw.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if !preflight {
+		return false
+	}
+
+	if !allowed {
+		rw.WriteHeader(http.StatusForbidden)
+		return true
+	}
+	// SDKs send the content type and sometimes auth headers, so allow
+	// whatever the browser asks for
+	headers := req.Header.Get("Access-Control-Request-Headers")
+	if headers == "" {
+		headers = "Content-Type"
+	}
+	rw.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	rw.Header().Set("Access-Control-Allow-Headers", headers)
+	rw.Header().Set("Access-Control-Max-Age", "3600")
+	rw.WriteHeader(http.StatusNoContent)
+	return true
+}
+
 // ServeHttp takes a listener and starts the HTTP server on that listener.
 // Blocks until Stop() is called.
 func (hs *HttpServer) Serve(listener net.Listener) error {
@@ -75,18 +206,27 @@ func (hs *HttpServer) Serve(listener net.Listener) error {
 	return err
 }
 
-// ListenAndServeHttp starts a TCP listener then starts the HTTP server using
+// ListenAndServeHttp starts a TCP or unix:// socket listener then starts the HTTP server using
 // ServeHttp for you.
 func (hs *HttpServer) ListenAndServe(otlpEndpoint string) {
-	listener, err := net.Listen("tcp", otlpEndpoint)
+	listener, err := listen(otlpEndpoint)
 	if err != nil {
 		log.Fatalf("failed to listen on OTLP endpoint %q: %s", otlpEndpoint, err)
 	}
-	if err := hs.Serve(listener); err != nil {
+	if hs.tls != nil {
+		listener = tls.NewListener(listener, hs.tls)
+	}
+	if err := hs.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("failed to serve: %s", err)
 	}
 }
 
+// SetTlsConfig has ListenAndServe serve TLS with conf.
+func (hs *HttpServer) SetTlsConfig(conf *tls.Config) {
+	hs.tls = conf.Clone()
+	hs.tls.NextProtos = []string{"http/1.1"}
+}
+
 // Stop closes the http server and all active connections immediately.
 func (hs *HttpServer) Stop() {
 	hs.server.Close()
@@ -96,3 +236,38 @@ func (hs *HttpServer) Stop() {
 func (hs *HttpServer) StopWait() {
 	hs.server.Shutdown(context.Background())
 }
+
+// Shutdown stops the http server gracefully, waiting for in-flight exports
+// until ctx is done and then closing any connections that are left.
+func (hs *HttpServer) Shutdown(ctx context.Context) (DrainStats, error) {
+	hs.inflight.drain()
+	go hs.server.Shutdown(ctx) // stops listening and closes idle connections
+	stats, err := hs.inflight.wait(ctx)
+	if err != nil {
+		hs.server.Close()
+	}
+	return stats, err
+}
+
+// decompressBody undoes the Content-Encoding clients may use, gzip or zstd.
+func decompressBody(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return data, nil
+	case "gzip":
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	case "zstd":
+		d, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer d.Close()
+		return d.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q", encoding)
+	}
+}
@@ -0,0 +1,161 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHttpServerCors(t *testing.T) {
+	exported, _ := proto.Marshal(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{
+			ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{{Name: "from the browser"}}}},
+		}},
+	})
+	spans := 0
+	cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+		spans++
+		return false
+	}
+
+	preflight := func(hs *HttpServer, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/v1/traces", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-api-key")
+		rec := httptest.NewRecorder()
+		hs.ServeHTTP(rec, req)
+		return rec
+	}
+	post := func(hs *HttpServer, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader(exported))
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		hs.ServeHTTP(rec, req)
+		return rec
+	}
+
+	hs := NewHttpServer(cb, func(OtlpServer) {})
+	hs.SetCorsOrigins([]string{"http://localhost:3000"})
+
+	rec := preflight(hs, "http://localhost:3000")
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("expected %d for an allowed preflight, got %d", http.StatusNoContent, rec.Code)
+	}
+	for header, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "http://localhost:3000",
+		"Access-Control-Allow-Methods": "POST, OPTIONS",
+		"Access-Control-Allow-Headers": "content-type,x-api-key",
+		"Vary":                         "Origin",
+	} {
+		if got := rec.Header().Get(header); got != want {
+			t.Errorf("expected preflight %s %q, got %q", header, want, got)
+		}
+	}
+	if spans != 0 {
+		t.Errorf("expected the preflight not to be handled as an export, got %d span(s)", spans)
+	}
+
+	rec = preflight(hs, "http://evil.example")
+	if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected a forbidden preflight without CORS headers for another origin, got %d %v", rec.Code, rec.Header())
+	}
+
+	rec = post(hs, "http://localhost:3000")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+		t.Errorf("expected the export to be allowed, got %d %v", rec.Code, rec.Header())
+	}
+	// other origins' exports are still accepted, the browser keeps the page
+	// from reading the response
+	rec = post(hs, "http://evil.example")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("expected no CORS headers for another origin, got %v", rec.Header())
+	}
+	if spans != 2 {
+		t.Errorf("expected 2 spans, got %d", spans)
+	}
+
+	hs.SetCorsOrigins([]string{"*"})
+	if rec := preflight(hs, "http://anything.example"); rec.Header().Get("Access-Control-Allow-Origin") != "http://anything.example" {
+		t.Errorf("expected * to allow any origin, got %v", rec.Header())
+	}
+
+	// without CORS, no headers are added
+	hs.SetCorsOrigins(nil)
+	if rec := post(hs, "http://localhost:3000"); rec.Header().Get("Access-Control-Allow-Origin") != "" || rec.Header().Get("Vary") != "" {
+		t.Errorf("expected no CORS headers when it's off, got %v", rec.Header())
+	}
+}
+
+func TestHttpServerJson(t *testing.T) {
+	var got []*tracepb.Span
+	cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+		got = append(got, span)
+		return false
+	}
+	hs := NewHttpServer(cb, func(OtlpServer) {})
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+		wantSpans   int
+	}{
+		{
+			name:        "OTLP/JSON with hex ids",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0101010101010101","name":"from json","kind":"SPAN_KIND_SERVER","someNewField":1}]}]}]}`,
+			wantCode:    http.StatusOK,
+			wantSpans:   1,
+		},
+		{
+			name:        "invalid json",
+			contentType: "application/json",
+			body:        `{"resourceSpans":`,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        "hello",
+			wantCode:    http.StatusUnsupportedMediaType,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got = nil
+			req := httptest.NewRequest(http.MethodPost, "/v1/traces", bytes.NewReader([]byte(tc.body)))
+			req.Header.Set("Content-Type", tc.contentType)
+			rec := httptest.NewRecorder()
+			hs.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Errorf("expected status %d, got %d", tc.wantCode, rec.Code)
+			}
+			if len(got) != tc.wantSpans {
+				t.Fatalf("expected %d spans, got %d", tc.wantSpans, len(got))
+			}
+			if tc.wantSpans == 0 {
+				return
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("expected a json response, got %q", ct)
+			}
+			span := got[0]
+			if hex.EncodeToString(span.TraceId) != "0102030405060708090a0b0c0d0e0f10" || hex.EncodeToString(span.SpanId) != "0101010101010101" {
+				t.Errorf("ids were not decoded from hex, got %x and %x", span.TraceId, span.SpanId)
+			}
+			if span.Name != "from json" || span.Kind != tracepb.Span_SPAN_KIND_SERVER {
+				t.Errorf("expected a server span named \"from json\", got %q %s", span.Name, span.Kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package otlpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+)
+
+// maxLineSize is the largest OTLP/JSON line accepted, which is also as big
+// as a UDP datagram can get.
+const maxLineSize = 65535
+
+// LineServer is an experimental OTLP server that reads newline-delimited
+// OTLP/JSON ExportTraceServiceRequests from UDP datagrams or a stream such as
+// stdin. It's for environments that can't speak gRPC or HTTP, e.g. embedded
+// devices and initramfs scripts that can still fire off a UDP packet.
+type LineServer struct {
+	callback Callback
+	reader   io.Reader
+	conn     net.PacketConn
+	meta     map[string]string // for readers, udp sets it per datagram
+	stoponce sync.Once
+	stopper  chan struct{}
+	stopfunc Stopper
+	inflight inflight
+}
+
+// NewUdpServer returns a LineServer that will listen for datagrams when
+// ListenAndServe is called.
+func NewUdpServer(cb Callback, stop Stopper) *LineServer {
+	return &LineServer{
+		callback: cb,
+		stopper:  make(chan struct{}),
+		stopfunc: stop,
+	}
+}
+
+// NewReaderServer returns a LineServer that reads lines from the provided
+// reader until EOF or Stop.
+func NewReaderServer(reader io.Reader, cb Callback, stop Stopper) *LineServer {
+	return &LineServer{
+		callback: cb,
+		reader:   reader,
+		meta:     map[string]string{"proto": "stdin"},
+		stopper:  make(chan struct{}),
+		stopfunc: stop,
+	}
+}
+
+// ListenAndServe starts reading. For UDP it listens on the endpoint, for
+// readers the endpoint is ignored. Blocks until Stop() is called, or
+// until EOF on readers.
+func (ls *LineServer) ListenAndServe(otlpEndpoint string) {
+	if ls.reader != nil {
+		ls.serveReader()
+		return
+	}
+
+	conn, err := net.ListenPacket("udp", otlpEndpoint)
+	if err != nil {
+		log.Fatalf("failed to listen on UDP endpoint %q: %s", otlpEndpoint, err)
+	}
+	ls.conn = conn
+	defer conn.Close()
+	ls.serveUdp()
+}
+
+// Serve is not supported since UDP and stdin don't use a net.Listener.
+func (ls *LineServer) Serve(listener net.Listener) error {
+	return fmt.Errorf("the line server does not support net.Listener, use ListenAndServe")
+}
+
+// Stop closes the UDP socket or stops reading lines. Safe to call multiple times.
+func (ls *LineServer) Stop() {
+	ls.stoponce.Do(func() {
+		close(ls.stopper)
+		if ls.conn != nil {
+			ls.conn.Close()
+		}
+		ls.stopfunc(ls)
+	})
+}
+
+// StopWait is the same as Stop since there are no requests in flight to
+// wait for.
+func (ls *LineServer) StopWait() {
+	ls.Stop()
+}
+
+// Shutdown stops reading and waits until ctx is done for the line being
+// handled, if any, to finish.
+func (ls *LineServer) Shutdown(ctx context.Context) (DrainStats, error) {
+	ls.inflight.drain()
+	ls.Stop()
+	return ls.inflight.wait(ctx)
+}
+
+// serveUdp handles each datagram as one or more lines of OTLP/JSON.
+func (ls *LineServer) serveUdp() {
+	buf := make([]byte, maxLineSize)
+	for {
+		n, addr, err := ls.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-ls.stopper:
+				return
+			default:
+				log.Fatalf("failed to read UDP datagram: %s", err)
+			}
+		}
+
+		meta := map[string]string{"proto": "udp", "remote": addr.String()}
+		for _, line := range bytes.Split(buf[:n], []byte("\n")) {
+			if ls.handleLine(line, meta) {
+				go ls.Stop()
+				return
+			}
+		}
+	}
+}
+
+// serveReader reads lines in a goroutine so Stop doesn't have to wait for
+// a blocked read.
+func (ls *LineServer) serveReader() {
+	lines := make(chan []byte)
+	go func() {
+		scanner := bufio.NewScanner(ls.reader)
+		scanner.Buffer(make([]byte, maxLineSize), maxLineSize)
+		for scanner.Scan() {
+			line := append([]byte{}, scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ls.stopper:
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("failed to read OTLP/JSON line: %s", err)
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				ls.Stop()
+				return
+			}
+			if ls.handleLine(line, ls.meta) {
+				ls.Stop()
+				return
+			}
+		case <-ls.stopper:
+			return
+		}
+	}
+}
+
+// handleLine decodes one line and runs the callback on its spans. Bad input
+// is logged and skipped so one garbled packet doesn't take the server down.
+func (ls *LineServer) handleLine(line []byte, meta map[string]string) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return false
+	}
+
+	if !ls.inflight.begin() {
+		return false
+	}
+	defer ls.inflight.end()
+
+	msg := coltracepb.ExportTraceServiceRequest{}
+	if err := otlpclient.UnmarshalOtlpJson(line, &msg); err != nil {
+		log.Printf("ignoring invalid OTLP/JSON line: %s", err)
+		return false
+	}
+
+	return doCallback(context.Background(), ls.callback, &msg, map[string]string{}, meta)
+}
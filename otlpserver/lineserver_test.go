@@ -0,0 +1,56 @@
+package otlpserver
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestReaderServer(t *testing.T) {
+	input := strings.Join([]string{
+		`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"0102030405060708","name":"first","kind":2,"startTimeUnixNano":"1617739561000000000"}]}]}]}`,
+		`this is not json`,
+		``,
+		`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"0102030405060708090a0b0c0d0e0f10","spanId":"1112131415161718","parentSpanId":"0102030405060708","name":"second"}]}]}]}`,
+	}, "\n")
+
+	got := []*tracepb.Span{}
+	cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+		if meta["proto"] != "stdin" {
+			t.Errorf("expected proto meta to be stdin but got %q", meta["proto"])
+		}
+		got = append(got, span)
+		return false
+	}
+
+	stopped := false
+	ls := NewReaderServer(strings.NewReader(input), cb, func(OtlpServer) { stopped = true })
+	ls.ListenAndServe("") // returns on EOF
+
+	if !stopped {
+		t.Error("stop function was not called at EOF")
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected 2 spans but got %d", len(got))
+	}
+
+	if tid := hex.EncodeToString(got[0].TraceId); tid != "0102030405060708090a0b0c0d0e0f10" {
+		t.Errorf("hex trace id was not decoded, got %q", tid)
+	}
+
+	if got[0].Kind != tracepb.Span_SPAN_KIND_SERVER {
+		t.Errorf("expected kind server but got %s", got[0].Kind)
+	}
+
+	if got[0].StartTimeUnixNano != 1617739561000000000 {
+		t.Errorf("start time was not decoded, got %d", got[0].StartTimeUnixNano)
+	}
+
+	if psid := hex.EncodeToString(got[1].ParentSpanId); psid != "0102030405060708" {
+		t.Errorf("hex parent span id was not decoded, got %q", psid)
+	}
+}
@@ -0,0 +1,35 @@
+package otlpserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixPrefix marks an endpoint given to ListenAndServe as a Unix socket path.
+const unixPrefix = "unix://"
+
+// listen opens a TCP listener on host:port, or a Unix domain socket for
+// unix:///path/to.sock so the server can run as a sidecar without TCP.
+// A socket left behind by a server that didn't exit cleanly is removed,
+// but one that's still accepting connections is left alone.
+func listen(otlpEndpoint string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(otlpEndpoint, unixPrefix)
+	if !isUnix {
+		return net.Listen("tcp", otlpEndpoint)
+	}
+
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%q exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("another server is already listening on %q", path)
+		}
+		os.Remove(path)
+	}
+
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,46 @@
+package otlpserver
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	dir := t.TempDir()
+
+	// a socket left behind by a server that was killed is replaced
+	stale := filepath.Join(dir, "stale.sock")
+	old, err := net.Listen("unix", stale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.(*net.UnixListener).SetUnlinkOnClose(false)
+	old.Close()
+
+	listener, err := listen("unix://" + stale)
+	if err != nil {
+		t.Fatalf("expected the stale socket to be replaced, got %s", err)
+	}
+
+	// but not while a server is using it
+	if _, err := listen("unix://" + stale); err == nil {
+		t.Error("expected an error listening on a socket that's in use")
+	}
+	listener.Close()
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the socket to be removed on close, got %v", err)
+	}
+
+	// and other files are never removed
+	file := filepath.Join(dir, "file")
+	os.WriteFile(file, []byte("data"), 0600)
+	if _, err := listen("unix://" + file); err == nil {
+		t.Error("expected an error listening on a regular file")
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("expected the regular file to be left alone, got %s", err)
+	}
+}
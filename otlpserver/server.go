@@ -6,10 +6,13 @@ package otlpserver
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
+	"os"
 
 	colv1 "go.opentelemetry.io/proto/otlp/collector/trace/v1"
 	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
 )
 
 // Callback is a type for the function passed to newServer that is
@@ -20,6 +23,31 @@ type Callback func(context.Context, *tracepb.Span, []*tracepb.Span_Event, *trace
 // server is shut down.
 type Stopper func(OtlpServer)
 
+// the signals other than traces that servers can receive
+const (
+	LogsSignal    = "logs"
+	MetricsSignal = "metrics"
+)
+
+// SignalCallback is called with each logs or metrics export request a server
+// receives, an *ExportLogsServiceRequest or *ExportMetricsServiceRequest
+// depending on the signal.
+type SignalCallback func(ctx context.Context, signal string, req proto.Message, headers map[string]string, meta map[string]string)
+
+// SignalServer is implemented by the servers that can receive logs and
+// metrics as well as traces, which are OTLP/gRPC and OTLP/HTTP. Until a
+// SignalCallback is set, they only take traces. Must be called once, before
+// the server starts serving.
+type SignalServer interface {
+	SetSignalCallback(cb SignalCallback)
+}
+
+// TlsServer is implemented by the servers that can serve TLS, which are
+// OTLP/gRPC and OTLP/HTTP. Must be called before ListenAndServe.
+type TlsServer interface {
+	SetTlsConfig(conf *tls.Config)
+}
+
 // OtlpServer abstracts the minimum interface required for an OTLP
 // server to be either HTTP or gRPC (but not both, for now).
 type OtlpServer interface {
@@ -27,16 +55,24 @@ type OtlpServer interface {
 	Serve(listener net.Listener) error
 	Stop()
 	StopWait()
+	// Shutdown stops taking new requests and waits for in-flight ones until
+	// ctx is done, then stops the server. Returns ctx's error if requests
+	// were abandoned at the deadline.
+	Shutdown(ctx context.Context) (DrainStats, error)
 }
 
 // NewServer will start the requested server protocol, one of grpc, http/protobuf,
-// and http/json.
+// and http/json, or the experimental udp and stdin OTLP/JSON line servers.
 func NewServer(protocol string, cb Callback, stop Stopper) OtlpServer {
 	switch protocol {
 	case "grpc":
 		return NewGrpcServer(cb, stop)
 	case "http":
 		return NewHttpServer(cb, stop)
+	case "udp":
+		return NewUdpServer(cb, stop)
+	case "stdin":
+		return NewReaderServer(os.Stdin, cb, stop)
 	}
 
 	return nil
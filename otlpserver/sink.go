@@ -0,0 +1,71 @@
+package otlpserver
+
+import (
+	"context"
+	"errors"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SpanSink is implemented by anything that consumes spans received by the
+// server, e.g. the tui renderer or the json file writer. Sinks can be stacked
+// with MultiSink so one server can feed several outputs at the same time.
+type SpanSink interface {
+	// Consume is called for each incoming span. Returning true tells the
+	// server it is done and can stop.
+	Consume(context.Context, *tracepb.Span, []*tracepb.Span_Event, *tracepb.ResourceSpans, map[string]string, map[string]string) bool
+	// Close is called once when the server is shutting down.
+	Close() error
+}
+
+// CallbackSink adapts a plain Callback func to the SpanSink interface.
+type CallbackSink Callback
+
+// Consume calls through to the wrapped Callback.
+func (cs CallbackSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	return cs(ctx, span, events, rss, headers, meta)
+}
+
+// Close fulfills the interface and does nothing.
+func (cs CallbackSink) Close() error {
+	return nil
+}
+
+// MultiSink is a list of sinks that all receive every span.
+type MultiSink []SpanSink
+
+// NewMultiSink returns a MultiSink that fans out spans to all of the
+// provided sinks, in order. nil sinks are skipped so callers can pass
+// optional sinks without checking them first.
+func NewMultiSink(sinks ...SpanSink) MultiSink {
+	out := MultiSink{}
+	for _, sink := range sinks {
+		if sink != nil {
+			out = append(out, sink)
+		}
+	}
+	return out
+}
+
+// Consume passes the span to every sink. All sinks see the span even if an
+// earlier one reports it is done, and the server stops if any sink is done.
+func (ms MultiSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	var done bool
+	for _, sink := range ms {
+		if sink.Consume(ctx, span, events, rss, headers, meta) {
+			done = true
+		}
+	}
+	return done
+}
+
+// Close closes all of the sinks and returns any errors joined together.
+func (ms MultiSink) Close() error {
+	errs := []error{}
+	for _, sink := range ms {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,130 @@
+package otlpserver
+
+import (
+	"context"
+	"sync"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// BufferSink keeps received spans in memory, bounded by a span count and a
+// byte size, evicting the oldest spans to make room for new ones so a long
+// capture against a chatty app can't run the host out of memory. It doesn't
+// pass spans anywhere, stack it with other sinks using MultiSink.
+type BufferSink struct {
+	maxSpans int // 0 for no limit
+	maxBytes int // 0 for no limit
+	spans    []BufferedSpan
+	bytes    int
+	evicted  int
+	onEvict  func(BufferedSpan)
+	mu       sync.Mutex
+}
+
+// BufferedSpan is a span and its events as kept by BufferSink.
+type BufferedSpan struct {
+	Span   *tracepb.Span
+	Events []*tracepb.Span_Event
+	size   int
+}
+
+// NewBufferSink returns a BufferSink that holds at most maxSpans spans taking
+// up at most maxBytes, as measured by their protobuf encoding. Either limit
+// can be 0 to turn it off.
+func NewBufferSink(maxSpans, maxBytes int) *BufferSink {
+	return &BufferSink{
+		maxSpans: maxSpans,
+		maxBytes: maxBytes,
+		spans:    []BufferedSpan{},
+	}
+}
+
+// OnEvict sets a func that is called with each span as it's evicted, so
+// callers can drop anything else they were keeping about it. It's called
+// with the buffer locked and must not call back into the BufferSink.
+func (bs *BufferSink) OnEvict(fn func(BufferedSpan)) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	bs.onEvict = fn
+}
+
+// Consume adds the span to the buffer, evicting the oldest spans if it's
+// over either limit, and always returns false.
+func (bs *BufferSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	bs.Add(span, events)
+	return false
+}
+
+// Add puts the span on the end of the buffer and evicts from the front
+// until the buffer fits in its limits again. A single span larger than
+// maxBytes is still kept, until the next one comes in.
+func (bs *BufferSink) Add(span *tracepb.Span, events []*tracepb.Span_Event) {
+	size := proto.Size(span)
+	for _, e := range events {
+		size += proto.Size(e)
+	}
+
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	bs.spans = append(bs.spans, BufferedSpan{Span: span, Events: events, size: size})
+	bs.bytes += size
+
+	var drop int
+	for drop < len(bs.spans)-1 &&
+		((bs.maxSpans > 0 && len(bs.spans)-drop > bs.maxSpans) ||
+			(bs.maxBytes > 0 && bs.bytes > bs.maxBytes)) {
+		bs.bytes -= bs.spans[drop].size
+		if bs.onEvict != nil {
+			bs.onEvict(bs.spans[drop])
+		}
+		drop++
+	}
+
+	if drop > 0 {
+		bs.evicted += drop
+		// zero the evicted entries so they can be collected, the backing array
+		// is replaced the next time append has to grow it
+		clear(bs.spans[:drop])
+		bs.spans = bs.spans[drop:]
+	}
+}
+
+// Close fulfills the interface and does nothing.
+func (bs *BufferSink) Close() error {
+	return nil
+}
+
+// Spans returns the spans in the buffer, oldest first.
+func (bs *BufferSink) Spans() []*tracepb.Span {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	out := make([]*tracepb.Span, len(bs.spans))
+	for i, bspan := range bs.spans {
+		out[i] = bspan.Span
+	}
+	return out
+}
+
+// Len returns the number of spans in the buffer.
+func (bs *BufferSink) Len() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return len(bs.spans)
+}
+
+// Bytes returns the approximate size of the spans in the buffer.
+func (bs *BufferSink) Bytes() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.bytes
+}
+
+// Evicted returns the number of spans evicted so far.
+func (bs *BufferSink) Evicted() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return bs.evicted
+}
@@ -0,0 +1,81 @@
+package otlpserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// DedupeSink wraps another sink and drops spans whose trace id and span id
+// were already seen within the window. Clients retry exports that timed out
+// on their end but made it to the server, so without this a capture can
+// count the same span twice.
+type DedupeSink struct {
+	next       SpanSink
+	window     time.Duration
+	seen       map[string]time.Time
+	lastSweep  time.Time
+	duplicates int
+	now        func() time.Time // swappable for tests
+	mu         sync.Mutex
+}
+
+// NewDedupeSink returns a DedupeSink that passes first-seen spans on to next.
+func NewDedupeSink(next SpanSink, window time.Duration) *DedupeSink {
+	return &DedupeSink{
+		next:   next,
+		window: window,
+		seen:   map[string]time.Time{},
+		now:    time.Now,
+	}
+}
+
+// Consume drops the span if it's a duplicate, otherwise passes it on to the
+// wrapped sink and returns its result.
+func (ds *DedupeSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	if ds.isDuplicate(span) {
+		return false
+	}
+	return ds.next.Consume(ctx, span, events, rss, headers, meta)
+}
+
+// Close closes the wrapped sink.
+func (ds *DedupeSink) Close() error {
+	return ds.next.Close()
+}
+
+// Duplicates returns the number of spans dropped so far.
+func (ds *DedupeSink) Duplicates() int {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	return ds.duplicates
+}
+
+// isDuplicate records the span's ids and reports whether they were already
+// seen inside the window. Expired ids are swept out at most once per window
+// so the map doesn't grow forever on long-running servers.
+func (ds *DedupeSink) isDuplicate(span *tracepb.Span) bool {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+
+	now := ds.now()
+	if now.Sub(ds.lastSweep) > ds.window {
+		for key, at := range ds.seen {
+			if now.Sub(at) > ds.window {
+				delete(ds.seen, key)
+			}
+		}
+		ds.lastSweep = now
+	}
+
+	key := string(span.GetTraceId()) + string(span.GetSpanId())
+	if at, ok := ds.seen[key]; ok && now.Sub(at) <= ds.window {
+		ds.duplicates++
+		return true
+	}
+
+	ds.seen[key] = now
+	return false
+}
@@ -0,0 +1,97 @@
+package otlpserver
+
+import (
+	"context"
+	"regexp"
+	"slices"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// SpanFilter selects spans by service, name, and attributes. A span has to
+// match every field that's set, and empty fields match everything.
+type SpanFilter struct {
+	// Services matches spans whose resource service.name is any of these.
+	Services []string
+	// Name matches span names anywhere in the name, anchor it for exact matches.
+	Name *regexp.Regexp
+	// Attrs matches spans that have all of these attributes, on the span or
+	// its resource, with these values as strings.
+	Attrs map[string]string
+}
+
+// IsEmpty returns true when the filter matches every span.
+func (sf SpanFilter) IsEmpty() bool {
+	return len(sf.Services) == 0 && sf.Name == nil && len(sf.Attrs) == 0
+}
+
+// Match returns true when the span, in the ResourceSpans it came in, passes
+// the filter.
+func (sf SpanFilter) Match(span *tracepb.Span, rss *tracepb.ResourceSpans) bool {
+	resource := rss.GetResource().GetAttributes()
+
+	if len(sf.Services) > 0 {
+		service := unknownService
+		if v := findAttr(resource, "service.name"); v != nil {
+			service = otlpclient.AnyValueToString(v)
+		}
+		if !slices.Contains(sf.Services, service) {
+			return false
+		}
+	}
+
+	if sf.Name != nil && !sf.Name.MatchString(span.GetName()) {
+		return false
+	}
+
+	for key, want := range sf.Attrs {
+		v := findAttr(span.GetAttributes(), key)
+		if v == nil {
+			v = findAttr(resource, key)
+		}
+		if v == nil || otlpclient.AnyValueToString(v) != want {
+			return false
+		}
+	}
+
+	return true
+}
+
+// findAttr returns the value of the attribute with the key, or nil.
+func findAttr(attrs []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, attr := range attrs {
+		if attr.GetKey() == key {
+			return attr.GetValue()
+		}
+	}
+	return nil
+}
+
+// FilterSink wraps another sink and only passes on spans that match the
+// filter, so a busy shared endpoint can be tapped for just the spans of
+// interest.
+type FilterSink struct {
+	next   SpanSink
+	filter SpanFilter
+}
+
+// NewFilterSink returns a FilterSink that passes matching spans on to next.
+func NewFilterSink(next SpanSink, filter SpanFilter) *FilterSink {
+	return &FilterSink{next: next, filter: filter}
+}
+
+// Consume drops the span if it doesn't match the filter, otherwise passes it
+// on to the wrapped sink and returns its result.
+func (fs *FilterSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	if !fs.filter.Match(span, rss) {
+		return false
+	}
+	return fs.next.Consume(ctx, span, events, rss, headers, meta)
+}
+
+// Close closes the wrapped sink.
+func (fs *FilterSink) Close() error {
+	return fs.next.Close()
+}
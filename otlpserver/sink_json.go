@@ -0,0 +1,104 @@
+package otlpserver
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// JsonSink writes spans and events out as json, to files in a directory
+// tree and/or as lines to an io.Writer.
+type JsonSink struct {
+	dir string
+	out io.Writer
+}
+
+// NewJsonSink returns a JsonSink. When dir is not empty, spans are written to
+// dir/traceid/spanid/span.json and events to event-N.json files next to it,
+// along with the server meta of the export the span came in, e.g. its
+// protocol and TLS details, in meta.json.
+// When out is not nil, each json document is written to it as a line.
+func NewJsonSink(dir string, out io.Writer) *JsonSink {
+	return &JsonSink{dir: dir, out: out}
+}
+
+// Consume writes the span and its events as json. Always returns false.
+func (js *JsonSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	// TODO: check for existence of outdir and error when it doesn't exist
+	var outpath string
+	if js.dir != "" {
+		// create trace directory
+		outpath = filepath.Join(js.dir, hex.EncodeToString(span.TraceId))
+		os.Mkdir(outpath, 0755) // ignore errors for now
+
+		// create span directory
+		outpath = filepath.Join(outpath, hex.EncodeToString(span.SpanId))
+		os.Mkdir(outpath, 0755) // ignore errors for now
+	}
+
+	// TODO: if a span comes in twice should we continue to overwrite span.json
+	// or attempt some kind of merge? (e.g. of attributes)
+	sjs, err := json.Marshal(span)
+	if err != nil {
+		log.Fatalf("failed to marshal span to json: %s", err)
+	}
+
+	// write the span to /path/tid/sid/span.json
+	js.write(outpath, "span.json", sjs)
+
+	for i, e := range events {
+		ejs, err := json.Marshal(e)
+		if err != nil {
+			log.Fatalf("failed to marshal span event to json: %s", err)
+		}
+
+		// write events to /path/tid/sid/event-%d.json
+		// TODO: ordering might be a problem if people rely on it...
+		filename := "event-" + strconv.Itoa(i) + ".json"
+		js.write(outpath, filename, ejs)
+	}
+
+	// meta.json is only written to the directory tree, since --stdout is
+	// for spans and events
+	if outpath != "" && len(meta) > 0 {
+		mjs, err := json.Marshal(meta)
+		if err != nil {
+			log.Fatalf("failed to marshal server meta to json: %s", err)
+		}
+		metafile := filepath.Join(outpath, "meta.json")
+		if err := os.WriteFile(metafile, mjs, 0644); err != nil {
+			log.Fatalf("could not write to file %q: %s", metafile, err)
+		}
+	}
+
+	return false
+}
+
+// Close fulfills the interface and does nothing.
+func (js *JsonSink) Close() error {
+	return nil
+}
+
+// write takes a directory path, a filename, and json. When the path is not empty
+// string the json is written to path/filename. If an output writer was provided
+// the json will be printed to it as a line.
+func (js *JsonSink) write(path, filename string, data []byte) {
+	if path != "" {
+		spanfile := filepath.Join(path, filename)
+		err := os.WriteFile(spanfile, data, 0644)
+		if err != nil {
+			log.Fatalf("could not write to file %q: %s", spanfile, err)
+		}
+	}
+
+	if js.out != nil {
+		js.out.Write(append(data, '\n'))
+	}
+}
@@ -0,0 +1,119 @@
+package otlpserver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// unknownService is the service label for spans without a service.name.
+const unknownService = "unknown_service"
+
+// MetricsSink counts the spans received per service and serves the counts in
+// the Prometheus text format, so a long-running server can be watched from
+// Grafana or anything else that scrapes Prometheus. It doesn't pass spans
+// anywhere, stack it with other sinks using MultiSink.
+type MetricsSink struct {
+	started  time.Time
+	services map[string]*serviceCounts
+	mu       sync.Mutex
+}
+
+// serviceCounts is what MetricsSink counts for each service.
+type serviceCounts struct {
+	spans  uint64
+	bytes  uint64 // protobuf size of the spans
+	errors uint64 // spans with an error status
+}
+
+// NewMetricsSink returns an empty MetricsSink.
+func NewMetricsSink() *MetricsSink {
+	return &MetricsSink{
+		started:  time.Now(),
+		services: map[string]*serviceCounts{},
+	}
+}
+
+// Consume counts the span and always returns false.
+func (ms *MetricsSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	service := unknownService
+	for _, attr := range rss.GetResource().GetAttributes() {
+		if attr.GetKey() == "service.name" && attr.GetValue().GetStringValue() != "" {
+			service = attr.GetValue().GetStringValue()
+		}
+	}
+	size := proto.Size(span)
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	counts, ok := ms.services[service]
+	if !ok {
+		counts = &serviceCounts{}
+		ms.services[service] = counts
+	}
+	counts.spans++
+	counts.bytes += uint64(size)
+	if span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		counts.errors++
+	}
+
+	return false
+}
+
+// Close fulfills the interface and does nothing.
+func (ms *MetricsSink) Close() error {
+	return nil
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (ms *MetricsSink) WriteTo(w io.Writer) (int64, error) {
+	ms.mu.Lock()
+	services := make([]string, 0, len(ms.services))
+	counts := make(map[string]serviceCounts, len(ms.services))
+	for service, c := range ms.services {
+		services = append(services, service)
+		counts[service] = *c
+	}
+	ms.mu.Unlock()
+	sort.Strings(services)
+
+	var sb strings.Builder
+	writeCounter := func(name, help string, value func(serviceCounts) uint64) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, service := range services {
+			fmt.Fprintf(&sb, "%s{service=\"%s\"} %d\n", name, escapeLabelValue(service), value(counts[service]))
+		}
+	}
+	writeCounter("otelcli_server_spans_received_total", "Spans received, by service.name.",
+		func(c serviceCounts) uint64 { return c.spans })
+	writeCounter("otelcli_server_span_bytes_received_total", "Protobuf-encoded size of the spans received, by service.name.",
+		func(c serviceCounts) uint64 { return c.bytes })
+	writeCounter("otelcli_server_error_spans_total", "Spans received with an error status, by service.name.",
+		func(c serviceCounts) uint64 { return c.errors })
+	fmt.Fprintf(&sb, "# HELP otelcli_server_start_time_seconds Unix time the server started.\n")
+	fmt.Fprintf(&sb, "# TYPE otelcli_server_start_time_seconds gauge\n")
+	fmt.Fprintf(&sb, "otelcli_server_start_time_seconds %d\n", ms.started.Unix())
+
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// ServeHTTP serves the metrics, e.g. on /metrics.
+func (ms *MetricsSink) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	ms.WriteTo(rw)
+}
+
+// escapeLabelValue escapes a Prometheus label value.
+func escapeLabelValue(in string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(in)
+}
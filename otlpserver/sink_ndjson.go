@@ -0,0 +1,158 @@
+package otlpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"sync"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// RotatingFile is an append-only file that's rotated when it would grow past
+// maxSize. Rotated files are renamed path.1, path.2, and so on, with path.1
+// the newest, and only maxFiles of them are kept.
+type RotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+	mu       sync.Mutex
+}
+
+// OpenRotatingFile opens path for appending, creating it if needed. A maxSize
+// of 0 never rotates. A maxFiles of 0 deletes the file instead of keeping it
+// when rotating.
+func OpenRotatingFile(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	rf := RotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return &rf, nil
+}
+
+// Write appends p to the file, rotating first if p would push it past
+// maxSize. Each write lands in one file so callers that write whole lines
+// never get a line split across files.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, fs.ErrClosed
+	}
+
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
+
+// open opens the file at path and picks up its current size so restarting a
+// capture keeps appending to the same file.
+func (rf *RotatingFile) open() error {
+	file, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	rf.file = file
+	rf.size = info.Size()
+	return nil
+}
+
+// rotate shifts path.N to path.N+1, drops the oldest, and starts a new file.
+func (rf *RotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	if rf.maxFiles < 1 {
+		if err := os.Remove(rf.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return rf.open()
+	}
+
+	oldest := rf.rotatedPath(rf.maxFiles)
+	if err := os.Remove(oldest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	for i := rf.maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(rf.rotatedPath(i), rf.rotatedPath(i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(rf.path, rf.rotatedPath(1)); err != nil {
+		return err
+	}
+
+	return rf.open()
+}
+
+// rotatedPath returns the name of the nth rotated file.
+func (rf *RotatingFile) rotatedPath(n int) string {
+	return fmt.Sprintf("%s.%d", rf.path, n)
+}
+
+// NdjsonSink writes each span, with its events, as one line of json. It's
+// meant for long captures where a file per span adds up to too many files.
+type NdjsonSink struct {
+	out *RotatingFile
+}
+
+// NewNdjsonSink returns an NdjsonSink that writes to out and closes it when
+// the server shuts down.
+func NewNdjsonSink(out *RotatingFile) *NdjsonSink {
+	return &NdjsonSink{out: out}
+}
+
+// Consume writes the span as a line of json. Always returns false.
+func (ns *NdjsonSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	sjs, err := json.Marshal(span)
+	if err != nil {
+		log.Fatalf("failed to marshal span to json: %s", err)
+	}
+
+	// one write per line so rotation never splits a span across files
+	_, err = ns.out.Write(append(sjs, '\n'))
+	if err != nil {
+		log.Fatalf("could not write to file %q: %s", ns.out.path, err)
+	}
+
+	return false
+}
+
+// Close closes the file.
+func (ns *NdjsonSink) Close() error {
+	return ns.out.Close()
+}
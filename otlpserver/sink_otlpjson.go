@@ -0,0 +1,152 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// OtlpJsonFilename is the file OtlpJsonSink appends to in its directory.
+const OtlpJsonFilename = "traces.jsonl"
+
+// OtlpJsonSink writes each span it receives as a newline-delimited OTLP/JSON
+// export request, with its resource, scope, links, dropped counts, and typed
+// attributes intact. The output can be replayed with otel-cli span send
+// --from-file.
+type OtlpJsonSink struct {
+	w *otlpJsonWriter
+}
+
+// NewOtlpJsonSink returns an OtlpJsonSink. When dir is not empty, requests
+// are appended to dir/traces.jsonl. When out is not nil, each request is
+// written to it as a line.
+func NewOtlpJsonSink(dir string, out io.Writer) (*OtlpJsonSink, error) {
+	w, err := newOtlpJsonWriter(dir, OtlpJsonFilename, out, "")
+	if err != nil {
+		return nil, err
+	}
+	return &OtlpJsonSink{w: w}, nil
+}
+
+// Consume writes the span. Always returns false.
+func (ojs *OtlpJsonSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	ojs.w.write(&coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{SpanResourceSpans(span, rss)},
+	})
+
+	return false
+}
+
+// Close closes the file, if there is one.
+func (ojs *OtlpJsonSink) Close() error {
+	return ojs.w.close()
+}
+
+// otlpJsonWriter appends export requests of any signal as OTLP/JSON lines to
+// a file and/or an io.Writer. Lines written to out are wrapped in an object
+// with the tag as its only key when there is one, e.g. {"logs":{...}}, so
+// signals sharing out can be told apart.
+type otlpJsonWriter struct {
+	file *os.File
+	out  io.Writer
+	tag  string
+	mu   sync.Mutex
+}
+
+// newOtlpJsonWriter opens dir/filename for appending when dir is not empty.
+func newOtlpJsonWriter(dir, filename string, out io.Writer, tag string) (*otlpJsonWriter, error) {
+	w := otlpJsonWriter{out: out, tag: tag}
+	if dir != "" {
+		path := filepath.Join(dir, filename)
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %q: %w", path, err)
+		}
+		w.file = file
+	}
+	return &w, nil
+}
+
+// write writes the request as a line.
+func (w *otlpJsonWriter) write(msg proto.Message) {
+	data, err := otlpclient.MarshalOtlpJson(msg)
+	if err != nil {
+		log.Fatalf("failed to marshal to OTLP/JSON: %s", err)
+	}
+	data = append(data, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file != nil {
+		if _, err := w.file.Write(data); err != nil {
+			log.Fatalf("could not write to file %q: %s", w.file.Name(), err)
+		}
+	}
+	if w.out != nil {
+		line := data
+		if w.tag != "" {
+			line = fmt.Appendf(nil, "{%q:%s}\n", w.tag, bytes.TrimSuffix(data, []byte("\n")))
+		}
+		w.out.Write(line)
+	}
+}
+
+// SyncWriter serializes writes to an io.Writer shared by several sinks, e.g.
+// stdout, so lines from different sinks don't interleave. Sinks write each
+// line in one Write.
+type SyncWriter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewSyncWriter returns a SyncWriter that writes to w.
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+// Write writes p to the underlying writer while holding the lock.
+func (sw *SyncWriter) Write(p []byte) (int, error) {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.w.Write(p)
+}
+
+// close closes the file, if there is one.
+func (w *otlpJsonWriter) close() error {
+	if w.file != nil {
+		return w.file.Close()
+	}
+	return nil
+}
+
+// SpanResourceSpans returns a copy of the ResourceSpans the span came in with
+// only that span in it, keeping its resource and scope. Sinks that write or
+// forward whole ResourceSpans use it so spans dropped by a FilterSink or
+// DedupeSink in front of them are left out.
+func SpanResourceSpans(span *tracepb.Span, rss *tracepb.ResourceSpans) *tracepb.ResourceSpans {
+	ss := &tracepb.ScopeSpans{Spans: []*tracepb.Span{span}}
+	for _, in := range rss.GetScopeSpans() {
+		if slices.Contains(in.GetSpans(), span) {
+			ss.Scope = in.GetScope()
+			ss.SchemaUrl = in.GetSchemaUrl()
+			break
+		}
+	}
+
+	return &tracepb.ResourceSpans{
+		Resource:   rss.GetResource(),
+		ScopeSpans: []*tracepb.ScopeSpans{ss},
+		SchemaUrl:  rss.GetSchemaUrl(),
+	}
+}
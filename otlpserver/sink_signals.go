@@ -0,0 +1,60 @@
+package otlpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// the files SignalJsonSink appends to in each signal's directory
+const (
+	LogsJsonFilename    = "logs.jsonl"
+	MetricsJsonFilename = "metrics.jsonl"
+)
+
+// SignalJsonSink writes the logs and metrics export requests a server
+// receives as newline-delimited OTLP/JSON, the same way OtlpJsonSink writes
+// traces, so otel-cli server can capture all three signals.
+type SignalJsonSink struct {
+	writers map[string]*otlpJsonWriter
+}
+
+// NewSignalJsonSink returns a SignalJsonSink. Requests for a signal are
+// appended to logs.jsonl or metrics.jsonl in its directory in dirs, if it has
+// one. When out is not nil, requests of every signal are written to it as
+// lines tagged with the signal, e.g. {"logs":{...}}. Use a SyncWriter when
+// out is shared with other sinks.
+func NewSignalJsonSink(dirs map[string]string, out io.Writer) (*SignalJsonSink, error) {
+	sjs := SignalJsonSink{writers: map[string]*otlpJsonWriter{}}
+	for signal, filename := range map[string]string{
+		LogsSignal:    LogsJsonFilename,
+		MetricsSignal: MetricsJsonFilename,
+	} {
+		w, err := newOtlpJsonWriter(dirs[signal], filename, out, signal)
+		if err != nil {
+			sjs.Close()
+			return nil, err
+		}
+		sjs.writers[signal] = w
+	}
+	return &sjs, nil
+}
+
+// Consume writes the request to its signal's file and out. It's a
+// SignalCallback.
+func (sjs *SignalJsonSink) Consume(ctx context.Context, signal string, req proto.Message, headers map[string]string, meta map[string]string) {
+	if w, ok := sjs.writers[signal]; ok {
+		w.write(req)
+	}
+}
+
+// Close closes the files.
+func (sjs *SignalJsonSink) Close() error {
+	var errs []error
+	for _, w := range sjs.writers {
+		errs = append(errs, w.close())
+	}
+	return errors.Join(errs...)
+}
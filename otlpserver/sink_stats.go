@@ -0,0 +1,151 @@
+package otlpserver
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// slowestSpanCount is how many of the slowest spans a StatsSnapshot lists.
+const slowestSpanCount = 5
+
+// StatsSink keeps aggregate stats about spans received over a sliding window,
+// e.g. for a live dashboard in the tui. It doesn't pass spans anywhere, stack
+// it with other sinks using MultiSink.
+type StatsSink struct {
+	window  time.Duration
+	started time.Time
+	spans   []spanStat       // in the order received, oldest first
+	now     func() time.Time // swappable for tests
+	mu      sync.Mutex
+}
+
+// spanStat is what StatsSink keeps about each span in the window.
+type spanStat struct {
+	received time.Time
+	name     string
+	traceId  string
+	spanId   string
+	duration time.Duration
+	isError  bool
+}
+
+// StatsSnapshot is the aggregate stats for the spans in the window.
+type StatsSnapshot struct {
+	Window      time.Duration
+	Spans       int
+	SpansPerSec float64
+	Errors      int
+	ErrorRate   float64 // 0.0 - 1.0
+	Slowest     []SlowSpan
+}
+
+// SlowSpan is one entry in StatsSnapshot's list of slowest spans.
+type SlowSpan struct {
+	Name     string
+	TraceId  string
+	SpanId   string
+	Duration time.Duration
+}
+
+// NewStatsSink returns a StatsSink that aggregates over the window.
+func NewStatsSink(window time.Duration) *StatsSink {
+	return &StatsSink{
+		window:  window,
+		started: time.Now(),
+		spans:   []spanStat{},
+		now:     time.Now,
+	}
+}
+
+// Consume records the span and always returns false.
+func (ss *StatsSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	var duration time.Duration
+	if span.EndTimeUnixNano > span.StartTimeUnixNano {
+		duration = time.Duration(span.EndTimeUnixNano - span.StartTimeUnixNano)
+	}
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	now := ss.now()
+	ss.expire(now)
+	ss.spans = append(ss.spans, spanStat{
+		received: now,
+		name:     span.Name,
+		traceId:  hex.EncodeToString(span.TraceId),
+		spanId:   hex.EncodeToString(span.SpanId),
+		duration: duration,
+		isError:  span.GetStatus().GetCode() == tracepb.Status_STATUS_CODE_ERROR,
+	})
+
+	return false
+}
+
+// Close fulfills the interface and does nothing.
+func (ss *StatsSink) Close() error {
+	return nil
+}
+
+// Snapshot returns the stats for the spans received within the window. Until
+// the server has been up for a whole window, rates are over the time since it
+// started so they aren't understated.
+func (ss *StatsSink) Snapshot() StatsSnapshot {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	now := ss.now()
+	ss.expire(now)
+
+	snap := StatsSnapshot{
+		Window:  ss.window,
+		Spans:   len(ss.spans),
+		Slowest: []SlowSpan{},
+	}
+
+	elapsed := now.Sub(ss.started)
+	if elapsed > ss.window {
+		elapsed = ss.window
+	}
+	if elapsed > 0 {
+		snap.SpansPerSec = float64(snap.Spans) / elapsed.Seconds()
+	}
+
+	for _, s := range ss.spans {
+		if s.isError {
+			snap.Errors++
+		}
+	}
+	if snap.Spans > 0 {
+		snap.ErrorRate = float64(snap.Errors) / float64(snap.Spans)
+	}
+
+	sorted := make([]spanStat, len(ss.spans))
+	copy(sorted, ss.spans)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].duration > sorted[j].duration })
+	for i := 0; i < len(sorted) && i < slowestSpanCount; i++ {
+		snap.Slowest = append(snap.Slowest, SlowSpan{
+			Name:     sorted[i].name,
+			TraceId:  sorted[i].traceId,
+			SpanId:   sorted[i].spanId,
+			Duration: sorted[i].duration,
+		})
+	}
+
+	return snap
+}
+
+// expire drops spans that were received before the window. Must be called
+// with the lock held.
+func (ss *StatsSink) expire(now time.Time) {
+	cutoff := now.Add(-ss.window)
+	i := sort.Search(len(ss.spans), func(i int) bool { return ss.spans[i].received.After(cutoff) })
+	if i > 0 {
+		// copy down rather than reslice so the old entries can be collected
+		ss.spans = append(ss.spans[:0], ss.spans[i:]...)
+	}
+}
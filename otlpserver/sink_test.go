@@ -0,0 +1,589 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestMultiSink(t *testing.T) {
+	var calls int
+	counter := func(done bool) SpanSink {
+		return CallbackSink(func(context.Context, *tracepb.Span, []*tracepb.Span_Event, *tracepb.ResourceSpans, map[string]string, map[string]string) bool {
+			calls++
+			return done
+		})
+	}
+
+	ms := NewMultiSink(counter(false), nil, counter(true), counter(false))
+	if len(ms) != 3 {
+		t.Errorf("expected nil sinks to be dropped, got %d sinks", len(ms))
+	}
+
+	if !ms.Consume(context.Background(), &tracepb.Span{}, nil, nil, nil, nil) {
+		t.Error("expected MultiSink to be done when any sink is done")
+	}
+
+	if calls != 3 {
+		t.Errorf("expected every sink to be called once, got %d calls", calls)
+	}
+
+	if err := ms.Close(); err != nil {
+		t.Errorf("unexpected error from Close: %s", err)
+	}
+}
+
+func TestDedupeSink(t *testing.T) {
+	var calls int
+	counter := CallbackSink(func(context.Context, *tracepb.Span, []*tracepb.Span_Event, *tracepb.ResourceSpans, map[string]string, map[string]string) bool {
+		calls++
+		return false
+	})
+
+	now := time.Unix(1700000000, 0)
+	ds := NewDedupeSink(counter, time.Minute)
+	ds.now = func() time.Time { return now }
+
+	span := func(tid, sid byte) *tracepb.Span {
+		return &tracepb.Span{TraceId: []byte{tid}, SpanId: []byte{sid}}
+	}
+	consume := func(s *tracepb.Span) {
+		ds.Consume(context.Background(), s, nil, nil, nil, nil)
+	}
+
+	consume(span(1, 1))
+	consume(span(1, 1)) // retried export
+	consume(span(1, 2)) // same trace, different span
+	consume(span(2, 1)) // different trace, same span id
+	if calls != 3 || ds.Duplicates() != 1 {
+		t.Errorf("expected 3 spans passed and 1 duplicate, got %d and %d", calls, ds.Duplicates())
+	}
+
+	// once the window passes the same ids are let through again
+	now = now.Add(2 * time.Minute)
+	consume(span(1, 1))
+	if calls != 4 || ds.Duplicates() != 1 {
+		t.Errorf("expected span to pass after the window, got %d calls and %d duplicates", calls, ds.Duplicates())
+	}
+}
+
+func TestStatsSink(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	ss := NewStatsSink(time.Minute)
+	ss.started = now.Add(-time.Hour)
+	ss.now = func() time.Time { return now }
+
+	span := func(name string, ms uint64, isError bool) *tracepb.Span {
+		s := &tracepb.Span{Name: name, StartTimeUnixNano: 1000, EndTimeUnixNano: 1000 + ms*1000000}
+		if isError {
+			s.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR}
+		}
+		return s
+	}
+	consume := func(s *tracepb.Span) {
+		if ss.Consume(context.Background(), s, nil, nil, nil, nil) {
+			t.Error("StatsSink should never report done")
+		}
+	}
+
+	consume(span("old", 9000, true))
+	now = now.Add(2 * time.Minute) // "old" falls out of the window
+	for i, ms := range []uint64{10, 50, 20, 70, 30, 60} {
+		consume(span(string(rune('a'+i)), ms, ms > 50))
+	}
+
+	snap := ss.Snapshot()
+	if snap.Spans != 6 || snap.Errors != 2 {
+		t.Errorf("expected 6 spans and 2 errors, got %d and %d", snap.Spans, snap.Errors)
+	}
+	if snap.SpansPerSec != 0.1 {
+		t.Errorf("expected 0.1 spans/sec, got %f", snap.SpansPerSec)
+	}
+	if snap.ErrorRate < 0.33 || snap.ErrorRate > 0.34 {
+		t.Errorf("expected 1/3 error rate, got %f", snap.ErrorRate)
+	}
+
+	names := ""
+	for _, s := range snap.Slowest {
+		names += s.Name
+	}
+	if names != "dfbec" {
+		t.Errorf("expected slowest spans dfbec, got %q", names)
+	}
+	if snap.Slowest[0].Duration != 70*time.Millisecond {
+		t.Errorf("expected slowest span to take 70ms, got %s", snap.Slowest[0].Duration)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if snap := ss.Snapshot(); snap.Spans != 0 || snap.SpansPerSec != 0 || len(snap.Slowest) != 0 {
+		t.Errorf("expected empty stats after the window, got %+v", snap)
+	}
+}
+
+func TestFilterSink(t *testing.T) {
+	var names string
+	recorder := CallbackSink(func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+		names += span.Name
+		return false
+	})
+
+	str := func(key, value string) *commonpb.KeyValue {
+		return &commonpb.KeyValue{Key: key, Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}}}
+	}
+	web := &tracepb.ResourceSpans{Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		str("service.name", "web"),
+		str("deployment.environment", "prod"),
+	}}}
+	worker := &tracepb.ResourceSpans{Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		str("service.name", "worker"),
+	}}}
+	spans := []struct {
+		span *tracepb.Span
+		rss  *tracepb.ResourceSpans
+	}{
+		{&tracepb.Span{Name: "a", Attributes: []*commonpb.KeyValue{str("http.route", "/login")}}, web},
+		{&tracepb.Span{Name: "b", Attributes: []*commonpb.KeyValue{{Key: "retries", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 3}}}}}, web},
+		{&tracepb.Span{Name: "c", Attributes: []*commonpb.KeyValue{str("http.route", "/login")}}, worker},
+		{&tracepb.Span{Name: "d"}, &tracepb.ResourceSpans{}},
+	}
+
+	for _, tc := range []struct {
+		filter SpanFilter
+		want   string
+	}{
+		{SpanFilter{}, "abcd"},
+		{SpanFilter{Services: []string{"web"}}, "ab"},
+		{SpanFilter{Services: []string{"worker", "unknown_service"}}, "cd"},
+		{SpanFilter{Name: regexp.MustCompile("^[bc]$")}, "bc"},
+		{SpanFilter{Attrs: map[string]string{"http.route": "/login"}}, "ac"},
+		{SpanFilter{Attrs: map[string]string{"retries": "3"}}, "b"},
+		// resource attributes match too
+		{SpanFilter{Attrs: map[string]string{"deployment.environment": "prod"}}, "ab"},
+		{SpanFilter{Attrs: map[string]string{"http.route": "/login", "deployment.environment": "prod"}}, "a"},
+		{SpanFilter{Services: []string{"worker"}, Name: regexp.MustCompile("a")}, ""},
+	} {
+		names = ""
+		fs := NewFilterSink(recorder, tc.filter)
+		for _, s := range spans {
+			if fs.Consume(context.Background(), s.span, nil, s.rss, nil, nil) {
+				t.Error("FilterSink should pass on the wrapped sink's result")
+			}
+		}
+		if names != tc.want {
+			t.Errorf("expected filter %+v to pass %q, got %q", tc.filter, tc.want, names)
+		}
+		if tc.filter.IsEmpty() != (tc.want == "abcd") {
+			t.Errorf("expected IsEmpty to be %t for %+v", tc.want == "abcd", tc.filter)
+		}
+	}
+}
+
+func TestOtlpJsonSink(t *testing.T) {
+	rss := &tracepb.ResourceSpans{
+		Resource: &resourcepb.Resource{DroppedAttributesCount: 2},
+		ScopeSpans: []*tracepb.ScopeSpans{
+			{Scope: &commonpb.InstrumentationScope{Name: "empty"}},
+			{Spans: []*tracepb.Span{
+				{TraceId: []byte{1}, SpanId: []byte{1}, Name: "a", DroppedLinksCount: 3},
+				{TraceId: []byte{1}, SpanId: []byte{2}, Name: "b", Links: []*tracepb.Span_Link{{TraceId: []byte{2}, SpanId: []byte{3}}}},
+			}},
+			{Spans: []*tracepb.Span{{TraceId: []byte{1}, SpanId: []byte{4}, Name: "c"}}},
+		},
+	}
+
+	dir := t.TempDir()
+	var out bytes.Buffer
+	ojs, err := NewOtlpJsonSink(dir, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// the server calls sinks for each span, each is written with its scope
+	for _, ss := range rss.ScopeSpans {
+		for _, span := range ss.Spans {
+			if ojs.Consume(context.Background(), span, nil, rss, nil, nil) {
+				t.Error("OtlpJsonSink should never report done")
+			}
+		}
+	}
+	if err := ojs.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %s", err)
+	}
+
+	written, err := os.ReadFile(filepath.Join(dir, OtlpJsonFilename))
+	if err != nil {
+		t.Fatalf("failed to read the file: %s", err)
+	}
+	if !bytes.Equal(written, out.Bytes()) {
+		t.Errorf("expected the file and stdout to match, got %q and %q", written, out.String())
+	}
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", len(lines), out.String())
+	}
+	var req coltracepb.ExportTraceServiceRequest
+	if err := otlpclient.UnmarshalOtlpJson([]byte(lines[1]), &req); err != nil {
+		t.Fatalf("failed to unmarshal the OTLP/JSON: %s", err)
+	}
+	want := &tracepb.ResourceSpans{
+		Resource:   rss.Resource,
+		ScopeSpans: []*tracepb.ScopeSpans{{Spans: []*tracepb.Span{rss.ScopeSpans[1].Spans[1]}}},
+	}
+	if len(req.ResourceSpans) != 1 || !proto.Equal(want, req.ResourceSpans[0]) {
+		t.Errorf("expected the span to be written unchanged, got %v", req.ResourceSpans)
+	}
+}
+
+func TestSignalJsonSink(t *testing.T) {
+	logsDir := t.TempDir()
+	var out bytes.Buffer
+	sjs, err := NewSignalJsonSink(map[string]string{LogsSignal: logsDir}, &out)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	logsReq := &collogspb.ExportLogsServiceRequest{}
+	metricsReq := &colmetricspb.ExportMetricsServiceRequest{}
+	sjs.Consume(context.Background(), LogsSignal, logsReq, nil, nil)
+	sjs.Consume(context.Background(), MetricsSignal, metricsReq, nil, nil)
+	if err := sjs.Close(); err != nil {
+		t.Fatalf("unexpected error from Close: %s", err)
+	}
+
+	// only logs have a directory, both go to out
+	written, err := os.ReadFile(filepath.Join(logsDir, LogsJsonFilename))
+	if err != nil {
+		t.Fatalf("failed to read the logs file: %s", err)
+	}
+	if string(written) != "{}\n" {
+		t.Errorf("expected one logs request in the file, got %q", written)
+	}
+	if out.String() != "{\"logs\":{}}\n{\"metrics\":{}}\n" {
+		t.Errorf("expected both requests tagged with their signal in the output, got %q", out.String())
+	}
+}
+
+func TestMetricsSink(t *testing.T) {
+	ms := NewMetricsSink()
+	ms.started = time.Unix(1700000000, 0)
+
+	resource := func(service string) *tracepb.ResourceSpans {
+		attrs := []*commonpb.KeyValue{}
+		if service != "" {
+			attrs = append(attrs, &commonpb.KeyValue{
+				Key:   "service.name",
+				Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: service}},
+			})
+		}
+		return &tracepb.ResourceSpans{Resource: &resourcepb.Resource{Attributes: attrs}}
+	}
+	ok := &tracepb.Span{Name: "ok"}
+	failed := &tracepb.Span{Name: "failed", Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR}}
+
+	for _, s := range []struct {
+		span    *tracepb.Span
+		service string
+	}{{ok, "web"}, {failed, "web"}, {ok, `say "hi"`}, {ok, ""}} {
+		if ms.Consume(context.Background(), s.span, nil, resource(s.service), nil, nil) {
+			t.Error("MetricsSink should never report done")
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := ms.WriteTo(&buf); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	out := buf.String()
+
+	okSize, failedSize := proto.Size(ok), proto.Size(failed)
+	for _, want := range []string{
+		"# TYPE otelcli_server_spans_received_total counter\n",
+		`otelcli_server_spans_received_total{service="say \"hi\""} 1` + "\n",
+		`otelcli_server_spans_received_total{service="unknown_service"} 1` + "\n",
+		`otelcli_server_spans_received_total{service="web"} 2` + "\n",
+		fmt.Sprintf(`otelcli_server_span_bytes_received_total{service="web"} %d`+"\n", okSize+failedSize),
+		`otelcli_server_error_spans_total{service="web"} 1` + "\n",
+		`otelcli_server_error_spans_total{service="unknown_service"} 0` + "\n",
+		"otelcli_server_start_time_seconds 1700000000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestBufferSink(t *testing.T) {
+	span := func(id byte, name string) *tracepb.Span {
+		return &tracepb.Span{TraceId: []byte{id % 2}, SpanId: []byte{id}, Name: name}
+	}
+	names := func(bs *BufferSink) string {
+		out := ""
+		for _, s := range bs.Spans() {
+			out += s.Name
+		}
+		return out
+	}
+
+	// count limit evicts the oldest spans first
+	evicted := ""
+	bs := NewBufferSink(3, 0)
+	bs.OnEvict(func(bspan BufferedSpan) { evicted += bspan.Span.Name })
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		if bs.Consume(context.Background(), span(byte(i), name), nil, nil, nil, nil) {
+			t.Error("BufferSink should never report done")
+		}
+	}
+	if names(bs) != "cde" || evicted != "ab" || bs.Evicted() != 2 {
+		t.Errorf("expected cde kept and ab evicted, got %q kept, %q evicted, count %d", names(bs), evicted, bs.Evicted())
+	}
+
+	// byte limit, each of these spans is the same size
+	size := proto.Size(span(0, "x"))
+	bs = NewBufferSink(0, size*2)
+	for i, name := range []string{"a", "b", "c"} {
+		bs.Add(span(byte(i), name), nil)
+	}
+	if names(bs) != "bc" || bs.Bytes() != size*2 || bs.Evicted() != 1 {
+		t.Errorf("expected bc kept in %d bytes, got %q in %d bytes with %d evicted", size*2, names(bs), bs.Bytes(), bs.Evicted())
+	}
+
+	// events count toward the size, and a span bigger than the limit is kept
+	// until the next one arrives
+	big := span(3, "d")
+	events := []*tracepb.Span_Event{{Name: strings.Repeat("x", size*4)}}
+	bs.Add(big, events)
+	if names(bs) != "d" || bs.Len() != 1 {
+		t.Errorf("expected only the oversized span kept, got %q", names(bs))
+	}
+	bs.Add(span(4, "e"), nil)
+	if names(bs) != "e" || bs.Evicted() != 4 {
+		t.Errorf("expected the oversized span evicted, got %q with %d evicted", names(bs), bs.Evicted())
+	}
+
+	// no limits keeps everything
+	bs = NewBufferSink(0, 0)
+	for i := 0; i < 100; i++ {
+		bs.Add(span(byte(i), "x"), nil)
+	}
+	if bs.Len() != 100 || bs.Evicted() != 0 {
+		t.Errorf("expected all 100 spans kept without limits, got %d", bs.Len())
+	}
+}
+
+func TestJsonSinkMeta(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	span := &tracepb.Span{Name: "meta", TraceId: make([]byte, 16), SpanId: make([]byte, 8)}
+	meta := map[string]string{"proto": "grpc", "tls-version": "TLS 1.3"}
+	NewJsonSink(dir, &out).Consume(context.Background(), span, nil, nil, nil, meta)
+
+	data, err := os.ReadFile(filepath.Join(dir, hex.EncodeToString(span.TraceId), hex.EncodeToString(span.SpanId), "meta.json"))
+	if err != nil {
+		t.Fatalf("expected meta.json next to span.json: %s", err)
+	}
+	got := map[string]string{}
+	if err := json.Unmarshal(data, &got); err != nil || got["tls-version"] != "TLS 1.3" {
+		t.Errorf("expected meta.json to have the server meta, got %q", string(data))
+	}
+	if strings.Contains(out.String(), "tls-version") {
+		t.Error("expected the server meta to stay out of the output")
+	}
+}
+
+func TestNdjsonSinkRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.ndjson")
+	span := &tracepb.Span{Name: "rotate me"}
+	line, _ := json.Marshal(span)
+	lineLen := int64(len(line) + 1)
+
+	// room for two lines per file, keeping two rotated files
+	rf, err := OpenRotatingFile(path, lineLen*2, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sink := NewNdjsonSink(rf)
+	for i := 0; i < 7; i++ {
+		sink.Consume(context.Background(), span, nil, nil, nil, nil)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("unexpected error from Close: %s", err)
+	}
+
+	// 7 lines: path.3 would have held the first two, path.2 and path.1 two
+	// each, and the current file the last one
+	for file, lines := range map[string]int{path: 1, path + ".1": 2, path + ".2": 2} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %s", file, err)
+		}
+		if got := strings.Count(string(data), "\n"); got != lines {
+			t.Errorf("expected %d lines in %s but got %d", lines, file, got)
+		}
+		if !strings.HasPrefix(string(data), string(line)+"\n") {
+			t.Errorf("expected %s to start with a whole span but got %q", file, string(data))
+		}
+	}
+	if _, err := os.Stat(path + ".3"); err == nil {
+		t.Error("expected only 2 rotated files to be kept")
+	}
+
+	// reopening appends instead of truncating
+	rf, err = OpenRotatingFile(path, 0, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rf.Write([]byte("more\n"))
+	rf.Close()
+	if data, _ := os.ReadFile(path); string(data) != string(line)+"\nmore\n" {
+		t.Errorf("expected reopened file to be appended to but got %q", string(data))
+	}
+}
+
+func TestTraceFormatSink(t *testing.T) {
+	if _, err := NewTraceFormatSink("otlp", "", nil); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+
+	rss := &tracepb.ResourceSpans{Resource: &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "deployer"}}},
+	}}}
+	parent := &tracepb.Span{
+		TraceId:           []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanId:            []byte{1, 1, 1, 1, 1, 1, 1, 1},
+		Name:              "deploy",
+		Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: 1700000000000000000,
+		EndTimeUnixNano:   1700000002500000000,
+	}
+	child := &tracepb.Span{
+		TraceId:           parent.TraceId,
+		SpanId:            []byte{2, 2, 2, 2, 2, 2, 2, 2},
+		ParentSpanId:      parent.SpanId,
+		Name:              "upload",
+		Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+		StartTimeUnixNano: 1700000001000000000,
+		EndTimeUnixNano:   1700000001000500000,
+		Attributes: []*commonpb.KeyValue{
+			{Key: "retries", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 2}}},
+		},
+		Events: []*tracepb.Span_Event{{Name: "retrying", TimeUnixNano: 1700000001000100000}},
+		Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timed out"},
+	}
+
+	// jaeger files are rewritten with the whole trace as spans arrive
+	dir := t.TempDir()
+	jaeger, err := NewTraceFormatSink("jaeger", dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jaeger.Consume(context.Background(), parent, nil, rss, nil, nil)
+	jaeger.Consume(context.Background(), child, child.Events, rss, nil, nil)
+	jaeger.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "0102030405060708090a0b0c0d0e0f10.json"))
+	if err != nil {
+		t.Fatalf("expected a file for the trace: %s", err)
+	}
+	var doc jaegerDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("jaeger output is not json: %s", err)
+	}
+	if len(doc.Data) != 1 || len(doc.Data[0].Spans) != 2 {
+		t.Fatalf("expected one trace with both spans, got %s", data)
+	}
+	if doc.Data[0].Processes["p1"].ServiceName != "deployer" || len(doc.Data[0].Processes) != 1 {
+		t.Errorf("expected one process for the deployer service, got %v", doc.Data[0].Processes)
+	}
+	js := doc.Data[0].Spans[1]
+	if js.StartTime != 1700000001000000 || js.Duration != 500 {
+		t.Errorf("expected times in microseconds, got start %d duration %d", js.StartTime, js.Duration)
+	}
+	if len(js.References) != 1 || js.References[0].RefType != "CHILD_OF" || js.References[0].SpanID != "0101010101010101" {
+		t.Errorf("expected a CHILD_OF reference to the parent, got %v", js.References)
+	}
+	tags := map[string]interface{}{}
+	for _, tag := range js.Tags {
+		tags[tag.Key] = tag.Value
+	}
+	if tags["retries"] != float64(2) || tags["span.kind"] != "client" || tags["error"] != true || tags["otel.status_description"] != "timed out" {
+		t.Errorf("unexpected jaeger tags %v", tags)
+	}
+	if len(js.Logs) != 1 || js.Logs[0].Fields[0].Value != "retrying" {
+		t.Errorf("expected the event as a log, got %v", js.Logs)
+	}
+
+	// zipkin goes to the writer as one array when the sink closes
+	var out bytes.Buffer
+	zipkin, err := NewTraceFormatSink("zipkin", "", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zipkin.Consume(context.Background(), parent, nil, rss, nil, nil)
+	zipkin.Consume(context.Background(), child, child.Events, rss, nil, nil)
+	if out.Len() != 0 {
+		t.Errorf("expected nothing written before Close, got %q", out.String())
+	}
+	zipkin.Close()
+
+	var spans []zipkinSpan
+	if err := json.Unmarshal(out.Bytes(), &spans); err != nil {
+		t.Fatalf("zipkin output is not a json array: %s", err)
+	}
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %s", out.String())
+	}
+	if spans[0].Kind != "" || spans[0].ParentID != "" {
+		t.Errorf("expected the internal root span without kind or parent, got %+v", spans[0])
+	}
+	zs := spans[1]
+	if zs.Kind != "CLIENT" || zs.ParentID != "0101010101010101" || zs.LocalEndpoint.ServiceName != "deployer" {
+		t.Errorf("unexpected zipkin span %+v", zs)
+	}
+	if zs.Tags["retries"] != "2" || zs.Tags["error"] != "timed out" || zs.Tags["otel.status_code"] != "ERROR" {
+		t.Errorf("unexpected zipkin tags %v", zs.Tags)
+	}
+	if len(zs.Annotations) != 1 || zs.Annotations[0].Value != "retrying" || zs.Annotations[0].Timestamp != 1700000001000100 {
+		t.Errorf("expected the event as an annotation, got %v", zs.Annotations)
+	}
+
+	// a bounded sink drops evicted spans, and whole traces once they're empty
+	out.Reset()
+	other := &tracepb.Span{TraceId: []byte{9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9, 9}, SpanId: []byte{3, 3, 3, 3, 3, 3, 3, 3}, Name: "other"}
+	bounded, err := NewTraceFormatSink("zipkin", "", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buffer := NewBufferSink(2, 0)
+	bounded.SetBuffer(buffer)
+	bounded.Consume(context.Background(), parent, nil, rss, nil, nil)
+	bounded.Consume(context.Background(), child, child.Events, rss, nil, nil)
+	bounded.Consume(context.Background(), other, nil, rss, nil, nil)
+	if len(bounded.order) != 2 || len(bounded.traces[hex.EncodeToString(parent.TraceId)]) != 1 {
+		t.Errorf("expected the parent evicted from its trace, got %v", bounded.order)
+	}
+	bounded.Consume(context.Background(), other, nil, rss, nil, nil)
+	if len(bounded.order) != 1 || buffer.Evicted() != 2 {
+		t.Errorf("expected the first trace dropped once empty, got %v", bounded.order)
+	}
+	bounded.Close()
+	spans = nil
+	if err := json.Unmarshal(out.Bytes(), &spans); err != nil || len(spans) != 2 {
+		t.Errorf("expected only the 2 buffered spans written, got %s", out.String())
+	}
+}
@@ -0,0 +1,421 @@
+package otlpserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/equinix-labs/otel-cli/otlpclient"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// TraceFormats are the formats TraceFormatSink can write.
+var TraceFormats = []string{"jaeger", "zipkin"}
+
+// TraceFormatSink collects spans by trace and writes them out as Jaeger UI
+// JSON, the format the Jaeger UI can upload, or Zipkin v2 JSON. Unlike
+// JsonSink these formats hold whole traces, so spans are kept in memory.
+type TraceFormatSink struct {
+	format string
+	dir    string
+	out    io.Writer
+	traces map[string][]formatSpan
+	order  []string    // trace ids in the order they were first seen
+	buffer *BufferSink // optional, bounds the spans held in memory
+	mu     sync.Mutex
+}
+
+// formatSpan is a received span along with the resource it came with.
+type formatSpan struct {
+	span     *tracepb.Span
+	resource map[string]*commonpb.AnyValue
+}
+
+// NewTraceFormatSink returns a TraceFormatSink for format, one of
+// TraceFormats. When dir is not empty, each trace is written to
+// dir/traceid.json and rewritten as spans arrive, so the file is always
+// complete enough to load. When out is not nil, every trace is written to it
+// as one document when the sink is closed.
+func NewTraceFormatSink(format, dir string, out io.Writer) (*TraceFormatSink, error) {
+	if format != "jaeger" && format != "zipkin" {
+		return nil, fmt.Errorf("unsupported trace format %q, must be one of %s", format, strings.Join(TraceFormats, ", "))
+	}
+
+	return &TraceFormatSink{
+		format: format,
+		dir:    dir,
+		out:    out,
+		traces: make(map[string][]formatSpan),
+	}, nil
+}
+
+// Consume adds the span to its trace and rewrites the trace's file. Always
+// returns false.
+func (tfs *TraceFormatSink) Consume(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+
+	tid := hex.EncodeToString(span.TraceId)
+	if _, ok := tfs.traces[tid]; !ok {
+		tfs.order = append(tfs.order, tid)
+	}
+
+	resource := make(map[string]*commonpb.AnyValue)
+	for _, attr := range rss.GetResource().GetAttributes() {
+		resource[attr.Key] = attr.Value
+	}
+	tfs.traces[tid] = append(tfs.traces[tid], formatSpan{span: span, resource: resource})
+	if tfs.buffer != nil {
+		tfs.buffer.Add(span, events)
+	}
+
+	if tfs.dir != "" {
+		data, err := tfs.marshal([]string{tid})
+		if err != nil {
+			log.Fatalf("failed to marshal trace to %s json: %s", tfs.format, err)
+		}
+		tracefile := filepath.Join(tfs.dir, tid+".json")
+		if err := os.WriteFile(tracefile, data, 0644); err != nil {
+			log.Fatalf("could not write to file %q: %s", tracefile, err)
+		}
+	}
+
+	return false
+}
+
+// SetBuffer bounds the spans held in memory by the buffer's limits. Spans it
+// evicts are dropped from their traces, though trace files already written
+// to dir keep them.
+func (tfs *TraceFormatSink) SetBuffer(buffer *BufferSink) {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	tfs.buffer = buffer
+	buffer.OnEvict(tfs.evict)
+}
+
+// evict removes an evicted span from its trace, and the trace once it's
+// empty. Called by the buffer from inside Consume, with tfs.mu held.
+func (tfs *TraceFormatSink) evict(bspan BufferedSpan) {
+	tid := hex.EncodeToString(bspan.Span.TraceId)
+	spans := slices.DeleteFunc(tfs.traces[tid], func(fs formatSpan) bool {
+		return fs.span == bspan.Span
+	})
+
+	if len(spans) > 0 {
+		tfs.traces[tid] = spans
+		return
+	}
+
+	delete(tfs.traces, tid)
+	if i := slices.Index(tfs.order, tid); i >= 0 {
+		tfs.order = slices.Delete(tfs.order, i, i+1)
+	}
+}
+
+// Close writes every trace to the output writer, if there is one.
+func (tfs *TraceFormatSink) Close() error {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+
+	if tfs.out == nil || len(tfs.order) == 0 {
+		return nil
+	}
+
+	data, err := tfs.marshal(tfs.order)
+	if err != nil {
+		return err
+	}
+	if _, err := tfs.out.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// marshal renders the given traces as one document in the sink's format.
+func (tfs *TraceFormatSink) marshal(tids []string) ([]byte, error) {
+	if tfs.format == "zipkin" {
+		spans := []zipkinSpan{}
+		for _, tid := range tids {
+			for _, fs := range tfs.traces[tid] {
+				spans = append(spans, toZipkinSpan(fs))
+			}
+		}
+		return json.Marshal(spans)
+	}
+
+	doc := jaegerDocument{Data: []jaegerTrace{}}
+	for _, tid := range tids {
+		doc.Data = append(doc.Data, toJaegerTrace(tid, tfs.traces[tid]))
+	}
+	return json.Marshal(doc)
+}
+
+// jaegerDocument is the top level of the JSON the Jaeger UI loads, the same
+// shape as its query API responses.
+type jaegerDocument struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     uint64            `json:"startTime"` // microseconds
+	Duration      uint64            `json:"duration"`  // microseconds
+	Tags          []jaegerTag       `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerTag struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type jaegerLog struct {
+	Timestamp uint64      `json:"timestamp"`
+	Fields    []jaegerTag `json:"fields"`
+}
+
+type jaegerProcess struct {
+	ServiceName string      `json:"serviceName"`
+	Tags        []jaegerTag `json:"tags"`
+}
+
+// toJaegerTrace converts a trace's spans, giving each distinct resource its
+// own process.
+func toJaegerTrace(tid string, spans []formatSpan) jaegerTrace {
+	jt := jaegerTrace{TraceID: tid, Spans: []jaegerSpan{}, Processes: map[string]jaegerProcess{}}
+	processIds := map[string]string{} // process json -> id
+
+	for _, fs := range spans {
+		process := jaegerProcess{ServiceName: serviceName(fs.resource), Tags: []jaegerTag{}}
+		for _, key := range sortedKeys(fs.resource) {
+			if key != "service.name" {
+				process.Tags = append(process.Tags, toJaegerTag(key, fs.resource[key]))
+			}
+		}
+		pjs, _ := json.Marshal(process)
+		pid, ok := processIds[string(pjs)]
+		if !ok {
+			pid = "p" + strconv.Itoa(len(processIds)+1)
+			processIds[string(pjs)] = pid
+			jt.Processes[pid] = process
+		}
+
+		jt.Spans = append(jt.Spans, toJaegerSpan(fs.span, pid))
+	}
+
+	return jt
+}
+
+func toJaegerSpan(span *tracepb.Span, pid string) jaegerSpan {
+	tid := hex.EncodeToString(span.TraceId)
+	js := jaegerSpan{
+		TraceID:       tid,
+		SpanID:        hex.EncodeToString(span.SpanId),
+		OperationName: span.Name,
+		References:    []jaegerReference{},
+		StartTime:     span.StartTimeUnixNano / 1000,
+		Duration:      spanDurationMicros(span),
+		Tags:          []jaegerTag{},
+		Logs:          []jaegerLog{},
+		ProcessID:     pid,
+	}
+
+	if len(span.ParentSpanId) > 0 {
+		js.References = append(js.References, jaegerReference{
+			RefType: "CHILD_OF",
+			TraceID: tid,
+			SpanID:  hex.EncodeToString(span.ParentSpanId),
+		})
+	}
+	for _, link := range span.Links {
+		js.References = append(js.References, jaegerReference{
+			RefType: "FOLLOWS_FROM",
+			TraceID: hex.EncodeToString(link.TraceId),
+			SpanID:  hex.EncodeToString(link.SpanId),
+		})
+	}
+
+	for _, attr := range span.Attributes {
+		js.Tags = append(js.Tags, toJaegerTag(attr.Key, attr.Value))
+	}
+	if kind := spanKindName(span.Kind); kind != "" {
+		js.Tags = append(js.Tags, jaegerTag{Key: "span.kind", Type: "string", Value: strings.ToLower(kind)})
+	}
+	if code := statusCodeName(span.Status); code != "" {
+		js.Tags = append(js.Tags, jaegerTag{Key: "otel.status_code", Type: "string", Value: code})
+	}
+	if span.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		js.Tags = append(js.Tags, jaegerTag{Key: "error", Type: "bool", Value: true})
+		if msg := span.Status.GetMessage(); msg != "" {
+			js.Tags = append(js.Tags, jaegerTag{Key: "otel.status_description", Type: "string", Value: msg})
+		}
+	}
+
+	for _, event := range span.Events {
+		jl := jaegerLog{
+			Timestamp: event.TimeUnixNano / 1000,
+			Fields:    []jaegerTag{{Key: "event", Type: "string", Value: event.Name}},
+		}
+		for _, attr := range event.Attributes {
+			jl.Fields = append(jl.Fields, toJaegerTag(attr.Key, attr.Value))
+		}
+		js.Logs = append(js.Logs, jl)
+	}
+
+	return js
+}
+
+// toJaegerTag converts an attribute, keeping its type where Jaeger has one.
+func toJaegerTag(key string, v *commonpb.AnyValue) jaegerTag {
+	switch v.GetValue().(type) {
+	case *commonpb.AnyValue_BoolValue:
+		return jaegerTag{Key: key, Type: "bool", Value: v.GetBoolValue()}
+	case *commonpb.AnyValue_IntValue:
+		return jaegerTag{Key: key, Type: "int64", Value: v.GetIntValue()}
+	case *commonpb.AnyValue_DoubleValue:
+		return jaegerTag{Key: key, Type: "float64", Value: v.GetDoubleValue()}
+	case *commonpb.AnyValue_BytesValue:
+		return jaegerTag{Key: key, Type: "binary", Value: base64.StdEncoding.EncodeToString(v.GetBytesValue())}
+	default:
+		return jaegerTag{Key: key, Type: "string", Value: otlpclient.AnyValueToString(v)}
+	}
+}
+
+// zipkinSpan is a span in Zipkin's v2 JSON format.
+type zipkinSpan struct {
+	TraceID       string             `json:"traceId"`
+	ID            string             `json:"id"`
+	ParentID      string             `json:"parentId,omitempty"`
+	Name          string             `json:"name"`
+	Kind          string             `json:"kind,omitempty"`
+	Timestamp     uint64             `json:"timestamp"` // microseconds
+	Duration      uint64             `json:"duration"`  // microseconds
+	LocalEndpoint zipkinEndpoint     `json:"localEndpoint"`
+	Tags          map[string]string  `json:"tags,omitempty"`
+	Annotations   []zipkinAnnotation `json:"annotations,omitempty"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+func toZipkinSpan(fs formatSpan) zipkinSpan {
+	span := fs.span
+	zs := zipkinSpan{
+		TraceID:       hex.EncodeToString(span.TraceId),
+		ID:            hex.EncodeToString(span.SpanId),
+		Name:          span.Name,
+		Timestamp:     span.StartTimeUnixNano / 1000,
+		Duration:      spanDurationMicros(span),
+		LocalEndpoint: zipkinEndpoint{ServiceName: serviceName(fs.resource)},
+		Tags:          map[string]string{},
+	}
+
+	if len(span.ParentSpanId) > 0 {
+		zs.ParentID = hex.EncodeToString(span.ParentSpanId)
+	}
+
+	// zipkin has no internal kind, those spans leave it out
+	if kind := spanKindName(span.Kind); kind != "INTERNAL" {
+		zs.Kind = kind
+	}
+
+	for _, attr := range span.Attributes {
+		zs.Tags[attr.Key] = otlpclient.AnyValueToString(attr.Value)
+	}
+	if code := statusCodeName(span.Status); code != "" {
+		zs.Tags["otel.status_code"] = code
+	}
+	if span.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+		zs.Tags["error"] = span.Status.GetMessage()
+	}
+
+	for _, event := range span.Events {
+		zs.Annotations = append(zs.Annotations, zipkinAnnotation{
+			Timestamp: event.TimeUnixNano / 1000,
+			Value:     event.Name,
+		})
+	}
+
+	return zs
+}
+
+// spanDurationMicros returns how long the span took, or 0 for spans that end
+// before they start.
+func spanDurationMicros(span *tracepb.Span) uint64 {
+	if span.EndTimeUnixNano < span.StartTimeUnixNano {
+		return 0
+	}
+	return (span.EndTimeUnixNano - span.StartTimeUnixNano) / 1000
+}
+
+// spanKindName returns the kind without its prefix, e.g. SERVER, or an empty
+// string when it's unspecified.
+func spanKindName(kind tracepb.Span_SpanKind) string {
+	if kind == tracepb.Span_SPAN_KIND_UNSPECIFIED {
+		return ""
+	}
+	return strings.TrimPrefix(kind.String(), "SPAN_KIND_")
+}
+
+// statusCodeName returns OK or ERROR, or an empty string when the status is
+// unset.
+func statusCodeName(status *tracepb.Status) string {
+	if status.GetCode() == tracepb.Status_STATUS_CODE_UNSET {
+		return ""
+	}
+	return strings.TrimPrefix(status.GetCode().String(), "STATUS_CODE_")
+}
+
+// serviceName returns the resource's service.name, which both formats
+// require, falling back to what the SDKs use when it isn't set.
+func serviceName(resource map[string]*commonpb.AnyValue) string {
+	if v, ok := resource["service.name"]; ok {
+		return otlpclient.AnyValueToString(v)
+	}
+	return "unknown_service"
+}
+
+// sortedKeys returns the map's keys in order so output is stable.
+func sortedKeys(m map[string]*commonpb.AnyValue) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
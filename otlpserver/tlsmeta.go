@@ -0,0 +1,87 @@
+package otlpserver
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// addTlsMeta adds what was negotiated for a TLS connection to an export's
+// server meta: tls-version, tls-cipher, tls-alpn when the client asked for
+// a protocol, tls-server-name when it sent SNI, and tls-client-subject when
+// it authenticated with a certificate. Does nothing for plaintext, so meta
+// from plaintext exports doesn't change.
+func addTlsMeta(meta map[string]string, state *tls.ConnectionState) {
+	if state == nil {
+		return
+	}
+
+	meta["tls-version"] = tls.VersionName(state.Version)
+	meta["tls-cipher"] = tls.CipherSuiteName(state.CipherSuite)
+	if state.NegotiatedProtocol != "" {
+		meta["tls-alpn"] = state.NegotiatedProtocol
+	}
+	if state.ServerName != "" {
+		meta["tls-server-name"] = state.ServerName
+	}
+	if len(state.PeerCertificates) > 0 {
+		meta["tls-client-subject"] = state.PeerCertificates[0].Subject.String()
+	}
+}
+
+// tlsPassthroughCreds are gRPC transport credentials for servers that are
+// handed a listener that may already be doing TLS. They don't do any TLS of
+// their own, they only finish the handshake of *tls.Conn connections so the
+// connection state is available to RPCs from their peer's AuthInfo.
+type tlsPassthroughCreds struct{}
+
+// plaintextAuthInfo is the AuthInfo of connections that aren't TLS.
+type plaintextAuthInfo struct {
+	credentials.CommonAuthInfo
+}
+
+// AuthType implements credentials.AuthInfo.
+func (plaintextAuthInfo) AuthType() string {
+	return "insecure"
+}
+
+// ServerHandshake implements credentials.TransportCredentials.
+func (tlsPassthroughCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return conn, plaintextAuthInfo{credentials.CommonAuthInfo{SecurityLevel: credentials.NoSecurity}}, nil
+	}
+
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, nil, err
+	}
+	info := credentials.TLSInfo{
+		State:          tlsConn.ConnectionState(),
+		CommonAuthInfo: credentials.CommonAuthInfo{SecurityLevel: credentials.PrivacyAndIntegrity},
+	}
+	return conn, info, nil
+}
+
+// ClientHandshake implements credentials.TransportCredentials, servers don't
+// use it.
+func (tlsPassthroughCreds) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tlsPassthroughCreds are only for servers")
+}
+
+// Info implements credentials.TransportCredentials.
+func (tlsPassthroughCreds) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "insecure"}
+}
+
+// Clone implements credentials.TransportCredentials.
+func (c tlsPassthroughCreds) Clone() credentials.TransportCredentials {
+	return c
+}
+
+// OverrideServerName implements credentials.TransportCredentials.
+func (tlsPassthroughCreds) OverrideServerName(string) error {
+	return nil
+}
@@ -0,0 +1,199 @@
+package otlpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/protobuf/proto"
+)
+
+// selfSignedCert makes a throwaway certificate for cn.
+func selfSignedCert(t *testing.T, cn string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %s", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %s", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestServerTlsMeta(t *testing.T) {
+	serverTls := &tls.Config{
+		Certificates: []tls.Certificate{selfSignedCert(t, "server")},
+		ClientAuth:   tls.RequireAnyClientCert,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	clientTls := &tls.Config{
+		Certificates:       []tls.Certificate{selfSignedCert(t, "fleet-host-42")},
+		InsecureSkipVerify: true,
+		ServerName:         "localhost",
+		MinVersion:         tls.VersionTLS13,
+	}
+	req := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{{ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{{Name: "tls", TraceId: make([]byte, 16), SpanId: make([]byte, 8)}},
+		}}}},
+	}
+
+	start := func(t *testing.T, protocol string) (string, chan map[string]string) {
+		got := make(chan map[string]string, 1)
+		cb := func(ctx context.Context, span *tracepb.Span, events []*tracepb.Span_Event, rss *tracepb.ResourceSpans, headers map[string]string, meta map[string]string) bool {
+			got <- meta
+			return false
+		}
+		cs := NewServer(protocol, cb, func(OtlpServer) {})
+		listener, err := tls.Listen("tcp", "localhost:0", serverTls)
+		if err != nil {
+			t.Fatalf("failed to listen: %s", err)
+		}
+		go cs.Serve(listener)
+		t.Cleanup(cs.Stop)
+		return listener.Addr().String(), got
+	}
+	check := func(t *testing.T, got chan map[string]string, want map[string]string) {
+		select {
+		case meta := <-got:
+			for k, v := range want {
+				if meta[k] != v {
+					t.Errorf("expected meta %s to be %q, got %q", k, v, meta[k])
+				}
+			}
+			if meta["tls-cipher"] == "" {
+				t.Error("expected the TLS cipher in the meta")
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the export")
+		}
+	}
+
+	t.Run("grpc", func(t *testing.T) {
+		addr, got := start(t, "grpc")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTls)))
+		if err != nil {
+			t.Fatalf("failed to connect: %s", err)
+		}
+		defer conn.Close()
+
+		if _, err := coltracepb.NewTraceServiceClient(conn).Export(ctx, req); err != nil {
+			t.Fatalf("failed to export: %s", err)
+		}
+		check(t, got, map[string]string{
+			"proto":              "grpc",
+			"tls-version":        "TLS 1.3",
+			"tls-alpn":           "h2",
+			"tls-server-name":    "localhost",
+			"tls-client-subject": "CN=fleet-host-42",
+		})
+	})
+
+	t.Run("http", func(t *testing.T) {
+		addr, got := start(t, "http")
+		body, _ := proto.Marshal(req)
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: clientTls}}
+		resp, err := client.Post("https://"+addr+"/v1/traces", "application/x-protobuf", bytes.NewReader(body))
+		if err != nil {
+			t.Fatalf("failed to export: %s", err)
+		}
+		resp.Body.Close()
+
+		check(t, got, map[string]string{
+			"proto":              "HTTP/1.1",
+			"tls-version":        "TLS 1.3",
+			"tls-client-subject": "CN=fleet-host-42",
+		})
+	})
+}
+
+// plaintext connections are passed through with no TLS meta
+func TestTlsPassthroughCredsPlaintext(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+
+	conn, info, err := tlsPassthroughCreds{}.ServerHandshake(server)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if conn != server || info.AuthType() != "insecure" {
+		t.Errorf("expected the connection back as is with insecure auth info, got %s", info.AuthType())
+	}
+}
+
+// SetTlsConfig has ListenAndServe serve TLS on the address it's given
+func TestSetTlsConfig(t *testing.T) {
+	serverTls := &tls.Config{Certificates: []tls.Certificate{selfSignedCert(t, "server")}}
+	clientTls := &tls.Config{InsecureSkipVerify: true, ServerName: "localhost"}
+
+	for _, protocol := range []string{"grpc", "http"} {
+		t.Run(protocol, func(t *testing.T) {
+			// find a free port for ListenAndServe
+			l, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %s", err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+
+			cb := func(context.Context, *tracepb.Span, []*tracepb.Span_Event, *tracepb.ResourceSpans, map[string]string, map[string]string) bool {
+				return false
+			}
+			cs := NewServer(protocol, cb, func(OtlpServer) {})
+			cs.(TlsServer).SetTlsConfig(serverTls)
+			go cs.ListenAndServe(addr)
+			t.Cleanup(cs.Stop)
+
+			var conn *tls.Conn
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				conn, err = tls.Dial("tcp", addr, clientTls)
+				if err == nil || time.Now().After(deadline) {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err != nil {
+				t.Fatalf("failed to connect over TLS: %s", err)
+			}
+			defer conn.Close()
+
+			want := map[string]string{"grpc": "h2", "http": "http/1.1"}[protocol]
+			clientTls := clientTls.Clone()
+			clientTls.NextProtos = []string{want}
+			alpn, err := tls.Dial("tcp", addr, clientTls)
+			if err != nil {
+				t.Fatalf("failed to connect with ALPN %q: %s", want, err)
+			}
+			defer alpn.Close()
+			if got := alpn.ConnectionState().NegotiatedProtocol; got != want {
+				t.Errorf("expected ALPN %q, got %q", want, got)
+			}
+		})
+	}
+}
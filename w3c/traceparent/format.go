@@ -0,0 +1,361 @@
+package traceparent
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Format is a propagation format, the envvars and carrier file lines a
+// traceparent is read from and written as. W3C is the default and the only
+// one that carries a tracestate, the others are converted to and from the
+// same Traceparent struct for systems that don't speak W3C.
+type Format string
+
+const (
+	// W3C is TRACEPARENT and TRACESTATE.
+	W3C Format = "w3c"
+	// B3 is Zipkin's single B3 header: traceid-spanid-sampled.
+	B3 Format = "b3"
+	// B3Multi is Zipkin's X-B3-* headers as X_B3_* envvars.
+	B3Multi Format = "b3multi"
+	// Jaeger is Jaeger's uber-trace-id header as UBER_TRACE_ID:
+	// traceid:spanid:parentspanid:flags.
+	Jaeger Format = "jaeger"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{W3C, B3, B3Multi, Jaeger}
+
+// ErrUnknownFormat is returned by ParseFormat for unsupported formats.
+var ErrUnknownFormat = errors.New("unknown propagation format")
+
+// ParseFormat returns the Format named by in.
+func ParseFormat(in string) (Format, error) {
+	for _, f := range Formats {
+		if string(f) == in {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q, must be one of %v", ErrUnknownFormat, in, Formats)
+}
+
+// EnvVars returns the names of the envvars the format reads and writes.
+func (f Format) EnvVars() []string {
+	switch f {
+	case B3:
+		return []string{"B3"}
+	case B3Multi:
+		return []string{"X_B3_TRACEID", "X_B3_SPANID", "X_B3_PARENTSPANID", "X_B3_SAMPLED", "X_B3_FLAGS"}
+	case Jaeger:
+		return []string{"UBER_TRACE_ID"}
+	default:
+		return []string{"TRACEPARENT", "TRACESTATE"}
+	}
+}
+
+// Env returns the traceparent as NAME=value envvars in the format.
+func (f Format) Env(tp Traceparent) []string {
+	sampled := "0"
+	if tp.Sampling {
+		sampled = "1"
+	}
+
+	switch f {
+	case B3:
+		return []string{"B3=" + tp.TraceIdString() + "-" + tp.SpanIdString() + "-" + sampled}
+	case B3Multi:
+		return []string{
+			"X_B3_TRACEID=" + tp.TraceIdString(),
+			"X_B3_SPANID=" + tp.SpanIdString(),
+			"X_B3_SAMPLED=" + sampled,
+		}
+	case Jaeger:
+		// parent span id is deprecated in Jaeger and always 0 when sent
+		return []string{"UBER_TRACE_ID=" + tp.TraceIdString() + ":" + tp.SpanIdString() + ":0:" + sampled}
+	default:
+		out := []string{"TRACEPARENT=" + tp.Encode()}
+		if len(tp.Tracestate) > 0 {
+			out = append(out, "TRACESTATE="+tp.Tracestate.Encode())
+		}
+		return out
+	}
+}
+
+// Load reads a traceparent in the format from the values returned by get,
+// which is given envvar names. Returns an uninitialized Traceparent and no
+// error when the format's envvars aren't set.
+func (f Format) Load(get func(string) string, mode ParseMode) (Traceparent, error) {
+	switch f {
+	case B3:
+		return parseB3(get("B3"), mode)
+	case B3Multi:
+		return parseB3Multi(get, mode)
+	case Jaeger:
+		return parseJaeger(get("UBER_TRACE_ID"), mode)
+	default:
+		tp := get("TRACEPARENT")
+		if tp == "" {
+			return Traceparent{}, nil
+		}
+		out, err := ParseWithMode(tp, mode)
+		if err != nil {
+			return out, err
+		}
+		out.Tracestate = parseCarrierTracestate(get("TRACESTATE"))
+		return out, nil
+	}
+}
+
+// LoadFromEnvWithFormat loads the traceparent from the format's envvars.
+func LoadFromEnvWithFormat(f Format, mode ParseMode) (Traceparent, error) {
+	return f.Load(os.Getenv, mode)
+}
+
+// LoadFromFileWithFormat is LoadFromFileWithMode for any format. Carrier
+// files in other formats are NAME=value lines, the same as FprintFormat
+// writes them.
+func LoadFromFileWithFormat(filename string, f Format, mode ParseMode) (Traceparent, error) {
+	if f == W3C {
+		return LoadFromFileWithMode(filename, mode)
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("could not open file '%s' for read: %s", filename, err)
+	}
+	defer file.Close()
+
+	vars, _ := scanVars(file)
+	out, err := f.Load(func(name string) string { return vars[name] }, mode)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("file '%s' was read but does not contain a valid %s traceparent: %w", filename, f, err)
+	}
+	return out, nil
+}
+
+// LoadFromReaderWithFormat is LoadFromReaderWithMode for any format. Only
+// W3C accepts a bare traceparent, other formats need NAME=value lines.
+func LoadFromReaderWithFormat(r io.Reader, f Format, mode ParseMode) (Traceparent, error) {
+	if f == W3C {
+		return LoadFromReaderWithMode(r, mode)
+	}
+
+	vars, err := scanVars(r)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("could not read traceparent: %w", err)
+	}
+	out, err := f.Load(func(name string) string { return vars[name] }, mode)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("input was read but does not contain a valid %s traceparent: %w", f, err)
+	}
+	return out, nil
+}
+
+// FprintFormat is Fprint for any format.
+func (tp Traceparent) FprintFormat(target io.Writer, export bool, f Format) error {
+	var exported string
+	if export {
+		exported = "export "
+	}
+
+	_, err := fmt.Fprintf(target, "# trace id: %s\n#  span id: %s\n", tp.TraceIdString(), tp.SpanIdString())
+	if err != nil {
+		return err
+	}
+
+	for _, env := range f.Env(tp) {
+		name, value, _ := strings.Cut(env, "=")
+		// tracestate values can have spaces and quotes so they're quoted
+		// for sourcing, everything else is passed through as-is
+		if _, err := fmt.Fprintf(target, "%s%s=%s\n", exported, name, shellQuote(value)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SaveToFileWithFormat is SaveToFile for any format.
+func (tp Traceparent) SaveToFileWithFormat(carrierFile string, export bool, f Format) error {
+	file, err := os.OpenFile(carrierFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failure opening file '%s' for write: %w", carrierFile, err)
+	}
+	defer file.Close()
+
+	return tp.FprintFormat(file, export, f)
+}
+
+// scanVars reads NAME=value lines, skipping blanks and comments, with
+// 'export ' and shell quoting stripped. The first value for a name wins.
+func scanVars(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		if _, ok := vars[name]; !ok {
+			vars[name] = shellUnquote(value)
+		}
+	}
+	return vars, scanner.Err()
+}
+
+// parseB3 parses a single B3 header: traceid-spanid[-sampled[-parentspanid]].
+// A header with only the sampling decision doesn't have a traceparent in it.
+func parseB3(in string, mode ParseMode) (Traceparent, error) {
+	if mode == Lenient {
+		in = strings.TrimSpace(in)
+	}
+	if in == "" || in == "0" || in == "1" || in == "d" {
+		return Traceparent{}, nil
+	}
+
+	fields := strings.Split(in, "-")
+	if len(fields) < 2 || len(fields) > 4 {
+		return Traceparent{}, &ParseError{Input: in, Category: ErrMalformed, Detail: "expected traceid-spanid-sampled"}
+	}
+
+	sampled := ""
+	if len(fields) > 2 {
+		sampled = fields[2]
+	}
+	return b3Traceparent(in, fields[0], fields[1], sampled, "", mode)
+}
+
+// parseB3Multi parses the X_B3_* envvars.
+func parseB3Multi(get func(string) string, mode ParseMode) (Traceparent, error) {
+	traceId, spanId := get("X_B3_TRACEID"), get("X_B3_SPANID")
+	if mode == Lenient {
+		traceId, spanId = strings.TrimSpace(traceId), strings.TrimSpace(spanId)
+	}
+	if traceId == "" && spanId == "" {
+		return Traceparent{}, nil
+	}
+
+	input := "X_B3_TRACEID=" + traceId + " X_B3_SPANID=" + spanId
+	return b3Traceparent(input, traceId, spanId, strings.TrimSpace(get("X_B3_SAMPLED")), strings.TrimSpace(get("X_B3_FLAGS")), mode)
+}
+
+// b3Traceparent builds a Traceparent from the B3 fields. Trace ids may be
+// 64 or 128 bits, 64 bit ones are padded to 128 with zeroes. A sampling
+// decision that's missing or deferred is taken as sampled since otel-cli is
+// the one deciding, and the debug flag implies sampled.
+func b3Traceparent(input, traceIdHex, spanIdHex, sampled, flags string, mode ParseMode) (Traceparent, error) {
+	fail := func(category error, detail string) (Traceparent, error) {
+		return Traceparent{}, &ParseError{Input: input, Category: category, Detail: detail}
+	}
+
+	if len(traceIdHex) != 16 && len(traceIdHex) != 32 {
+		return fail(ErrInvalidTraceId, "must be 16 or 32 hex characters")
+	}
+	traceId, err := parseForeignId(traceIdHex, 16, mode)
+	if err != nil {
+		return fail(ErrInvalidTraceId, err.Error())
+	}
+
+	if len(spanIdHex) != 16 {
+		return fail(ErrInvalidSpanId, "must be 16 hex characters")
+	}
+	spanId, err := parseForeignId(spanIdHex, 8, mode)
+	if err != nil {
+		return fail(ErrInvalidSpanId, err.Error())
+	}
+
+	tp := Traceparent{TraceId: traceId, SpanId: spanId, Initialized: true}
+	switch {
+	case flags == "1":
+		tp.Sampling = true
+	case sampled == "" || sampled == "1" || sampled == "d" || sampled == "true":
+		tp.Sampling = true
+	case sampled == "0" || sampled == "false":
+		tp.Sampling = false
+	default:
+		return fail(ErrInvalidFlags, fmt.Sprintf("sampling state %q must be 0, 1, or d", sampled))
+	}
+
+	return tp, nil
+}
+
+// parseJaeger parses an uber-trace-id: traceid:spanid:parentspanid:flags.
+// Jaeger drops leading zeroes from the ids so they're padded back out.
+// The colons may be URL encoded since it's usually an HTTP header.
+func parseJaeger(in string, mode ParseMode) (Traceparent, error) {
+	if mode == Lenient {
+		in = strings.TrimSpace(in)
+	}
+	if in == "" {
+		return Traceparent{}, nil
+	}
+
+	input := in
+	fail := func(category error, detail string) (Traceparent, error) {
+		return Traceparent{}, &ParseError{Input: input, Category: category, Detail: detail}
+	}
+
+	in = strings.ReplaceAll(strings.ReplaceAll(in, "%3A", ":"), "%3a", ":")
+	fields := strings.Split(in, ":")
+	if len(fields) != 4 {
+		return fail(ErrMalformed, "expected traceid:spanid:parentspanid:flags")
+	}
+
+	if len(fields[0]) == 0 || len(fields[0]) > 32 {
+		return fail(ErrInvalidTraceId, "must be 1 to 32 hex characters")
+	}
+	traceId, err := parseForeignId(fields[0], 16, mode)
+	if err != nil {
+		return fail(ErrInvalidTraceId, err.Error())
+	}
+
+	if len(fields[1]) == 0 || len(fields[1]) > 16 {
+		return fail(ErrInvalidSpanId, "must be 1 to 16 hex characters")
+	}
+	spanId, err := parseForeignId(fields[1], 8, mode)
+	if err != nil {
+		return fail(ErrInvalidSpanId, err.Error())
+	}
+
+	flags, err := strconv.ParseUint(fields[3], 16, 8)
+	if err != nil {
+		return fail(ErrInvalidFlags, "must be hex")
+	}
+
+	return Traceparent{
+		TraceId:     traceId,
+		SpanId:      spanId,
+		Sampling:    flags&1 == 1,
+		Initialized: true,
+	}, nil
+}
+
+// parseForeignId decodes a hex id that may be shorter than size bytes,
+// left-padding it with zeroes. Strict mode has the same rules as W3C: lower
+// case hex and not all zeroes.
+func parseForeignId(in string, size int, mode ParseMode) ([]byte, error) {
+	if mode == Strict && !isLowerHex(in) {
+		return nil, errors.New("must be lower case hex")
+	}
+
+	in = strings.Repeat("0", size*2-len(in)) + strings.ToLower(in)
+	out, err := hex.DecodeString(in)
+	if err != nil {
+		return nil, errors.New("not valid hex")
+	}
+	if mode == Strict && isAllZero(out) {
+		return nil, errors.New("cannot be all zeroes")
+	}
+	return out, nil
+}
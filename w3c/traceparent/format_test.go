@@ -0,0 +1,122 @@
+package traceparent
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatRoundTrip(t *testing.T) {
+	tp, _ := Parse("00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01")
+	unsampled := tp
+	unsampled.Sampling = false
+
+	for _, f := range Formats {
+		for _, in := range []Traceparent{tp, unsampled} {
+			env := map[string]string{}
+			for _, e := range f.Env(in) {
+				name, value, _ := strings.Cut(e, "=")
+				env[name] = value
+			}
+
+			got, err := f.Load(func(name string) string { return env[name] }, Strict)
+			if err != nil {
+				t.Errorf("[%s] unexpected error loading %v: %s", f, env, err)
+			} else if got.Encode() != in.Encode() {
+				t.Errorf("[%s] expected %s but got %s from %v", f, in.Encode(), got.Encode(), env)
+			}
+		}
+
+		// nothing set is not an error
+		got, err := f.Load(func(string) string { return "" }, Strict)
+		if err != nil || got.Initialized {
+			t.Errorf("[%s] expected nothing from empty envvars but got %v, %v", f, got, err)
+		}
+	}
+}
+
+func TestFormatLoad(t *testing.T) {
+	for _, tc := range []struct {
+		format Format
+		env    map[string]string
+		want   string
+		err    error
+	}{
+		// 64 bit trace ids are padded
+		{format: B3, env: map[string]string{"B3": "a3ce929d0e0e4736-00f067aa0ba902b7-1"},
+			want: "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		// parent span id and debug
+		{format: B3, env: map[string]string{"B3": "80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-d-05e3ac9a4f6e3b90"},
+			want: "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01"},
+		// a sampling decision on its own has no ids
+		{format: B3, env: map[string]string{"B3": "0"}, want: ""},
+		{format: B3, env: map[string]string{"B3": "80f198ee56343ba864fe8b2a57d3eff7"}, err: ErrMalformed},
+		{format: B3, env: map[string]string{"B3": "80f198ee56343ba8-e457b5a2e4d86bd1-x"}, err: ErrInvalidFlags},
+		{format: B3Multi, env: map[string]string{"X_B3_TRACEID": "80f198ee56343ba864fe8b2a57d3eff7", "X_B3_SPANID": "e457b5a2e4d86bd1", "X_B3_SAMPLED": "0"},
+			want: "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-00"},
+		{format: B3Multi, env: map[string]string{"X_B3_TRACEID": "80f198ee56343ba864fe8b2a57d3eff7", "X_B3_SPANID": "e457b5a2e4d86bd1", "X_B3_SAMPLED": "0", "X_B3_FLAGS": "1"},
+			want: "00-80f198ee56343ba864fe8b2a57d3eff7-e457b5a2e4d86bd1-01"},
+		{format: B3Multi, env: map[string]string{"X_B3_TRACEID": "80f198ee56343ba864fe8b2a57d3eff7"}, err: ErrInvalidSpanId},
+		// jaeger drops leading zeroes and may be url encoded
+		{format: Jaeger, env: map[string]string{"UBER_TRACE_ID": "a3ce929d0e0e4736:f067aa0ba902b7:0:1"},
+			want: "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{format: Jaeger, env: map[string]string{"UBER_TRACE_ID": "a3ce929d0e0e4736%3Af067aa0ba902b7%3A0%3A2"},
+			want: "00-0000000000000000a3ce929d0e0e4736-00f067aa0ba902b7-00"},
+		{format: Jaeger, env: map[string]string{"UBER_TRACE_ID": "a3ce929d0e0e4736:f067aa0ba902b7:1"}, err: ErrMalformed},
+		{format: Jaeger, env: map[string]string{"UBER_TRACE_ID": "xyz:f067aa0ba902b7:0:1"}, err: ErrInvalidTraceId},
+		{format: Jaeger, env: map[string]string{"UBER_TRACE_ID": "a3ce929d0e0e4736:f067aa0ba902b7:0:zz"}, err: ErrInvalidFlags},
+	} {
+		got, err := tc.format.Load(func(name string) string { return tc.env[name] }, Lenient)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("[%s] expected error %v for %v but got %v", tc.format, tc.err, tc.env, err)
+		} else if err == nil && tc.want == "" && got.Initialized {
+			t.Errorf("[%s] expected no traceparent for %v but got %s", tc.format, tc.env, got.Encode())
+		} else if err == nil && tc.want != "" && got.Encode() != tc.want {
+			t.Errorf("[%s] expected %s for %v but got %s", tc.format, tc.want, tc.env, got.Encode())
+		}
+	}
+
+	// strict mode doesn't allow upper case or all-zero ids
+	if _, err := B3.Load(func(string) string { return "80F198EE56343BA8-E457B5A2E4D86BD1-1" }, Strict); !errors.Is(err, ErrInvalidTraceId) {
+		t.Errorf("expected an invalid trace id error in strict mode but got %v", err)
+	}
+	if _, err := Jaeger.Load(func(string) string { return "0:1:0:1" }, Strict); !errors.Is(err, ErrInvalidTraceId) {
+		t.Errorf("expected an invalid trace id error in strict mode but got %v", err)
+	}
+}
+
+func TestFormatCarrierFile(t *testing.T) {
+	tp, _ := Parse("00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01")
+
+	for _, f := range Formats {
+		carrier := filepath.Join(t.TempDir(), "carrier")
+		if err := tp.SaveToFileWithFormat(carrier, true, f); err != nil {
+			t.Fatalf("[%s] SaveToFileWithFormat returned an unexpected error: %s", f, err)
+		}
+
+		got, err := LoadFromFileWithFormat(carrier, f, Strict)
+		if err != nil {
+			t.Errorf("[%s] LoadFromFileWithFormat returned an unexpected error: %s", f, err)
+		} else if got.Encode() != tp.Encode() {
+			t.Errorf("[%s] expected %s but got %s", f, tp.Encode(), got.Encode())
+		}
+	}
+
+	var buf bytes.Buffer
+	tp.FprintFormat(&buf, false, B3Multi)
+	got, err := LoadFromReaderWithFormat(&buf, B3Multi, Strict)
+	if err != nil || got.Encode() != tp.Encode() {
+		t.Errorf("expected %s from reader but got %s, %v", tp.Encode(), got.Encode(), err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	if f, err := ParseFormat("jaeger"); err != nil || f != Jaeger {
+		t.Errorf("expected jaeger but got %q, %v", f, err)
+	}
+	if _, err := ParseFormat("xray"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected an unknown format error but got %v", err)
+	}
+}
@@ -5,24 +5,17 @@ package traceparent
 import (
 	"bufio"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"os"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-var traceparentRe *regexp.Regexp
 var emptyTraceId = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
 var emptySpanId = []byte{0, 0, 0, 0, 0, 0, 0, 0}
 
-func init() {
-	// only anchored at the front because traceparents can include more things
-	// per the standard but only the first 4 are required for our uses
-	traceparentRe = regexp.MustCompile("^([[:xdigit:]]{2})-([[:xdigit:]]{32})-([[:xdigit:]]{16})-([[:xdigit:]]{2})")
-}
-
 // Traceparent represents a parsed W3C traceparent.
 type Traceparent struct {
 	Version     int
@@ -30,6 +23,8 @@ type Traceparent struct {
 	SpanId      []byte
 	Sampling    bool
 	Initialized bool
+	// Tracestate is the W3C tracestate that travels with the traceparent.
+	Tracestate Tracestate
 }
 
 // Encode returns the traceparent as a W3C formatted string.
@@ -52,7 +47,7 @@ func (tp Traceparent) Encode() string {
 		spanId = tp.SpanIdString()
 	}
 
-	return fmt.Sprintf("%02d-%s-%s-%02d", tp.Version, traceId, spanId, sampling)
+	return fmt.Sprintf("%02x-%s-%s-%02x", tp.Version, traceId, spanId, sampling)
 }
 
 // TraceIdString returns the trace id in string form.
@@ -78,6 +73,11 @@ func (tp Traceparent) SpanIdString() string {
 // just a bare traceparent string. Whitespace, "export " and "TRACEPARENT=" are
 // stripped automatically so the file can also be a valid shell snippet.
 func LoadFromFile(filename string) (Traceparent, error) {
+	return LoadFromFileWithMode(filename, Lenient)
+}
+
+// LoadFromFileWithMode is LoadFromFile with the choice of parse mode.
+func LoadFromFileWithMode(filename string, mode ParseMode) (Traceparent, error) {
 	file, err := os.Open(filename)
 	if err != nil {
 		errOut := fmt.Errorf("could not open file '%s' for read: %s", filename, err)
@@ -87,46 +87,94 @@ func LoadFromFile(filename string) (Traceparent, error) {
 	}
 	defer file.Close()
 
-	// only use the line that contains TRACEPARENT
-	var tp string
-	scanner := bufio.NewScanner(file)
+	tp, ts, _ := scanCarrier(file, false)
+
+	// silently fail if no traceparent was found
+	if tp == "" {
+		return Traceparent{}, nil
+	}
+
+	out, err := ParseWithMode(tp, mode)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("file '%s' was read but does not contain a valid traceparent: %w", filename, err)
+	}
+	out.Tracestate = parseCarrierTracestate(ts)
+
+	return out, nil
+}
+
+// LoadFromReaderWithMode reads a traceparent from r, e.g. stdin in a
+// pipeline. It takes the same format as LoadFromFile, and also a bare
+// traceparent on the first line that isn't blank or a comment.
+func LoadFromReaderWithMode(r io.Reader, mode ParseMode) (Traceparent, error) {
+	tp, ts, err := scanCarrier(r, true)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("could not read traceparent: %w", err)
+	}
+
+	if tp == "" {
+		return Traceparent{}, nil
+	}
+
+	out, err := ParseWithMode(tp, mode)
+	if err != nil {
+		return Traceparent{}, fmt.Errorf("input was read but does not contain a valid traceparent: %w", err)
+	}
+	out.Tracestate = parseCarrierTracestate(ts)
+
+	return out, nil
+}
+
+// scanCarrier finds the traceparent and tracestate in otel-cli's carrier
+// format, with 'export ', 'TRACEPARENT=' and 'TRACESTATE=' stripped. With
+// bare set, the first line that isn't blank or a comment is used as the
+// traceparent when there's no TRACEPARENT line.
+func scanCarrier(r io.Reader, bare bool) (tp, ts string, err error) {
+	var first string
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		// printSpanData emits comments with trace id and span id, ignore those
-		if strings.HasPrefix(line, "#") {
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+		if strings.HasPrefix(line, "TRACESTATE=") {
+			if ts == "" {
+				ts = shellUnquote(strings.TrimPrefix(line, "TRACESTATE="))
+			}
 		} else if strings.Contains(strings.ToUpper(line), "TRACEPARENT") {
-			tp = line
-			break
+			if tp == "" {
+				tp = strings.TrimPrefix(line, "TRACEPARENT=")
+			}
+		} else if first == "" {
+			first = line
 		}
 	}
 
-	// silently fail if no traceparent was found
-	if tp == "" {
-		return Traceparent{}, nil
+	if tp == "" && bare {
+		tp = first
 	}
 
-	// clean 'export TRACEPARENT=' and 'TRACEPARENT=' off the output
-	tp = strings.TrimPrefix(tp, "export ")
-	tp = strings.TrimPrefix(tp, "TRACEPARENT=")
+	return tp, ts, scanner.Err()
+}
 
-	if !traceparentRe.MatchString(tp) {
-		return Traceparent{}, fmt.Errorf("file '%s' was read but does not contain a valid traceparent", filename)
+// parseCarrierTracestate parses the tracestate that came with a traceparent.
+// An invalid tracestate is dropped without failing the traceparent, as the
+// W3C spec says.
+func parseCarrierTracestate(ts string) Tracestate {
+	out, err := ParseTracestate(ts)
+	if err != nil {
+		return Tracestate{}
 	}
-
-	return Parse(tp)
+	return out
 }
 
 // SaveToFile takes a context and filename and writes the tp from
 // that context into the specified file.
 func (tp Traceparent) SaveToFile(carrierFile string, export bool) error {
-	file, err := os.OpenFile(carrierFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
-	if err != nil {
-		return fmt.Errorf("failure opening file '%s' for write: %w", carrierFile, err)
-	}
-	defer file.Close()
-
-	return tp.Fprint(file, export)
+	return tp.SaveToFileWithFormat(carrierFile, export, W3C)
 }
 
 // Fprint formats a traceparent into otel-cli's shell-compatible text format.
@@ -135,61 +183,188 @@ func (tp Traceparent) SaveToFile(carrierFile string, export bool) error {
 func (tp Traceparent) Fprint(target io.Writer, export bool) error {
 	// --tp-export will print "export TRACEPARENT" so it's
 	// one less step to print to a file & source, or eval
-	var exported string
-	if export {
-		exported = "export "
-	}
-
-	traceId := tp.TraceIdString()
-	spanId := tp.SpanIdString()
-	_, err := fmt.Fprintf(target, "# trace id: %s\n#  span id: %s\n%sTRACEPARENT=%s\n", traceId, spanId, exported, tp.Encode())
-	return err
+	return tp.FprintFormat(target, export, W3C)
 }
 
 // LoadFromEnv loads the traceparent from the environment variable
 // TRACEPARENT and sets it in the returned Go context.
 func LoadFromEnv() (Traceparent, error) {
-	tp := os.Getenv("TRACEPARENT")
-	if tp == "" {
-		return Traceparent{}, nil
+	return LoadFromEnvWithMode(Lenient)
+}
+
+// LoadFromEnvWithMode is LoadFromEnv with the choice of parse mode.
+func LoadFromEnvWithMode(mode ParseMode) (Traceparent, error) {
+	return LoadFromEnvWithFormat(W3C, mode)
+}
+
+// ParseMode selects how strictly traceparents are validated.
+type ParseMode int
+
+const (
+	// Lenient is otel-cli's historical behavior: surrounding whitespace and
+	// anything after the flags are ignored, hex may be upper case, and all-zero
+	// ids are allowed since non-recording otel-cli propagates them.
+	Lenient ParseMode = iota
+	// Strict follows the W3C Trace Context spec to the letter.
+	Strict
+)
+
+// Parse errors are categorized with these so callers can use errors.Is to tell
+// the user what was wrong with their traceparent.
+var (
+	ErrMalformed      = errors.New("not in version-traceid-spanid-flags format")
+	ErrInvalidVersion = errors.New("invalid version")
+	ErrInvalidTraceId = errors.New("invalid trace id")
+	ErrInvalidSpanId  = errors.New("invalid span id")
+	ErrInvalidFlags   = errors.New("invalid flags")
+	ErrTrailingData   = errors.New("unexpected data after the flags")
+)
+
+// ParseError is returned for traceparents that can't be parsed. Category is
+// one of the Err* values above and detail says what specifically was wrong.
+type ParseError struct {
+	Input    string
+	Category error
+	Detail   string
+}
+
+func (pe *ParseError) Error() string {
+	if pe.Detail == "" {
+		return fmt.Sprintf("could not parse invalid traceparent %q: %s", pe.Input, pe.Category)
 	}
+	return fmt.Sprintf("could not parse invalid traceparent %q: %s: %s", pe.Input, pe.Category, pe.Detail)
+}
 
-	return Parse(tp)
+// Unwrap returns the category so errors.Is works.
+func (pe *ParseError) Unwrap() error {
+	return pe.Category
 }
 
-// Parse parses a string traceparent and returns the struct.
+// Parse parses a string traceparent leniently and returns the struct.
 func Parse(tp string) (Traceparent, error) {
-	var err error
-	out := Traceparent{}
+	return ParseWithMode(tp, Lenient)
+}
+
+// ParseStrict parses a string traceparent, rejecting anything the W3C spec
+// doesn't allow.
+func ParseStrict(tp string) (Traceparent, error) {
+	return ParseWithMode(tp, Strict)
+}
+
+// ParseWithMode parses a string traceparent and returns the struct. It never
+// panics, and only returns an initialized Traceparent when all of the
+// fields are valid so untrusted input can't produce corrupt ids.
+func ParseWithMode(tp string, mode ParseMode) (Traceparent, error) {
+	input := tp
+	fail := func(category error, detail string) (Traceparent, error) {
+		return Traceparent{}, &ParseError{Input: input, Category: category, Detail: detail}
+	}
 
-	parts := traceparentRe.FindStringSubmatch(tp)
-	if len(parts) != 5 {
-		return out, fmt.Errorf("could not parse invalid traceparent %q", tp)
+	if mode == Lenient {
+		tp = strings.TrimSpace(tp)
+	}
+
+	// version-traceid-spanid-flags is 2+1+32+1+16+1+2 = 55 bytes
+	if len(tp) < 55 || tp[2] != '-' || tp[35] != '-' || tp[52] != '-' {
+		return fail(ErrMalformed, "")
+	}
+	version, traceId, spanId, flags, rest := tp[0:2], tp[3:35], tp[36:52], tp[53:55], tp[55:]
+
+	if mode == Strict {
+		for _, field := range []struct {
+			value    string
+			category error
+		}{
+			{version, ErrInvalidVersion},
+			{traceId, ErrInvalidTraceId},
+			{spanId, ErrInvalidSpanId},
+			{flags, ErrInvalidFlags},
+		} {
+			if !isLowerHex(field.value) {
+				return fail(field.category, "must be lower case hex")
+			}
+		}
 	}
 
-	out.Version, err = strconv.Atoi(parts[1])
+	out := Traceparent{}
+
+	v, err := strconv.ParseUint(version, 16, 8)
 	if err != nil {
-		return out, fmt.Errorf("could not parse traceparent version component in %q", tp)
+		return fail(ErrInvalidVersion, "not hex")
+	} else if v == 0xff {
+		return fail(ErrInvalidVersion, "ff is forbidden")
 	}
+	out.Version = int(v)
 
-	out.TraceId, err = hex.DecodeString(parts[2])
+	out.TraceId, err = hex.DecodeString(traceId)
 	if err != nil {
-		return out, fmt.Errorf("could not parse traceparent trace id component in %q", tp)
+		return fail(ErrInvalidTraceId, "not hex")
 	}
 
-	out.SpanId, err = hex.DecodeString(parts[3])
+	out.SpanId, err = hex.DecodeString(spanId)
 	if err != nil {
-		return out, fmt.Errorf("could not parse traceparent span id component in %q", tp)
+		return fail(ErrInvalidSpanId, "not hex")
 	}
 
-	sampleFlag, err := strconv.ParseInt(parts[4], 10, 64)
+	f, err := strconv.ParseUint(flags, 16, 8)
 	if err != nil {
-		return out, fmt.Errorf("could not parse traceparent sampling bits component in %q", tp)
+		return fail(ErrInvalidFlags, "not hex")
+	}
+	out.Sampling = f&0x01 == 0x01
+
+	if mode == Strict {
+		if isAllZero(out.TraceId) {
+			return fail(ErrInvalidTraceId, "all zeroes")
+		}
+		if isAllZero(out.SpanId) {
+			return fail(ErrInvalidSpanId, "all zeroes")
+		}
+		// version 00 is exactly 55 bytes, future versions may append fields
+		if rest != "" && (out.Version == 0 || rest[0] != '-') {
+			return fail(ErrTrailingData, "")
+		}
 	}
-	out.Sampling = (sampleFlag == 1)
 
 	// mark that this is a successfully parsed struct
 	out.Initialized = true
 
 	return out, nil
 }
+
+func isLowerHex(in string) bool {
+	for _, c := range in {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAllZero(in []byte) bool {
+	for _, b := range in {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// shellQuote single quotes in if it has anything a shell would interpret.
+func shellQuote(in string) string {
+	safe := func(c rune) bool {
+		return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+			strings.ContainsRune("_-*/@=,.:+", c)
+	}
+	if strings.IndexFunc(in, func(c rune) bool { return !safe(c) }) < 0 {
+		return in
+	}
+	return "'" + strings.ReplaceAll(in, "'", `'\''`) + "'"
+}
+
+// shellUnquote reverses shellQuote.
+func shellUnquote(in string) string {
+	if len(in) >= 2 && strings.HasPrefix(in, "'") && strings.HasSuffix(in, "'") {
+		return strings.ReplaceAll(in[1:len(in)-1], `'\''`, "'")
+	}
+	return in
+}
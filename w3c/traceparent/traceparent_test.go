@@ -2,6 +2,7 @@ package traceparent
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -117,6 +118,32 @@ func TestLoadTraceparent(t *testing.T) {
 	}
 }
 
+func TestLoadFromReader(t *testing.T) {
+	want := "00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01"
+	for _, in := range []string{
+		want,
+		"\n" + want + "\n",
+		"# trace id: f61fc53f926e07a9c3893b1a722e1b65\n#  span id: 7a2d6a804f3de137\nexport TRACEPARENT=" + want + "\n",
+		"something else\nTRACEPARENT=" + want,
+	} {
+		tp, err := LoadFromReaderWithMode(strings.NewReader(in), Lenient)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", in, err)
+		} else if tp.Encode() != want {
+			t.Errorf("expected %s from %q but got %s", want, in, tp.Encode())
+		}
+	}
+
+	tp, err := LoadFromReaderWithMode(strings.NewReader(""), Lenient)
+	if err != nil || tp.Initialized {
+		t.Errorf("expected no traceparent from empty input, got %v, %v", tp, err)
+	}
+
+	if _, err := LoadFromReaderWithMode(strings.NewReader("not a traceparent\n"), Lenient); err == nil {
+		t.Error("expected an error for input without a valid traceparent")
+	}
+}
+
 func TestWriteTraceparentToFile(t *testing.T) {
 	testTp := "00-ce1c6ae29edafc52eb6dd223da7d20b4-1c617f036253531c-01"
 	tp, err := Parse(testTp)
@@ -152,3 +179,130 @@ func TestWriteTraceparentToFile(t *testing.T) {
 		t.Errorf("invalid data in traceparent file, expected '%s', got '%s'", testTp, data)
 	}
 }
+
+// traceparentVectors are adapted from the W3C Trace Context test suite
+// https://github.com/w3c/trace-context/blob/main/test/test.py
+// along with whether they should parse in Strict and Lenient mode.
+var traceparentVectors = []struct {
+	in       string
+	strict   error // nil if valid
+	lenient  error
+	sampling bool
+}{
+	// valid
+	{in: "00-12345678901234567890123456789012-1234567890123456-00"},
+	{in: "00-12345678901234567890123456789012-1234567890123456-01", sampling: true},
+	{in: "00-12345678901234567890123456789012-1234567890123456-03", sampling: true},
+	{in: "00-12345678901234567890123456789012-1234567890123456-ff", sampling: true},
+	{in: "00-abcdefabcdefabcdefabcdefabcdefab-abcdefabcdefabcd-00"},
+	// future versions can append fields after a dash
+	{in: "cc-12345678901234567890123456789012-1234567890123456-01", sampling: true},
+	{in: "cc-12345678901234567890123456789012-1234567890123456-01-what-the-future-will-be-like", sampling: true},
+	{in: "cc-12345678901234567890123456789012-1234567890123456-01.what-the-future-will-not-be-like", strict: ErrTrailingData, sampling: true},
+
+	// version
+	{in: "ff-12345678901234567890123456789012-1234567890123456-01", strict: ErrInvalidVersion, lenient: ErrInvalidVersion},
+	{in: "0-12345678901234567890123456789012-1234567890123456-01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "000-12345678901234567890123456789012-1234567890123456-01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "qw-12345678901234567890123456789012-1234567890123456-01", strict: ErrInvalidVersion, lenient: ErrInvalidVersion},
+	{in: ".0-12345678901234567890123456789012-1234567890123456-01", strict: ErrInvalidVersion, lenient: ErrInvalidVersion},
+	{in: "0A-12345678901234567890123456789012-1234567890123456-01", strict: ErrInvalidVersion, sampling: true},
+
+	// trace id
+	{in: "00-00000000000000000000000000000000-1234567890123456-01", strict: ErrInvalidTraceId, sampling: true},
+	{in: "00-ABCDEFABCDEFABCDEFABCDEFABCDEFAB-1234567890123456-01", strict: ErrInvalidTraceId, sampling: true},
+	{in: "00-1234567890123456789012345678901-1234567890123456-01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "00-123456789012345678901234567890123-1234567890123456-01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "00-1234567890123456789012345678901.-1234567890123456-01", strict: ErrInvalidTraceId, lenient: ErrInvalidTraceId},
+
+	// span id
+	{in: "00-12345678901234567890123456789012-0000000000000000-01", strict: ErrInvalidSpanId, sampling: true},
+	{in: "00-12345678901234567890123456789012-ABCDEFABCDEFABCD-01", strict: ErrInvalidSpanId, sampling: true},
+	{in: "00-12345678901234567890123456789012-123456789012345-01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "00-12345678901234567890123456789012-123456789012345.-01", strict: ErrInvalidSpanId, lenient: ErrInvalidSpanId},
+
+	// flags
+	{in: "00-12345678901234567890123456789012-1234567890123456-0", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: "00-12345678901234567890123456789012-1234567890123456-0g", strict: ErrInvalidFlags, lenient: ErrInvalidFlags},
+	{in: "00-12345678901234567890123456789012-1234567890123456-A1", strict: ErrInvalidFlags, sampling: true},
+
+	// version 00 is exactly 55 bytes
+	{in: "00-12345678901234567890123456789012-1234567890123456-011", strict: ErrTrailingData, sampling: true},
+	{in: "00-12345678901234567890123456789012-1234567890123456-01-", strict: ErrTrailingData, sampling: true},
+	{in: "00-12345678901234567890123456789012-1234567890123456-01-what-the-future-will-be-like", strict: ErrTrailingData, sampling: true},
+
+	// separators and whitespace
+	{in: "00_12345678901234567890123456789012_1234567890123456_01", strict: ErrMalformed, lenient: ErrMalformed},
+	{in: " 00-12345678901234567890123456789012-1234567890123456-01", strict: ErrMalformed, sampling: true},
+	{in: "00-12345678901234567890123456789012-1234567890123456-01 ", strict: ErrTrailingData, sampling: true},
+	{in: "", strict: ErrMalformed, lenient: ErrMalformed},
+}
+
+func TestParseVectors(t *testing.T) {
+	for _, tc := range traceparentVectors {
+		for _, mode := range []struct {
+			name string
+			mode ParseMode
+			want error
+		}{
+			{"strict", Strict, tc.strict},
+			{"lenient", Lenient, tc.lenient},
+		} {
+			tp, err := ParseWithMode(tc.in, mode.mode)
+			if mode.want == nil {
+				if err != nil {
+					t.Errorf("%s %q: unexpected error: %s", mode.name, tc.in, err)
+				} else if !tp.Initialized || tp.Sampling != tc.sampling {
+					t.Errorf("%s %q: got initialized=%t sampling=%t, expected sampling=%t", mode.name, tc.in, tp.Initialized, tp.Sampling, tc.sampling)
+				}
+				continue
+			}
+
+			if !errors.Is(err, mode.want) {
+				t.Errorf("%s %q: expected error %q but got %v", mode.name, tc.in, mode.want, err)
+			}
+			if tp.Initialized {
+				t.Errorf("%s %q: traceparent should not be initialized on error", mode.name, tc.in)
+			}
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, tc := range traceparentVectors {
+		f.Add(tc.in)
+	}
+
+	f.Fuzz(func(t *testing.T, in string) {
+		strict, strictErr := ParseStrict(in)
+		lenient, lenientErr := Parse(in)
+
+		for _, tp := range []Traceparent{strict, lenient} {
+			if tp.Initialized && (len(tp.TraceId) != 16 || len(tp.SpanId) != 8) {
+				t.Fatalf("parsed %q into ids of the wrong length: %d/%d", in, len(tp.TraceId), len(tp.SpanId))
+			}
+		}
+
+		for _, err := range []error{strictErr, lenientErr} {
+			var pe *ParseError
+			if err != nil && !errors.As(err, &pe) {
+				t.Fatalf("expected a *ParseError for %q but got %T", in, err)
+			}
+		}
+
+		// anything strict accepts, lenient must accept the same way
+		if strictErr == nil {
+			if lenientErr != nil {
+				t.Fatalf("strict accepted %q but lenient did not: %s", in, lenientErr)
+			}
+			if strict.Encode() != lenient.Encode() {
+				t.Fatalf("strict and lenient disagree on %q: %s vs %s", in, strict.Encode(), lenient.Encode())
+			}
+			// strict input is lower case, so version 00 round trips exactly,
+			// except for flags which are reduced to the sampled bit
+			if strict.Version == 0 && strict.Encode()[:52] != in[:52] {
+				t.Fatalf("%q did not round trip, got %q", in, strict.Encode())
+			}
+		}
+	})
+}
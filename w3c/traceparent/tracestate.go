@@ -0,0 +1,159 @@
+package traceparent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxTracestateMembers is the most list members a W3C tracestate can have.
+const maxTracestateMembers = 32
+
+// ErrInvalidTracestate is the category of all tracestate parse errors.
+var ErrInvalidTracestate = errors.New("invalid tracestate")
+
+// TracestateMember is one vendor's key and opaque value in a tracestate.
+type TracestateMember struct {
+	Key   string
+	Value string
+}
+
+// Tracestate represents a parsed W3C tracestate, the vendor-specific state
+// that travels with a traceparent. Members are in order, and the W3C spec
+// wants the most recently updated one first.
+type Tracestate []TracestateMember
+
+// ParseTracestate parses a tracestate header value. Empty list members are
+// skipped as the spec says, anything else that's invalid fails the whole
+// tracestate since a partial one can't be trusted either.
+func ParseTracestate(in string) (Tracestate, error) {
+	out := Tracestate{}
+	seen := map[string]bool{}
+	for _, member := range strings.Split(in, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(member, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: list member %q is not key=value", ErrInvalidTracestate, member)
+		}
+		if err := validateTracestateMember(key, value); err != nil {
+			return nil, err
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%w: key %q appears more than once", ErrInvalidTracestate, key)
+		}
+		seen[key] = true
+
+		out = append(out, TracestateMember{Key: key, Value: value})
+	}
+
+	if len(out) > maxTracestateMembers {
+		return nil, fmt.Errorf("%w: %d list members is more than the limit of %d", ErrInvalidTracestate, len(out), maxTracestateMembers)
+	}
+
+	return out, nil
+}
+
+// Encode returns the tracestate in W3C header format.
+func (ts Tracestate) Encode() string {
+	members := make([]string, len(ts))
+	for i, m := range ts {
+		members[i] = m.Key + "=" + m.Value
+	}
+	return strings.Join(members, ",")
+}
+
+// Get returns the value for key and whether it was there.
+func (ts Tracestate) Get(key string) (string, bool) {
+	for _, m := range ts {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns a copy of the tracestate with key set to value and moved to the
+// front, as the spec requires for updated keys. When that goes over the limit
+// the last member is dropped.
+func (ts Tracestate) Set(key, value string) (Tracestate, error) {
+	if err := validateTracestateMember(key, value); err != nil {
+		return ts, err
+	}
+
+	out := append(Tracestate{{Key: key, Value: value}}, ts.Delete(key)...)
+	if len(out) > maxTracestateMembers {
+		out = out[:maxTracestateMembers]
+	}
+
+	return out, nil
+}
+
+// Delete returns a copy of the tracestate without key.
+func (ts Tracestate) Delete(key string) Tracestate {
+	out := Tracestate{}
+	for _, m := range ts {
+		if m.Key != key {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// validateTracestateMember checks a key and value against the W3C grammar.
+// Keys are lower case letters, digits, and _-*/ starting with a letter, or
+// tenant@system where the tenant may start with a digit. Values are up to
+// 256 printable ASCII characters except comma and equals, not ending in a
+// space.
+func validateTracestateMember(key, value string) error {
+	fail := func(detail string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidTracestate, detail)
+	}
+
+	if tenant, system, multi := strings.Cut(key, "@"); multi {
+		if len(tenant) == 0 || len(tenant) > 241 || !isKeyChars(tenant) || !isLowerAlphaNum(tenant[0]) {
+			return fail(fmt.Sprintf("key %q has an invalid tenant", key))
+		}
+		if len(system) == 0 || len(system) > 14 || !isKeyChars(system) || !isLowerAlpha(system[0]) {
+			return fail(fmt.Sprintf("key %q has an invalid system", key))
+		}
+	} else if len(key) == 0 || len(key) > 256 || !isKeyChars(key) || !isLowerAlpha(key[0]) {
+		return fail(fmt.Sprintf("key %q must be lower case letters, digits, and _-*/ starting with a letter", key))
+	}
+
+	if len(value) == 0 || len(value) > 256 {
+		return fail(fmt.Sprintf("value for %q must be 1 to 256 characters", key))
+	}
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return fail(fmt.Sprintf("value for %q has invalid character %q", key, c))
+		}
+	}
+	if value[len(value)-1] == ' ' {
+		return fail(fmt.Sprintf("value for %q cannot end with a space", key))
+	}
+
+	return nil
+}
+
+func isKeyChars(in string) bool {
+	for i := 0; i < len(in); i++ {
+		c := in[i]
+		if !isLowerAlphaNum(c) && c != '_' && c != '-' && c != '*' && c != '/' {
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isLowerAlphaNum(c byte) bool {
+	return isLowerAlpha(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,111 @@
+package traceparent
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseTracestate(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want string // re-encoded, ignored on error
+		err  error
+	}{
+		{in: "", want: ""},
+		{in: "rojo=00f067aa0ba902b7", want: "rojo=00f067aa0ba902b7"},
+		// whitespace and empty list members are dropped
+		{in: " congo=t61rcWkgMzE ,, rojo=00f067aa0ba902b7\t", want: "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7"},
+		{in: "tenant1@vendor=x,1tenant@vendor=y", want: "tenant1@vendor=x,1tenant@vendor=y"},
+		{in: "a-b_c*d/e=has spaces inside", want: "a-b_c*d/e=has spaces inside"},
+		// invalid
+		{in: "novalue", err: ErrInvalidTracestate},
+		{in: "Upper=x", err: ErrInvalidTracestate},
+		{in: "1leadingdigit=x", err: ErrInvalidTracestate},
+		{in: "key=", err: ErrInvalidTracestate},
+		{in: "key=a=b", err: ErrInvalidTracestate},
+		// optional whitespace around members is trimmed
+		{in: "key=trailing ", want: "key=trailing"},
+		{in: "tenant@1system=x", err: ErrInvalidTracestate},
+		{in: "dup=1,dup=2", err: ErrInvalidTracestate},
+	} {
+		ts, err := ParseTracestate(tc.in)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("expected error %v for %q but got %v", tc.err, tc.in, err)
+		} else if err == nil && ts.Encode() != tc.want {
+			t.Errorf("expected %q from %q but got %q", tc.want, tc.in, ts.Encode())
+		}
+	}
+
+	members := make([]string, maxTracestateMembers+1)
+	for i := range members {
+		members[i] = fmt.Sprintf("k%d=v", i)
+	}
+	if _, err := ParseTracestate(strings.Join(members, ",")); !errors.Is(err, ErrInvalidTracestate) {
+		t.Errorf("expected an error for %d list members but got %v", len(members), err)
+	}
+}
+
+func TestTracestateMutation(t *testing.T) {
+	ts, _ := ParseTracestate("congo=t61rcWkgMzE,rojo=00f067aa0ba902b7")
+
+	// updated keys move to the front
+	ts, err := ts.Set("rojo", "updated")
+	if err != nil {
+		t.Fatalf("unexpected error from Set: %s", err)
+	}
+	if ts.Encode() != "rojo=updated,congo=t61rcWkgMzE" {
+		t.Errorf("unexpected tracestate after Set: %q", ts.Encode())
+	}
+	if v, ok := ts.Get("congo"); !ok || v != "t61rcWkgMzE" {
+		t.Errorf("expected congo=t61rcWkgMzE but got %q, %t", v, ok)
+	}
+
+	if _, err := ts.Set("BAD", "x"); !errors.Is(err, ErrInvalidTracestate) {
+		t.Errorf("expected an error setting an invalid key but got %v", err)
+	}
+	if _, err := ts.Set("key", "trailing "); !errors.Is(err, ErrInvalidTracestate) {
+		t.Errorf("expected an error setting a value ending in a space but got %v", err)
+	}
+
+	ts = ts.Delete("congo")
+	if ts.Encode() != "rojo=updated" {
+		t.Errorf("unexpected tracestate after Delete: %q", ts.Encode())
+	}
+
+	// adding to a full tracestate drops the last member
+	full := Tracestate{}
+	for i := 0; i < maxTracestateMembers; i++ {
+		full = append(full, TracestateMember{Key: fmt.Sprintf("k%d", i), Value: "v"})
+	}
+	full, _ = full.Set("new", "v")
+	if len(full) != maxTracestateMembers || full[0].Key != "new" {
+		t.Errorf("expected %d members starting with new but got %q", maxTracestateMembers, full.Encode())
+	}
+	if _, ok := full.Get(fmt.Sprintf("k%d", maxTracestateMembers-1)); ok {
+		t.Error("expected the last member to be dropped")
+	}
+}
+
+func TestTracestateCarrierFile(t *testing.T) {
+	tp, _ := Parse("00-f61fc53f926e07a9c3893b1a722e1b65-7a2d6a804f3de137-01")
+	tp.Tracestate, _ = ParseTracestate("congo=it's fine,rojo=00f067aa0ba902b7")
+
+	carrier := filepath.Join(t.TempDir(), "carrier")
+	if err := tp.SaveToFile(carrier, true); err != nil {
+		t.Fatalf("SaveToFile returned an unexpected error: %s", err)
+	}
+
+	got, err := LoadFromFile(carrier)
+	if err != nil {
+		t.Fatalf("LoadFromFile returned an unexpected error: %s", err)
+	}
+	if got.Encode() != tp.Encode() {
+		t.Errorf("expected traceparent %s but got %s", tp.Encode(), got.Encode())
+	}
+	if got.Tracestate.Encode() != tp.Tracestate.Encode() {
+		t.Errorf("expected tracestate %q but got %q", tp.Tracestate.Encode(), got.Tracestate.Encode())
+	}
+}